@@ -0,0 +1,240 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/api/cms"
+	"backend/errors"
+	"backend/forms"
+	"backend/migrations"
+	"backend/models"
+	"backend/utils"
+)
+
+// passwordOrRandom returns the given password, or a random one when it is empty.
+func passwordOrRandom(password string) (string, bool, error) {
+	if password != "" {
+		return password, false, nil
+	}
+
+	password, err := utils.RandomCode(16)
+	return password, true, err
+}
+
+func createAdmin(args []string) int {
+	flags := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	email := flags.String("email", "", "email of the admin (required)")
+	first := flags.String("first", "Site", "first name, for a new account")
+	last := flags.String("last", "Admin", "last name, for a new account")
+	password := flags.String("password", "", "password, for a new account (random if empty)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *email == "" {
+		flags.Usage()
+		return 2
+	}
+
+	um := models.NewMongoUserInterface()
+	user, err := um.FindOne(*email)
+	if err != nil && err != errors.ErrorResourceNotFound {
+		fmt.Println(err)
+		return 1
+	}
+
+	if user == nil {
+		pass, generated, errs := passwordOrRandom(*password)
+		if errs != nil {
+			fmt.Println(errs)
+			return 1
+		}
+
+		err = um.Register(forms.UserRegisterForm{
+			Email:                *email,
+			Password:             pass,
+			PasswordConfirmation: pass,
+			First:                *first,
+			Last:                 *last,
+		}, nil)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+
+		if user, err = um.FindOne(*email); err != nil {
+			fmt.Println(err)
+			return 1
+		}
+
+		if generated {
+			fmt.Printf("created %s with password %s\n", *email, pass)
+		} else {
+			fmt.Printf("created %s\n", *email)
+		}
+	}
+
+	if err = um.SetAdmin(user.ID, true); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	fmt.Printf("%s is an admin\n", *email)
+
+	return 0
+}
+
+func resetPassword(args []string) int {
+	flags := flag.NewFlagSet("reset-password", flag.ContinueOnError)
+	email := flags.String("email", "", "email of the user (required)")
+	password := flags.String("password", "", "new password (random if empty)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *email == "" {
+		flags.Usage()
+		return 2
+	}
+
+	um := models.NewMongoUserInterface()
+	user, err := um.FindOne(*email)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	pass, generated, errs := passwordOrRandom(*password)
+	if errs != nil {
+		fmt.Println(errs)
+		return 1
+	}
+
+	if err = um.SetPassword(user.ID, pass); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	if generated {
+		fmt.Printf("password of %s reset to %s\n", *email, pass)
+	} else {
+		fmt.Printf("password of %s reset\n", *email)
+	}
+
+	return 0
+}
+
+func requeueStuckSubmissions(args []string) int {
+	flags := flag.NewFlagSet("requeue-stuck-submissions", flag.ContinueOnError)
+	olderThan := flags.Duration("older-than", 30*time.Minute, "requeue submissions in grading for longer than this")
+	dryRun := flags.Bool("dry-run", false, "only list the stuck submissions")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	am := models.NewMongoAssignmentInterface()
+	sm := models.NewMongoSubmissionInterface()
+
+	submissions, err := sm.InProgressBefore(time.Now().Add(-*olderThan))
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	failed := 0
+	for _, submission := range submissions {
+		submitted := utils.DateTimeToTime(submission.SubmissionDate).Format(time.RFC3339)
+		if *dryRun {
+			fmt.Printf("stuck %s submitted %s\n", submission.ID.Hex(), submitted)
+			continue
+		}
+
+		assign, err := am.Get(submission.AssignmentID)
+		var graders []string
+		if err == nil {
+			graders, err = cms.GradersFor(assign)
+		}
+		if err == nil {
+			_, err = sm.Requeue(submission, assign.Job(submission.UserID), graders)
+		}
+		if err != nil {
+			fmt.Printf("failed  %s submitted %s: %s\n", submission.ID.Hex(), submitted, err)
+			failed++
+			continue
+		}
+		fmt.Printf("requeued %s submitted %s\n", submission.ID.Hex(), submitted)
+	}
+	fmt.Printf("%d stuck submissions\n", len(submissions))
+
+	if failed > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+func reindex(args []string) int {
+	flags := flag.NewFlagSet("reindex", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	migrator, err := migrations.New()
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	created, err := migrator.EnsureIndexes()
+	for _, index := range created {
+		fmt.Printf("created %s on %s\n", index.Name, index.Collection)
+	}
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	fmt.Printf("%d indexes created\n", len(created))
+
+	return 0
+}
+
+func exportCourse(args []string) int {
+	flags := flag.NewFlagSet("export-course", flag.ContinueOnError)
+	cid := flags.String("course", "", "id of the course (required)")
+	out := flags.String("out", "", "file to write the zip to (named after the course if empty)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	id, errs := primitive.ObjectIDFromHex(*cid)
+	if errs != nil {
+		flags.Usage()
+		return 2
+	}
+
+	course, err := models.NewMongoCourseInterface().GetByID(id)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	archive, filename, err := cms.BuildCourseArchive(*course, "export")
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	if *out != "" {
+		filename = *out
+	}
+
+	if errs = ioutil.WriteFile(filename, archive.Bytes(), 0600); errs != nil {
+		fmt.Println(errs)
+		return 1
+	}
+	fmt.Printf("wrote %s\n", filename)
+
+	return 0
+}
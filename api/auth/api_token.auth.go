@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/errors"
+	"backend/models"
+	"backend/models/tokenmodels"
+	"backend/models/usermodels"
+)
+
+var tm = models.NewMongoTokenInterface()
+
+// tokenIdentity who an API token request is made as, PayloadFunc adds the
+// token's scopes to the user's claims.
+type tokenIdentity struct {
+	user  *usermodels.MongoUser
+	token *tokenmodels.MongoAPIToken
+}
+
+// tokenRestricted routes that can never be used with an API token, so a
+// leaked token cannot be used to take over the account.
+var tokenRestricted = map[string]bool{
+	"user/tokens":             true,
+	"user/token/create":       true,
+	"user/token/:tkid/revoke": true,
+	"user/delete":             true,
+	"user/delete/cancel":      true,
+	"user/export":             true,
+}
+
+// submitRoutes the routes the submit scope allows, starting and submitting
+// assignments and the command line protocol.
+var submitRoutes = map[string]bool{
+	"course/:cid/assignment/submit/:aid":     true,
+	"course/:cid/assignment/start/:aid":      true,
+	"course/:cid/assignment/submit/:aid/git": true,
+	"course/:cid/assignment/precheck/:aid":   true,
+	"cli/whoami":                             true,
+	"cli/assignments":                        true,
+	"cli/course/:cid/submit/:aid":            true,
+	"cli/course/:cid/submission/:sid":        true,
+}
+
+// calendarRoutes the iCalendar feeds, the routes a token can be given in the
+// token query parameter.
+var calendarRoutes = map[string]bool{
+	"calendar.ics":             true,
+	"course/:cid/calendar.ics": true,
+}
+
+func scopeAllows(scopes []interface{}, route, method string) bool {
+	if tokenRestricted[route] {
+		return false
+	}
+
+	for _, scope := range scopes {
+		switch scope {
+		case tokenmodels.ScopeWrite:
+			return true
+		case tokenmodels.ScopeRead:
+			if method == "GET" {
+				return true
+			}
+		case tokenmodels.ScopeSubmit:
+			if submitRoutes[route] {
+				return true
+			}
+		case tokenmodels.ScopeCalendar:
+			if calendarRoutes[route] && method == "GET" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func abortWithError(c *gin.Context, err errors.APIError) {
+	c.AbortWithStatusJSON(err.StatusCode(), gin.H{
+		"error": err.Error(),
+	})
+}
+
+// APITokens authenticates requests sent with an API token,
+// "Authorization: Token <token>", instead of a login. The token is swapped for
+// a short lived jwt carrying the token's scopes, so the request then goes
+// through the same authorization as a logged in user's. Tokens only work on
+// the cms routes, they cannot be refreshed into a session. Calendar feeds also
+// take a calendar scoped token in the token query parameter.
+func APITokens() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		path := c.Request.URL.Path
+		if !strings.HasPrefix(path, "/api/v1/plague_doctor/") {
+			c.Next()
+			return
+		}
+
+		raw := strings.TrimPrefix(header, "Token ")
+		fromQuery := false
+		if !strings.HasPrefix(header, "Token ") {
+			if !strings.HasSuffix(path, ".ics") || c.Query("token") == "" {
+				c.Next()
+				return
+			}
+			raw, fromQuery = c.Query("token"), true
+		}
+
+		if !tokenmodels.IsToken(raw) {
+			abortWithError(c, errors.ErrorInvalidAPIToken)
+			return
+		}
+
+		token, err := tm.Authenticate(raw)
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		// a token in a url ends up in logs and histories, so only one that
+		// can do nothing but read calendars is accepted there
+		if fromQuery && (len(token.Scopes) != 1 || token.Scopes[0] != tokenmodels.ScopeCalendar) {
+			abortWithError(c, errors.ErrorInvalidAPIToken)
+			return
+		}
+
+		// counted in mongo so the limit holds across every instance
+		count, err := tm.CountRequest(token.ID, time.Now())
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+		if count > token.RateLimit {
+			c.Header("Retry-After", "60")
+			abortWithError(c, errors.ErrorRateLimitExceeded)
+			return
+		}
+
+		user, err := um.FindOneById(token.UserID)
+		if err != nil || user.Deleted {
+			abortWithError(c, errors.ErrorInvalidAPIToken)
+			return
+		}
+
+		claims, _ := freshClaims(&tokenIdentity{user, token})
+		jwtToken, errs := internalToken(claims)
+		if errs != nil {
+			abortWithError(c, errors.ErrorGenerateTokenFailure)
+			return
+		}
+
+		tm.Touch(token.ID)
+
+		c.Request.Header.Set("Authorization", AuthMiddleware.TokenHeadName+" "+jwtToken)
+		c.Next()
+	}
+}
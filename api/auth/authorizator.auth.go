@@ -18,7 +18,7 @@ func allowed(levels []string, claims map[string]interface{}, c *gin.Context) boo
 	val, found := enrolledCourses[cid.(string)]
 	c.Set("role", val)
 	if found && (in(levels, "any") || in(levels, val.(string))) {
-		return true
+		return val != "assistant" || sectionAllowed(c)
 	}
 
 	if in(levels, "student") && exists {
@@ -34,6 +34,35 @@ func allowed(levels []string, claims map[string]interface{}, c *gin.Context) boo
 	return allowed
 }
 
+// sectionAllowed reports whether an assistant may act on the student a route
+// names, by :suid or as the owner of the submission :sid, in a course whose
+// grading is scoped to sections. Routes naming no student are allowed.
+func sectionAllowed(c *gin.Context) bool {
+	uid, _ := c.Get("uid")
+	suid, found := c.Get("suid")
+	if !found {
+		sid, exists := c.Get("sid")
+		if !exists {
+			return true
+		}
+
+		// a missing submission is reported by the handler
+		submission, err := sm.Get(sid, "assistant")
+		if err != nil {
+			return true
+		}
+		suid = submission.UserID
+	}
+
+	cid, _ := c.Get("cid")
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		return true
+	}
+
+	return course.AssistantGrades(uid.(primitive.ObjectID), suid.(primitive.ObjectID))
+}
+
 func in(terms []string, term string) bool {
 	for _, val := range terms {
 		if val == term {
@@ -50,6 +79,10 @@ func determineLevel(route string) []string {
 		allowed = append(allowed, "admin")
 	}
 
+	if _, found := routeLevels["orgadmin"][route]; found {
+		allowed = append(allowed, "orgadmin")
+	}
+
 	if _, found := routeLevels["any"][route]; found {
 		allowed = append(allowed, "any")
 	}
@@ -75,7 +108,7 @@ func determineLevel(route string) []string {
 
 // Authorizator a default function for a gin jwt, that authorizes a user.
 func Authorizator(d interface{}, c *gin.Context) bool {
-	route := strings.TrimPrefix(c.Request.URL.String(), "/api/v1/plague_doctor/")
+	route := strings.TrimPrefix(c.Request.URL.Path, "/api/v1/plague_doctor/")
 	for _, p := range c.Params {
 		route = strings.Replace(route, p.Value, ":"+p.Key, 1)
 	}
@@ -85,12 +118,32 @@ func Authorizator(d interface{}, c *gin.Context) bool {
 	val, _ := primitive.ObjectIDFromHex(uids)
 	c.Set("uid", val)
 
+	if orgs, found := claims["org"].(string); found {
+		org, _ := primitive.ObjectIDFromHex(orgs)
+		c.Set("org", org)
+	}
+
+	if scopes, ok := claims["scopes"].([]interface{}); ok && !scopeAllows(scopes, route, c.Request.Method) {
+		return false
+	}
+
+	if staff, ok := claims["impersonator"].(string); ok && !impersonationAllows(c, claims, staff, route) {
+		return false
+	}
+
 	userLevelForRouteShouldBe := determineLevel(route)
 	if in(userLevelForRouteShouldBe, "whitelisted") {
 		return true
 	}
 
 	admin := claims["admin"].(bool)
+	orgAdmin, _ := claims["orgAdmin"].(bool)
+	if in(userLevelForRouteShouldBe, "orgadmin") && (admin || orgAdmin) {
+		return true
+	} else if in(userLevelForRouteShouldBe, "orgadmin") {
+		return false
+	}
+
 	if in(userLevelForRouteShouldBe, "admin") && admin {
 		return true
 	} else if in(userLevelForRouteShouldBe, "admin") && !admin {
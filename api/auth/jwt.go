@@ -1,21 +1,23 @@
 package auth
 
 import (
-	"os"
 	"time"
 
 	jwt "github.com/appleboy/gin-jwt"
 
+	"backend/config"
 	"backend/models"
 )
 
 var um = models.NewMongoUserInterface()
 var sm = models.NewMongoSubmissionInterface()
+var om = models.NewMongoOrganizationInterface()
+var cm = models.NewMongoCourseInterface()
 
 // AuthMiddleware is a jwt middleware for auth requests
 var AuthMiddleware, _ = jwt.New(&jwt.GinJWTMiddleware{
-	Realm:           os.Getenv("JWT_REALM"),
-	Key:             []byte(os.Getenv("JWT_SECRET")),
+	Realm:           config.C.JWTRealm,
+	Key:             internalKey,
 	Timeout:         time.Hour,
 	MaxRefresh:      time.Hour * 24,
 	Authenticator:   Authenticator,
@@ -36,30 +38,174 @@ var AuthMiddleware, _ = jwt.New(&jwt.GinJWTMiddleware{
 
 var routeLevels = map[string]map[string]string{
 	"admin": {
+		"admin/orgs":                "Organizations",
+		"admin/org/create":          "CreateOrganization",
+		"admin/org/:oid/user/:suid": "SetUserOrganization",
+
+		"admin/user/:suid/export": "ExportUserData",
+		"admin/user/:suid/delete": "DeleteUser",
+
+		"admin/flags":                  "FeatureFlags",
+		"admin/flag/:flag":             "UpdateFeatureFlag",
+		"admin/flag/:flag/course/:cid": "UpdateCourseFeatureFlag",
+		"admin/course/:cid/quota":      "SetCourseQuota",
+		"admin/reports/usage":          "UsageReport",
+		"admin/grading/capacity":       "GradingCapacity",
+		"admin/graders":                "Graders",
+		"admin/grader/create":          "CreateGrader",
+		"admin/grader/:grid":           "UpdateGrader",
+		"admin/grader/:grid/delete":    "DeleteGrader",
+
+		"admin/keys/rotate": "RotateSigningKeys",
+
+		"admin/jobs":           "Jobs",
+		"admin/job/:jid":       "GetJob",
+		"admin/job/:jid/retry": "RetryJob",
+
+		"admin/webhooks":                 "Webhooks",
+		"admin/webhook/create":           "CreateWebhook",
+		"admin/webhook/:whid/deliveries": "WebhookDeliveries",
+		"admin/webhook/:whid/delete":     "DeleteWebhook",
+
+		"admin/research/export": "ResearchExport",
+	},
+	// orgadmin routes are for admins of the user's organization, and admins.
+	"orgadmin": {
 		"create/course": "CreateCourse",
+
+		"org/update":      "UpdateOrganization",
+		"org/users":       "OrganizationUsers",
+		"org/courses":     "OrganizationCourses",
+		"org/admin/:suid": "SetOrganizationAdmin",
 	},
 	"any": {
 		"course/:cid":             "GetCourse",
 		"course/:cid/assignments": "CourseAssignments",
 		"course/:cid/assignment/:aid/submission/:sid/details":       "GetSubmission",
+		"course/:cid/assignment/:aid/submission/:sid/progress":      "SubmissionProgress",
 		"course/:cid/assignment/:aid/submission/:sid/download/:num": "DownloadSubmission",
 		"course/:cid/assignment/:aid/details":                       "GetAssignment",
+		"course/:cid/assignment/:aid/attachment/:fid":               "GetAttachment",
+		"course/:cid/announcements":                                 "CourseAnnouncements",
+		"course/:cid/assignment/:aid/threads":                       "AssignmentThreads",
+		"course/:cid/assignment/thread/:aid":                        "CreateThread",
+		"course/:cid/thread/:tid":                                   "GetThread",
+		"course/:cid/thread/:tid/post":                              "CreatePost",
+		"course/:cid/assignment/:aid/submission/:sid/output/:num":   "SubmissionOutput",
+
+		"cli/course/:cid/submission/:sid": "CLISubmission",
+
+		"course/:cid/assignment/:aid/submission/:sid/artifact/:slot": "SubmissionArtifact",
+		"course/:cid/assignment/:aid/submission/:sid/document":       "SubmissionDocument",
+
+		"course/:cid/assignment/:aid/quiz/attempt/:qaid": "GetQuizAttempt",
+
+		"course/:cid/assignment/:aid/leaderboard":        "Leaderboard",
+		"course/:cid/assignment/:aid/leaderboard/optout": "LeaderboardOptOut",
+		"course/:cid/assignment/precheck/:aid":           "PrecheckSubmission",
+		"course/:cid/assignment/:aid/submissions/diff":   "SubmissionDiff",
+		"course/:cid/assignment/:aid/history/:suid":      "SubmissionHistory",
+
+		"course/:cid/disputes":             "CourseDisputes",
+		"course/:cid/dispute/:did":         "GetDispute",
+		"course/:cid/dispute/:did/message": "AddDisputeMessage",
+		"course/:cid/gradebook":            "Gradebook",
+		"course/:cid/attendance":           "CourseAttendance",
+		"course/:cid/calendar.ics":         "CourseCalendar",
+		"course/:cid/features":             "CourseFeatures",
+
+		"course/:cid/extensions":      "CourseExtensionRequests",
+		"course/:cid/extension/:erid": "GetExtensionRequest",
+
+		"course/:cid/home":                  "CourseHome",
+		"course/:cid/modules":               "CourseModules",
+		"course/:cid/module/:mid/file/:fid": "GetModuleFile",
 	},
 	"assistant": map[string]string{
 		"course/:cid/add/user":                          "CourseAddUser",
 		"course/:cid/assignment/create":                 "CreateAssignment",
 		"course/:cid/assignment/fromfile":               "CreateAssignmentFromFile",
+		"course/:cid/assignment/import":                 "PreviewAssignmentImport",
 		"course/:cid/assignment/:aid/delete/assignment": "DeleteAssignment",
 		"course/:cid/assignment/:aid/csv":               "GradesAsCSV",
 		"course/:cid/assignment/:aid/update":            "UpdateAssignment",
+		"course/:cid/assignment/:aid/prerequisites":     "UpdatePrerequisites",
 		"course/:cid/update":                            "UpdateCourse",
 		"course/:cid/submission/:sid/update":            "UpdateGrade",
+
+		"course/:cid/assignment/attachment/:aid":             "UploadAttachment",
+		"course/:cid/assignment/:aid/attachment/:fid/delete": "DeleteAttachment",
+		"course/:cid/assignment/fixture/:aid":                "UploadFixture",
+		"course/:cid/assignment/:aid/fixtures":               "GetFixtures",
+		"course/:cid/assignment/:aid/fixture/:fid":           "GetFixture",
+		"course/:cid/assignment/:aid/fixture/:fid/delete":    "DeleteFixture",
+		"course/:cid/assignment/dryrun/:aid":                 "DryRunAssignment",
+		"course/:cid/assignment/reference/:aid":              "UploadReferenceSolution",
+		"course/:cid/assignment/:aid/reference":              "GetReferenceSolution",
+		"course/:cid/assignment/:aid/reference/download":     "DownloadReferenceSolution",
+		"course/:cid/assignment/:aid/reference/delete":       "DeleteReferenceSolution",
+		"course/:cid/assignment/:aid/readiness":              "AssignmentReadiness",
+
+		"course/:cid/thread/:tid/post/:pid/endorse": "EndorsePost",
+		"course/:cid/dispute/:did/status":           "UpdateDisputeStatus",
+		"course/:cid/bonuses":                       "CourseBonuses",
+		"course/:cid/bonus/:suid":                   "GrantBonus",
+		"course/:cid/bonus/:bid/delete":             "RevokeBonus",
+		"course/:cid/gradebook/csv":                 "GradebookAsCSV",
+		"course/:cid/officehours":                   "UpdateOfficeHours",
+
+		"course/:cid/webhooks":                 "Webhooks",
+		"course/:cid/webhook/create":           "CreateWebhook",
+		"course/:cid/webhook/:whid/deliveries": "WebhookDeliveries",
+		"course/:cid/webhook/:whid/delete":     "DeleteWebhook",
+
+		"course/:cid/attendance/create":           "CreateAttendanceSession",
+		"course/:cid/attendance/:atid":            "GetAttendanceSession",
+		"course/:cid/attendance/:atid/mark/:suid": "MarkAttendance",
+		"course/:cid/attendance/:atid/close":      "CloseAttendanceSession",
+		"course/:cid/attendance/:atid/delete":     "DeleteAttendanceSession",
+
+		"course/:cid/waitlist":          "CourseWaitlist",
+		"course/:cid/pending":           "CoursePendingEnrollments",
+		"course/:cid/pending/delete":    "CancelPendingEnrollment",
+		"course/:cid/sections":          "CourseSections",
+		"course/:cid/quota":             "CourseQuota",
+		"course/:cid/impersonate/:suid": "ImpersonateStudent",
+
+		"course/:cid/assignment/:aid/submission/:sid/job": "SubmissionJob",
+		"course/:cid/submission/:sid/job":                 "CancelSubmissionJob",
+
+		"course/:cid/assignment/:aid/starts":                "AssignmentStarts",
+		"course/:cid/assignment/:aid/accommodation/:suid":   "GrantAccommodation",
+		"course/:cid/assignment/:aid/anomalies":             "SubmissionAnomalies",
+		"course/:cid/assignment/:aid/submissions/search":    "SearchSubmissionCode",
+		"course/:cid/assignment/:aid/grading":               "GradingProgress",
+		"course/:cid/assignment/:aid/grading/:sid/complete": "CompleteGrading",
+
+		"course/:cid/assignment/:aid/submission/:sid/review": "ReviewSubmission",
+
+		"course/:cid/questions":            "CourseQuestions",
+		"course/:cid/question/create":      "CreateQuestion",
+		"course/:cid/question/:qid/update": "UpdateQuestion",
+		"course/:cid/question/:qid/delete": "DeleteQuestion",
+
+		"course/:cid/assignment/:aid/regrades":      "Regrades",
+		"course/:cid/assignment/:aid/regrade/:rgid": "RegradeReport",
+
+		"course/:cid/assignment/:aid/tests/stability": "TestStabilityReport",
+
+		"course/:cid/extension/:erid/decide": "DecideExtension",
+
+		"course/:cid/policies": "PolicyTemplates",
+
+		"course/:cid/results/csv": "TestResultsAsCSV",
 	},
 	"teacher": {
 		"course/:cid/add/user":                          "CourseAddUser",
 		"course/:cid/add/users":                         "CourseAddUsers",
 		"course/:cid/assignment/create":                 "CreateAssignment",
 		"course/:cid/assignment/fromfile":               "CreateAssignmentFromFile",
+		"course/:cid/assignment/import":                 "PreviewAssignmentImport",
 		"course/:cid/assignment/:aid/delete/assignment": "DeleteAssignment",
 		"course/:cid/assignment/:aid/delete/course":     "DeleteCourse",
 		"course/:cid/assignment/:aid/file":              "AssignmentAsFile",
@@ -67,8 +213,131 @@ var routeLevels = map[string]map[string]string{
 		"course/:cid/assignment/:aid/update":            "UpdateAssignment",
 		"course/:cid/update":                            "UpdateCourse",
 		"course/:cid/submission/:sid/update":            "UpdateGrade",
+
+		"course/:cid/assignment/attachment/:aid":             "UploadAttachment",
+		"course/:cid/assignment/:aid/attachment/:fid/delete": "DeleteAttachment",
+		"course/:cid/assignment/fixture/:aid":                "UploadFixture",
+		"course/:cid/assignment/:aid/fixtures":               "GetFixtures",
+		"course/:cid/assignment/:aid/fixture/:fid":           "GetFixture",
+		"course/:cid/assignment/:aid/fixture/:fid/delete":    "DeleteFixture",
+		"course/:cid/assignment/dryrun/:aid":                 "DryRunAssignment",
+		"course/:cid/assignment/reference/:aid":              "UploadReferenceSolution",
+		"course/:cid/assignment/:aid/reference":              "GetReferenceSolution",
+		"course/:cid/assignment/:aid/reference/download":     "DownloadReferenceSolution",
+		"course/:cid/assignment/:aid/reference/delete":       "DeleteReferenceSolution",
+		"course/:cid/assignment/:aid/readiness":              "AssignmentReadiness",
+
+		"course/:cid/announcement/create":       "CreateAnnouncement",
+		"course/:cid/announcement/:anid/update": "UpdateAnnouncement",
+		"course/:cid/announcement/:anid/delete": "DeleteAnnouncement",
+
+		"course/:cid/thread/:tid/post/:pid/endorse": "EndorsePost",
+		"course/:cid/dispute/:did/status":           "UpdateDisputeStatus",
+		"course/:cid/bonuses":                       "CourseBonuses",
+		"course/:cid/bonus/:suid":                   "GrantBonus",
+		"course/:cid/bonus/:bid/delete":             "RevokeBonus",
+		"course/:cid/gradebook/csv":                 "GradebookAsCSV",
+		"course/:cid/officehours":                   "UpdateOfficeHours",
+
+		"course/:cid/webhooks":                 "Webhooks",
+		"course/:cid/webhook/create":           "CreateWebhook",
+		"course/:cid/webhook/:whid/deliveries": "WebhookDeliveries",
+		"course/:cid/webhook/:whid/delete":     "DeleteWebhook",
+
+		"course/:cid/attendance/create":           "CreateAttendanceSession",
+		"course/:cid/attendance/:atid":            "GetAttendanceSession",
+		"course/:cid/attendance/:atid/mark/:suid": "MarkAttendance",
+		"course/:cid/attendance/:atid/close":      "CloseAttendanceSession",
+		"course/:cid/attendance/:atid/delete":     "DeleteAttendanceSession",
+
+		"course/:cid/invites":              "CourseInviteCodes",
+		"course/:cid/invite/create":        "CreateInviteCode",
+		"course/:cid/invite/:role/disable": "DisableInviteCode",
+
+		"course/:cid/waitlist":          "CourseWaitlist",
+		"course/:cid/waitlist/admit":    "AdmitFromWaitlist",
+		"course/:cid/pending":           "CoursePendingEnrollments",
+		"course/:cid/pending/delete":    "CancelPendingEnrollment",
+		"course/:cid/sections":          "CourseSections",
+		"course/:cid/quota":             "CourseQuota",
+		"course/:cid/impersonate/:suid": "ImpersonateStudent",
+		"course/:cid/impersonations":    "Impersonations",
+		"course/:cid/sections/update":   "UpdateSections",
+		"course/:cid/assignments/bulk":  "BulkUpdateAssignments",
+
+		"course/:cid/student/:suid/drop":      "DropStudent",
+		"course/:cid/student/:suid/reinstate": "ReinstateStudent",
+
+		"course/:cid/retention":      "UpdateRetention",
+		"course/:cid/gradescale":     "UpdateGradeScale",
+		"course/:cid/archives":       "CourseArchives",
+		"course/:cid/archive/create": "CreateArchive",
+		"course/:cid/archived":       "SetCourseArchived",
+		"course/:cid/cartridge":      "ExportCartridge",
+		"course/:cid/archive/:fid":   "DownloadArchive",
+
+		"course/:cid/assignment/:aid/submission/:sid/job": "SubmissionJob",
+		"course/:cid/submission/:sid/job":                 "CancelSubmissionJob",
+
+		"course/:cid/assignment/:aid/starts":                "AssignmentStarts",
+		"course/:cid/assignment/:aid/accommodation/:suid":   "GrantAccommodation",
+		"course/:cid/assignment/:aid/anomalies":             "SubmissionAnomalies",
+		"course/:cid/assignment/:aid/submissions/search":    "SearchSubmissionCode",
+		"course/:cid/assignment/distribute/:aid":            "DistributeGrading",
+		"course/:cid/assignment/:aid/grading":               "GradingProgress",
+		"course/:cid/assignment/:aid/grading/:sid/complete": "CompleteGrading",
+
+		"course/:cid/assignment/:aid/submission/:sid/review": "ReviewSubmission",
+
+		"course/:cid/questions":            "CourseQuestions",
+		"course/:cid/question/create":      "CreateQuestion",
+		"course/:cid/question/:qid/update": "UpdateQuestion",
+		"course/:cid/question/:qid/delete": "DeleteQuestion",
+		"course/:cid/assignment/:aid/quiz": "UpdateQuiz",
+
+		"course/:cid/assignment/:aid/regrade":       "RegradeAssignment",
+		"course/:cid/assignment/:aid/regrades":      "Regrades",
+		"course/:cid/assignment/:aid/regrade/:rgid": "RegradeReport",
+
+		"course/:cid/assignment/:aid/tests/stability": "TestStabilityReport",
+
+		"course/:cid/extension/:erid/decide": "DecideExtension",
+
+		"course/:cid/policies":        "PolicyTemplates",
+		"course/:cid/policies/update": "UpdatePolicyTemplates",
+
+		"course/:cid/module/create":                "CreateModule",
+		"course/:cid/modules/reorder":              "ReorderModules",
+		"course/:cid/module/:mid/update":           "UpdateModule",
+		"course/:cid/module/:mid/delete":           "DeleteModule",
+		"course/:cid/module/:mid/file":             "UploadModuleFile",
+		"course/:cid/module/:mid/file/:fid/delete": "DeleteModuleFile",
+
+		"course/:cid/results/csv": "TestResultsAsCSV",
+
+		"course/:cid/research/consent": "UpdateResearchConsent",
 	},
 	"student": {
 		"course/:cid/:section/assignment/submit/:aid": "SubmitAssignment",
+		"course/:cid/assignment/start/:aid":           "StartAssignment",
+		"course/:cid/assignment/:aid/status":          "AssignmentSubmissionStatus",
+		"cli/course/:cid/submit/:aid":                 "CLISubmit",
+		"course/:cid/assignment/submit/:aid/git":      "SubmitRepository",
+		"course/:cid/assignment/:aid/repository":      "LinkRepository",
+		"course/:cid/submission/:sid/job":             "CancelSubmissionJob",
+		"course/:cid/disputes/open/:sid":              "OpenDispute",
+		"course/:cid/attendance/checkin/:atid":        "CheckInAttendance",
+		"course/:cid/share/:sid":                      "CreateShareLink",
+		"course/:cid/share/:sid/:shid":                "RevokeShareLink",
+
+		"course/:cid/assignment/:aid/quiz/start":                "StartQuiz",
+		"course/:cid/assignment/:aid/quiz/attempt/:qaid/save":   "SaveQuizAttempt",
+		"course/:cid/assignment/:aid/quiz/attempt/:qaid/submit": "SubmitQuizAttempt",
+
+		"course/:cid/assignment/submit/:aid/editor": "SubmitEditor",
+		"course/:cid/assignment/:aid/editor":        "EditorBuffers",
+		"course/:cid/assignment/:aid/draft":         "SaveDraft",
+
+		"course/:cid/assignment/:aid/extension": "RequestExtension",
 	},
 }
@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"crypto/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/config"
+	"backend/errors"
+)
+
+const (
+	// keyRefreshInterval how long the signing keys are used before they are
+	// read again.
+	keyRefreshInterval = time.Minute
+	// unknownKeyInterval how often a token naming an unknown key can make the
+	// keys be read again.
+	unknownKeyInterval = 10 * time.Second
+)
+
+// keyring the keys tokens given to clients are signed with. A token is
+// verified with the key its kid names, so every active key is accepted while
+// the newest signs. The keys are read again every minute, or when a token
+// names one that is not known yet, so every instance picks up a rotation.
+type keyring struct {
+	sync.RWMutex
+	keys     []config.JWTKey
+	loadedAt time.Time
+}
+
+var keys = &keyring{keys: config.C.JWTKeys, loadedAt: time.Now()}
+
+// internalKey signs the tokens handed to gin-jwt once a client's token has
+// been verified, as gin-jwt only knows a single key. It never leaves the
+// process.
+var internalKey = randomKey()
+
+func randomKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+
+	return key
+}
+
+// reload reads the keys from the configuration again.
+func (k *keyring) reload() ([]config.JWTKey, error) {
+	loaded, err := config.JWTKeys()
+
+	k.Lock()
+	defer k.Unlock()
+
+	// a broken secret keeps the keys that were working
+	k.loadedAt = time.Now()
+	if err != nil {
+		return k.keys, err
+	}
+
+	k.keys = loaded
+	return loaded, nil
+}
+
+// active the active keys, oldest first.
+func (k *keyring) active() []config.JWTKey {
+	k.RLock()
+	stale := time.Since(k.loadedAt) >= keyRefreshInterval
+	active := k.keys
+	k.RUnlock()
+
+	if !stale {
+		return active
+	}
+
+	active, err := k.reload()
+	if err != nil {
+		tyrgin.ErrorLogger(err, "Failed to reload the signing keys.")
+	}
+
+	return active
+}
+
+func (k *keyring) find(kid string) (config.JWTKey, bool) {
+	for _, key := range k.active() {
+		if key.ID == kid {
+			return key, true
+		}
+	}
+
+	k.RLock()
+	recent := time.Since(k.loadedAt) < unknownKeyInterval
+	k.RUnlock()
+	if recent {
+		return config.JWTKey{}, false
+	}
+
+	active, _ := k.reload()
+	for _, key := range active {
+		if key.ID == kid {
+			return key, true
+		}
+	}
+
+	return config.JWTKey{}, false
+}
+
+// sign signs claims with the newest key, naming it in the kid header.
+func (k *keyring) sign(claims jwt.MapClaims) (string, error) {
+	active := k.active()
+	key := active[len(active)-1]
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(AuthMiddleware.SigningAlgorithm), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Secret)
+}
+
+// parse verifies a client's token with the key it names, tokens without a kid
+// were signed with JWT_SECRET.
+func (k *keyring) parse(tokenString string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.GetSigningMethod(AuthMiddleware.SigningAlgorithm) {
+			return nil, errors.ErrorUnknownSigningKey
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			kid = config.DefaultKeyID
+		}
+
+		key, found := k.find(kid)
+		if !found {
+			return nil, errors.ErrorUnknownSigningKey
+		}
+
+		return key.Secret, nil
+	})
+}
+
+// internalToken signs claims with the key gin-jwt verifies with.
+func internalToken(claims jwt.MapClaims) (string, error) {
+	return jwt.NewWithClaims(jwt.GetSigningMethod(AuthMiddleware.SigningAlgorithm), claims).SignedString(AuthMiddleware.Key)
+}
+
+// SigningKeys verifies the token a client sent, in the Authorization header or
+// the auth cookie, with the signing keys and hands gin-jwt the same claims
+// signed with its key. Expired tokens are passed on too, so they can still be
+// refreshed, gin-jwt checks the expiry itself. A token that does not verify is
+// left as is for gin-jwt to reject.
+func SigningKeys() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		tokenString := strings.TrimPrefix(header, AuthMiddleware.TokenHeadName+" ")
+		if header == "" {
+			tokenString, _ = c.Cookie(AuthMiddleware.CookieName)
+		} else if tokenString == header {
+			// an API token, or a scheme gin-jwt will refuse
+			c.Next()
+			return
+		}
+
+		if tokenString == "" {
+			c.Next()
+			return
+		}
+
+		token, err := keys.parse(tokenString)
+		if err != nil {
+			validationErr, ok := err.(*jwt.ValidationError)
+			if !ok || validationErr.Errors != jwt.ValidationErrorExpired {
+				c.Next()
+				return
+			}
+		}
+
+		internal, err := internalToken(token.Claims.(jwt.MapClaims))
+		if err == nil {
+			c.Request.Header.Set("Authorization", AuthMiddleware.TokenHeadName+" "+internal)
+		}
+
+		c.Next()
+	}
+}
+
+// RotateSigningKeys reads the signing keys again, after a key has been added
+// to or removed from JWT_KEYS, so new tokens are signed with the newest key
+// right away. Tokens signed with a key that is still listed keep working.
+func RotateSigningKeys(c *gin.Context) {
+	active, err := keys.reload()
+	if err != nil {
+		tyrgin.ErrorLogger(err, "Failed to reload the signing keys.")
+		c.Set("error", errors.ErrorInvalidSigningKeys)
+		return
+	}
+
+	kids := make([]string, 0, len(active))
+	for _, key := range active {
+		kids = append(kids, key.ID)
+	}
+
+	c.JSON(200, gin.H{
+		"message":    "Signing keys reloaded.",
+		"keys":       kids,
+		"signingKey": kids[len(kids)-1],
+	})
+}
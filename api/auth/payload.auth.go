@@ -12,11 +12,37 @@ func PayloadFunc(data interface{}) jwt.MapClaims {
 	case *models.MongoUser:
 		user := data.(*models.MongoUser)
 		courses := user.CoursesAsMap()
-		return jwt.MapClaims{
-			"uid":     user.ID,
-			"courses": courses,
-			"admin":   user.Admin,
+		claims := jwt.MapClaims{
+			"uid":      user.ID,
+			"courses":  courses,
+			"admin":    user.Admin,
+			"orgAdmin": user.OrgAdmin,
+			"timezone": user.Timezone,
 		}
+		if user.OrganizationID != nil {
+			claims["org"] = user.OrganizationID.Hex()
+		}
+		return claims
+	case *tokenIdentity:
+		identity := data.(*tokenIdentity)
+		claims := PayloadFunc(identity.user)
+		claims["tokenID"] = identity.token.ID
+		claims["scopes"] = identity.token.Scopes
+		return claims
+	case *impersonation:
+		identity := data.(*impersonation)
+		claims := jwt.MapClaims{
+			"uid":          identity.student.ID,
+			"courses":      map[string]string{identity.course.Hex(): "student"},
+			"admin":        false,
+			"orgAdmin":     false,
+			"timezone":     identity.student.Timezone,
+			"impersonator": identity.staff.Hex(),
+		}
+		if identity.student.OrganizationID != nil {
+			claims["org"] = identity.student.OrganizationID.Hex()
+		}
+		return claims
 	default:
 		return jwt.MapClaims{}
 	}
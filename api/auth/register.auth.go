@@ -3,6 +3,7 @@ package auth
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/goware/emailx"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
 
 	"backend/errors"
 	"backend/forms"
@@ -24,6 +25,33 @@ func isValidEmail(email string) error {
 	return nil
 }
 
+// registrationOrganization the organization a registration joins, the one it
+// names or the one of its email's domain, nil for none.
+func registrationOrganization(form forms.UserRegisterForm) (*primitive.ObjectID, errors.APIError) {
+	if form.Organization == "" {
+		org, err := om.ForEmail(form.Email)
+		if err == errors.ErrorResourceNotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return &org.ID, nil
+	}
+
+	org, err := om.GetBySlug(form.Organization)
+	if err != nil {
+		return nil, err
+	}
+
+	if !org.Auth.AllowRegistration || !org.AllowsEmail(form.Email) {
+		return nil, errors.ErrorOrganizationRegistration
+	}
+
+	return &org.ID, nil
+}
+
 // Register a function that registers a User.
 func Register(c *gin.Context) {
 	var register forms.UserRegisterForm
@@ -33,7 +61,13 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	err = um.Register(register)
+	oid, errs := registrationOrganization(register)
+	if errs != nil {
+		c.Set("error", errs)
+		return
+	}
+
+	err = um.Register(register, oid)
 	if err != nil {
 		c.Set("error", err)
 		return
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"net/http"
+	"time"
+
+	ginjwt "github.com/appleboy/gin-jwt"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+
+	"backend/errors"
+)
+
+// freshClaims the claims of a new token, PayloadFunc's for the data, expiring
+// after the timeout.
+func freshClaims(data interface{}) (jwt.MapClaims, time.Time) {
+	claims := jwt.MapClaims{}
+	for key, val := range AuthMiddleware.PayloadFunc(data) {
+		claims[key] = val
+	}
+	expire := AuthMiddleware.TimeFunc().Add(AuthMiddleware.Timeout)
+	claims["exp"] = expire.Unix()
+	claims["orig_iat"] = AuthMiddleware.TimeFunc().Unix()
+
+	return claims, expire
+}
+
+// signToken signs a token for a client with the claims PayloadFunc gives the
+// data, using the newest signing key.
+func signToken(data interface{}) (string, time.Time, errors.APIError) {
+	claims, expire := freshClaims(data)
+	tokenString, err := keys.sign(claims)
+	if err != nil {
+		return "", expire, errors.ErrorGenerateTokenFailure
+	}
+
+	return tokenString, expire, nil
+}
+
+func setTokenCookie(c *gin.Context, tokenString string, expire time.Time) {
+	c.SetCookie(
+		AuthMiddleware.CookieName,
+		tokenString,
+		int(expire.Unix()-time.Now().Unix()),
+		"/",
+		AuthMiddleware.CookieDomain,
+		AuthMiddleware.SecureCookie,
+		AuthMiddleware.CookieHTTPOnly,
+	)
+}
+
+// Login signs a token for the user the credentials in the body belong to.
+// Replaces gin-jwt's login handler, which signs with its own key.
+func Login(c *gin.Context) {
+	data, err := AuthMiddleware.Authenticator(c)
+	if err != nil {
+		Unauthorized(c, http.StatusUnauthorized, AuthMiddleware.HTTPStatusMessageFunc(err, c))
+		return
+	}
+
+	tokenString, expire, errs := signToken(data)
+	if errs != nil {
+		Unauthorized(c, http.StatusUnauthorized, AuthMiddleware.HTTPStatusMessageFunc(ginjwt.ErrFailedTokenCreation, c))
+		return
+	}
+
+	setTokenCookie(c, tokenString, expire)
+	TokenResponse(c, http.StatusOK, tokenString, expire)
+}
+
+// RefreshToken signs a new token with the claims of one that is still within
+// the refresh window, using the newest signing key, so sessions move onto a
+// new key as they refresh.
+func RefreshToken(c *gin.Context) {
+	claims, err := AuthMiddleware.CheckIfTokenExpire(c)
+	if err != nil {
+		Unauthorized(c, http.StatusUnauthorized, AuthMiddleware.HTTPStatusMessageFunc(err, c))
+		return
+	}
+
+	if impersonating(claims) {
+		Unauthorized(c, http.StatusUnauthorized, AuthMiddleware.HTTPStatusMessageFunc(ginjwt.ErrExpiredToken, c))
+		return
+	}
+
+	refreshed := jwt.MapClaims{}
+	for key, val := range claims {
+		refreshed[key] = val
+	}
+	expire := AuthMiddleware.TimeFunc().Add(AuthMiddleware.Timeout)
+	refreshed["exp"] = expire.Unix()
+	refreshed["orig_iat"] = AuthMiddleware.TimeFunc().Unix()
+
+	tokenString, err := keys.sign(refreshed)
+	if err != nil {
+		Unauthorized(c, http.StatusUnauthorized, AuthMiddleware.HTTPStatusMessageFunc(ginjwt.ErrFailedTokenCreation, c))
+		return
+	}
+
+	setTokenCookie(c, tokenString, expire)
+	TokenResponse(c, http.StatusOK, tokenString, expire)
+}
+
+// IssueToken signs a fresh token for a user and sets it as the auth cookie.
+// Used whenever a user's course claims change mid session. Requests made with
+// an API token are not given a session, their next request picks up the
+// change anyway.
+func IssueToken(c *gin.Context, user interface{}) (string, time.Time, errors.APIError) {
+	if _, found := ginjwt.ExtractClaims(c)["tokenID"]; found {
+		return "", time.Time{}, nil
+	}
+
+	tokenString, expire, err := signToken(user)
+	if err != nil {
+		return "", expire, err
+	}
+
+	setTokenCookie(c, tokenString, expire)
+
+	return tokenString, expire, nil
+}
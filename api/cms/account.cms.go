@@ -0,0 +1,300 @@
+package cms
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/config"
+	"backend/errors"
+	"backend/models/cmsmodels/quizmodels"
+	"backend/models/usermodels"
+)
+
+func writeJSONToZip(archive *zip.Writer, name string, data interface{}) errors.APIError {
+	w, err := archive.Create(name)
+	if err != nil {
+		return errors.ErrorFailedToCreateArchive
+	}
+
+	bs, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return errors.ErrorFailedToConvertStructToJSON
+	}
+
+	if _, err = w.Write(bs); err != nil {
+		return errors.ErrorFailedToCreateArchive
+	}
+
+	return nil
+}
+
+// exportUserData builds a zip archive of every piece of personal data stored
+// about a user: their profile, enrollments, submissions with grades and
+// submitted files, quiz attempts, drafts, extension requests, notifications
+// and discussion posts.
+func exportUserData(uid primitive.ObjectID) (*bytes.Buffer, errors.APIError) {
+	user, err := um.FindOneById(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	submissions, err := sm.GetUsersSubmissions(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	notifications, err := nm.GetUsersNotifications(uid, false, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	threads, err := dm.GetPostsByAuthor(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts, err := qm.UserAttempts(uid)
+	if err != nil {
+		return nil, err
+	}
+	quizAttempts := make([]quizmodels.AttemptView, len(attempts))
+	for index, attempt := range attempts {
+		quizAttempts[index] = attempt.View()
+	}
+
+	drafts, err := dfm.UserDrafts(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	extensionRequests, err := exm.UserRequests(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	archive := zip.NewWriter(buf)
+
+	profile := gin.H{
+		"id":                   user.ID,
+		"email":                user.Email,
+		"firstName":            user.First,
+		"lastName":             user.Last,
+		"admin":                user.Admin,
+		"timezone":             user.Timezone,
+		"digest":               user.Digest,
+		"deletionScheduledFor": user.DeletionScheduledFor,
+	}
+	files := map[string]interface{}{
+		"profile.json":          profile,
+		"enrollments.json":      user.EnrolledCourses,
+		"submissions.json":      submissions,
+		"notifications.json":    notifications,
+		"discussion_posts.json": threads,
+		"quiz_attempts.json":    quizAttempts,
+		"drafts.json":           drafts,
+		"extensions.json":       extensionRequests,
+	}
+	for name, data := range files {
+		if err = writeJSONToZip(archive, name, data); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, submission := range submissions {
+		file, _, err := gfs.Download(submission.FileID)
+		if err != nil {
+			// the file may already have been removed with its assignment
+			continue
+		}
+
+		w, errs := archive.Create(fmt.Sprintf("submissions/%s/%s", submission.ID.Hex(), submission.File))
+		if errs != nil {
+			return nil, errors.ErrorFailedToCreateArchive
+		}
+
+		if _, errs = io.Copy(w, file); errs != nil {
+			return nil, errors.ErrorFailedToCreateArchive
+		}
+
+		for _, artifact := range submission.Artifacts {
+			file, _, err := gfs.Download(artifact.FileID)
+			if err != nil {
+				continue
+			}
+
+			w, errs := archive.Create(fmt.Sprintf("submissions/%s/%s/%s", submission.ID.Hex(), artifact.Slot, artifact.Filename))
+			if errs != nil {
+				return nil, errors.ErrorFailedToCreateArchive
+			}
+
+			if _, errs = io.Copy(w, file); errs != nil {
+				return nil, errors.ErrorFailedToCreateArchive
+			}
+		}
+	}
+
+	if errs := archive.Close(); errs != nil {
+		return nil, errors.ErrorFailedToCreateArchive
+	}
+
+	return buf, nil
+}
+
+func sendUserExport(c *gin.Context, uid primitive.ObjectID) {
+	buf, err := exportUserData(uid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	additonalHeaders := map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="user-%s-export.zip"`, uid.Hex()),
+	}
+
+	c.DataFromReader(200, int64(buf.Len()), "application/zip", buf, additonalHeaders)
+}
+
+// ExportMyData downloads the logged in user's personal data as a zip.
+func ExportMyData(c *gin.Context) {
+	uid, _ := c.Get("uid")
+	sendUserExport(c, uid.(primitive.ObjectID))
+}
+
+// ExportUserData lets an admin download a user's personal data as a zip.
+func ExportUserData(c *gin.Context) {
+	suid, _ := c.Get("suid")
+	sendUserExport(c, suid.(primitive.ObjectID))
+}
+
+func scheduleDeletion(c *gin.Context, uid interface{}) {
+	purgeAfter := time.Now().Add(config.C.AccountDeletionGrace)
+
+	err := um.RequestDeletion(uid, purgeAfter)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":              "Account Deletion Scheduled.",
+		"deletionScheduledFor": purgeAfter,
+	})
+}
+
+// RequestAccountDeletion schedules the logged in user's account for deletion
+// once the grace period passes.
+func RequestAccountDeletion(c *gin.Context) {
+	uid, _ := c.Get("uid")
+	scheduleDeletion(c, uid)
+}
+
+// DeleteUser lets an admin schedule a user's account for deletion.
+func DeleteUser(c *gin.Context) {
+	suid, _ := c.Get("suid")
+	scheduleDeletion(c, suid)
+}
+
+// CancelAccountDeletion keeps the logged in user's account if it is still in
+// its grace period.
+func CancelAccountDeletion(c *gin.Context) {
+	uid, _ := c.Get("uid")
+
+	err := um.CancelDeletion(uid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Account Deletion Cancelled.",
+	})
+}
+
+// purgeAccount anonymizes a user. Their submissions and grades are kept, under
+// the anonymized account, so course statistics are unaffected, but submitted
+// files, extension requests, notifications and their name on discussion posts
+// are removed.
+func purgeAccount(user usermodels.MongoUser) errors.APIError {
+	submissions, err := sm.GetUsersSubmissions(user.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, submission := range submissions {
+		gfs.Delete(submission.FileID)
+		for _, fid := range submission.OutputFileIDs() {
+			gfs.Delete(fid)
+		}
+		for _, fid := range submission.ArtifactFileIDs() {
+			gfs.Delete(fid)
+		}
+	}
+
+	if err = cdm.DeleteByUserID(user.ID); err != nil {
+		return err
+	}
+
+	if err = imm.DeleteByUserID(user.ID); err != nil {
+		return err
+	}
+
+	if err = tm.RevokeByUser(user.ID); err != nil {
+		return err
+	}
+
+	if err = nm.DeleteByUserID(user.ID); err != nil {
+		return err
+	}
+
+	if err = qm.DeleteAttemptsByUserID(user.ID); err != nil {
+		return err
+	}
+
+	if err = dfm.DeleteByUserID(user.ID); err != nil {
+		return err
+	}
+
+	if err = regm.RemoveUser(user.ID); err != nil {
+		return err
+	}
+
+	if err = exm.DeleteByUserID(user.ID); err != nil {
+		return err
+	}
+
+	if err = dm.AnonymizeAuthor(user.ID, "Deleted User"); err != nil {
+		return err
+	}
+
+	if err = dsm.AnonymizeAuthor(user.ID, "Deleted User"); err != nil {
+		return err
+	}
+
+	return um.Anonymize(user.ID)
+}
+
+// PurgeDeletedAccounts is a job that anonymizes accounts whose deletion grace
+// period has passed.
+func PurgeDeletedAccounts([]byte) error {
+	users, err := um.DueForPurge()
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if err = purgeAccount(user); err != nil {
+			tyrgin.ErrorLogger(err, "Failed to purge account "+user.ID.Hex())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package cms
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/forms"
+	"backend/models/cmsmodels/announcementmodels"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+// notifyAnnouncement fans a published announcement out to the course's
+// students and assistants.
+func notifyAnnouncement(announcement announcementmodels.MongoAnnouncement) errors.APIError {
+	course, err := cm.GetByID(announcement.CourseID)
+	if err != nil {
+		return err
+	}
+
+	recipients := make([]primitive.ObjectID, 0)
+	for _, uid := range append(course.Students, course.Assistants...) {
+		if uid != announcement.AuthorID {
+			recipients = append(recipients, uid)
+		}
+	}
+
+	err = nm.Notify(
+		recipients,
+		announcement.CourseID,
+		"announcement",
+		fmt.Sprintf("%s %d: %s", course.Department, course.Number, announcement.Title),
+		fmt.Sprintf("/course/%s/announcements", announcement.CourseID.Hex()),
+	)
+	if err != nil {
+		return err
+	}
+
+	return anm.MarkNotified(announcement.ID)
+}
+
+// NotifyScheduledAnnouncements is a job that sends notifications for scheduled
+// announcements whose publish date has passed.
+func NotifyScheduledAnnouncements([]byte) error {
+	announcements, err := anm.DueForNotification()
+	if err != nil {
+		return err
+	}
+
+	for _, announcement := range announcements {
+		if err = notifyAnnouncement(announcement); err != nil {
+			tyrgin.ErrorLogger(err, "Failed to notify announcement "+announcement.ID.Hex())
+		}
+	}
+
+	return nil
+}
+
+// CourseAnnouncements lists a course's announcements, pinned first.
+func CourseAnnouncements(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	role, _ := c.Get("role")
+
+	announcements, err := anm.GetByCourse(cid, role.(string))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":       "Course announcements.",
+		"announcements": announcements,
+	})
+}
+
+func CreateAnnouncement(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	uid, _ := c.Get("uid")
+
+	var create forms.CreateAnnouncementForm
+	if err := c.ShouldBindJSON(&create); err != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	announcement, err := anm.Create(cid, uid, create)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if announcement.Published() {
+		if err = notifyAnnouncement(*announcement); err != nil {
+			tyrgin.ErrorLogger(err, "Failed to notify announcement "+announcement.ID.Hex())
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"message":      "Announcement Created.",
+		"announcement": announcement,
+	})
+}
+
+func UpdateAnnouncement(c *gin.Context) {
+	anid, _ := c.Get("anid")
+	cid, _ := c.Get("cid")
+
+	announcement, err := anm.Get(anid, cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	var up forms.UpdateAnnouncementForm
+	if errs := c.ShouldBindJSON(&up); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	if up.Title != nil {
+		announcement.Title = *up.Title
+	}
+	if up.Body != nil {
+		announcement.Body = *up.Body
+	}
+	if up.Pinned != nil {
+		announcement.Pinned = *up.Pinned
+	}
+	if up.PublishDate != nil {
+		announcement.PublishDate = *up.PublishDate
+	}
+
+	err = anm.Update(*announcement)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Announcement Updated.",
+	})
+}
+
+func DeleteAnnouncement(c *gin.Context) {
+	anid, _ := c.Get("anid")
+	cid, _ := c.Get("cid")
+
+	announcement, err := anm.Get(anid, cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = anm.Delete(announcement.ID)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = mdm.RemoveAnnouncement(announcement.ID)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Announcement Deleted.",
+	})
+}
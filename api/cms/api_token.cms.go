@@ -0,0 +1,91 @@
+package cms
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/errors"
+	"backend/forms"
+	"backend/models/tokenmodels"
+	"backend/utils"
+)
+
+// APITokens lists the logged in user's API tokens.
+func APITokens(c *gin.Context) {
+	uid, _ := c.Get("uid")
+
+	tokens, err := tm.GetByUser(uid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "API Tokens.",
+		"tokens":  tokens,
+	})
+}
+
+// CreateAPIToken generates an API token for the logged in user. The token is
+// only shown in this response. Its rate limit, in requests per minute, cannot
+// exceed API_TOKEN_RATE_LIMIT.
+func CreateAPIToken(c *gin.Context) {
+	uid, _ := c.Get("uid")
+
+	var form forms.CreateAPITokenForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	if len(form.Scopes) == 0 {
+		c.Set("error", errors.ErrorInvalidTokenScope)
+		return
+	}
+	for _, scope := range form.Scopes {
+		if !tokenmodels.ValidScope(scope) {
+			c.Set("error", errors.ErrorInvalidTokenScope)
+			return
+		}
+	}
+
+	if form.ExpiresAt != nil && utils.DateTimeToTime(*form.ExpiresAt).Before(time.Now()) {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	rateLimit := tokenmodels.DefaultRateLimit()
+	if form.RateLimit > 0 && form.RateLimit < rateLimit {
+		rateLimit = form.RateLimit
+	}
+
+	token, apiToken, err := tm.Create(uid, form.Name, form.Scopes, rateLimit, form.ExpiresAt)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(201, gin.H{
+		"status_code": 201,
+		"message":     "API Token Created.",
+		"token":       token,
+		"apiToken":    apiToken,
+	})
+}
+
+// RevokeAPIToken disables one of the logged in user's API tokens.
+func RevokeAPIToken(c *gin.Context) {
+	uid, _ := c.Get("uid")
+	tkid, _ := c.Get("tkid")
+
+	err := tm.Revoke(uid, tkid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "API Token Revoked.",
+	})
+}
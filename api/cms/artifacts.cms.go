@@ -0,0 +1,145 @@
+package cms
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/models/cmsmodels/submissionmodels"
+)
+
+// artifactUpload a file a student uploaded to one of the assignment's slots,
+// read but not yet stored.
+type artifactUpload struct {
+	slot     string
+	filename string
+	content  []byte
+}
+
+// readArtifacts reads the files of the assignment's upload slots from the
+// form fields artifact.<slot>, refusing submissions missing a required one
+// or with a file of a type its slot does not accept.
+func readArtifacts(c *gin.Context, assign *assignmentmodels.MongoAssignment) ([]artifactUpload, errors.APIError) {
+	uploads := make([]artifactUpload, 0, len(assign.ArtifactSlots))
+	for _, slot := range assign.ArtifactSlots {
+		header, errs := c.FormFile("artifact." + slot.Name)
+		if errs != nil {
+			if slot.Required {
+				c.Set("errorDetails", gin.H{"slot": slot.Name})
+				return nil, errors.ErrorMissingArtifact
+			}
+			continue
+		}
+
+		if !slot.Accepts(header.Filename) {
+			c.Set("errorDetails", gin.H{"slot": slot.Name, "extensions": slot.Extensions})
+			return nil, errors.ErrorUnacceptedArtifact
+		}
+
+		file, errs := header.Open()
+		if errs != nil {
+			return nil, errors.ErrorFailedToOpenFile
+		}
+		content, errs := ioutil.ReadAll(file)
+		file.Close()
+		if errs != nil {
+			return nil, errors.ErrorFailedToReadFile
+		}
+
+		uploads = append(uploads, artifactUpload{slot.Name, header.Filename, content})
+	}
+
+	return uploads, nil
+}
+
+// artifactBytes the size of the uploads, counted against the course quota
+// with the code.
+func artifactBytes(uploads []artifactUpload) int64 {
+	var size int64
+	for _, upload := range uploads {
+		size += int64(len(upload.content))
+	}
+
+	return size
+}
+
+// storeArtifacts uploads the artifacts of a submission to gridfs, removing
+// the ones stored already when one fails.
+func storeArtifacts(sid primitive.ObjectID, uploads []artifactUpload) ([]submissionmodels.Artifact, errors.APIError) {
+	artifacts := make([]submissionmodels.Artifact, 0, len(uploads))
+	for _, upload := range uploads {
+		fid := primitive.NewObjectID()
+		name := fmt.Sprintf("artifact-%s-%s-%s", sid.Hex(), upload.slot, upload.filename)
+		if err := gfs.Upload(&fid, name, bytes.NewReader(upload.content)); err != nil {
+			deleteArtifacts(artifacts)
+			return nil, err
+		}
+
+		artifacts = append(artifacts, submissionmodels.Artifact{
+			Slot:        upload.slot,
+			FileID:      fid,
+			Filename:    upload.filename,
+			ContentType: http.DetectContentType(upload.content),
+			Size:        int64(len(upload.content)),
+		})
+	}
+
+	return artifacts, nil
+}
+
+func deleteArtifacts(artifacts []submissionmodels.Artifact) {
+	for _, artifact := range artifacts {
+		gfs.Delete(artifact.FileID)
+	}
+}
+
+// SubmissionArtifact serves the file a submission has in an upload slot, to
+// staff and the student who submitted it.
+func SubmissionArtifact(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	sid, _ := c.Get("sid")
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	submission, err := sm.Get(sid, role.(string))
+	if err != nil || submission.AssignmentID != aid {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	if role == "student" && submission.UserID != uid {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	artifact := submission.Artifact(c.Param("slot"))
+	if artifact == nil {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	file, numBytes, err := gfs.Download(artifact.FileID)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	additonalHeaders := map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": artifact.Filename}),
+	}
+
+	c.DataFromReader(200, numBytes, artifact.ContentType, file, additonalHeaders)
+}
@@ -0,0 +1,159 @@
+package cms
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/utils"
+)
+
+// checkCourseAssignment errors unless the assignment belongs to the course, so
+// routes of one course cannot reach another's assignments by their id.
+func checkCourseAssignment(cid, aid interface{}) errors.APIError {
+	course, err := cm.FindByAssignment(aid)
+	if err != nil {
+		return err
+	}
+
+	if course.ID != cid.(primitive.ObjectID) {
+		return errors.ErrorResourceNotFound
+	}
+
+	return nil
+}
+
+// UploadAttachment stores an image or pdf for an assignment and returns the url
+// it can be embedded with in the assignment description.
+func UploadAttachment(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	af, errs := c.FormFile("attachment")
+	if errs != nil {
+		c.Set("error", errors.ErrorUploadingFile)
+		return
+	}
+
+	content, contentType, err := utils.CheckAttachmentType(af)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	fid := primitive.NewObjectID()
+	err = gfs.Upload(&fid, af.Filename, bytes.NewReader(content))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	attachment := assignmentmodels.Attachment{
+		ID:          fid,
+		Filename:    af.Filename,
+		ContentType: contentType,
+		Size:        int64(len(content)),
+		UploadDate:  primitive.DateTime(time.Now().UnixNano() / 1000000),
+	}
+
+	err = am.AddAttachment(aid, attachment)
+	if err != nil {
+		gfs.Delete(fid)
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(201, gin.H{
+		"status_code": 201,
+		"message":     "Attachment Uploaded.",
+		"attachment":  attachment,
+		"url": fmt.Sprintf(
+			"/api/v1/plague_doctor/course/%s/assignment/%s/attachment/%s",
+			c.Param("cid"),
+			c.Param("aid"),
+			fid.Hex(),
+		),
+	})
+}
+
+// GetAttachment serves an assignment attachment to users enrolled in the course.
+func GetAttachment(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	fid, _ := c.Get("fid")
+	role, _ := c.Get("role")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assign, attachment, err := am.GetAttachment(aid, fid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if role == "student" && !assign.Published {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	file, numBytes, err := gfs.Download(attachment.ID)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	additonalHeaders := map[string]string{
+		"Content-Disposition": mime.FormatMediaType("inline", map[string]string{"filename": attachment.Filename}),
+		"Cache-Control":       "private, max-age=86400",
+	}
+
+	c.DataFromReader(200, numBytes, attachment.ContentType, file, additonalHeaders)
+}
+
+// DeleteAttachment removes an attachment from an assignment.
+func DeleteAttachment(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	fid, _ := c.Get("fid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	_, attachment, err := am.GetAttachment(aid, fid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = am.RemoveAttachment(aid, attachment.ID)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = gfs.Delete(attachment.ID)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Attachment Deleted.",
+	})
+}
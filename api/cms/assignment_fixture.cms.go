@@ -0,0 +1,209 @@
+package cms
+
+import (
+	"mime"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/config"
+	"backend/errors"
+	"backend/models/cmsmodels/assignmentmodels"
+)
+
+// findFixture finds one of an assignment's fixtures by id.
+func findFixture(aid, fid interface{}) (*assignmentmodels.MongoAssignment, *assignmentmodels.Fixture, errors.APIError) {
+	assign, err := am.Get(aid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for index := range assign.Fixtures {
+		if assign.Fixtures[index].ID == fid {
+			return assign, &assign.Fixtures[index], nil
+		}
+	}
+
+	return nil, nil, errors.ErrorResourceNotFound
+}
+
+// UploadFixture stores a file tests can use as their input, expected output or
+// as a file in their working directory. Uploading a fixture with the name of
+// an existing one replaces it.
+func UploadFixture(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	ff, errs := c.FormFile("fixture")
+	if errs != nil {
+		c.Set("error", errors.ErrorUploadingFile)
+		return
+	}
+
+	assign, err := am.Get(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+	previous := assign.Fixture(ff.Filename)
+
+	file, errs := ff.Open()
+	if errs != nil {
+		c.Set("error", errors.ErrorUploadingFile)
+		return
+	}
+	defer file.Close()
+
+	fid := primitive.NewObjectID()
+	err = gfs.Upload(&fid, ff.Filename, file)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	fixture := assignmentmodels.Fixture{
+		ID:         fid,
+		Filename:   ff.Filename,
+		Size:       ff.Size,
+		UploadDate: primitive.DateTime(time.Now().UnixNano() / 1000000),
+	}
+
+	err = am.AddFixture(aid, fixture)
+	if err != nil {
+		gfs.Delete(fid)
+		c.Set("error", err)
+		return
+	}
+
+	if previous != nil {
+		am.RemoveFixture(aid, previous.ID)
+		gfs.Delete(previous.ID)
+	}
+
+	c.JSON(201, gin.H{
+		"status_code": 201,
+		"message":     "Fixture Uploaded.",
+		"fixture":     fixture,
+	})
+}
+
+// GetFixtures lists an assignment's fixtures.
+func GetFixtures(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assign, err := am.Get(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":  "Assignment fixtures.",
+		"fixtures": assign.Fixtures,
+	})
+}
+
+// GetFixture downloads one of an assignment's fixtures.
+func GetFixture(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	fid, _ := c.Get("fid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	_, fixture, err := findFixture(aid, fid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	serveFixture(c, fixture)
+}
+
+// JobDownloadFixture serves a fixture to court herald.
+func JobDownloadFixture(c *gin.Context) {
+	key := c.Param("secret")
+	if key != config.C.JobSecret {
+		c.Set("error", errors.ErrorInvalidJobSecret)
+		return
+	}
+
+	aid, _ := c.Get("aid")
+	fid, _ := c.Get("fid")
+
+	_, fixture, err := findFixture(aid, fid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	serveFixture(c, fixture)
+}
+
+func serveFixture(c *gin.Context, fixture *assignmentmodels.Fixture) {
+	file, numBytes, err := gfs.Download(fixture.ID)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	additonalHeaders := map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": fixture.Filename}),
+	}
+
+	c.DataFromReader(200, numBytes, "application/octet-stream", file, additonalHeaders)
+}
+
+// DeleteFixture removes a fixture no test uses from an assignment.
+func DeleteFixture(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	fid, _ := c.Get("fid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assign, fixture, err := findFixture(aid, fid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if assign.UsesFixture(fixture.Filename) {
+		c.Set("error", errors.ErrorFixtureInUse)
+		return
+	}
+
+	err = am.RemoveFixture(aid, fixture.ID)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = gfs.Delete(fixture.ID)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Fixture Deleted.",
+	})
+}
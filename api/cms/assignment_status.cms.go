@@ -0,0 +1,109 @@
+package cms
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/utils"
+)
+
+// States of an assignment's submission window.
+const (
+	windowOpen       = "open"
+	windowNotStarted = "notStarted"
+	windowClosed     = "closed"
+)
+
+// inFlightSubmission the submission of the student's that is being graded.
+type inFlightSubmission struct {
+	SubmissionID         primitive.ObjectID `json:"submissionID"`
+	AttemptNumber        int                `json:"attemptNumber"`
+	Queued               bool               `json:"queued"`
+	SubmittedAt          primitive.DateTime `json:"submittedAt"`
+	EstimatedWaitSeconds int                `json:"estimatedWaitSeconds"`
+}
+
+// AssignmentSubmissionStatus tells a student where they stand on an
+// assignment: the attempts they used and have left, whether they can submit
+// now, how long until the due date and their timed window close, and the
+// submission being graded, if any.
+func AssignmentSubmissionStatus(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	uid, _ := c.Get("uid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assign, attempts, err := am.LatestUserSubmission(aid, uid)
+	if err != nil || !assign.Published {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	now := time.Now()
+	due := assign.DueDateFor(uid.(primitive.ObjectID))
+	dueDate := utils.DateTimeToTime(due)
+	left := assign.NumAttempts - attempts
+	if left < 0 {
+		left = 0
+	}
+
+	status := gin.H{
+		"state":     windowOpen,
+		"timed":     assign.Timed(),
+		"timeLimit": assign.TimeLimit,
+	}
+	window := assign.Window(uid.(primitive.ObjectID))
+	switch {
+	case assign.Timed() && window == nil:
+		status["state"] = windowNotStarted
+	case assign.Timed() && now.After(window.EndsAt):
+		status["state"] = windowClosed
+		status["startedAt"], status["endsAt"] = window.StartedAt, window.EndsAt
+	case assign.Timed():
+		status["startedAt"], status["endsAt"] = window.StartedAt, window.EndsAt
+		status["secondsLeft"] = int(window.EndsAt.Sub(now) / time.Second)
+	}
+
+	secondsUntilDue := int(dueDate.Sub(now) / time.Second)
+	if secondsUntilDue < 0 {
+		secondsUntilDue = 0
+	}
+
+	var inFlight *inFlightSubmission
+	submission, err := sm.InFlight(aid, uid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+	if submission != nil {
+		inFlight = &inFlightSubmission{
+			SubmissionID:         submission.ID,
+			AttemptNumber:        submission.AttemptNumber,
+			Queued:               submission.Queued,
+			SubmittedAt:          submission.SubmissionDate,
+			EstimatedWaitSeconds: estimatedWait(submission),
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"message":             "Assignment status.",
+		"attemptsUsed":        attempts,
+		"attemptsLeft":        left,
+		"numAttempts":         assign.NumAttempts,
+		"canSubmit":           left > 0 && status["state"] == windowOpen,
+		"window":              status,
+		"dueDate":             due,
+		"dueDateLocal":        utils.Localize(due, userLocation(c)),
+		"pastDue":             now.After(dueDate),
+		"secondsUntilDue":     secondsUntilDue,
+		"attestationRequired": assign.Attestation != "",
+		"grading":             inFlight,
+	})
+}
@@ -0,0 +1,289 @@
+package cms
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/features"
+	"backend/forms"
+	"backend/models/cmsmodels/attendancemodels"
+	"backend/utils"
+)
+
+// studentSession what a student sees of an attendance session.
+type studentSession struct {
+	ID        primitive.ObjectID `json:"id"`
+	Title     string             `json:"title"`
+	CreatedAt primitive.DateTime `json:"createdAt"`
+	ClosesAt  primitive.DateTime `json:"closesAt"`
+	Open      bool               `json:"open"`
+	Present   bool               `json:"present"`
+}
+
+// attendanceRate the percentage of sessions a student attended, nil when the
+// course has no sessions.
+func attendanceRate(sessions []attendancemodels.MongoSession, uid primitive.ObjectID) *float64 {
+	if len(sessions) == 0 {
+		return nil
+	}
+
+	attended := 0
+	for _, session := range sessions {
+		if session.Attended(uid) {
+			attended++
+		}
+	}
+
+	rate := 100 * float64(attended) / float64(len(sessions))
+	return &rate
+}
+
+// CreateAttendanceSession opens a session students can check in to.
+func CreateAttendanceSession(c *gin.Context) {
+	if !featureEnabled(c, features.Attendance) {
+		return
+	}
+
+	cid, _ := c.Get("cid")
+	uid, _ := c.Get("uid")
+
+	var form forms.CreateAttendanceForm
+	if errs := c.ShouldBindJSON(&form); errs != nil || form.Minutes < 0 || form.RotateSeconds < 0 {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	session, err := atm.Create(cid.(primitive.ObjectID), uid.(primitive.ObjectID), form.Title, form.Minutes, form.RotateSeconds)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(201, gin.H{
+		"status_code": 201,
+		"message":     "Attendance Session Created.",
+		"session":     session,
+		"code":        session.Code(time.Now()),
+	})
+}
+
+// CourseAttendance lists a course's sessions. Students see whether they were
+// present at each and their attendance rate.
+func CourseAttendance(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	sessions, err := atm.FindByCourse(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if role != "student" {
+		c.JSON(200, gin.H{
+			"message":  "Course attendance.",
+			"sessions": sessions,
+		})
+		return
+	}
+
+	now := time.Now()
+	own := make([]studentSession, len(sessions))
+	for index, session := range sessions {
+		own[index] = studentSession{
+			session.ID,
+			session.Title,
+			session.CreatedAt,
+			session.ClosesAt,
+			session.Open(now),
+			session.Attended(uid.(primitive.ObjectID)),
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"message":    "Course attendance.",
+		"sessions":   own,
+		"attendance": attendanceRate(sessions, uid.(primitive.ObjectID)),
+	})
+}
+
+// GetAttendanceSession shows a session with its current code, for staff to
+// display in class.
+func GetAttendanceSession(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	atid, _ := c.Get("atid")
+
+	session, err := atm.Get(atid, cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	now := time.Now()
+	response := gin.H{
+		"message": "Attendance session.",
+		"session": session,
+		"open":    session.Open(now),
+	}
+	if session.Open(now) {
+		response["code"] = session.Code(now)
+	}
+
+	c.JSON(200, response)
+}
+
+// CheckInAttendance records the student as present if they give the
+// session's current code.
+func CheckInAttendance(c *gin.Context) {
+	if !featureEnabled(c, features.Attendance) {
+		return
+	}
+
+	cid, _ := c.Get("cid")
+	uid, _ := c.Get("uid")
+	atid, _ := c.Get("atid")
+
+	var form forms.CheckInForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	session, err := atm.Get(atid, cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	now := time.Now()
+	if !session.Open(now) {
+		c.Set("error", errors.ErrorAttendanceClosed)
+		return
+	}
+
+	if !session.ValidCode(strings.ToUpper(strings.TrimSpace(form.Code)), now) {
+		c.Set("error", errors.ErrorInvalidCheckInCode)
+		return
+	}
+
+	err = atm.AddCheckIn(atid, attendancemodels.CheckIn{
+		UserID:    uid.(primitive.ObjectID),
+		CheckedAt: utils.TimeToDateTime(now),
+	})
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Checked In.",
+	})
+}
+
+// MarkAttendance lets staff mark a student present, or absent with
+// present=false, after the fact.
+func MarkAttendance(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	uid, _ := c.Get("uid")
+	atid, _ := c.Get("atid")
+	suid, _ := c.Get("suid")
+
+	_, err := atm.Get(atid, cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if c.Query("present") == "false" {
+		err = atm.RemoveCheckIn(atid, suid)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"message": "Student Marked Absent.",
+		})
+		return
+	}
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	enrolled := false
+	for _, student := range course.Students {
+		if student == suid {
+			enrolled = true
+		}
+	}
+	if !enrolled {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	marker := uid.(primitive.ObjectID)
+	err = atm.AddCheckIn(atid, attendancemodels.CheckIn{
+		UserID:    suid.(primitive.ObjectID),
+		CheckedAt: utils.TimeToDateTime(time.Now()),
+		MarkedBy:  &marker,
+	})
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Student Marked Present.",
+	})
+}
+
+// CloseAttendanceSession stops students checking in.
+func CloseAttendanceSession(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	atid, _ := c.Get("atid")
+
+	_, err := atm.Get(atid, cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = atm.Close(atid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Attendance Session Closed.",
+	})
+}
+
+func DeleteAttendanceSession(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	atid, _ := c.Get("atid")
+
+	_, err := atm.Get(atid, cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = atm.Delete(atid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Attendance Session Deleted.",
+	})
+}
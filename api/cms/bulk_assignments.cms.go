@@ -0,0 +1,159 @@
+package cms
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/forms"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/utils"
+)
+
+// Operations of a bulk assignment update.
+const (
+	bulkShiftDueDates = "shiftDueDates"
+	bulkPublish       = "publish"
+	bulkUnpublish     = "unpublish"
+	bulkSetLimits     = "setLimits"
+)
+
+// bulkResult what a bulk operation does, or would have done, to one
+// assignment.
+type bulkResult struct {
+	AssignmentID primitive.ObjectID `json:"assignmentID"`
+	Name         string             `json:"name,omitempty"`
+	// Status is ok, notFound, invalid or notReady.
+	Status      string             `json:"status"`
+	DueDate     primitive.DateTime `json:"dueDate,omitempty"`
+	Published   bool               `json:"published"`
+	NumAttempts int                `json:"numAttempts"`
+	TimeLimit   int                `json:"timeLimit"`
+	// Blockers why an assignment cannot be published.
+	Blockers []assignmentmodels.ReadinessIssue `json:"blockers,omitempty"`
+}
+
+// applyBulkOperation changes an assignment as the operation says, false when
+// the result is not a valid assignment.
+func applyBulkOperation(assign *assignmentmodels.MongoAssignment, form forms.BulkAssignmentsForm) bool {
+	switch form.Operation {
+	case bulkShiftDueDates:
+		due := utils.DateTimeToTime(assign.DueDate).AddDate(0, 0, form.Days)
+		assign.DueDate = utils.TimeToDateTime(due)
+	case bulkPublish:
+		assign.Published = true
+	case bulkUnpublish:
+		assign.Published = false
+	case bulkSetLimits:
+		if form.NumAttempts != nil {
+			assign.NumAttempts = *form.NumAttempts
+		}
+		if form.TimeLimit != nil {
+			assign.TimeLimit = *form.TimeLimit
+		}
+	}
+
+	return assign.NumAttempts > 0 && assign.TimeLimit >= 0
+}
+
+// BulkUpdateAssignments applies one operation to many assignments of a course
+// at once: shifting their due dates by a number of days, e.g. for a snow day,
+// publishing or unpublishing them, or setting their attempts and time limit.
+// Either every assignment is changed or none is, the report says what
+// happened to each, or what was wrong with them.
+func BulkUpdateAssignments(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	var form forms.BulkAssignmentsForm
+	if errs := c.ShouldBindJSON(&form); errs != nil || len(form.AssignmentIDs) == 0 {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	switch form.Operation {
+	case bulkShiftDueDates:
+		if form.Days == 0 {
+			c.Set("error", errors.ErrorInvalidJSON)
+			return
+		}
+	case bulkPublish, bulkUnpublish:
+	case bulkSetLimits:
+		if form.NumAttempts == nil && form.TimeLimit == nil {
+			c.Set("error", errors.ErrorInvalidJSON)
+			return
+		}
+	default:
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	inCourse := make(map[primitive.ObjectID]bool)
+	for _, aid := range course.Assignments {
+		inCourse[aid] = true
+	}
+
+	valid := true
+	results := make([]bulkResult, 0, len(form.AssignmentIDs))
+	assigns := make([]assignmentmodels.MongoAssignment, 0, len(form.AssignmentIDs))
+	for _, aid := range form.AssignmentIDs {
+		result := bulkResult{AssignmentID: aid, Status: "ok"}
+
+		var assign *assignmentmodels.MongoAssignment
+		if inCourse[aid] {
+			assign, err = am.Get(aid)
+		}
+		if !inCourse[aid] || err != nil {
+			result.Status = "notFound"
+			results = append(results, result)
+			valid = false
+			continue
+		}
+
+		wasPublished := assign.Published
+		if !applyBulkOperation(assign, form) {
+			result.Status = "invalid"
+			valid = false
+		} else if assign.Published && !wasPublished {
+			if readiness := assign.Readiness(time.Now()); !readiness.Ready {
+				result.Status = "notReady"
+				result.Blockers = readiness.Blockers
+				valid = false
+			}
+		}
+
+		result.Name = assign.Name
+		result.DueDate = assign.DueDate
+		result.Published = assign.Published
+		result.NumAttempts = assign.NumAttempts
+		result.TimeLimit = assign.TimeLimit
+		results = append(results, result)
+		assigns = append(assigns, *assign)
+	}
+
+	if !valid {
+		c.Set("errorDetails", results)
+		c.Set("error", errors.ErrorBulkOperationFailed)
+		return
+	}
+
+	err = am.UpdateMany(assigns)
+	if err != nil {
+		c.Set("errorDetails", results)
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":   "Assignments Updated.",
+		"operation": form.Operation,
+		"results":   results,
+	})
+}
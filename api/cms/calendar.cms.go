@@ -0,0 +1,152 @@
+package cms
+
+import (
+	"fmt"
+	"time"
+
+	jwt "github.com/appleboy/gin-jwt"
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/forms"
+	"backend/models/cmsmodels/coursemodels"
+	"backend/utils"
+)
+
+// courseEvents a course's due dates, exam windows and office hours. Students
+// only see published assignments and their own exam windows.
+func courseEvents(course *coursemodels.MongoCourse, role string, uid primitive.ObjectID) []utils.CalendarEvent {
+	name := fmt.Sprintf("%s %d", course.Department, course.Number)
+	events := make([]utils.CalendarEvent, 0)
+
+	for _, aid := range course.Assignments {
+		assign, err := am.Get(aid)
+		if err != nil || (role == "student" && !assign.Published) {
+			continue
+		}
+
+		due := utils.DateTimeToTime(assign.DueDate)
+		if !assign.Timed() {
+			events = append(events, utils.CalendarEvent{
+				UID:     assign.ID.Hex() + "-due@plague-doctor",
+				Summary: fmt.Sprintf("%s: %s due", name, assign.Name),
+				Start:   due,
+				End:     due,
+			})
+			continue
+		}
+
+		// a timed assignment is shown as the student's own window once they
+		// start it, before then as the last window that ends by the due date
+		event := utils.CalendarEvent{
+			UID:         assign.ID.Hex() + "-exam@plague-doctor",
+			Summary:     fmt.Sprintf("%s: %s exam", name, assign.Name),
+			Description: fmt.Sprintf("Timed, %d minutes once started. Start by the due date.", assign.TimeLimit+assign.ExtraMinutes(uid)),
+			Start:       due.Add(-time.Duration(assign.TimeLimit+assign.ExtraMinutes(uid)) * time.Minute),
+			End:         due,
+		}
+		if window := assign.Window(uid); role == "student" && window != nil {
+			event.Description = "Your exam window."
+			event.Start, event.End = window.StartedAt, window.EndsAt
+		}
+		events = append(events, event)
+	}
+
+	var until time.Time
+	if course.EndDate != 0 {
+		until = utils.DateTimeToTime(course.EndDate)
+	}
+	for _, hour := range course.OfficeHours {
+		start, end := hour.FirstOccurrence()
+		summary := fmt.Sprintf("%s office hours", name)
+		if hour.Host != "" {
+			summary = fmt.Sprintf("%s office hours (%s)", name, hour.Host)
+		}
+
+		events = append(events, utils.CalendarEvent{
+			UID:      hour.ID.Hex() + "-officehours@plague-doctor",
+			Summary:  summary,
+			Location: hour.Location,
+			Start:    start,
+			End:      end,
+			Weekly:   true,
+			Until:    until,
+			TimeZone: utils.LoadLocation(hour.Timezone),
+		})
+	}
+
+	return events
+}
+
+func sendCalendar(c *gin.Context, name string, events []utils.CalendarEvent) {
+	c.Data(200, "text/calendar; charset=utf-8", utils.ICalendar(name, events))
+}
+
+// CourseCalendar an iCalendar feed of a course's due dates, exam windows and
+// office hours, for calendar apps to subscribe to with a calendar token.
+func CourseCalendar(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	name := fmt.Sprintf("%s %d-%s %s", course.Department, course.Number, course.Section, course.LongName)
+	sendCalendar(c, name, courseEvents(course, role.(string), uid.(primitive.ObjectID)))
+}
+
+// UserCalendar one iCalendar feed of every course the user is enrolled in.
+func UserCalendar(c *gin.Context) {
+	uid, _ := c.Get("uid")
+	claims := jwt.ExtractClaims(c)
+
+	events := make([]utils.CalendarEvent, 0)
+	for cids, role := range claims["courses"].(map[string]interface{}) {
+		cid, errs := primitive.ObjectIDFromHex(cids)
+		if errs != nil {
+			continue
+		}
+
+		course, err := cm.GetByID(cid)
+		if err != nil {
+			continue
+		}
+
+		events = append(events, courseEvents(course, role.(string), uid.(primitive.ObjectID))...)
+	}
+
+	sendCalendar(c, "Plague Doctor", events)
+}
+
+// UpdateOfficeHours replaces the course's weekly office hours.
+func UpdateOfficeHours(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	var form forms.UpdateOfficeHoursForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	hours, err := coursemodels.NewOfficeHours(form)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = cm.SetOfficeHours(cid, hours)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":     "Office Hours Updated.",
+		"officeHours": hours,
+	})
+}
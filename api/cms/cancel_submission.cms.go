@@ -0,0 +1,97 @@
+package cms
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/courtherald"
+	"backend/errors"
+	"backend/models/cmsmodels/submissionmodels"
+)
+
+// refundable whether the attempt of a submission can be given back. Only a
+// student's latest attempt can be, students only while it is still held
+// back and they cannot have seen any of its results.
+func refundable(submission *submissionmodels.MongoSubmission, role string) bool {
+	if role == "student" && !submission.Queued {
+		return false
+	}
+
+	_, attempt, err := am.LatestUserSubmission(submission.AssignmentID, submission.UserID)
+	return err == nil && attempt == submission.AttemptNumber
+}
+
+// CancelSubmissionJob stops grading a submission, so a student can resubmit
+// straight away or staff can kill a runaway job. With ?refund=true the
+// attempt is given back.
+func CancelSubmissionJob(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	sid, _ := c.Get("sid")
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	submission, err := sm.GetUsersSubmission(sid, uid)
+	if err != nil && role != "student" {
+		submission, err = sm.Get(sid, role.(string))
+	}
+	if err != nil {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	course, err := cm.FindByAssignment(submission.AssignmentID)
+	if err != nil || course.ID != cid {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	if !submission.InProgress {
+		c.Set("error", errors.ErrorSubmissionNotGrading)
+		return
+	}
+
+	refund := c.Query("refund") == "true"
+	if refund && !refundable(submission, role.(string)) {
+		c.Set("error", errors.ErrorAttemptNotRefundable)
+		return
+	}
+
+	if !submission.Queued {
+		if err = courtherald.CancelJob(submission.GraderURL, submission.ID); err != nil {
+			c.Set("error", err)
+			return
+		}
+	}
+
+	if err = sm.Cancel(submission.ID, refund); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if refund {
+		if err = am.DeleteSubmission(submission.AssignmentID, submission.ID); err != nil {
+			tyrgin.ErrorLogger(err, "Failed to refund the attempt of submission "+submission.ID.Hex())
+		}
+	}
+
+	if submission.UserID != uid {
+		err = nm.Notify(
+			[]primitive.ObjectID{submission.UserID},
+			course.ID,
+			"submission",
+			fmt.Sprintf("%s %d: course staff cancelled grading attempt %d", course.Department, course.Number, submission.AttemptNumber),
+			fmt.Sprintf("/course/%s/assignment/%s", course.ID.Hex(), submission.AssignmentID.Hex()),
+		)
+		if err != nil {
+			tyrgin.ErrorLogger(err, "Failed to notify the student of a cancelled submission")
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"message":  "Grading Cancelled.",
+		"refunded": refund,
+	})
+}
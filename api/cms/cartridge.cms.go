@@ -0,0 +1,88 @@
+package cms
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/errors"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/utils"
+)
+
+// cartridgeItem an assignment as it is exported, its description rendered
+// and the links to its attachments pointed at the copies in the cartridge.
+func cartridgeItem(cids string, assign *assignmentmodels.MongoAssignment) (utils.CartridgeItem, errors.APIError) {
+	item := utils.CartridgeItem{
+		ID:    assign.ID.Hex(),
+		Title: assign.Name,
+		HTML:  utils.RenderMarkdown(assign.Description),
+		Files: make([]utils.CartridgeFile, 0),
+	}
+
+	for _, attachment := range assign.Attachments {
+		file, _, err := gfs.Download(attachment.ID)
+		if err != nil {
+			return item, err
+		}
+
+		content, errs := ioutil.ReadAll(file)
+		if errs != nil {
+			return item, errors.ErrorFailedToReadFile
+		}
+
+		path := utils.CartridgeFilePath(attachment.ID.Hex(), attachment.Filename)
+		url := fmt.Sprintf("/api/v1/plague_doctor/course/%s/assignment/%s/attachment/%s", cids, item.ID, attachment.ID.Hex())
+		item.HTML = strings.Replace(item.HTML, url, utils.CartridgeFileBase+"/"+path, -1)
+		item.Files = append(item.Files, utils.CartridgeFile{Path: path, Content: content})
+	}
+
+	return item, nil
+}
+
+// ExportCartridge packages the course's assignments and their descriptions as
+// an IMS Common Cartridge, to move them into Canvas or Moodle. Only published
+// assignments are included with ?published=true.
+func ExportCartridge(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	cids, _ := c.Get("cids")
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	items := make([]utils.CartridgeItem, 0)
+	for _, aid := range course.Assignments {
+		assign, err := am.Get(aid)
+		if err != nil {
+			continue
+		}
+		if c.Query("published") == "true" && !assign.Published {
+			continue
+		}
+
+		item, err := cartridgeItem(cids.(string), assign)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+		items = append(items, item)
+	}
+
+	title := fmt.Sprintf("%s %d %s", course.Department, course.Number, course.LongName)
+	buf, err := utils.BuildCartridge("cartridge_"+course.ID.Hex(), title, items)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	additonalHeaders := map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s-%d.imscc"`, course.Department, course.Number),
+	}
+
+	c.DataFromReader(200, int64(buf.Len()), "application/zip", buf, additonalHeaders)
+}
@@ -0,0 +1,215 @@
+package cms
+
+import (
+	"fmt"
+	"time"
+
+	jwt "github.com/appleboy/gin-jwt"
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/models/cmsmodels/submissionmodels"
+	"backend/utils"
+)
+
+// The cli endpoints are a small, stable protocol for command line submitters.
+// Responses only carry what a terminal needs, and fields are only ever added,
+// never renamed or removed, without bumping cliProtocolVersion.
+const cliProtocolVersion = 1
+
+type (
+	cliAssignment struct {
+		ID              primitive.ObjectID         `json:"id"`
+		CourseID        primitive.ObjectID         `json:"courseID"`
+		Course          string                     `json:"course"`
+		Name            string                     `json:"name"`
+		DueDate         time.Time                  `json:"dueDate"`
+		AttemptsUsed    int                        `json:"attemptsUsed"`
+		AttemptsAllowed int                        `json:"attemptsAllowed"`
+		TimeLimit       int                        `json:"timeLimit,omitempty"`
+		Window          *assignmentmodels.Window   `json:"window,omitempty"`
+		Attestation     string                     `json:"attestation,omitempty"`
+		Tests           []cliAssignmentTestSummary `json:"tests"`
+	}
+
+	cliAssignmentTestSummary struct {
+		Name string `json:"name"`
+	}
+
+	cliResult struct {
+		Name   string `json:"name"`
+		Passed bool   `json:"passed"`
+	}
+)
+
+// cliStatus sums a submission's grading up in one word.
+func cliStatus(submission *submissionmodels.MongoSubmission) string {
+	switch {
+	case submission.Manual && submission.Review == nil:
+		return "submitted"
+	case submission.Queued:
+		return "queued"
+	case submission.InProgress:
+		return "grading"
+	case submission.Cancelled:
+		return "cancelled"
+	case submission.BuildFailed:
+		return "build_failed"
+	case submission.ErrorTesting:
+		return "error"
+	default:
+		return "graded"
+	}
+}
+
+// CLIWhoAmI lets a command line submitter check its credentials.
+func CLIWhoAmI(c *gin.Context) {
+	uid, _ := c.Get("uid")
+	claims := jwt.ExtractClaims(c)
+
+	user, err := um.FindOneById(uid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"protocol": cliProtocolVersion,
+		"id":       user.ID,
+		"email":    user.Email,
+		"name":     user.First + " " + user.Last,
+		"scopes":   claims["scopes"],
+	})
+}
+
+// CLIAssignments lists the assignments the user can still submit to in the
+// courses they are a student in.
+func CLIAssignments(c *gin.Context) {
+	uid, _ := c.Get("uid")
+	claims := jwt.ExtractClaims(c)
+
+	courses, err := um.GetCourses(uid, claims["courses"].(map[string]interface{}))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	now := time.Now()
+	assignments := make([]cliAssignment, 0)
+	for _, course := range courses {
+		if course.Role != "student" {
+			continue
+		}
+
+		published, err := cm.GetAssignments(course.ID, course.Role)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		for _, agg := range published {
+			assign, attempts, err := am.LatestUserSubmission(agg.ID, uid)
+			if err != nil || attempts >= assign.NumAttempts {
+				continue
+			}
+
+			window := assign.Window(uid.(primitive.ObjectID))
+			if assign.Timed() && window != nil && now.After(window.EndsAt) {
+				continue
+			}
+
+			tests := make([]cliAssignmentTestSummary, 0)
+			for _, test := range assign.Tests {
+				if test.StudentFacing {
+					tests = append(tests, cliAssignmentTestSummary{test.Name})
+				}
+			}
+
+			assignments = append(assignments, cliAssignment{
+				ID:              assign.ID,
+				CourseID:        course.ID,
+				Course:          fmt.Sprintf("%s %d%s", course.Department, course.Number, course.Section),
+				Name:            assign.Name,
+				DueDate:         utils.DateTimeToTime(assign.DueDate),
+				AttemptsUsed:    attempts,
+				AttemptsAllowed: assign.NumAttempts,
+				TimeLimit:       assign.TimeLimit,
+				Window:          window,
+				Attestation:     assign.Attestation,
+				Tests:           tests,
+			})
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"protocol":    cliProtocolVersion,
+		"assignments": assignments,
+	})
+}
+
+// CLISubmit takes a multipart upload of a submission tarball in the
+// submission field. Assignments with an attestation need acceptAttestation
+// set to true, timed ones must be started first.
+func CLISubmit(c *gin.Context) {
+	receipt, err := submitAssignment(c)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(201, gin.H{
+		"protocol":             cliProtocolVersion,
+		"submissionID":         receipt.SubmissionID,
+		"attemptNumber":        receipt.AttemptNumber,
+		"status":               receipt.status(),
+		"estimatedWaitSeconds": receipt.EstimatedWaitSeconds,
+	})
+}
+
+// CLISubmission is polled by command line submitters until grading finishes.
+func CLISubmission(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	sid, _ := c.Get("sid")
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	submission, err := sm.GetUsersSubmission(sid, uid)
+	if err != nil && role != "student" {
+		submission, err = sm.Get(sid, role.(string))
+	}
+	if err != nil {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	course, err := cm.FindByAssignment(submission.AssignmentID)
+	if err != nil || course.ID != cid {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	utils.Redact(submission, utils.ViewFor(role.(string)))
+
+	passed := 0
+	results := make([]cliResult, 0)
+	for _, result := range submission.Results {
+		if result.Passed {
+			passed++
+		}
+		results = append(results, cliResult{result.Name, result.Passed})
+	}
+
+	c.JSON(200, gin.H{
+		"protocol":             cliProtocolVersion,
+		"submissionID":         submission.ID,
+		"attemptNumber":        submission.AttemptNumber,
+		"status":               cliStatus(submission),
+		"estimatedWaitSeconds": estimatedWait(submission),
+		"passed":               passed,
+		"total":                len(results),
+		"results":              results,
+		"errorReason":          submission.ErrorReason,
+	})
+}
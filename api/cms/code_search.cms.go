@@ -0,0 +1,237 @@
+package cms
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/errors"
+	"backend/jobs"
+	"backend/models/cmsmodels/codemodels"
+	"backend/utils"
+)
+
+const (
+	// maxIndexedFileSize files larger than this are not searchable.
+	maxIndexedFileSize = 256 * 1024
+	// maxIndexedSize the text of a submission kept for searching, well under
+	// the size of a mongo document.
+	maxIndexedSize = 8 * 1024 * 1024
+	// maxCodeMatches the matching lines returned for a student.
+	maxCodeMatches = 20
+	// maxExcerptLength matching lines are cut to this many characters.
+	maxExcerptLength = 200
+)
+
+// codeMatch a line of a submission matching a code search.
+type codeMatch struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Excerpt string `json:"excerpt"`
+}
+
+// codeIndexPayload the submission an index job extracts the text of.
+type codeIndexPayload struct {
+	SubmissionID string `json:"submissionID"`
+}
+
+// enqueueCodeIndex queues the extraction of a submission's text, failures are
+// only logged as the submission can be indexed when it is searched.
+func enqueueCodeIndex(sid primitive.ObjectID) {
+	_, errs := jobs.Enqueue("submissions.indexCode", codeIndexPayload{sid.Hex()})
+	if errs != nil {
+		tyrgin.ErrorLogger(errs, "Failed to queue the code index of submission "+sid.Hex())
+	}
+}
+
+// IndexSubmissionCode extracts the text files of a submission's archive and
+// stores them for code search. Binary and large files are left out.
+func IndexSubmissionCode(payload []byte) error {
+	var index codeIndexPayload
+	if errs := json.Unmarshal(payload, &index); errs != nil {
+		return errs
+	}
+
+	sid, errs := primitive.ObjectIDFromHex(index.SubmissionID)
+	if errs != nil {
+		return errs
+	}
+
+	submission, err := sm.Get(sid, "teacher")
+	if err != nil {
+		return err
+	}
+
+	if submission.FilePurged {
+		return nil
+	}
+
+	file, _, err := gfs.Download(submission.FileID)
+	if err != nil {
+		return err
+	}
+
+	content, errs := ioutil.ReadAll(file)
+	if errs != nil {
+		return errors.ErrorFailedToReadFile
+	}
+
+	files, err := utils.ArchiveFiles(content, maxIndexedFileSize)
+	if err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	code := codemodels.MongoSubmissionCode{
+		SubmissionID: submission.ID,
+		AssignmentID: submission.AssignmentID,
+		UserID:       submission.UserID,
+		Files:        make([]codemodels.File, 0),
+	}
+	size := 0
+	for _, path := range paths {
+		text := files[path]
+		if text == nil || isBinary(text) || size+len(text) > maxIndexedSize {
+			continue
+		}
+
+		size += len(text)
+		code.Files = append(code.Files, codemodels.File{Path: path, Text: string(text)})
+	}
+
+	return cdm.Index(code)
+}
+
+// matchCode the lines of a submission's files matching the search.
+func matchCode(code codemodels.MongoSubmissionCode, search *regexp.Regexp) []codeMatch {
+	matches := make([]codeMatch, 0)
+	for _, file := range code.Files {
+		for i, line := range strings.Split(file.Text, "\n") {
+			if !search.MatchString(line) {
+				continue
+			}
+
+			line = strings.TrimSpace(line)
+			if len(line) > maxExcerptLength {
+				line = line[:maxExcerptLength] + "..."
+			}
+			matches = append(matches, codeMatch{file.Path, i + 1, line})
+
+			if len(matches) == maxCodeMatches {
+				return matches
+			}
+		}
+	}
+
+	return matches
+}
+
+// SearchSubmissionCode searches the latest submission of every student of an
+// assignment for ?q=, a regular expression with ?regex=true, e.g. to find a
+// banned import. Matches are found line by line. Submissions whose text has
+// not been extracted yet are queued and counted as unindexed.
+func SearchSubmissionCode(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	q := c.Query("q")
+	if q == "" {
+		c.Set("error", errors.ErrorInvalidQuery)
+		return
+	}
+
+	// literal searches let mongo skip submissions without the text, regular
+	// expressions are only run here as go and mongo differ in their syntax
+	pattern := regexp.QuoteMeta(q)
+	prefilter := pattern
+	if c.Query("regex") == "true" {
+		pattern, prefilter = q, ""
+	}
+
+	search, errs := regexp.Compile(pattern)
+	if errs != nil {
+		c.Set("error", errors.ErrorInvalidSearchPattern)
+		return
+	}
+
+	latest, err := sm.LatestPerStudent(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	sids := make([]primitive.ObjectID, 0, len(latest))
+	for _, submission := range latest {
+		sids = append(sids, submission.SubmissionID)
+	}
+
+	indexed, err := cdm.Indexed(sids)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	unindexed := 0
+	for _, submission := range latest {
+		if !indexed[submission.SubmissionID] && !submission.FilePurged {
+			enqueueCodeIndex(submission.SubmissionID)
+			unindexed++
+		}
+	}
+
+	codes, err := cdm.Find(sids, prefilter)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	attempts := make(map[primitive.ObjectID]int)
+	for _, submission := range latest {
+		attempts[submission.SubmissionID] = submission.AttemptNumber
+	}
+
+	students := make([]gin.H, 0)
+	for _, code := range codes {
+		matches := matchCode(code, search)
+		if len(matches) == 0 {
+			continue
+		}
+
+		student := gin.H{
+			"userID":        code.UserID,
+			"submissionID":  code.SubmissionID,
+			"attemptNumber": attempts[code.SubmissionID],
+			"matches":       matches,
+		}
+		if user, err := um.FindOneById(code.UserID); err == nil {
+			student["firstName"] = user.First
+			student["lastName"] = user.Last
+			student["email"] = user.Email
+		}
+
+		students = append(students, student)
+	}
+
+	c.JSON(200, gin.H{
+		"message":   "Code Search.",
+		"students":  students,
+		"searched":  len(codes),
+		"unindexed": unindexed,
+	})
+}
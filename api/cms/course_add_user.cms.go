@@ -7,6 +7,8 @@ import (
 	"backend/forms"
 )
 
+// CourseAddUser enrolls a user by email. An email nobody registered with yet is
+// enrolled once its account is created.
 func CourseAddUser(c *gin.Context) {
 	cid, _ := c.Get("cid")
 
@@ -17,21 +19,35 @@ func CourseAddUser(c *gin.Context) {
 	}
 
 	user, err := um.FindOne(addUser.Email)
-	if err != nil {
-		c.Set("error", err)
+	if err == errors.ErrorResourceNotFound {
+		err = addPendingEnrollment(c, cid, addUser.Email, addUser.Level)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"msg":     "User not registered, enrolled once they register.",
+			"pending": true,
+		})
 		return
 	}
-
-	err = um.AddCourse(addUser.Level, cid, user.ID)
 	if err != nil {
 		c.Set("error", err)
 		return
 	}
 
-	err = cm.AddUser(addUser.Level, user.ID, cid)
+	waitlisted, err := enrollUser(addUser.Level, user.ID, cid)
 	if err != nil {
 		c.Set("error", err)
+		return
+	}
 
+	if waitlisted {
+		c.JSON(200, gin.H{
+			"msg":        "Course full, user waitlisted.",
+			"waitlisted": true,
+		})
 		return
 	}
 
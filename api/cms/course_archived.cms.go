@@ -0,0 +1,36 @@
+package cms
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"backend/errors"
+	"backend/forms"
+)
+
+// SetCourseArchived archives a course, after which every write to it is
+// refused, or restores it.
+func SetCourseArchived(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	var form forms.SetArchivedForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	err := cm.SetArchived(cid, form.Archived)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	message := "Course Restored."
+	if form.Archived {
+		message = "Course Archived."
+	}
+
+	c.JSON(200, gin.H{
+		"message":  message,
+		"archived": form.Archived,
+	})
+}
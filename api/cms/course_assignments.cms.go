@@ -2,19 +2,90 @@ package cms
 
 import (
 	"github.com/gin-gonic/gin"
+
+	"backend/utils"
 )
 
 // CourseAssignments is the function for a route to display all assignments a course has.
+// With ?include=stats every assignment comes with the student's status, or for
+// staff how many students have submitted, so the frontend needs no request
+// per assignment. Students are not shown assignments they have not met the
+// prerequisites of.
 func CourseAssignments(c *gin.Context) {
 	cid, _ := c.Get("cid")
 	role, _ := c.Get("role")
 
+	if c.Query("include") == "stats" {
+		courseAssignmentsWithStats(c, cid, role.(string))
+		return
+	}
+
 	assignments, err := cm.GetAssignments(cid, role.(string))
 	if err != nil {
 		c.Set("error", err)
 		return
 	}
-	c.JSON(200, gin.H{
+
+	if role == "student" {
+		locked, err := studentLockedAssignments(c, cid)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		unlocked := assignments[:0]
+		for _, assignment := range assignments {
+			if !locked[assignment.ID] {
+				unlocked = append(unlocked, assignment)
+			}
+		}
+		assignments = unlocked
+	}
+
+	loc := userLocation(c)
+	for i := range assignments {
+		local := utils.Localize(assignments[i].DueDate, loc)
+		assignments[i].DueDateLocal = &local
+	}
+
+	conditionalJSON(c, gin.H{
+		"message":     "Course assignments.",
+		"assignments": assignments,
+	})
+}
+
+func courseAssignmentsWithStats(c *gin.Context, cid interface{}, role string) {
+	uid, _ := c.Get("uid")
+
+	assignments, err := cm.GetAssignmentsWithStats(cid, uid, role)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if role == "student" {
+		locked, err := studentLockedAssignments(c, cid)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		unlocked := assignments[:0]
+		for _, assignment := range assignments {
+			if !locked[assignment.ID] {
+				unlocked = append(unlocked, assignment)
+			}
+		}
+		assignments = unlocked
+	}
+
+	loc := userLocation(c)
+	for i := range assignments {
+		local := utils.Localize(assignments[i].DueDate, loc)
+		assignments[i].DueDateLocal = &local
+	}
+
+	conditionalJSON(c, gin.H{
 		"message":     "Course assignments.",
 		"assignments": assignments,
 	})
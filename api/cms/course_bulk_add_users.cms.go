@@ -7,6 +7,8 @@ import (
 	"backend/forms"
 )
 
+// CourseAddUsers enrolls users by email, emails nobody registered with yet are
+// enrolled once their accounts are created.
 func CourseAddUsers(c *gin.Context) {
 	cid, _ := c.Get("cid")
 
@@ -16,27 +18,39 @@ func CourseAddUsers(c *gin.Context) {
 		return
 	}
 
+	waitlisted := make([]string, 0)
+	pending := make([]string, 0)
 	for _, email := range addUsers.Emails {
 		user, err := um.FindOne(email)
+		if err == errors.ErrorResourceNotFound {
+			err = addPendingEnrollment(c, cid, email, addUsers.Level)
+			if err != nil {
+				c.Set("error", err)
+				return
+			}
+
+			pending = append(pending, email)
+			continue
+		}
 		if err != nil {
 			c.Set("error", err)
 			return
 		}
 
-		err = um.AddCourse(addUsers.Level, cid, user.ID)
+		onWaitlist, err := enrollUser(addUsers.Level, user.ID, cid)
 		if err != nil {
 			c.Set("error", err)
 			return
 		}
 
-		err = cm.AddUser(addUsers.Level, user.ID, cid)
-		if err != nil {
-			c.Set("error", err)
-			return
+		if onWaitlist {
+			waitlisted = append(waitlisted, email)
 		}
 	}
 
 	c.JSON(200, gin.H{
-		"message": "User added.",
+		"message":    "User added.",
+		"waitlisted": waitlisted,
+		"pending":    pending,
 	})
 }
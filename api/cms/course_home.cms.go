@@ -0,0 +1,150 @@
+package cms
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/forms"
+	"backend/models/cmsmodels/announcementmodels"
+	"backend/models/cmsmodels/modulemodels"
+	"backend/utils"
+)
+
+// homeModule a module as shown on the course home, with its assignments and
+// announcements in full.
+type homeModule struct {
+	ID            primitive.ObjectID                     `json:"id"`
+	Title         string                                 `json:"title"`
+	Description   string                                 `json:"description"`
+	Position      int                                    `json:"position"`
+	StartDate     *primitive.DateTime                    `json:"startDate,omitempty"`
+	Published     bool                                   `json:"published"`
+	Assignments   []forms.AssignmentStatsAggQuery        `json:"assignments"`
+	Announcements []announcementmodels.MongoAnnouncement `json:"announcements"`
+	Files         []modulemodels.File                    `json:"files"`
+}
+
+// CourseHome is the course page: its modules in order, each with the
+// assignments, announcements and files it groups, which of them is current
+// and the assignments no module holds. Students see published modules and
+// the assignments and announcements they could see anyway.
+func CourseHome(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	modules, err := mdm.ByCourse(cid, role.(string))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assignments, err := cm.GetAssignmentsWithStats(cid, uid, role.(string))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if role == "student" {
+		locked, err := studentLockedAssignments(c, cid)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		unlocked := assignments[:0]
+		for _, assignment := range assignments {
+			if !locked[assignment.ID] {
+				unlocked = append(unlocked, assignment)
+			}
+		}
+		assignments = unlocked
+	}
+
+	loc := userLocation(c)
+	byAssignment := make(map[primitive.ObjectID]forms.AssignmentStatsAggQuery, len(assignments))
+	for i := range assignments {
+		local := utils.Localize(assignments[i].DueDate, loc)
+		assignments[i].DueDateLocal = &local
+		byAssignment[assignments[i].ID] = assignments[i]
+	}
+
+	announcements, err := anm.GetByCourse(cid, role.(string))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+	byAnnouncement := make(map[primitive.ObjectID]announcementmodels.MongoAnnouncement, len(announcements))
+	for _, announcement := range announcements {
+		byAnnouncement[announcement.ID] = announcement
+	}
+
+	now := utils.TimeToDateTime(time.Now())
+	home := make([]homeModule, 0, len(modules))
+	inModule := make(map[primitive.ObjectID]bool)
+	var current *primitive.ObjectID
+	for _, module := range modules {
+		shown := homeModule{
+			ID:            module.ID,
+			Title:         module.Title,
+			Description:   module.Description,
+			Position:      module.Position,
+			StartDate:     module.StartDate,
+			Published:     module.Published,
+			Assignments:   make([]forms.AssignmentStatsAggQuery, 0, len(module.Assignments)),
+			Announcements: make([]announcementmodels.MongoAnnouncement, 0, len(module.Announcements)),
+			Files:         module.Files,
+		}
+
+		for _, aid := range module.Assignments {
+			if assignment, ok := byAssignment[aid]; ok {
+				shown.Assignments = append(shown.Assignments, assignment)
+				inModule[aid] = true
+			}
+		}
+		for _, anid := range module.Announcements {
+			if announcement, ok := byAnnouncement[anid]; ok {
+				shown.Announcements = append(shown.Announcements, announcement)
+			}
+		}
+
+		// the current module is the last one that has started
+		if module.StartDate != nil && *module.StartDate <= now {
+			id := module.ID
+			current = &id
+		}
+
+		home = append(home, shown)
+	}
+
+	unsorted := make([]forms.AssignmentStatsAggQuery, 0)
+	for _, assignment := range assignments {
+		if !inModule[assignment.ID] {
+			unsorted = append(unsorted, assignment)
+		}
+	}
+
+	conditionalJSON(c, gin.H{
+		"message": "Course Home.",
+		"course": gin.H{
+			"id":         course.ID,
+			"department": course.Department,
+			"number":     course.Number,
+			"section":    course.Section,
+			"longName":   course.LongName,
+			"semester":   course.Semester,
+			"role":       role,
+		},
+		"modules":             home,
+		"currentModule":       current,
+		"unsortedAssignments": unsorted,
+	})
+}
@@ -31,6 +31,16 @@ func CreateAssignment(c *gin.Context) {
 	}
 	versionCheck(&capre)
 
+	if capre.TimeLimit < 0 {
+		c.Set("error", errors.ErrorInvalidTimeLimit)
+		return
+	}
+
+	if capre.Manual && capre.Quiz {
+		c.Set("error", errors.ErrorInvalidQuiz)
+		return
+	}
+
 	var tests []cmsforms.CreateAssignmentTest
 	for _, test := range capre.Tests {
 		var toAdd cmsforms.CreateAssignmentTest
@@ -38,6 +48,22 @@ func CreateAssignment(c *gin.Context) {
 		tests = append(tests, toAdd)
 	}
 
+	var rubric []cmsforms.RubricCriterion
+	if capre.Rubric != "" {
+		if errs := json.Unmarshal([]byte(capre.Rubric), &rubric); errs != nil {
+			c.Set("error", errors.ErrorInvalidRubric)
+			return
+		}
+	}
+
+	var latePolicy *cmsforms.LatePolicy
+	if capre.LatePolicy != "" {
+		if errs := json.Unmarshal([]byte(capre.LatePolicy), &latePolicy); errs != nil {
+			c.Set("error", errors.ErrorInvalidLatePolicy)
+			return
+		}
+	}
+
 	capost := forms.CreateAssignmentPostForm{
 		capre.Language,
 		capre.Version,
@@ -47,6 +73,48 @@ func CreateAssignment(c *gin.Context) {
 		capre.DueDate,
 		capre.TestBuildCMD,
 		tests,
+		capre.TimeLimit,
+		capre.Attestation,
+		capre.LeaderboardMetric,
+		capre.LeaderboardLowerIsBetter,
+		capre.PrecheckFiles,
+		capre.PrecheckExclusive,
+		capre.PrecheckBuild,
+		capre.ExtraCredit,
+		capre.Requirements,
+		capre.ShowHiddenSummary,
+		capre.Manual,
+		rubric,
+		capre.Quiz,
+		capre.CooldownMinutes,
+		latePolicy,
+		"",
+	}
+
+	cid, _ := c.Get("cid")
+	if capre.Policy != "" {
+		course, err := cm.GetByID(cid)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		template, found := course.FindPolicyTemplate(capre.Policy)
+		if !found {
+			c.Set("error", errors.ErrorUnknownPolicyTemplate)
+			return
+		}
+
+		// settings the form gives override the template's
+		template.Apply(&capost, func(field string) bool {
+			_, given := c.GetPostForm(field)
+			return given
+		})
+	}
+
+	if capost.NumAttempts < 1 {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
 	}
 
 	cids, _ := c.Get("cids")
@@ -56,7 +124,6 @@ func CreateAssignment(c *gin.Context) {
 		return
 	}
 
-	cid, _ := c.Get("cid")
 	err = cm.AddAssignment(*aid, cid)
 	if err != nil {
 		c.Set("error", err)
@@ -69,17 +136,20 @@ func CreateAssignment(c *gin.Context) {
 		return
 	}
 
-	supportingFiles, err := utils.CheckFileType(sf)
-	if err != nil {
-		c.Set("error", err)
-		return
-	}
-
-	err = gfs.Upload(supportingFilesID, capre.Name, bytes.NewReader(supportingFiles))
-	if err != nil {
-		c.Set("error", err)
-		am.Delete(*aid)
-		return
+	// manually graded assignments and quizzes need no supporting files
+	if sf != nil || !(capre.Manual || capre.Quiz) {
+		supportingFiles, err := utils.CheckFileType(sf)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		err = gfs.Upload(supportingFilesID, capre.Name, bytes.NewReader(supportingFiles))
+		if err != nil {
+			c.Set("error", err)
+			am.Delete(*aid)
+			return
+		}
 	}
 
 	c.JSON(200, gin.H{
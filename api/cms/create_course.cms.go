@@ -1,10 +1,6 @@
 package cms
 
 import (
-	//	"fmt"
-	"time"
-
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 
 	"backend/api/auth"
@@ -21,7 +17,7 @@ func CreateCourse(c *gin.Context) {
 		return
 	}
 
-	cid, err := cm.Create(uid, createCourse)
+	cid, err := cm.Create(uid, contextOrganization(c), createCourse)
 	if err != nil {
 		c.Set("error", err)
 		return
@@ -39,25 +35,11 @@ func CreateCourse(c *gin.Context) {
 		return
 	}
 
-	token := jwt.New(jwt.GetSigningMethod(auth.AuthMiddleware.SigningAlgorithm))
-	claims := token.Claims.(jwt.MapClaims)
-	for key, val := range auth.AuthMiddleware.PayloadFunc(user) {
-		claims[key] = val
+	tokenString, expire, err := auth.IssueToken(c, user)
+	if err != nil {
+		c.Set("error", err)
+		return
 	}
-	expire := auth.AuthMiddleware.TimeFunc().Add(auth.AuthMiddleware.Timeout)
-	claims["exp"] = expire.Unix()
-	claims["orig_iat"] = auth.AuthMiddleware.TimeFunc().Unix()
-	tokenString, _ := token.SignedString(auth.AuthMiddleware.Key)
-
-	c.SetCookie(
-		auth.AuthMiddleware.CookieName,
-		tokenString,
-		int(expire.Unix()-time.Now().Unix()),
-		"/",
-		auth.AuthMiddleware.CookieDomain,
-		auth.AuthMiddleware.SecureCookie,
-		auth.AuthMiddleware.CookieHTTPOnly,
-	)
 
 	c.JSON(200, gin.H{
 		"message": "Course created.",
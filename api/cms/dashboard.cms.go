@@ -3,8 +3,10 @@ package cms
 import (
 	jwt "github.com/appleboy/gin-jwt"
 	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
 
 	"backend/forms"
+	"backend/utils"
 )
 
 // Dashboard is the function for a route to display all course a user has.
@@ -25,11 +27,23 @@ func Dashboard(c *gin.Context) {
 		return
 	}
 
+	loc := utils.LoadLocation(user.Timezone)
 	assignments := make([]forms.AssignmentAggQuery, 0)
+	cids := make([]primitive.ObjectID, 0)
+	// quotas of the courses the user is staff of
+	quotas := gin.H{}
 	for _, course := range courses {
+		cids = append(cids, course.ID)
+		if course.Role != "student" {
+			if staffCourse, err := cm.GetByID(course.ID); err == nil {
+				quotas[course.ID.Hex()] = quotaStatus(staffCourse)
+			}
+		}
 		courseAssignments, err := cm.GetAssignments(course.ID, course.Role)
 		for i := range courseAssignments {
 			courseAssignments[i].CourseID = course.ID
+			local := utils.Localize(courseAssignments[i].DueDate, loc)
+			courseAssignments[i].DueDateLocal = &local
 		}
 		if err != nil {
 			c.Set("error", err)
@@ -45,6 +59,12 @@ func Dashboard(c *gin.Context) {
 		return
 	}
 
+	announcements, err := anm.GetFeed(cids, 10)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
 	c.JSON(200, gin.H{
 		"status_code":           200,
 		"msg":                   "User's Info.",
@@ -52,5 +72,8 @@ func Dashboard(c *gin.Context) {
 		"courses":               courses,
 		"assignments":           assignments,
 		"mostRecentSubmissions": submissions,
+		"announcements":         announcements,
+		"quotas":                quotas,
+		"timezone":              loc.String(),
 	})
 }
@@ -2,6 +2,7 @@ package cms
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
 )
 
 func DeleteAssignment(c *gin.Context) {
@@ -20,14 +21,37 @@ func DeleteAssignment(c *gin.Context) {
 		return
 	}
 
-	for _, sid := range assign.Submissions {
-		err = gfs.Delete(sid)
+	for _, attachment := range assign.Attachments {
+		err = gfs.Delete(attachment.ID)
 		if err != nil {
 			c.Set("error", err)
 			return
 		}
 	}
 
+	for _, fixture := range assign.Fixtures {
+		gfs.Delete(fixture.ID)
+	}
+	if assign.ReferenceSolution != nil {
+		gfs.Delete(assign.ReferenceSolution.FileID)
+	}
+
+	submissions, err := sm.GetByAssignmentIDs([]primitive.ObjectID{assign.ID})
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	for _, submission := range submissions {
+		gfs.Delete(submission.FileID)
+		for _, fid := range submission.OutputFileIDs() {
+			gfs.Delete(fid)
+		}
+		for _, fid := range submission.ArtifactFileIDs() {
+			gfs.Delete(fid)
+		}
+	}
+
 	err = am.Delete(aid)
 	if err != nil {
 		c.Set("error", err)
@@ -40,6 +64,60 @@ func DeleteAssignment(c *gin.Context) {
 		return
 	}
 
+	err = cdm.DeleteByAssignmentID(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = gtm.DeleteByAssignmentID(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = dm.DeleteByAssignmentID(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = dsm.DeleteByAssignmentID(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = qm.DeleteAttemptsByAssignmentID(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = dfm.DeleteByAssignmentID(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = regm.DeleteByAssignmentID(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = exm.DeleteByAssignmentID(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = mdm.RemoveAssignment(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
 	c.JSON(200, gin.H{
 		"message": "Assignment Deleted.",
 	})
@@ -39,6 +39,102 @@ func DeleteCourse(c *gin.Context) {
 			c.Set("error", err)
 			return
 		}
+
+		err = cdm.DeleteByAssignmentID(aid)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		err = gtm.DeleteByAssignmentID(aid)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+	}
+
+	err = anm.DeleteByCourseID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = dm.DeleteByCourseID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = dsm.DeleteByCourseID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = atm.DeleteByCourseID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = whm.DeleteByCourseID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = imm.DeleteByCourseID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = qm.DeleteByCourseID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = dfm.DeleteByCourseID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = regm.DeleteByCourseID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = exm.DeleteByCourseID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	modules, err := mdm.ByCourse(cid, "teacher")
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	for _, module := range modules {
+		for _, file := range module.Files {
+			gfs.Delete(file.ID)
+		}
+	}
+
+	err = mdm.DeleteByCourseID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = fm.DeleteCourseOverrides(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
 	}
 
 	err = cm.Delete(cid)
@@ -0,0 +1,201 @@
+package cms
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/config"
+	"backend/errors"
+	"backend/forms"
+	"backend/models/usermodels"
+	"backend/utils"
+)
+
+// digestHorizon how far ahead a digest looks for due dates.
+func digestHorizon(frequency string) time.Duration {
+	if frequency == usermodels.DigestDaily {
+		return 24 * time.Hour
+	}
+
+	return 7 * 24 * time.Hour
+}
+
+// unsubscribeURL where a digest can be turned off from. Needs PUBLIC_URL, as
+// digests are not sent in response to a request.
+func unsubscribeURL(token string) string {
+	return fmt.Sprintf("%s/api/v1/plague_doctor/digest/unsubscribe/%s", config.C.PublicURL, token)
+}
+
+// buildDigest lists the assignments a student has due before the horizon and
+// their submissions still being graded, empty when there is nothing to say.
+func buildDigest(user usermodels.MongoUser, now time.Time) (string, errors.APIError) {
+	loc := utils.LoadLocation(user.Timezone)
+	horizon := now.Add(digestHorizon(user.Digest))
+
+	levels := make(map[string]interface{})
+	for cid, role := range user.CoursesAsMap() {
+		levels[cid] = role
+	}
+
+	courses, err := um.GetCourses(user.ID, levels)
+	if err != nil {
+		return "", err
+	}
+
+	upcoming := new(strings.Builder)
+	for _, course := range courses {
+		if course.Role != "student" {
+			continue
+		}
+
+		assignments, err := cm.GetAssignments(course.ID, course.Role)
+		if err != nil {
+			return "", err
+		}
+
+		for _, assign := range assignments {
+			due := utils.DateTimeToTime(assign.DueDate)
+			if due.Before(now) || due.After(horizon) {
+				continue
+			}
+
+			status := "not submitted"
+			if _, attempts, err := am.LatestUserSubmission(assign.ID, user.ID); err == nil && attempts > 0 {
+				status = fmt.Sprintf("%d attempt(s) submitted", attempts)
+			}
+
+			fmt.Fprintf(
+				upcoming,
+				"- %s %d: %s, due %s (%s)\n",
+				course.Department, course.Number, assign.Name, due.In(loc).Format("Mon Jan 2 3:04 PM MST"), status,
+			)
+		}
+	}
+
+	submissions, err := sm.GetUsersSubmissions(user.ID)
+	if err != nil {
+		return "", err
+	}
+
+	grading := new(strings.Builder)
+	for _, submission := range submissions {
+		if !submission.InProgress || submission.Withdrawn {
+			continue
+		}
+
+		assign, err := am.Get(submission.AssignmentID)
+		if err != nil {
+			continue
+		}
+
+		fmt.Fprintf(grading, "- %s, attempt %d\n", assign.Name, submission.AttemptNumber)
+	}
+
+	if upcoming.Len() == 0 && grading.Len() == 0 {
+		return "", nil
+	}
+
+	body := new(strings.Builder)
+	fmt.Fprintf(body, "Hi %s,\n\n", user.First)
+	if upcoming.Len() > 0 {
+		fmt.Fprintf(body, "Due by %s:\n%s\n", horizon.In(loc).Format("Mon Jan 2"), upcoming)
+	}
+	if grading.Len() > 0 {
+		fmt.Fprintf(body, "Still being graded:\n%s\n", grading)
+	}
+
+	return body.String(), nil
+}
+
+// sendDigest emails a user their digest, if they have anything due.
+func sendDigest(user usermodels.MongoUser, now time.Time) errors.APIError {
+	body, err := buildDigest(user, now)
+	if err != nil {
+		return err
+	}
+
+	token := user.DigestToken
+	if token == "" {
+		code, errs := utils.RandomCode(32)
+		if errs != nil {
+			return errors.ErrorSendingMail
+		}
+		token = code
+	}
+
+	if body != "" {
+		link := unsubscribeURL(token)
+		body += fmt.Sprintf("You get this %s digest from Plague Doctor. Unsubscribe: %s\n", user.Digest, link)
+
+		err = utils.SendMail(user.Email, "Upcoming deadlines", body, map[string]string{"List-Unsubscribe": "<" + link + ">"})
+		if err != nil {
+			return err
+		}
+	}
+
+	// an empty digest counts as sent so the user is not checked again until
+	// their next one is due
+	return um.DigestSent(user.ID, now, token)
+}
+
+// SendDigests is a job that emails users whose digest is due. It is only
+// scheduled when SMTP_HOST is set.
+func SendDigests([]byte) error {
+	now := time.Now()
+	users, err := um.DueForDigest(now)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if err = sendDigest(user, now); err != nil {
+			tyrgin.ErrorLogger(err, "Failed to send digest to "+user.ID.Hex())
+		}
+	}
+
+	return nil
+}
+
+// UpdateDigest changes how often the logged in user is emailed a digest.
+func UpdateDigest(c *gin.Context) {
+	uid, _ := c.Get("uid")
+
+	var form forms.UserDigestForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	if !usermodels.ValidDigest(form.Frequency) {
+		c.Set("error", errors.ErrorInvalidDigestFrequency)
+		return
+	}
+
+	err := um.SetDigest(uid, form.Frequency)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Digest Updated.",
+		"digest":  form.Frequency,
+	})
+}
+
+// UnsubscribeDigest turns off digests from the link in one, without logging in.
+func UnsubscribeDigest(c *gin.Context) {
+	err := um.UnsubscribeDigest(c.Param("token"))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Unsubscribed From Digests.",
+	})
+}
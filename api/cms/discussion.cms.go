@@ -0,0 +1,200 @@
+package cms
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/forms"
+	"backend/models/cmsmodels/discussionmodels"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+func authorName(uid interface{}) (string, errors.APIError) {
+	user, err := um.FindOneById(uid)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s %s", user.First, user.Last), nil
+}
+
+// AssignmentThreads lists the discussion threads of an assignment along with
+// how many of them have unread activity.
+func AssignmentThreads(c *gin.Context) {
+	aid, _ := c.Get("aid")
+	cid, _ := c.Get("cid")
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	err := checkCourseAssignment(cid, aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	threads, err := dm.GetByAssignment(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	unread := 0
+	summaries := make([]discussionmodels.ThreadSummary, 0)
+	for _, thread := range threads {
+		thread.Redact(uid.(primitive.ObjectID), role.(string))
+		summary := thread.Summary(uid.(primitive.ObjectID))
+		if summary.Unread {
+			unread++
+		}
+		summaries = append(summaries, summary)
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Assignment threads.",
+		"threads": summaries,
+		"unread":  unread,
+	})
+}
+
+func CreateThread(c *gin.Context) {
+	aid, _ := c.Get("aid")
+	cid, _ := c.Get("cid")
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	var create forms.CreateThreadForm
+	if err := c.ShouldBindJSON(&create); err != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	err := checkCourseAssignment(cid, aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	name, err := authorName(uid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	question := discussionmodels.NewPost(uid.(primitive.ObjectID), name, create.Body, create.Anonymous)
+	thread, err := dm.Create(cid, aid, create.Title, question)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	thread.Redact(uid.(primitive.ObjectID), role.(string))
+	c.JSON(200, gin.H{
+		"message": "Thread Created.",
+		"thread":  thread,
+	})
+}
+
+// GetThread returns a thread with all of its posts and marks it as read.
+func GetThread(c *gin.Context) {
+	tid, _ := c.Get("tid")
+	cid, _ := c.Get("cid")
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	thread, err := dm.Get(tid, cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = dm.MarkRead(tid, uid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	thread.Redact(uid.(primitive.ObjectID), role.(string))
+	c.JSON(200, gin.H{
+		"message": "Thread.",
+		"thread":  thread,
+	})
+}
+
+func CreatePost(c *gin.Context) {
+	tid, _ := c.Get("tid")
+	cid, _ := c.Get("cid")
+	uid, _ := c.Get("uid")
+
+	thread, err := dm.Get(tid, cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	var create forms.CreatePostForm
+	if errs := c.ShouldBindJSON(&create); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	name, err := authorName(uid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	post := discussionmodels.NewPost(uid.(primitive.ObjectID), name, create.Body, create.Anonymous)
+	err = dm.AddPost(tid, post)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	dm.MarkRead(tid, uid)
+
+	question := thread.Posts[0]
+	if question.AuthorID != post.AuthorID {
+		err = nm.Notify(
+			[]primitive.ObjectID{question.AuthorID},
+			cid,
+			"discussion",
+			fmt.Sprintf("New reply to \"%s\"", thread.Title),
+			fmt.Sprintf("/course/%s/thread/%s", thread.CourseID.Hex(), thread.ID.Hex()),
+		)
+		if err != nil {
+			tyrgin.ErrorLogger(err, "Failed to notify thread author.")
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Post Created.",
+	})
+}
+
+// EndorsePost lets staff mark a post as the answer to a thread. Passing
+// ?endorsed=false removes the endorsement.
+func EndorsePost(c *gin.Context) {
+	tid, _ := c.Get("tid")
+	pid, _ := c.Get("pid")
+	cid, _ := c.Get("cid")
+
+	_, err := dm.Get(tid, cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = dm.Endorse(tid, pid, c.Query("endorsed") != "false")
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Post Endorsed.",
+	})
+}
@@ -0,0 +1,299 @@
+package cms
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/errors"
+	"backend/forms"
+	"backend/models/cmsmodels/disputemodels"
+	"backend/models/cmsmodels/submissionmodels"
+	"backend/utils"
+)
+
+// notifyDispute lets the other side of a dispute know about activity on it:
+// the student when staff act, the course staff when the student does.
+func notifyDispute(dispute *disputemodels.MongoDispute, staff bool, message string) {
+	course, err := cm.GetByID(dispute.CourseID)
+	if err != nil {
+		tyrgin.ErrorLogger(err, "Failed to notify dispute "+dispute.ID.Hex())
+		return
+	}
+
+	// disputes go to the assistants of the student's section, when it has any
+	recipients := []primitive.ObjectID{dispute.UserID}
+	if !staff {
+		assistants := course.SectionAssistants(dispute.UserID)
+		if len(assistants) == 0 {
+			assistants = course.Assistants
+		}
+		recipients = append(append([]primitive.ObjectID{}, course.Professors...), assistants...)
+	}
+
+	err = nm.Notify(
+		recipients,
+		course.ID,
+		"dispute",
+		fmt.Sprintf("%s %d: %s", course.Department, course.Number, message),
+		fmt.Sprintf("/course/%s/dispute/%s", course.ID.Hex(), dispute.ID.Hex()),
+	)
+	if err != nil {
+		tyrgin.ErrorLogger(err, "Failed to notify dispute "+dispute.ID.Hex())
+	}
+}
+
+// getDispute finds a dispute the user may see, students only their own.
+func getDispute(c *gin.Context) (*disputemodels.MongoDispute, errors.APIError) {
+	did, _ := c.Get("did")
+	cid, _ := c.Get("cid")
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	dispute, err := dsm.Get(did, cid)
+	if err != nil || (role == "student" && dispute.UserID != uid) {
+		return nil, errors.ErrorResourceNotFound
+	}
+
+	return dispute, nil
+}
+
+// OpenDispute lets a student appeal the grade of one of their submissions.
+// A submission can only have one unresolved dispute at a time.
+func OpenDispute(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	sid, _ := c.Get("sid")
+	uid, _ := c.Get("uid")
+
+	var open forms.OpenDisputeForm
+	if errs := c.ShouldBindJSON(&open); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	submission, err := sm.Get(sid, "student")
+	if err != nil || submission.UserID != uid {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	course, err := cm.FindByAssignment(submission.AssignmentID)
+	if err != nil || course.ID != cid {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	if _, err = dsm.FindOpen(submission.ID); err == nil {
+		c.Set("error", errors.ErrorDisputeAlreadyOpen)
+		return
+	}
+
+	name, err := authorName(uid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	message := disputemodels.NewMessage(submission.UserID, name, open.Message, false)
+	dispute, err := dsm.Create(course.ID, submission.AssignmentID, submission.ID, submission.UserID, message)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	notifyDispute(dispute, false, fmt.Sprintf("%s disputed the grade of attempt %d", name, submission.AttemptNumber))
+
+	c.JSON(201, gin.H{
+		"status_code": 201,
+		"message":     "Dispute Opened.",
+		"dispute":     dispute,
+	})
+}
+
+// CourseDisputes lists a course's disputes, filtered with ?status=. Students
+// only see their own, staff can filter to a section with ?section= or
+// ?mine=true.
+func CourseDisputes(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	status := c.Query("status")
+	if status != "" && !disputemodels.ValidStatus(status) {
+		c.Set("error", errors.ErrorInvalidQuery)
+		return
+	}
+
+	var student *primitive.ObjectID
+	if role == "student" {
+		id := uid.(primitive.ObjectID)
+		student = &id
+	}
+
+	disputes, err := dsm.Find(cid, student, status)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if role != "student" {
+		course, err := cm.GetByID(cid)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		students, err := sectionStudents(c, course)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		if students != nil {
+			filtered := disputes[:0]
+			for _, dispute := range disputes {
+				if students[dispute.UserID] {
+					filtered = append(filtered, dispute)
+				}
+			}
+			disputes = filtered
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"message":  "Course disputes.",
+		"disputes": disputes,
+	})
+}
+
+func GetDispute(c *gin.Context) {
+	dispute, err := getDispute(c)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Dispute.",
+		"dispute": dispute,
+	})
+}
+
+// AddDisputeMessage adds a message from the student or staff to an unresolved
+// dispute.
+func AddDisputeMessage(c *gin.Context) {
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	dispute, err := getDispute(c)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if dispute.Closed() {
+		c.Set("error", errors.ErrorDisputeClosed)
+		return
+	}
+
+	var body forms.DisputeMessageForm
+	if errs := c.ShouldBindJSON(&body); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	name, err := authorName(uid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	staff := role != "student"
+	message := disputemodels.NewMessage(uid.(primitive.ObjectID), name, body.Body, staff)
+	err = dsm.AddMessage(dispute.ID, message)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	notifyDispute(dispute, staff, fmt.Sprintf("%s replied to a grade dispute", name))
+
+	c.JSON(200, gin.H{
+		"message":        "Dispute Message Added.",
+		"disputeMessage": message,
+	})
+}
+
+// UpdateDisputeStatus lets staff move a dispute along. Resolving it as
+// adjusted overrides the submission's grade with the one given.
+func UpdateDisputeStatus(c *gin.Context) {
+	uid, _ := c.Get("uid")
+
+	dispute, err := getDispute(c)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	var update forms.UpdateDisputeStatusForm
+	if errs := c.ShouldBindJSON(&update); errs != nil || !disputemodels.ValidStatus(update.Status) {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	if dispute.Closed() {
+		c.Set("error", errors.ErrorDisputeClosed)
+		return
+	}
+
+	staffID := uid.(primitive.ObjectID)
+	var gradeOverride *float64
+	if update.Status == disputemodels.StatusAdjusted {
+		if update.Grade == nil {
+			c.Set("error", errors.ErrorGradeOverrideRequired)
+			return
+		}
+
+		gradeOverride = update.Grade
+		err = sm.OverrideGrade(dispute.SubmissionID, submissionmodels.GradeOverride{
+			Grade:     *update.Grade,
+			Reason:    update.Message,
+			By:        staffID,
+			At:        utils.TimeToDateTime(time.Now()),
+			DisputeID: &dispute.ID,
+		})
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+	}
+
+	if update.Message != "" {
+		name, err := authorName(uid)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		err = dsm.AddMessage(dispute.ID, disputemodels.NewMessage(staffID, name, update.Message, true))
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+	}
+
+	err = dsm.SetStatus(dispute.ID, update.Status, staffID, gradeOverride)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	notifyDispute(dispute, true, fmt.Sprintf("your grade dispute is now %s", update.Status))
+
+	c.JSON(200, gin.H{
+		"message": "Dispute Status Updated.",
+	})
+}
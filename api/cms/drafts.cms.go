@@ -0,0 +1,173 @@
+package cms
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/forms"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/models/cmsmodels/draftmodels"
+	"backend/utils"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+// SaveDraft saves the work in progress of the editor or the command line as
+// the student's draft of an assignment, replacing the one they had. Drafts
+// do not use up attempts and are kept until the student's deadline.
+func SaveDraft(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	uid, _ := c.Get("uid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	var form forms.SaveDraftForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	assign, err := am.Get(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if assign.Quiz != nil {
+		c.Set("error", errors.ErrorQuizAssignment)
+		return
+	}
+
+	if assign.Manual {
+		c.Set("error", errors.ErrorManualSubmissionType)
+		return
+	}
+
+	if err = checkPrerequisites(c, assign, uid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	now := time.Now()
+	deadline := assign.Deadline(uid.(primitive.ObjectID))
+	if now.After(deadline) {
+		c.Set("error", errors.ErrorDraftDeadlinePassed)
+		return
+	}
+
+	draft, err := draftmodels.NewDraft(form)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+	draft.UserID = uid.(primitive.ObjectID)
+	draft.AssignmentID = aid.(primitive.ObjectID)
+	draft.CourseID = cid.(primitive.ObjectID)
+	draft.ExpiresAt = utils.TimeToDateTime(deadline)
+
+	if err = dfm.Save(draft, now); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":   "Draft Saved.",
+		"savedAt":   draft.SavedAt,
+		"expiresAt": draft.ExpiresAt,
+	})
+}
+
+// AssignmentDraft returns the student's latest draft of an assignment, its
+// files keyed by their path like the editor's buffers.
+func AssignmentDraft(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	uid, _ := c.Get("uid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	draft, err := dfm.Get(aid, uid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if draft == nil {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":   "Draft.",
+		"source":    draft.Source,
+		"savedAt":   draft.SavedAt,
+		"expiresAt": draft.ExpiresAt,
+		"files":     draft.Buffers(),
+	})
+}
+
+// DiscardDraft deletes the student's draft of an assignment.
+func DiscardDraft(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	uid, _ := c.Get("uid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if err := dfm.Delete(aid, uid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Draft Discarded.",
+	})
+}
+
+// PurgeExpiredDrafts deletes the drafts whose deadline has passed. Drafts of
+// assignments whose deadline was moved since they were saved are kept until
+// the new one.
+func PurgeExpiredDrafts([]byte) error {
+	now := time.Now()
+	drafts, err := dfm.Expired(now)
+	if err != nil {
+		return err
+	}
+
+	assigns := make(map[primitive.ObjectID]*assignmentmodels.MongoAssignment)
+	for _, draft := range drafts {
+		assign, found := assigns[draft.AssignmentID]
+		if !found {
+			assign, err = am.Get(draft.AssignmentID)
+			if err != nil {
+				tyrgin.ErrorLogger(err, "Failed to find the assignment of draft "+draft.ID.Hex())
+				continue
+			}
+			assigns[draft.AssignmentID] = assign
+		}
+
+		if deadline := assign.Deadline(draft.UserID); deadline.After(now) {
+			err = dfm.Extend(draft.ID, utils.TimeToDateTime(deadline))
+		} else {
+			err = dfm.DeleteByID(draft.ID)
+		}
+		if err != nil {
+			tyrgin.ErrorLogger(err, "Failed to purge draft "+draft.ID.Hex())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package cms
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/config"
+	"backend/errors"
+	"backend/events"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/models/cmsmodels/dryrunmodels"
+	"backend/models/cmsmodels/submissionmodels"
+	"backend/utils"
+)
+
+// dryRunPoll how often a dry run checks for its report when no change tells
+// it one arrived.
+const dryRunPoll = 5 * time.Second
+
+// findDryRun returns the dry run a court herald callback is for, if any.
+func findDryRun(sid interface{}) *dryrunmodels.MongoDryRun {
+	run, err := drm.Get(sid)
+	if err != nil {
+		return nil
+	}
+
+	return run
+}
+
+// finishDryRun records court herald's report for the instance waiting on the
+// dry run.
+func finishDryRun(run *dryrunmodels.MongoDryRun, report submissionmodels.GradeReport, errored bool) errors.APIError {
+	for index := range report.Results {
+		result := &report.Results[index]
+		result.HTML = utils.SanitizeHTML(result.HTML)
+		result.Output = submissionmodels.TruncateOutput(result.Output)
+		result.Stderr = submissionmodels.TruncateOutput(result.Stderr)
+	}
+	report.BuildOutput = submissionmodels.TruncateOutput(report.BuildOutput)
+
+	return drm.Finish(run.ID, report, errored)
+}
+
+// gradeDryRun grades a stored solution against an assignment's tests without
+// recording a submission, waiting until court herald reports back to any
+// instance.
+func gradeDryRun(ctx context.Context, assign *assignmentmodels.MongoAssignment, uid, fid primitive.ObjectID, filename string) (*submissionmodels.GradeReport, bool, string, errors.APIError) {
+	submission := submissionmodels.MongoSubmission{
+		ID:             primitive.NewObjectID(),
+		UserID:         uid,
+		FileID:         fid,
+		AssignmentID:   assign.ID,
+		SubmissionDate: utils.TimeToDateTime(time.Now()),
+		File:           filename,
+	}
+
+	// watched before court herald is called so a quick report is not missed
+	reported := make(chan struct{}, 1)
+	stop := events.Watch(events.DryRunsCollection, func(change events.Change) {
+		if change.ID == submission.ID {
+			select {
+			case reported <- struct{}{}:
+			default:
+			}
+		}
+	})
+	defer stop()
+
+	run, err := drm.Create(submission, time.Now().Add(-2*config.C.DryRunTimeout))
+	if err != nil {
+		return nil, false, "", err
+	}
+	defer drm.Delete(run.ID)
+
+	graders, err := GradersFor(assign)
+	if err != nil {
+		return nil, false, "", err
+	}
+
+	job, err := sm.DryRun(submission, assign.Job(uid), graders)
+	if err != nil {
+		return nil, false, "", err
+	}
+
+	timeout := time.After(config.C.DryRunTimeout)
+	poll := time.NewTicker(dryRunPoll)
+	defer poll.Stop()
+	for {
+		select {
+		case <-reported:
+		case <-poll.C:
+		case <-timeout:
+			return nil, false, job, errors.ErrorDryRunTimedOut
+		case <-ctx.Done():
+			return nil, false, job, errors.ErrorDryRunTimedOut
+		}
+
+		run, err = drm.Get(submission.ID)
+		if err == nil && run != nil && run.Report != nil {
+			return run.Report, run.Errored, job, nil
+		}
+	}
+}
+
+// DryRunAssignment grades a reference solution uploaded by staff against the
+// assignment's tests without recording a submission, and returns the results
+// once court herald is done.
+func DryRunAssignment(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	uid, _ := c.Get("uid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	sub, errs := c.FormFile("submission")
+	if errs != nil {
+		c.Set("error", errors.ErrorUploadingFile)
+		return
+	}
+
+	files, err := utils.CheckFileType(sub)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assign, err := am.Get(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if assign.Manual {
+		c.Set("error", errors.ErrorManuallyGraded)
+		return
+	}
+
+	if assign.Quiz != nil {
+		c.Set("error", errors.ErrorQuizAssignment)
+		return
+	}
+
+	fid := primitive.NewObjectID()
+	err = gfs.Upload(&fid, fmt.Sprintf("dryrun-%s.tar.gz", assign.ID.Hex()), bytes.NewReader(files))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+	defer gfs.Delete(fid)
+
+	report, errored, job, err := gradeDryRun(c.Request.Context(), assign, uid.(primitive.ObjectID), fid, sub.Filename)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":      "Dry Run Finished.",
+		"job":          job,
+		"errorTesting": errored,
+		"buildOutput":  report.BuildOutput,
+		"buildFailed":  report.BuildFailed,
+		"results":      report.Results,
+		"metrics":      report.Metrics,
+	})
+}
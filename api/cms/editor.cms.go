@@ -0,0 +1,163 @@
+package cms
+
+import (
+	"io/ioutil"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/errors"
+	"backend/forms"
+	"backend/models/cmsmodels/submissionmodels"
+	"backend/utils"
+)
+
+// maxEditorFileSize files larger than this are not loaded into the editor.
+const maxEditorFileSize = 512 * 1024
+
+// SubmitEditor submits the buffers of the in browser editor, keyed by their
+// path, instead of an upload. They are archived server side and graded like
+// an uploaded tarball, counting against the student's attempts.
+func SubmitEditor(c *gin.Context) {
+	var form forms.SubmitEditorForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	cid, _ := c.Get("cid")
+	uid, _ := c.Get("uid")
+	aid, _ := c.Get("aid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assign, err := am.Get(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if assign.Manual {
+		c.Set("error", errors.ErrorManualSubmissionType)
+		return
+	}
+
+	files := make(map[string][]byte, len(form.Files))
+	for name, content := range form.Files {
+		files[name] = []byte(content)
+	}
+
+	submissionFiles, err := utils.BuildArchive(files)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	receipt, err := submitFiles(c, aid, uid, submissionFiles, form.AcceptAttestation, nil)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(201, gin.H{
+		"status_code":          201,
+		"message":              "Submission Grader Started.",
+		"job":                  receipt.Job,
+		"submissionID":         receipt.SubmissionID,
+		"status":               receipt.status(),
+		"estimatedWaitSeconds": receipt.EstimatedWaitSeconds,
+	})
+}
+
+// editorSubmission the student's submission to load into the editor, the
+// attempt asked for or else their latest.
+func editorSubmission(c *gin.Context) (*submissionmodels.MongoSubmission, errors.APIError) {
+	cid, _ := c.Get("cid")
+	uid, _ := c.Get("uid")
+	aid, _ := c.Get("aid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		return nil, err
+	}
+
+	submissions, err := sm.GetUsersAssignmentSubmissions(aid, uid)
+	if err != nil {
+		return nil, err
+	}
+	if len(submissions) == 0 {
+		return nil, errors.ErrorResourceNotFound
+	}
+
+	submission := &submissions[len(submissions)-1]
+	if c.Query("attempt") != "" {
+		attempt, errs := strconv.Atoi(c.Query("attempt"))
+		if errs != nil {
+			return nil, errors.ErrorInvalidQuery
+		}
+
+		submission = nil
+		for index := range submissions {
+			if submissions[index].AttemptNumber == attempt {
+				submission = &submissions[index]
+			}
+		}
+	}
+
+	if submission == nil || submission.Manual || submission.FilePurged {
+		return nil, errors.ErrorResourceNotFound
+	}
+
+	return submission, nil
+}
+
+// EditorBuffers returns the files of a student's previous attempt, to prefill
+// the in browser editor. Binary files and ones too large to edit are listed
+// as omitted.
+func EditorBuffers(c *gin.Context) {
+	submission, err := editorSubmission(c)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	file, _, err := gfs.Download(submission.FileID)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	content, errs := ioutil.ReadAll(file)
+	if errs != nil {
+		c.Set("error", errors.ErrorFailedToReadFile)
+		return
+	}
+
+	archived, err := utils.ArchiveFiles(content, maxEditorFileSize)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	buffers := make(map[string]string, len(archived))
+	omitted := make([]string, 0)
+	for path, data := range archived {
+		if data == nil || isBinary(data) {
+			omitted = append(omitted, path)
+			continue
+		}
+		buffers[path] = string(data)
+	}
+	sort.Strings(omitted)
+
+	c.JSON(200, gin.H{
+		"message":       "Editor buffers.",
+		"submissionID":  submission.ID,
+		"attemptNumber": submission.AttemptNumber,
+		"files":         buffers,
+		"omitted":       omitted,
+	})
+}
@@ -0,0 +1,159 @@
+package cms
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/forms"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+// enrollUser adds a user to a course with the given level. Students joining a
+// course that has reached its enrollment cap are placed on the waitlist
+// instead, in which case waitlisted is true. Courses of an organization only
+// take its users.
+func enrollUser(level string, uid, cid interface{}) (bool, errors.APIError) {
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		return false, err
+	}
+
+	if course.Archived {
+		return false, errors.ErrorCourseArchived
+	}
+
+	if course.OrganizationID != nil {
+		user, err := um.FindOneById(uid)
+		if err != nil {
+			return false, err
+		}
+
+		if user.OrganizationID == nil || *user.OrganizationID != *course.OrganizationID {
+			return false, errors.ErrorOrganizationMismatch
+		}
+	}
+
+	if level == "student" && course.Full() {
+		alreadyEnrolled, _ := um.CourseExists(cid, uid)
+		if alreadyEnrolled {
+			return false, errors.ErrorUserAlreadyEnrolled
+		}
+
+		return true, cm.AddToWaitlist(cid, uid)
+	}
+
+	err = um.AddCourse(level, cid, uid)
+	if err != nil {
+		return false, err
+	}
+
+	return false, cm.AddUser(level, uid, cid)
+}
+
+// CourseWaitlist lists the students waiting for a seat, in admission order.
+func CourseWaitlist(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	waitlist := make([]gin.H, 0)
+	for position, entry := range course.Waitlist {
+		user, err := um.FindOneById(entry.UserID)
+		if err != nil {
+			continue
+		}
+
+		waitlist = append(waitlist, gin.H{
+			"position":  position + 1,
+			"userID":    entry.UserID,
+			"email":     user.Email,
+			"firstName": user.First,
+			"lastName":  user.Last,
+			"addedAt":   entry.AddedAt,
+		})
+	}
+
+	c.JSON(200, gin.H{
+		"message":       "Course waitlist.",
+		"maxEnrollment": course.MaxEnrollment,
+		"enrolled":      len(course.Students),
+		"waitlist":      waitlist,
+	})
+}
+
+// AdmitFromWaitlist enrolls the first count students on the waitlist, in the
+// order they joined it, and notifies them. No more are admitted than the
+// course has free seats.
+func AdmitFromWaitlist(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	var admit forms.WaitlistAdmitForm
+	if err := c.ShouldBindJSON(&admit); err != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+	if admit.Count <= 0 {
+		admit.Count = 1
+	}
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if seats := course.FreeSeats(); seats >= 0 && admit.Count > seats {
+		admit.Count = seats
+	}
+
+	admitted := make([]primitive.ObjectID, 0)
+	for _, entry := range course.Waitlist {
+		if len(admitted) == admit.Count {
+			break
+		}
+
+		err = cm.RemoveFromWaitlist(cid, entry.UserID)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		err = um.AddCourse("student", cid, entry.UserID)
+		if err != nil && err != errors.ErrorUserAlreadyEnrolled {
+			c.Set("error", err)
+			return
+		}
+
+		err = cm.AddUser("student", entry.UserID, cid)
+		if err != nil && err != errors.ErrorUserAlreadyEnrolled {
+			c.Set("error", err)
+			return
+		}
+
+		admitted = append(admitted, entry.UserID)
+	}
+
+	err = nm.Notify(
+		admitted,
+		cid,
+		"enrollment",
+		fmt.Sprintf("You have been admitted to %s %d from the waitlist.", course.Department, course.Number),
+		fmt.Sprintf("/course/%s", course.ID.Hex()),
+	)
+	if err != nil {
+		tyrgin.ErrorLogger(err, "Failed to notify admitted students.")
+	}
+
+	c.JSON(200, gin.H{
+		"message":  "Students Admitted.",
+		"admitted": admitted,
+	})
+}
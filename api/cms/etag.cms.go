@@ -0,0 +1,51 @@
+package cms
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/errors"
+)
+
+// etagMatches reports whether an If-None-Match header names etag, weak
+// comparison as the frontend only polls.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// conditionalJSON responds with body and an ETag of its contents, or 304 Not
+// Modified when the client already has it. The resources do not record when
+// they last changed, as a submission changes an assignment's view, so the
+// ETag is taken from the response itself: the query still runs, but a poll
+// that finds nothing new transfers nothing.
+func conditionalJSON(c *gin.Context, body interface{}) {
+	bs, err := json.Marshal(body)
+	if err != nil {
+		c.Set("error", errors.ErrorFailedToConvertStructToJSON)
+		return
+	}
+
+	sum := sha1.Sum(bs)
+	etag := `W/"` + hex.EncodeToString(sum[:]) + `"`
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", bs)
+}
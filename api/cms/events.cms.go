@@ -0,0 +1,51 @@
+package cms
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/events"
+	"backend/features"
+	"backend/models/cmsmodels/webhookmodels"
+	"backend/utils"
+)
+
+// SubscribeEvents hooks the side effects of domain events up to the event bus.
+// Called once at startup, before the server takes requests.
+func SubscribeEvents() {
+	events.Subscribe(events.SubmissionGradedEvent, func(event events.Event) {
+		graded := event.Data.(events.SubmissionGraded)
+		reportRepositoryStatus(graded.SubmissionID)
+		dispatchGraded(graded.SubmissionID)
+		recordGradingUsage(graded.SubmissionID)
+	})
+
+	events.Subscribe(events.AssignmentPublishedEvent, func(event events.Event) {
+		published := event.Data.(events.AssignmentPublished)
+		dispatchAssignmentEvent(published.AssignmentID, webhookmodels.EventAssignmentPublished, gin.H{
+			"assignmentID": published.AssignmentID,
+			"name":         published.Name,
+			"dueDate":      utils.DateTimeToTime(published.DueDate).Format(time.RFC3339),
+		})
+	})
+
+	events.Subscribe(events.EnrollmentAddedEvent, func(event events.Event) {
+		added := event.Data.(events.EnrollmentAdded)
+		dispatchEnrolled(added.CourseID, added.UserID, added.Role)
+	})
+
+	events.Subscribe(events.UserRegisteredEvent, func(event events.Event) {
+		registered := event.Data.(events.UserRegistered)
+		attachPendingEnrollments(registered.UserID, registered.Email)
+	})
+
+	events.Watch(events.SubmissionsCollection, func(change events.Change) {
+		forgetJob(change.ID)
+	})
+
+	// flags overridden through any replica apply on every replica at once
+	events.Watch(events.FeatureFlagsCollection, func(events.Change) {
+		features.Invalidate()
+	})
+}
@@ -0,0 +1,276 @@
+package cms
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/errors"
+	"backend/forms"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/models/cmsmodels/extensionmodels"
+	"backend/utils"
+)
+
+// notifyExtension lets the other side of an extension request know about it:
+// the student when staff decide it, the course staff when it is requested.
+func notifyExtension(request *extensionmodels.MongoExtensionRequest, staff bool, message string) {
+	course, err := cm.GetByID(request.CourseID)
+	if err != nil {
+		tyrgin.ErrorLogger(err, "Failed to notify extension request "+request.ID.Hex())
+		return
+	}
+
+	// requests go to the assistants of the student's section, when it has any
+	recipients := []primitive.ObjectID{request.UserID}
+	if !staff {
+		assistants := course.SectionAssistants(request.UserID)
+		if len(assistants) == 0 {
+			assistants = course.Assistants
+		}
+		recipients = append(append([]primitive.ObjectID{}, course.Professors...), assistants...)
+	}
+
+	err = nm.Notify(
+		recipients,
+		course.ID,
+		"extension",
+		fmt.Sprintf("%s %d: %s", course.Department, course.Number, message),
+		fmt.Sprintf("/course/%s/extension/%s", course.ID.Hex(), request.ID.Hex()),
+	)
+	if err != nil {
+		tyrgin.ErrorLogger(err, "Failed to notify extension request "+request.ID.Hex())
+	}
+}
+
+// getExtensionRequest finds an extension request the user may see, students
+// only their own.
+func getExtensionRequest(c *gin.Context) (*extensionmodels.MongoExtensionRequest, errors.APIError) {
+	erid, _ := c.Get("erid")
+	cid, _ := c.Get("cid")
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	request, err := exm.Get(erid, cid)
+	if err != nil || (role == "student" && request.UserID != uid) {
+		return nil, errors.ErrorResourceNotFound
+	}
+
+	return request, nil
+}
+
+// RequestExtension lets a student ask for a later due date on an assignment,
+// with their reason. A student can only have one pending request for an
+// assignment at a time.
+func RequestExtension(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	uid, _ := c.Get("uid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	var form forms.RequestExtensionForm
+	if errs := c.ShouldBindJSON(&form); errs != nil || strings.TrimSpace(form.Reason) == "" {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	assign, err := am.Get(aid)
+	if err != nil || !assign.Published {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	student := uid.(primitive.ObjectID)
+	if form.DueDate <= assign.DueDateFor(student) {
+		c.Set("error", errors.ErrorInvalidExtension)
+		return
+	}
+
+	pending, err := exm.FindPending(aid, uid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+	if pending != nil {
+		c.Set("error", errors.ErrorExtensionAlreadyRequested)
+		return
+	}
+
+	name, err := authorName(uid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	request, err := exm.Create(cid.(primitive.ObjectID), assign.ID, student, strings.TrimSpace(form.Reason), form.DueDate, time.Now())
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	notifyExtension(request, false, fmt.Sprintf("%s requested an extension on %s", name, assign.Name))
+
+	c.JSON(201, gin.H{
+		"message":          "Extension Requested.",
+		"extensionRequest": request,
+	})
+}
+
+// CourseExtensionRequests lists a course's extension requests, oldest first,
+// filtered with ?status=. Students only see their own, staff can filter to a
+// section with ?section= or ?mine=true.
+func CourseExtensionRequests(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	status := c.Query("status")
+	if status != "" && !extensionmodels.ValidStatus(status) {
+		c.Set("error", errors.ErrorInvalidQuery)
+		return
+	}
+
+	var student *primitive.ObjectID
+	if role == "student" {
+		id := uid.(primitive.ObjectID)
+		student = &id
+	}
+
+	requests, err := exm.Find(cid, student, status)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if role != "student" {
+		course, err := cm.GetByID(cid)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		students, err := sectionStudents(c, course)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		if students != nil {
+			filtered := requests[:0]
+			for _, request := range requests {
+				if students[request.UserID] {
+					filtered = append(filtered, request)
+				}
+			}
+			requests = filtered
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"message":           "Extension Requests.",
+		"extensionRequests": requests,
+	})
+}
+
+func GetExtensionRequest(c *gin.Context) {
+	request, err := getExtensionRequest(c)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":          "Extension Request.",
+		"extensionRequest": request,
+	})
+}
+
+// DecideExtension lets staff approve or deny a pending extension request.
+// Approving it gives the student the due date they asked for, or the one
+// given instead, as their extension on the assignment.
+func DecideExtension(c *gin.Context) {
+	uid, _ := c.Get("uid")
+
+	request, err := getExtensionRequest(c)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	var form forms.DecideExtensionForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+	if form.Status != extensionmodels.StatusApproved && form.Status != extensionmodels.StatusDenied {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	if request.Status != extensionmodels.StatusPending {
+		c.Set("error", errors.ErrorExtensionDecided)
+		return
+	}
+
+	assign, err := am.Get(request.AssignmentID)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	staffID := uid.(primitive.ObjectID)
+	var dueDate *primitive.DateTime
+	if form.Status == extensionmodels.StatusApproved {
+		granted := request.RequestedDueDate
+		if form.DueDate != nil {
+			granted = *form.DueDate
+		}
+		if granted <= assign.DueDate {
+			c.Set("error", errors.ErrorInvalidExtension)
+			return
+		}
+		dueDate = &granted
+	}
+
+	err = exm.Decide(request.ID, form.Status, staffID, dueDate, strings.TrimSpace(form.Message), time.Now())
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	message := fmt.Sprintf("your extension request on %s was denied", assign.Name)
+	if dueDate != nil {
+		err = am.SetExtension(assign.ID, assignmentmodels.Extension{
+			UserID:    request.UserID,
+			DueDate:   *dueDate,
+			GrantedBy: staffID,
+			RequestID: &request.ID,
+		})
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		message = fmt.Sprintf(
+			"your extension request on %s was approved, it is now due %s",
+			assign.Name,
+			utils.DateTimeToTime(*dueDate).Format(time.RFC1123),
+		)
+	}
+
+	notifyExtension(request, true, message)
+
+	c.JSON(200, gin.H{
+		"message": "Extension Request Decided.",
+		"status":  form.Status,
+		"dueDate": dueDate,
+	})
+}
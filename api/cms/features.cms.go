@@ -0,0 +1,123 @@
+package cms
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/features"
+	"backend/forms"
+)
+
+// featureEnabled reports whether a flag is on for the course of the request,
+// setting the error when it is not.
+func featureEnabled(c *gin.Context, name string) bool {
+	cid, _ := c.Get("cid")
+	if features.Enabled(name, cid.(primitive.ObjectID)) {
+		return true
+	}
+
+	c.Set("error", errors.ErrorFeatureDisabled)
+	return false
+}
+
+// CourseFeatures lists whether each flag is on for a course, for the frontend
+// to hide what is off.
+func CourseFeatures(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	c.JSON(200, gin.H{
+		"message":  "Course Features.",
+		"features": features.ForCourse(cid.(primitive.ObjectID)),
+	})
+}
+
+// FeatureFlags lists every flag with its default and overrides.
+func FeatureFlags(c *gin.Context) {
+	overrides := features.Overrides()
+
+	flags := make([]gin.H, 0)
+	for _, flag := range features.Flags {
+		entry := gin.H{
+			"name":        flag.Name,
+			"description": flag.Description,
+			"default":     features.Default(flag.Name),
+		}
+		if override, found := overrides[flag.Name]; found {
+			entry["enabled"] = override.Enabled
+			entry["courses"] = override.Courses
+		}
+
+		flags = append(flags, entry)
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Feature Flags.",
+		"flags":   flags,
+	})
+}
+
+// bindFlag the flag of the route and the override of the request.
+func bindFlag(c *gin.Context) (string, *bool, bool) {
+	name := c.Param("flag")
+	if !features.Known(name) {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return "", nil, false
+	}
+
+	var form forms.FeatureFlagForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return "", nil, false
+	}
+
+	return name, form.Enabled, true
+}
+
+// UpdateFeatureFlag turns a flag on or off for every course, or back to its
+// default when enabled is null.
+func UpdateFeatureFlag(c *gin.Context) {
+	uid, _ := c.Get("uid")
+
+	name, enabled, ok := bindFlag(c)
+	if !ok {
+		return
+	}
+
+	if err := fm.SetGlobal(name, enabled, uid); err != nil {
+		c.Set("error", err)
+		return
+	}
+	features.Invalidate()
+
+	c.JSON(200, gin.H{
+		"message": "Feature Flag Updated.",
+	})
+}
+
+// UpdateCourseFeatureFlag turns a flag on or off for one course, or removes the
+// course's override when enabled is null.
+func UpdateCourseFeatureFlag(c *gin.Context) {
+	uid, _ := c.Get("uid")
+	cid, _ := c.Get("cid")
+
+	name, enabled, ok := bindFlag(c)
+	if !ok {
+		return
+	}
+
+	if _, err := cm.GetByID(cid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if err := fm.SetCourse(name, cid.(primitive.ObjectID), enabled, uid); err != nil {
+		c.Set("error", err)
+		return
+	}
+	features.Invalidate()
+
+	c.JSON(200, gin.H{
+		"message": "Feature Flag Updated.",
+	})
+}
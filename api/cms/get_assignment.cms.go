@@ -1,22 +1,113 @@
 package cms
 
 import (
+	"strconv"
+
 	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/utils"
 )
 
+// staffSubmissionsQuery which students' submissions staff asked for: those
+// whose latest submission has the ?status=, sorted by ?sort=name, score or
+// date, - first for descending, and paged by ?page= and ?limit=. Every
+// student is returned when no limit is given.
+func staffSubmissionsQuery(c *gin.Context) (assignmentmodels.StaffSubmissionsQuery, errors.APIError) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil || limit < 0 || limit > 200 {
+		limit = 0
+	}
+
+	return assignmentmodels.NewStaffSubmissionsQuery(
+		c.Query("status"),
+		c.Query("sort"),
+		c.Query("includeWithdrawn") == "true",
+		int64((page-1)*limit),
+		int64(limit),
+	)
+}
+
+// GetAssignment shows an assignment, to students with their own submissions
+// and to staff with their students'.
 func GetAssignment(c *gin.Context) {
 	// lets verify assignment is in course in future?
 	aid, _ := c.Get("aid")
 	uid, _ := c.Get("uid")
 	role, _ := c.Get("role")
 
-	assignment, err := am.GetFull(aid, uid, role.(string))
+	if role != "student" {
+		query, err := staffSubmissionsQuery(c)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		assignment, err := am.GetFullForStaff(aid, query)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		if assignment != nil {
+			local := utils.Localize(assignment.DueDate, userLocation(c))
+			assignment.DueDateLocal = &local
+		}
+
+		conditionalJSON(c, gin.H{
+			"status_code": 200,
+			"msg":         "assignment.",
+			"assignment":  assignment,
+		})
+		return
+	}
+
+	assignment, err := am.GetFullForStudent(aid, uid)
 	if err != nil {
 		c.Set("error", err)
 		return
 	}
 
-	c.JSON(200, gin.H{
+	if assignment != nil {
+		assign, err := am.Get(aid)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		if err = checkPrerequisites(c, assign, uid); err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		if assign.Timed() {
+			assignment.Window = assign.Window(uid.(primitive.ObjectID))
+		}
+
+		if len(assign.Milestones) > 0 {
+			submissions, err := sm.GetUsersAssignmentSubmissions(aid, uid)
+			if err != nil {
+				c.Set("error", err)
+				return
+			}
+			_, assignment.MilestoneScores = assign.MilestoneGrade(submissions)
+		}
+
+		assignment.Tests = assign.TestsFor(uid.(primitive.ObjectID))
+		utils.Redact(assignment, utils.ViewStudent)
+
+		local := utils.Localize(assignment.DueDate, userLocation(c))
+		assignment.DueDateLocal = &local
+	}
+
+	conditionalJSON(c, gin.H{
 		"status_code": 200,
 		"msg":         "assignment.",
 		"assignment":  assignment,
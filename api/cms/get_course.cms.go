@@ -2,6 +2,7 @@ package cms
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
 )
 
 func GetCourse(c *gin.Context) {
@@ -9,14 +10,27 @@ func GetCourse(c *gin.Context) {
 	role, _ := c.Get("role")
 	uid, _ := c.Get("uid")
 
-	course, err := cm.Get(cid, uid, role.(string))
+	hidden := make([]primitive.ObjectID, 0)
+	if role == "student" {
+		locked, err := studentLockedAssignments(c, cid)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		for aid := range locked {
+			hidden = append(hidden, aid)
+		}
+	}
+
+	course, err := cm.Get(cid, uid, role.(string), hidden)
 	course["role"] = role
 	if err != nil {
 		c.Set("error", err)
 		return
 	}
 
-	c.JSON(200, gin.H{
+	conditionalJSON(c, gin.H{
 		"status_code": 200,
 		"msg":         "Course Info.",
 		"course":      course,
@@ -2,21 +2,32 @@ package cms
 
 import (
 	"github.com/gin-gonic/gin"
+
+	"backend/utils"
 )
 
 func GetSubmission(c *gin.Context) {
 	sid, _ := c.Get("sid")
 	role, _ := c.Get("role")
 
-	submission, err := sm.Get(sid, role.(string))
+	submission, err := sm.Get(sid, "any")
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assign, err := am.Get(submission.AssignmentID)
 	if err != nil {
 		c.Set("error", err)
 		return
 	}
+	submission.RedactFor(utils.ViewFor(role.(string)), assign.ShowHiddenSummary)
 
 	c.JSON(200, gin.H{
 		"status_code": 200,
 		"msg":         "submission.",
 		"submission":  submission,
+		"latePenalty": assign.LatePenalty(submission.UserID, submission.SubmissionDate),
+		"daysLate":    assign.DaysLate(submission.UserID, submission.SubmissionDate),
 	})
 }
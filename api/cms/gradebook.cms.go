@@ -0,0 +1,453 @@
+package cms
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/forms"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/models/cmsmodels/coursemodels"
+	"backend/models/cmsmodels/submissionmodels"
+	"backend/utils"
+)
+
+type (
+	// gradebookScore a student's grade on one assignment, out of 100, less
+	// the assignment's late penalty.
+	gradebookScore struct {
+		AssignmentID primitive.ObjectID `json:"assignmentID"`
+		Score        float64            `json:"score"`
+		Attempt      int                `json:"attempt"`
+	}
+
+	// gradebookRow a student's grades in a course. Total is the average of
+	// the regular assignments with extra credit scores added to the sum
+	// before dividing, plus bonus points. It can exceed 100. Curved is the
+	// total after the course's curve and Letter the grade it earns.
+	gradebookRow struct {
+		UserID      primitive.ObjectID `json:"userID"`
+		FirstName   string             `json:"firstName"`
+		LastName    string             `json:"lastName"`
+		Scores      []gradebookScore   `json:"scores"`
+		BonusPoints float64            `json:"bonusPoints"`
+		Total       float64            `json:"total"`
+		Curved      float64            `json:"curved"`
+		Letter      string             `json:"letter"`
+		// Attendance the percentage of sessions attended, absent when the
+		// course takes no attendance.
+		Attendance *float64 `json:"attendance,omitempty"`
+	}
+
+	gradebookAssignment struct {
+		ID          primitive.ObjectID `json:"id"`
+		Name        string             `json:"name"`
+		ExtraCredit bool               `json:"extraCredit"`
+	}
+)
+
+// buildGradebook computes every student's latest grade on each published
+// assignment of the course and their total, curved and lettered by the scale.
+func buildGradebook(course *coursemodels.MongoCourse, scale coursemodels.GradeScale) ([]gradebookAssignment, []gradebookRow, errors.APIError) {
+	assignments := make([]gradebookAssignment, 0)
+	milestoned := make(map[primitive.ObjectID]*assignmentmodels.MongoAssignment)
+	latePolicies := make(map[primitive.ObjectID]*assignmentmodels.MongoAssignment)
+	regular := 0
+	for _, aid := range course.Assignments {
+		assign, err := am.Get(aid)
+		if err != nil || !assign.Published {
+			continue
+		}
+		if len(assign.Milestones) > 0 {
+			milestoned[assign.ID] = assign
+		} else if assign.LatePolicy != nil {
+			latePolicies[assign.ID] = assign
+		}
+
+		assignments = append(assignments, gradebookAssignment{assign.ID, assign.Name, assign.ExtraCredit})
+		if !assign.ExtraCredit {
+			regular++
+		}
+	}
+
+	aids := make([]primitive.ObjectID, len(assignments))
+	for index, assign := range assignments {
+		aids[index] = assign.ID
+	}
+
+	submissions, err := sm.ReportByAssignmentIDs(aids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sessions, err := atm.FindByCourse(course.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// submissions are sorted oldest first, so the latest attempt wins
+	latest := make(map[primitive.ObjectID]map[primitive.ObjectID]gradebookScore)
+	byMilestones := make(map[primitive.ObjectID]map[primitive.ObjectID][]submissionmodels.MongoSubmission)
+	for _, submission := range submissions {
+		if submission.Withdrawn {
+			continue
+		}
+		if latest[submission.UserID] == nil {
+			latest[submission.UserID] = make(map[primitive.ObjectID]gradebookScore)
+			byMilestones[submission.UserID] = make(map[primitive.ObjectID][]submissionmodels.MongoSubmission)
+		}
+		score := submission.Score()
+		// staff overrides stand as given
+		if assign := latePolicies[submission.AssignmentID]; assign != nil && submission.GradeOverride == nil {
+			score = math.Max(0, score-assign.LatePenalty(submission.UserID, submission.SubmissionDate))
+		}
+		latest[submission.UserID][submission.AssignmentID] = gradebookScore{
+			submission.AssignmentID,
+			score,
+			submission.AttemptNumber,
+		}
+		if milestoned[submission.AssignmentID] != nil {
+			byMilestones[submission.UserID][submission.AssignmentID] = append(byMilestones[submission.UserID][submission.AssignmentID], submission)
+		}
+	}
+
+	// assignments with milestones are graded on every submission made in time
+	for uid, assignSubmissions := range byMilestones {
+		for aid, submitted := range assignSubmissions {
+			score := latest[uid][aid]
+			score.Score, _ = milestoned[aid].MilestoneGrade(submitted)
+			latest[uid][aid] = score
+		}
+	}
+
+	rows := make([]gradebookRow, 0, len(course.Students))
+	for _, suid := range course.Students {
+		student, err := um.FindOneById(suid)
+		if err != nil {
+			continue
+		}
+
+		row := gradebookRow{
+			UserID:      student.ID,
+			FirstName:   student.First,
+			LastName:    student.Last,
+			Scores:      make([]gradebookScore, 0, len(assignments)),
+			BonusPoints: course.BonusPoints(student.ID),
+			Attendance:  attendanceRate(sessions, student.ID),
+		}
+
+		sum := 0.0
+		for _, assign := range assignments {
+			score, found := latest[student.ID][assign.ID]
+			if !found {
+				score = gradebookScore{AssignmentID: assign.ID}
+			}
+			row.Scores = append(row.Scores, score)
+			sum += score.Score
+		}
+
+		if regular > 0 {
+			row.Total = sum / float64(regular)
+		}
+		row.Total += row.BonusPoints
+
+		rows = append(rows, row)
+	}
+
+	totals := make([]float64, len(rows))
+	for index, row := range rows {
+		totals[index] = row.Total
+	}
+	for index, curved := range scale.Apply(totals) {
+		rows[index].Curved = curved
+		rows[index].Letter = scale.Letter(curved)
+	}
+
+	return assignments, rows, nil
+}
+
+// filterGradebook keeps the rows of the students, every row for nil.
+func filterGradebook(rows []gradebookRow, students map[primitive.ObjectID]bool) []gradebookRow {
+	if students == nil {
+		return rows
+	}
+
+	filtered := make([]gradebookRow, 0, len(students))
+	for _, row := range rows {
+		if students[row.UserID] {
+			filtered = append(filtered, row)
+		}
+	}
+
+	return filtered
+}
+
+// Gradebook shows every student's latest grade on each published assignment
+// of the course and their course total. Students only see their own row,
+// staff can filter to a section with ?section= or ?mine=true.
+func Gradebook(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	scale := course.Scale()
+	// the curve depends on the whole class, so rows are filtered afterwards
+	assignments, rows, err := buildGradebook(course, scale)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if role == "student" {
+		own := make([]gradebookRow, 0, 1)
+		for _, row := range rows {
+			if row.UserID == uid {
+				own = append(own, row)
+			}
+		}
+		rows = own
+	} else {
+		students, err := sectionStudents(c, course)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+		rows = filterGradebook(rows, students)
+	}
+
+	conditionalJSON(c, gin.H{
+		"message":     "Course gradebook.",
+		"assignments": assignments,
+		"students":    rows,
+		"gradeScale":  scale,
+	})
+}
+
+// gradebookCSV writes the gradebook with a column per assignment.
+func gradebookCSV(assignments []gradebookAssignment, rows []gradebookRow) (*bytes.Buffer, errors.APIError) {
+	header := []string{"First Name", "Last Name"}
+	for _, assign := range assignments {
+		header = append(header, assign.Name)
+	}
+	header = append(header, "Bonus", "Total", "Curved", "Letter")
+	// every row has attendance when the course takes it
+	attendance := len(rows) > 0 && rows[0].Attendance != nil
+	if attendance {
+		header = append(header, "Attendance")
+	}
+
+	records := [][]string{header}
+	for _, row := range rows {
+		record := []string{row.FirstName, row.LastName}
+		for _, score := range row.Scores {
+			record = append(record, strconv.FormatFloat(score.Score, 'f', 2, 64))
+		}
+		record = append(
+			record,
+			strconv.FormatFloat(row.BonusPoints, 'f', -1, 64),
+			strconv.FormatFloat(row.Total, 'f', 2, 64),
+			strconv.FormatFloat(row.Curved, 'f', 2, 64),
+			row.Letter,
+		)
+		if attendance {
+			record = append(record, strconv.FormatFloat(*row.Attendance, 'f', 2, 64))
+		}
+		records = append(records, record)
+	}
+
+	buf := &bytes.Buffer{}
+	if errs := csv.NewWriter(buf).WriteAll(records); errs != nil {
+		return nil, errors.ErrorFailedToWriteCSV
+	}
+
+	return buf, nil
+}
+
+// GradebookAsCSV downloads the course gradebook with curved totals and letter
+// grades.
+func GradebookAsCSV(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assignments, rows, err := buildGradebook(course, course.Scale())
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	students, err := sectionStudents(c, course)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	file, err := gradebookCSV(assignments, filterGradebook(rows, students))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	additonalHeaders := map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s-%d-%s-gradebook.csv"`, course.Department, course.Number, course.Section),
+	}
+
+	c.DataFromReader(200, int64(file.Len()), "text/csv", file, additonalHeaders)
+}
+
+// UpdateGradeScale sets the course's letter grade cutoffs and curve. With
+// preview the gradebook the scale would produce is returned without saving it.
+func UpdateGradeScale(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	var form forms.UpdateGradeScaleForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	scale, err := coursemodels.NewGradeScale(form)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if form.Preview {
+		assignments, rows, err := buildGradebook(course, *scale)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"message":     "Grade Scale Preview.",
+			"assignments": assignments,
+			"students":    rows,
+			"gradeScale":  scale,
+		})
+		return
+	}
+
+	err = cm.SetGradeScale(cid, scale)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":    "Grade Scale Updated.",
+		"gradeScale": scale,
+	})
+}
+
+// CourseBonuses lists the bonus points given in a course.
+func CourseBonuses(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	bonuses := course.Bonuses
+	if bonuses == nil {
+		bonuses = make([]coursemodels.Bonus, 0)
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Course bonuses.",
+		"bonuses": bonuses,
+	})
+}
+
+// GrantBonus gives a student bonus points towards their course total.
+// Negative points take points away.
+func GrantBonus(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	uid, _ := c.Get("uid")
+	suid, _ := c.Get("suid")
+
+	var grant forms.GrantBonusForm
+	if errs := c.ShouldBindJSON(&grant); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	enrolled := false
+	for _, student := range course.Students {
+		if student == suid {
+			enrolled = true
+		}
+	}
+	if !enrolled {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	bonus := coursemodels.Bonus{
+		ID:        primitive.NewObjectID(),
+		UserID:    suid.(primitive.ObjectID),
+		Points:    grant.Points,
+		Reason:    grant.Reason,
+		GrantedBy: uid.(primitive.ObjectID),
+		GrantedAt: utils.TimeToDateTime(time.Now()),
+	}
+
+	err = cm.AddBonus(cid, bonus)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(201, gin.H{
+		"status_code": 201,
+		"message":     "Bonus Granted.",
+		"bonus":       bonus,
+	})
+}
+
+// RevokeBonus takes back bonus points.
+func RevokeBonus(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	bid, _ := c.Get("bid")
+
+	err := cm.RemoveBonus(cid, bid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Bonus Revoked.",
+	})
+}
@@ -0,0 +1,202 @@
+package cms
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/courtherald"
+	"backend/errors"
+	"backend/forms"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/models/gradermodels"
+)
+
+// GradersFor the graders an assignment's submissions are sent to, in the
+// order they are tried. The registered graders whose rules match come first,
+// then COURT_HERALD_URL unless the assignment has requirements only a
+// registered grader can meet.
+func GradersFor(assign *assignmentmodels.MongoAssignment) ([]string, errors.APIError) {
+	department := ""
+	if course, err := cm.FindByAssignment(assign.ID); err == nil {
+		department = course.Department
+	}
+
+	graders, err := grm.Find()
+	if err != nil {
+		return nil, err
+	}
+
+	urls := gradermodels.Route(graders, assign.Language, department, assign.Requirements)
+	if len(assign.Requirements) == 0 {
+		urls = append(urls, "")
+	}
+
+	if len(urls) == 0 {
+		return nil, errors.ErrorNoGraderAvailable
+	}
+
+	return urls, nil
+}
+
+// splitList a comma separated list, without blanks.
+func splitList(list string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// applyGraderForm sets the fields given in the form on a grader.
+func applyGraderForm(grader *gradermodels.MongoGrader, form forms.GraderForm) errors.APIError {
+	if form.Name != nil {
+		grader.Name = strings.TrimSpace(*form.Name)
+	}
+	if form.URL != nil {
+		grader.URL = strings.TrimSuffix(strings.TrimSpace(*form.URL), "/")
+	}
+	if form.Labels != nil {
+		grader.Labels = splitList(*form.Labels)
+	}
+	if form.Languages != nil {
+		grader.Languages = splitList(*form.Languages)
+	}
+	if form.Departments != nil {
+		grader.Departments = splitList(*form.Departments)
+	}
+	if form.Priority != nil {
+		grader.Priority = *form.Priority
+	}
+	if form.Enabled != nil {
+		grader.Enabled = *form.Enabled
+	}
+
+	u, err := url.Parse(grader.URL)
+	if grader.Name == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.ErrorInvalidGrader
+	}
+
+	return nil
+}
+
+// Graders lists the registered graders with their health.
+func Graders(c *gin.Context) {
+	graders, err := grm.Find()
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Graders.",
+		"default": courtherald.URL(""),
+		"graders": graders,
+	})
+}
+
+// CreateGrader registers a court herald cluster and the rules of what it
+// grades.
+func CreateGrader(c *gin.Context) {
+	var form forms.GraderForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	grader := gradermodels.MongoGrader{
+		Labels:      make([]string, 0),
+		Languages:   make([]string, 0),
+		Departments: make([]string, 0),
+		Enabled:     true,
+	}
+	if err := applyGraderForm(&grader, form); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	created, err := grm.Create(grader)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(201, gin.H{
+		"message": "Grader Created.",
+		"grader":  created,
+	})
+}
+
+// UpdateGrader changes a grader's URL, rules or priority, or disables it.
+func UpdateGrader(c *gin.Context) {
+	grid, _ := c.Get("grid")
+
+	var form forms.GraderForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	grader, err := grm.Get(grid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if err = applyGraderForm(grader, form); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if err = grm.Update(*grader); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Grader Updated.",
+		"grader":  grader,
+	})
+}
+
+// DeleteGrader unregisters a grader. Jobs it is running keep reporting back
+// and can still be looked up.
+func DeleteGrader(c *gin.Context) {
+	grid, _ := c.Get("grid")
+
+	if err := grm.Delete(grid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Grader Deleted.",
+	})
+}
+
+// CheckGraderHealth is a job that checks every registered grader answers,
+// so submissions are routed to healthy ones first.
+func CheckGraderHealth([]byte) error {
+	graders, err := grm.Find()
+	if err != nil {
+		return err
+	}
+
+	for _, grader := range graders {
+		problem := ""
+		if err := courtherald.Health(grader.URL); err != nil {
+			problem = err.Error()
+		}
+
+		if err := grm.SetHealth(grader.ID, problem); err != nil {
+			tyrgin.ErrorLogger(err, "Failed to record the health of grader "+grader.Name)
+		}
+	}
+
+	return nil
+}
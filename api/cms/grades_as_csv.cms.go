@@ -11,7 +11,7 @@ func GradesAsCSV(c *gin.Context) {
 	aid, _ := c.Get("aid")
 	cid, _ := c.Get("cid")
 
-	file, filename, numBytes, err := cm.GetGradesAsCSV(aid, cid)
+	file, filename, numBytes, err := cm.GetGradesAsCSV(aid, cid, c.Query("includeWithdrawn") == "true")
 	if err != nil {
 		c.Set("error", errors.ErrorFailedToWriteCSV)
 	}
@@ -0,0 +1,255 @@
+package cms
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/forms"
+	"backend/models/cmsmodels/coursemodels"
+	"backend/models/cmsmodels/gradingmodels"
+	"backend/models/cmsmodels/submissionmodels"
+	"backend/utils"
+)
+
+// graderProgress how far a grader is with their share of an assignment.
+type graderProgress struct {
+	GraderID  primitive.ObjectID `json:"graderID"`
+	FirstName string             `json:"firstName"`
+	LastName  string             `json:"lastName"`
+	Assigned  int                `json:"assigned"`
+	Completed int                `json:"completed"`
+}
+
+// canGrade reports whether a staff member may grade a student, assistants only
+// their sections' students when grading is scoped to sections.
+func canGrade(course *coursemodels.MongoCourse, grader, suid primitive.ObjectID) bool {
+	for _, professor := range course.Professors {
+		if professor == grader {
+			return true
+		}
+	}
+
+	return course.AssistantGrades(grader, suid)
+}
+
+// distributeGrading gives each submission to the grader with the fewest open
+// tasks who may grade its student, the graders' order breaking ties.
+// Submissions nobody may grade are returned.
+func distributeGrading(course *coursemodels.MongoCourse, aid primitive.ObjectID, graders []primitive.ObjectID, existing []gradingmodels.MongoGradingTask, pending []submissionmodels.LatestSubmission) ([]gradingmodels.MongoGradingTask, []primitive.ObjectID) {
+	load := make(map[primitive.ObjectID]int)
+	for _, task := range existing {
+		if !task.Done() {
+			load[task.GraderID]++
+		}
+	}
+
+	tasks := make([]gradingmodels.MongoGradingTask, 0, len(pending))
+	unassigned := make([]primitive.ObjectID, 0)
+	for _, submission := range pending {
+		chosen := -1
+		for index, grader := range graders {
+			if !canGrade(course, grader, submission.UserID) {
+				continue
+			}
+			if chosen < 0 || load[grader] < load[graders[chosen]] {
+				chosen = index
+			}
+		}
+
+		if chosen < 0 {
+			unassigned = append(unassigned, submission.UserID)
+			continue
+		}
+
+		grader := graders[chosen]
+		load[grader]++
+		tasks = append(tasks, gradingmodels.NewTask(course.ID, aid, submission.SubmissionID, submission.UserID, grader))
+	}
+
+	return tasks, unassigned
+}
+
+// DistributeGrading splits the latest submissions of an assignment that no one
+// is grading yet evenly among the graders given, who must be staff of the
+// course. Students already given to a grader keep them.
+func DistributeGrading(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	var form forms.DistributeGradingForm
+	if errs := c.ShouldBindJSON(&form); errs != nil || len(form.Graders) == 0 {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	staff := append(append([]primitive.ObjectID{}, course.Professors...), course.Assistants...)
+	for _, grader := range form.Graders {
+		found := false
+		for _, member := range staff {
+			found = found || member == grader
+		}
+		if !found {
+			c.Set("error", errors.ErrorInvalidGraders)
+			return
+		}
+	}
+
+	latest, err := sm.LatestPerStudent(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	existing, err := gtm.FindByAssignment(aid, nil)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assigned := make(map[primitive.ObjectID]bool)
+	for _, task := range existing {
+		assigned[task.StudentID] = true
+	}
+
+	pending := make([]submissionmodels.LatestSubmission, 0)
+	for _, submission := range latest {
+		if !assigned[submission.UserID] {
+			pending = append(pending, submission)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].UserID.Hex() < pending[j].UserID.Hex()
+	})
+
+	tasks, unassigned := distributeGrading(course, aid.(primitive.ObjectID), form.Graders, existing, pending)
+	err = gtm.Create(tasks)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":    "Grading Distributed.",
+		"tasks":      tasks,
+		"unassigned": unassigned,
+	})
+}
+
+// GradingProgress shows each grader's progress on an assignment and the tasks,
+// only the user's own with ?mine=true.
+func GradingProgress(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	uid, _ := c.Get("uid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	tasks, err := gtm.FindByAssignment(aid, nil)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	progress := make([]*graderProgress, 0)
+	byGrader := make(map[primitive.ObjectID]*graderProgress)
+	for _, task := range tasks {
+		grader, found := byGrader[task.GraderID]
+		if !found {
+			grader = &graderProgress{GraderID: task.GraderID}
+			if user, err := um.FindOneById(task.GraderID); err == nil {
+				grader.FirstName, grader.LastName = user.First, user.Last
+			}
+			byGrader[task.GraderID] = grader
+			progress = append(progress, grader)
+		}
+
+		grader.Assigned++
+		if task.Done() {
+			grader.Completed++
+		}
+	}
+
+	if c.Query("mine") == "true" {
+		own := make([]gradingmodels.MongoGradingTask, 0)
+		for _, task := range tasks {
+			if task.GraderID == uid {
+				own = append(own, task)
+			}
+		}
+		tasks = own
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Grading Progress.",
+		"graders": progress,
+		"tasks":   tasks,
+	})
+}
+
+// CompleteGrading marks a submission's grading task done, giving it the grade
+// when one is sent.
+func CompleteGrading(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	sid, _ := c.Get("sid")
+	uid, _ := c.Get("uid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	var form forms.CompleteGradingForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	task, err := gtm.Get(aid, sid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	staffID := uid.(primitive.ObjectID)
+	if form.Grade != nil {
+		err = sm.OverrideGrade(task.SubmissionID, submissionmodels.GradeOverride{
+			Grade:  *form.Grade,
+			Reason: form.Reason,
+			By:     staffID,
+			At:     utils.TimeToDateTime(time.Now()),
+		})
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+	}
+
+	err = gtm.Complete(aid, sid, staffID)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Grading Completed.",
+	})
+}
@@ -0,0 +1,156 @@
+package cms
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/config"
+	"backend/errors"
+	"backend/forms"
+	"backend/jobs"
+	"backend/models/cmsmodels/submissionmodels"
+	"backend/models/heraldmodels"
+	"backend/utils"
+)
+
+// heraldStatus the status court herald last reported, nil when it is missing
+// or too old to tell anything about the load.
+func heraldStatus() *heraldmodels.MongoHeraldStatus {
+	status, err := hm.Get()
+	if err != nil || !status.Fresh(config.C.HeraldStatusMaxAge) {
+		return nil
+	}
+
+	return status
+}
+
+// gradingOverloaded whether new submissions should be held back rather than
+// sent to court herald.
+func gradingOverloaded() bool {
+	status := heraldStatus()
+	return status != nil && status.Overloaded(config.C.GradingQueueThreshold)
+}
+
+// estimatedWait the seconds a held submission is likely to wait before it
+// starts grading, 0 when court herald has not reported recently.
+func estimatedWait(submission *submissionmodels.MongoSubmission) int {
+	status := heraldStatus()
+	if status == nil || !submission.Queued {
+		return 0
+	}
+
+	ahead, err := sm.HeldBefore(submission.SubmissionDate)
+	if err != nil {
+		return 0
+	}
+
+	return int(status.EstimatedWait(ahead) / time.Second)
+}
+
+// ReportHeraldCapacity is called by court herald to report how many jobs it is
+// running, how many it can run and how many are waiting for a slot.
+func ReportHeraldCapacity(c *gin.Context) {
+	key := c.Param("secret")
+	if key != config.C.JobSecret {
+		c.Set("error", errors.ErrorInvalidJobSecret)
+		return
+	}
+
+	var form forms.HeraldCapacityForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	status := heraldmodels.MongoHeraldStatus{
+		Running:           form.Running,
+		Capacity:          form.Capacity,
+		Queued:            form.Queued,
+		AverageJobSeconds: form.AverageJobSeconds,
+	}
+	if err := hm.Report(status); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	held, err := sm.HeldBefore(utils.TimeToDateTime(time.Now()))
+	if err == nil && held > 0 && !status.Overloaded(config.C.GradingQueueThreshold) {
+		if _, errs := jobs.Enqueue("submissions.release", nil); errs != nil {
+			tyrgin.ErrorLogger(errs, "Failed to queue the release of held submissions")
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Capacity Reported.",
+		"held":    held,
+	})
+}
+
+// ReleaseHeldSubmissions is a job that sends the submissions held back while
+// court herald was overloaded or unreachable, oldest first, as far as its
+// capacity allows.
+// Without a recent status, or with the threshold disabled, all of them are
+// sent. It fails, to be retried, while court herald is unreachable.
+func ReleaseHeldSubmissions([]byte) error {
+	var limit int64
+	if status := heraldStatus(); status != nil && config.C.GradingQueueThreshold > 0 {
+		limit = int64(status.Free(config.C.GradingQueueThreshold))
+		if limit == 0 {
+			return nil
+		}
+	}
+
+	submissions, err := sm.Held(limit)
+	if err != nil {
+		return err
+	}
+
+	for _, submission := range submissions {
+		assign, err := am.Get(submission.AssignmentID)
+		if err != nil {
+			failGrading(submission, "The assignment for this submission no longer exists.")
+			continue
+		}
+
+		graders, err := GradersFor(assign)
+		if err == nil {
+			_, err = sm.Release(submission, assign.Job(submission.UserID), graders)
+		}
+		if err == errors.ErrorUnableToReachMicroService {
+			return err
+		}
+		if err != nil {
+			tyrgin.ErrorLogger(err, "Failed to release submission "+submission.ID.Hex())
+		}
+	}
+
+	return nil
+}
+
+// GradingCapacity shows admins court herald's last reported load, how many
+// submissions are held back and how the calls to court herald are going.
+func GradingCapacity(c *gin.Context) {
+	status, err := hm.Get()
+	if err != nil && err != errors.ErrorResourceNotFound {
+		c.Set("error", err)
+		return
+	}
+
+	held, err := sm.HeldBefore(utils.TimeToDateTime(time.Now()))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":    "Grading Capacity.",
+		"status":     status,
+		"fresh":      status != nil && status.Fresh(config.C.HeraldStatusMaxAge),
+		"overloaded": gradingOverloaded(),
+		"threshold":  config.C.GradingQueueThreshold,
+		"held":       held,
+		"graders":    utils.CourtHeraldStats(),
+	})
+}
@@ -0,0 +1,69 @@
+package cms
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/api/auth"
+	"backend/errors"
+)
+
+// ImpersonateStudent gives a staff member a short lived token to view the
+// course as one of its students, published state, visible tests and results
+// filtered as the student sees them. The token can only read this course.
+func ImpersonateStudent(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	cids, _ := c.Get("cids")
+	uid, _ := c.Get("uid")
+	suid, _ := c.Get("suid")
+
+	student, err := um.FindOneById(suid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if student.CoursesAsMap()[cids.(string)] != "student" {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	staff := uid.(primitive.ObjectID)
+	tokenString, expire, err := auth.ImpersonationToken(student, staff, cid.(primitive.ObjectID))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = imm.Log(cid.(primitive.ObjectID), staff, student.ID, c.Request.Method, c.Request.URL.RequestURI())
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":       "Impersonation Started.",
+		"impersonating": student.ID,
+		"token":         tokenString,
+		"expire":        expire.Format(time.RFC3339),
+	})
+}
+
+// Impersonations the log of every request staff made while viewing the
+// course as a student, newest first.
+func Impersonations(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	entries, err := imm.FindByCourse(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":        "Impersonations Found.",
+		"impersonations": entries,
+	})
+}
@@ -0,0 +1,183 @@
+package cms
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/errors"
+	"backend/forms"
+	"backend/forms/cmsforms"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/utils"
+)
+
+// assignmentImporter maps an assignment definition of another autograder onto
+// an assignment, along with warnings of what did not carry over and needs
+// a look before it is saved.
+type assignmentImporter func(content []byte) (forms.CreateAssignmentPostForm, []string, errors.APIError)
+
+// assignmentImporters the formats assignments can be imported from.
+var assignmentImporters = map[string]assignmentImporter{
+	"githubClassroom": importGitHubClassroom,
+	"gradescope":      importGradescope,
+}
+
+// classroomAutograding GitHub Classroom's .github/classroom/autograding.json.
+type classroomAutograding struct {
+	Tests []struct {
+		Name       string  `json:"name"`
+		Setup      string  `json:"setup"`
+		Run        string  `json:"run"`
+		Input      string  `json:"input"`
+		Output     string  `json:"output"`
+		Comparison string  `json:"comparison"`
+		Timeout    float64 `json:"timeout"`
+		Points     float64 `json:"points"`
+	} `json:"tests"`
+}
+
+// importGitHubClassroom maps each autograding test onto a test. Setup commands
+// become the build command, and "included" comparisons a regex matching
+// output that contains the expected output.
+func importGitHubClassroom(content []byte) (forms.CreateAssignmentPostForm, []string, errors.APIError) {
+	var form forms.CreateAssignmentPostForm
+	var autograding classroomAutograding
+	if err := json.Unmarshal(content, &autograding); err != nil || len(autograding.Tests) == 0 {
+		return form, nil, errors.ErrorInvalidImport
+	}
+
+	warnings := make([]string, 0)
+	setups := make([]string, 0)
+	points := autograding.Tests[0].Points
+	for _, test := range autograding.Tests {
+		if test.Setup != "" && !containsString(setups, test.Setup) {
+			setups = append(setups, test.Setup)
+		}
+
+		imported := cmsforms.CreateAssignmentTest{
+			Name:           test.Name,
+			ExpectedOutput: test.Output,
+			StudentFacing:  true,
+			TestCMD:        test.Run,
+		}
+		switch test.Comparison {
+		case "exact", "":
+			imported.Match = assignmentmodels.MatchExact
+		case "regex":
+			imported.Match = assignmentmodels.MatchRegex
+		case "included":
+			imported.Match = assignmentmodels.MatchRegex
+			imported.ExpectedOutput = "(?s).*" + regexp.QuoteMeta(test.Output) + ".*"
+		default:
+			warnings = append(warnings, fmt.Sprintf("%s: unknown comparison %q, compared exactly", test.Name, test.Comparison))
+		}
+
+		if test.Input != "" {
+			warnings = append(warnings, fmt.Sprintf("%s: reads input, upload it as a fixture and set it as the test's input fixture", test.Name))
+		}
+		if test.Points != points {
+			warnings = append(warnings, fmt.Sprintf("%s: is worth %g points, every test counts the same here", test.Name, test.Points))
+		}
+
+		form.Tests = append(form.Tests, imported)
+	}
+	form.TestBuildCMD = strings.Join(setups, " && ")
+	warnings = append(warnings, "Timeouts are not imported, every test runs with the grader's own.")
+
+	return form, warnings, nil
+}
+
+// importGradescope maps a Gradescope autograder archive onto a single test
+// running it. Gradescope autograders score themselves, so what the test
+// should print has to be filled in.
+func importGradescope(content []byte) (forms.CreateAssignmentPostForm, []string, errors.APIError) {
+	var form forms.CreateAssignmentPostForm
+	paths, err := utils.ArchivePaths(content)
+	if err != nil || !containsString(paths, "run_autograder") {
+		return form, nil, errors.ErrorInvalidImport
+	}
+
+	warnings := []string{
+		"Upload the autograder archive as the assignment's supporting files.",
+		"Gradescope autograders report their own scores, fill in the output run_autograder should print, or split it into one test per check.",
+	}
+	if containsString(paths, "setup.sh") {
+		form.TestBuildCMD = "bash setup.sh"
+	}
+	form.Tests = []cmsforms.CreateAssignmentTest{{
+		Name:          "run_autograder",
+		StudentFacing: true,
+		TestCMD:       "bash run_autograder",
+		Match:         assignmentmodels.MatchTrimmed,
+	}}
+
+	return form, warnings, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PreviewAssignmentImport maps an assignment definition of another autograder,
+// named by format, onto an assignment without saving it. The assignment in
+// the response is reviewed, completed, and then created by uploading it to
+// course/:cid/assignment/create/file.
+func PreviewAssignmentImport(c *gin.Context) {
+	importer, found := assignmentImporters[c.PostForm("format")]
+	if !found {
+		c.Set("error", errors.ErrorUnsupportedImportFormat)
+		return
+	}
+
+	file, errs := c.FormFile("file")
+	if errs != nil {
+		c.Set("error", errors.ErrorFileDNE)
+		return
+	}
+
+	f, errs := file.Open()
+	if errs != nil {
+		c.Set("error", errors.ErrorFailedToOpenFile)
+		return
+	}
+	defer f.Close()
+
+	content, errs := ioutil.ReadAll(f)
+	if errs != nil {
+		c.Set("error", errors.ErrorFailedToReadFile)
+		return
+	}
+
+	form, warnings, err := importer(content)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	form.Name = c.PostForm("name")
+	form.Language = c.PostForm("language")
+	form.NumAttempts = 1
+	for _, missing := range []struct{ field, value string }{{"name", form.Name}, {"language", form.Language}} {
+		if missing.value == "" {
+			warnings = append(warnings, fmt.Sprintf("Set the assignment's %s.", missing.field))
+		}
+	}
+	warnings = append(warnings, "Set the assignment's description and due date.")
+
+	c.JSON(200, gin.H{
+		"message":    "Assignment Import Previewed.",
+		"assignment": form,
+		"warnings":   warnings,
+	})
+}
@@ -4,8 +4,29 @@ import (
 	"backend/models"
 )
 
+var anm = models.NewMongoAnnouncementInterface()
 var am = models.NewMongoAssignmentInterface()
+var atm = models.NewMongoAttendanceInterface()
+var cdm = models.NewMongoCodeInterface()
 var cm = models.NewMongoCourseInterface()
+var dm = models.NewMongoDiscussionInterface()
+var dsm = models.NewMongoDisputeInterface()
+var dfm = models.NewMongoDraftInterface()
+var drm = models.NewMongoDryRunInterface()
+var exm = models.NewMongoExtensionInterface()
+var fm = models.NewMongoFeatureFlagInterface()
+var gtm = models.NewMongoGradingInterface()
+var grm = models.NewMongoGraderInterface()
+var hm = models.NewMongoHeraldInterface()
+var imm = models.NewMongoImpersonationInterface()
 var gfs = models.NewGridFSInterface()
+var jm = models.NewMongoJobInterface()
+var mdm = models.NewMongoModuleInterface()
+var nm = models.NewMongoNotificationInterface()
+var om = models.NewMongoOrganizationInterface()
+var qm = models.NewMongoQuizInterface()
+var regm = models.NewMongoRegradeInterface()
 var um = models.NewMongoUserInterface()
 var sm = models.NewMongoSubmissionInterface()
+var tm = models.NewMongoTokenInterface()
+var whm = models.NewMongoWebhookInterface()
@@ -0,0 +1,148 @@
+package cms
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/api/auth"
+	"backend/errors"
+	"backend/forms"
+	"backend/models/cmsmodels/coursemodels"
+	"backend/utils"
+)
+
+// inviteRoles the roles a user may enroll themselves as with an invite code.
+var inviteRoles = map[string]bool{
+	"assistant": true,
+	"student":   true,
+}
+
+// defaultInviteLifetime how long an invite code is valid when no expiry is given.
+const defaultInviteLifetime = 14 * 24 * time.Hour
+
+// CreateInviteCode generates a new invite code for a course role, replacing the
+// role's previous code.
+func CreateInviteCode(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	var create forms.CreateInviteCodeForm
+	if err := c.ShouldBindJSON(&create); err != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	if !inviteRoles[create.Role] {
+		c.Set("error", errors.ErrorInvalidRole)
+		return
+	}
+
+	code, errs := utils.RandomCode(8)
+	if errs != nil {
+		c.Set("error", errors.ErrorGenerateTokenFailure)
+		return
+	}
+
+	now := time.Now()
+	invite := coursemodels.InviteCode{
+		Code:      code,
+		Role:      create.Role,
+		ExpiresAt: primitive.DateTime(now.Add(defaultInviteLifetime).UnixNano() / 1000000),
+		Disabled:  false,
+		CreatedAt: primitive.DateTime(now.UnixNano() / 1000000),
+	}
+	if create.ExpiresAt != nil {
+		invite.ExpiresAt = *create.ExpiresAt
+	}
+
+	err := cm.SetInviteCode(cid, invite)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Invite Code Created.",
+		"invite":  invite,
+		"link":    fmt.Sprintf("/join/%s", invite.Code),
+	})
+}
+
+// CourseInviteCodes lists the invite codes of a course.
+func CourseInviteCodes(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Course invite codes.",
+		"invites": course.InviteCodes,
+	})
+}
+
+func DisableInviteCode(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	err := cm.DisableInviteCode(cid, c.Param("role"))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Invite Code Disabled.",
+	})
+}
+
+// RedeemInviteCode enrolls the logged in user in the course an invite code
+// belongs to and returns a token carrying the new enrollment.
+func RedeemInviteCode(c *gin.Context) {
+	uid, _ := c.Get("uid")
+
+	course, invite, err := cm.FindByInviteCode(c.Param("code"))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	waitlisted, err := enrollUser(invite.Role, uid, course.ID)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if waitlisted {
+		c.JSON(200, gin.H{
+			"message":    "Course full, added to waitlist.",
+			"courseID":   course.ID,
+			"waitlisted": true,
+		})
+		return
+	}
+
+	user, err := um.FindOneById(uid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	tokenString, expire, err := auth.IssueToken(c, user)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":  "Enrolled.",
+		"courseID": course.ID,
+		"role":     invite.Role,
+		"token":    tokenString,
+		"expire":   expire,
+	})
+}
@@ -2,25 +2,32 @@ package cms
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/gin-gonic/gin"
 
+	"backend/config"
 	"backend/errors"
+	"backend/models/cmsmodels/submissionmodels"
 )
 
 func JobDownloadSubmission(c *gin.Context) {
 	key := c.Param("secret")
-	if key != os.Getenv("JOB_SECRET") {
+	if key != config.C.JobSecret {
 		c.Set("error", errors.ErrorInvalidJobSecret)
 		return
 	}
 
 	sid, _ := c.Get("sid")
-	sub, err := sm.Get(sid, "any")
-	if err != nil {
-		c.Set("error", err)
-		return
+	var sub *submissionmodels.MongoSubmission
+	if run := findDryRun(sid); run != nil {
+		sub = &run.Submission
+	} else {
+		var err errors.APIError
+		sub, err = sm.Get(sid, "any")
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
 	}
 
 	file, numBytes, err := gfs.Download(sub.FileID)
@@ -38,7 +45,7 @@ func JobDownloadSubmission(c *gin.Context) {
 
 func JobDownloadSupportingFiles(c *gin.Context) {
 	key := c.Param("secret")
-	if key != os.Getenv("JOB_SECRET") {
+	if key != config.C.JobSecret {
 		c.Set("error", errors.ErrorInvalidJobSecret)
 		return
 	}
@@ -0,0 +1,124 @@
+package cms
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/errors"
+	"backend/jobs"
+	"backend/models/jobmodels"
+	"backend/utils"
+)
+
+// RegisterJobs registers the handlers of the background jobs and schedules the
+// recurring ones. Called once at startup, before the workers start.
+func RegisterJobs() {
+	jobs.Register("announcements.notify", 0, NotifyScheduledAnnouncements)
+	jobs.Every("announcements.notify", time.Minute)
+
+	jobs.Register("accounts.purge", 0, PurgeDeletedAccounts)
+	jobs.Every("accounts.purge", time.Hour)
+
+	jobs.Register("courses.retention", 0, ApplyRetentionPolicies)
+	jobs.Every("courses.retention", 24*time.Hour)
+
+	jobs.Register("submissions.recover", 0, RecoverStuckSubmissions)
+	jobs.Every("submissions.recover", 5*time.Minute)
+
+	jobs.Register("submissions.release", 0, ReleaseHeldSubmissions)
+	jobs.Every("submissions.release", time.Minute)
+
+	jobs.Register("graders.health", 0, CheckGraderHealth)
+	jobs.Every("graders.health", time.Minute)
+
+	jobs.Register("assignments.checkReferences", 0, CheckReferenceSolutions)
+	jobs.Every("assignments.checkReferences", 24*time.Hour)
+
+	jobs.Register("submissions.indexCode", 0, IndexSubmissionCode)
+
+	jobs.Register("webhooks.retry", 0, RetryWebhookDeliveries)
+	jobs.Every("webhooks.retry", time.Minute)
+
+	jobs.Register("quizzes.submitClosed", 0, SubmitClosedQuizAttempts)
+	jobs.Every("quizzes.submitClosed", time.Minute)
+
+	jobs.Register("drafts.purge", 0, PurgeExpiredDrafts)
+	jobs.Every("drafts.purge", time.Hour)
+
+	jobs.Register("regrades.dispatch", 0, DispatchRegrade)
+
+	if utils.MailConfigured() {
+		jobs.Register("digests.send", 0, SendDigests)
+		jobs.Every("digests.send", time.Hour)
+	}
+}
+
+// Jobs lists the latest background jobs, filtered with ?name= and ?status=,
+// the recurring schedules and the instance scheduling them.
+func Jobs(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && !jobmodels.ValidStatus(status) {
+		c.Set("error", errors.ErrorInvalidQuery)
+		return
+	}
+
+	limit, errs := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if errs != nil || limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	history, err := jm.Find(c.Query("name"), status, int64(limit))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	scheduled := make([]gin.H, 0)
+	for _, schedule := range jobs.Schedules() {
+		scheduled = append(scheduled, gin.H{
+			"name":  schedule.Name,
+			"every": schedule.Interval.String(),
+		})
+	}
+
+	c.JSON(200, gin.H{
+		"message":   "Jobs.",
+		"jobs":      history,
+		"scheduled": scheduled,
+		"leader":    jobs.Leader(),
+	})
+}
+
+// GetJob a background job's status, attempts and last error.
+func GetJob(c *gin.Context) {
+	jid, _ := c.Get("jid")
+
+	job, err := jm.Get(jid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Job.",
+		"job":     job,
+	})
+}
+
+// RetryJob queues a failed job to run again.
+func RetryJob(c *gin.Context) {
+	jid, _ := c.Get("jid")
+
+	job, err := jm.Requeue(jid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Job Requeued.",
+		"job":     job,
+	})
+}
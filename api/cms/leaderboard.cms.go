@@ -0,0 +1,128 @@
+package cms
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/features"
+	"backend/forms"
+)
+
+// leaderboardAlias a stable pseudonym for a student on an assignment's
+// leaderboard, different on every assignment so students cannot be followed
+// across them.
+func leaderboardAlias(aid, uid primitive.ObjectID) string {
+	hash := sha256.Sum256(append(aid[:], uid[:]...))
+	return "Student " + hex.EncodeToString(hash[:3])
+}
+
+// Leaderboard ranks students' best passing submissions by the assignment's
+// metric. Students are shown under pseudonyms, staff also get who they are.
+func Leaderboard(c *gin.Context) {
+	if !featureEnabled(c, features.Leaderboards) {
+		return
+	}
+
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assign, err := am.Get(aid)
+	if err != nil || (role == "student" && !assign.Published) {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	if assign.Leaderboard == nil {
+		c.Set("error", errors.ErrorNoLeaderboard)
+		return
+	}
+
+	limit, errs := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if errs != nil || limit <= 0 || limit > 500 {
+		limit = 50
+	}
+
+	entries, err := sm.Leaderboard(aid, assign.Leaderboard.Metric, assign.Leaderboard.LowerIsBetter, assign.Leaderboard.OptOuts, limit)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	optedOut := false
+	for _, optOut := range assign.Leaderboard.OptOuts {
+		if optOut == uid {
+			optedOut = true
+		}
+	}
+
+	ranking := make([]gin.H, len(entries))
+	for index, entry := range entries {
+		ranking[index] = gin.H{
+			"rank":           index + 1,
+			"alias":          leaderboardAlias(assign.ID, entry.UserID),
+			"value":          entry.Value,
+			"attemptNumber":  entry.AttemptNumber,
+			"submissionDate": entry.SubmissionDate,
+			"you":            entry.UserID == uid,
+		}
+
+		if role != "student" {
+			ranking[index]["userID"] = entry.UserID
+			ranking[index]["submissionID"] = entry.SubmissionID
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"message":       "Leaderboard.",
+		"metric":        assign.Leaderboard.Metric,
+		"lowerIsBetter": assign.Leaderboard.LowerIsBetter,
+		"optedOut":      optedOut,
+		"leaderboard":   ranking,
+	})
+}
+
+// LeaderboardOptOut lets a student leave, or rejoin, an assignment's
+// leaderboard.
+func LeaderboardOptOut(c *gin.Context) {
+	if !featureEnabled(c, features.Leaderboards) {
+		return
+	}
+
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	uid, _ := c.Get("uid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	var form forms.LeaderboardOptOutForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	err := am.SetLeaderboardOptOut(aid, uid, form.OptOut)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":  "Leaderboard Preference Updated.",
+		"optedOut": form.OptOut,
+	})
+}
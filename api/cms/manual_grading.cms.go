@@ -0,0 +1,133 @@
+package cms
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/forms"
+)
+
+// documentName the name a document submitted to a manually graded
+// assignment is stored under, keeping the extension it was uploaded with.
+func documentName(c *gin.Context, aid, uid interface{}) string {
+	ext := ".pdf"
+	if header, errs := c.FormFile("submission"); errs == nil && path.Ext(header.Filename) != "" {
+		ext = path.Ext(header.Filename)
+	}
+
+	return fmt.Sprintf("sub-%s-%s%s", aid.(primitive.ObjectID).Hex(), uid.(primitive.ObjectID).Hex(), ext)
+}
+
+// SubmissionDocument serves the document submitted to a manually graded
+// assignment, to staff and the student who submitted it.
+func SubmissionDocument(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	sid, _ := c.Get("sid")
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	submission, err := sm.Get(sid, role.(string))
+	if err != nil || submission.AssignmentID != aid || !submission.Manual {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	if role == "student" && submission.UserID != uid {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	file, numBytes, err := gfs.Download(submission.FileID)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	// sniffed from the start of the file, the most DetectContentType reads
+	head := make([]byte, 512)
+	n, _ := file.Read(head)
+	file.Seek(0, io.SeekStart)
+
+	// Only PDFs are shown in the browser. Anything else the student uploaded,
+	// HTML above all, is downloaded so it cannot run in the API's origin.
+	contentType, disposition := "application/pdf", "inline"
+	if http.DetectContentType(head[:n]) != contentType {
+		contentType, disposition = "application/octet-stream", "attachment"
+	}
+
+	additonalHeaders := map[string]string{
+		"Content-Disposition":    mime.FormatMediaType(disposition, map[string]string{"filename": submission.File}),
+		"X-Content-Type-Options": "nosniff",
+	}
+
+	c.DataFromReader(200, numBytes, contentType, file, additonalHeaders)
+}
+
+// ReviewSubmission grades a submission of a manually graded assignment on
+// its rubric, completing its grading task when it has one.
+func ReviewSubmission(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	sid, _ := c.Get("sid")
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	var form forms.ReviewSubmissionForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	assign, err := am.Get(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	submission, err := sm.Get(sid, role.(string))
+	if err != nil || submission.AssignmentID != assign.ID {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	staffID := uid.(primitive.ObjectID)
+	review, err := assign.Review(form, staffID, time.Now())
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = sm.SetReview(submission.ID, *review)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if _, err = gtm.Get(aid, submission.ID); err == nil {
+		gtm.Complete(aid, submission.ID, staffID)
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Submission Reviewed.",
+		"review":  review,
+	})
+}
@@ -0,0 +1,369 @@
+package cms
+
+import (
+	"bytes"
+	"mime"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/forms"
+	"backend/models/cmsmodels/modulemodels"
+	"backend/utils"
+)
+
+// checkModuleContents makes sure a module only holds assignments and
+// announcements of its course, each once.
+func checkModuleContents(cid interface{}, assignments, announcements []primitive.ObjectID) errors.APIError {
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[primitive.ObjectID]bool)
+	for _, aid := range assignments {
+		if seen[aid] || !containsObjectID(course.Assignments, aid) {
+			return errors.ErrorInvalidModule
+		}
+		seen[aid] = true
+	}
+
+	if len(announcements) == 0 {
+		return nil
+	}
+
+	courseAnnouncements, err := anm.GetByCourse(cid, "teacher")
+	if err != nil {
+		return err
+	}
+	ofCourse := make(map[primitive.ObjectID]bool, len(courseAnnouncements))
+	for _, announcement := range courseAnnouncements {
+		ofCourse[announcement.ID] = true
+	}
+
+	for _, anid := range announcements {
+		if seen[anid] || !ofCourse[anid] {
+			return errors.ErrorInvalidModule
+		}
+		seen[anid] = true
+	}
+
+	return nil
+}
+
+// containsObjectID whether an id is in a list of them.
+func containsObjectID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CourseModules lists the course's modules in order, students only the
+// published ones.
+func CourseModules(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	role, _ := c.Get("role")
+
+	modules, err := mdm.ByCourse(cid, role.(string))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Course Modules.",
+		"modules": modules,
+	})
+}
+
+// CreateModule adds a module at the end of the course.
+func CreateModule(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	var form forms.CreateModuleForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	if form.Assignments == nil {
+		form.Assignments = make([]primitive.ObjectID, 0)
+	}
+	if form.Announcements == nil {
+		form.Announcements = make([]primitive.ObjectID, 0)
+	}
+
+	err := checkModuleContents(cid, form.Assignments, form.Announcements)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	module := &modulemodels.MongoModule{
+		CourseID:      cid.(primitive.ObjectID),
+		Title:         form.Title,
+		Description:   form.Description,
+		StartDate:     form.StartDate,
+		Published:     form.Published,
+		Assignments:   form.Assignments,
+		Announcements: form.Announcements,
+	}
+	if err = mdm.Create(module, time.Now()); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(201, gin.H{
+		"message": "Module Created.",
+		"module":  module,
+	})
+}
+
+// UpdateModule changes the fields of a module that are given, checking its
+// assignments and announcements are the course's.
+func UpdateModule(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	mid, _ := c.Get("mid")
+
+	module, err := mdm.Get(mid, cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	var up forms.UpdateModuleForm
+	if errs := c.ShouldBindJSON(&up); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	if up.Title != nil {
+		module.Title = *up.Title
+	}
+	if up.Description != nil {
+		module.Description = *up.Description
+	}
+	if up.StartDate != nil {
+		module.StartDate = up.StartDate
+	}
+	if up.Published != nil {
+		module.Published = *up.Published
+	}
+	if up.Assignments != nil {
+		module.Assignments = up.Assignments
+	}
+	if up.Announcements != nil {
+		module.Announcements = up.Announcements
+	}
+
+	err = checkModuleContents(cid, module.Assignments, module.Announcements)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if err = mdm.Update(*module, time.Now()); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Module Updated.",
+		"module":  module,
+	})
+}
+
+// DeleteModule removes a module and its files. Its assignments and
+// announcements stay in the course.
+func DeleteModule(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	mid, _ := c.Get("mid")
+
+	module, err := mdm.Get(mid, cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if err = mdm.Delete(module); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	for _, file := range module.Files {
+		gfs.Delete(file.ID)
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Module Deleted.",
+	})
+}
+
+// ReorderModules puts the course's modules in the order given, which must
+// list every one of them once.
+func ReorderModules(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	var form forms.ReorderModulesForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	modules, err := mdm.ByCourse(cid, "teacher")
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if len(form.Modules) != len(modules) {
+		c.Set("error", errors.ErrorInvalidModuleOrder)
+		return
+	}
+	listed := make(map[primitive.ObjectID]bool, len(form.Modules))
+	for _, mid := range form.Modules {
+		listed[mid] = true
+	}
+	for _, module := range modules {
+		if !listed[module.ID] {
+			c.Set("error", errors.ErrorInvalidModuleOrder)
+			return
+		}
+	}
+
+	if err = mdm.Reorder(cid, form.Modules); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Modules Reordered.",
+		"modules": form.Modules,
+	})
+}
+
+// UploadModuleFile hands out an image or pdf, like lecture slides, in a
+// module.
+func UploadModuleFile(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	mid, _ := c.Get("mid")
+
+	module, err := mdm.Get(mid, cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	mf, errs := c.FormFile("file")
+	if errs != nil {
+		c.Set("error", errors.ErrorUploadingFile)
+		return
+	}
+
+	content, contentType, err := utils.CheckAttachmentType(mf)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	fid := primitive.NewObjectID()
+	err = gfs.Upload(&fid, mf.Filename, bytes.NewReader(content))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	file := modulemodels.File{
+		ID:          fid,
+		Filename:    mf.Filename,
+		ContentType: contentType,
+		Size:        int64(len(content)),
+		UploadDate:  utils.TimeToDateTime(time.Now()),
+	}
+
+	err = mdm.AddFile(module.ID, file)
+	if err != nil {
+		gfs.Delete(fid)
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(201, gin.H{
+		"message": "Module File Uploaded.",
+		"file":    file,
+	})
+}
+
+// GetModuleFile serves a module's file, to students only once the module is
+// published.
+func GetModuleFile(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	mid, _ := c.Get("mid")
+	fid, _ := c.Get("fid")
+	role, _ := c.Get("role")
+
+	module, err := mdm.Get(mid, cid)
+	if err != nil || (role == "student" && !module.Published) {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	file := module.File(fid.(primitive.ObjectID))
+	if file == nil {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	content, numBytes, err := gfs.Download(file.ID)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	additonalHeaders := map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("inline", map[string]string{"filename": file.Filename}),
+		"Cache-Control":          "private, max-age=86400",
+		"X-Content-Type-Options": "nosniff",
+	}
+
+	c.DataFromReader(200, numBytes, file.ContentType, content, additonalHeaders)
+}
+
+// DeleteModuleFile removes a file from a module.
+func DeleteModuleFile(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	mid, _ := c.Get("mid")
+	fid, _ := c.Get("fid")
+
+	module, err := mdm.Get(mid, cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	file := module.File(fid.(primitive.ObjectID))
+	if file == nil {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	if err = mdm.RemoveFile(module.ID, file.ID); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if err = gfs.Delete(file.ID); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Module File Deleted.",
+	})
+}
@@ -0,0 +1,50 @@
+package cms
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Notifications lists the logged in user's newest notifications.
+func Notifications(c *gin.Context) {
+	uid, _ := c.Get("uid")
+
+	notifications, err := nm.GetUsersNotifications(uid, c.Query("unread") == "true", 50)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":       "User's notifications.",
+		"notifications": notifications,
+	})
+}
+
+func ReadNotification(c *gin.Context) {
+	nid, _ := c.Get("nid")
+	uid, _ := c.Get("uid")
+
+	err := nm.MarkRead(nid, uid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Notification Read.",
+	})
+}
+
+func ReadAllNotifications(c *gin.Context) {
+	uid, _ := c.Get("uid")
+
+	err := nm.MarkAllRead(uid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Notifications Read.",
+	})
+}
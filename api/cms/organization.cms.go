@@ -0,0 +1,215 @@
+package cms
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/forms"
+)
+
+// contextOrganization the logged in user's organization, nil for none.
+func contextOrganization(c *gin.Context) *primitive.ObjectID {
+	org, exists := c.Get("org")
+	if !exists {
+		return nil
+	}
+
+	oid := org.(primitive.ObjectID)
+	return &oid
+}
+
+// Organizations lists every organization.
+func Organizations(c *gin.Context) {
+	orgs, err := om.Find()
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":       "Organizations.",
+		"organizations": orgs,
+	})
+}
+
+// CreateOrganization adds an organization, its admins are then set with
+// SetUserOrganization.
+func CreateOrganization(c *gin.Context) {
+	var form forms.CreateOrganizationForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	org, err := om.Create(form)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(201, gin.H{
+		"message":      "Organization Created.",
+		"organization": org,
+		"status_code":  201,
+	})
+}
+
+// SetUserOrganization moves a user into an organization, as one of its admins
+// with ?admin=true. Their courses are left as they are.
+func SetUserOrganization(c *gin.Context) {
+	oid, _ := c.Get("oid")
+	suid, _ := c.Get("suid")
+
+	org, err := om.Get(oid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = um.SetOrganization(suid, org.ID, c.Query("admin") == "true")
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "User Organization Set.",
+	})
+}
+
+// OrganizationBranding the branding of an organization, public so login pages
+// can show it.
+func OrganizationBranding(c *gin.Context) {
+	org, err := om.GetBySlug(c.Param("slug"))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":  "Organization Branding.",
+		"name":     org.Name,
+		"branding": org.Branding,
+	})
+}
+
+// CurrentOrganization the logged in user's organization.
+func CurrentOrganization(c *gin.Context) {
+	oid := contextOrganization(c)
+	if oid == nil {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	org, err := om.Get(*oid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":      "Organization.",
+		"organization": org,
+	})
+}
+
+// UpdateOrganization changes the name, branding or auth settings of the logged
+// in admin's organization.
+func UpdateOrganization(c *gin.Context) {
+	oid := contextOrganization(c)
+	if oid == nil {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	var form forms.UpdateOrganizationForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	org, err := om.Update(*oid, form)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":      "Organization Updated.",
+		"organization": org,
+	})
+}
+
+// OrganizationUsers lists the users of the logged in admin's organization.
+func OrganizationUsers(c *gin.Context) {
+	oid := contextOrganization(c)
+	if oid == nil {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	users, err := um.FindByOrganization(*oid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Organization Users.",
+		"users":   users,
+	})
+}
+
+// OrganizationCourses lists the courses of the logged in admin's organization.
+func OrganizationCourses(c *gin.Context) {
+	oid := contextOrganization(c)
+	if oid == nil {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	courses, err := cm.FindByOrganization(*oid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Organization Courses.",
+		"courses": courses,
+	})
+}
+
+// SetOrganizationAdmin makes a user of the logged in admin's organization an
+// admin of it, or no longer one with ?admin=false.
+func SetOrganizationAdmin(c *gin.Context) {
+	suid, _ := c.Get("suid")
+
+	oid := contextOrganization(c)
+	if oid == nil {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	user, err := um.FindOneById(suid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if user.OrganizationID == nil || *user.OrganizationID != *oid {
+		c.Set("error", errors.ErrorOrganizationMismatch)
+		return
+	}
+
+	err = um.SetOrganization(user.ID, *oid, c.Query("admin") != "false")
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Organization Admin Set.",
+	})
+}
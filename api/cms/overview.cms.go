@@ -0,0 +1,163 @@
+package cms
+
+import (
+	"sort"
+	"time"
+
+	jwt "github.com/appleboy/gin-jwt"
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/models/cmsmodels/coursemodels"
+	"backend/utils"
+)
+
+type (
+	// overviewGrade a student's course total as the gradebook has it.
+	overviewGrade struct {
+		Total  float64 `json:"total"`
+		Curved float64 `json:"curved"`
+		Letter string  `json:"letter"`
+	}
+
+	// overviewAssignment an assignment a student can still submit and has
+	// not, Late once it is past their due date but still taken.
+	overviewAssignment struct {
+		ID           primitive.ObjectID `json:"id"`
+		Name         string             `json:"name"`
+		DueDate      primitive.DateTime `json:"dueDate"`
+		DueDateLocal utils.LocalTime    `json:"dueDateLocal"`
+		NumAttempts  int                `json:"numAttempts"`
+		Late         bool               `json:"late"`
+	}
+
+	// overviewCourse how a user is doing in one of their courses. Only
+	// students have a grade, pending assignments and ungraded submissions.
+	overviewCourse struct {
+		ID                  primitive.ObjectID   `json:"id"`
+		Department          string               `json:"department"`
+		Number              int                  `json:"number"`
+		Section             string               `json:"section"`
+		LongName            string               `json:"longName"`
+		Role                string               `json:"role"`
+		Grade               *overviewGrade       `json:"grade,omitempty"`
+		PendingAssignments  []overviewAssignment `json:"pendingAssignments"`
+		UngradedSubmissions int                  `json:"ungradedSubmissions"`
+	}
+)
+
+// studentGrade the student's row of the course's gradebook, nil when they
+// have none.
+func studentGrade(course *coursemodels.MongoCourse, uid primitive.ObjectID) *overviewGrade {
+	_, rows, err := buildGradebook(course, course.Scale())
+	if err != nil {
+		return nil
+	}
+
+	for _, row := range rows {
+		if row.UserID == uid {
+			return &overviewGrade{row.Total, row.Curved, row.Letter}
+		}
+	}
+
+	return nil
+}
+
+// Overview shows a user every course they are enrolled in at once: in the
+// ones they take, their current grade, the assignments they have yet to
+// submit, soonest due first, and how many of their submissions are still
+// being graded. How far they are on each assignment comes from one
+// aggregation across the courses.
+func Overview(c *gin.Context) {
+	uid, _ := c.Get("uid")
+
+	claims := jwt.ExtractClaims(c)
+	courses, err := um.GetCourses(uid, claims["courses"].(map[string]interface{}))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	student := uid.(primitive.ObjectID)
+	taken := make([]primitive.ObjectID, 0)
+	for _, course := range courses {
+		if course.Role == "student" {
+			taken = append(taken, course.ID)
+		}
+	}
+
+	progress, err := cm.StudentOverview(taken, student)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+	byCourse := make(map[primitive.ObjectID][]coursemodels.OverviewAssignment)
+	for _, assignment := range progress {
+		byCourse[assignment.CourseID] = append(byCourse[assignment.CourseID], assignment)
+	}
+
+	now := time.Now()
+	loc := userLocation(c)
+	overview := make([]overviewCourse, 0, len(courses))
+	for _, course := range courses {
+		shown := overviewCourse{
+			ID:                 course.ID,
+			Department:         course.Department,
+			Number:             course.Number,
+			Section:            course.Section,
+			LongName:           course.LongName,
+			Role:               course.Role,
+			PendingAssignments: make([]overviewAssignment, 0),
+		}
+
+		if course.Role == "student" {
+			full, err := cm.GetByID(course.ID)
+			if err != nil {
+				c.Set("error", err)
+				return
+			}
+
+			locked, err := lockedAssignments(full, student)
+			if err != nil {
+				c.Set("error", err)
+				return
+			}
+
+			shown.Grade = studentGrade(full, student)
+			for _, assignment := range byCourse[course.ID] {
+				shown.UngradedSubmissions += assignment.Ungraded
+
+				assign := assignment.Assignment
+				if assignment.Attempts > 0 || locked[assign.ID] {
+					continue
+				}
+
+				late := !now.Before(assign.Deadline(student))
+				if late && !assign.AcceptsLate(student, now) {
+					continue
+				}
+
+				dueDate := assign.DueDateFor(student)
+				shown.PendingAssignments = append(shown.PendingAssignments, overviewAssignment{
+					ID:           assign.ID,
+					Name:         assign.Name,
+					DueDate:      dueDate,
+					DueDateLocal: utils.Localize(dueDate, loc),
+					NumAttempts:  assign.NumAttempts,
+					Late:         late,
+				})
+			}
+
+			sort.Slice(shown.PendingAssignments, func(i, j int) bool {
+				return shown.PendingAssignments[i].DueDate < shown.PendingAssignments[j].DueDate
+			})
+		}
+
+		overview = append(overview, shown)
+	}
+
+	conditionalJSON(c, gin.H{
+		"message": "User's Overview.",
+		"courses": overview,
+	})
+}
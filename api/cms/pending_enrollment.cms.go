@@ -0,0 +1,114 @@
+package cms
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/models/cmsmodels/coursemodels"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+// addPendingEnrollment enrolls an email nobody registered with yet, by the
+// staff member uid.
+func addPendingEnrollment(c *gin.Context, cid interface{}, email, level string) errors.APIError {
+	uid, _ := c.Get("uid")
+
+	return cm.AddPendingEnrollment(cid, email, level, uid.(primitive.ObjectID))
+}
+
+// attachPendingEnrollments enrolls a new account in the courses its email was
+// enrolled in before it registered, and tells the student and the staff member
+// who enrolled them.
+func attachPendingEnrollments(uid primitive.ObjectID, email string) {
+	courses, err := cm.WithPendingEnrollment(email)
+	if err != nil {
+		tyrgin.ErrorLogger(err, "Failed to find the pending enrollments of "+uid.Hex())
+		return
+	}
+
+	for _, course := range courses {
+		pending, _ := course.PendingEnrollment(email)
+		if err = attachPendingEnrollment(uid, course, pending); err != nil {
+			tyrgin.ErrorLogger(err, "Failed to attach the pending enrollment of "+uid.Hex()+" in "+course.ID.Hex())
+		}
+	}
+}
+
+func attachPendingEnrollment(uid primitive.ObjectID, course coursemodels.MongoCourse, pending coursemodels.PendingEnrollment) errors.APIError {
+	err := cm.RemovePendingEnrollment(course.ID, pending.Email)
+	if err != nil {
+		// another instance attached it
+		return nil
+	}
+
+	waitlisted, err := enrollUser(pending.Role, uid, course.ID)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s %d", course.Department, course.Number)
+	link := fmt.Sprintf("/course/%s", course.ID.Hex())
+	studentMessage := fmt.Sprintf("You have been enrolled in %s.", name)
+	staffMessage := fmt.Sprintf("%s registered and joined %s.", pending.Email, name)
+	if waitlisted {
+		studentMessage = fmt.Sprintf("You have been added to the waitlist of %s.", name)
+		staffMessage = fmt.Sprintf("%s registered and was waitlisted in %s, as it is full.", pending.Email, name)
+	}
+
+	if err = nm.Notify([]primitive.ObjectID{uid}, course.ID, "enrollment", studentMessage, link); err != nil {
+		tyrgin.ErrorLogger(err, "Failed to notify "+uid.Hex()+" of their enrollment.")
+	}
+
+	if err = nm.Notify([]primitive.ObjectID{pending.AddedBy}, course.ID, "enrollment", staffMessage, link+"/users"); err != nil {
+		tyrgin.ErrorLogger(err, "Failed to notify "+pending.AddedBy.Hex()+" of a pending enrollment.")
+	}
+
+	return nil
+}
+
+// CoursePendingEnrollments lists the emails enrolled in a course that have not
+// registered yet.
+func CoursePendingEnrollments(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	pending := course.PendingEnrollments
+	if pending == nil {
+		pending = make([]coursemodels.PendingEnrollment, 0)
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Pending Enrollments.",
+		"pending": pending,
+	})
+}
+
+// CancelPendingEnrollment removes the pending enrollment of ?email=.
+func CancelPendingEnrollment(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	email := c.Query("email")
+	if email == "" {
+		c.Set("error", errors.ErrorInvalidQuery)
+		return
+	}
+
+	err := cm.RemovePendingEnrollment(cid, email)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Pending Enrollment Cancelled.",
+	})
+}
@@ -0,0 +1,60 @@
+package cms
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"backend/errors"
+	"backend/forms"
+	"backend/models/cmsmodels/coursemodels"
+)
+
+// PolicyTemplates lists the course's policy templates, the settings staff
+// create assignments with by giving the template's name as policy.
+func PolicyTemplates(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	templates := course.PolicyTemplates
+	if templates == nil {
+		templates = make([]coursemodels.PolicyTemplate, 0)
+	}
+
+	c.JSON(200, gin.H{
+		"message":  "Policy Templates.",
+		"policies": templates,
+	})
+}
+
+// UpdatePolicyTemplates replaces every policy template of the course.
+// Assignments already created with a template keep their settings.
+func UpdatePolicyTemplates(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	var form forms.UpdatePolicyTemplatesForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	templates, err := coursemodels.NewPolicyTemplates(form)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = cm.SetPolicyTemplates(cid, templates)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":  "Policy Templates Updated.",
+		"policies": templates,
+	})
+}
@@ -0,0 +1,88 @@
+package cms
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/utils"
+)
+
+// PrecheckSubmission runs an assignment's quick checks on a submission
+// without recording it or using up an attempt: that the archive contains the
+// files in its manifest and, when the assignment enables it, that it builds. Only
+// the file check is repeated when submitting, builds are left to grading.
+func PrecheckSubmission(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	sub, errs := c.FormFile("submission")
+	if errs != nil {
+		c.Set("error", errors.ErrorUploadingFile)
+		return
+	}
+
+	files, err := utils.CheckFileType(sub)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assign, err := am.Get(aid)
+	if err != nil || (role == "student" && !assign.Published) {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	paths, err := utils.ArchivePaths(files)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	mismatch := assign.CheckManifest(paths)
+	response := gin.H{
+		"message":  "Precheck Finished.",
+		"files":    paths,
+		"manifest": mismatch,
+	}
+
+	passed := mismatch == nil
+	checkBuild := assign.Precheck != nil && assign.Precheck.Build
+	if checkBuild && passed {
+		fid := primitive.NewObjectID()
+		err = gfs.Upload(&fid, fmt.Sprintf("precheck-%s.tar.gz", assign.ID.Hex()), bytes.NewReader(files))
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+		defer gfs.Delete(fid)
+
+		// Only the build runs, without any tests.
+		buildOnly := *assign
+		buildOnly.Tests = nil
+
+		report, errored, _, err := gradeDryRun(c.Request.Context(), &buildOnly, uid.(primitive.ObjectID), fid, sub.Filename)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		passed = !errored && !report.BuildFailed
+		response["buildFailed"] = report.BuildFailed || errored
+		response["buildOutput"] = report.BuildOutput
+	}
+	response["passed"] = passed
+
+	c.JSON(200, response)
+}
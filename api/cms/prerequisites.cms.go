@@ -0,0 +1,127 @@
+package cms
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/forms"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/models/cmsmodels/coursemodels"
+)
+
+// lockedAssignments the assignments of a course a student has not met the
+// prerequisites of, which they neither see nor can submit to.
+func lockedAssignments(course *coursemodels.MongoCourse, uid interface{}) (map[primitive.ObjectID]bool, errors.APIError) {
+	locked := make(map[primitive.ObjectID]bool)
+
+	assigns, err := am.WithPrerequisites(course.Assignments)
+	if err != nil || len(assigns) == 0 {
+		return locked, err
+	}
+
+	required := make([]primitive.ObjectID, 0)
+	for _, assign := range assigns {
+		for _, prerequisite := range assign.Prerequisites {
+			required = append(required, prerequisite.AssignmentID)
+		}
+	}
+
+	best, err := sm.BestScores(uid, required)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, assign := range assigns {
+		if len(assign.UnmetPrerequisites(best)) > 0 {
+			locked[assign.ID] = true
+		}
+	}
+
+	return locked, nil
+}
+
+// studentLockedAssignments lockedAssignments of the course for the student
+// asking.
+func studentLockedAssignments(c *gin.Context, cid interface{}) (map[primitive.ObjectID]bool, errors.APIError) {
+	uid, _ := c.Get("uid")
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		return nil, err
+	}
+
+	return lockedAssignments(course, uid)
+}
+
+// checkPrerequisites refuses a student an assignment until they have met its
+// prerequisites, telling them which ones they have not.
+func checkPrerequisites(c *gin.Context, assign *assignmentmodels.MongoAssignment, uid interface{}) errors.APIError {
+	if len(assign.Prerequisites) == 0 {
+		return nil
+	}
+
+	required := make([]primitive.ObjectID, len(assign.Prerequisites))
+	for i, prerequisite := range assign.Prerequisites {
+		required[i] = prerequisite.AssignmentID
+	}
+
+	best, err := sm.BestScores(uid, required)
+	if err != nil {
+		return err
+	}
+
+	if unmet := assign.UnmetPrerequisites(best); len(unmet) > 0 {
+		c.Set("errorDetails", gin.H{"unmet": unmet})
+		return errors.ErrorAssignmentLocked
+	}
+
+	return nil
+}
+
+// UpdatePrerequisites replaces the assignments, and scores on them, a
+// student needs before an assignment unlocks for them.
+func UpdatePrerequisites(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	var form forms.UpdatePrerequisitesForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	existing, err := am.WithPrerequisites(course.Assignments)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	prerequisites, err := assignmentmodels.NewPrerequisites(aid.(primitive.ObjectID), course.Assignments, existing, form)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = am.SetPrerequisites(aid, prerequisites)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":       "Prerequisites Updated.",
+		"prerequisites": prerequisites,
+	})
+}
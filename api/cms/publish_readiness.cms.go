@@ -0,0 +1,46 @@
+package cms
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AssignmentReadiness checks whether an assignment can be published, listing
+// what blocks it and what staff should look at first. With
+// ?checkReference=true the reference solution is graded again beforehand,
+// which waits on court herald.
+func AssignmentReadiness(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assign, err := am.Get(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if c.Query("checkReference") == "true" && assign.ReferenceSolution != nil {
+		if err = checkReferenceSolution(*assign); err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		assign, err = am.Get(aid)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"message":   "Assignment Readiness.",
+		"published": assign.Published,
+		"readiness": assign.Readiness(time.Now()),
+	})
+}
@@ -0,0 +1,506 @@
+package cms
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/errors"
+	"backend/features"
+	"backend/forms"
+	"backend/models/cmsmodels/quizmodels"
+	"backend/utils"
+)
+
+// CourseQuestions lists a course's question bank, only the questions tagged
+// ?tag= when it is given.
+func CourseQuestions(c *gin.Context) {
+	if !featureEnabled(c, features.Quizzes) {
+		return
+	}
+
+	cid, _ := c.Get("cid")
+
+	questions, err := qm.CourseQuestions(cid, c.Query("tag"))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":   "Course questions.",
+		"questions": questions,
+	})
+}
+
+// CreateQuestion adds a question to a course's question bank.
+func CreateQuestion(c *gin.Context) {
+	if !featureEnabled(c, features.Quizzes) {
+		return
+	}
+
+	cid, _ := c.Get("cid")
+	uid, _ := c.Get("uid")
+
+	var form forms.QuestionForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	question, err := quizmodels.NewQuestion(form)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+	question.CourseID = cid.(primitive.ObjectID)
+	question.CreatedBy = uid.(primitive.ObjectID)
+
+	created, err := qm.CreateQuestion(question)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(201, gin.H{
+		"status_code": 201,
+		"message":     "Question Created.",
+		"question":    created,
+	})
+}
+
+// UpdateQuestion replaces a question of the bank. Quiz attempts already
+// started keep it as it was.
+func UpdateQuestion(c *gin.Context) {
+	if !featureEnabled(c, features.Quizzes) {
+		return
+	}
+
+	cid, _ := c.Get("cid")
+	qid, _ := c.Get("qid")
+
+	var form forms.QuestionForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	existing, err := qm.GetQuestion(qid, cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	question, err := quizmodels.NewQuestion(form)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+	question.ID, question.CourseID = existing.ID, existing.CourseID
+	question.CreatedBy, question.CreatedAt = existing.CreatedBy, existing.CreatedAt
+
+	err = qm.UpdateQuestion(question)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":  "Question Updated.",
+		"question": question,
+	})
+}
+
+// DeleteQuestion removes a question from the bank. Quizzes asking it every
+// attempt cannot be started until it is replaced.
+func DeleteQuestion(c *gin.Context) {
+	if !featureEnabled(c, features.Quizzes) {
+		return
+	}
+
+	cid, _ := c.Get("cid")
+	qid, _ := c.Get("qid")
+
+	err := qm.DeleteQuestion(qid, cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Question Deleted.",
+	})
+}
+
+// UpdateQuiz chooses the questions of a quiz from the course's bank.
+func UpdateQuiz(c *gin.Context) {
+	if !featureEnabled(c, features.Quizzes) {
+		return
+	}
+
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	var form forms.UpdateQuizForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	assign, err := am.Get(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if assign.Quiz == nil {
+		c.Set("error", errors.ErrorNotAQuiz)
+		return
+	}
+
+	bank, err := qm.CourseQuestions(cid, "")
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	quiz, err := quizmodels.NewQuiz(form, bank)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = am.SetQuiz(aid, quiz)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Quiz Updated.",
+		"quiz":    quiz,
+	})
+}
+
+// StartQuiz starts an attempt at a quiz, drawing its questions, or returns
+// the attempt the student has not submitted yet. Timed quizzes start the
+// student's window with their first attempt, which is submitted on its own
+// when the window closes.
+func StartQuiz(c *gin.Context) {
+	if !featureEnabled(c, features.Quizzes) {
+		return
+	}
+
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	uid, _ := c.Get("uid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assign, attempts, err := am.LatestUserSubmission(aid, uid)
+	if err != nil || !assign.Published {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	if assign.Quiz == nil {
+		c.Set("error", errors.ErrorNotAQuiz)
+		return
+	}
+
+	open, err := qm.OpenAttempt(aid, uid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+	if open != nil {
+		c.JSON(200, gin.H{
+			"message": "Quiz Attempt Resumed.",
+			"attempt": open.View(),
+		})
+		return
+	}
+
+	if attempts+1 > assign.NumAttempts {
+		c.Set("error", errors.ErrorSubmissionAttemptsExceeded)
+		return
+	}
+
+	if err = checkPrerequisites(c, assign, uid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	now := time.Now()
+	var closesAt primitive.DateTime
+	if assign.Timed() {
+		window := assign.Window(uid.(primitive.ObjectID))
+		if window == nil {
+			if err = am.Start(aid, uid, now); err != nil {
+				c.Set("error", err)
+				return
+			}
+			if assign, err = am.Get(aid); err != nil {
+				c.Set("error", err)
+				return
+			}
+			window = assign.Window(uid.(primitive.ObjectID))
+		}
+
+		if now.After(window.EndsAt) {
+			c.Set("error", errors.ErrorSubmissionWindowClosed)
+			return
+		}
+		closesAt = utils.TimeToDateTime(window.EndsAt)
+	}
+
+	bank, err := qm.CourseQuestions(cid, "")
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	questions, err := quizmodels.Assemble(assign.Quiz, bank, rand.New(rand.NewSource(now.UnixNano())))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	attempt, err := qm.StartAttempt(quizmodels.MongoAttempt{
+		AssignmentID:  assign.ID,
+		CourseID:      cid.(primitive.ObjectID),
+		UserID:        uid.(primitive.ObjectID),
+		AttemptNumber: attempts + 1,
+		Questions:     questions,
+		ClosesAt:      closesAt,
+	})
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(201, gin.H{
+		"status_code": 201,
+		"message":     "Quiz Attempt Started.",
+		"attempt":     attempt.View(),
+	})
+}
+
+// quizAttempt the attempt of the route, refused to students it is not
+// theirs.
+func quizAttempt(c *gin.Context) (*quizmodels.MongoAttempt, errors.APIError) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	qaid, _ := c.Get("qaid")
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		return nil, err
+	}
+
+	attempt, err := qm.GetAttempt(qaid, aid)
+	if err != nil {
+		return nil, err
+	}
+
+	if role == "student" && attempt.UserID != uid {
+		return nil, errors.ErrorResourceNotFound
+	}
+
+	return attempt, nil
+}
+
+// GetQuizAttempt shows a quiz attempt: to the student taking it without the
+// answers, to staff as it was drawn, answers and all.
+func GetQuizAttempt(c *gin.Context) {
+	if !featureEnabled(c, features.Quizzes) {
+		return
+	}
+
+	role, _ := c.Get("role")
+
+	attempt, err := quizAttempt(c)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if role == "student" {
+		c.JSON(200, gin.H{
+			"message": "Quiz attempt.",
+			"attempt": attempt.View(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Quiz attempt.",
+		"attempt": attempt,
+	})
+}
+
+// SaveQuizAttempt autosaves the responses of an attempt that changed, for
+// the frontend to call as the student answers so closing the page or the
+// window running out loses nothing.
+func SaveQuizAttempt(c *gin.Context) {
+	if !featureEnabled(c, features.Quizzes) {
+		return
+	}
+
+	var form forms.SaveQuizResponsesForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	attempt, err := quizAttempt(c)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	now := time.Now()
+	if !attempt.Open(now) {
+		c.Set("error", errors.ErrorQuizAttemptClosed)
+		return
+	}
+
+	responses, err := attempt.CheckResponses(form)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = qm.SaveResponses(attempt.ID, responses, now)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Quiz Attempt Saved.",
+		"savedAt": utils.TimeToDateTime(now),
+	})
+}
+
+// finishQuizAttempt scores an attempt and stores it as a submission of the
+// quiz, graded by its score.
+func finishQuizAttempt(attempt quizmodels.MongoAttempt, at time.Time) (primitive.ObjectID, errors.APIError) {
+	attempt.Score, attempt.Results = attempt.Grade()
+
+	sid := primitive.NewObjectID()
+	err := qm.Finish(attempt, sid, at)
+	if err != nil {
+		return sid, err
+	}
+
+	err = am.InsertSubmission(attempt.AssignmentID, attempt.UserID, sid, attempt.AttemptNumber)
+	if err != nil {
+		qm.Reopen(attempt.ID)
+		return sid, err
+	}
+
+	err = sm.SubmitQuiz(attempt.AssignmentID, attempt.UserID, sid, attempt.ID, attempt.AttemptNumber, attempt.Score)
+	if err != nil {
+		am.DeleteSubmission(attempt.AssignmentID, sid)
+		qm.Reopen(attempt.ID)
+		return sid, err
+	}
+
+	return sid, nil
+}
+
+// SubmitQuizAttempt saves the responses sent with it, if any, and submits
+// the attempt for scoring. Once its window has closed only the responses
+// saved before are scored.
+func SubmitQuizAttempt(c *gin.Context) {
+	if !featureEnabled(c, features.Quizzes) {
+		return
+	}
+
+	var form forms.SaveQuizResponsesForm
+	if c.Request.ContentLength > 0 {
+		if errs := c.ShouldBindJSON(&form); errs != nil {
+			c.Set("error", errors.ErrorInvalidJSON)
+			return
+		}
+	}
+
+	attempt, err := quizAttempt(c)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if attempt.Submitted {
+		c.Set("error", errors.ErrorQuizAttemptClosed)
+		return
+	}
+
+	now := time.Now()
+	if attempt.Open(now) {
+		responses, err := attempt.CheckResponses(form)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+		if attempt.Responses == nil {
+			attempt.Responses = make(map[string]quizmodels.Response)
+		}
+		for qid, response := range responses {
+			attempt.Responses[qid] = response
+		}
+	}
+
+	sid, err := finishQuizAttempt(*attempt, now)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	submitted, err := qm.GetAttempt(attempt.ID, attempt.AssignmentID)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(201, gin.H{
+		"status_code":  201,
+		"message":      "Quiz Attempt Submitted.",
+		"submissionID": sid,
+		"attempt":      submitted.View(),
+	})
+}
+
+// SubmitClosedQuizAttempts is a job submitting the attempts whose timed
+// window closed before the student submitted them, with the responses they
+// saved.
+func SubmitClosedQuizAttempts([]byte) error {
+	now := time.Now()
+	attempts, err := qm.Closed(now)
+	if err != nil {
+		return err
+	}
+
+	for _, attempt := range attempts {
+		_, err = finishQuizAttempt(attempt, now)
+		if err != nil && err != errors.ErrorQuizAttemptClosed {
+			tyrgin.ErrorLogger(err, "Failed to submit quiz attempt "+attempt.ID.Hex())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package cms
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/forms"
+	"backend/models/cmsmodels/coursemodels"
+	"backend/utils"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+// quotaStatus a course's quota and how much of it is used, for staff.
+func quotaStatus(course *coursemodels.MongoCourse) gin.H {
+	return gin.H{
+		"quota":          course.QuotaLimits(),
+		"default":        course.Quota == nil,
+		"storageBytes":   course.Usage.StorageBytes,
+		"gradingMinutes": course.Usage.GradingSeconds / 60,
+		"exceeded":       course.QuotaExceeded(0),
+	}
+}
+
+// checkQuota refuses a submission of size bytes to a course over a hard quota.
+// Over a soft quota it is accepted, and the professors are told once.
+func checkQuota(course *coursemodels.MongoCourse, size int64) errors.APIError {
+	if !course.QuotaExceeded(size) {
+		return nil
+	}
+
+	if course.QuotaLimits().Hard {
+		return errors.ErrorCourseQuotaExceeded
+	}
+
+	warnQuota(course)
+	return nil
+}
+
+// warnQuota tells the professors of a course it used up its quota, unless
+// they were told since the quota last changed.
+func warnQuota(course *coursemodels.MongoCourse) {
+	first, err := cm.MarkQuotaWarned(course.ID)
+	if err != nil || !first {
+		return
+	}
+
+	err = nm.Notify(
+		course.Professors,
+		course.ID,
+		"quota",
+		fmt.Sprintf("%s %d has used up its storage or grading quota, ask an admin to raise it", course.Department, course.Number),
+		fmt.Sprintf("/course/%s/quota", course.ID.Hex()),
+	)
+	if err != nil {
+		tyrgin.ErrorLogger(err, "Failed to warn of the quota of course "+course.ID.Hex())
+	}
+}
+
+// recordGradingUsage counts the time court herald spent grading a submission
+// against its course's quota.
+func recordGradingUsage(sid primitive.ObjectID) {
+	submission, err := sm.Get(sid, "any")
+	if err != nil || submission.DispatchedAt == 0 {
+		return
+	}
+
+	course, err := cm.FindByAssignment(submission.AssignmentID)
+	if err != nil {
+		return
+	}
+
+	seconds := time.Since(utils.DateTimeToTime(submission.DispatchedAt)).Seconds()
+	if err = cm.AddUsage(course.ID, 0, seconds); err != nil {
+		tyrgin.ErrorLogger(err, "Failed to record the grading time of submission "+sid.Hex())
+	}
+	if err = sm.RecordUsage(sid, 0, seconds); err != nil {
+		tyrgin.ErrorLogger(err, "Failed to record the grading time of submission "+sid.Hex())
+	}
+}
+
+// CourseQuota the course's quota and how much of it is used.
+func CourseQuota(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Course Quota.",
+		"quota":   quotaStatus(course),
+	})
+}
+
+// SetCourseQuota gives a course its own quota, or returns it to the default.
+func SetCourseQuota(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	var form forms.CourseQuotaForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	if form.StorageMB < 0 || form.GradingMinutes < 0 {
+		c.Set("error", errors.ErrorInvalidQuota)
+		return
+	}
+
+	var quota *coursemodels.Quota
+	if !form.Default {
+		quota = &coursemodels.Quota{
+			StorageMB:      form.StorageMB,
+			GradingMinutes: form.GradingMinutes,
+			Hard:           form.Hard,
+		}
+	}
+
+	err := cm.SetQuota(cid, quota)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Course Quota Updated.",
+		"quota":   quotaStatus(course),
+	})
+}
@@ -0,0 +1,262 @@
+package cms
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/errors"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/utils"
+)
+
+// checkReferenceSolution grades an assignment's reference solution and lets
+// the course staff know when it stops passing.
+func checkReferenceSolution(assign assignmentmodels.MongoAssignment) errors.APIError {
+	reference := assign.ReferenceSolution
+	if reference == nil {
+		return nil
+	}
+
+	check := assignmentmodels.SelfCheck{Failed: make([]string, 0)}
+	report, errored, job, err := gradeDryRun(context.Background(), &assign, reference.UploadedBy, reference.FileID, reference.Filename)
+	if err == errors.ErrorUnableToReachMicroService {
+		return err
+	}
+
+	check.Job = job
+	switch {
+	case err != nil:
+		check.Failed = append(check.Failed, err.Error())
+	case errored:
+		check.Failed = append(check.Failed, "grading failed")
+	case report.BuildFailed:
+		check.Failed = append(check.Failed, "build failed")
+	default:
+		for _, result := range report.Results {
+			if !result.Passed {
+				check.Failed = append(check.Failed, result.Name)
+			}
+		}
+		if len(report.Results) < len(assign.Tests) {
+			check.Failed = append(check.Failed, "tests missing from the results")
+		}
+	}
+	check.Passed = len(check.Failed) == 0
+	check.CheckedAt = utils.TimeToDateTime(time.Now())
+
+	err = am.RecordSelfCheck(assign.ID, reference.FileID, check)
+	if err != nil {
+		return err
+	}
+
+	previouslyPassed := reference.LastCheck == nil || reference.LastCheck.Passed
+	if check.Passed || !previouslyPassed {
+		return nil
+	}
+
+	course, err := cm.FindByAssignment(assign.ID)
+	if err != nil {
+		return err
+	}
+
+	return nm.Notify(
+		append(course.Professors, course.Assistants...),
+		course.ID,
+		"selfcheck",
+		fmt.Sprintf("%s %d: the reference solution of %s no longer passes its tests", course.Department, course.Number, assign.Name),
+		fmt.Sprintf("/course/%s/assignment/%s", course.ID.Hex(), assign.ID.Hex()),
+	)
+}
+
+// CheckReferenceSolutions is a job that grades every stored reference solution
+// against the current tests. It fails, to be retried, while court herald is
+// unreachable.
+func CheckReferenceSolutions([]byte) error {
+	assignments, err := am.WithReferenceSolutions()
+	if err != nil {
+		return err
+	}
+
+	for _, assign := range assignments {
+		err = checkReferenceSolution(assign)
+		if err == errors.ErrorUnableToReachMicroService {
+			return err
+		}
+		if err != nil {
+			tyrgin.ErrorLogger(err, "Failed to check the reference solution of "+assign.ID.Hex())
+		}
+	}
+
+	return nil
+}
+
+// UploadReferenceSolution stores a reference solution for an assignment,
+// replacing the previous one, and checks it right away.
+func UploadReferenceSolution(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	uid, _ := c.Get("uid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	sub, errs := c.FormFile("reference")
+	if errs != nil {
+		c.Set("error", errors.ErrorUploadingFile)
+		return
+	}
+
+	files, err := utils.CheckFileType(sub)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assign, err := am.Get(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	fid := primitive.NewObjectID()
+	err = gfs.Upload(&fid, fmt.Sprintf("reference-%s.tar.gz", assign.ID.Hex()), bytes.NewReader(files))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	reference := assignmentmodels.ReferenceSolution{
+		FileID:     fid,
+		Filename:   sub.Filename,
+		UploadDate: utils.TimeToDateTime(time.Now()),
+		UploadedBy: uid.(primitive.ObjectID),
+	}
+
+	err = am.SetReferenceSolution(aid, &reference)
+	if err != nil {
+		gfs.Delete(fid)
+		c.Set("error", err)
+		return
+	}
+
+	if assign.ReferenceSolution != nil {
+		gfs.Delete(assign.ReferenceSolution.FileID)
+	}
+
+	assign.ReferenceSolution = &reference
+	go func() {
+		if err := checkReferenceSolution(*assign); err != nil {
+			tyrgin.ErrorLogger(err, "Failed to check the reference solution of "+assign.ID.Hex())
+		}
+	}()
+
+	c.JSON(201, gin.H{
+		"status_code":       201,
+		"message":           "Reference Solution Uploaded.",
+		"referenceSolution": reference,
+	})
+}
+
+// GetReferenceSolution shows staff the reference solution and its last check.
+func GetReferenceSolution(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assign, err := am.Get(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if assign.ReferenceSolution == nil {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":           "Reference solution.",
+		"referenceSolution": assign.ReferenceSolution,
+	})
+}
+
+// DownloadReferenceSolution downloads the reference solution.
+func DownloadReferenceSolution(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assign, err := am.Get(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if assign.ReferenceSolution == nil {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	file, numBytes, err := gfs.Download(assign.ReferenceSolution.FileID)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	additonalHeaders := map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": assign.ReferenceSolution.Filename}),
+	}
+
+	c.DataFromReader(200, numBytes, "application/tar+gzip", file, additonalHeaders)
+}
+
+// DeleteReferenceSolution removes the reference solution.
+func DeleteReferenceSolution(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assign, err := am.Get(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if assign.ReferenceSolution == nil {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	err = am.SetReferenceSolution(aid, nil)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	gfs.Delete(assign.ReferenceSolution.FileID)
+
+	c.JSON(200, gin.H{
+		"message": "Reference Solution Deleted.",
+	})
+}
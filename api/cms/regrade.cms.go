@@ -0,0 +1,303 @@
+package cms
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/jobs"
+	"backend/models/cmsmodels/regrademodels"
+	"backend/models/cmsmodels/submissionmodels"
+)
+
+// regradePayload the regrade a dispatch job sends to court herald.
+type regradePayload struct {
+	RegradeID string `json:"regradeID"`
+}
+
+// RegradeAssignment grades the latest submission of every student of an
+// assignment again, e.g. after its tests were fixed, keeping how each was
+// graded before for the comparison report. Submissions are sent to court
+// herald by a background job.
+func RegradeAssignment(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	uid, _ := c.Get("uid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assign, err := am.Get(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if assign.Quiz != nil {
+		c.Set("error", errors.ErrorQuizAssignment)
+		return
+	}
+
+	if assign.Manual {
+		c.Set("error", errors.ErrorManuallyGraded)
+		return
+	}
+
+	latest, err := sm.LatestPerStudent(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	submissions, err := sm.GetByAssignmentIDs([]primitive.ObjectID{assign.ID})
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+	byID := make(map[primitive.ObjectID]*submissionmodels.MongoSubmission, len(submissions))
+	for index := range submissions {
+		byID[submissions[index].ID] = &submissions[index]
+	}
+
+	entries := make([]regrademodels.Entry, 0, len(latest))
+	for _, student := range latest {
+		submission, found := byID[student.SubmissionID]
+		if !found || submission.InProgress || submission.FilePurged || submission.Cancelled {
+			continue
+		}
+
+		entries = append(entries, regrademodels.Entry{
+			SubmissionID:  submission.ID,
+			UserID:        submission.UserID,
+			AttemptNumber: submission.AttemptNumber,
+			Before:        regrademodels.OutcomeOf(submission),
+		})
+	}
+
+	if len(entries) == 0 {
+		c.Set("error", errors.ErrorNothingToRegrade)
+		return
+	}
+
+	regrade := &regrademodels.MongoRegrade{
+		CourseID:     cid.(primitive.ObjectID),
+		AssignmentID: assign.ID,
+		StartedBy:    uid.(primitive.ObjectID),
+		Entries:      entries,
+	}
+	if err = regm.Create(regrade, time.Now()); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if _, errs := jobs.Enqueue("regrades.dispatch", regradePayload{regrade.ID.Hex()}); errs != nil {
+		c.Set("error", errors.ErrorDatabaseFailedCreate)
+		return
+	}
+
+	c.JSON(201, gin.H{
+		"message":     "Regrade Started.",
+		"regradeID":   regrade.ID,
+		"submissions": len(entries),
+	})
+}
+
+// DispatchRegrade sends the submissions of a regrade back to court herald,
+// those it has not sent yet when retried.
+func DispatchRegrade(payload []byte) error {
+	var dispatch regradePayload
+	if errs := json.Unmarshal(payload, &dispatch); errs != nil {
+		return errs
+	}
+
+	rgid, errs := primitive.ObjectIDFromHex(dispatch.RegradeID)
+	if errs != nil {
+		return errs
+	}
+
+	regrade, err := regm.GetByID(rgid)
+	if err != nil {
+		return err
+	}
+
+	assign, err := am.Get(regrade.AssignmentID)
+	if err != nil {
+		return err
+	}
+
+	graders, err := GradersFor(assign)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range regrade.Entries {
+		if entry.Dispatched {
+			continue
+		}
+
+		submission, err := sm.Get(entry.SubmissionID, "teacher")
+		if err == nil && !submission.InProgress {
+			if _, err = sm.Regrade(*submission, assign.Job(submission.UserID), graders); err != nil {
+				return err
+			}
+		}
+
+		if err = regm.MarkDispatched(regrade.ID, entry.SubmissionID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Regrades lists the regrades of an assignment, newest first.
+func Regrades(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	regrades, err := regm.ForAssignment(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":  "Regrades.",
+		"regrades": regrades,
+	})
+}
+
+func regradeCSV(comparisons []regrademodels.Comparison, students map[primitive.ObjectID][]string) (*bytes.Buffer, errors.APIError) {
+	records := [][]string{{
+		"First Name", "Last Name", "Email", "Attempt", "Before", "After", "Delta", "Status", "Newly Passing", "Newly Failing",
+	}}
+	for _, comparison := range comparisons {
+		status := "graded"
+		switch {
+		case comparison.Pending:
+			status = "pending"
+		case comparison.Errored:
+			status = "errored"
+		}
+
+		after, delta := "", ""
+		if !comparison.Pending {
+			after = strconv.FormatFloat(comparison.After, 'f', 2, 64)
+			delta = strconv.FormatFloat(comparison.Delta, 'f', 2, 64)
+		}
+
+		record := append([]string{}, students[comparison.UserID]...)
+		record = append(
+			record,
+			strconv.Itoa(comparison.AttemptNumber),
+			strconv.FormatFloat(comparison.Before, 'f', 2, 64),
+			after,
+			delta,
+			status,
+			strings.Join(comparison.NewlyPassing, "; "),
+			strings.Join(comparison.NewlyFailing, "; "),
+		)
+		records = append(records, record)
+	}
+
+	buf := &bytes.Buffer{}
+	if errs := csv.NewWriter(buf).WriteAll(records); errs != nil {
+		return nil, errors.ErrorFailedToWriteCSV
+	}
+
+	return buf, nil
+}
+
+// RegradeReport compares every student's submission before and after a
+// regrade: their score, its change and the tests newly passing or failing.
+// Submissions still being regraded are pending. ?format=csv downloads it.
+func RegradeReport(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	rgid, _ := c.Get("rgid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	regrade, err := regm.Get(rgid, aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	students, err := sectionStudents(c, course)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	submissions, err := sm.GetByAssignmentIDs([]primitive.ObjectID{regrade.AssignmentID})
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+	current := make(map[primitive.ObjectID]*submissionmodels.MongoSubmission, len(submissions))
+	for index := range submissions {
+		current[submissions[index].ID] = &submissions[index]
+	}
+
+	comparisons := make([]regrademodels.Comparison, 0, len(regrade.Entries))
+	for _, comparison := range regrade.Compare(current) {
+		if students == nil || students[comparison.UserID] {
+			comparisons = append(comparisons, comparison)
+		}
+	}
+
+	if c.Query("format") == "csv" {
+		names := make(map[primitive.ObjectID][]string, len(comparisons))
+		for _, comparison := range comparisons {
+			names[comparison.UserID] = []string{"", "", ""}
+			if student, err := um.FindOneById(comparison.UserID); err == nil {
+				names[comparison.UserID] = []string{student.First, student.Last, student.Email}
+			}
+		}
+
+		file, err := regradeCSV(comparisons, names)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		additonalHeaders := map[string]string{
+			"Content-Disposition": fmt.Sprintf(`attachment; filename="%s-regrade-%s.csv"`, regrade.AssignmentID.Hex(), regrade.ID.Hex()),
+		}
+
+		c.DataFromReader(200, int64(file.Len()), "text/csv", file, additonalHeaders)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":     "Regrade Report.",
+		"regradeID":   regrade.ID,
+		"startedAt":   regrade.StartedAt,
+		"comparisons": comparisons,
+	})
+}
@@ -0,0 +1,304 @@
+package cms
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/config"
+	"backend/errors"
+	"backend/features"
+	"backend/forms"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/models/cmsmodels/submissionmodels"
+	"backend/utils"
+)
+
+// pushEvent the parts of a GitHub push webhook delivery that are used.
+type pushEvent struct {
+	Ref        string `json:"ref"`
+	After      string `json:"after"`
+	Repository struct {
+		CloneURL string `json:"clone_url"`
+	} `json:"repository"`
+}
+
+// webhookURL where GitHub should deliver a linked repository's pushes. Uses
+// PUBLIC_URL when the server is behind a proxy.
+func webhookURL(c *gin.Context, lid primitive.ObjectID) string {
+	base := config.C.PublicURL
+	if base == "" {
+		base = "https://" + c.Request.Host
+	}
+
+	return fmt.Sprintf("%s/api/v1/plague_doctor/webhook/github/%s", base, lid.Hex())
+}
+
+// validSignature checks a delivery was signed with the link's secret.
+func validSignature(secret string, body []byte, signature string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// postStatus sets the commit status of a linked repository, if the student
+// gave a token to post it with.
+func postStatus(link *assignmentmodels.RepositoryLink, commit, state, description string) {
+	if link.StatusToken == "" {
+		return
+	}
+
+	err := utils.PostCommitStatus(link.URL, commit, link.StatusToken, state, description)
+	if err != nil {
+		tyrgin.ErrorLogger(err, "Failed to post commit status to "+link.URL)
+	}
+}
+
+// reportRepositoryStatus posts a graded submission's result back to the
+// commit it was pushed from.
+func reportRepositoryStatus(sid interface{}) {
+	submission, err := sm.Get(sid, "teacher")
+	if err != nil || submission.Repository == nil || submission.Repository.LinkID == nil {
+		return
+	}
+
+	assign, link, err := am.FindRepositoryLink(*submission.Repository.LinkID)
+	if err != nil {
+		return
+	}
+
+	// the student reads the status, so it only counts the tests they see
+	shown, hidden := submissionmodels.CountResults(submission.Results)
+	passed := shown.Passed + hidden.Passed
+
+	state := "failure"
+	description := fmt.Sprintf("Attempt %d: %d/%d tests passed", submission.AttemptNumber, shown.Passed, shown.Total)
+	if assign.ShowHiddenSummary && hidden.Total > 0 {
+		description += fmt.Sprintf(", %d/%d hidden", hidden.Passed, hidden.Total)
+	}
+	switch {
+	case submission.BuildFailed:
+		description = fmt.Sprintf("Attempt %d: build failed", submission.AttemptNumber)
+	case submission.ErrorTesting:
+		state = "error"
+		description = fmt.Sprintf("Attempt %d could not be graded", submission.AttemptNumber)
+	case passed == len(submission.Results):
+		state = "success"
+	}
+
+	postStatus(link, submission.Repository.Commit, state, description)
+}
+
+// LinkRepository registers a student's repository so pushes to a branch are
+// submitted automatically. The response has the webhook url and secret to
+// add to the repository's settings. Giving a GitHub token with the
+// repo:status scope has results posted back to each commit.
+func LinkRepository(c *gin.Context) {
+	if !featureEnabled(c, features.GitSubmissions) {
+		return
+	}
+
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	uid, _ := c.Get("uid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	var form forms.LinkRepositoryForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	if form.Branch == "" {
+		form.Branch = "master"
+	}
+
+	if err := utils.CheckRepository(form.Repository, strings.Repeat("0", 40)); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assign, err := am.Get(aid)
+	if err != nil || !assign.Published {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	if assign.Attestation != "" && !form.AcceptAttestation {
+		c.Set("error", errors.ErrorAttestationRequired)
+		return
+	}
+
+	secret := make([]byte, 20)
+	if _, errs := rand.Read(secret); errs != nil {
+		c.Set("error", errors.ErrorGenerateTokenFailure)
+		return
+	}
+
+	link := assignmentmodels.RepositoryLink{
+		ID:                  primitive.NewObjectID(),
+		UserID:              uid.(primitive.ObjectID),
+		URL:                 form.Repository,
+		Branch:              form.Branch,
+		Secret:              hex.EncodeToString(secret),
+		StatusToken:         form.StatusToken,
+		AttestationAccepted: form.AcceptAttestation,
+		CreatedAt:           utils.TimeToDateTime(time.Now()),
+	}
+
+	err = am.LinkRepository(aid, link)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(201, gin.H{
+		"status_code": 201,
+		"message":     "Repository Linked.",
+		"link":        link,
+		"webhookURL":  webhookURL(c, link.ID),
+		"secret":      link.Secret,
+		"contentType": "application/json",
+		"events":      []string{"push"},
+	})
+}
+
+// UnlinkRepository stops submitting a student's pushes.
+func UnlinkRepository(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	uid, _ := c.Get("uid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err := am.UnlinkRepository(aid, uid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Repository Unlinked.",
+	})
+}
+
+// submitPush archives a pushed commit and submits it for the link's student.
+func submitPush(c *gin.Context, assign *assignmentmodels.MongoAssignment, link *assignmentmodels.RepositoryLink, commit string) {
+	course, err := cm.FindByAssignment(assign.ID)
+	if err != nil {
+		return
+	}
+
+	if course.Archived {
+		postStatus(link, commit, "error", "This course is archived")
+		return
+	}
+
+	user, err := um.FindOneById(link.UserID)
+	if err != nil || user.CoursesAsMap()[course.ID.Hex()] != "student" {
+		postStatus(link, commit, "error", "Not enrolled as a student in this course")
+		return
+	}
+
+	submissionFiles, err := utils.ArchiveCommit(link.URL, commit)
+	if err != nil {
+		postStatus(link, commit, "error", "Could not fetch this commit")
+		return
+	}
+
+	repository := &submissionmodels.Repository{
+		URL:    link.URL,
+		Commit: commit,
+		LinkID: &link.ID,
+	}
+	receipt, err := submitFiles(c, assign.ID, link.UserID, submissionFiles, link.AttestationAccepted, repository)
+	if err != nil {
+		postStatus(link, commit, "error", "Not submitted: "+strings.ToLower(err.Error()))
+		return
+	}
+
+	if receipt.Queued {
+		postStatus(link, commit, "pending", fmt.Sprintf("Attempt %d is queued, the grader is under high load", receipt.AttemptNumber))
+		return
+	}
+
+	postStatus(link, commit, "pending", fmt.Sprintf("Attempt %d is being graded", receipt.AttemptNumber))
+}
+
+// GitHubWebhook receives pushes to linked repositories. Pushes to the linked
+// branch are submitted in the background, so GitHub gets its answer in time.
+func GitHubWebhook(c *gin.Context) {
+	lid, _ := c.Get("lid")
+
+	assign, link, err := am.FindRepositoryLink(lid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	body, errs := ioutil.ReadAll(c.Request.Body)
+	if errs != nil {
+		c.Set("error", errors.ErrorFailedToReadFile)
+		return
+	}
+
+	if !validSignature(link.Secret, body, c.GetHeader("X-Hub-Signature-256")) {
+		c.Set("error", errors.ErrorInvalidWebhookSignature)
+		return
+	}
+
+	if c.GetHeader("X-GitHub-Event") != "push" {
+		c.JSON(200, gin.H{"message": "Event Ignored."})
+		return
+	}
+
+	var push pushEvent
+	if errs = json.Unmarshal(body, &push); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	if push.Ref != "refs/heads/"+link.Branch || !utils.SameRepository(push.Repository.CloneURL, link.URL) || strings.Trim(push.After, "0") == "" {
+		c.JSON(200, gin.H{"message": "Push Ignored."})
+		return
+	}
+
+	if delivery := c.GetHeader("X-GitHub-Delivery"); delivery != "" {
+		fresh, err := am.RecordRepositoryDelivery(lid, delivery)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		if !fresh {
+			c.JSON(200, gin.H{"message": "Push Already Received."})
+			return
+		}
+	}
+
+	go submitPush(c.Copy(), assign, link, push.After)
+
+	c.JSON(202, gin.H{
+		"status_code": 202,
+		"message":     "Push Accepted.",
+	})
+}
@@ -0,0 +1,316 @@
+package cms
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/forms"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/models/cmsmodels/coursemodels"
+	"backend/models/cmsmodels/submissionmodels"
+	"backend/utils"
+)
+
+// researchScope the pseudonyms of research exports are the same across
+// courses and exports, so a student can be followed through their courses.
+const researchScope = "research"
+
+// researchSchemaVersion changes whenever a record of the export does.
+const researchSchemaVersion = 1
+
+// researchSchema documents every record of a research export, the first line
+// of every export.
+var researchSchema = gin.H{
+	"type":    "schema",
+	"version": researchSchemaVersion,
+	"description": "One JSON record per line, this one first. Users, courses and assignments are " +
+		"named by stable pseudonyms (P-, C- and A- followed by 12 hex digits), the same in every " +
+		"export made with the same secret. Free text, such as names, assignment descriptions, test " +
+		"output, feedback and reasons, is left out. Only courses whose staff consented are exported. " +
+		"Dates are RFC 3339 in UTC.",
+	"records": gin.H{
+		"course": gin.H{
+			"course":          "pseudonym of the course",
+			"department":      "department the course belongs to",
+			"number":          "course number",
+			"semester":        "semester the course ran",
+			"students":        "number of students enrolled",
+			"consentGivenBy":  "pseudonym of the professor who consented",
+			"consentGivenAt":  "when they consented",
+			"assignmentCount": "number of published assignments",
+		},
+		"assignment": gin.H{
+			"course":      "pseudonym of the course",
+			"assignment":  "pseudonym of the assignment",
+			"dueDate":     "the due date, before any extension",
+			"numAttempts": "submissions a student may make",
+			"tests":       "number of tests, 0 for manually graded assignments",
+			"extraCredit": "whether it adds to the total without counting towards it",
+			"manual":      "whether staff grade it by hand on a rubric",
+		},
+		"submission": gin.H{
+			"course":      "pseudonym of the course",
+			"assignment":  "pseudonym of the assignment",
+			"student":     "pseudonym of the student",
+			"attempt":     "attempt number, from 1",
+			"submittedAt": "when it was submitted",
+			"status":      "graded, grading, error, buildFailed, cancelled or refunded",
+			"daysLate":    "started days after the student's due date, 0 when on time",
+			"score":       "percentage of tests passed, before any grade given by staff",
+			"results":     "each test's index in the assignment (id), whether it passed and its durationMS when measured",
+		},
+		"grade": gin.H{
+			"course":     "pseudonym of the course",
+			"assignment": "pseudonym of the assignment",
+			"student":    "pseudonym of the student",
+			"attempt":    "attempt number of the submission graded",
+			"kind":       "override, a grade staff gave, or review, a rubric grade",
+			"grade":      "the grade given, out of 100",
+			"by":         "pseudonym of the staff member",
+			"at":         "when it was given",
+		},
+	},
+}
+
+type (
+	researchResult struct {
+		ID         int      `json:"id"`
+		Passed     bool     `json:"passed"`
+		DurationMS *float64 `json:"durationMS,omitempty"`
+	}
+
+	researchSubmission struct {
+		Type        string           `json:"type"`
+		Course      string           `json:"course"`
+		Assignment  string           `json:"assignment"`
+		Student     string           `json:"student"`
+		Attempt     int              `json:"attempt"`
+		SubmittedAt string           `json:"submittedAt"`
+		Status      string           `json:"status"`
+		DaysLate    int              `json:"daysLate"`
+		Score       float64          `json:"score"`
+		Results     []researchResult `json:"results"`
+	}
+
+	researchGrade struct {
+		Type       string  `json:"type"`
+		Course     string  `json:"course"`
+		Assignment string  `json:"assignment"`
+		Student    string  `json:"student"`
+		Attempt    int     `json:"attempt"`
+		Kind       string  `json:"kind"`
+		Grade      float64 `json:"grade"`
+		By         string  `json:"by"`
+		At         string  `json:"at"`
+	}
+)
+
+func researchDate(date primitive.DateTime) string {
+	return utils.DateTimeToTime(date).UTC().Format(time.RFC3339)
+}
+
+// researchStatus how far along a submission's grading got.
+func researchStatus(submission submissionmodels.MongoSubmission) string {
+	switch {
+	case submission.Refunded:
+		return "refunded"
+	case submission.Cancelled:
+		return "cancelled"
+	case submission.InProgress:
+		return "grading"
+	case submission.BuildFailed:
+		return "buildFailed"
+	case submission.ErrorTesting:
+		return "error"
+	}
+
+	return "graded"
+}
+
+// researchCourse writes a consenting course's records: the course, its
+// published assignments, then every submission and grade, oldest first.
+func researchCourse(out *json.Encoder, course coursemodels.MongoCourse) errors.APIError {
+	cp := pseudonym("C", researchScope, course.ID)
+
+	assigns := make(map[primitive.ObjectID]*assignmentmodels.MongoAssignment, len(course.Assignments))
+	aids := make([]primitive.ObjectID, 0, len(course.Assignments))
+	records := make([]interface{}, 0, len(course.Assignments))
+	for _, aid := range course.Assignments {
+		assign, err := am.Get(aid)
+		if err != nil || !assign.Published {
+			continue
+		}
+
+		aids = append(aids, aid)
+		assigns[aid] = assign
+		records = append(records, gin.H{
+			"type":        "assignment",
+			"course":      cp,
+			"assignment":  pseudonym("A", researchScope, aid),
+			"dueDate":     researchDate(assign.DueDate),
+			"numAttempts": assign.NumAttempts,
+			"tests":       len(assign.Tests),
+			"extraCredit": assign.ExtraCredit,
+			"manual":      assign.Manual,
+		})
+	}
+
+	errs := out.Encode(gin.H{
+		"type":            "course",
+		"course":          cp,
+		"department":      course.Department,
+		"number":          course.Number,
+		"semester":        course.Semester,
+		"students":        len(course.Students),
+		"consentGivenBy":  pseudonym("P", researchScope, course.ResearchConsent.GivenBy),
+		"consentGivenAt":  researchDate(course.ResearchConsent.GivenAt),
+		"assignmentCount": len(aids),
+	})
+	if errs != nil {
+		return errors.ErrorFailedToWriteJSON
+	}
+	for _, record := range records {
+		if errs = out.Encode(record); errs != nil {
+			return errors.ErrorFailedToWriteJSON
+		}
+	}
+
+	submissions, err := sm.ResearchSubmissions(aids)
+	if err != nil {
+		return err
+	}
+
+	for _, submission := range submissions {
+		assign := assigns[submission.AssignmentID]
+		ap := pseudonym("A", researchScope, submission.AssignmentID)
+		student := pseudonym("P", researchScope, submission.UserID)
+
+		record := researchSubmission{
+			Type:        "submission",
+			Course:      cp,
+			Assignment:  ap,
+			Student:     student,
+			Attempt:     submission.AttemptNumber,
+			SubmittedAt: researchDate(submission.SubmissionDate),
+			Status:      researchStatus(submission),
+			DaysLate:    assign.DaysLate(submission.UserID, submission.SubmissionDate),
+			Results:     make([]researchResult, len(submission.Results)),
+		}
+		passed := 0
+		for index, result := range submission.Results {
+			record.Results[index] = researchResult{ID: result.ID, Passed: result.Passed}
+			if result.DurationMS > 0 {
+				duration := result.DurationMS
+				record.Results[index].DurationMS = &duration
+			}
+			if result.Passed {
+				passed++
+			}
+		}
+		if len(submission.Results) > 0 {
+			record.Score = 100 * float64(passed) / float64(len(submission.Results))
+		}
+
+		if errs = out.Encode(record); errs != nil {
+			return errors.ErrorFailedToWriteJSON
+		}
+
+		grade := researchGrade{
+			Type:       "grade",
+			Course:     cp,
+			Assignment: ap,
+			Student:    student,
+			Attempt:    submission.AttemptNumber,
+		}
+		switch {
+		case submission.Review != nil:
+			grade.Kind, grade.Grade = "review", submission.Review.Grade
+			grade.By, grade.At = pseudonym("P", researchScope, submission.Review.By), researchDate(submission.Review.At)
+		case submission.GradeOverride != nil:
+			grade.Kind, grade.Grade = "override", submission.GradeOverride.Grade
+			grade.By, grade.At = pseudonym("P", researchScope, submission.GradeOverride.By), researchDate(submission.GradeOverride.At)
+		default:
+			continue
+		}
+
+		if errs = out.Encode(grade); errs != nil {
+			return errors.ErrorFailedToWriteJSON
+		}
+	}
+
+	return nil
+}
+
+// ResearchExport downloads an anonymized dataset of the courses whose staff
+// consented, as JSON lines: the schema documenting every record first, then
+// each course's assignments, submissions and grades under stable
+// pseudonyms, derived with PSEUDONYM_SECRET, without any free text.
+func ResearchExport(c *gin.Context) {
+	courses, err := cm.WithResearchConsent()
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	file := &bytes.Buffer{}
+	out := json.NewEncoder(file)
+	schema := gin.H{"generatedAt": time.Now().UTC().Format(time.RFC3339), "courses": len(courses)}
+	for key, value := range researchSchema {
+		schema[key] = value
+	}
+	if errs := out.Encode(schema); errs != nil {
+		c.Set("error", errors.ErrorFailedToWriteJSON)
+		return
+	}
+
+	for _, course := range courses {
+		if err = researchCourse(out, course); err != nil {
+			c.Set("error", err)
+			return
+		}
+	}
+
+	additonalHeaders := map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="research-export-%s.jsonl"`, time.Now().UTC().Format("2006-01-02")),
+	}
+
+	c.DataFromReader(200, int64(file.Len()), "application/x-ndjson", file, additonalHeaders)
+}
+
+// UpdateResearchConsent lets a professor give, or withdraw, the course's
+// consent to being part of research exports.
+func UpdateResearchConsent(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	uid, _ := c.Get("uid")
+
+	var form forms.ResearchConsentForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	var consent *coursemodels.ResearchConsent
+	if *form.Consent {
+		consent = &coursemodels.ResearchConsent{
+			GivenBy: uid.(primitive.ObjectID),
+			GivenAt: utils.TimeToDateTime(time.Now()),
+		}
+	}
+
+	err := cm.SetResearchConsent(cid, consent)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":         "Research Consent Updated.",
+		"researchConsent": consent,
+	})
+}
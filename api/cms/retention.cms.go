@@ -0,0 +1,326 @@
+package cms
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/errors"
+	"backend/forms"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/models/cmsmodels/coursemodels"
+	"backend/utils"
+)
+
+// BuildCourseArchive zips a course's metadata, assignments, a grade sheet per
+// assignment, the gradebook, every submission's results and its discussions. It returns the
+// zip and a filename for it.
+func BuildCourseArchive(course coursemodels.MongoCourse, reason string) (*bytes.Buffer, string, errors.APIError) {
+	buf := new(bytes.Buffer)
+	archive := zip.NewWriter(buf)
+
+	metadata := gin.H{
+		"course":      course,
+		"retention":   course.RetentionPolicy(),
+		"reason":      reason,
+		"generatedAt": time.Now(),
+	}
+	if err := writeJSONToZip(archive, "course.json", metadata); err != nil {
+		return nil, "", err
+	}
+
+	assignments := make([]assignmentmodels.MongoAssignment, 0)
+	for _, aid := range course.Assignments {
+		assign, err := am.Get(aid)
+		if err != nil {
+			continue
+		}
+		assignments = append(assignments, *assign)
+
+		grades, _, _, err := cm.GetGradesAsCSV(aid, course.ID, true)
+		if err != nil {
+			return nil, "", err
+		}
+
+		w, errs := archive.Create(fmt.Sprintf("grades/%s.csv", aid.Hex()))
+		if errs != nil {
+			return nil, "", errors.ErrorFailedToCreateArchive
+		}
+
+		if _, errs = grades.WriteTo(w); errs != nil {
+			return nil, "", errors.ErrorFailedToCreateArchive
+		}
+	}
+	if err := writeJSONToZip(archive, "assignments.json", assignments); err != nil {
+		return nil, "", err
+	}
+
+	gradebookAssignments, rows, err := buildGradebook(&course, course.Scale())
+	if err != nil {
+		return nil, "", err
+	}
+	gradebook, err := gradebookCSV(gradebookAssignments, rows)
+	if err != nil {
+		return nil, "", err
+	}
+	w, errs := archive.Create("gradebook.csv")
+	if errs != nil {
+		return nil, "", errors.ErrorFailedToCreateArchive
+	}
+	if _, errs = gradebook.WriteTo(w); errs != nil {
+		return nil, "", errors.ErrorFailedToCreateArchive
+	}
+
+	submissions, err := sm.ReportByAssignmentIDs(course.Assignments)
+	if err != nil {
+		return nil, "", err
+	}
+	if err = writeJSONToZip(archive, "submissions.json", submissions); err != nil {
+		return nil, "", err
+	}
+
+	threads, err := dm.GetByCourse(course.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	if err = writeJSONToZip(archive, "discussions.json", threads); err != nil {
+		return nil, "", err
+	}
+
+	if errs := archive.Close(); errs != nil {
+		return nil, "", errors.ErrorFailedToCreateArchive
+	}
+
+	filename := fmt.Sprintf("%s-%d-%s-%s-archive.zip", course.Department, course.Number, course.Section, course.Semester)
+
+	return buf, filename, nil
+}
+
+// archiveCourse stores an archive of the course in gridfs and records it on
+// the course.
+func archiveCourse(course coursemodels.MongoCourse, reason string) (*coursemodels.CourseArchive, errors.APIError) {
+	buf, filename, err := BuildCourseArchive(course, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	fid := primitive.NewObjectID()
+	if err = gfs.Upload(&fid, filename, buf); err != nil {
+		return nil, err
+	}
+
+	courseArchive := coursemodels.CourseArchive{
+		FileID:    fid,
+		Reason:    reason,
+		CreatedAt: utils.TimeToDateTime(time.Now()),
+	}
+	if err = cm.AddArchive(course.ID, courseArchive); err != nil {
+		gfs.Delete(fid)
+		return nil, err
+	}
+
+	return &courseArchive, nil
+}
+
+// retentionExpired reports whether data kept for days after end is due for
+// purging. Zero days keeps data forever.
+func retentionExpired(end time.Time, days int) bool {
+	return days > 0 && time.Now().After(end.AddDate(0, 0, days))
+}
+
+// applyRetention purges the data of an ended course that is past its retention
+// period, archiving the course first.
+func applyRetention(course coursemodels.MongoCourse) errors.APIError {
+	policy := course.RetentionPolicy()
+	end := utils.DateTimeToTime(course.EndDate)
+
+	purgeFiles := !course.SubmissionFilesPurged && retentionExpired(end, policy.SubmissionFileDays)
+	purgeDiscussions := !course.DiscussionsPurged && retentionExpired(end, policy.DiscussionDays)
+	if !purgeFiles && !purgeDiscussions {
+		return nil
+	}
+
+	_, err := archiveCourse(course, "retention")
+	if err != nil {
+		return err
+	}
+
+	if purgeFiles {
+		submissions, err := sm.GetByAssignmentIDs(course.Assignments)
+		if err != nil {
+			return err
+		}
+
+		sids := make([]primitive.ObjectID, 0)
+		for _, submission := range submissions {
+			if !submission.FilePurged {
+				gfs.Delete(submission.FileID)
+				for _, fid := range submission.OutputFileIDs() {
+					gfs.Delete(fid)
+				}
+				for _, fid := range submission.ArtifactFileIDs() {
+					gfs.Delete(fid)
+				}
+				sids = append(sids, submission.ID)
+			}
+		}
+
+		if err = sm.MarkFilesPurged(sids); err != nil {
+			return err
+		}
+
+		if err = cdm.DeleteBySubmissions(sids); err != nil {
+			return err
+		}
+	}
+
+	if purgeDiscussions {
+		if err = dm.DeleteByCourseID(course.ID); err != nil {
+			return err
+		}
+	}
+
+	return cm.MarkPurged(course.ID, purgeFiles, purgeDiscussions)
+}
+
+// ApplyRetentionPolicies is a job that purges the data of ended courses past
+// their retention periods.
+func ApplyRetentionPolicies([]byte) error {
+	courses, err := cm.EndedWithDataToPurge()
+	if err != nil {
+		return err
+	}
+
+	for _, course := range courses {
+		if err = applyRetention(course); err != nil {
+			tyrgin.ErrorLogger(err, "Failed to apply retention policy to course "+course.ID.Hex())
+		}
+	}
+
+	return nil
+}
+
+// UpdateRetention sets when a course ends and how long its data is kept after.
+// Periods left out of the request fall back to the default policy.
+func UpdateRetention(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	var form forms.CourseRetentionForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	var retention *coursemodels.RetentionPolicy
+	if form.SubmissionFileDays != nil || form.DiscussionDays != nil {
+		policy := coursemodels.DefaultRetentionPolicy()
+		if form.SubmissionFileDays != nil {
+			policy.SubmissionFileDays = *form.SubmissionFileDays
+		}
+		if form.DiscussionDays != nil {
+			policy.DiscussionDays = *form.DiscussionDays
+		}
+
+		if policy.SubmissionFileDays < 0 || policy.DiscussionDays < 0 {
+			c.Set("error", errors.ErrorInvalidRetentionPeriod)
+			return
+		}
+		retention = &policy
+	}
+
+	err := cm.SetRetention(cid, form.EndDate, retention)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	course := coursemodels.MongoCourse{Retention: retention}
+	c.JSON(200, gin.H{
+		"message":   "Retention Policy Updated.",
+		"endDate":   form.EndDate,
+		"retention": course.RetentionPolicy(),
+	})
+}
+
+// CourseArchives lists the archives generated for a course.
+func CourseArchives(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	archives := course.Archives
+	if archives == nil {
+		archives = make([]coursemodels.CourseArchive, 0)
+	}
+
+	c.JSON(200, gin.H{
+		"message":   "Course archives.",
+		"retention": course.RetentionPolicy(),
+		"archives":  archives,
+	})
+}
+
+// CreateArchive generates a course archive on demand.
+func CreateArchive(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	archive, err := archiveCourse(*course, "manual")
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(201, gin.H{
+		"status_code": 201,
+		"message":     "Archive Created.",
+		"archive":     archive,
+	})
+}
+
+// DownloadArchive serves one of a course's archives.
+func DownloadArchive(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	fid, _ := c.Get("fid")
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	for _, archive := range course.Archives {
+		if archive.FileID != fid {
+			continue
+		}
+
+		file, numBytes, err := gfs.Download(archive.FileID)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		additonalHeaders := map[string]string{
+			"Content-Disposition": fmt.Sprintf(`attachment; filename="%s-archive.zip"`, archive.FileID.Hex()),
+		}
+
+		c.DataFromReader(200, numBytes, "application/zip", file, additonalHeaders)
+		return
+	}
+
+	c.Set("error", errors.ErrorResourceNotFound)
+}
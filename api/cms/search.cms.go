@@ -0,0 +1,114 @@
+package cms
+
+import (
+	"strconv"
+	"strings"
+
+	jwt "github.com/appleboy/gin-jwt"
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/models/usermodels"
+)
+
+// searchPage the page of results asked for with ?page= and ?limit=, 20 a page
+// by default.
+func searchPage(c *gin.Context) (int64, int64) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 50 {
+		limit = 20
+	}
+
+	return int64((page - 1) * limit), int64(limit)
+}
+
+// Search finds assignments and announcements of the user's courses matching
+// ?q=, staff also the students and staff of the courses they teach, most
+// relevant first. Students only find what is published.
+func Search(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if len(q) < 2 {
+		c.Set("error", errors.ErrorInvalidQuery)
+		return
+	}
+	skip, limit := searchPage(c)
+
+	staff := make([]primitive.ObjectID, 0)
+	student := make([]primitive.ObjectID, 0)
+	courses, _ := jwt.ExtractClaims(c)["courses"].(map[string]interface{})
+	for hex, role := range courses {
+		cid, err := primitive.ObjectIDFromHex(hex)
+		if err != nil {
+			continue
+		}
+
+		if role == "student" {
+			student = append(student, cid)
+		} else {
+			staff = append(staff, cid)
+		}
+	}
+
+	enrolled, err := cm.FindByIDs(append(append([]primitive.ObjectID{}, staff...), student...))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	staffCourses := make(map[primitive.ObjectID]bool)
+	for _, cid := range staff {
+		staffCourses[cid] = true
+	}
+
+	// assignments only know their course through the course's list of them
+	assignmentCourse := make(map[primitive.ObjectID]primitive.ObjectID)
+	staffAssignments := make([]primitive.ObjectID, 0)
+	studentAssignments := make([]primitive.ObjectID, 0)
+	for _, course := range enrolled {
+		for _, aid := range course.Assignments {
+			assignmentCourse[aid] = course.ID
+			if staffCourses[course.ID] {
+				staffAssignments = append(staffAssignments, aid)
+			} else {
+				studentAssignments = append(studentAssignments, aid)
+			}
+		}
+	}
+
+	assignments, err := am.Search(q, staffAssignments, studentAssignments, skip, limit)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+	for i := range assignments {
+		assignments[i].CourseID = assignmentCourse[assignments[i].ID]
+	}
+
+	announcements, err := anm.Search(q, staff, student, skip, limit)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	users := make([]usermodels.SearchResult, 0)
+	if len(staff) > 0 {
+		users, err = um.Search(q, staff, skip, limit)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"message":       "Search results.",
+		"assignments":   assignments,
+		"announcements": announcements,
+		"users":         users,
+	})
+}
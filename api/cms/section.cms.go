@@ -0,0 +1,121 @@
+package cms
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/forms"
+	"backend/models/cmsmodels/coursemodels"
+)
+
+// sectionStudents the students staff filter to: those of ?section=, or of the
+// assistant's own sections with ?mine=true. Assistants of a course whose
+// grading is scoped to sections always get theirs. Nil for every student.
+func sectionStudents(c *gin.Context, course *coursemodels.MongoCourse) (map[primitive.ObjectID]bool, errors.APIError) {
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	var sections []coursemodels.Section
+	filtered := false
+	if name := c.Query("section"); name != "" {
+		section, found := course.FindSection(name)
+		if !found {
+			return nil, errors.ErrorResourceNotFound
+		}
+		sections, filtered = []coursemodels.Section{section}, true
+	}
+
+	if role == "assistant" {
+		own := course.AssistantSections(uid.(primitive.ObjectID))
+		scoped := course.SectionScopedGrading && len(own) > 0
+		if scoped && filtered && !containsSection(own, sections[0].Name) {
+			return nil, errors.ErrorResourceNotFound
+		}
+		if !filtered && (scoped || c.Query("mine") == "true") {
+			sections, filtered = own, true
+		}
+	}
+
+	if !filtered {
+		return nil, nil
+	}
+
+	students := make(map[primitive.ObjectID]bool)
+	for _, section := range sections {
+		for _, suid := range section.Students {
+			students[suid] = true
+		}
+	}
+
+	return students, nil
+}
+
+func containsSection(sections []coursemodels.Section, name string) bool {
+	for _, section := range sections {
+		if section.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CourseSections lists a course's sections with their students and
+// assistants.
+func CourseSections(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	sections := course.Sections
+	if sections == nil {
+		sections = make([]coursemodels.Section, 0)
+	}
+
+	c.JSON(200, gin.H{
+		"message":       "Course Sections.",
+		"sections":      sections,
+		"scopedGrading": course.SectionScopedGrading,
+	})
+}
+
+// UpdateSections replaces a course's sections and whether assistants only
+// grade the students of theirs.
+func UpdateSections(c *gin.Context) {
+	cid, _ := c.Get("cid")
+
+	var form forms.UpdateSectionsForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	sections, err := coursemodels.NewSections(course, form)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = cm.SetSections(cid, sections, form.ScopedGrading)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":       "Sections Updated.",
+		"sections":      sections,
+		"scopedGrading": form.ScopedGrading,
+	})
+}
@@ -0,0 +1,173 @@
+package cms
+
+import (
+	"crypto/hmac"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/config"
+	"backend/errors"
+	"backend/forms"
+	"backend/models/cmsmodels/submissionmodels"
+	"backend/utils"
+)
+
+// maxShareHours how long a share link can last at most, two weeks.
+const maxShareHours = 14 * 24
+
+// shareToken the token of a share link: its id and a signature over the id
+// and the submission, so links cannot be guessed or pointed elsewhere.
+func shareToken(sid, shid primitive.ObjectID) string {
+	return shid.Hex() + "." + utils.Sign(config.C.ShareLinkSecret, sid.Hex()+"."+shid.Hex())
+}
+
+func shareURL(c *gin.Context, token string) string {
+	base := config.C.PublicURL
+	if base == "" {
+		base = "https://" + c.Request.Host
+	}
+
+	return fmt.Sprintf("%s/api/v1/plague_doctor/share/%s", base, token)
+}
+
+// sharedSubmission the submission a share token is for, as long as the token
+// is signed and its link neither expired nor revoked.
+func sharedSubmission(token string) (*submissionmodels.MongoSubmission, errors.APIError) {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return nil, errors.ErrorInvalidShareLink
+	}
+
+	shid, errs := primitive.ObjectIDFromHex(parts[0])
+	if errs != nil {
+		return nil, errors.ErrorInvalidShareLink
+	}
+
+	submission, err := sm.GetByShareLink(shid)
+	if err != nil {
+		return nil, errors.ErrorInvalidShareLink
+	}
+
+	link := submission.ShareLink(shid)
+	if !hmac.Equal([]byte(token), []byte(shareToken(submission.ID, shid))) || link == nil || !link.Active() {
+		return nil, errors.ErrorInvalidShareLink
+	}
+
+	return submission, nil
+}
+
+// CreateShareLink makes a link to one of the student's own submissions that
+// shows its results, as the student sees them, to anyone who has it.
+func CreateShareLink(c *gin.Context) {
+	sid, _ := c.Get("sid")
+	uid, _ := c.Get("uid")
+
+	var form forms.CreateShareLinkForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	if form.Hours < 1 || form.Hours > maxShareHours {
+		c.Set("error", errors.ErrorInvalidShareExpiry)
+		return
+	}
+
+	submission, err := sm.GetUsersSubmission(sid, uid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	link, err := sm.AddShareLink(submission.ID, time.Now().Add(time.Duration(form.Hours)*time.Hour))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":   "Share Link Created.",
+		"shareLink": link,
+		"url":       shareURL(c, shareToken(submission.ID, link.ID)),
+	})
+}
+
+// SubmissionShareLinks the share links of one of the student's submissions,
+// with their urls.
+func SubmissionShareLinks(c *gin.Context) {
+	sid, _ := c.Get("sid")
+	uid, _ := c.Get("uid")
+
+	submission, err := sm.GetUsersSubmission(sid, uid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	links := make([]gin.H, len(submission.ShareLinks))
+	for index, link := range submission.ShareLinks {
+		links[index] = gin.H{
+			"shareLink": link,
+			"active":    link.Active(),
+			"url":       shareURL(c, shareToken(submission.ID, link.ID)),
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"message":    "Share Links Found.",
+		"shareLinks": links,
+	})
+}
+
+// RevokeShareLink stops one of the student's share links from working.
+func RevokeShareLink(c *gin.Context) {
+	sid, _ := c.Get("sid")
+	shid, _ := c.Get("shid")
+	uid, _ := c.Get("uid")
+
+	submission, err := sm.GetUsersSubmission(sid, uid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = sm.RevokeShareLink(submission.ID, shid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Share Link Revoked.",
+	})
+}
+
+// ViewSharedSubmission shows the results of a shared submission to anyone
+// with the link, filtered as for the student and without where it was sent
+// from. Nothing can be changed through it.
+func ViewSharedSubmission(c *gin.Context) {
+	submission, err := sharedSubmission(c.Param("token"))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+	submission.FilterForShare()
+
+	assign, err := am.Get(submission.AssignmentID)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":    "Shared Submission.",
+		"assignment": assign.Name,
+		"submission": submission,
+		"score":      submission.Score(),
+		"daysLate":   assign.DaysLate(submission.UserID, submission.SubmissionDate),
+	})
+}
@@ -0,0 +1,249 @@
+package cms
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	jwt "github.com/appleboy/gin-jwt"
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/models/cmsmodels/coursemodels"
+	"backend/models/cmsmodels/disputemodels"
+	"backend/models/cmsmodels/extensionmodels"
+	"backend/models/cmsmodels/submissionmodels"
+	"backend/utils"
+)
+
+// staffFlagWindow how long after its due date an assignment's shared
+// submission sources are still flagged.
+const staffFlagWindow = 14 * 24 * time.Hour
+
+type (
+	// staffTask something staff have to act on, waiting since Since. Flags
+	// are IPs or device fingerprints, by Source, that submitted an
+	// assignment for several accounts.
+	staffTask struct {
+		Kind         string              `json:"kind"`
+		CourseID     primitive.ObjectID  `json:"courseID"`
+		AssignmentID primitive.ObjectID  `json:"assignmentID"`
+		ID           *primitive.ObjectID `json:"id,omitempty"`
+		StudentID    *primitive.ObjectID `json:"studentID,omitempty"`
+		Source       string              `json:"source,omitempty"`
+		Accounts     int                 `json:"accounts,omitempty"`
+		Since        primitive.DateTime  `json:"since"`
+		Link         string              `json:"link"`
+	}
+
+	// staffTaskCourse how many tasks of each kind a course has.
+	staffTaskCourse struct {
+		ID         primitive.ObjectID `json:"id"`
+		Department string             `json:"department"`
+		Number     int                `json:"number"`
+		Section    string             `json:"section"`
+		LongName   string             `json:"longName"`
+		Role       string             `json:"role"`
+		Counts     map[string]int     `json:"counts"`
+	}
+)
+
+// courseStaffTasks the tasks of a course a staff member may act on,
+// assistants only those of their sections' students when grading is scoped
+// to sections. Grading tasks handed out go to their grader alone.
+func courseStaffTasks(course *coursemodels.MongoCourse, uid primitive.ObjectID, now time.Time) ([]staffTask, errors.APIError) {
+	tasks := make([]staffTask, 0)
+	cid := course.ID.Hex()
+
+	awaiting, err := sm.AwaitingReview(course.Assignments)
+	if err != nil {
+		return nil, err
+	}
+	open, err := gtm.OpenByAssignments(course.Assignments)
+	if err != nil {
+		return nil, err
+	}
+	graders := make(map[primitive.ObjectID]primitive.ObjectID, len(open))
+	for _, task := range open {
+		graders[task.SubmissionID] = task.GraderID
+	}
+	for _, submission := range awaiting {
+		grader, assigned := graders[submission.ID]
+		if (assigned && grader != uid) || (!assigned && !canGrade(course, uid, submission.UserID)) {
+			continue
+		}
+
+		sid, suid := submission.ID, submission.UserID
+		tasks = append(tasks, staffTask{
+			Kind:         "grading",
+			CourseID:     course.ID,
+			AssignmentID: submission.AssignmentID,
+			ID:           &sid,
+			StudentID:    &suid,
+			Since:        submission.SubmissionDate,
+			Link:         fmt.Sprintf("/course/%s/assignment/%s/submission/%s", cid, submission.AssignmentID.Hex(), sid.Hex()),
+		})
+	}
+
+	disputes := make([]disputemodels.MongoDispute, 0)
+	for _, status := range []string{disputemodels.StatusOpen, disputemodels.StatusInReview} {
+		found, err := dsm.Find(course.ID, nil, status)
+		if err != nil {
+			return nil, err
+		}
+		disputes = append(disputes, found...)
+	}
+	for _, dispute := range disputes {
+		if !canGrade(course, uid, dispute.UserID) {
+			continue
+		}
+
+		did, suid := dispute.ID, dispute.UserID
+		tasks = append(tasks, staffTask{
+			Kind:         "dispute",
+			CourseID:     course.ID,
+			AssignmentID: dispute.AssignmentID,
+			ID:           &did,
+			StudentID:    &suid,
+			Since:        dispute.CreatedAt,
+			Link:         fmt.Sprintf("/course/%s/dispute/%s", cid, did.Hex()),
+		})
+	}
+
+	requests, err := exm.Find(course.ID, nil, extensionmodels.StatusPending)
+	if err != nil {
+		return nil, err
+	}
+	for _, request := range requests {
+		if !canGrade(course, uid, request.UserID) {
+			continue
+		}
+
+		erid, suid := request.ID, request.UserID
+		tasks = append(tasks, staffTask{
+			Kind:         "extension",
+			CourseID:     course.ID,
+			AssignmentID: request.AssignmentID,
+			ID:           &erid,
+			StudentID:    &suid,
+			Since:        request.CreatedAt,
+			Link:         fmt.Sprintf("/course/%s/extension/%s", cid, erid.Hex()),
+		})
+	}
+
+	for _, aid := range course.Assignments {
+		assign, err := am.Get(aid)
+		if err != nil || !assign.Published || now.Sub(utils.DateTimeToTime(assign.DueDate)) > staffFlagWindow {
+			continue
+		}
+
+		from, to := time.Time{}, now
+		if start, end, ok := examPeriod(assign); ok && assign.Timed() {
+			from, to = start, end
+		}
+
+		for _, source := range []string{"ip", "fingerprint"} {
+			shared, err := sm.SharedSources(aid, source, from, to, 2)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, flagged := range shared {
+				if !anyGradeable(course, uid, flagged) {
+					continue
+				}
+
+				tasks = append(tasks, staffTask{
+					Kind:         "flag",
+					CourseID:     course.ID,
+					AssignmentID: aid,
+					Source:       source,
+					Accounts:     len(flagged.Users),
+					Since:        flagged.Submissions[0].SubmissionDate,
+					Link:         fmt.Sprintf("/course/%s/assignment/%s/anomalies", cid, aid.Hex()),
+				})
+			}
+		}
+	}
+
+	return tasks, nil
+}
+
+// anyGradeable whether a staff member may grade any of the accounts a source
+// was shared by.
+func anyGradeable(course *coursemodels.MongoCourse, uid primitive.ObjectID, shared submissionmodels.SharedSource) bool {
+	for _, suid := range shared.Users {
+		if canGrade(course, uid, suid) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StaffTasks gathers what needs a staff member's attention across the
+// courses they teach or assist: submissions to grade on the rubric, open
+// disputes, pending extension requests and recently flagged shared
+// submission sources, oldest first, with how many of each every course has.
+func StaffTasks(c *gin.Context) {
+	uid, _ := c.Get("uid")
+
+	claims := jwt.ExtractClaims(c)
+	courses, err := um.GetCourses(uid, claims["courses"].(map[string]interface{}))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	staff := uid.(primitive.ObjectID)
+	now := time.Now()
+	tasks := make([]staffTask, 0)
+	summaries := make([]staffTaskCourse, 0)
+	counts := map[string]int{"grading": 0, "dispute": 0, "extension": 0, "flag": 0}
+	for _, course := range courses {
+		if course.Role != "teacher" && course.Role != "assistant" {
+			continue
+		}
+
+		full, err := cm.GetByID(course.ID)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		courseTasks, err := courseStaffTasks(full, staff, now)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		summary := staffTaskCourse{
+			ID:         course.ID,
+			Department: course.Department,
+			Number:     course.Number,
+			Section:    course.Section,
+			LongName:   course.LongName,
+			Role:       course.Role,
+			Counts:     map[string]int{"grading": 0, "dispute": 0, "extension": 0, "flag": 0},
+		}
+		for _, task := range courseTasks {
+			summary.Counts[task.Kind]++
+			counts[task.Kind]++
+		}
+
+		summaries = append(summaries, summary)
+		tasks = append(tasks, courseTasks...)
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].Since < tasks[j].Since
+	})
+
+	c.JSON(200, gin.H{
+		"message": "Staff Tasks.",
+		"counts":  counts,
+		"courses": summaries,
+		"tasks":   tasks,
+	})
+}
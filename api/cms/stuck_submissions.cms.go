@@ -0,0 +1,88 @@
+package cms
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/config"
+	"backend/courtherald"
+	"backend/errors"
+	"backend/models/cmsmodels/submissionmodels"
+)
+
+// failGrading marks a submission as failed to grade and lets the student know.
+func failGrading(submission submissionmodels.MongoSubmission, reason string) errors.APIError {
+	err := sm.MarkGradingFailed(submission.ID, reason)
+	if err != nil {
+		return err
+	}
+
+	course, err := cm.FindByAssignment(submission.AssignmentID)
+	if err != nil {
+		return err
+	}
+
+	return nm.Notify(
+		[]primitive.ObjectID{submission.UserID},
+		course.ID,
+		"submission",
+		fmt.Sprintf("%s %d: attempt %d could not be graded", course.Department, course.Number, submission.AttemptNumber),
+		fmt.Sprintf("/course/%s/assignment/%s", course.ID.Hex(), submission.AssignmentID.Hex()),
+	)
+}
+
+// recoverSubmission checks on a submission stuck in grading. Jobs still running
+// are left alone, otherwise the submission is requeued, or failed once it has
+// been requeued too many times.
+func recoverSubmission(submission submissionmodels.MongoSubmission, maxRequeues int) errors.APIError {
+	status, err := sm.JobStatus(&submission)
+	if err != nil {
+		return err
+	}
+
+	if status == courtherald.JobActive {
+		return nil
+	}
+
+	if submission.Requeues >= maxRequeues {
+		return failGrading(submission, "The grader stopped responding while testing this submission. Please resubmit or contact course staff.")
+	}
+
+	assign, err := am.Get(submission.AssignmentID)
+	if err != nil {
+		return failGrading(submission, "The assignment for this submission no longer exists.")
+	}
+
+	graders, err := GradersFor(assign)
+	if err != nil {
+		return err
+	}
+
+	_, err = sm.Requeue(submission, assign.Job(submission.UserID), graders)
+	return err
+}
+
+// RecoverStuckSubmissions is a job that looks for submissions that have been in
+// grading for longer than the timeout and recovers them. It fails, to be
+// retried, while court herald is unreachable.
+func RecoverStuckSubmissions([]byte) error {
+	submissions, err := sm.InProgressBefore(time.Now().Add(-config.C.StuckSubmissionTimeout))
+	if err != nil {
+		return err
+	}
+
+	for _, submission := range submissions {
+		err = recoverSubmission(submission, config.C.StuckSubmissionMaxRequeues)
+		if err == errors.ErrorUnableToReachMicroService {
+			return err
+		}
+		if err != nil {
+			tyrgin.ErrorLogger(err, "Failed to recover submission "+submission.ID.Hex())
+		}
+	}
+
+	return nil
+}
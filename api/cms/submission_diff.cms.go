@@ -0,0 +1,156 @@
+package cms
+
+import (
+	"bytes"
+	"io/ioutil"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/models/cmsmodels/submissionmodels"
+	"backend/utils"
+)
+
+// maxDiffFileSize files larger than this are only reported as changed.
+const maxDiffFileSize = 256 * 1024
+
+// fileDiff how one file changed between two submissions.
+type fileDiff struct {
+	Path string `json:"path"`
+	// Status is added, removed or modified.
+	Status string `json:"status"`
+	Diff   string `json:"diff,omitempty"`
+	// Binary and TooLarge files are not diffed.
+	Binary   bool `json:"binary,omitempty"`
+	TooLarge bool `json:"tooLarge,omitempty"`
+}
+
+// diffSubmission finds a submission to diff, that the user is allowed to see.
+func diffSubmission(c *gin.Context, hex string) (*submissionmodels.MongoSubmission, map[string][]byte, errors.APIError) {
+	aid, _ := c.Get("aid")
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	sid, errs := primitive.ObjectIDFromHex(hex)
+	if errs != nil {
+		return nil, nil, errors.ErrorInvalidQuery
+	}
+
+	submission, err := sm.Get(sid, role.(string))
+	if err != nil || submission.AssignmentID != aid || (role == "student" && submission.UserID != uid) {
+		return nil, nil, errors.ErrorResourceNotFound
+	}
+
+	if submission.FilePurged {
+		return nil, nil, errors.ErrorResourceNotFound
+	}
+
+	file, _, err := gfs.Download(submission.FileID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, errs := ioutil.ReadAll(file)
+	if errs != nil {
+		return nil, nil, errors.ErrorFailedToReadFile
+	}
+
+	files, err := utils.ArchiveFiles(content, maxDiffFileSize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return submission, files, nil
+}
+
+func isBinary(content []byte) bool {
+	return bytes.IndexByte(content, 0) >= 0 || !utf8.Valid(content)
+}
+
+// SubmissionDiff shows what changed between two submissions of the same
+// student, as a unified diff of every file that changed. Students can only
+// compare their own submissions.
+func SubmissionDiff(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	from, fromFiles, err := diffSubmission(c, c.Query("from"))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	to, toFiles, err := diffSubmission(c, c.Query("to"))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if from.UserID != to.UserID {
+		c.Set("error", errors.ErrorInvalidQuery)
+		return
+	}
+
+	paths := make([]string, 0, len(fromFiles)+len(toFiles))
+	for path := range fromFiles {
+		paths = append(paths, path)
+	}
+	for path := range toFiles {
+		if _, found := fromFiles[path]; !found {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	diffs := make([]fileDiff, 0)
+	for _, path := range paths {
+		before, inFrom := fromFiles[path]
+		after, inTo := toFiles[path]
+
+		diff := fileDiff{Path: path, Status: "modified"}
+		switch {
+		case !inFrom:
+			diff.Status = "added"
+		case !inTo:
+			diff.Status = "removed"
+		case before != nil && after != nil && bytes.Equal(before, after):
+			continue
+		}
+
+		fromName, toName := "a/"+path, "b/"+path
+		if !inFrom {
+			fromName = "/dev/null"
+		}
+		if !inTo {
+			toName = "/dev/null"
+		}
+
+		switch {
+		case (inFrom && before == nil) || (inTo && after == nil):
+			diff.TooLarge = true
+		case isBinary(before) || isBinary(after):
+			diff.Binary = true
+		default:
+			text, ok := utils.UnifiedDiff(fromName, toName, string(before), string(after), 3)
+			diff.Diff = text
+			diff.TooLarge = !ok
+		}
+
+		diffs = append(diffs, diff)
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Submission diff.",
+		"from":    from.ID,
+		"to":      to.ID,
+		"files":   diffs,
+	})
+}
@@ -0,0 +1,118 @@
+package cms
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/errors"
+)
+
+// SubmissionHistory downloads a zip of every attempt a student made at an
+// assignment, each with its submitted files and results, for grade disputes
+// and accreditation evidence. Students can only download their own.
+func SubmissionHistory(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	uid, _ := c.Get("uid")
+	suid, _ := c.Get("suid")
+	role, _ := c.Get("role")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	if role == "student" && suid != uid {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	assign, err := am.Get(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	student, err := um.FindOneById(suid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	submissions, err := sm.GetUsersAssignmentSubmissions(aid, suid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	archive := zip.NewWriter(buf)
+
+	history := gin.H{
+		"assignment": gin.H{
+			"id":      assign.ID,
+			"name":    assign.Name,
+			"dueDate": assign.DueDate,
+		},
+		"student": gin.H{
+			"id":        student.ID,
+			"email":     student.Email,
+			"firstName": student.First,
+			"lastName":  student.Last,
+		},
+		"attempts":    len(submissions),
+		"generatedAt": time.Now(),
+	}
+	if err = writeJSONToZip(archive, "history.json", history); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	for _, submission := range submissions {
+		if role == "student" {
+			submission.FilterForStudent()
+		}
+
+		dir := fmt.Sprintf("attempt-%d", submission.AttemptNumber)
+		if err = writeJSONToZip(archive, dir+"/results.json", submission); err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		if submission.FilePurged {
+			continue
+		}
+
+		file, _, err := gfs.Download(submission.FileID)
+		if err != nil {
+			continue
+		}
+
+		w, errs := archive.Create(fmt.Sprintf("%s/%s", dir, submission.File))
+		if errs != nil {
+			c.Set("error", errors.ErrorFailedToCreateArchive)
+			return
+		}
+
+		if _, errs = io.Copy(w, file); errs != nil {
+			c.Set("error", errors.ErrorFailedToCreateArchive)
+			return
+		}
+	}
+
+	if errs := archive.Close(); errs != nil {
+		c.Set("error", errors.ErrorFailedToCreateArchive)
+		return
+	}
+
+	additonalHeaders := map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s-%s-history.zip"`, assign.ID.Hex(), student.ID.Hex()),
+	}
+
+	c.DataFromReader(200, int64(buf.Len()), "application/zip", buf, additonalHeaders)
+}
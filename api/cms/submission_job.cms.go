@@ -0,0 +1,212 @@
+package cms
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/courtherald"
+	"backend/errors"
+	"backend/events"
+	"backend/models/cmsmodels/submissionmodels"
+	"backend/utils"
+)
+
+// jobCacheTTL how long a job status fetched from court herald is reused, so a
+// staff member refreshing a page does not hammer the grader.
+const jobCacheTTL = 10 * time.Second
+
+type cachedJob struct {
+	details   *courtherald.JobStatusResponse
+	fetchedAt time.Time
+}
+
+var (
+	jobCache   = make(map[string]cachedJob)
+	jobCacheMu sync.Mutex
+)
+
+func jobDetails(submission *submissionmodels.MongoSubmission, tail int) (*courtherald.JobStatusResponse, time.Time, errors.APIError) {
+	key := submission.ID.Hex() + ":" + strconv.Itoa(tail)
+
+	jobCacheMu.Lock()
+	cached, found := jobCache[key]
+	jobCacheMu.Unlock()
+	if found && time.Since(cached.fetchedAt) < jobCacheTTL {
+		return cached.details, cached.fetchedAt, nil
+	}
+
+	details, err := sm.JobDetails(submission, tail)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	now := time.Now()
+	jobCacheMu.Lock()
+	for k, job := range jobCache {
+		if time.Since(job.fetchedAt) >= jobCacheTTL {
+			delete(jobCache, k)
+		}
+	}
+	jobCache[key] = cachedJob{details, now}
+	jobCacheMu.Unlock()
+
+	return details, now, nil
+}
+
+// forgetJob drops the cached job statuses of a submission once it changes.
+func forgetJob(sid primitive.ObjectID) {
+	prefix := sid.Hex() + ":"
+
+	jobCacheMu.Lock()
+	defer jobCacheMu.Unlock()
+
+	for key := range jobCache {
+		if strings.HasPrefix(key, prefix) {
+			delete(jobCache, key)
+		}
+	}
+}
+
+// SubmissionJob shows staff the status of the court herald job grading a
+// submission. The number of log lines returned is set with ?tail=, 100 by default.
+func SubmissionJob(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	sid, _ := c.Get("sid")
+	role, _ := c.Get("role")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	submission, err := sm.Get(sid, role.(string))
+	if err != nil || submission.AssignmentID != aid {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	if submission.Queued {
+		c.JSON(200, gin.H{
+			"message":              "Submission grading job.",
+			"queued":               true,
+			"estimatedWaitSeconds": estimatedWait(submission),
+		})
+		return
+	}
+
+	tail, errs := strconv.Atoi(c.DefaultQuery("tail", "100"))
+	if errs != nil || tail < 0 || tail > 1000 {
+		tail = 100
+	}
+
+	details, fetchedAt, err := jobDetails(submission, tail)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":      "Submission grading job.",
+		"job":          submission.Job,
+		"inProgress":   submission.InProgress,
+		"queued":       submission.Queued,
+		"dispatchedAt": submission.DispatchedAt,
+		"requeues":     submission.Requeues,
+		"details":      details,
+		"fetchedAt":    fetchedAt,
+	})
+}
+
+// gradedWait how long a progress stream waits, after court herald is done,
+// for the submission's results to be recorded.
+const gradedWait = time.Minute
+
+// SubmissionProgress streams the progress of a submission's grading job as
+// server sent events, then a graded event with the submission once its
+// results are recorded, by whichever replica court herald reports to, or
+// until the client goes away. Submissions not being graded get a single
+// event of where they are.
+func SubmissionProgress(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	sid, _ := c.Get("sid")
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	submission, err := sm.GetUsersSubmission(sid, uid)
+	if err != nil && role != "student" {
+		submission, err = sm.Get(sid, role.(string))
+	}
+	if err != nil || submission.AssignmentID != aid {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	switch {
+	case !submission.InProgress:
+		c.SSEvent("progress", courtherald.ProgressEvent{Stage: courtherald.StageDone, At: time.Now()})
+		return
+	case submission.Queued:
+		c.SSEvent("progress", courtherald.ProgressEvent{Stage: courtherald.StageQueued, At: time.Now()})
+		return
+	}
+
+	assign, err := am.Get(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	// watched before streaming so results recorded meanwhile are not missed
+	graded := make(chan struct{}, 1)
+	stop := events.Watch(events.SubmissionsCollection, func(change events.Change) {
+		if change.ID == submission.ID && change.Graded() {
+			select {
+			case graded <- struct{}{}:
+			default:
+			}
+		}
+	})
+	defer stop()
+
+	ctx := c.Request.Context()
+	view := utils.ViewFor(role.(string))
+	started := false
+	err = courtherald.WatchProgress(ctx, submission.GraderURL, submission.ID, func(event courtherald.ProgressEvent) bool {
+		started = true
+		c.SSEvent("progress", submissionmodels.RedactProgress(event, view, assign.ShowHiddenSummary))
+		c.Writer.Flush()
+		return true
+	})
+	if err != nil && !started {
+		c.Set("error", err)
+		return
+	}
+
+	select {
+	case <-graded:
+	case <-time.After(gradedWait):
+		return
+	case <-ctx.Done():
+		return
+	}
+
+	submission, err = sm.Get(sid, "any")
+	if err != nil {
+		return
+	}
+	submission.RedactFor(view, assign.ShowHiddenSummary)
+	c.SSEvent("graded", submission)
+}
@@ -0,0 +1,70 @@
+package cms
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/errors"
+)
+
+// SubmissionOutput serves the full output of one of a submission's test
+// results, including outputs too large to be stored on the submission.
+// Students can only read the student facing results of their own submissions.
+func SubmissionOutput(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	sid, _ := c.Get("sid")
+	uid, _ := c.Get("uid")
+	role, _ := c.Get("role")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	submission, err := sm.Get(sid, role.(string))
+	if err != nil || submission.AssignmentID != aid {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	if role == "student" && submission.UserID != uid {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	num, errs := strconv.Atoi(c.Param("num"))
+	if errs != nil {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	for _, result := range submission.Results {
+		if result.ID != num {
+			continue
+		}
+
+		additonalHeaders := map[string]string{
+			"Content-Disposition": fmt.Sprintf(`inline; filename="%s-result-%d.txt"`, submission.ID.Hex(), result.ID),
+		}
+
+		if result.OutputFileID == nil {
+			c.DataFromReader(200, int64(len(result.Output)), "text/plain; charset=utf-8", strings.NewReader(result.Output), additonalHeaders)
+			return
+		}
+
+		file, numBytes, err := gfs.Download(*result.OutputFileID)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		c.DataFromReader(200, numBytes, "text/plain; charset=utf-8", file, additonalHeaders)
+		return
+	}
+
+	c.Set("error", errors.ErrorResourceNotFound)
+}
@@ -0,0 +1,110 @@
+package cms
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/errors"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/models/cmsmodels/submissionmodels"
+)
+
+// submissionSource records where a submission came from. The device
+// fingerprint hashes the fingerprint the client sends, falling back to the
+// headers identifying the browser.
+func submissionSource(c *gin.Context) *submissionmodels.Source {
+	device := c.GetHeader("X-Device-Fingerprint")
+	if device == "" {
+		device = c.Request.UserAgent() + "|" + c.GetHeader("Accept-Language")
+	}
+	hash := sha256.Sum256([]byte(device))
+
+	return &submissionmodels.Source{
+		IP:          c.ClientIP(),
+		UserAgent:   c.Request.UserAgent(),
+		Fingerprint: hex.EncodeToString(hash[:]),
+	}
+}
+
+// examPeriod the time from the first student starting a timed assignment to
+// the last window closing.
+func examPeriod(assign *assignmentmodels.MongoAssignment) (time.Time, time.Time, bool) {
+	var from, to time.Time
+	for _, start := range assign.Starts {
+		window := assign.Window(start.UserID)
+		if from.IsZero() || window.StartedAt.Before(from) {
+			from = window.StartedAt
+		}
+		if window.EndsAt.After(to) {
+			to = window.EndsAt
+		}
+	}
+
+	return from, to, !from.IsZero()
+}
+
+// SubmissionAnomalies flags IPs and devices that submitted an assignment for
+// several accounts. Timed assignments are checked over the exam, others over
+// the whole assignment unless from and to (RFC3339) are given.
+func SubmissionAnomalies(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assign, err := am.Get(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	minAccounts, errs := strconv.Atoi(c.DefaultQuery("minAccounts", "2"))
+	if errs != nil || minAccounts < 2 {
+		c.Set("error", errors.ErrorInvalidQuery)
+		return
+	}
+
+	from, to := time.Time{}, time.Now()
+	if start, end, ok := examPeriod(assign); ok && assign.Timed() {
+		from, to = start, end
+	}
+	if c.Query("from") != "" {
+		if from, errs = time.Parse(time.RFC3339, c.Query("from")); errs != nil {
+			c.Set("error", errors.ErrorInvalidQuery)
+			return
+		}
+	}
+	if c.Query("to") != "" {
+		if to, errs = time.Parse(time.RFC3339, c.Query("to")); errs != nil {
+			c.Set("error", errors.ErrorInvalidQuery)
+			return
+		}
+	}
+
+	sharedIPs, err := sm.SharedSources(aid, "ip", from, to, minAccounts)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	sharedDevices, err := sm.SharedSources(aid, "fingerprint", from, to, minAccounts)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":       "Submission Anomalies.",
+		"from":          from,
+		"to":            to,
+		"sharedIPs":     sharedIPs,
+		"sharedDevices": sharedDevices,
+	})
+}
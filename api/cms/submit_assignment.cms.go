@@ -2,74 +2,353 @@ package cms
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"fmt"
+	"time"
 
-	jwt "github.com/appleboy/gin-jwt"
 	"github.com/gin-gonic/gin"
 	"github.com/mongodb/mongo-go-driver/bson/primitive"
 
 	"backend/errors"
+	"backend/features"
+	"backend/forms"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/models/cmsmodels/submissionmodels"
+	"backend/models/usermodels"
 	"backend/utils"
 )
 
-// SubmitAssignment will submit and grade the submission. Also updates the assignment.
-func SubmitAssignment(c *gin.Context) {
-	sub, err := c.FormFile("submission")
+// submissionReceipt what a student is told once their submission is queued.
+// Submissions held back while court herald is overloaded have no job yet and
+// an estimate of how long they will wait.
+type submissionReceipt struct {
+	SubmissionID         primitive.ObjectID `json:"submissionID"`
+	AttemptNumber        int                `json:"attemptNumber"`
+	Job                  string             `json:"job"`
+	Queued               bool               `json:"queued"`
+	EstimatedWaitSeconds int                `json:"estimatedWaitSeconds"`
+	// Manual submissions wait for staff to grade them instead.
+	Manual bool `json:"-"`
+}
+
+// status what the receipt tells a student their submission is doing.
+func (r *submissionReceipt) status() string {
+	if r.Manual {
+		return "submitted"
+	}
+
+	if r.Queued {
+		return "queued"
+	}
+
+	return "grading"
+}
+
+// submitAssignment stores the uploaded submission and starts grading it. Shared
+// by the web and command line submit endpoints.
+func submitAssignment(c *gin.Context) (*submissionReceipt, errors.APIError) {
+	sub, errs := c.FormFile("submission")
+	if errs != nil {
+		return nil, errors.ErrorUploadingFile
+	}
+
+	cid, _ := c.Get("cid")
+	uid, _ := c.Get("uid")
+	aid, _ := c.Get("aid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		return nil, err
+	}
+
+	assign, err := am.Get(aid)
 	if err != nil {
-		c.Set("error", err)
-		return
+		return nil, err
 	}
 
-	submissionFiles, err := utils.CheckFileType(sub)
+	// manually graded assignments take the document itself
+	var submissionFiles []byte
+	if assign.Manual {
+		submissionFiles, _, err = utils.CheckAttachmentType(sub)
+		if err == errors.ErrorUnsupportedFileType {
+			err = errors.ErrorManualSubmissionType
+		}
+	} else {
+		submissionFiles, err = utils.CheckFileType(sub)
+	}
 	if err != nil {
-		c.Set("error", err)
-		return
+		return nil, err
 	}
 
-	// Upload
-	claims := jwt.ExtractClaims(c)
+	return submitFiles(c, aid, uid, submissionFiles, c.PostForm("acceptAttestation") == "true", nil)
+}
+
+// checkCooldown refuses a student's submission until the assignment's
+// cooldown since their last one has passed, telling them when it does.
+func checkCooldown(c *gin.Context, assign *assignmentmodels.MongoAssignment, uid interface{}) errors.APIError {
+	if assign.CooldownMinutes == 0 {
+		return nil
+	}
+
+	submissions, err := sm.GetUsersAssignmentSubmissions(assign.ID, uid)
+	if err != nil || len(submissions) == 0 {
+		return err
+	}
+
+	last := utils.DateTimeToTime(submissions[len(submissions)-1].SubmissionDate)
+	retryAt := last.Add(time.Duration(assign.CooldownMinutes) * time.Minute)
+	if time.Now().Before(retryAt) {
+		c.Set("errorDetails", gin.H{"retryAt": retryAt})
+		return errors.ErrorSubmissionCooldown
+	}
+
+	return nil
+}
+
+// checkStudentRules holds a student of the course to the assignment's
+// prerequisites, late policy and cooldown. Whether the submitter is a student
+// comes from their enrollment rather than the request's role, as pushes to a
+// linked repository are submitted without one.
+func checkStudentRules(c *gin.Context, assign *assignmentmodels.MongoAssignment, submitter *usermodels.MongoUser, cid primitive.ObjectID) errors.APIError {
+	if submitter.CoursesAsMap()[cid.Hex()] != "student" {
+		return nil
+	}
+
+	if err := checkPrerequisites(c, assign, submitter.ID); err != nil {
+		return err
+	}
+
+	if !assign.AcceptsLate(submitter.ID, time.Now()) {
+		return errors.ErrorSubmissionTooLate
+	}
+
+	return checkCooldown(c, assign, submitter.ID)
+}
+
+// submitFiles checks the student can submit, stores the submitted tar.gz and
+// starts grading it. Submissions of manually graded assignments are the
+// document, stored for staff to grade instead.
+func submitFiles(c *gin.Context, aid, uid interface{}, submissionFiles []byte, acceptedAttestation bool, repository *submissionmodels.Repository) (*submissionReceipt, errors.APIError) {
+	// See if previous submission exists
+	assign, attempt, err := am.LatestUserSubmission(aid, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if attempt+1 > assign.NumAttempts {
+		return nil, errors.ErrorSubmissionAttemptsExceeded
+	}
+
+	if assign.Quiz != nil {
+		return nil, errors.ErrorQuizAssignment
+	}
+
+	if assign.Manual && repository != nil {
+		return nil, errors.ErrorManualSubmissionType
+	}
+
+	course, err := cm.FindByAssignment(aid)
+	if err != nil {
+		return nil, err
+	}
+
+	submitter, err := um.FindOneById(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkStudentRules(c, assign, submitter, course.ID); err != nil {
+		return nil, err
+	}
 
+	var attestation *submissionmodels.Attestation
+	if assign.Attestation != "" {
+		if !acceptedAttestation {
+			return nil, errors.ErrorAttestationRequired
+		}
+
+		attestation = &submissionmodels.Attestation{
+			Text:       assign.Attestation,
+			AcceptedAt: utils.TimeToDateTime(time.Now()),
+			IP:         c.ClientIP(),
+		}
+	}
+
+	if assign.Precheck != nil && !assign.Manual {
+		paths, err := utils.ArchivePaths(submissionFiles)
+		if err != nil {
+			return nil, err
+		}
+
+		if mismatch := assign.CheckManifest(paths); mismatch != nil {
+			c.Set("errorDetails", mismatch)
+			return nil, errors.ErrorManifestMismatch
+		}
+	}
+
+	artifactUploads, err := readArtifacts(c, assign)
+	if err != nil {
+		return nil, err
+	}
+
+	if assign.Timed() {
+		window := assign.Window(uid.(primitive.ObjectID))
+		if window == nil {
+			return nil, errors.ErrorAssignmentNotStarted
+		}
+
+		if time.Now().After(window.EndsAt) {
+			return nil, errors.ErrorSubmissionWindowClosed
+		}
+	}
+
+	size := int64(len(submissionFiles)) + artifactBytes(artifactUploads)
+	if err = checkQuota(course, size); err != nil {
+		return nil, err
+	}
+
+	var graders []string
+	if !assign.Manual {
+		graders, err = GradersFor(assign)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Upload
 	sid := primitive.NewObjectID()
 	fid := primitive.NewObjectID()
-	submittedFilesName := fmt.Sprintf("sub-%s-%s.tar.gz", c.Param("aid"), claims["uid"])
+	submittedFilesName := fmt.Sprintf("sub-%s-%s.tar.gz", aid.(primitive.ObjectID).Hex(), uid.(primitive.ObjectID).Hex())
+	if assign.Manual {
+		submittedFilesName = documentName(c, aid, uid)
+	}
 	reader := bytes.NewReader(submissionFiles)
 	err = gfs.Upload(&fid, submittedFilesName, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	artifacts, err := storeArtifacts(sid, artifactUploads)
+	if err != nil {
+		gfs.Delete(fid)
+		return nil, err
+	}
+
+	err = am.InsertSubmission(aid, uid, sid, attempt+1)
+	if err != nil {
+		gfs.Delete(fid)
+		deleteArtifacts(artifacts)
+		return nil, err
+	}
+
+	provenance := submissionmodels.Provenance{
+		Attestation: attestation,
+		Source:      submissionSource(c),
+		Repository:  repository,
+		Artifacts:   artifacts,
+		Checksum:    fmt.Sprintf("%x", sha256.Sum256(submissionFiles)),
+	}
+
+	if assign.Manual {
+		err = sm.SubmitManual(aid, fid, uid, sid, attempt+1, submittedFilesName, provenance)
+		if err != nil {
+			am.DeleteSubmission(aid, sid)
+			gfs.Delete(fid)
+			deleteArtifacts(artifacts)
+			return nil, err
+		}
+
+		cm.AddUsage(course.ID, size, 0)
+		sm.RecordUsage(sid, int64(len(submissionFiles)), 0)
+
+		return &submissionReceipt{SubmissionID: sid, AttemptNumber: attempt + 1, Manual: true}, nil
+	}
+
+	job, hold, err := sm.Submit(aid, fid, uid, sid, attempt+1, submittedFilesName, assign.Job(uid.(primitive.ObjectID)), graders, provenance, gradingOverloaded())
+	if err != nil {
+		am.DeleteSubmission(aid, sid)
+		gfs.Delete(fid)
+		deleteArtifacts(artifacts)
+		return nil, err
+	}
+
+	cm.AddUsage(course.ID, size, 0)
+	sm.RecordUsage(sid, int64(len(submissionFiles)), 0)
+	enqueueCodeIndex(sid)
+
+	receipt := &submissionReceipt{SubmissionID: sid, AttemptNumber: attempt + 1, Job: job, Queued: hold}
+	if hold {
+		receipt.EstimatedWaitSeconds = estimatedWait(&submissionmodels.MongoSubmission{
+			Queued:         true,
+			SubmissionDate: utils.TimeToDateTime(time.Now()),
+		})
+	}
+
+	return receipt, nil
+}
+
+// SubmitAssignment will submit and grade the submission. Also updates the assignment.
+func SubmitAssignment(c *gin.Context) {
+	receipt, err := submitAssignment(c)
 	if err != nil {
 		c.Set("error", err)
 		return
 	}
 
+	c.JSON(201, gin.H{
+		"status_code":          201,
+		"message":              "Submission Grader Started.",
+		"job":                  receipt.Job,
+		"submissionID":         receipt.SubmissionID,
+		"status":               receipt.status(),
+		"estimatedWaitSeconds": receipt.EstimatedWaitSeconds,
+	})
+}
+
+// SubmitRepository submits a commit of a git repository instead of an upload.
+// The commit is archived server side and graded like an uploaded tarball.
+func SubmitRepository(c *gin.Context) {
+	if !featureEnabled(c, features.GitSubmissions) {
+		return
+	}
+
+	cid, _ := c.Get("cid")
 	uid, _ := c.Get("uid")
 	aid, _ := c.Get("aid")
 
-	// See if previous submission exists
-	assign, attempt, err := am.LatestUserSubmission(aid, uid)
-	if err != nil {
+	if err := checkCourseAssignment(cid, aid); err != nil {
 		c.Set("error", err)
 		return
 	}
 
-	if attempt+1 > assign.NumAttempts {
-		c.Set("error", errors.ErrorSubmissionAttemptsExceeded)
+	var form forms.SubmitRepositoryForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
 		return
 	}
 
-	err = am.InsertSubmission(aid, uid, sid, attempt+1)
+	submissionFiles, err := utils.ArchiveCommit(form.Repository, form.Commit)
 	if err != nil {
 		c.Set("error", err)
 		return
 	}
 
-	job, err := sm.Submit(aid, fid, uid, sid, attempt+1, submittedFilesName, assign.Tests, assign.TestBuildCMD, assign.Language)
+	repository := &submissionmodels.Repository{
+		URL:    form.Repository,
+		Commit: form.Commit,
+	}
+
+	receipt, err := submitFiles(c, aid, uid, submissionFiles, form.AcceptAttestation, repository)
 	if err != nil {
-		am.DeleteSubmission(aid, sid)
 		c.Set("error", err)
 		return
 	}
 
 	c.JSON(201, gin.H{
-		"status_code": 201,
-		"message":     "Submission Grader Started.",
-		"job":         job,
+		"status_code":          201,
+		"message":              "Submission Grader Started.",
+		"job":                  receipt.Job,
+		"submissionID":         receipt.SubmissionID,
+		"status":               receipt.status(),
+		"estimatedWaitSeconds": receipt.EstimatedWaitSeconds,
 	})
 }
@@ -0,0 +1,45 @@
+package cms
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/models/usermodels"
+	"backend/utils"
+)
+
+// A push to a linked repository is submitted with the webhook's context,
+// which has no role, so the rules must follow from the enrollment alone.
+func TestCheckStudentRulesOnPushes(t *testing.T) {
+	cid := primitive.NewObjectID()
+	pushed, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	enrolled := func(role string) *usermodels.MongoUser {
+		return &usermodels.MongoUser{
+			ID:              primitive.NewObjectID(),
+			EnrolledCourses: []usermodels.EnrolledCourse{{CourseID: cid, EnrollmentType: role}},
+		}
+	}
+	dueIn := func(d time.Duration) *assignmentmodels.MongoAssignment {
+		return &assignmentmodels.MongoAssignment{
+			DueDate:    utils.TimeToDateTime(time.Now().Add(d)),
+			LatePolicy: &assignmentmodels.LatePolicy{PenaltyPerDay: 10, MaxDaysLate: 1},
+		}
+	}
+
+	if err := checkStudentRules(pushed, dueIn(-72*time.Hour), enrolled("student"), cid); err != errors.ErrorSubmissionTooLate {
+		t.Errorf("a student's push past the late policy got %v, want %v", err, errors.ErrorSubmissionTooLate)
+	}
+	if err := checkStudentRules(pushed, dueIn(24*time.Hour), enrolled("student"), cid); err != nil {
+		t.Errorf("a student's push before the due date was refused: %v", err)
+	}
+	if err := checkStudentRules(pushed, dueIn(-72*time.Hour), enrolled("teacher"), cid); err != nil {
+		t.Errorf("staff were held to the late policy: %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package cms
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/errors"
+	"backend/models/cmsmodels/submissionmodels"
+)
+
+// TestStabilityReport flags the tests of an assignment that look flaky, which
+// identical resubmissions both passed and failed, or timing sensitive, whose
+// duration varies by more than ?maxVariation (0.5) of its mean over at least
+// ?minRuns (5) timed runs. ?flagged=true lists only those.
+func TestStabilityReport(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	minRuns, errs := strconv.Atoi(c.DefaultQuery("minRuns", "5"))
+	if errs != nil || minRuns < 2 {
+		c.Set("error", errors.ErrorInvalidQuery)
+		return
+	}
+
+	maxVariation, errs := strconv.ParseFloat(c.DefaultQuery("maxVariation", "0.5"), 64)
+	if errs != nil || maxVariation <= 0 {
+		c.Set("error", errors.ErrorInvalidQuery)
+		return
+	}
+
+	runs, err := sm.GradedRuns(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	tests := submissionmodels.Stability(runs, minRuns, maxVariation)
+	if c.Query("flagged") == "true" {
+		flagged := make([]submissionmodels.TestStability, 0)
+		for _, test := range tests {
+			if test.Flaky || test.TimingSensitive {
+				flagged = append(flagged, test)
+			}
+		}
+		tests = flagged
+	}
+
+	c.JSON(200, gin.H{
+		"message":     "Test Stability.",
+		"submissions": len(runs),
+		"tests":       tests,
+	})
+}
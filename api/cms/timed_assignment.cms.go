@@ -0,0 +1,132 @@
+package cms
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/forms"
+	"backend/models/cmsmodels/assignmentmodels"
+)
+
+// StartAssignment begins a student's window on a timed assignment. Starting
+// again returns the window already running.
+func StartAssignment(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	uid, _ := c.Get("uid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assign, err := am.Get(aid)
+	if err != nil || !assign.Published {
+		c.Set("error", errors.ErrorResourceNotFound)
+		return
+	}
+
+	if !assign.Timed() {
+		c.Set("error", errors.ErrorAssignmentNotTimed)
+		return
+	}
+
+	window := assign.Window(uid.(primitive.ObjectID))
+	if window == nil {
+		if err = am.Start(aid, uid, time.Now()); err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		if assign, err = am.Get(aid); err != nil {
+			c.Set("error", err)
+			return
+		}
+		window = assign.Window(uid.(primitive.ObjectID))
+	}
+
+	c.JSON(200, gin.H{
+		"message":   "Assignment Started.",
+		"timeLimit": assign.TimeLimit,
+		"window":    window,
+	})
+}
+
+// AssignmentStarts lists when each student started a timed assignment, when
+// their window closes and the accommodations they have been given.
+func AssignmentStarts(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	assign, err := am.Get(aid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	starts := make([]gin.H, 0)
+	for _, start := range assign.Starts {
+		user, err := um.FindOneById(start.UserID)
+		if err != nil {
+			continue
+		}
+
+		starts = append(starts, gin.H{
+			"userID":    start.UserID,
+			"email":     user.Email,
+			"firstName": user.First,
+			"lastName":  user.Last,
+			"window":    assign.Window(start.UserID),
+		})
+	}
+
+	accommodations := assign.Accommodations
+	if accommodations == nil {
+		accommodations = make([]assignmentmodels.Accommodation, 0)
+	}
+
+	c.JSON(200, gin.H{
+		"message":        "Assignment Starts.",
+		"timeLimit":      assign.TimeLimit,
+		"starts":         starts,
+		"accommodations": accommodations,
+	})
+}
+
+// GrantAccommodation gives a student extra minutes on a timed assignment. It
+// can be granted before or after the student starts.
+func GrantAccommodation(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	aid, _ := c.Get("aid")
+	suid, _ := c.Get("suid")
+
+	if err := checkCourseAssignment(cid, aid); err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	var form forms.AccommodationForm
+	if err := c.ShouldBindJSON(&form); err != nil || *form.ExtraMinutes < 0 {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	err := am.SetAccommodation(aid, suid, *form.ExtraMinutes)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":      "Accommodation Granted.",
+		"extraMinutes": *form.ExtraMinutes,
+	})
+}
@@ -0,0 +1,61 @@
+package cms
+
+import (
+	"time"
+
+	jwt "github.com/appleboy/gin-jwt"
+	"github.com/gin-gonic/gin"
+
+	"backend/api/auth"
+	"backend/errors"
+	"backend/forms"
+	"backend/utils"
+)
+
+// userLocation returns the timezone of the logged in user from their token.
+func userLocation(c *gin.Context) *time.Location {
+	timezone, _ := jwt.ExtractClaims(c)["timezone"].(string)
+	return utils.LoadLocation(timezone)
+}
+
+// UpdateTimezone changes the timezone dates are presented to the user in and
+// returns a token carrying it.
+func UpdateTimezone(c *gin.Context) {
+	uid, _ := c.Get("uid")
+
+	var form forms.UserTimezoneForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	if !utils.ValidTimezone(form.Timezone) {
+		c.Set("error", errors.ErrorInvalidTimezone)
+		return
+	}
+
+	err := um.SetTimezone(uid, form.Timezone)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	user, err := um.FindOneById(uid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	tokenString, expire, err := auth.IssueToken(c, user)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":  "Timezone Updated.",
+		"timezone": form.Timezone,
+		"token":    tokenString,
+		"expire":   expire,
+	})
+}
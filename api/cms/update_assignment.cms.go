@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
 
 	"backend/errors"
 	"backend/forms"
@@ -73,6 +76,7 @@ func UpdateAssignment(c *gin.Context) {
 	if up.DueDate != nil {
 		assign.DueDate = *up.DueDate
 	}
+	wasPublished := assign.Published
 	if up.Published != nil {
 		assign.Published = *up.Published
 	}
@@ -84,13 +88,155 @@ func UpdateAssignment(c *gin.Context) {
 		for _, test := range up.Tests {
 			var toAdd assignmentmodels.Test
 			json.Unmarshal([]byte(test), &toAdd)
+			if err := assignmentmodels.CheckMatch(toAdd); err != nil {
+				c.Set("error", err)
+				return
+			}
 			tests = append(tests, toAdd)
 		}
+		if missing := assign.MissingFixture(tests); missing != "" {
+			c.Set("error", errors.ErrorUnknownFixture)
+			return
+		}
 		assign.Tests = tests
 	}
 	if up.NumAttempts != nil {
 		assign.NumAttempts = *up.NumAttempts
 	}
+	if up.TimeLimit != nil {
+		if *up.TimeLimit < 0 {
+			c.Set("error", errors.ErrorInvalidTimeLimit)
+			return
+		}
+		assign.TimeLimit = *up.TimeLimit
+	}
+	if up.Attestation != nil {
+		assign.Attestation = *up.Attestation
+	}
+	if up.LeaderboardMetric != nil {
+		if *up.LeaderboardMetric == "" {
+			assign.Leaderboard = nil
+		} else if assign.Leaderboard == nil {
+			assign.Leaderboard = &assignmentmodels.Leaderboard{
+				Metric:  *up.LeaderboardMetric,
+				OptOuts: make([]primitive.ObjectID, 0),
+			}
+		} else {
+			assign.Leaderboard.Metric = *up.LeaderboardMetric
+		}
+	}
+	if up.LeaderboardLowerIsBetter != nil && assign.Leaderboard != nil {
+		assign.Leaderboard.LowerIsBetter = *up.LeaderboardLowerIsBetter
+	}
+
+	if up.ExtraCredit != nil {
+		assign.ExtraCredit = *up.ExtraCredit
+	}
+	if up.Requirements != nil {
+		assign.Requirements = assignmentmodels.NewRequirements(*up.Requirements)
+	}
+	if up.ShowHiddenSummary != nil {
+		assign.ShowHiddenSummary = *up.ShowHiddenSummary
+	}
+	if up.CooldownMinutes != nil {
+		if *up.CooldownMinutes < 0 {
+			c.Set("error", errors.ErrorInvalidCooldown)
+			return
+		}
+		assign.CooldownMinutes = *up.CooldownMinutes
+	}
+	if up.LatePolicy != nil {
+		assign.LatePolicy = nil
+		if *up.LatePolicy != "" {
+			var late assignmentmodels.LatePolicy
+			if errs := json.Unmarshal([]byte(*up.LatePolicy), &late); errs != nil {
+				c.Set("error", errors.ErrorInvalidLatePolicy)
+				return
+			}
+			if err := assignmentmodels.CheckLatePolicy(&late); err != nil {
+				c.Set("error", err)
+				return
+			}
+			assign.LatePolicy = &late
+		}
+	}
+	if up.PrecheckFiles != nil || up.PrecheckExclusive != nil || up.PrecheckBuild != nil {
+		files, exclusive, build := "", false, false
+		if assign.Precheck != nil {
+			files = strings.Join(assign.Precheck.RequiredFiles, ",")
+			exclusive, build = assign.Precheck.Exclusive, assign.Precheck.Build
+		}
+		if up.PrecheckFiles != nil {
+			files = *up.PrecheckFiles
+		}
+		if up.PrecheckExclusive != nil {
+			exclusive = *up.PrecheckExclusive
+		}
+		if up.PrecheckBuild != nil {
+			build = *up.PrecheckBuild
+		}
+
+		assign.Precheck, err = assignmentmodels.NewPrecheck(files, exclusive, build)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+	}
+
+	if up.Milestones != nil {
+		var milestones []forms.AssignmentMilestoneForm
+		if errs := json.Unmarshal([]byte(*up.Milestones), &milestones); errs != nil {
+			c.Set("error", errors.ErrorInvalidJSON)
+			return
+		}
+
+		assign.Milestones, err = assign.NewMilestones(milestones)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+	} else if err = assign.CheckMilestones(assign.Milestones); err != nil {
+		// the tests or due date changed under the milestones
+		c.Set("error", err)
+		return
+	}
+
+	if up.ArtifactSlots != nil {
+		var slots []forms.ArtifactSlotForm
+		if errs := json.Unmarshal([]byte(*up.ArtifactSlots), &slots); errs != nil {
+			c.Set("error", errors.ErrorInvalidJSON)
+			return
+		}
+
+		assign.ArtifactSlots, err = assignmentmodels.NewArtifactSlots(slots)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+	}
+
+	if up.Rubric != nil {
+		var rubric []forms.RubricCriterionForm
+		if errs := json.Unmarshal([]byte(*up.Rubric), &rubric); errs != nil {
+			c.Set("error", errors.ErrorInvalidJSON)
+			return
+		}
+
+		assign.Rubric, err = assignmentmodels.NewRubric(rubric)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+	}
+
+	// checked with the rest of the update applied, which can fix a blocker
+	if assign.Published && !wasPublished {
+		if readiness := assign.Readiness(time.Now()); !readiness.Ready {
+			c.Set("errorDetails", readiness)
+			c.Set("error", errors.ErrorAssignmentNotReady)
+			return
+		}
+	}
 
 	err = am.Update(*assign)
 	if err != nil {
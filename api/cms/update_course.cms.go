@@ -38,6 +38,9 @@ func UpdateCourse(c *gin.Context) {
 	if up.Semester != nil {
 		course.Semester = *up.Semester
 	}
+	if up.MaxEnrollment != nil {
+		course.MaxEnrollment = *up.MaxEnrollment
+	}
 
 	err = cm.Update(*course)
 	if err != nil {
@@ -1,38 +1,163 @@
 package cms
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"backend/errors"
+	"backend/models/cmsmodels/assignmentmodels"
 	submodels "backend/models/cmsmodels/submissionmodels"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	tyrgin "github.com/stevens-tyr/tyr-gin"
 )
 
+// bindGradeReport reads a grade report from court herald. Older graders send a
+// bare array of test results, newer ones a report with the build output too.
+func bindGradeReport(c *gin.Context) (submodels.GradeReport, error) {
+	var report submodels.GradeReport
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return report, err
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return report, nil
+	}
+
+	if body[0] == '[' {
+		err = json.Unmarshal(body, &report.Results)
+	} else {
+		err = json.Unmarshal(body, &report)
+	}
+
+	return report, err
+}
+
+// offloadLargeOutputs moves test outputs too large to store on the submission
+// into gridfs, leaving their beginning on the result. If gridfs fails the
+// output is only truncated.
+func offloadLargeOutputs(sid primitive.ObjectID, report *submodels.GradeReport) {
+	max := submodels.MaxOutputLength()
+	for index := range report.Results {
+		result := &report.Results[index]
+		if len(result.Output) <= max {
+			continue
+		}
+
+		fid := primitive.NewObjectID()
+		filename := fmt.Sprintf("%s-result-%d.txt", sid.Hex(), result.ID)
+		err := gfs.Upload(&fid, filename, strings.NewReader(result.Output))
+		if err != nil {
+			tyrgin.ErrorLogger(err, "Failed to store the output of submission "+sid.Hex())
+		} else {
+			result.OutputFileID = &fid
+			result.OutputSize = int64(len(result.Output))
+		}
+
+		result.Output = submodels.TruncateOutput(result.Output)
+	}
+}
+
+// recordMatches notes on each result how its output was compared, for graders
+// that do not report it, so the diff view knows what was ignored.
+func recordMatches(sid interface{}, report *submodels.GradeReport) {
+	submission, err := sm.Get(sid, "any")
+	if err != nil {
+		return
+	}
+
+	assign, err := am.Get(submission.AssignmentID)
+	if err != nil {
+		return
+	}
+
+	for index := range report.Results {
+		result := &report.Results[index]
+		if result.Match != "" {
+			continue
+		}
+
+		result.Match = assignmentmodels.MatchExact
+		for _, test := range assign.Tests {
+			if test.Name == result.Name && test.Match != "" {
+				result.Match = test.Match
+				result.Epsilon = test.Epsilon
+				break
+			}
+		}
+	}
+}
+
 // UpdateGrade will be called by court_herald to update the grade from brian
 func UpdateGrade(c *gin.Context) {
 	sid, _ := c.Get("sid")
 
-	var testResults []submodels.WorkerResult
-	c.BindJSON(&testResults)
+	report, errs := bindGradeReport(c)
+	if errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
 
-	err := sm.UpdateGrade(sid, testResults)
+	if run := findDryRun(sid); run != nil {
+		if err := finishDryRun(run, report, false); err != nil {
+			c.Set("error", err)
+			return
+		}
+		c.JSON(200, gin.H{
+			"message": "Dry Run Updated.",
+		})
+		return
+	}
+
+	offloadLargeOutputs(sid.(primitive.ObjectID), &report)
+	recordMatches(sid, &report)
+
+	err := sm.UpdateGrade(sid, report)
 	if err != nil {
 		c.Set("error", err)
 		return
 	}
+
 	c.JSON(200, gin.H{
 		"message": "Submission Grade Updated.",
 	})
 }
 
-// UpdateGradeError will edit the grade if an error is encountered while grading
+// UpdateGradeError will edit the grade if an error is encountered while grading.
+// The build output is stored when court herald sends it.
 func UpdateGradeError(c *gin.Context) {
 	sid, _ := c.Get("sid")
 
-	err := sm.UpdateError(sid)
+	report, errs := bindGradeReport(c)
+	if errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	if run := findDryRun(sid); run != nil {
+		if err := finishDryRun(run, report, true); err != nil {
+			c.Set("error", err)
+			return
+		}
+		c.JSON(200, gin.H{
+			"message": "Dry Run Updated.",
+		})
+		return
+	}
+
+	err := sm.UpdateError(sid, report)
 	if err != nil {
 		c.Set("error", err)
 		return
 	}
-  
+
 	c.JSON(200, gin.H{
 		"message": "Submission Error Update.",
 	})
@@ -0,0 +1,156 @@
+package cms
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+	"backend/models/cmsmodels/submissionmodels"
+)
+
+// usageMonth parses a month of a usage report, e.g. 2024-09.
+func usageMonth(value string, fallback time.Time) (time.Time, errors.APIError) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	month, err := time.Parse("2006-01", value)
+	if err != nil {
+		return month, errors.ErrorInvalidReportRange
+	}
+
+	return month, nil
+}
+
+// usageByDepartment sums the rows of each department's courses per month,
+// counting a student active in several of its courses once.
+func usageByDepartment(rows []submissionmodels.UsageRow) []submissionmodels.UsageRow {
+	totals := make(map[string]*submissionmodels.UsageRow)
+	users := make(map[string]map[primitive.ObjectID]bool)
+	keys := make([]string, 0)
+	for _, row := range rows {
+		key := row.Month + "/" + row.Department
+		total, found := totals[key]
+		if !found {
+			total = &submissionmodels.UsageRow{Department: row.Department, Month: row.Month}
+			totals[key] = total
+			users[key] = make(map[primitive.ObjectID]bool)
+			keys = append(keys, key)
+		}
+
+		total.Jobs += row.Jobs
+		total.GradingSeconds += row.GradingSeconds
+		total.StorageBytes += row.StorageBytes
+		for _, uid := range row.Users {
+			users[key][uid] = true
+		}
+		total.ActiveUsers = len(users[key])
+	}
+
+	sort.Strings(keys)
+	departments := make([]submissionmodels.UsageRow, len(keys))
+	for index, key := range keys {
+		departments[index] = *totals[key]
+	}
+
+	return departments
+}
+
+func usageCSV(rows []submissionmodels.UsageRow, byDepartment bool) (*bytes.Buffer, errors.APIError) {
+	header := []string{"Month", "Department", "Number", "Semester", "Course ID"}
+	if byDepartment {
+		header = header[:2]
+	}
+	header = append(header, "Grading Jobs", "Grading Minutes", "Storage MB", "Active Users")
+
+	records := [][]string{header}
+	for _, row := range rows {
+		record := []string{row.Month, row.Department}
+		if !byDepartment {
+			record = append(record, strconv.Itoa(row.Number), row.Semester, row.CourseID.Hex())
+		}
+		record = append(
+			record,
+			strconv.Itoa(row.Jobs),
+			strconv.FormatFloat(row.GradingSeconds/60, 'f', 2, 64),
+			strconv.FormatFloat(float64(row.StorageBytes)/(1<<20), 'f', 2, 64),
+			strconv.Itoa(row.ActiveUsers),
+		)
+		records = append(records, record)
+	}
+
+	buf := &bytes.Buffer{}
+	if errs := csv.NewWriter(buf).WriteAll(records); errs != nil {
+		return nil, errors.ErrorFailedToWriteCSV
+	}
+
+	return buf, nil
+}
+
+// UsageReport totals grading jobs, grading minutes, storage and active
+// students per course, or per department with ?by=department, and month, for
+// chargeback and capacity planning. ?from and ?to are months, e.g. 2024-09,
+// the last twelve months by default. ?format=csv downloads it.
+func UsageReport(c *gin.Context) {
+	now := time.Now().UTC()
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	from, err := usageMonth(c.Query("from"), thisMonth.AddDate(0, -11, 0))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	to, err := usageMonth(c.Query("to"), thisMonth)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	// to is inclusive
+	to = to.AddDate(0, 1, 0)
+	if !from.Before(to) {
+		c.Set("error", errors.ErrorInvalidReportRange)
+		return
+	}
+
+	rows, err := sm.Usage(from, to)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	byDepartment := c.Query("by") == "department"
+	if byDepartment {
+		rows = usageByDepartment(rows)
+	}
+
+	if c.Query("format") == "csv" {
+		file, err := usageCSV(rows, byDepartment)
+		if err != nil {
+			c.Set("error", err)
+			return
+		}
+
+		additonalHeaders := map[string]string{
+			"Content-Disposition": fmt.Sprintf(`attachment; filename="usage-%s-%s.csv"`, from.Format("2006-01"), to.AddDate(0, -1, 0).Format("2006-01")),
+		}
+
+		c.DataFromReader(200, int64(file.Len()), "text/csv", file, additonalHeaders)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Usage Report.",
+		"from":    from.Format("2006-01"),
+		"to":      to.AddDate(0, -1, 0).Format("2006-01"),
+		"usage":   rows,
+	})
+}
@@ -0,0 +1,271 @@
+package cms
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/errors"
+	"backend/features"
+	"backend/forms"
+	"backend/models/cmsmodels/webhookmodels"
+	"backend/utils"
+)
+
+// webhookMaxAttempts times a delivery is tried before it is marked as failed.
+const webhookMaxAttempts = 5
+
+// webhookBackoff how long to wait after a delivery's nth failed attempt,
+// a minute quadrupling each time.
+func webhookBackoff(attempts int) time.Duration {
+	return time.Minute << uint(2*(attempts-1))
+}
+
+// attemptDelivery posts a delivery and records the outcome, scheduling a retry
+// if it failed and attempts remain.
+func attemptDelivery(hook webhookmodels.MongoWebhook, delivery webhookmodels.Delivery) {
+	status, err := utils.PostWebhook(hook.URL, hook.Secret, delivery.Event, delivery.ID.Hex(), []byte(delivery.Payload))
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastStatusCode = status
+	delivery.LastError = ""
+	switch {
+	case err == nil:
+		delivery.Status = webhookmodels.DeliveryDelivered
+		delivery.DeliveredAt = utils.TimeToDateTime(now)
+		delivery.NextAttemptAt = 0
+	case delivery.Attempts >= webhookMaxAttempts:
+		delivery.Status = webhookmodels.DeliveryFailed
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = 0
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = utils.TimeToDateTime(now.Add(webhookBackoff(delivery.Attempts)))
+	}
+
+	if err := whm.UpdateDelivery(hook.ID, delivery); err != nil {
+		tyrgin.ErrorLogger(err, "Failed to record delivery "+delivery.ID.Hex())
+	}
+}
+
+// dispatchWebhookEvent sends an event of a course to every webhook subscribed
+// to it. The first attempt is made right away, retries by
+// RetryWebhookDeliveries.
+func dispatchWebhookEvent(cid primitive.ObjectID, event string, data interface{}) {
+	hooks, err := whm.Subscribed(cid, event)
+	if err != nil {
+		tyrgin.ErrorLogger(err, "Failed to find webhooks for "+event)
+		return
+	}
+
+	now := time.Now()
+	for _, hook := range hooks {
+		delivery := webhookmodels.Delivery{
+			ID:        primitive.NewObjectID(),
+			Event:     event,
+			Status:    webhookmodels.DeliveryPending,
+			CreatedAt: utils.TimeToDateTime(now),
+			// only retried by the job if the first attempt never finishes
+			NextAttemptAt: utils.TimeToDateTime(now.Add(webhookBackoff(1))),
+		}
+
+		payload, errs := json.Marshal(gin.H{
+			"id":        delivery.ID,
+			"event":     event,
+			"courseID":  cid,
+			"createdAt": now.UTC().Format(time.RFC3339),
+			"data":      data,
+		})
+		if errs != nil {
+			continue
+		}
+		delivery.Payload = string(payload)
+
+		if err = whm.AddDelivery(hook.ID, delivery); err != nil {
+			tyrgin.ErrorLogger(err, "Failed to log delivery to webhook "+hook.ID.Hex())
+			continue
+		}
+
+		go attemptDelivery(hook, delivery)
+	}
+}
+
+// dispatchAssignmentEvent sends an event of the course an assignment belongs to.
+func dispatchAssignmentEvent(aid primitive.ObjectID, event string, data interface{}) {
+	course, err := cm.FindByAssignment(aid)
+	if err != nil {
+		return
+	}
+
+	dispatchWebhookEvent(course.ID, event, data)
+}
+
+// dispatchGraded sends the submission.graded event of a submission.
+func dispatchGraded(sid interface{}) {
+	submission, err := sm.Get(sid, "teacher")
+	if err != nil {
+		return
+	}
+
+	dispatchAssignmentEvent(submission.AssignmentID, webhookmodels.EventSubmissionGraded, gin.H{
+		"submissionID":  submission.ID,
+		"assignmentID":  submission.AssignmentID,
+		"userID":        submission.UserID,
+		"attemptNumber": submission.AttemptNumber,
+		"score":         submission.Score(),
+		"errored":       submission.ErrorTesting,
+	})
+}
+
+// dispatchEnrolled sends the enrollment.added event of a user joining a course.
+func dispatchEnrolled(cid, uid primitive.ObjectID, role string) {
+	dispatchWebhookEvent(cid, webhookmodels.EventEnrollmentAdded, gin.H{
+		"userID": uid,
+		"role":   role,
+	})
+}
+
+// RetryWebhookDeliveries is a job that retries failed deliveries whose backoff
+// has passed.
+func RetryWebhookDeliveries([]byte) error {
+	now := time.Now()
+	hooks, err := whm.Pending(now)
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range hooks {
+		for _, delivery := range hook.Deliveries {
+			if delivery.Status == webhookmodels.DeliveryPending && !utils.DateTimeToTime(delivery.NextAttemptAt).After(now) {
+				attemptDelivery(hook, delivery)
+			}
+		}
+	}
+
+	return nil
+}
+
+// webhookCourse the course of a course webhook route, nil on the admin routes
+// for global webhooks.
+func webhookCourse(c *gin.Context) *primitive.ObjectID {
+	cid, exists := c.Get("cid")
+	if !exists {
+		return nil
+	}
+
+	id := cid.(primitive.ObjectID)
+	return &id
+}
+
+// Webhooks lists a course's webhooks, or the global ones for admins.
+func Webhooks(c *gin.Context) {
+	hooks, err := whm.Find(webhookCourse(c))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":  "Webhooks.",
+		"webhooks": hooks,
+		"events":   webhookmodels.Events,
+	})
+}
+
+// CreateWebhook registers a URL for events. The secret payloads are signed
+// with is only shown in this response. URLs whose host resolves to a
+// loopback, private or link-local address are refused.
+func CreateWebhook(c *gin.Context) {
+	uid, _ := c.Get("uid")
+
+	if _, exists := c.Get("cid"); exists && !featureEnabled(c, features.CourseWebhooks) {
+		return
+	}
+
+	var form forms.CreateWebhookForm
+	if errs := c.ShouldBindJSON(&form); errs != nil {
+		c.Set("error", errors.ErrorInvalidJSON)
+		return
+	}
+
+	target, errs := url.Parse(form.URL)
+	if errs != nil || (target.Scheme != "https" && target.Scheme != "http") || target.Host == "" {
+		c.Set("error", errors.ErrorInvalidWebhook)
+		return
+	}
+
+	if errs = utils.CheckWebhookHost(target.Hostname()); errs != nil {
+		c.Set("error", errors.ErrorPrivateWebhook)
+		return
+	}
+
+	if len(form.Events) == 0 {
+		c.Set("error", errors.ErrorInvalidWebhook)
+		return
+	}
+	for _, event := range form.Events {
+		if !webhookmodels.ValidEvent(event) {
+			c.Set("error", errors.ErrorInvalidWebhook)
+			return
+		}
+	}
+
+	hook, err := whm.Create(webhookCourse(c), uid.(primitive.ObjectID), form.URL, form.Events)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(201, gin.H{
+		"status_code": 201,
+		"message":     "Webhook Created.",
+		"webhook":     hook,
+		"secret":      hook.Secret,
+	})
+}
+
+// WebhookDeliveries the recent deliveries of a webhook, newest first.
+func WebhookDeliveries(c *gin.Context) {
+	whid, _ := c.Get("whid")
+
+	hook, err := whm.Get(whid, webhookCourse(c))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	deliveries := make([]webhookmodels.Delivery, len(hook.Deliveries))
+	for index, delivery := range hook.Deliveries {
+		deliveries[len(deliveries)-1-index] = delivery
+	}
+
+	c.JSON(200, gin.H{
+		"message":    "Webhook deliveries.",
+		"deliveries": deliveries,
+	})
+}
+
+func DeleteWebhook(c *gin.Context) {
+	whid, _ := c.Get("whid")
+
+	_, err := whm.Get(whid, webhookCourse(c))
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = whm.Delete(whid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Webhook Deleted.",
+	})
+}
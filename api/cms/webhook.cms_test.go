@@ -0,0 +1,27 @@
+package cms
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWebhookBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1: time.Minute,
+		2: 4 * time.Minute,
+		3: 16 * time.Minute,
+		4: 64 * time.Minute,
+	} {
+		if got := webhookBackoff(attempts); got != want {
+			t.Errorf("webhookBackoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+
+	var waited time.Duration
+	for attempts := 1; attempts < webhookMaxAttempts; attempts++ {
+		waited += webhookBackoff(attempts)
+	}
+	if waited != 85*time.Minute {
+		t.Errorf("a failing delivery is retried over %v, want %v", waited, 85*time.Minute)
+	}
+}
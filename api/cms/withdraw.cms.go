@@ -0,0 +1,74 @@
+package cms
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// DropStudent withdraws a student from a course. Their enrollment and
+// submissions are kept, flagged as withdrawn, so grade history survives.
+func DropStudent(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	suid, _ := c.Get("suid")
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = cm.Withdraw(cid, suid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = um.SetCourseWithdrawn(cid, suid, true)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = sm.SetWithdrawn(course.Assignments, suid, true)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Student Dropped.",
+	})
+}
+
+// ReinstateStudent undoes a drop, restoring the student's enrollment and submissions.
+func ReinstateStudent(c *gin.Context) {
+	cid, _ := c.Get("cid")
+	suid, _ := c.Get("suid")
+
+	course, err := cm.GetByID(cid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = cm.Reinstate(cid, suid)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = um.SetCourseWithdrawn(cid, suid, false)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	err = sm.SetWithdrawn(course.Assignments, suid, false)
+	if err != nil {
+		c.Set("error", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message": "Student Reinstated.",
+	})
+}
@@ -12,19 +12,25 @@ import (
 // SetUp is a function to set up the routes for plague doctor microservice.
 func SetUp() *gin.Engine {
 	server := tyrgin.SetupRouter()
+	server.Use(middleware.CORS())
+	server.Use(middleware.BodyLimits())
+	server.Use(middleware.Compress())
 
 	tyrgin.ServeReact(server)
 
 	server.MaxMultipartMemory = 50 << 20
 
 	server.Use(middleware.ObjectIDs())
+	server.Use(auth.SigningKeys())
+	server.Use(auth.APITokens())
 	server.Use(middleware.ErrorHandler())
+	server.Use(middleware.ReadOnlyArchives())
 	server.StaticFile("favicon.ico", "./static/assets/favicon.ico")
 	server.Static("/assets", "./static/assets/")
 
 	var authEndpoints = []tyrgin.APIAction{
-		tyrgin.NewRoute(auth.AuthMiddleware.LoginHandler, "login", tyrgin.POST),
-		tyrgin.NewRoute(auth.AuthMiddleware.RefreshHandler, "refresh_token", tyrgin.GET),
+		tyrgin.NewRoute(auth.Login, "login", tyrgin.POST),
+		tyrgin.NewRoute(auth.RefreshToken, "refresh_token", tyrgin.GET),
 		tyrgin.NewRoute(auth.Register, "register", tyrgin.POST),
 	}
 
@@ -36,24 +42,211 @@ func SetUp() *gin.Engine {
 	tyrgin.AddRoutes(server, true, auth.AuthMiddleware, "1", "auth", secureAuthEndpoints)
 
 	var secureCmsEndpoints = []tyrgin.APIAction{
+		tyrgin.NewRoute(cms.CancelAccountDeletion, "user/delete/cancel", tyrgin.POST),
+		tyrgin.NewRoute(cms.DeleteUser, "admin/user/:suid/delete", tyrgin.POST),
+		tyrgin.NewRoute(cms.ExportMyData, "user/export", tyrgin.GET),
+		tyrgin.NewRoute(cms.ExportUserData, "admin/user/:suid/export", tyrgin.GET),
+		tyrgin.NewRoute(cms.RequestAccountDeletion, "user/delete", tyrgin.POST),
+		tyrgin.NewRoute(cms.APITokens, "user/tokens", tyrgin.GET),
+		tyrgin.NewRoute(cms.CreateAPIToken, "user/token/create", tyrgin.POST),
+		tyrgin.NewRoute(cms.RevokeAPIToken, "user/token/:tkid/revoke", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.CLIAssignments, "cli/assignments", tyrgin.GET),
+		tyrgin.NewRoute(cms.CLISubmission, "cli/course/:cid/submission/:sid", tyrgin.GET),
+		tyrgin.NewRoute(cms.CLISubmit, "cli/course/:cid/submit/:aid", tyrgin.POST),
+		tyrgin.NewRoute(cms.CLIWhoAmI, "cli/whoami", tyrgin.GET),
+		tyrgin.NewRoute(cms.AdmitFromWaitlist, "course/:cid/waitlist/admit", tyrgin.POST),
 		tyrgin.NewRoute(cms.AssignmentAsFile, "course/:cid/assignment/:aid/file", tyrgin.GET),
+		tyrgin.NewRoute(cms.CourseAnnouncements, "course/:cid/announcements", tyrgin.GET),
+		tyrgin.NewRoute(cms.CourseArchives, "course/:cid/archives", tyrgin.GET),
 		tyrgin.NewRoute(cms.CourseAssignments, "course/:cid/assignments", tyrgin.GET),
+		tyrgin.NewRoute(cms.CourseInviteCodes, "course/:cid/invites", tyrgin.GET),
+		tyrgin.NewRoute(cms.CourseWaitlist, "course/:cid/waitlist", tyrgin.GET),
+		tyrgin.NewRoute(cms.CoursePendingEnrollments, "course/:cid/pending", tyrgin.GET),
+		tyrgin.NewRoute(cms.CourseSections, "course/:cid/sections", tyrgin.GET),
+		tyrgin.NewRoute(cms.PolicyTemplates, "course/:cid/policies", tyrgin.GET),
+		tyrgin.NewRoute(cms.BulkUpdateAssignments, "course/:cid/assignments/bulk", tyrgin.POST),
+		tyrgin.NewRoute(cms.UpdateSections, "course/:cid/sections/update", tyrgin.POST),
+		tyrgin.NewRoute(cms.UpdatePolicyTemplates, "course/:cid/policies/update", tyrgin.POST),
+		tyrgin.NewRoute(cms.CancelPendingEnrollment, "course/:cid/pending/delete", tyrgin.DELETE),
 		tyrgin.NewRoute(cms.CourseAddUser, "course/:cid/add/user", tyrgin.POST),
 		tyrgin.NewRoute(cms.CourseAddUsers, "course/:cid/add/users", tyrgin.POST),
+		tyrgin.NewRoute(cms.CreateAnnouncement, "course/:cid/announcement/create", tyrgin.POST),
+		tyrgin.NewRoute(cms.CreateArchive, "course/:cid/archive/create", tyrgin.POST),
+		tyrgin.NewRoute(cms.CourseQuota, "course/:cid/quota", tyrgin.GET),
+		tyrgin.NewRoute(cms.SetCourseQuota, "admin/course/:cid/quota", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.UsageReport, "admin/reports/usage", tyrgin.GET),
+		tyrgin.NewRoute(cms.GradingCapacity, "admin/grading/capacity", tyrgin.GET),
+		tyrgin.NewRoute(cms.Graders, "admin/graders", tyrgin.GET),
+		tyrgin.NewRoute(cms.CreateGrader, "admin/grader/create", tyrgin.POST),
+		tyrgin.NewRoute(cms.UpdateGrader, "admin/grader/:grid", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.DeleteGrader, "admin/grader/:grid/delete", tyrgin.DELETE),
+		tyrgin.NewRoute(cms.ExportCartridge, "course/:cid/cartridge", tyrgin.GET),
+		tyrgin.NewRoute(cms.SetCourseArchived, "course/:cid/archived", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.CreateShareLink, "course/:cid/share/:sid", tyrgin.POST),
+		tyrgin.NewRoute(cms.SubmissionShareLinks, "course/:cid/share/:sid", tyrgin.GET),
+		tyrgin.NewRoute(cms.RevokeShareLink, "course/:cid/share/:sid/:shid", tyrgin.DELETE),
+		tyrgin.NewRoute(cms.ImpersonateStudent, "course/:cid/impersonate/:suid", tyrgin.POST),
+		tyrgin.NewRoute(cms.Impersonations, "course/:cid/impersonations", tyrgin.GET),
 		tyrgin.NewRoute(cms.CreateAssignment, "course/:cid/assignment/create", tyrgin.POST),
 		tyrgin.NewRoute(cms.CreateAssignmentFromFile, "course/:cid/assignment/create/file", tyrgin.POST),
+		tyrgin.NewRoute(cms.PreviewAssignmentImport, "course/:cid/assignment/import", tyrgin.POST),
 		tyrgin.NewRoute(cms.CreateCourse, "create/course", tyrgin.POST),
+
+		tyrgin.NewRoute(cms.CurrentOrganization, "org", tyrgin.GET),
+		tyrgin.NewRoute(cms.UpdateOrganization, "org/update", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.OrganizationUsers, "org/users", tyrgin.GET),
+		tyrgin.NewRoute(cms.OrganizationCourses, "org/courses", tyrgin.GET),
+		tyrgin.NewRoute(cms.SetOrganizationAdmin, "org/admin/:suid", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.Organizations, "admin/orgs", tyrgin.GET),
+		tyrgin.NewRoute(cms.CreateOrganization, "admin/org/create", tyrgin.POST),
+		tyrgin.NewRoute(cms.SetUserOrganization, "admin/org/:oid/user/:suid", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.CreateInviteCode, "course/:cid/invite/create", tyrgin.POST),
+		tyrgin.NewRoute(cms.CreatePost, "course/:cid/thread/:tid/post", tyrgin.POST),
+		tyrgin.NewRoute(cms.OpenDispute, "course/:cid/disputes/open/:sid", tyrgin.POST),
+		tyrgin.NewRoute(cms.GrantBonus, "course/:cid/bonus/:suid", tyrgin.POST),
+		tyrgin.NewRoute(cms.AddDisputeMessage, "course/:cid/dispute/:did/message", tyrgin.POST),
+		tyrgin.NewRoute(cms.CreateThread, "course/:cid/assignment/thread/:aid", tyrgin.POST),
 		tyrgin.NewRoute(cms.Dashboard, "dashboard", tyrgin.GET),
+		tyrgin.NewRoute(cms.Overview, "me/overview", tyrgin.GET),
+		tyrgin.NewRoute(cms.StaffTasks, "me/staff-tasks", tyrgin.GET),
+		tyrgin.NewRoute(cms.DeleteAnnouncement, "course/:cid/announcement/:anid/delete", tyrgin.DELETE),
 		tyrgin.NewRoute(cms.DeleteAssignment, "course/:cid/assignment/:aid/delete", tyrgin.DELETE),
+		tyrgin.NewRoute(cms.DeleteAttachment, "course/:cid/assignment/:aid/attachment/:fid/delete", tyrgin.DELETE),
+		tyrgin.NewRoute(cms.DeleteFixture, "course/:cid/assignment/:aid/fixture/:fid/delete", tyrgin.DELETE),
+		tyrgin.NewRoute(cms.DeleteReferenceSolution, "course/:cid/assignment/:aid/reference/delete", tyrgin.DELETE),
+		tyrgin.NewRoute(cms.EndorsePost, "course/:cid/thread/:tid/post/:pid/endorse", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.UpdateDisputeStatus, "course/:cid/dispute/:did/status", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.RevokeBonus, "course/:cid/bonus/:bid/delete", tyrgin.DELETE),
+		tyrgin.NewRoute(cms.DisableInviteCode, "course/:cid/invite/:role/disable", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.DownloadArchive, "course/:cid/archive/:fid", tyrgin.GET),
+		tyrgin.NewRoute(cms.DropStudent, "course/:cid/student/:suid/drop", tyrgin.PATCH),
 		tyrgin.NewRoute(cms.DeleteCourse, "course/:cid/delete", tyrgin.DELETE),
 		tyrgin.NewRoute(cms.GetSubmission, "course/:cid/assignment/:aid/submission/:sid/details", tyrgin.GET),
+		tyrgin.NewRoute(cms.SubmissionProgress, "course/:cid/assignment/:aid/submission/:sid/progress", tyrgin.GET),
 		tyrgin.NewRoute(cms.DownloadSubmission, "course/:cid/assignment/:aid/submission/:sid/download/:num", tyrgin.GET),
+		tyrgin.NewRoute(cms.SubmissionArtifact, "course/:cid/assignment/:aid/submission/:sid/artifact/:slot", tyrgin.GET),
+		tyrgin.NewRoute(cms.SubmissionDocument, "course/:cid/assignment/:aid/submission/:sid/document", tyrgin.GET),
 		tyrgin.NewRoute(cms.GetAssignment, "course/:cid/assignment/:aid/details", tyrgin.GET),
+		tyrgin.NewRoute(cms.AssignmentSubmissionStatus, "course/:cid/assignment/:aid/status", tyrgin.GET),
+		tyrgin.NewRoute(cms.GetAttachment, "course/:cid/assignment/:aid/attachment/:fid", tyrgin.GET),
+		tyrgin.NewRoute(cms.GetFixtures, "course/:cid/assignment/:aid/fixtures", tyrgin.GET),
+		tyrgin.NewRoute(cms.SubmissionDiff, "course/:cid/assignment/:aid/submissions/diff", tyrgin.GET),
+		tyrgin.NewRoute(cms.SubmissionHistory, "course/:cid/assignment/:aid/history/:suid", tyrgin.GET),
+		tyrgin.NewRoute(cms.GetFixture, "course/:cid/assignment/:aid/fixture/:fid", tyrgin.GET),
+		tyrgin.NewRoute(cms.GetReferenceSolution, "course/:cid/assignment/:aid/reference", tyrgin.GET),
+		tyrgin.NewRoute(cms.AssignmentReadiness, "course/:cid/assignment/:aid/readiness", tyrgin.GET),
+		tyrgin.NewRoute(cms.DownloadReferenceSolution, "course/:cid/assignment/:aid/reference/download", tyrgin.GET),
 		tyrgin.NewRoute(cms.GetCourse, "course/:cid", tyrgin.GET),
+		tyrgin.NewRoute(cms.GetThread, "course/:cid/thread/:tid", tyrgin.GET),
+		tyrgin.NewRoute(cms.CourseDisputes, "course/:cid/disputes", tyrgin.GET),
+		tyrgin.NewRoute(cms.Gradebook, "course/:cid/gradebook", tyrgin.GET),
+		tyrgin.NewRoute(cms.GradebookAsCSV, "course/:cid/gradebook/csv", tyrgin.GET),
+		tyrgin.NewRoute(cms.TestResultsAsCSV, "course/:cid/results/csv", tyrgin.GET),
+		tyrgin.NewRoute(cms.UpdateResearchConsent, "course/:cid/research/consent", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.ResearchExport, "admin/research/export", tyrgin.GET),
+		tyrgin.NewRoute(cms.CourseCalendar, "course/:cid/calendar.ics", tyrgin.GET),
+		tyrgin.NewRoute(cms.UserCalendar, "calendar.ics", tyrgin.GET),
+		tyrgin.NewRoute(cms.UpdateOfficeHours, "course/:cid/officehours", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.Webhooks, "course/:cid/webhooks", tyrgin.GET),
+		tyrgin.NewRoute(cms.CreateWebhook, "course/:cid/webhook/create", tyrgin.POST),
+		tyrgin.NewRoute(cms.WebhookDeliveries, "course/:cid/webhook/:whid/deliveries", tyrgin.GET),
+		tyrgin.NewRoute(cms.DeleteWebhook, "course/:cid/webhook/:whid/delete", tyrgin.DELETE),
+		tyrgin.NewRoute(cms.FeatureFlags, "admin/flags", tyrgin.GET),
+		tyrgin.NewRoute(cms.UpdateFeatureFlag, "admin/flag/:flag", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.UpdateCourseFeatureFlag, "admin/flag/:flag/course/:cid", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.CourseFeatures, "course/:cid/features", tyrgin.GET),
+
+		tyrgin.NewRoute(auth.RotateSigningKeys, "admin/keys/rotate", tyrgin.POST),
+
+		tyrgin.NewRoute(cms.Search, "search", tyrgin.GET),
+
+		tyrgin.NewRoute(cms.Jobs, "admin/jobs", tyrgin.GET),
+		tyrgin.NewRoute(cms.GetJob, "admin/job/:jid", tyrgin.GET),
+		tyrgin.NewRoute(cms.RetryJob, "admin/job/:jid/retry", tyrgin.PATCH),
+
+		tyrgin.NewRoute(cms.Webhooks, "admin/webhooks", tyrgin.GET),
+		tyrgin.NewRoute(cms.CreateWebhook, "admin/webhook/create", tyrgin.POST),
+		tyrgin.NewRoute(cms.WebhookDeliveries, "admin/webhook/:whid/deliveries", tyrgin.GET),
+		tyrgin.NewRoute(cms.DeleteWebhook, "admin/webhook/:whid/delete", tyrgin.DELETE),
+		tyrgin.NewRoute(cms.CreateAttendanceSession, "course/:cid/attendance/create", tyrgin.POST),
+		tyrgin.NewRoute(cms.CheckInAttendance, "course/:cid/attendance/checkin/:atid", tyrgin.POST),
+		tyrgin.NewRoute(cms.CourseAttendance, "course/:cid/attendance", tyrgin.GET),
+		tyrgin.NewRoute(cms.GetAttendanceSession, "course/:cid/attendance/:atid", tyrgin.GET),
+		tyrgin.NewRoute(cms.MarkAttendance, "course/:cid/attendance/:atid/mark/:suid", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.CloseAttendanceSession, "course/:cid/attendance/:atid/close", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.DeleteAttendanceSession, "course/:cid/attendance/:atid/delete", tyrgin.DELETE),
+		tyrgin.NewRoute(cms.UpdateGradeScale, "course/:cid/gradescale", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.UpdatePrerequisites, "course/:cid/assignment/:aid/prerequisites", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.CourseBonuses, "course/:cid/bonuses", tyrgin.GET),
+		tyrgin.NewRoute(cms.GetDispute, "course/:cid/dispute/:did", tyrgin.GET),
 		tyrgin.NewRoute(cms.GradesAsCSV, "course/:cid/assignment/:aid/csv", tyrgin.GET),
+		tyrgin.NewRoute(cms.Notifications, "notifications", tyrgin.GET),
+		tyrgin.NewRoute(cms.ReadAllNotifications, "notifications/read", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.RedeemInviteCode, "join/:code", tyrgin.POST),
+		tyrgin.NewRoute(cms.ReinstateStudent, "course/:cid/student/:suid/reinstate", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.ReadNotification, "notification/:nid/read", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.SubmissionOutput, "course/:cid/assignment/:aid/submission/:sid/output/:num", tyrgin.GET),
+		tyrgin.NewRoute(cms.SubmissionJob, "course/:cid/assignment/:aid/submission/:sid/job", tyrgin.GET),
+		tyrgin.NewRoute(cms.CancelSubmissionJob, "course/:cid/submission/:sid/job", tyrgin.DELETE),
+		tyrgin.NewRoute(cms.StartAssignment, "course/:cid/assignment/start/:aid", tyrgin.POST),
+		tyrgin.NewRoute(cms.SubmissionAnomalies, "course/:cid/assignment/:aid/anomalies", tyrgin.GET),
+		tyrgin.NewRoute(cms.SearchSubmissionCode, "course/:cid/assignment/:aid/submissions/search", tyrgin.GET),
+		tyrgin.NewRoute(cms.DistributeGrading, "course/:cid/assignment/distribute/:aid", tyrgin.POST),
+		tyrgin.NewRoute(cms.GradingProgress, "course/:cid/assignment/:aid/grading", tyrgin.GET),
+		tyrgin.NewRoute(cms.CompleteGrading, "course/:cid/assignment/:aid/grading/:sid/complete", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.ReviewSubmission, "course/:cid/assignment/:aid/submission/:sid/review", tyrgin.POST),
+
+		tyrgin.NewRoute(cms.CourseQuestions, "course/:cid/questions", tyrgin.GET),
+		tyrgin.NewRoute(cms.CreateQuestion, "course/:cid/question/create", tyrgin.POST),
+		tyrgin.NewRoute(cms.UpdateQuestion, "course/:cid/question/:qid/update", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.DeleteQuestion, "course/:cid/question/:qid/delete", tyrgin.DELETE),
+		tyrgin.NewRoute(cms.UpdateQuiz, "course/:cid/assignment/:aid/quiz", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.StartQuiz, "course/:cid/assignment/:aid/quiz/start", tyrgin.POST),
+		tyrgin.NewRoute(cms.GetQuizAttempt, "course/:cid/assignment/:aid/quiz/attempt/:qaid", tyrgin.GET),
+		tyrgin.NewRoute(cms.SaveQuizAttempt, "course/:cid/assignment/:aid/quiz/attempt/:qaid/save", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.SubmitQuizAttempt, "course/:cid/assignment/:aid/quiz/attempt/:qaid/submit", tyrgin.POST),
+		tyrgin.NewRoute(cms.AssignmentStarts, "course/:cid/assignment/:aid/starts", tyrgin.GET),
+		tyrgin.NewRoute(cms.GrantAccommodation, "course/:cid/assignment/:aid/accommodation/:suid", tyrgin.PATCH),
 		tyrgin.NewRoute(cms.SubmitAssignment, "course/:cid/assignment/submit/:aid", tyrgin.POST),
+		tyrgin.NewRoute(cms.Leaderboard, "course/:cid/assignment/:aid/leaderboard", tyrgin.GET),
+		tyrgin.NewRoute(cms.LeaderboardOptOut, "course/:cid/assignment/:aid/leaderboard/optout", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.LinkRepository, "course/:cid/assignment/:aid/repository", tyrgin.POST),
+		tyrgin.NewRoute(cms.UnlinkRepository, "course/:cid/assignment/:aid/repository", tyrgin.DELETE),
+		tyrgin.NewRoute(cms.SubmitRepository, "course/:cid/assignment/submit/:aid/git", tyrgin.POST),
+		tyrgin.NewRoute(cms.SubmitEditor, "course/:cid/assignment/submit/:aid/editor", tyrgin.POST),
+		tyrgin.NewRoute(cms.EditorBuffers, "course/:cid/assignment/:aid/editor", tyrgin.GET),
+		tyrgin.NewRoute(cms.SaveDraft, "course/:cid/assignment/:aid/draft", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.AssignmentDraft, "course/:cid/assignment/:aid/draft", tyrgin.GET),
+		tyrgin.NewRoute(cms.DiscardDraft, "course/:cid/assignment/:aid/draft", tyrgin.DELETE),
+		tyrgin.NewRoute(cms.RegradeAssignment, "course/:cid/assignment/:aid/regrade", tyrgin.POST),
+		tyrgin.NewRoute(cms.Regrades, "course/:cid/assignment/:aid/regrades", tyrgin.GET),
+		tyrgin.NewRoute(cms.RegradeReport, "course/:cid/assignment/:aid/regrade/:rgid", tyrgin.GET),
+		tyrgin.NewRoute(cms.TestStabilityReport, "course/:cid/assignment/:aid/tests/stability", tyrgin.GET),
+		tyrgin.NewRoute(cms.RequestExtension, "course/:cid/assignment/:aid/extension", tyrgin.POST),
+		tyrgin.NewRoute(cms.CourseExtensionRequests, "course/:cid/extensions", tyrgin.GET),
+		tyrgin.NewRoute(cms.GetExtensionRequest, "course/:cid/extension/:erid", tyrgin.GET),
+		tyrgin.NewRoute(cms.DecideExtension, "course/:cid/extension/:erid/decide", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.CourseHome, "course/:cid/home", tyrgin.GET),
+		tyrgin.NewRoute(cms.CourseModules, "course/:cid/modules", tyrgin.GET),
+		tyrgin.NewRoute(cms.CreateModule, "course/:cid/module/create", tyrgin.POST),
+		tyrgin.NewRoute(cms.ReorderModules, "course/:cid/modules/reorder", tyrgin.POST),
+		tyrgin.NewRoute(cms.UpdateModule, "course/:cid/module/:mid/update", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.DeleteModule, "course/:cid/module/:mid/delete", tyrgin.DELETE),
+		tyrgin.NewRoute(cms.UploadModuleFile, "course/:cid/module/:mid/file", tyrgin.POST),
+		tyrgin.NewRoute(cms.GetModuleFile, "course/:cid/module/:mid/file/:fid", tyrgin.GET),
+		tyrgin.NewRoute(cms.DeleteModuleFile, "course/:cid/module/:mid/file/:fid/delete", tyrgin.DELETE),
+		tyrgin.NewRoute(cms.AssignmentThreads, "course/:cid/assignment/:aid/threads", tyrgin.GET),
+		tyrgin.NewRoute(cms.UpdateAnnouncement, "course/:cid/announcement/:anid/update", tyrgin.PATCH),
 		tyrgin.NewRoute(cms.UpdateAssignment, "course/:cid/assignment/:aid/update", tyrgin.PATCH),
 		tyrgin.NewRoute(cms.UpdateCourse, "course/:cid/update", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.UpdateRetention, "course/:cid/retention", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.UpdateTimezone, "user/timezone", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.UpdateDigest, "user/digest", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.UploadAttachment, "course/:cid/assignment/attachment/:aid", tyrgin.POST),
+		tyrgin.NewRoute(cms.UploadFixture, "course/:cid/assignment/fixture/:aid", tyrgin.POST),
+		tyrgin.NewRoute(cms.DryRunAssignment, "course/:cid/assignment/dryrun/:aid", tyrgin.POST),
+		tyrgin.NewRoute(cms.PrecheckSubmission, "course/:cid/assignment/precheck/:aid", tyrgin.POST),
+		tyrgin.NewRoute(cms.UploadReferenceSolution, "course/:cid/assignment/reference/:aid", tyrgin.POST),
 	}
 
 	var cmsEndpoints = []tyrgin.APIAction{
@@ -61,7 +254,13 @@ func SetUp() *gin.Engine {
 		tyrgin.NewRoute(cms.UpdateGradeError, "job/:secret/submission/:sid/error", tyrgin.PATCH),
 		tyrgin.NewRoute(cms.JobDownloadSubmission, "job/:secret/submission/:sid/download", tyrgin.GET),
 		tyrgin.NewRoute(cms.JobDownloadSupportingFiles, "job/:secret/assignment/:aid/supportingfiles/download", tyrgin.GET),
+		tyrgin.NewRoute(cms.JobDownloadFixture, "job/:secret/assignment/:aid/fixture/:fid/download", tyrgin.GET),
+		tyrgin.NewRoute(cms.ReportHeraldCapacity, "job/:secret/capacity", tyrgin.PATCH),
+		tyrgin.NewRoute(cms.UnsubscribeDigest, "digest/unsubscribe/:token", tyrgin.GET),
+		tyrgin.NewRoute(cms.OrganizationBranding, "branding/:slug", tyrgin.GET),
+		tyrgin.NewRoute(cms.ViewSharedSubmission, "share/:token", tyrgin.GET),
 		tyrgin.NewRoute(auth.Register, "register", tyrgin.POST),
+		tyrgin.NewRoute(cms.GitHubWebhook, "webhook/github/:lid", tyrgin.POST),
 	}
 
 	tyrgin.AddRoutes(server, true, auth.AuthMiddleware, "1", "plague_doctor", secureCmsEndpoints)
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// command a subcommand of the binary, run instead of the server.
+type command struct {
+	name        string
+	description string
+	run         func(args []string) int
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"create-admin", "create an admin account, or promote an existing user", createAdmin},
+		{"export-course", "write a zip of a course's grades and metadata", exportCourse},
+		{"migrate", "apply, revert or list database migrations", migrate},
+		{"reindex", "create any missing database indexes", reindex},
+		{"requeue-stuck-submissions", "resend submissions stuck in grading to court herald", requeueStuckSubmissions},
+		{"reset-password", "set a new password for a user", resetPassword},
+		{"seed", "fill a development database with fake data", seedDatabase},
+		{"help", "list the available commands", help},
+	}
+}
+
+func help(args []string) int {
+	fmt.Println("usage: plague_doctor [command] [flags]")
+	fmt.Println("without a command the server is started.")
+	fmt.Println()
+	for _, cmd := range commands {
+		fmt.Printf("  %-27s %s\n", cmd.name, cmd.description)
+	}
+	fmt.Println()
+	fmt.Println("run plague_doctor [command] -h for a command's flags.")
+
+	return 0
+}
+
+// runCommand runs the subcommand named by the first argument, if there is one,
+// and reports whether it did.
+func runCommand(args []string) (int, bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:]), true
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+	help(nil)
+
+	return 2, true
+}
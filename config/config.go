@@ -0,0 +1,508 @@
+// Package config loads every setting of the backend from the environment once,
+// when the program starts, so a missing or malformed setting is reported
+// before the server takes requests rather than when a handler first needs it.
+//
+// Any setting can instead be read from a file, as Kubernetes mounts secrets:
+// KEY_FILE names the file, or a file named KEY in SECRETS_DIR is used.
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// SMTP the server emails are sent through, none when Host is empty.
+	SMTP struct {
+		Host     string
+		Port     string
+		Username string
+		Password string
+		From     string
+	}
+
+	// EventBus where domain events are forwarded to, none when NATSURL is
+	// empty.
+	EventBus struct {
+		NATSURL       string
+		SubjectPrefix string
+	}
+
+	// JWTKey a key tokens are signed with, named by the kid in their header.
+	JWTKey struct {
+		ID     string
+		Secret []byte
+	}
+
+	// CORS which other origins, such as a frontend on another domain, can
+	// call the API from a browser.
+	CORS struct {
+		// AllowedOrigins exact origins, "*" for any or a "https://*.domain"
+		// wildcard for its subdomains.
+		AllowedOrigins   []string
+		AllowCredentials bool
+		MaxAge           time.Duration
+	}
+
+	// Jobs how background jobs are run.
+	Jobs struct {
+		Workers          int
+		PollInterval     time.Duration
+		HistoryRetention time.Duration
+	}
+
+	// Grader how court herald is called. Each request has Timeout, failed
+	// ones are retried Retries times, and after BreakerFailures failures in
+	// a row calls are refused for BreakerCooldown.
+	Grader struct {
+		Timeout         time.Duration
+		Retries         int
+		BreakerFailures int
+		BreakerCooldown time.Duration
+	}
+
+	// DevGrader the grader built into development binaries, which runs tests
+	// as local commands, or in Image with docker, instead of court herald. It
+	// listens on Addr and calls the backend back at BackendURL.
+	DevGrader struct {
+		Enabled    bool
+		Addr       string
+		BackendURL string
+		Image      string
+	}
+
+	// AnalyticsReads where the heavy read only aggregations, such as
+	// gradebooks, exports and reports, read from, so they can be sent to
+	// secondaries and leave the primary to submissions. ReadPreference and
+	// ReadConcern are mongo's names for them, a secondary may lag by at
+	// most MaxStaleness when set.
+	AnalyticsReads struct {
+		ReadPreference string
+		ReadConcern    string
+		MaxStaleness   time.Duration
+	}
+
+	// Config every setting of the backend.
+	Config struct {
+		Env          string
+		MongoURI     string
+		DBName       string
+		GridFSDBName string
+		UploadSize   int
+		// MaxJSONBody and MaxUploadBody the largest request bodies, in bytes,
+		// of JSON requests and of file uploads and grade reports.
+		MaxJSONBody    int64
+		MaxUploadBody  int64
+		CourtHeraldURL string
+		// PublicURL the server is reached at, for links sent outside of a
+		// request.
+		PublicURL string
+		JWTRealm  string
+		// JWTKeys the active signing keys, oldest first, the last signs new
+		// tokens.
+		JWTKeys []JWTKey
+		// JobSecret court herald authenticates its downloads and callbacks with.
+		JobSecret        string
+		DefaultTimezone  string
+		MigrateOnStartup bool
+		// ShareLinkSecret signs submission share links. It has to differ
+		// from JobSecret, which court herald holds too.
+		ShareLinkSecret string
+		// PseudonymSecret keys the pseudonyms students are given in
+		// anonymized exports. It has to differ from JobSecret, as anyone
+		// holding it can tell who is behind a pseudonym.
+		PseudonymSecret string
+		// StripEmailPlusTags treats bob+tag@school.edu as bob@school.edu.
+		StripEmailPlusTags bool
+
+		AccountDeletionGrace        time.Duration
+		RetentionSubmissionFileDays int
+		RetentionDiscussionDays     int
+		// CourseStorageQuotaMB and CourseGradingMinutesQuota the quota of
+		// courses admins did not give one, zero is unlimited.
+		CourseStorageQuotaMB       int
+		CourseGradingMinutesQuota  int
+		CourseQuotaHard            bool
+		StuckSubmissionTimeout     time.Duration
+		StuckSubmissionMaxRequeues int
+		MaxResultOutputKB          int
+		APITokenRateLimit          int
+		GitSubmissionHosts         []string
+		DryRunTimeout              time.Duration
+		// ImpersonationTimeout how long a view as student token lasts.
+		ImpersonationTimeout time.Duration
+		// GradingQueueThreshold how many jobs may wait in court herald before
+		// new submissions are held back, zero never holds them. A status older
+		// than HeraldStatusMaxAge is ignored.
+		GradingQueueThreshold int
+		HeraldStatusMaxAge    time.Duration
+		// FeatureFlags the flag=true|false defaults of the deployment.
+		FeatureFlags map[string]bool
+
+		SMTP     SMTP
+		EventBus EventBus
+		CORS     CORS
+		Jobs     Jobs
+		Grader   Grader
+		// ChangeStreams whether live updates and cache invalidation are read
+		// from mongo change streams, which need a replica set, so every
+		// replica sees the changes the others make.
+		ChangeStreams bool
+		// AnalyticsReads primary, as every other read, by default.
+		AnalyticsReads AnalyticsReads
+		// DevGrader set with GRADER_MODE=dev, court herald otherwise.
+		DevGrader DevGrader
+	}
+
+	// loader reads settings, collecting every problem instead of stopping at
+	// the first.
+	loader struct {
+		problems []string
+	}
+)
+
+// DefaultKeyID the kid of JWT_SECRET.
+const DefaultKeyID = "default"
+
+// C the settings of the process, loaded when the package is initialized.
+var C *Config
+
+var problems []string
+
+func init() {
+	C, problems = Load()
+}
+
+// Check reports every missing or malformed setting. Called first thing in main
+// so the program exits before doing anything with a broken configuration.
+func Check() error {
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("invalid configuration:\n  %s", strings.Join(problems, "\n  "))
+}
+
+// JWTKeys reads the signing keys again, so keys added to or removed from a
+// mounted secret are picked up without a restart.
+func JWTKeys() ([]JWTKey, error) {
+	l := &loader{}
+	keys := l.jwtKeys()
+	if len(l.problems) != 0 {
+		return nil, fmt.Errorf("invalid signing keys: %s", strings.Join(l.problems, ", "))
+	}
+
+	return keys, nil
+}
+
+// lookup a setting from its file, when it has one, or the environment.
+func (l *loader) lookup(key string) string {
+	path := os.Getenv(key + "_FILE")
+	if path == "" && os.Getenv("SECRETS_DIR") != "" {
+		candidate := filepath.Join(os.Getenv("SECRETS_DIR"), key)
+		if _, err := os.Stat(candidate); err == nil {
+			path = candidate
+		}
+	}
+
+	if path == "" {
+		return strings.TrimSpace(os.Getenv(key))
+	}
+
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		l.problems = append(l.problems, fmt.Sprintf("%s: cannot read %s: %s", key, path, err))
+		return ""
+	}
+
+	return strings.TrimSpace(string(bs))
+}
+
+func (l *loader) str(key, fallback string) string {
+	if val := l.lookup(key); val != "" {
+		return val
+	}
+
+	return fallback
+}
+
+func (l *loader) required(key string) string {
+	val := l.lookup(key)
+	if val == "" {
+		l.problems = append(l.problems, key+" is required")
+	}
+
+	return val
+}
+
+func (l *loader) integer(key string, fallback, min int) int {
+	val := l.lookup(key)
+	if val == "" {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(val)
+	if err != nil || n < min {
+		l.problems = append(l.problems, fmt.Sprintf("%s must be a whole number of at least %d, not %q", key, min, val))
+		return fallback
+	}
+
+	return n
+}
+
+func (l *loader) duration(key string, fallback time.Duration) time.Duration {
+	val := l.lookup(key)
+	if val == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(val)
+	if err != nil || d <= 0 {
+		l.problems = append(l.problems, fmt.Sprintf("%s must be a positive duration such as 30m, not %q", key, val))
+		return fallback
+	}
+
+	return d
+}
+
+func (l *loader) days(key string, fallback, min int) time.Duration {
+	return time.Duration(l.integer(key, fallback, min)) * 24 * time.Hour
+}
+
+func (l *loader) boolean(key string, fallback bool) bool {
+	val := l.lookup(key)
+	if val == "" {
+		return fallback
+	}
+
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		l.problems = append(l.problems, fmt.Sprintf("%s must be true or false, not %q", key, val))
+		return fallback
+	}
+
+	return b
+}
+
+func (l *loader) list(key, fallback string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(l.str(key, fallback), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func (l *loader) oneOf(key, fallback string, choices ...string) string {
+	val := l.str(key, fallback)
+	for _, choice := range choices {
+		if val == choice {
+			return val
+		}
+	}
+
+	l.problems = append(l.problems, fmt.Sprintf("%s must be one of %s, not %q", key, strings.Join(choices, ", "), val))
+	return fallback
+}
+
+func (l *loader) flags(key string) map[string]bool {
+	flags := make(map[string]bool)
+	for _, setting := range l.list(key, "") {
+		parts := strings.SplitN(setting, "=", 2)
+		enabled, err := strconv.ParseBool(parts[len(parts)-1])
+		if len(parts) != 2 || err != nil {
+			l.problems = append(l.problems, fmt.Sprintf("%s must be a list of flag=true|false, not %q", key, setting))
+			continue
+		}
+
+		flags[parts[0]] = enabled
+	}
+
+	return flags
+}
+
+// jwtKeys JWT_SECRET, when set, as the key "default", which signs tokens
+// without a kid, followed by the kid:secret pairs of JWT_KEYS.
+func (l *loader) jwtKeys() []JWTKey {
+	keys := make([]JWTKey, 0)
+	if secret := l.lookup("JWT_SECRET"); secret != "" {
+		keys = append(keys, JWTKey{DefaultKeyID, []byte(secret)})
+	}
+
+	for _, pair := range strings.FieldsFunc(l.lookup("JWT_KEYS"), func(r rune) bool {
+		return r == ',' || r == '\n'
+	}) {
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			l.problems = append(l.problems, "JWT_KEYS must be a list of kid:secret")
+			continue
+		}
+
+		keys = append(keys, JWTKey{parts[0], []byte(parts[1])})
+	}
+
+	if len(keys) == 0 {
+		l.problems = append(l.problems, "JWT_SECRET or JWT_KEYS is required")
+	}
+
+	return keys
+}
+
+func (l *loader) timezone(key string) string {
+	val := l.lookup(key)
+	if val == "" {
+		return ""
+	}
+
+	if _, err := time.LoadLocation(val); err != nil {
+		l.problems = append(l.problems, fmt.Sprintf("%s must be an IANA timezone, not %q", key, val))
+		return ""
+	}
+
+	return val
+}
+
+// Load reads every setting, returning the problems found along with the
+// settings, which fall back to their defaults where there was a problem.
+func Load() (*Config, []string) {
+	l := &loader{}
+
+	env := l.str("ENV", "dev")
+	// the frontend's development server runs on its own port
+	defaultOrigins := ""
+	if env == "dev" {
+		defaultOrigins = "http://localhost:3000"
+	}
+
+	// the dev grader stands in for court herald, which need not be set
+	mode := l.str("GRADER_MODE", "courtherald")
+	devGrader := DevGrader{
+		Enabled:    mode == "dev",
+		Addr:       l.str("DEV_GRADER_ADDR", "localhost:5556"),
+		BackendURL: strings.TrimSuffix(l.str("DEV_GRADER_BACKEND_URL", "http://localhost:5555"), "/"),
+		Image:      l.str("DEV_GRADER_IMAGE", ""),
+	}
+	courtHeraldURL := "http://" + devGrader.Addr
+	if !devGrader.Enabled {
+		courtHeraldURL = l.required("COURT_HERALD_URL")
+	}
+	if mode != "dev" && mode != "courtherald" {
+		l.problems = append(l.problems, fmt.Sprintf("GRADER_MODE must be courtherald or dev, not %q", mode))
+	}
+
+	c := &Config{
+		Env:            env,
+		MongoURI:       l.required("MONGO_URI"),
+		DBName:         l.required("DB_NAME"),
+		GridFSDBName:   l.required("GRIDFS_DB_NAME"),
+		UploadSize:     l.integer("UPLOAD_SIZE", 0, 0),
+		MaxJSONBody:    int64(l.integer("MAX_JSON_BODY_KB", 1024, 1)) << 10,
+		MaxUploadBody:  int64(l.integer("MAX_UPLOAD_BODY_MB", 50, 1)) << 20,
+		CourtHeraldURL: strings.TrimSuffix(courtHeraldURL, "/"),
+		PublicURL:      strings.TrimSuffix(l.str("PUBLIC_URL", ""), "/"),
+		JWTRealm:       l.str("JWT_REALM", ""),
+		JWTKeys:        l.jwtKeys(),
+		JobSecret:      l.required("JOB_SECRET"),
+
+		DefaultTimezone:  l.timezone("DEFAULT_TIMEZONE"),
+		MigrateOnStartup: l.boolean("MIGRATE_ON_STARTUP", true),
+		ShareLinkSecret:  l.required("SHARE_LINK_SECRET"),
+		PseudonymSecret:  l.required("PSEUDONYM_SECRET"),
+
+		AccountDeletionGrace:        l.days("ACCOUNT_DELETION_GRACE_DAYS", 30, 0),
+		RetentionSubmissionFileDays: l.integer("RETENTION_SUBMISSION_FILE_DAYS", 730, 0),
+		RetentionDiscussionDays:     l.integer("RETENTION_DISCUSSION_DAYS", 0, 0),
+		CourseStorageQuotaMB:        l.integer("COURSE_STORAGE_QUOTA_MB", 0, 0),
+		CourseGradingMinutesQuota:   l.integer("COURSE_GRADING_MINUTES_QUOTA", 0, 0),
+		CourseQuotaHard:             l.boolean("COURSE_QUOTA_HARD", false),
+		StuckSubmissionTimeout:      l.duration("STUCK_SUBMISSION_TIMEOUT", 30*time.Minute),
+		StuckSubmissionMaxRequeues:  l.integer("STUCK_SUBMISSION_MAX_REQUEUES", 2, 0),
+		MaxResultOutputKB:           l.integer("MAX_RESULT_OUTPUT_KB", 64, 1),
+		APITokenRateLimit:           l.integer("API_TOKEN_RATE_LIMIT", 60, 1),
+		GitSubmissionHosts:          l.list("GIT_SUBMISSION_HOSTS", "github.com,gitlab.com,bitbucket.org"),
+		DryRunTimeout:               l.duration("DRY_RUN_TIMEOUT", 2*time.Minute),
+		ImpersonationTimeout:        l.duration("IMPERSONATION_TIMEOUT", 15*time.Minute),
+		GradingQueueThreshold:       l.integer("GRADING_QUEUE_THRESHOLD", 0, 0),
+		HeraldStatusMaxAge:          l.duration("HERALD_STATUS_MAX_AGE", 2*time.Minute),
+		FeatureFlags:                l.flags("FEATURE_FLAGS"),
+		StripEmailPlusTags:          l.boolean("EMAIL_STRIP_PLUS_TAGS", false),
+
+		SMTP: SMTP{
+			Host:     l.str("SMTP_HOST", ""),
+			Port:     l.str("SMTP_PORT", "587"),
+			Username: l.str("SMTP_USERNAME", ""),
+			Password: l.str("SMTP_PASSWORD", ""),
+			From:     l.str("MAIL_FROM", ""),
+		},
+		EventBus: EventBus{
+			NATSURL:       l.str("EVENT_BUS_NATS_URL", ""),
+			SubjectPrefix: l.str("EVENT_BUS_SUBJECT_PREFIX", "tyr"),
+		},
+		CORS: CORS{
+			AllowedOrigins:   l.list("CORS_ALLOWED_ORIGINS", defaultOrigins),
+			AllowCredentials: l.boolean("CORS_ALLOW_CREDENTIALS", true),
+			MaxAge:           l.duration("CORS_MAX_AGE", 10*time.Minute),
+		},
+		Jobs: Jobs{
+			Workers:          l.integer("JOB_WORKERS", 4, 1),
+			PollInterval:     time.Duration(l.integer("JOB_POLL_SECONDS", 5, 1)) * time.Second,
+			HistoryRetention: l.days("JOB_HISTORY_DAYS", 14, 1),
+		},
+		Grader: Grader{
+			Timeout:         l.duration("GRADER_TIMEOUT", 10*time.Second),
+			Retries:         l.integer("GRADER_RETRIES", 2, 0),
+			BreakerFailures: l.integer("GRADER_BREAKER_FAILURES", 5, 1),
+			BreakerCooldown: l.duration("GRADER_BREAKER_COOLDOWN", 30*time.Second),
+		},
+		ChangeStreams: l.boolean("CHANGE_STREAMS", env != "dev"),
+		AnalyticsReads: AnalyticsReads{
+			ReadPreference: l.oneOf("ANALYTICS_READ_PREFERENCE", "primary", "primary", "primaryPreferred", "secondary", "secondaryPreferred", "nearest"),
+			ReadConcern:    l.oneOf("ANALYTICS_READ_CONCERN", "local", "local", "majority"),
+			MaxStaleness:   l.duration("ANALYTICS_MAX_STALENESS", 0),
+		},
+		DevGrader: devGrader,
+	}
+
+	if c.JobSecret != "" && c.ShareLinkSecret == c.JobSecret {
+		l.problems = append(l.problems, "SHARE_LINK_SECRET must differ from JOB_SECRET")
+	}
+	if c.JobSecret != "" && c.PseudonymSecret == c.JobSecret {
+		l.problems = append(l.problems, "PSEUDONYM_SECRET must differ from JOB_SECRET")
+	}
+
+	if c.SMTP.Host != "" && c.SMTP.From == "" {
+		l.problems = append(l.problems, "MAIL_FROM is required when SMTP_HOST is set")
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" && c.CORS.AllowCredentials {
+			l.problems = append(l.problems, "CORS_ALLOWED_ORIGINS cannot be * while CORS_ALLOW_CREDENTIALS is true")
+		} else if origin != "*" && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			l.problems = append(l.problems, fmt.Sprintf("CORS_ALLOWED_ORIGINS must be http(s) origins, not %q", origin))
+		}
+	}
+
+	// mongo refuses a max staleness under 90s, or with only the primary
+	if stale := c.AnalyticsReads.MaxStaleness; stale != 0 {
+		if c.AnalyticsReads.ReadPreference == "primary" {
+			l.problems = append(l.problems, "ANALYTICS_MAX_STALENESS cannot be set while ANALYTICS_READ_PREFERENCE is primary")
+		} else if stale < 90*time.Second {
+			l.problems = append(l.problems, fmt.Sprintf("ANALYTICS_MAX_STALENESS must be at least 90s, not %s", stale))
+		}
+	}
+
+	// tyr-gin connects to mongo with MONGO_URI itself, so one read from a
+	// file is handed back to it
+	if c.MongoURI != "" {
+		os.Setenv("MONGO_URI", c.MongoURI)
+	}
+
+	return c, l.problems
+}
@@ -0,0 +1,187 @@
+package courtherald
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/config"
+	"backend/errors"
+	"backend/utils"
+)
+
+// URL the base URL of a grader, COURT_HERALD_URL for submissions that do
+// not name one.
+func URL(grader string) string {
+	if grader == "" {
+		return config.C.CourtHeraldURL
+	}
+
+	return grader
+}
+
+func endpoint(sid primitive.ObjectID, action string) string {
+	return fmt.Sprintf("/api/%s/grader/%s/%s", Version, sid.Hex(), action)
+}
+
+// call makes a call to the grader at base and decodes its answer into out, nil to
+// ignore it. Failures to reach it, and its 5xx answers, are reported as the
+// microservice being unreachable so callers can retry later.
+func call(base, method, path string, in, out interface{}) (int, errors.APIError) {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return 0, errors.ErrorInvalidJSON
+		}
+	}
+
+	resp, err := utils.CourtHerald(URL(base), method, path, body)
+	if err != nil {
+		return 0, errors.ErrorUnableToReachMicroService
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return resp.StatusCode, errors.ErrorUnableToReachMicroService
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || out == nil {
+		return resp.StatusCode, nil
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp.StatusCode, errors.ErrorInvalidGraderResponse
+	}
+
+	return resp.StatusCode, nil
+}
+
+// SubmitJob starts grading a submission on the grader at base and returns
+// the job's name.
+func SubmitJob(base string, req SubmitJobRequest) (string, errors.APIError) {
+	if err := req.Validate(); err != nil {
+		return "", err
+	}
+
+	var resp SubmitJobResponse
+	status, err := call(base, "POST", endpoint(req.Submission.ID, "new"), &req, &resp)
+	if err != nil {
+		return "", err
+	}
+
+	if status < 200 || status >= 300 {
+		return "", errors.ErrorUnableToCreateJob
+	}
+
+	if err = resp.Validate(); err != nil {
+		return "", err
+	}
+
+	return resp.Job, nil
+}
+
+// JobStatus how the job grading a submission is doing, with the last tail
+// lines of its logs. A job court herald has no record of has the missing
+// status.
+func JobStatus(base string, sid primitive.ObjectID, tail int) (*JobStatusResponse, errors.APIError) {
+	var resp JobStatusResponse
+	status, err := call(base, "GET", fmt.Sprintf("%s?tail=%d", endpoint(sid, "status"), tail), nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if status == http.StatusNotFound {
+		return &JobStatusResponse{Status: JobMissing}, nil
+	}
+
+	if status < 200 || status >= 300 {
+		return nil, errors.ErrorUnableToReachMicroService
+	}
+
+	if err = resp.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// CancelJob stops the job grading a submission. Cancelling a job court herald
+// has no record of, e.g. one that already finished, is not an error.
+func CancelJob(base string, sid primitive.ObjectID) errors.APIError {
+	status, err := call(base, "POST", endpoint(sid, "cancel"), nil, nil)
+	if err != nil {
+		return err
+	}
+
+	if status != http.StatusNotFound && (status < 200 || status >= 300) {
+		return errors.ErrorUnableToReachMicroService
+	}
+
+	return nil
+}
+
+// WatchProgress streams the progress of the job grading a submission to fn
+// until the job is done, fn returns false or the context ends.
+func WatchProgress(ctx context.Context, base string, sid primitive.ObjectID, fn func(ProgressEvent) bool) errors.APIError {
+	resp, err := utils.CourtHeraldStream(ctx, URL(base), endpoint(sid, "progress"))
+	if err != nil {
+		return errors.ErrorUnableToReachMicroService
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errors.ErrorResourceNotFound
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.ErrorUnableToReachMicroService
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var event ProgressEvent
+		if err = json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return errors.ErrorInvalidGraderResponse
+		}
+		if errs := event.Validate(); errs != nil {
+			return errs
+		}
+
+		if !fn(event) || event.Stage == StageDone {
+			return nil
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	if scanner.Err() != nil {
+		return errors.ErrorUnableToReachMicroService
+	}
+
+	return nil
+}
+
+// Health checks the grader at base answers and can take jobs.
+func Health(base string) errors.APIError {
+	status, err := call(base, "GET", fmt.Sprintf("/api/%s/health", Version), nil, nil)
+	if err != nil {
+		return err
+	}
+
+	if status < 200 || status >= 300 {
+		return errors.ErrorUnableToReachMicroService
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package courtherald_test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/config"
+	"backend/courtherald"
+	"backend/errors"
+	"backend/testutil"
+	"backend/utils"
+)
+
+// fakeGrader serves a fake grader, with calls failing fast and the circuit
+// opening after three failures.
+func fakeGrader(t *testing.T, retries int) (*testutil.FakeGrader, string) {
+	config.C.Grader = config.Grader{
+		Timeout:         200 * time.Millisecond,
+		Retries:         retries,
+		BreakerFailures: 3,
+		BreakerCooldown: time.Minute,
+	}
+
+	fake := testutil.NewFakeGrader()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return fake, server.URL
+}
+
+func jobRequest() courtherald.SubmitJobRequest {
+	return courtherald.SubmitJobRequest{
+		Submission: courtherald.Submission{
+			ID:           primitive.NewObjectID(),
+			AssignmentID: primitive.NewObjectID(),
+		},
+		Job: courtherald.Job{
+			Language: "python",
+			Tests:    []courtherald.Test{{Name: "greets", TestCMD: "python hello.py", ExpectedOutput: "hello"}},
+		},
+	}
+}
+
+func TestSubmitJobStartsJob(t *testing.T) {
+	fake, base := fakeGrader(t, 0)
+	req := jobRequest()
+
+	job, err := courtherald.SubmitJob(base, req)
+	if err != nil {
+		t.Fatalf("SubmitJob failed: %s", err)
+	}
+	if job != "fake-"+req.Submission.ID.Hex() {
+		t.Errorf("SubmitJob returned job %q", job)
+	}
+
+	sent, found := fake.Job(req.Submission.ID.Hex())
+	if !found || len(sent.Tests) != 1 || sent.Language != "python" {
+		t.Errorf("the grader was sent %+v", sent)
+	}
+}
+
+func TestSubmitJobRefusesIncompleteJob(t *testing.T) {
+	fake, base := fakeGrader(t, 0)
+	req := jobRequest()
+	req.Language = ""
+
+	if _, err := courtherald.SubmitJob(base, req); err != errors.ErrorInvalidGradingJob {
+		t.Errorf("SubmitJob returned %v, not ErrorInvalidGradingJob", err)
+	}
+	if fake.Calls() != 0 {
+		t.Errorf("the grader was called %d times", fake.Calls())
+	}
+}
+
+func TestSubmitJobIsNotRetriedOnServerError(t *testing.T) {
+	fake, base := fakeGrader(t, 2)
+	fake.Then(testutil.OutcomeError)
+
+	if _, err := courtherald.SubmitJob(base, jobRequest()); err != errors.ErrorUnableToReachMicroService {
+		t.Errorf("SubmitJob returned %v, not ErrorUnableToReachMicroService", err)
+	}
+	if fake.Calls() != 1 {
+		t.Errorf("the grader was called %d times, a job it may have started was retried", fake.Calls())
+	}
+}
+
+func TestSubmitJobIsRetriedWhenUnavailable(t *testing.T) {
+	fake, base := fakeGrader(t, 2)
+	fake.Then(testutil.OutcomeUnavailable)
+
+	if _, err := courtherald.SubmitJob(base, jobRequest()); err != nil {
+		t.Fatalf("SubmitJob failed: %s", err)
+	}
+	if fake.Calls() != 2 {
+		t.Errorf("the grader was called %d times, not 2", fake.Calls())
+	}
+}
+
+func TestJobStatus(t *testing.T) {
+	fake, base := fakeGrader(t, 2)
+	passing, failing := jobRequest(), jobRequest()
+	fake.Then(testutil.OutcomePass, testutil.OutcomeFail)
+	courtherald.SubmitJob(base, passing)
+	courtherald.SubmitJob(base, failing)
+
+	for sid, want := range map[primitive.ObjectID]string{
+		passing.Submission.ID:   courtherald.JobSucceeded,
+		failing.Submission.ID:   courtherald.JobFailed,
+		primitive.NewObjectID(): courtherald.JobMissing,
+	} {
+		status, err := courtherald.JobStatus(base, sid, 0)
+		if err != nil {
+			t.Fatalf("JobStatus failed: %s", err)
+		}
+		if status.Status != want {
+			t.Errorf("JobStatus is %q, not %q", status.Status, want)
+		}
+	}
+}
+
+func TestJobStatusIsRetriedOnServerError(t *testing.T) {
+	fake, base := fakeGrader(t, 2)
+	req := jobRequest()
+	courtherald.SubmitJob(base, req)
+	fake.Then(testutil.OutcomeError, testutil.OutcomeError)
+
+	status, err := courtherald.JobStatus(base, req.Submission.ID, 0)
+	if err != nil {
+		t.Fatalf("JobStatus failed: %s", err)
+	}
+	if status.Status != courtherald.JobSucceeded || fake.Calls() != 4 {
+		t.Errorf("JobStatus is %q after %d calls", status.Status, fake.Calls())
+	}
+	if stats := utils.CourtHeraldStats()[base]; stats.Retries != 2 {
+		t.Errorf("%d retries were counted, not 2", stats.Retries)
+	}
+}
+
+func TestJobStatusTimesOut(t *testing.T) {
+	fake, base := fakeGrader(t, 0)
+	fake.Then(testutil.OutcomeTimeout)
+
+	if _, err := courtherald.JobStatus(base, primitive.NewObjectID(), 0); err != errors.ErrorUnableToReachMicroService {
+		t.Errorf("JobStatus returned %v, not ErrorUnableToReachMicroService", err)
+	}
+	if stats := utils.CourtHeraldStats()[base]; stats.Timeouts != 1 {
+		t.Errorf("%d timeouts were counted, not 1", stats.Timeouts)
+	}
+}
+
+func TestCircuitOpensAfterFailures(t *testing.T) {
+	fake, base := fakeGrader(t, 0)
+	fake.Then(testutil.OutcomeError, testutil.OutcomeError, testutil.OutcomeError)
+
+	for i := 0; i < 4; i++ {
+		if _, err := courtherald.JobStatus(base, primitive.NewObjectID(), 0); err != errors.ErrorUnableToReachMicroService {
+			t.Fatalf("call %d returned %v, not ErrorUnableToReachMicroService", i, err)
+		}
+	}
+
+	if fake.Calls() != 3 {
+		t.Errorf("the grader was called %d times, the circuit did not open after 3 failures", fake.Calls())
+	}
+	stats := utils.CourtHeraldStats()[base]
+	if stats.Circuit != utils.CircuitOpen || stats.Rejected != 1 {
+		t.Errorf("the circuit is %s with %d calls rejected", stats.Circuit, stats.Rejected)
+	}
+}
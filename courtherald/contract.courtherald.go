@@ -0,0 +1,174 @@
+// Package courtherald is the contract between the backend and court herald,
+// the service that runs grading jobs. Every request and answer is a typed
+// message validated before it is sent or after it is received, instead of a
+// map assembled where the call is made. Court herald speaks JSON over HTTP,
+// progress is streamed as one JSON event per line.
+package courtherald
+
+import (
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/errors"
+)
+
+// Version the version of the API, part of every path.
+const Version = "v1"
+
+// Statuses of a grading job.
+const (
+	JobActive    = "active"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobMissing   = "missing"
+)
+
+// Stages a grading job reports progress from.
+const (
+	StageQueued   = "queued"
+	StageBuilding = "building"
+	StageTesting  = "testing"
+	StageDone     = "done"
+)
+
+// Roles a fixture has in a test.
+const (
+	FixtureInput          = "input"
+	FixtureExpectedOutput = "expectedOutput"
+	FixtureFile           = "file"
+)
+
+type (
+	// Submission the submission a job grades, its archive is downloaded with
+	// the job secret.
+	Submission struct {
+		ID             primitive.ObjectID `json:"id"`
+		UserID         primitive.ObjectID `json:"userID"`
+		FileID         primitive.ObjectID `json:"fileID"`
+		AssignmentID   primitive.ObjectID `json:"assignmentID"`
+		AttemptNumber  int                `json:"attemptNumber"`
+		SubmissionDate primitive.DateTime `json:"submissionDate"`
+		File           string             `json:"file"`
+	}
+
+	// Fixture a file a test downloads, see the fixture roles.
+	Fixture struct {
+		ID       primitive.ObjectID `json:"id"`
+		Filename string             `json:"filename"`
+		Role     string             `json:"role"`
+	}
+
+	// Test a test resolved for the student, with the variant they were given.
+	Test struct {
+		Name                  string    `json:"name"`
+		TestCMD               string    `json:"testCMD"`
+		ExpectedOutput        string    `json:"expectedOutput"`
+		StudentFacing         bool      `json:"studentFacing"`
+		InputFixture          string    `json:"inputFixture,omitempty"`
+		ExpectedOutputFixture string    `json:"expectedOutputFixture,omitempty"`
+		Fixtures              []string  `json:"fixtures,omitempty"`
+		Match                 string    `json:"match,omitempty"`
+		Epsilon               float64   `json:"epsilon,omitempty"`
+		Variant               *int      `json:"variant,omitempty"`
+		FixtureFiles          []Fixture `json:"fixtureFiles,omitempty"`
+	}
+
+	// Job how an assignment's submissions are built and tested.
+	Job struct {
+		Tests        []Test `json:"tests"`
+		TestBuildCMD string `json:"testBuildCMD"`
+		Language     string `json:"language"`
+	}
+
+	// SubmitJobRequest starts grading a submission.
+	SubmitJobRequest struct {
+		Submission Submission `json:"submission"`
+		Job
+	}
+
+	// SubmitJobResponse names the job started.
+	SubmitJobResponse struct {
+		Job string `json:"job"`
+	}
+
+	// JobStatusResponse how a job is doing, with the tail of its logs.
+	JobStatusResponse struct {
+		Job    string `json:"job,omitempty"`
+		Status string `json:"status"`
+		// Position in court herald's queue while the job waits for a slot.
+		Position   *int       `json:"position,omitempty"`
+		StartedAt  *time.Time `json:"startedAt,omitempty"`
+		FinishedAt *time.Time `json:"finishedAt,omitempty"`
+		Logs       []string   `json:"logs,omitempty"`
+	}
+
+	// ProgressEvent a step of a running job. The last event has the done
+	// stage.
+	ProgressEvent struct {
+		Stage     string    `json:"stage"`
+		Test      string    `json:"test,omitempty"`
+		Completed int       `json:"completed"`
+		Total     int       `json:"total"`
+		Message   string    `json:"message,omitempty"`
+		At        time.Time `json:"at"`
+	}
+)
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Validate checks the request is complete before it is sent.
+func (r *SubmitJobRequest) Validate() errors.APIError {
+	if r.Submission.ID.IsZero() || r.Submission.AssignmentID.IsZero() || r.Language == "" {
+		return errors.ErrorInvalidGradingJob
+	}
+
+	for _, test := range r.Tests {
+		if test.Name == "" || test.TestCMD == "" {
+			return errors.ErrorInvalidGradingJob
+		}
+
+		for _, fixture := range test.FixtureFiles {
+			if fixture.ID.IsZero() || !oneOf(fixture.Role, FixtureInput, FixtureExpectedOutput, FixtureFile) {
+				return errors.ErrorInvalidGradingJob
+			}
+		}
+	}
+
+	return nil
+}
+
+// Validate checks court herald named the job it started.
+func (r *SubmitJobResponse) Validate() errors.APIError {
+	if r.Job == "" {
+		return errors.ErrorInvalidGraderResponse
+	}
+
+	return nil
+}
+
+// Validate checks the status is one court herald may report.
+func (r *JobStatusResponse) Validate() errors.APIError {
+	if !oneOf(r.Status, JobActive, JobSucceeded, JobFailed, JobMissing) {
+		return errors.ErrorInvalidGraderResponse
+	}
+
+	return nil
+}
+
+// Validate checks the event is from a known stage and counts sensibly.
+func (e *ProgressEvent) Validate() errors.APIError {
+	if !oneOf(e.Stage, StageQueued, StageBuilding, StageTesting, StageDone) || e.Completed < 0 || e.Completed > e.Total {
+		return errors.ErrorInvalidGraderResponse
+	}
+
+	return nil
+}
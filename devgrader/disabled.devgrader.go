@@ -0,0 +1,12 @@
+//go:build !devgrader
+// +build !devgrader
+
+package devgrader
+
+import "errors"
+
+// Start fails, the dev grader is left out of binaries built without
+// -tags devgrader.
+func Start() error {
+	return errors.New("GRADER_MODE=dev needs a binary built with -tags devgrader")
+}
@@ -0,0 +1,255 @@
+//go:build devgrader
+// +build devgrader
+
+// Package devgrader is a minimal court herald for development, so the whole
+// submit, grade and results loop runs locally. It answers the court herald
+// API on DEV_GRADER_ADDR and grades each submission in a temporary
+// directory, running the build and test commands as local commands, or in
+// DEV_GRADER_IMAGE with docker, then reports back like court herald does.
+// It is only built with -tags devgrader and used with GRADER_MODE=dev.
+package devgrader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/config"
+	"backend/courtherald"
+)
+
+// maxLogs the log lines kept of a job.
+const maxLogs = 1000
+
+// job a submission being graded, or graded since the server started.
+type job struct {
+	name       string
+	req        courtherald.SubmitJobRequest
+	status     string
+	startedAt  time.Time
+	finishedAt *time.Time
+	logs       []string
+	events     []courtherald.ProgressEvent
+	cancel     context.CancelFunc
+	// changed is closed, and replaced, whenever an event is added.
+	changed chan struct{}
+}
+
+var (
+	jobs   = make(map[primitive.ObjectID]*job)
+	jobsMu sync.Mutex
+)
+
+// Start serves the court herald API on DEV_GRADER_ADDR in the background.
+func Start() error {
+	listener, err := net.Listen("tcp", config.C.DevGrader.Addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(fmt.Sprintf("/api/%s/health", courtherald.Version), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc(fmt.Sprintf("/api/%s/grader/", courtherald.Version), route)
+
+	go http.Serve(listener, mux)
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// route dispatches /api/v1/grader/:sid/:action.
+func route(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, fmt.Sprintf("/api/%s/grader/", courtherald.Version)), "/")
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+
+	sid, err := primitive.ObjectIDFromHex(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case parts[1] == "new" && r.Method == "POST":
+		newJob(w, r)
+	case parts[1] == "status" && r.Method == "GET":
+		jobStatus(w, r, sid)
+	case parts[1] == "cancel" && r.Method == "POST":
+		cancelJob(w, sid)
+	case parts[1] == "progress" && r.Method == "GET":
+		progress(w, r, sid)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func find(sid primitive.ObjectID) *job {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+
+	return jobs[sid]
+}
+
+func newJob(w http.ResponseWriter, r *http.Request) {
+	var req courtherald.SubmitJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Validate() != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid job"})
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		name:      "dev-" + req.Submission.ID.Hex(),
+		req:       req,
+		status:    courtherald.JobActive,
+		startedAt: time.Now(),
+		cancel:    cancel,
+		changed:   make(chan struct{}),
+	}
+
+	jobsMu.Lock()
+	if previous, found := jobs[req.Submission.ID]; found {
+		previous.cancel()
+	}
+	jobs[req.Submission.ID] = j
+	jobsMu.Unlock()
+
+	go j.run(ctx)
+
+	writeJSON(w, http.StatusCreated, courtherald.SubmitJobResponse{Job: j.name})
+}
+
+func jobStatus(w http.ResponseWriter, r *http.Request, sid primitive.ObjectID) {
+	j := find(sid)
+	if j == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	tail, _ := strconv.Atoi(r.URL.Query().Get("tail"))
+
+	jobsMu.Lock()
+	startedAt := j.startedAt
+	resp := courtherald.JobStatusResponse{
+		Job:        j.name,
+		Status:     j.status,
+		StartedAt:  &startedAt,
+		FinishedAt: j.finishedAt,
+	}
+	if tail > 0 {
+		from := len(j.logs) - tail
+		if from < 0 {
+			from = 0
+		}
+		resp.Logs = append([]string{}, j.logs[from:]...)
+	}
+	jobsMu.Unlock()
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func cancelJob(w http.ResponseWriter, sid primitive.ObjectID) {
+	j := find(sid)
+	if j == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	j.cancel()
+	w.WriteHeader(http.StatusOK)
+}
+
+// progress streams a job's events, one JSON event per line, until it is done.
+func progress(w http.ResponseWriter, r *http.Request, sid primitive.ObjectID) {
+	j := find(sid)
+	if j == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	sent := 0
+	for {
+		jobsMu.Lock()
+		events := j.events[sent:]
+		changed := j.changed
+		jobsMu.Unlock()
+
+		for _, event := range events {
+			encoder.Encode(event)
+			if event.Stage == courtherald.StageDone {
+				return
+			}
+		}
+		sent += len(events)
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// event records a step of the job for those following its progress.
+func (j *job) event(stage, test string, completed int) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+
+	j.events = append(j.events, courtherald.ProgressEvent{
+		Stage:     stage,
+		Test:      test,
+		Completed: completed,
+		Total:     len(j.req.Tests),
+		At:        time.Now(),
+	})
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// log adds lines to the job's logs, keeping the last maxLogs.
+func (j *job) log(output string) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		j.logs = append(j.logs, line)
+	}
+	if len(j.logs) > maxLogs {
+		j.logs = j.logs[len(j.logs)-maxLogs:]
+	}
+}
+
+// finish marks the job done with a status.
+func (j *job) finish(status string) {
+	jobsMu.Lock()
+	now := time.Now()
+	j.status = status
+	j.finishedAt = &now
+	jobsMu.Unlock()
+
+	j.event(courtherald.StageDone, "", len(j.req.Tests))
+}
@@ -0,0 +1,288 @@
+//go:build devgrader
+// +build devgrader
+
+package devgrader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/config"
+	"backend/courtherald"
+	"backend/utils"
+)
+
+const (
+	// commandTimeout how long the build or a test may run.
+	commandTimeout = 2 * time.Minute
+	// maxFileSize the largest file extracted from a submission.
+	maxFileSize = 64 << 20
+)
+
+type (
+	// result of a test, as court herald reports it.
+	result struct {
+		ID            int     `json:"id"`
+		Panicked      bool    `json:"panicked"`
+		Passed        bool    `json:"passed"`
+		StudentFacing bool    `json:"studentFacing"`
+		Output        string  `json:"output"`
+		HTML          string  `json:"html"`
+		TestCMD       string  `json:"testCMD"`
+		Name          string  `json:"name"`
+		Match         string  `json:"match,omitempty"`
+		Epsilon       float64 `json:"epsilon,omitempty"`
+		Stderr        string  `json:"stderr"`
+	}
+
+	// report the results of a submission, as court herald sends them.
+	report struct {
+		BuildOutput string   `json:"buildOutput"`
+		BuildFailed bool     `json:"buildFailed"`
+		Results     []result `json:"results"`
+	}
+)
+
+// backend calls one of the backend's job endpoints.
+func backend(method, path string, body []byte) ([]byte, error) {
+	url := fmt.Sprintf("%s/api/v1/plague_doctor/job/%s/%s", config.C.DevGrader.BackendURL, config.C.JobSecret, path)
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+
+	return content, nil
+}
+
+// extract downloads an archive and writes its files into dir.
+func extract(path, dir string) error {
+	content, err := backend("GET", path, nil)
+	if err != nil {
+		return err
+	}
+
+	files, errs := utils.ArchiveFiles(content, maxFileSize)
+	if errs != nil {
+		return errs
+	}
+
+	for name, data := range files {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(target, data, 0755); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// command runs a shell command in dir, in DEV_GRADER_IMAGE when set, and
+// returns its stdout and stderr.
+func command(ctx context.Context, dir, cmd string, stdin []byte) (string, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	var run *exec.Cmd
+	if image := config.C.DevGrader.Image; image != "" {
+		run = exec.CommandContext(ctx, "docker", "run", "--rm", "-i", "--network", "none", "-v", dir+":/work", "-w", "/work", image, "sh", "-c", cmd)
+	} else {
+		run = exec.CommandContext(ctx, "sh", "-c", cmd)
+		run.Dir = dir
+	}
+
+	var stdout, stderr bytes.Buffer
+	run.Stdin = bytes.NewReader(stdin)
+	run.Stdout = &stdout
+	run.Stderr = &stderr
+	err := run.Run()
+
+	return stdout.String(), stderr.String(), err
+}
+
+// run grades the submission and reports the results to the backend, unless
+// the job is cancelled.
+func (j *job) run(ctx context.Context) {
+	submission := j.req.Submission
+	path := fmt.Sprintf("submission/%s/", submission.ID.Hex())
+
+	rep, err := j.grade(ctx)
+	if ctx.Err() != nil {
+		j.log("cancelled")
+		j.finish(courtherald.JobFailed)
+		return
+	}
+
+	action := "update"
+	if err != nil {
+		j.log(err.Error())
+		rep = &report{BuildOutput: err.Error(), BuildFailed: true, Results: make([]result, 0)}
+		action = "error"
+	} else if rep.BuildFailed {
+		action = "error"
+	}
+
+	body, _ := json.Marshal(rep)
+	if _, err = backend("PATCH", path+action, body); err != nil {
+		j.log("failed to report the results: " + err.Error())
+		j.finish(courtherald.JobFailed)
+		return
+	}
+
+	j.finish(courtherald.JobSucceeded)
+}
+
+// grade builds and tests the submission in a temporary directory.
+func (j *job) grade(ctx context.Context) (*report, error) {
+	submission := j.req.Submission
+	dir, err := ioutil.TempDir("", "devgrader-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	j.event(courtherald.StageBuilding, "", 0)
+	if err = extract(fmt.Sprintf("submission/%s/download", submission.ID.Hex()), dir); err != nil {
+		return nil, err
+	}
+	// assignments without supporting files have nothing to download
+	if err = extract(fmt.Sprintf("assignment/%s/supportingfiles/download", submission.AssignmentID.Hex()), dir); err != nil {
+		j.log("no supporting files: " + err.Error())
+	}
+
+	rep := &report{Results: make([]result, 0)}
+	if j.req.TestBuildCMD != "" {
+		stdout, stderr, err := command(ctx, dir, j.req.TestBuildCMD, nil)
+		rep.BuildOutput = stdout + stderr
+		j.log(rep.BuildOutput)
+		if err != nil {
+			rep.BuildFailed = true
+			return rep, nil
+		}
+	}
+
+	for index, test := range j.req.Tests {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		j.event(courtherald.StageTesting, test.Name, index)
+
+		var stdin []byte
+		expected := test.ExpectedOutput
+		for _, fixture := range test.FixtureFiles {
+			content, err := backend("GET", fmt.Sprintf("assignment/%s/fixture/%s/download", submission.AssignmentID.Hex(), fixture.ID.Hex()), nil)
+			if err != nil {
+				return nil, err
+			}
+
+			switch fixture.Role {
+			case courtherald.FixtureInput:
+				stdin = content
+			case courtherald.FixtureExpectedOutput:
+				expected = string(content)
+			default:
+				if err = ioutil.WriteFile(filepath.Join(dir, filepath.Base(fixture.Filename)), content, 0644); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		stdout, stderr, err := command(ctx, dir, test.TestCMD, stdin)
+		j.log(fmt.Sprintf("%s: %s", test.Name, strings.TrimSpace(stdout+stderr)))
+
+		_, exited := err.(*exec.ExitError)
+		rep.Results = append(rep.Results, result{
+			ID:            index,
+			Panicked:      err != nil && !exited,
+			Passed:        err == nil && matches(test.Match, test.Epsilon, stdout, expected),
+			StudentFacing: test.StudentFacing,
+			Output:        stdout,
+			TestCMD:       test.TestCMD,
+			Name:          test.Name,
+			Match:         test.Match,
+			Epsilon:       test.Epsilon,
+			Stderr:        stderr,
+		})
+	}
+
+	return rep, nil
+}
+
+// matches compares a test's output to its expected output the way the
+// assignment asks, exactly by default.
+func matches(match string, epsilon float64, output, expected string) bool {
+	switch match {
+	case "trimmed":
+		return trimLines(output) == trimLines(expected)
+	case "caseInsensitive":
+		return strings.EqualFold(output, expected)
+	case "regex":
+		re, err := regexp.Compile("^(?:" + expected + ")$")
+		return err == nil && re.MatchString(output)
+	case "numeric":
+		got, want := strings.Fields(output), strings.Fields(expected)
+		if len(got) != len(want) {
+			return false
+		}
+		for index := range got {
+			a, errA := strconv.ParseFloat(got[index], 64)
+			b, errB := strconv.ParseFloat(want[index], 64)
+			if errA != nil || errB != nil {
+				if got[index] != want[index] {
+					return false
+				}
+			} else if math.Abs(a-b) > epsilon {
+				return false
+			}
+		}
+		return true
+	case "json":
+		var got, want interface{}
+		if json.Unmarshal([]byte(output), &got) != nil || json.Unmarshal([]byte(expected), &want) != nil {
+			return false
+		}
+		return reflect.DeepEqual(got, want)
+	default:
+		return output == expected
+	}
+}
+
+func trimLines(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for index, line := range lines {
+		lines[index] = strings.TrimSpace(line)
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,38 @@
+//go:build devgrader
+// +build devgrader
+
+package devgrader
+
+import "testing"
+
+func TestMatches(t *testing.T) {
+	for _, c := range []struct {
+		match, output, expected string
+		epsilon                 float64
+		want                    bool
+	}{
+		{"", "hello\n", "hello\n", 0, true},
+		{"", "hello \n", "hello\n", 0, false},
+		{"exact", "Hello", "hello", 0, false},
+		{"trimmed", "  hello  \n world \n\n", "hello\nworld", 0, true},
+		{"trimmed", "hello\n\nworld", "hello\nworld", 0, false},
+		{"caseInsensitive", "HeLLo", "hello", 0, true},
+		{"caseInsensitive", "hello!", "hello", 0, false},
+		{"regex", "hello alice", `hello \w+`, 0, true},
+		{"regex", "well, hello alice", `hello \w+`, 0, false},
+		{"regex", "hello", `hello (`, 0, false},
+		{"numeric", "3.1416 2", "3.14159 2.0", 0.001, true},
+		{"numeric", "3.15", "3.14159", 0.001, false},
+		{"numeric", "1.0000001", "1", 0, false},
+		{"numeric", "area: 2.0", "area: 2", 0, true},
+		{"numeric", "area 2.0", "area: 2", 0, false},
+		{"numeric", "1 2", "1 2 3", 1, false},
+		{"json", `{"b": [1, 2], "a": "x"}`, `{"a":"x","b":[1,2]}`, 0, true},
+		{"json", `{"a": 1}`, `{"a": "1"}`, 0, false},
+		{"json", `not json`, `not json`, 0, false},
+	} {
+		if got := matches(c.match, c.epsilon, c.output, c.expected); got != c.want {
+			t.Errorf("matches(%q, %v, %q, %q) = %v, want %v", c.match, c.epsilon, c.output, c.expected, got, c.want)
+		}
+	}
+}
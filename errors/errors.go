@@ -62,4 +62,102 @@ var (
 	ErrorInvalidJobSecret            = &Error{errors.New("INVALID JOB SECRET"), http.StatusUnauthorized}
 	ErrorUnableToReachMicroService   = &Error{errors.New("MICROSERVICE CONNECT FAILURE"), http.StatusInternalServerError}
 	ErrorUnableToCreateJob           = &Error{errors.New("K8S JOB CREATION FAILURE"), http.StatusInternalServerError}
+	ErrorInvalidInviteCode           = &Error{errors.New("INVITE CODE INVALID OR EXPIRED"), http.StatusNotFound}
+	ErrorInvalidRole                 = &Error{errors.New("INVALID COURSE ROLE"), http.StatusBadRequest}
+	ErrorUserAlreadyWaitlisted       = &Error{errors.New("USER ALREADY ON COURSE WAITLIST"), http.StatusConflict}
+	ErrorInvalidTimezone             = &Error{errors.New("INVALID TIMEZONE"), http.StatusBadRequest}
+	ErrorNoPendingDeletion           = &Error{errors.New("NO PENDING ACCOUNT DELETION"), http.StatusNotFound}
+	ErrorInvalidRetentionPeriod      = &Error{errors.New("RETENTION PERIOD CANNOT BE NEGATIVE"), http.StatusBadRequest}
+	ErrorFailedToCreateArchive       = &Error{errors.New("FAILED TO CREATE ARCHIVE"), http.StatusInternalServerError}
+	ErrorAssignmentNotTimed          = &Error{errors.New("ASSIGNMENT IS NOT TIMED"), http.StatusBadRequest}
+	ErrorAssignmentNotStarted        = &Error{errors.New("TIMED ASSIGNMENT MUST BE STARTED BEFORE SUBMITTING"), http.StatusForbidden}
+	ErrorSubmissionWindowClosed      = &Error{errors.New("SUBMISSION WINDOW FOR TIMED ASSIGNMENT HAS CLOSED"), http.StatusForbidden}
+	ErrorAttestationRequired         = &Error{errors.New("ASSIGNMENT ATTESTATION MUST BE ACCEPTED"), http.StatusBadRequest}
+	ErrorInvalidQuery                = &Error{errors.New("INVALID QUERY PARAMETER"), http.StatusBadRequest}
+	ErrorInvalidAPIToken             = &Error{errors.New("INVALID OR REVOKED API TOKEN"), http.StatusUnauthorized}
+	ErrorInvalidTokenScope           = &Error{errors.New("INVALID API TOKEN SCOPE"), http.StatusBadRequest}
+	ErrorRateLimitExceeded           = &Error{errors.New("RATE LIMIT EXCEEDED"), http.StatusTooManyRequests}
+	ErrorInvalidRepository           = &Error{errors.New("REPOSITORY MUST BE A HTTPS URL ON AN ALLOWED HOST WITH A FULL COMMIT SHA"), http.StatusBadRequest}
+	ErrorFailedToCloneRepository     = &Error{errors.New("FAILED TO FETCH REPOSITORY COMMIT"), http.StatusBadRequest}
+	ErrorRepositoryTooLarge          = &Error{errors.New("REPOSITORY COMMIT TOO LARGE"), http.StatusRequestEntityTooLarge}
+	ErrorInvalidWebhookSignature     = &Error{errors.New("INVALID WEBHOOK SIGNATURE"), http.StatusUnauthorized}
+	ErrorNoLeaderboard               = &Error{errors.New("ASSIGNMENT HAS NO LEADERBOARD"), http.StatusNotFound}
+	ErrorUnknownFixture              = &Error{errors.New("TEST USES A FIXTURE THE ASSIGNMENT DOES NOT HAVE"), http.StatusBadRequest}
+	ErrorFixtureInUse                = &Error{errors.New("FIXTURE IS USED BY A TEST"), http.StatusConflict}
+	ErrorInvalidTestMatch            = &Error{errors.New("INVALID TEST OUTPUT MATCH"), http.StatusBadRequest}
+	ErrorDryRunTimedOut              = &Error{errors.New("DRY RUN DID NOT FINISH IN TIME"), http.StatusGatewayTimeout}
+	ErrorInvalidPrecheck             = &Error{errors.New("INVALID PRECHECK FILE PATTERN"), http.StatusBadRequest}
+	ErrorManifestMismatch            = &Error{errors.New("SUBMISSION FILES DO NOT MATCH THE MANIFEST"), http.StatusBadRequest}
+	ErrorDisputeAlreadyOpen          = &Error{errors.New("SUBMISSION ALREADY HAS AN OPEN DISPUTE"), http.StatusConflict}
+	ErrorDisputeClosed               = &Error{errors.New("DISPUTE IS RESOLVED"), http.StatusConflict}
+	ErrorGradeOverrideRequired       = &Error{errors.New("ADJUSTED DISPUTES NEED A GRADE"), http.StatusBadRequest}
+	ErrorInvalidGradeScale           = &Error{errors.New("INVALID GRADE SCALE"), http.StatusBadRequest}
+	ErrorInvalidCheckInCode          = &Error{errors.New("INVALID CHECK IN CODE"), http.StatusBadRequest}
+	ErrorAttendanceClosed            = &Error{errors.New("ATTENDANCE SESSION IS CLOSED"), http.StatusConflict}
+	ErrorInvalidOfficeHours          = &Error{errors.New("INVALID OFFICE HOURS"), http.StatusBadRequest}
+	ErrorSendingMail                 = &Error{errors.New("FAILED TO SEND EMAIL"), http.StatusInternalServerError}
+	ErrorInvalidDigestFrequency      = &Error{errors.New("DIGEST FREQUENCY MUST BE OFF, DAILY OR WEEKLY"), http.StatusBadRequest}
+	ErrorInvalidWebhook              = &Error{errors.New("WEBHOOK NEEDS AN HTTP(S) URL AND KNOWN EVENTS"), http.StatusBadRequest}
+	ErrorInvalidOrganization         = &Error{errors.New("ORGANIZATION NEEDS A NAME, A LOWERCASE SLUG AND VALID BRANDING"), http.StatusBadRequest}
+	ErrorOrganizationRegistration    = &Error{errors.New("ORGANIZATION DOES NOT ALLOW THIS REGISTRATION"), http.StatusForbidden}
+	ErrorOrganizationMismatch        = &Error{errors.New("USER BELONGS TO ANOTHER ORGANIZATION"), http.StatusForbidden}
+	ErrorFeatureDisabled             = &Error{errors.New("FEATURE IS NOT ENABLED FOR THIS COURSE"), http.StatusForbidden}
+	ErrorUnknownSigningKey           = &Error{errors.New("TOKEN IS NOT SIGNED WITH AN ACTIVE KEY"), http.StatusUnauthorized}
+	ErrorInvalidSigningKeys          = &Error{errors.New("SIGNING KEYS ARE MISCONFIGURED"), http.StatusInternalServerError}
+	ErrorRequestTooLarge             = &Error{errors.New("REQUEST BODY IS TOO LARGE"), http.StatusRequestEntityTooLarge}
+	ErrorInvalidSearchPattern        = &Error{errors.New("INVALID SEARCH PATTERN"), http.StatusBadRequest}
+	ErrorInvalidSections             = &Error{errors.New("INVALID COURSE SECTIONS"), http.StatusBadRequest}
+	ErrorInvalidGraders              = &Error{errors.New("GRADERS MUST BE STAFF OF THE COURSE"), http.StatusBadRequest}
+	ErrorBulkOperationFailed         = &Error{errors.New("BULK OPERATION NOT APPLIED"), http.StatusBadRequest}
+	ErrorCourseArchived              = &Error{errors.New("COURSE IS ARCHIVED AND READ ONLY"), http.StatusForbidden}
+	ErrorInvalidShareLink            = &Error{errors.New("SHARE LINK IS INVALID, EXPIRED OR REVOKED"), http.StatusNotFound}
+	ErrorInvalidShareExpiry          = &Error{errors.New("SHARE LINKS MUST EXPIRE WITHIN 14 DAYS"), http.StatusBadRequest}
+	ErrorUnsupportedImportFormat     = &Error{errors.New("UNSUPPORTED IMPORT FORMAT"), http.StatusBadRequest}
+	ErrorInvalidImport               = &Error{errors.New("FILE IS NOT A VALID DEFINITION OF THE IMPORT FORMAT"), http.StatusBadRequest}
+	ErrorInvalidQuota                = &Error{errors.New("QUOTAS CANNOT BE NEGATIVE"), http.StatusBadRequest}
+	ErrorCourseQuotaExceeded         = &Error{errors.New("COURSE HAS USED UP ITS STORAGE OR GRADING QUOTA"), http.StatusForbidden}
+	ErrorInvalidReportRange          = &Error{errors.New("REPORT RANGE MUST BE MONTHS LIKE 2024-09, FROM NO LATER THAN TO"), http.StatusBadRequest}
+	ErrorInvalidGradingJob           = &Error{errors.New("GRADING JOB DOES NOT MATCH THE COURT HERALD CONTRACT"), http.StatusUnprocessableEntity}
+	ErrorInvalidGraderResponse       = &Error{errors.New("COURT HERALD ANSWER DOES NOT MATCH THE CONTRACT"), http.StatusBadGateway}
+	ErrorSubmissionNotGrading        = &Error{errors.New("SUBMISSION IS NOT BEING GRADED"), http.StatusConflict}
+	ErrorAttemptNotRefundable        = &Error{errors.New("ATTEMPT CANNOT BE REFUNDED"), http.StatusForbidden}
+	ErrorNoGraderAvailable           = &Error{errors.New("NO GRADER CAN GRADE THIS ASSIGNMENT"), http.StatusServiceUnavailable}
+	ErrorInvalidGrader               = &Error{errors.New("INVALID GRADER"), http.StatusBadRequest}
+	ErrorInvalidTimeLimit            = &Error{errors.New("TIME LIMIT CANNOT BE NEGATIVE"), http.StatusBadRequest}
+	ErrorAssignmentNotReady          = &Error{errors.New("ASSIGNMENT IS NOT READY TO BE PUBLISHED"), http.StatusConflict}
+	ErrorAssignmentLocked            = &Error{errors.New("ASSIGNMENT IS LOCKED UNTIL ITS PREREQUISITES ARE MET"), http.StatusForbidden}
+	ErrorInvalidPrerequisites        = &Error{errors.New("PREREQUISITES MUST BE OTHER ASSIGNMENTS OF THE COURSE, SCORES 0 TO 100, WITHOUT CYCLES"), http.StatusBadRequest}
+	ErrorInvalidMilestones           = &Error{errors.New("MILESTONES NEED UNIQUE NAMES, SOME OF THE TESTS AND TO BE DUE BY THE ASSIGNMENT"), http.StatusBadRequest}
+	ErrorInvalidArtifactSlots        = &Error{errors.New("UPLOAD SLOTS NEED UNIQUE LOWERCASE NAMES"), http.StatusBadRequest}
+	ErrorMissingArtifact             = &Error{errors.New("A REQUIRED UPLOAD SLOT HAS NO FILE"), http.StatusBadRequest}
+	ErrorUnacceptedArtifact          = &Error{errors.New("FILE TYPE IS NOT ACCEPTED BY ITS UPLOAD SLOT"), http.StatusBadRequest}
+	ErrorInvalidRubric               = &Error{errors.New("RUBRIC CRITERIA NEED A NAME AND POINTS"), http.StatusBadRequest}
+	ErrorInvalidReview               = &Error{errors.New("A REVIEW MUST SCORE EVERY RUBRIC CRITERION ONCE WITHIN ITS POINTS"), http.StatusBadRequest}
+	ErrorNotManuallyGraded           = &Error{errors.New("ASSIGNMENT IS NOT GRADED BY HAND ON A RUBRIC"), http.StatusBadRequest}
+	ErrorManuallyGraded              = &Error{errors.New("ASSIGNMENT IS GRADED BY HAND, NOT BY COURT HERALD"), http.StatusBadRequest}
+	ErrorManualSubmissionType        = &Error{errors.New("MANUALLY GRADED ASSIGNMENTS TAKE A PDF OR IMAGE UPLOAD"), http.StatusBadRequest}
+	ErrorInvalidQuestion             = &Error{errors.New("QUESTIONS NEED A PROMPT, POINTS AND AN ANSWER OF THEIR KIND"), http.StatusBadRequest}
+	ErrorInvalidQuiz                 = &Error{errors.New("QUIZZES TAKE QUESTIONS OF THE COURSE'S BANK AND POOLS IT CAN FILL"), http.StatusBadRequest}
+	ErrorNotEnoughQuestions          = &Error{errors.New("QUESTION BANK HAS TOO FEW QUESTIONS FOR THE QUIZ"), http.StatusConflict}
+	ErrorNotAQuiz                    = &Error{errors.New("ASSIGNMENT IS NOT A QUIZ"), http.StatusBadRequest}
+	ErrorQuizAssignment              = &Error{errors.New("QUIZZES ARE TAKEN AS QUIZ ATTEMPTS, NOT SUBMITTED"), http.StatusBadRequest}
+	ErrorQuizAttemptClosed           = &Error{errors.New("QUIZ ATTEMPT WAS ALREADY SUBMITTED"), http.StatusConflict}
+	ErrorInvalidQuizResponse         = &Error{errors.New("RESPONSES MUST ANSWER QUESTIONS OF THE ATTEMPT"), http.StatusBadRequest}
+	ErrorInvalidEditorFiles          = &Error{errors.New("EDITOR FILES NEED RELATIVE PATHS, AT MOST 200 FILES AND 5MB"), http.StatusBadRequest}
+	ErrorInvalidDraft                = &Error{errors.New("DRAFTS ARE SAVED FROM THE EDITOR OR CLI WITH RELATIVE PATHS, AT MOST 200 FILES AND 5MB"), http.StatusBadRequest}
+	ErrorDraftDeadlinePassed         = &Error{errors.New("DRAFTS CANNOT BE SAVED AFTER THE DEADLINE"), http.StatusConflict}
+	ErrorNothingToRegrade            = &Error{errors.New("ASSIGNMENT HAS NO GRADED SUBMISSIONS TO REGRADE"), http.StatusConflict}
+	ErrorExtensionAlreadyRequested   = &Error{errors.New("AN EXTENSION REQUEST FOR THIS ASSIGNMENT IS ALREADY PENDING"), http.StatusConflict}
+	ErrorExtensionDecided            = &Error{errors.New("EXTENSION REQUEST WAS ALREADY DECIDED"), http.StatusConflict}
+	ErrorInvalidExtension            = &Error{errors.New("EXTENSIONS MUST BE TO A DATE AFTER THE CURRENT DUE DATE"), http.StatusBadRequest}
+	ErrorInvalidLatePolicy           = &Error{errors.New("LATE POLICIES TAKE 0 TO 100 POINTS A DAY FOR AT MOST 365 DAYS"), http.StatusBadRequest}
+	ErrorInvalidCooldown             = &Error{errors.New("COOLDOWN MUST NOT BE NEGATIVE"), http.StatusBadRequest}
+	ErrorInvalidPolicyTemplate       = &Error{errors.New("POLICY TEMPLATES NEED UNIQUE NAMES AND VALID SETTINGS"), http.StatusBadRequest}
+	ErrorUnknownPolicyTemplate       = &Error{errors.New("COURSE HAS NO POLICY TEMPLATE OF THAT NAME"), http.StatusBadRequest}
+	ErrorSubmissionCooldown          = &Error{errors.New("SUBMITTED TOO RECENTLY, WAIT FOR THE COOLDOWN"), http.StatusTooManyRequests}
+	ErrorSubmissionTooLate           = &Error{errors.New("LATE SUBMISSIONS ARE NO LONGER ACCEPTED"), http.StatusConflict}
+	ErrorInvalidModule               = &Error{errors.New("MODULES CAN ONLY HOLD ASSIGNMENTS AND ANNOUNCEMENTS OF THEIR COURSE"), http.StatusBadRequest}
+	ErrorInvalidModuleOrder          = &Error{errors.New("REORDERING MUST LIST EVERY MODULE OF THE COURSE ONCE"), http.StatusBadRequest}
+	ErrorFailedToWriteJSON           = &Error{errors.New("FAILED TO WRITE JSON"), http.StatusInternalServerError}
+	ErrorPrivateWebhook              = &Error{errors.New("WEBHOOK URL MUST BE PUBLIC"), http.StatusBadRequest}
 )
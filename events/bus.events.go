@@ -0,0 +1,139 @@
+// Package events is an in-process bus for domain events. Models publish what
+// happened and features with side effects, such as webhooks, subscribe to it
+// instead of being called from every request handler that causes the event.
+// Events can also be forwarded to an external broker for other services.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/config"
+)
+
+// Event names.
+const (
+	// All subscribes a handler to every event.
+	All = "*"
+
+	SubmissionGradedEvent    = "submission.graded"
+	AssignmentPublishedEvent = "assignment.published"
+	EnrollmentAddedEvent     = "enrollment.added"
+	UserRegisteredEvent      = "user.registered"
+)
+
+type (
+	// Event something that happened, Data is one of the event structs below.
+	Event struct {
+		Name string      `json:"name"`
+		Data interface{} `json:"data"`
+		At   time.Time   `json:"at"`
+	}
+
+	// SubmissionGraded a submission's results, or its failure to grade, were
+	// recorded.
+	SubmissionGraded struct {
+		SubmissionID primitive.ObjectID `json:"submissionID"`
+		Errored      bool               `json:"errored"`
+	}
+
+	// AssignmentPublished an assignment became visible to students.
+	AssignmentPublished struct {
+		AssignmentID primitive.ObjectID `json:"assignmentID"`
+		Name         string             `json:"name"`
+		DueDate      primitive.DateTime `json:"dueDate"`
+	}
+
+	// EnrollmentAdded a user joined a course.
+	EnrollmentAdded struct {
+		CourseID primitive.ObjectID `json:"courseID"`
+		UserID   primitive.ObjectID `json:"userID"`
+		Role     string             `json:"role"`
+	}
+
+	// UserRegistered an account was created.
+	UserRegistered struct {
+		UserID primitive.ObjectID `json:"userID"`
+		Email  string             `json:"email"`
+	}
+
+	// Handler reacts to an event. Handlers run in their own goroutine so they
+	// never hold up the request that published the event.
+	Handler func(Event)
+
+	// Forwarder sends events to an external broker.
+	Forwarder interface {
+		Forward(name string, payload []byte) error
+	}
+)
+
+var (
+	lock      sync.RWMutex
+	handlers  = make(map[string][]Handler)
+	forwarder Forwarder
+)
+
+// Subscribe calls handler with every event of a name, or every event for All.
+func Subscribe(name string, handler Handler) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	handlers[name] = append(handlers[name], handler)
+}
+
+// Forward sends every event to a broker as well, nil stops forwarding.
+func Forward(to Forwarder) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	forwarder = to
+}
+
+// Configure forwards events to NATS when EVENT_BUS_NATS_URL is set.
+func Configure() {
+	if url := config.C.EventBus.NATSURL; url != "" {
+		Forward(NewNATSForwarder(url, config.C.EventBus.SubjectPrefix))
+	}
+}
+
+func run(handler Handler, event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			tyrgin.ErrorLogger(fmt.Errorf("%v", r), "Event handler for "+event.Name+" panicked.")
+		}
+	}()
+
+	handler(event)
+}
+
+// Publish sends an event to its subscribers and the forwarder.
+func Publish(name string, data interface{}) {
+	event := Event{name, data, time.Now()}
+
+	lock.RLock()
+	subscribed := append(append([]Handler{}, handlers[name]...), handlers[All]...)
+	to := forwarder
+	lock.RUnlock()
+
+	for _, handler := range subscribed {
+		go run(handler, event)
+	}
+
+	if to != nil {
+		go func() {
+			payload, err := json.Marshal(event)
+			if err == nil {
+				err = to.Forward(name, payload)
+			}
+			if err != nil {
+				tyrgin.ErrorLogger(err, "Failed to forward event "+name)
+			}
+		}()
+	}
+}
@@ -0,0 +1,212 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/config"
+)
+
+// Collections whose changes are watched.
+const (
+	SubmissionsCollection  = "submissions"
+	FeatureFlagsCollection = "featureflags"
+	DryRunsCollection      = "dryruns"
+)
+
+// Change operations.
+const (
+	ChangeInsert  = "insert"
+	ChangeUpdate  = "update"
+	ChangeReplace = "replace"
+	ChangeDelete  = "delete"
+)
+
+// reopenInterval how long a change stream that could not be opened, or
+// broke, waits before it is opened again.
+const reopenInterval = 30 * time.Second
+
+type (
+	// Change a document of a watched collection was written. Read from a
+	// change stream every replica of the backend sees every change, whichever
+	// replica made it, unlike the events published on the bus.
+	Change struct {
+		Collection string
+		Operation  string
+		ID         primitive.ObjectID
+		// Updated the fields an update set, nil for other operations and
+		// changes made without change streams.
+		Updated bson.M
+	}
+
+	// ChangeHandler reacts to a change. Handlers run on the goroutine reading
+	// the change stream, so they must not block.
+	ChangeHandler func(Change)
+
+	// changeDocument what a change stream returns of a change.
+	changeDocument struct {
+		Token         bson.M `bson:"_id"`
+		OperationType string `bson:"operationType"`
+		DocumentKey   struct {
+			ID primitive.ObjectID `bson:"_id"`
+		} `bson:"documentKey"`
+		UpdateDescription struct {
+			UpdatedFields bson.M `bson:"updatedFields"`
+		} `bson:"updateDescription"`
+	}
+)
+
+var (
+	changeLock          sync.RWMutex
+	watchers            = make(map[string]map[int]ChangeHandler)
+	nextWatcher         int
+	streaming           = make(map[string]bool)
+	streamedCollections = []string{SubmissionsCollection, FeatureFlagsCollection, DryRunsCollection}
+)
+
+// Watch calls handler with every change of a collection until stop is called.
+func Watch(collection string, handler ChangeHandler) (stop func()) {
+	changeLock.Lock()
+	defer changeLock.Unlock()
+
+	nextWatcher++
+	id := nextWatcher
+	if watchers[collection] == nil {
+		watchers[collection] = make(map[int]ChangeHandler)
+	}
+	watchers[collection][id] = handler
+
+	return func() {
+		changeLock.Lock()
+		defer changeLock.Unlock()
+
+		delete(watchers[collection], id)
+	}
+}
+
+// Changed tells this replica's watchers of a change it made itself. Only
+// used while the collection's change stream is not open, such as against a
+// development database that is not a replica set, as the stream delivers
+// the change otherwise.
+func Changed(change Change) {
+	changeLock.RLock()
+	streamed := streaming[change.Collection]
+	changeLock.RUnlock()
+
+	if !streamed {
+		deliver(change)
+	}
+}
+
+// Graded reports whether a change of a submission took it out of grading.
+func (c Change) Graded() bool {
+	if c.Collection != SubmissionsCollection || c.Operation != ChangeUpdate {
+		return false
+	}
+
+	// changes made without change streams are only told of once graded
+	inProgress, set := c.Updated["inProgress"]
+	return c.Updated == nil || (set && inProgress == false)
+}
+
+func deliver(change Change) {
+	changeLock.RLock()
+	handlers := make([]ChangeHandler, 0, len(watchers[change.Collection]))
+	for _, handler := range watchers[change.Collection] {
+		handlers = append(handlers, handler)
+	}
+	changeLock.RUnlock()
+
+	for _, handler := range handlers {
+		handler(change)
+	}
+}
+
+func setStreaming(collection string, open bool) {
+	changeLock.Lock()
+	defer changeLock.Unlock()
+
+	streaming[collection] = open
+}
+
+// StreamChanges opens a change stream on every watched collection, when
+// CHANGE_STREAMS is on, reopening streams that break where they left off.
+// Called once at startup.
+func StreamChanges() {
+	if !config.C.ChangeStreams {
+		return
+	}
+
+	for _, collection := range streamedCollections {
+		go streamChanges(collection)
+	}
+}
+
+func streamChanges(collection string) {
+	var resumeAfter bson.M
+	for {
+		token, err := readChanges(collection, resumeAfter)
+		setStreaming(collection, false)
+		if token != nil {
+			resumeAfter = token
+		}
+		if err != nil {
+			tyrgin.ErrorLogger(err, "Change stream of "+collection+" closed, reopening it shortly.")
+		}
+
+		time.Sleep(reopenInterval)
+	}
+}
+
+// readChanges delivers the changes of a collection until its stream breaks,
+// returning the resume token of the last change delivered.
+func readChanges(collection string, resumeAfter bson.M) (bson.M, error) {
+	ctx := context.Background()
+	db, err := tyrgin.GetMongoDB(config.C.DBName)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.ChangeStream()
+	if resumeAfter != nil {
+		opts.SetResumeAfter(resumeAfter)
+	}
+
+	cur, err := tyrgin.GetMongoCollection(collection, db).Watch(ctx, []interface{}{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	setStreaming(collection, true)
+
+	var token bson.M
+	for cur.Next(ctx) {
+		var doc changeDocument
+		if err = cur.Decode(&doc); err != nil {
+			return token, err
+		}
+		token = doc.Token
+
+		change := Change{
+			Collection: collection,
+			Operation:  doc.OperationType,
+			ID:         doc.DocumentKey.ID,
+		}
+		if doc.OperationType == ChangeUpdate {
+			change.Updated = doc.UpdateDescription.UpdatedFields
+			if change.Updated == nil {
+				change.Updated = bson.M{}
+			}
+		}
+		deliver(change)
+	}
+
+	return token, cur.Err()
+}
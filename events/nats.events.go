@@ -0,0 +1,103 @@
+package events
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NATSForwarder publishes events to a NATS server over its text protocol, on
+// the subject "<prefix>.<event name>". It only publishes, so it needs no
+// client library. The connection is made on first use and again after an
+// error.
+type NATSForwarder struct {
+	sync.Mutex
+	address string
+	prefix  string
+	conn    net.Conn
+}
+
+// NewNATSForwarder forwards to a nats://host:port url, the prefix defaults to
+// "tyr".
+func NewNATSForwarder(address, prefix string) *NATSForwarder {
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		address = u.Host
+	}
+	if prefix == "" {
+		prefix = "tyr"
+	}
+
+	return &NATSForwarder{address: address, prefix: prefix}
+}
+
+// connect dials the server, reads its INFO and answers its pings until the
+// connection closes.
+func (n *NATSForwarder) connect() error {
+	conn, err := net.DialTimeout("tcp", n.address, 5*time.Second)
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	info, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(info, "INFO") {
+		conn.Close()
+		return fmt.Errorf("nats server at %s did not send INFO", n.address)
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	if _, err = conn.Write([]byte("CONNECT {\"verbose\":false,\"pedantic\":false,\"name\":\"plague-doctor\"}\r\n")); err != nil {
+		conn.Close()
+		return err
+	}
+
+	go func() {
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				n.Lock()
+				if n.conn == conn {
+					n.conn = nil
+				}
+				n.Unlock()
+				conn.Close()
+				return
+			}
+
+			if strings.HasPrefix(line, "PING") {
+				n.Lock()
+				conn.Write([]byte("PONG\r\n"))
+				n.Unlock()
+			}
+		}
+	}()
+
+	n.conn = conn
+	return nil
+}
+
+// Forward publishes an event's payload.
+func (n *NATSForwarder) Forward(name string, payload []byte) error {
+	n.Lock()
+	defer n.Unlock()
+
+	if n.conn == nil {
+		if err := n.connect(); err != nil {
+			return err
+		}
+	}
+
+	message := fmt.Sprintf("PUB %s.%s %d\r\n%s\r\n", n.prefix, name, len(payload), payload)
+	if _, err := n.conn.Write([]byte(message)); err != nil {
+		n.conn.Close()
+		n.conn = nil
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+// Package features decides which features are turned on for a course, so risky
+// features can be rolled out to pilot courses first. Every flag has a default,
+// which FEATURE_FLAGS can change for the deployment, and admins can override
+// it for every course or for single courses.
+package features
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/config"
+	"backend/models"
+	"backend/models/flagmodels"
+)
+
+// Flag names.
+const (
+	Leaderboards   = "leaderboards"
+	Attendance     = "attendance"
+	GitSubmissions = "gitSubmissions"
+	CourseWebhooks = "courseWebhooks"
+	Quizzes        = "quizzes"
+)
+
+// refreshInterval how long overrides are cached before they are read again.
+const refreshInterval = 30 * time.Second
+
+// Flag a feature that can be turned on or off.
+type Flag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`
+}
+
+// Flags every flag handlers consult.
+var Flags = []Flag{
+	{Leaderboards, "Assignment leaderboards", true},
+	{Attendance, "Attendance sessions and check ins", true},
+	{GitSubmissions, "Submitting and linking git repositories", true},
+	{CourseWebhooks, "Webhooks registered by course staff", true},
+	{Quizzes, "Quizzes from course question banks", true},
+}
+
+var (
+	fm = models.NewMongoFeatureFlagInterface()
+
+	lock      sync.RWMutex
+	overrides = make(map[string]flagmodels.MongoFlag)
+	loadedAt  time.Time
+)
+
+// Known reports whether name is a flag.
+func Known(name string) bool {
+	_, found := find(name)
+	return found
+}
+
+func find(name string) (Flag, bool) {
+	for _, flag := range Flags {
+		if flag.Name == name {
+			return flag, true
+		}
+	}
+
+	return Flag{}, false
+}
+
+// Default a flag's default for the deployment, FEATURE_FLAGS overrides the
+// built in one, e.g. "leaderboards=false,attendance=true".
+func Default(name string) bool {
+	if enabled, found := config.C.FeatureFlags[name]; found {
+		return enabled
+	}
+
+	flag, _ := find(name)
+	return flag.Default
+}
+
+// Overrides the overrides set by admins, read again once the cache is stale.
+func Overrides() map[string]flagmodels.MongoFlag {
+	lock.RLock()
+	fresh := time.Since(loadedAt) < refreshInterval
+	cached := overrides
+	lock.RUnlock()
+
+	if fresh {
+		return cached
+	}
+
+	flags, err := fm.Find()
+	if err != nil {
+		// keep serving the last overrides while the database is unreachable
+		tyrgin.ErrorLogger(err, "Failed to load feature flags.")
+		return cached
+	}
+
+	loaded := make(map[string]flagmodels.MongoFlag)
+	for _, flag := range flags {
+		loaded[flag.Name] = flag
+	}
+
+	lock.Lock()
+	overrides = loaded
+	loadedAt = time.Now()
+	lock.Unlock()
+
+	return loaded
+}
+
+// Invalidate drops the cached overrides after they change.
+func Invalidate() {
+	lock.Lock()
+	defer lock.Unlock()
+
+	loadedAt = time.Time{}
+}
+
+// Enabled reports whether a flag is on for a course. A course override wins
+// over the override for every course, which wins over the default.
+func Enabled(name string, cid primitive.ObjectID) bool {
+	override, found := Overrides()[name]
+	if !found {
+		return Default(name)
+	}
+
+	if enabled := override.Course(cid); enabled != nil {
+		return *enabled
+	}
+
+	if override.Enabled != nil {
+		return *override.Enabled
+	}
+
+	return Default(name)
+}
+
+// ForCourse every flag and whether it is on for a course.
+func ForCourse(cid primitive.ObjectID) map[string]bool {
+	enabled := make(map[string]bool)
+	for _, flag := range Flags {
+		enabled[flag.Name] = Enabled(flag.Name, cid)
+	}
+
+	return enabled
+}
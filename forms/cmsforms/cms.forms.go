@@ -2,16 +2,56 @@ package cmsforms
 
 import (
 	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/utils"
 )
 
 // Assignment Creation/Submission types/structs
 type (
 	AssignmentAgg struct {
-		ID        primitive.ObjectID `bson:"_id,omitempty" json:"id" binding:"required"`
-		DueDate   primitive.DateTime `bson:"dueDate" json:"dueDate" binding:"required"`
-		Name      string             `bson:"name" json:"name" binding:"required"`
-		Published bool               `bson:"published" json:"-" binding:"required"`
-		CourseID  primitive.ObjectID `bson:"courseID" json:"courseID" binding:",omitempty"`
+		ID           primitive.ObjectID `bson:"_id,omitempty" json:"id" binding:"required"`
+		DueDate      primitive.DateTime `bson:"dueDate" json:"dueDate" binding:"required"`
+		DueDateLocal *utils.LocalTime   `bson:"-" json:"dueDateLocal,omitempty"`
+		Name         string             `bson:"name" json:"name" binding:"required"`
+		Published    bool               `bson:"published" json:"-" binding:"required"`
+		CourseID     primitive.ObjectID `bson:"courseID" json:"courseID" binding:",omitempty"`
+	}
+
+	// AssignmentStatus how the student asking is doing on an assignment.
+	AssignmentStatus struct {
+		Submitted     bool                `bson:"-" json:"submitted"`
+		Attempts      int                 `bson:"attempts" json:"attempts"`
+		AttemptsLeft  int                 `bson:"-" json:"attemptsLeft"`
+		BestScore     *float64            `bson:"bestScore" json:"bestScore"`
+		Grading       bool                `bson:"grading" json:"grading"`
+		LastSubmitted *primitive.DateTime `bson:"lastSubmitted" json:"lastSubmitted,omitempty"`
+	}
+
+	// AssignmentStatsAgg an assignment of a course with the asking student's
+	// status, or for staff how many students have submitted.
+	AssignmentStatsAgg struct {
+		ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+		DueDate           primitive.DateTime `bson:"dueDate" json:"dueDate"`
+		DueDateLocal      *utils.LocalTime   `bson:"-" json:"dueDateLocal,omitempty"`
+		Name              string             `bson:"name" json:"name"`
+		CourseID          primitive.ObjectID `bson:"courseID" json:"courseID"`
+		NumAttempts       int                `bson:"numAttempts" json:"numAttempts"`
+		Status            *AssignmentStatus  `bson:"status,omitempty" json:"status,omitempty"`
+		SubmittedStudents *int               `bson:"submittedStudents,omitempty" json:"submittedStudents,omitempty"`
+	}
+
+	CreateAPIToken struct {
+		Name      string              `json:"name" binding:"required"`
+		Scopes    []string            `json:"scopes" binding:"required"`
+		RateLimit int                 `json:"rateLimit"`
+		ExpiresAt *primitive.DateTime `json:"expiresAt"`
+	}
+
+	CreateAnnouncement struct {
+		Title       string              `json:"title" binding:"required"`
+		Body        string              `json:"body" binding:"required"`
+		Pinned      bool                `json:"pinned"`
+		PublishDate *primitive.DateTime `json:"publishDate"`
 	}
 
 	CourseAddUser struct {
@@ -24,22 +64,65 @@ type (
 		Emails []string `json:"emails" binding:"required"`
 	}
 
-	CreateAssignmentTest struct {
-		Name           string `json:"name"`
-		ExpectedOutput string `json:"expectedOutput"`
-		StudentFacing  bool   `json:"studentFacing"`
+	CreateAssignmentTestVariant struct {
 		TestCMD        string `json:"testCMD"`
+		ExpectedOutput string `json:"expectedOutput"`
+	}
+
+	CreateAssignmentTest struct {
+		Name           string                        `json:"name"`
+		ExpectedOutput string                        `json:"expectedOutput"`
+		StudentFacing  bool                          `json:"studentFacing"`
+		TestCMD        string                        `json:"testCMD"`
+		Variants       []CreateAssignmentTestVariant `json:"variants"`
+
+		InputFixture          string   `json:"inputFixture"`
+		ExpectedOutputFixture string   `json:"expectedOutputFixture"`
+		Fixtures              []string `json:"fixtures"`
+
+		Match   string  `json:"match"`
+		Epsilon float64 `json:"epsilon"`
 	}
 
 	CreateAssignmentPreParse struct {
 		Language     string             `form:"language" binding:"required"`
 		Version      string             `form:"version"`
 		Name         string             `form:"name" binding:"required"`
-		NumAttempts  int                `form:"numAttempts" binding:"required"`
+		NumAttempts  int                `form:"numAttempts"`
 		Description  string             `form:"description" binding:"required"`
 		DueDate      primitive.DateTime `form:"dueDate" binding:"required"`
 		TestBuildCMD string             `form:"testBuildCMD"`
-		Tests        []string           `form:"tests" binding:"required"`
+		Tests        []string           `form:"tests"`
+		TimeLimit    int                `form:"timeLimit"`
+		Attestation  string             `form:"attestation"`
+		// LeaderboardMetric the grader metric to rank submissions by, no
+		// leaderboard when empty.
+		LeaderboardMetric        string `form:"leaderboardMetric"`
+		LeaderboardLowerIsBetter bool   `form:"leaderboardLowerIsBetter"`
+		// PrecheckFiles comma separated paths a submission must contain.
+		PrecheckFiles     string `form:"precheckFiles"`
+		PrecheckExclusive bool   `form:"precheckExclusive"`
+		PrecheckBuild     bool   `form:"precheckBuild"`
+		ExtraCredit       bool   `form:"extraCredit"`
+		// Requirements comma separated labels a grader needs to grade the
+		// assignment, e.g. gpu or arm64.
+		Requirements string `form:"requirements"`
+		// ShowHiddenSummary tells students how many hidden tests their
+		// submissions passed.
+		ShowHiddenSummary bool `form:"showHiddenSummary"`
+		// Manual assignments take a document staff grade by hand on the
+		// Rubric, a JSON list of RubricCriterion, instead of running tests.
+		Manual bool   `form:"manual"`
+		Rubric string `form:"rubric"`
+		// Quiz assignments are taken as quizzes, whose questions are chosen
+		// once it is created.
+		Quiz bool `form:"quiz"`
+		// Policy the name of a course policy template whose settings apply
+		// where the form does not give its own.
+		Policy          string `form:"policy"`
+		CooldownMinutes int    `form:"cooldownMinutes"`
+		// LatePolicy a JSON LatePolicy, none when empty.
+		LatePolicy string `form:"latePolicy"`
 	}
 
 	CreateAssignmentPostParse struct {
@@ -51,13 +134,359 @@ type (
 		DueDate      primitive.DateTime
 		TestBuildCMD string
 		Tests        []CreateAssignmentTest
+		TimeLimit    int
+		Attestation  string
+
+		LeaderboardMetric        string
+		LeaderboardLowerIsBetter bool
+
+		PrecheckFiles     string
+		PrecheckExclusive bool
+		PrecheckBuild     bool
+		ExtraCredit       bool
+		Requirements      string
+		ShowHiddenSummary bool
+
+		Manual bool
+		Rubric []RubricCriterion
+		Quiz   bool
+
+		CooldownMinutes int
+		LatePolicy      *LatePolicy
+		Policy          string
+	}
+
+	// LatePolicy takes PenaltyPerDay points off a submission for every day
+	// late, refusing those more than MaxDaysLate days late.
+	LatePolicy struct {
+		PenaltyPerDay float64 `json:"penaltyPerDay"`
+		MaxDaysLate   int     `json:"maxDaysLate"`
+	}
+
+	// PolicyTemplate assignment settings applied to the assignments created
+	// with it, those left out are not part of it.
+	PolicyTemplate struct {
+		Name              string      `json:"name" binding:"required"`
+		NumAttempts       *int        `json:"numAttempts"`
+		TimeLimit         *int        `json:"timeLimit"`
+		CooldownMinutes   *int        `json:"cooldownMinutes"`
+		LatePolicy        *LatePolicy `json:"latePolicy"`
+		ShowHiddenSummary *bool       `json:"showHiddenSummary"`
+		Attestation       *string     `json:"attestation"`
+	}
+
+	// UpdatePolicyTemplates replaces every policy template of a course.
+	UpdatePolicyTemplates struct {
+		Policies []PolicyTemplate `json:"policies"`
+	}
+
+	// ResearchConsent whether a course may be part of research exports.
+	ResearchConsent struct {
+		Consent *bool `json:"consent" binding:"required"`
+	}
+
+	CreateWebhook struct {
+		URL    string   `json:"url" binding:"required"`
+		Events []string `json:"events" binding:"required"`
+	}
+
+	// FeatureFlag turns a flag on or off, null removes the override.
+	FeatureFlag struct {
+		Enabled *bool `json:"enabled"`
+	}
+
+	OrganizationBranding struct {
+		DisplayName  string `json:"displayName"`
+		LogoURL      string `json:"logoURL"`
+		PrimaryColor string `json:"primaryColor"`
+	}
+
+	OrganizationAuth struct {
+		EmailDomains      []string `json:"emailDomains"`
+		AllowRegistration bool     `json:"allowRegistration"`
+	}
+
+	CreateOrganization struct {
+		Name     string                `json:"name" binding:"required"`
+		Slug     string                `json:"slug" binding:"required"`
+		Branding *OrganizationBranding `json:"branding"`
+		Auth     *OrganizationAuth     `json:"auth"`
+	}
+
+	// UpdateOrganization replaces whichever of the name, branding and auth
+	// settings are given.
+	UpdateOrganization struct {
+		Name     *string               `json:"name"`
+		Branding *OrganizationBranding `json:"branding"`
+		Auth     *OrganizationAuth     `json:"auth"`
+	}
+
+	CreateInviteCode struct {
+		Role      string              `json:"role" binding:"required"`
+		ExpiresAt *primitive.DateTime `json:"expiresAt"`
+	}
+
+	CreatePost struct {
+		Body      string `json:"body" binding:"required"`
+		Anonymous bool   `json:"anonymous"`
+	}
+
+	CreateThread struct {
+		Title     string `json:"title" binding:"required"`
+		Body      string `json:"body" binding:"required"`
+		Anonymous bool   `json:"anonymous"`
 	}
 
 	CreateCourse struct {
-		Department string `json:"department" binding:"required"`
-		Number     int    `json:"number" binding:"required"`
-		Section    string `json:"section" binding:"required"`
-		Semester   string `json:"semester" binding:"required"`
+		Department    string `json:"department" binding:"required"`
+		Number        int    `json:"number" binding:"required"`
+		Section       string `json:"section" binding:"required"`
+		Semester      string `json:"semester" binding:"required"`
+		MaxEnrollment int    `json:"maxEnrollment"`
+	}
+
+	Accommodation struct {
+		ExtraMinutes *int `json:"extraMinutes" binding:"required"`
+	}
+
+	GradeCutoff struct {
+		Letter string  `json:"letter" binding:"required"`
+		Min    float64 `json:"min"`
+	}
+
+	CurvePoint struct {
+		Percentile float64 `json:"percentile"`
+		Score      float64 `json:"score"`
+	}
+
+	GradeCurve struct {
+		Type   string       `json:"type" binding:"required"`
+		Shift  float64      `json:"shift"`
+		Points []CurvePoint `json:"points"`
+	}
+
+	// UpdateGradeScale sets a course's letter grade cutoffs and curve, or
+	// with Preview only shows the gradebook they would produce.
+	UpdateGradeScale struct {
+		Cutoffs []GradeCutoff `json:"cutoffs"`
+		Curve   *GradeCurve   `json:"curve"`
+		Preview bool          `json:"preview"`
+	}
+
+	// CreateAttendanceSession Minutes is how long students can check in,
+	// zero until the session is closed. RotateSeconds changes the code that
+	// often, zero for a fixed code.
+	CreateAttendanceSession struct {
+		Title         string `json:"title" binding:"required"`
+		Minutes       int    `json:"minutes"`
+		RotateSeconds int    `json:"rotateSeconds"`
+	}
+
+	CheckIn struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	OfficeHour struct {
+		Host     string `json:"host"`
+		Weekday  int    `json:"weekday"`
+		Start    string `json:"start" binding:"required"`
+		End      string `json:"end" binding:"required"`
+		Timezone string `json:"timezone" binding:"required"`
+		Location string `json:"location"`
+	}
+
+	// UpdateOfficeHours replaces every office hour of a course.
+	UpdateOfficeHours struct {
+		OfficeHours []OfficeHour `json:"officeHours"`
+	}
+
+	CourseSection struct {
+		Name       string               `json:"name" binding:"required"`
+		Students   []primitive.ObjectID `json:"students"`
+		Assistants []primitive.ObjectID `json:"assistants"`
+	}
+
+	// UpdateSections replaces every section of a course. With ScopedGrading
+	// assistants only grade the students of their sections.
+	UpdateSections struct {
+		Sections      []CourseSection `json:"sections"`
+		ScopedGrading bool            `json:"scopedGrading"`
+	}
+
+	// CreateShareLink how many hours a submission's share link lasts.
+	CreateShareLink struct {
+		Hours int `json:"hours" binding:"required"`
+	}
+
+	// CourseQuota the limits of a course, zero is unlimited. Default returns
+	// the course to the deployment's quota.
+	CourseQuota struct {
+		Default        bool `json:"default"`
+		StorageMB      int  `json:"storageMB"`
+		GradingMinutes int  `json:"gradingMinutes"`
+		Hard           bool `json:"hard"`
+	}
+
+	// SetArchived archives a course, freezing it read only, or restores it.
+	SetArchived struct {
+		Archived bool `json:"archived"`
+	}
+
+	// DistributeGrading splits the submissions of an assignment nobody grades
+	// yet among the graders.
+	DistributeGrading struct {
+		Graders []primitive.ObjectID `json:"graders" binding:"required"`
+	}
+
+	// CompleteGrading marks a submission graded, overriding its grade when one
+	// is given.
+	CompleteGrading struct {
+		Grade  *float64 `json:"grade"`
+		Reason string   `json:"reason"`
+	}
+
+	// BulkAssignments applies one operation to many assignments of a course:
+	// shiftDueDates by Days, publish, unpublish or setLimits, which sets
+	// NumAttempts and TimeLimit when given.
+	BulkAssignments struct {
+		AssignmentIDs []primitive.ObjectID `json:"assignmentIDs" binding:"required"`
+		Operation     string               `json:"operation" binding:"required"`
+		Days          int                  `json:"days"`
+		NumAttempts   *int                 `json:"numAttempts"`
+		TimeLimit     *int                 `json:"timeLimit"`
+	}
+
+	// AssignmentMilestone a part of an assignment with its own due date,
+	// graded on the named tests. DueDate is in milliseconds.
+	AssignmentMilestone struct {
+		Name    string             `json:"name"`
+		DueDate primitive.DateTime `json:"dueDate"`
+		Tests   []string           `json:"tests"`
+		Weight  float64            `json:"weight"`
+	}
+
+	// ArtifactSlot a file uploaded with each submission besides the code.
+	ArtifactSlot struct {
+		Name       string   `json:"name"`
+		Required   bool     `json:"required"`
+		Extensions []string `json:"extensions"`
+	}
+
+	// RubricCriterion something a manually graded submission is graded on,
+	// worth up to Points. Criteria kept from a previous rubric send their ID.
+	RubricCriterion struct {
+		ID          primitive.ObjectID `json:"id"`
+		Name        string             `json:"name"`
+		Description string             `json:"description"`
+		Points      float64            `json:"points"`
+	}
+
+	// CriterionScore the points a submission earned on a rubric criterion.
+	CriterionScore struct {
+		CriterionID primitive.ObjectID `json:"criterionID" binding:"required"`
+		Points      float64            `json:"points"`
+		Comment     string             `json:"comment"`
+	}
+
+	// Annotation a comment on a spot of a submitted document: its page and
+	// where on it, as fractions of its width and height from the top left.
+	Annotation struct {
+		Page int     `json:"page"`
+		X    float64 `json:"x"`
+		Y    float64 `json:"y"`
+		Text string  `json:"text" binding:"required"`
+	}
+
+	// ReviewSubmission grades a manually graded submission on the rubric.
+	ReviewSubmission struct {
+		Scores      []CriterionScore `json:"scores" binding:"required"`
+		Annotations []Annotation     `json:"annotations"`
+		Comment     string           `json:"comment"`
+	}
+
+	// AssignmentPrerequisite an assignment to score at least MinScore on,
+	// out of 100, before another unlocks.
+	AssignmentPrerequisite struct {
+		AssignmentID primitive.ObjectID `json:"assignmentID" binding:"required"`
+		MinScore     float64            `json:"minScore"`
+	}
+
+	// UpdatePrerequisites replaces an assignment's prerequisites, none
+	// unlocks it for everyone.
+	UpdatePrerequisites struct {
+		Prerequisites []AssignmentPrerequisite `json:"prerequisites"`
+	}
+
+	// Question a question of a course's quiz question bank. Kind decides
+	// how it is answered: multipleChoice by the indices of Choices in
+	// Correct, shortAnswer by any of Answers and numeric by Value, give or
+	// take Tolerance.
+	Question struct {
+		Kind      string   `json:"kind" binding:"required"`
+		Prompt    string   `json:"prompt" binding:"required"`
+		Choices   []string `json:"choices"`
+		Correct   []int    `json:"correct"`
+		Answers   []string `json:"answers"`
+		Value     *float64 `json:"value"`
+		Tolerance float64  `json:"tolerance"`
+		Points    float64  `json:"points"`
+		Tags      []string `json:"tags"`
+	}
+
+	// QuizPool draws Count questions tagged Tag from the question bank,
+	// from all of it when Tag is empty.
+	QuizPool struct {
+		Tag   string `json:"tag"`
+		Count int    `json:"count"`
+	}
+
+	// UpdateQuiz makes an assignment a quiz of Questions, asked in every
+	// attempt, and questions drawn from the Pools.
+	UpdateQuiz struct {
+		Questions        []primitive.ObjectID `json:"questions"`
+		Pools            []QuizPool           `json:"pools"`
+		ShuffleQuestions bool                 `json:"shuffleQuestions"`
+		ShuffleChoices   bool                 `json:"shuffleChoices"`
+	}
+
+	// QuizResponse a student's answer to a question of their attempt:
+	// Choices, the indices of the choices as they were shown, or Text.
+	QuizResponse struct {
+		QuestionID primitive.ObjectID `json:"questionID" binding:"required"`
+		Choices    []int              `json:"choices"`
+		Text       string             `json:"text"`
+	}
+
+	// SaveQuizResponses the answers of a quiz attempt changed since it was
+	// last saved.
+	SaveQuizResponses struct {
+		Responses []QuizResponse `json:"responses"`
+	}
+
+	// HeraldCapacity what court herald reports about its load.
+	HeraldCapacity struct {
+		Running           int     `json:"running"`
+		Capacity          int     `json:"capacity" binding:"required"`
+		Queued            int     `json:"queued"`
+		AverageJobSeconds float64 `json:"averageJobSeconds"`
+	}
+
+	// Grader registers a court herald cluster or changes it, only the fields
+	// given are set. Labels, Languages and Departments are comma separated.
+	Grader struct {
+		Name        *string `json:"name"`
+		URL         *string `json:"url"`
+		Labels      *string `json:"labels"`
+		Languages   *string `json:"languages"`
+		Departments *string `json:"departments"`
+		Priority    *int    `json:"priority"`
+		Enabled     *bool   `json:"enabled"`
+	}
+
+	CourseRetention struct {
+		EndDate            primitive.DateTime `json:"endDate" binding:"required"`
+		SubmissionFileDays *int               `json:"submissionFileDays"`
+		DiscussionDays     *int               `json:"discussionDays"`
 	}
 
 	// Course Aggregaton struct ot store information about a course.
@@ -70,9 +499,44 @@ type (
 		Role       string             `json:"role" binding:"required"`
 	}
 
+	LeaderboardOptOut struct {
+		OptOut bool `json:"optOut"`
+	}
+
+	GrantBonus struct {
+		Points float64 `json:"points" binding:"required"`
+		Reason string  `json:"reason"`
+	}
+
+	OpenDispute struct {
+		Message string `json:"message" binding:"required"`
+	}
+
+	DisputeMessage struct {
+		Body string `json:"body" binding:"required"`
+	}
+
+	// UpdateDisputeStatus moves a dispute along. Adjusting a dispute needs the
+	// grade to override the submission's with.
+	UpdateDisputeStatus struct {
+		Status  string   `json:"status" binding:"required"`
+		Grade   *float64 `json:"grade"`
+		Message string   `json:"message"`
+	}
+
+	LinkRepository struct {
+		Repository        string `json:"repository" binding:"required"`
+		Branch            string `json:"branch"`
+		StatusToken       string `json:"statusToken"`
+		AcceptAttestation bool   `json:"acceptAttestation"`
+	}
+
 	sub struct {
-		Time    primitive.DateTime `form:"submissionTime"`
-		Attempt int                `bson:"attemptNumber"`
+		Time          primitive.DateTime `form:"submissionTime"`
+		Attempt       int                `bson:"attemptNumber"`
+		GradeOverride *struct {
+			Grade float64 `bson:"grade"`
+		} `bson:"gradeOverride"`
 	}
 
 	student struct {
@@ -83,7 +547,64 @@ type (
 	}
 
 	GradeAgg struct {
-		Students []student `bson:"students"`
+		Students  []student `bson:"students"`
+		Withdrawn []student `bson:"withdrawn"`
+	}
+
+	SubmitRepository struct {
+		Repository        string `json:"repository" binding:"required"`
+		Commit            string `json:"commit" binding:"required"`
+		AcceptAttestation bool   `json:"acceptAttestation"`
+	}
+
+	SubmitEditor struct {
+		Files             map[string]string `json:"files" binding:"required"`
+		AcceptAttestation bool              `json:"acceptAttestation"`
+	}
+
+	SaveDraft struct {
+		Files  map[string]string `json:"files" binding:"required"`
+		Source string            `json:"source"`
+	}
+
+	RequestExtension struct {
+		Reason  string             `json:"reason" binding:"required"`
+		DueDate primitive.DateTime `json:"dueDate" binding:"required"`
+	}
+
+	// DecideExtension approves or denies an extension request. Approving it
+	// grants the due date requested unless another is given.
+	DecideExtension struct {
+		Status  string              `json:"status" binding:"required"`
+		DueDate *primitive.DateTime `json:"dueDate"`
+		Message string              `json:"message"`
+	}
+
+	// CreateModule a week or topic of a course, with the assignments and
+	// announcements it groups in order.
+	CreateModule struct {
+		Title         string               `json:"title" binding:"required"`
+		Description   string               `json:"description"`
+		StartDate     *primitive.DateTime  `json:"startDate"`
+		Published     bool                 `json:"published"`
+		Assignments   []primitive.ObjectID `json:"assignments"`
+		Announcements []primitive.ObjectID `json:"announcements"`
+	}
+
+	// UpdateModule replaces whichever of a module's settings are given, the
+	// lists of assignments and announcements as a whole.
+	UpdateModule struct {
+		Title         *string              `json:"title"`
+		Description   *string              `json:"description"`
+		StartDate     *primitive.DateTime  `json:"startDate"`
+		Published     *bool                `json:"published"`
+		Assignments   []primitive.ObjectID `json:"assignments"`
+		Announcements []primitive.ObjectID `json:"announcements"`
+	}
+
+	// ReorderModules every module of a course in its new order.
+	ReorderModules struct {
+		Modules []primitive.ObjectID `json:"modules" binding:"required"`
 	}
 
 	UpdateAssignment struct {
@@ -96,13 +617,50 @@ type (
 		TestBuildCMD *string             `form:"testBuildCMD"`
 		Tests        []string            `form:"tests"`
 		NumAttempts  *int                `form:"numAttempts"`
+		TimeLimit    *int                `form:"timeLimit"`
+		Attestation  *string             `form:"attestation"`
+
+		LeaderboardMetric        *string `form:"leaderboardMetric"`
+		LeaderboardLowerIsBetter *bool   `form:"leaderboardLowerIsBetter"`
+
+		PrecheckFiles     *string `form:"precheckFiles"`
+		PrecheckExclusive *bool   `form:"precheckExclusive"`
+		PrecheckBuild     *bool   `form:"precheckBuild"`
+		ExtraCredit       *bool   `form:"extraCredit"`
+		Requirements      *string `form:"requirements"`
+		ShowHiddenSummary *bool   `form:"showHiddenSummary"`
+		// Milestones a JSON list of AssignmentMilestone replacing the
+		// assignment's, [] for none.
+		Milestones *string `form:"milestones"`
+		// ArtifactSlots a JSON list of ArtifactSlot replacing the
+		// assignment's, [] for only the code.
+		ArtifactSlots *string `form:"artifactSlots"`
+		// Rubric a JSON list of RubricCriterion replacing the assignment's.
+		Rubric *string `form:"rubric"`
+
+		CooldownMinutes *int `form:"cooldownMinutes"`
+		// LatePolicy a JSON LatePolicy replacing the assignment's, "" for
+		// none.
+		LatePolicy *string `form:"latePolicy"`
+	}
+
+	UpdateAnnouncement struct {
+		Title       *string             `json:"title"`
+		Body        *string             `json:"body"`
+		Pinned      *bool               `json:"pinned"`
+		PublishDate *primitive.DateTime `json:"publishDate"`
 	}
 
 	UpdateCourse struct {
-		Department *string `json:"department"`
-		LongName   *string `json:"longName"`
-		Number     *int    `json:"number"`
-		Section    *string `json:"section"`
-		Semester   *string `json:"semester"`
+		Department    *string `json:"department"`
+		LongName      *string `json:"longName"`
+		Number        *int    `json:"number"`
+		Section       *string `json:"section"`
+		Semester      *string `json:"semester"`
+		MaxEnrollment *int    `json:"maxEnrollment"`
+	}
+
+	WaitlistAdmit struct {
+		Count int `json:"count"`
 	}
 )
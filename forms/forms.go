@@ -6,21 +6,87 @@ import (
 )
 
 type (
-	AssignmentAggQuery cmsf.AssignmentAgg
+	AccommodationForm cmsf.Accommodation
+
+	AssignmentAggQuery      cmsf.AssignmentAgg
+	AssignmentStatsAggQuery cmsf.AssignmentStatsAgg
+	AssignmentMilestoneForm cmsf.AssignmentMilestone
+	ArtifactSlotForm        cmsf.ArtifactSlot
+	RubricCriterionForm     cmsf.RubricCriterion
+	ReviewSubmissionForm    cmsf.ReviewSubmission
+	CriterionScoreForm      cmsf.CriterionScore
+
+	CheckInForm cmsf.CheckIn
 
 	CourseAggQuery        cmsf.CourseAgg
 	CourseAddUserForm     cmsf.CourseAddUser
 	CourseBulkAddUserForm cmsf.CourseBulkAddUser
+	CourseRetentionForm   cmsf.CourseRetention
 
+	CreateAPITokenForm       cmsf.CreateAPIToken
+	CreateAnnouncementForm   cmsf.CreateAnnouncement
+	CreateAttendanceForm     cmsf.CreateAttendanceSession
 	CreateAssignmentPreForm  cmsf.CreateAssignmentPreParse
 	CreateAssignmentPostForm cmsf.CreateAssignmentPostParse
 	CreateCourseForm         cmsf.CreateCourse
+	CreateInviteCodeForm     cmsf.CreateInviteCode
+	CreateModuleForm         cmsf.CreateModule
+	CreateOrganizationForm   cmsf.CreateOrganization
+	CreatePostForm           cmsf.CreatePost
+	CreateThreadForm         cmsf.CreateThread
+	CreateWebhookForm        cmsf.CreateWebhook
+
+	DecideExtensionForm cmsf.DecideExtension
+	DisputeMessageForm  cmsf.DisputeMessage
+
+	FeatureFlagForm cmsf.FeatureFlag
+
+	GradeAggQuery  cmsf.GradeAgg
+	GraderForm     cmsf.Grader
+	GrantBonusForm cmsf.GrantBonus
+
+	LeaderboardOptOutForm cmsf.LeaderboardOptOut
+	LinkRepositoryForm    cmsf.LinkRepository
 
-	GradeAggQuery cmsf.GradeAgg
+	OpenDisputeForm cmsf.OpenDispute
 
+	RequestExtensionForm cmsf.RequestExtension
+	ReorderModulesForm   cmsf.ReorderModules
+
+	SubmitRepositoryForm cmsf.SubmitRepository
+	SubmitEditorForm     cmsf.SubmitEditor
+	SaveDraftForm        cmsf.SaveDraft
+
+	UserDigestForm   uf.DigestForm
 	UserLoginForm    uf.LoginForm
 	UserRegisterForm uf.RegisterForm
+	UserTimezoneForm uf.TimezoneForm
+
+	UpdateAnnouncementForm  cmsf.UpdateAnnouncement
+	UpdateAssignmentForm    cmsf.UpdateAssignment
+	UpdateCourseForm        cmsf.UpdateCourse
+	UpdateDisputeStatusForm cmsf.UpdateDisputeStatus
+	UpdateGradeScaleForm    cmsf.UpdateGradeScale
+	UpdateOfficeHoursForm   cmsf.UpdateOfficeHours
+	UpdateSectionsForm      cmsf.UpdateSections
+	UpdateModuleForm        cmsf.UpdateModule
+	CreateShareLinkForm     cmsf.CreateShareLink
+	CourseQuotaForm         cmsf.CourseQuota
+	SetArchivedForm         cmsf.SetArchived
+	DistributeGradingForm   cmsf.DistributeGrading
+	CompleteGradingForm     cmsf.CompleteGrading
+	BulkAssignmentsForm     cmsf.BulkAssignments
+	UpdateOrganizationForm  cmsf.UpdateOrganization
+	HeraldCapacityForm      cmsf.HeraldCapacity
+	UpdatePrerequisitesForm cmsf.UpdatePrerequisites
+
+	UpdatePolicyTemplatesForm cmsf.UpdatePolicyTemplates
+
+	ResearchConsentForm cmsf.ResearchConsent
+
+	QuestionForm          cmsf.Question
+	UpdateQuizForm        cmsf.UpdateQuiz
+	SaveQuizResponsesForm cmsf.SaveQuizResponses
 
-	UpdateAssignmentForm cmsf.UpdateAssignment
-	UpdateCourseForm     cmsf.UpdateCourse
+	WaitlistAdmitForm cmsf.WaitlistAdmit
 )
@@ -13,4 +13,18 @@ type RegisterForm struct {
 	PasswordConfirmation string `bson:"passwordConfirmation" json:"passwordConfirmation" binding:"required"`
 	First                string `bson:"firstName" json:"firstName" binding:"required"`
 	Last                 string `bson:"lastName" json:"lastName" binding:"required"`
-}
\ No newline at end of file
+	Timezone             string `bson:"timezone" json:"timezone"`
+	// Organization the slug of the organization to join, found from the
+	// email's domain when empty.
+	Organization string `bson:"organization" json:"organization"`
+}
+
+// DigestForm struct a form to change how often a Tyr User is emailed a digest.
+type DigestForm struct {
+	Frequency string `json:"frequency" binding:"required"`
+}
+
+// TimezoneForm struct a form to change a Tyr User's timezone.
+type TimezoneForm struct {
+	Timezone string `json:"timezone" binding:"required"`
+}
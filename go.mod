@@ -10,9 +10,12 @@ require (
 	github.com/h2non/filetype v1.0.8
 	github.com/kr/pty v1.1.3 // indirect
 	github.com/mattn/go-colorable v0.1.0 // indirect
+	github.com/microcosm-cc/bluemonday v1.0.2
 	github.com/mongodb/mongo-go-driver v0.3.0
 	github.com/onsi/ginkgo v1.7.0 // indirect
 	github.com/onsi/gomega v1.4.3 // indirect
+	github.com/russross/blackfriday/v2 v2.0.1
+	github.com/shurcooL/sanitized_anchor_name v1.0.0 // indirect
 	github.com/stevens-tyr/tyr-gin v0.0.0-20190425213457-b732fe2f3bd4
 	github.com/tidwall/gjson v1.2.1 // indirect
 	github.com/ugorji/go/codec v0.0.0-20190204201341-e444a5086c43 // indirect
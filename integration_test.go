@@ -0,0 +1,427 @@
+//go:build integration
+// +build integration
+
+package main
+
+// The integration tests run the API against a real MongoDB, started in
+// docker, and a fake court herald, through the flows a course goes through:
+// registering, creating a course and an assignment, submitting, court herald
+// reporting back and the gradebook. Run them with
+//
+//	go test -tags integration -run Integration .
+//
+// Settings are read, and collections opened, when the packages initialize,
+// so TestMain starts mongo and runs the test binary again with the settings
+// pointing at it. Without docker the tests are skipped.
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mongodb/mongo-go-driver/bson"
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/api"
+	"backend/config"
+	"backend/models"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/seed"
+	"backend/testutil"
+)
+
+const (
+	integrationImage  = "mongo:4.0"
+	integrationSecret = "integration-job-secret"
+	integrationPass   = "integration123"
+)
+
+var (
+	integrationServer *gin.Engine
+	herald            = testutil.NewFakeGrader()
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv("INTEGRATION_CHILD") != "" {
+		os.Exit(runIntegration(m))
+	}
+
+	os.Exit(startIntegration())
+}
+
+func docker(args ...string) (string, error) {
+	out, err := exec.Command("docker", args...).CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
+
+// startIntegration starts mongo in docker and runs the tests again against it.
+func startIntegration() int {
+	if _, err := docker("version"); err != nil {
+		fmt.Println("skipping integration tests, docker is not available:", err)
+		return 0
+	}
+
+	container, err := docker("run", "-d", "--rm", "-p", "127.0.0.1::27017", integrationImage)
+	if err != nil {
+		fmt.Println("failed to start mongo:", container)
+		return 1
+	}
+	defer docker("rm", "-f", container)
+
+	port, err := docker("port", container, "27017")
+	if err != nil {
+		fmt.Println("failed to find mongo's port:", port)
+		return 1
+	}
+
+	ready := false
+	for deadline := time.Now().Add(30 * time.Second); time.Now().Before(deadline); time.Sleep(500 * time.Millisecond) {
+		if _, err = docker("exec", container, "mongo", "--quiet", "--eval", "db.runCommand({ping: 1})"); err == nil {
+			ready = true
+			break
+		}
+	}
+	if !ready {
+		fmt.Println("mongo did not start")
+		return 1
+	}
+
+	// the fake court herald listens in the child, on a port free now
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	heraldAddr := listener.Addr().String()
+	listener.Close()
+
+	child := exec.Command(os.Args[0], os.Args[1:]...)
+	child.Stdout, child.Stderr = os.Stdout, os.Stderr
+	child.Env = append(os.Environ(),
+		"INTEGRATION_CHILD=1",
+		"ENV=test",
+		"MONGO_URI=mongodb://"+port,
+		"DB_NAME=tyr_integration",
+		"GRIDFS_DB_NAME=tyr_integration_files",
+		"COURT_HERALD_URL=http://"+heraldAddr,
+		"JOB_SECRET="+integrationSecret,
+		"SHARE_LINK_SECRET=integration-share-link-secret",
+		"PSEUDONYM_SECRET=integration-pseudonym-secret",
+		"JWT_SECRET=integration-jwt-secret",
+		"GRADER_RETRIES=0",
+	)
+	if err = child.Run(); err != nil {
+		if exit, ok := err.(*exec.ExitError); ok {
+			return exit.ExitCode()
+		}
+		fmt.Println(err)
+		return 1
+	}
+
+	return 0
+}
+
+// runIntegration serves the fake court herald and runs the tests.
+func runIntegration(m *testing.M) int {
+	heraldURL, _ := url.Parse(os.Getenv("COURT_HERALD_URL"))
+	listener, err := net.Listen("tcp", heraldURL.Host)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	go http.Serve(listener, herald)
+
+	if err = migrateOnStartup(); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	gin.SetMode(gin.TestMode)
+	integrationServer = api.SetUp()
+
+	return m.Run()
+}
+
+// call makes a request to the API and decodes its JSON answer into out, nil
+// to ignore it.
+func call(t *testing.T, method, path, token, contentType string, body []byte, out interface{}) int {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp := httptest.NewRecorder()
+	integrationServer.ServeHTTP(resp, req)
+
+	if out != nil {
+		if err := json.Unmarshal(resp.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s answered %d with %q: %s", method, path, resp.Code, resp.Body.String(), err)
+		}
+	}
+
+	return resp.Code
+}
+
+func callJSON(t *testing.T, method, path, token string, in, out interface{}) int {
+	t.Helper()
+
+	body, _ := json.Marshal(in)
+	return call(t, method, path, token, "application/json", body, out)
+}
+
+func login(t *testing.T, email string) string {
+	t.Helper()
+
+	var resp struct {
+		Token string `json:"token"`
+	}
+	status := callJSON(t, "POST", "/api/v1/auth/login", "", gin.H{"email": email, "password": integrationPass}, &resp)
+	if status != 200 || resp.Token == "" {
+		t.Fatalf("login of %s answered %d", email, status)
+	}
+
+	return resp.Token
+}
+
+// tarball a tar.gz archive of the files.
+func tarball(files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+
+	return buf.Bytes()
+}
+
+// multipartBody a multipart form of the fields and files.
+func multipartBody(fields map[string][]string, files map[string][]byte) ([]byte, string) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for name, values := range fields {
+		for _, value := range values {
+			writer.WriteField(name, value)
+		}
+	}
+	for name, content := range files {
+		part, _ := writer.CreateFormFile(name, name+".tar.gz")
+		part.Write(content)
+	}
+	writer.Close()
+
+	return buf.Bytes(), writer.FormDataContentType()
+}
+
+func TestIntegrationGradingFlow(t *testing.T) {
+	profEmail, studentEmail := "prof@integration.test", "student@integration.test"
+
+	if code := createAdmin([]string{"-email", profEmail, "-password", integrationPass}); code != 0 {
+		t.Fatalf("create-admin exited with %d", code)
+	}
+
+	status := callJSON(t, "POST", "/api/v1/auth/register", "", gin.H{
+		"email":                studentEmail,
+		"password":             integrationPass,
+		"passwordConfirmation": integrationPass,
+		"firstName":            "Sam",
+		"lastName":             "Student",
+	}, nil)
+	if status != 200 {
+		t.Fatalf("register answered %d", status)
+	}
+
+	// the course
+	var created struct {
+		Token string `json:"token"`
+	}
+	status = callJSON(t, "POST", "/api/v1/plague_doctor/create/course", login(t, profEmail), gin.H{
+		"department": "CS",
+		"number":     115,
+		"section":    "A",
+		"semester":   "F19",
+	}, &created)
+	if status != 200 {
+		t.Fatalf("create course answered %d", status)
+	}
+	profToken := created.Token
+
+	course, err := models.NewMongoCourseInterface().FindOne(nil, "CS", "A", "F19", 115)
+	if err != nil {
+		t.Fatalf("course was not stored: %s", err)
+	}
+	coursePath := "/api/v1/plague_doctor/course/" + course.ID.Hex()
+
+	status = callJSON(t, "POST", coursePath+"/add/user", profToken, gin.H{"level": "student", "email": studentEmail}, nil)
+	if status != 200 {
+		t.Fatalf("add student answered %d", status)
+	}
+
+	// the assignment, with a hidden test
+	body, contentType := multipartBody(map[string][]string{
+		"language":    {"python"},
+		"name":        {"Hello"},
+		"numAttempts": {"3"},
+		"description": {"Say hello."},
+		"dueDate":     {strconv.FormatInt(time.Now().Add(24*time.Hour).UnixNano()/1000000, 10)},
+		"tests": {
+			`{"name": "greets", "testCMD": "python hello.py", "expectedOutput": "hello", "studentFacing": true}`,
+			`{"name": "hidden", "testCMD": "python hello.py --loud", "expectedOutput": "HELLO", "studentFacing": false}`,
+		},
+	}, map[string][]byte{"supportingFiles": tarball(map[string]string{"README": "tests"})})
+	status = call(t, "POST", coursePath+"/assignment/create", profToken, contentType, body, nil)
+	if status != 200 {
+		t.Fatalf("create assignment answered %d", status)
+	}
+
+	course, _ = models.NewMongoCourseInterface().GetByID(course.ID)
+	if len(course.Assignments) != 1 {
+		t.Fatalf("course has %d assignments, not 1", len(course.Assignments))
+	}
+	assignPath := coursePath + "/assignment/" + course.Assignments[0].Hex()
+
+	status = call(t, "PATCH", assignPath+"/update", profToken, "application/x-www-form-urlencoded", []byte("published=true"), nil)
+	if status != 200 {
+		t.Fatalf("publish assignment answered %d", status)
+	}
+
+	// the student submits and court herald is asked to grade it, failing
+	// the hidden test
+	herald.Then(testutil.OutcomeHiddenFail)
+	studentToken := login(t, studentEmail)
+	body, contentType = multipartBody(nil, map[string][]byte{"submission": tarball(map[string]string{"hello.py": "print('hello')"})})
+	var receipt struct {
+		SubmissionID string `json:"submissionID"`
+		Status       string `json:"status"`
+	}
+	status = call(t, "POST", coursePath+"/assignment/submit/"+course.Assignments[0].Hex(), studentToken, contentType, body, &receipt)
+	if status != 201 || receipt.Status != "grading" {
+		t.Fatalf("submit answered %d with status %q", status, receipt.Status)
+	}
+
+	job, found := herald.Job(receipt.SubmissionID)
+	if !found {
+		t.Fatalf("court herald was not sent submission %s", receipt.SubmissionID)
+	}
+	if len(job.Tests) != 2 || job.Language != "python" {
+		t.Fatalf("court herald was sent %d tests in %q, not the assignment's", len(job.Tests), job.Language)
+	}
+
+	// court herald reports the hidden test failed
+	status = call(t, "PATCH", "/api/v1/plague_doctor/job/"+integrationSecret+"/submission/"+receipt.SubmissionID+"/update", "", "application/json", herald.Report(receipt.SubmissionID), nil)
+	if status != 200 {
+		t.Fatalf("grade callback answered %d", status)
+	}
+
+	// the student sees their grade and only the tests meant for them
+	var gradebook struct {
+		Students []struct {
+			Scores []struct {
+				Score float64 `json:"score"`
+			} `json:"scores"`
+		} `json:"students"`
+	}
+	status = callJSON(t, "GET", coursePath+"/gradebook", studentToken, nil, &gradebook)
+	if status != 200 || len(gradebook.Students) != 1 || len(gradebook.Students[0].Scores) != 1 {
+		t.Fatalf("gradebook answered %d with %d rows", status, len(gradebook.Students))
+	}
+	if score := gradebook.Students[0].Scores[0].Score; score != 50 {
+		t.Errorf("gradebook score is %v, not 50", score)
+	}
+
+	var details struct {
+		Assignment struct {
+			Tests []struct {
+				Name string `json:"name"`
+			} `json:"tests"`
+		} `json:"assignment"`
+	}
+	status = callJSON(t, "GET", assignPath+"/details", studentToken, nil, &details)
+	if status != 200 {
+		t.Fatalf("assignment details answered %d", status)
+	}
+	for _, test := range details.Assignment.Tests {
+		if test.Name == "hidden" {
+			t.Errorf("the hidden test was shown to the student")
+		}
+	}
+
+	// nor on the course dashboard, which has every assignment at once
+	var dashboard struct {
+		Course struct {
+			Assignments []struct {
+				Tests []struct {
+					Name string `json:"name"`
+				} `json:"tests"`
+			} `json:"assignments"`
+		} `json:"course"`
+	}
+	status = callJSON(t, "GET", coursePath, studentToken, nil, &dashboard)
+	if status != 200 || len(dashboard.Course.Assignments) != 1 {
+		t.Fatalf("course answered %d with %d assignments", status, len(dashboard.Course.Assignments))
+	}
+	if tests := dashboard.Course.Assignments[0].Tests; len(tests) != 1 || tests[0].Name != "greets" {
+		t.Errorf("the course dashboard showed the student the tests %+v", tests)
+	}
+}
+
+// BenchmarkIntegrationStaffAssignment loads an assignment of a 500 student
+// course as staff do. It reseeds the database, so run it on its own with
+//
+//	go test -tags integration -run '^$' -bench StaffAssignment .
+func BenchmarkIntegrationStaffAssignment(b *testing.B) {
+	_, err := seed.Run(seed.Options{Courses: 1, StudentsPerClass: 500, Assignments: 1, Reset: true, RandomSeed: 1})
+	if err != nil {
+		b.Fatalf("seeding failed: %s", err)
+	}
+
+	db, err := tyrgin.GetMongoDB(config.C.DBName)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var assign struct {
+		ID interface{} `bson:"_id"`
+	}
+	if err = db.Collection("assignments").FindOne(context.Background(), bson.M{}).Decode(&assign); err != nil {
+		b.Fatalf("no assignment was seeded: %s", err)
+	}
+
+	am := models.NewMongoAssignmentInterface()
+	for name, query := range map[string]assignmentmodels.StaffSubmissionsQuery{
+		"all":          {},
+		"page":         {Limit: 50},
+		"byScorePage":  {Sort: assignmentmodels.SortByScore, Descending: true, Limit: 50},
+		"ungradedPage": {Status: assignmentmodels.SubmissionsUngraded, Limit: 50},
+	} {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := am.GetFullForStaff(assign.ID, query); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
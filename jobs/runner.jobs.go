@@ -0,0 +1,292 @@
+// Package jobs runs background work from the jobs collection. Work is handed
+// to a pool of workers which claim one job at a time, so several instances of
+// the backend can share the queue, and failed jobs are retried with a backoff.
+// Jobs can be enqueued to run once or scheduled to run on an interval, the
+// recurring jobs are scheduled by the one instance elected to lead.
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/config"
+	"backend/models"
+	"backend/models/jobmodels"
+	"backend/utils"
+)
+
+const (
+	// defaultMaxAttempts attempts a job gets unless it registers otherwise.
+	defaultMaxAttempts = 3
+	// lease how long a claimed job stays locked without its worker renewing it.
+	lease = 5 * time.Minute
+	// maxBackoff the longest a failed job waits before its next attempt.
+	maxBackoff = time.Hour
+	// SchedulerLease the lease of the instance scheduling recurring jobs.
+	SchedulerLease = "jobs.scheduler"
+	// leaderTTL how long the scheduler lease lasts unless its holder renews
+	// it, how long recurring jobs can go unscheduled when the leader dies.
+	leaderTTL = time.Minute
+)
+
+type (
+	// Handler does a job's work with the payload it was enqueued with.
+	Handler func(payload []byte) error
+
+	registration struct {
+		handler     Handler
+		maxAttempts int
+	}
+
+	// Schedule a job that runs every interval.
+	Schedule struct {
+		Name     string        `json:"name"`
+		Interval time.Duration `json:"interval"`
+	}
+)
+
+var (
+	jm = models.NewMongoJobInterface()
+	lm = models.NewMongoLeaseInterface()
+
+	lock      sync.RWMutex
+	handlers  = make(map[string]registration)
+	schedules = make([]Schedule, 0)
+)
+
+// Register sets the handler of the jobs of a name and how many times they are
+// attempted before they are marked failed, 0 for the default.
+func Register(name string, maxAttempts int, handler Handler) {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	handlers[name] = registration{handler, maxAttempts}
+}
+
+// Every runs the jobs of a name every interval once the workers start.
+func Every(name string, interval time.Duration) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	schedules = append(schedules, Schedule{name, interval})
+}
+
+// Schedules lists the recurring jobs.
+func Schedules() []Schedule {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	return append([]Schedule{}, schedules...)
+}
+
+func registered(name string) (registration, bool) {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	reg, found := handlers[name]
+	return reg, found
+}
+
+func names() []string {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	registeredNames := make([]string, 0, len(handlers))
+	for name := range handlers {
+		registeredNames = append(registeredNames, name)
+	}
+
+	return registeredNames
+}
+
+// EnqueueAt adds a job to run once runAt has passed, payload is stored as JSON.
+func EnqueueAt(name string, payload interface{}, runAt time.Time) (*jobmodels.MongoJob, error) {
+	reg, found := registered(name)
+	if !found {
+		return nil, fmt.Errorf("no handler registered for job %s", name)
+	}
+
+	bs, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	job, errs := jm.Enqueue(name, string(bs), runAt, reg.maxAttempts, 0)
+	if errs != nil {
+		return nil, errs
+	}
+
+	return job, nil
+}
+
+// Enqueue adds a job to run as soon as a worker is free.
+func Enqueue(name string, payload interface{}) (*jobmodels.MongoJob, error) {
+	return EnqueueAt(name, payload, time.Now())
+}
+
+// backoff how long to wait before the next attempt after a number of failed
+// ones, doubling from thirty seconds.
+func backoff(attempts int) time.Duration {
+	wait := 30 * time.Second
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+
+	if wait > maxBackoff {
+		return maxBackoff
+	}
+
+	return wait
+}
+
+// call runs a handler, turning a panic into an error.
+func call(handler Handler, payload []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return handler(payload)
+}
+
+// run does a claimed job, renewing its lock while it runs, and records the
+// outcome.
+func run(worker string, job *jobmodels.MongoJob) {
+	reg, _ := registered(job.Name)
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(lease / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				jm.ExtendLock(job.ID, worker, time.Now().Add(lease))
+			}
+		}
+	}()
+
+	err := call(reg.handler, []byte(job.Payload))
+	close(done)
+
+	now := time.Now()
+	var errs error
+	switch {
+	case err == nil:
+		errs = jm.Succeed(job.ID, now)
+	case job.Attempts < job.MaxAttempts:
+		errs = jm.Retry(job.ID, now.Add(backoff(job.Attempts)), err.Error())
+	default:
+		tyrgin.ErrorLogger(err, fmt.Sprintf("Job %s %s failed after %d attempts.", job.Name, job.ID.Hex(), job.Attempts))
+		errs = jm.Fail(job.ID, now, err.Error())
+	}
+	if errs != nil {
+		tyrgin.ErrorLogger(errs, "Failed to record the outcome of job "+job.ID.Hex())
+		return
+	}
+
+	if job.Recurring() && (err == nil || job.Attempts >= job.MaxAttempts) {
+		interval := time.Duration(job.Interval) * time.Second
+		if _, errs = jm.Enqueue(job.Name, job.Payload, now.Add(interval), job.MaxAttempts, interval); errs != nil {
+			tyrgin.ErrorLogger(errs, "Failed to schedule the next run of job "+job.Name)
+		}
+	}
+}
+
+func work(worker string) {
+	for {
+		now := time.Now()
+		job, err := jm.Claim(worker, names(), now, now.Add(lease))
+		if err != nil {
+			tyrgin.ErrorLogger(err, "Failed to claim a job.")
+		}
+		if job == nil {
+			time.Sleep(config.C.Jobs.PollInterval)
+			continue
+		}
+
+		run(worker, job)
+	}
+}
+
+// purgeHistory removes finished jobs past the history retention.
+func purgeHistory([]byte) error {
+	if err := jm.DeleteFinishedBefore(time.Now().Add(-config.C.Jobs.HistoryRetention)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Instance names this process in the leases it holds and the jobs it claims.
+func Instance() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+func schedule() {
+	for _, schedule := range Schedules() {
+		reg, found := registered(schedule.Name)
+		if !found {
+			tyrgin.ErrorLogger(fmt.Errorf("no handler registered for job %s", schedule.Name), "Failed to schedule a job.")
+			continue
+		}
+
+		if err := jm.EnsureScheduled(schedule.Name, schedule.Interval, reg.maxAttempts); err != nil {
+			tyrgin.ErrorLogger(err, "Failed to schedule job "+schedule.Name)
+		}
+	}
+}
+
+// lead schedules the recurring jobs whenever this instance holds the
+// scheduler lease. Only the leader schedules, as instances scheduling a job
+// at once could each enqueue a run of it and send its notifications twice.
+func lead() {
+	for {
+		held, err := lm.Acquire(SchedulerLease, Instance(), leaderTTL)
+		if err != nil {
+			tyrgin.ErrorLogger(err, "Failed to renew the scheduler lease.")
+		}
+		if held {
+			schedule()
+		}
+
+		time.Sleep(leaderTTL / 3)
+	}
+}
+
+// Leader the instance scheduling recurring jobs, empty when none is.
+func Leader() string {
+	lease, err := lm.Get(SchedulerLease)
+	if err != nil || time.Now().After(utils.DateTimeToTime(lease.ExpiresAt)) {
+		return ""
+	}
+
+	return lease.Holder
+}
+
+// Start starts electing the instance that schedules the recurring jobs and
+// the workers.
+func Start() {
+	Register("jobs.purgeHistory", 1, purgeHistory)
+	Every("jobs.purgeHistory", 24*time.Hour)
+
+	go lead()
+
+	for i := 0; i < config.C.Jobs.Workers; i++ {
+		go work(fmt.Sprintf("%s-%d", Instance(), i))
+	}
+}
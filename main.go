@@ -1,10 +1,49 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
 	"backend/api"
+	"backend/api/cms"
+	"backend/config"
+	"backend/devgrader"
+	"backend/events"
+	"backend/jobs"
 )
 
 func main() {
+	if err := config.Check(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if code, ran := runCommand(os.Args[1:]); ran {
+		os.Exit(code)
+	}
+
+	if err := migrateOnStartup(); err != nil {
+		tyrgin.ErrorLogger(err, "Failed to apply database migrations.")
+		os.Exit(1)
+	}
+
+	if config.C.DevGrader.Enabled {
+		if err := devgrader.Start(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	events.Configure()
+	cms.SubscribeEvents()
+	events.StreamChanges()
+
 	server := api.SetUp()
+
+	cms.RegisterJobs()
+	jobs.Start()
+
 	server.Run(":5555")
 }
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/errors"
+	"backend/models"
+)
+
+var cm = models.NewMongoCourseInterface()
+
+// archivedWrites the routes that may still be posted to for an archived
+// course: restoring it, exporting it and viewing it as a student.
+var archivedWrites = map[string]bool{
+	"course/:cid/archived":          true,
+	"course/:cid/archive/create":    true,
+	"course/:cid/impersonate/:suid": true,
+}
+
+// ReadOnlyArchives refuses every request that would change an archived course,
+// any but GET, HEAD and OPTIONS to a route naming the course, so submissions,
+// edits and enrollments stop without each handler checking. Everything stays
+// readable to those enrolled.
+func ReadOnlyArchives() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cid, found := c.Get("cid")
+		method := c.Request.Method
+		if !found || method == "GET" || method == "HEAD" || method == "OPTIONS" {
+			c.Next()
+			return
+		}
+
+		route := strings.TrimPrefix(c.Request.URL.Path, "/api/v1/plague_doctor/")
+		for _, p := range c.Params {
+			route = strings.Replace(route, p.Value, ":"+p.Key, 1)
+		}
+		if archivedWrites[route] {
+			c.Next()
+			return
+		}
+
+		archived, err := cm.IsArchived(cid)
+		if err == nil && archived {
+			c.Set("error", errors.ErrorCourseArchived)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
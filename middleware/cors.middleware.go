@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/config"
+)
+
+const (
+	corsMethods = "GET, POST, PUT, PATCH, DELETE"
+	corsHeaders = "Authorization, Content-Type"
+	// corsExposed the response headers a browser lets the frontend read.
+	corsExposed = "Content-Disposition, Retry-After"
+)
+
+// originAllowed reports whether origin is one of the allowed origins or
+// matches a "https://*.domain" wildcard.
+func originAllowed(origin string, allowed []string) bool {
+	for _, candidate := range allowed {
+		if candidate == "*" || strings.EqualFold(candidate, origin) {
+			return true
+		}
+
+		if i := strings.Index(candidate, "*."); i >= 0 {
+			scheme, domain := candidate[:i], candidate[i+1:]
+			if strings.HasPrefix(origin, scheme) && strings.HasSuffix(strings.ToLower(origin), strings.ToLower(domain)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// CORS lets the origins in CORS_ALLOWED_ORIGINS call the API from a browser,
+// answering preflight requests itself. Requests from other origins get no
+// CORS headers, so browsers refuse to hand their responses over, and their
+// preflights are refused.
+func CORS() gin.HandlerFunc {
+	settings := config.C.CORS
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		c.Writer.Header().Add("Vary", "Origin")
+		if !originAllowed(origin, settings.AllowedOrigins) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Origin", origin)
+		if settings.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			c.Header("Access-Control-Expose-Headers", corsExposed)
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Methods", corsMethods)
+		c.Header("Access-Control-Allow-Headers", corsHeaders)
+		c.Header("Access-Control-Max-Age", strconv.Itoa(int(settings.MaxAge.Seconds())))
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
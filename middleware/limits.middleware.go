@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/config"
+	"backend/errors"
+)
+
+// compressMinSize the smallest JSON response worth compressing.
+const compressMinSize = 1 << 10
+
+// bodyLimit the largest body a request can send. File uploads, and the grade
+// reports court herald sends with the tests' output, get the larger limit.
+func bodyLimit(c *gin.Context) int64 {
+	if strings.HasPrefix(c.ContentType(), "multipart/") || strings.HasPrefix(c.Request.URL.Path, "/api/v1/plague_doctor/job/") {
+		return config.C.MaxUploadBody
+	}
+
+	return config.C.MaxJSONBody
+}
+
+// BodyLimits refuses requests whose body is larger than MAX_JSON_BODY_KB, or
+// MAX_UPLOAD_BODY_MB for file uploads. A body without a length is cut off at
+// the limit, failing to bind.
+func BodyLimits() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := bodyLimit(c)
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatusJSON(
+				errors.ErrorRequestTooLarge.StatusCode(),
+				gin.H{
+					"error": errors.ErrorRequestTooLarge.Error(),
+				},
+			)
+			return
+		}
+
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		}
+
+		c.Next()
+	}
+}
+
+// gzipWriter holds back a JSON response until it is large enough to be worth
+// compressing, then compresses the rest of it. Other responses, such as file
+// downloads, are written as they are.
+type gzipWriter struct {
+	gin.ResponseWriter
+	buf         bytes.Buffer
+	gz          *gzip.Writer
+	passthrough bool
+}
+
+func (w *gzipWriter) Write(data []byte) (int, error) {
+	switch {
+	case w.gz != nil:
+		return w.gz.Write(data)
+	case w.passthrough:
+		return w.ResponseWriter.Write(data)
+	case !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json"):
+		w.passthrough = true
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.buf.Write(data)
+	if w.buf.Len() < compressMinSize {
+		return len(data), nil
+	}
+
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Del("Content-Length")
+	w.gz = gzip.NewWriter(w.ResponseWriter)
+	if _, err := w.gz.Write(w.buf.Bytes()); err != nil {
+		return 0, err
+	}
+	w.buf.Reset()
+
+	return len(data), nil
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends what is held back uncompressed, a flushed response is streamed.
+func (w *gzipWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	} else {
+		w.finish()
+		w.passthrough = true
+	}
+
+	w.ResponseWriter.Flush()
+}
+
+func (w *gzipWriter) finish() {
+	if w.gz != nil {
+		w.gz.Close()
+		return
+	}
+
+	if w.buf.Len() > 0 {
+		w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+// Compress gzips JSON responses of at least a kilobyte for clients that accept
+// it, such as the course dashboards with every student's submissions.
+func Compress() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		writer := &gzipWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		defer writer.finish()
+
+		c.Next()
+	}
+}
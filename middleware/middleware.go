@@ -9,24 +9,17 @@ import (
 	"github.com/stevens-tyr/tyr-gin"
 )
 
+// objectIDParams the route parameters that are parsed into ObjectIDs.
+var objectIDParams = []string{"aid", "anid", "atid", "bid", "cid", "did", "erid", "fid", "grid", "jid", "lid", "mid", "nid", "oid", "pid", "qaid", "qid", "rgid", "shid", "sid", "suid", "tid", "tkid", "whid"}
+
 func ObjectIDs() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if c.Param("aid") != "" {
-			val, err := primitive.ObjectIDFromHex(c.Param("aid"))
-			if err != nil {
-				c.AbortWithStatusJSON(
-					errors.ErrorInvalidObjectID.StatusCode(),
-					gin.H{
-						"error": errors.ErrorInvalidObjectID.Error(),
-					},
-				)
+		for _, param := range objectIDParams {
+			if c.Param(param) == "" {
+				continue
 			}
 
-			c.Set("aid", val)
-		}
-
-		if c.Param("cid") != "" {
-			val, err := primitive.ObjectIDFromHex(c.Param("cid"))
+			val, err := primitive.ObjectIDFromHex(c.Param(param))
 			if err != nil {
 				c.AbortWithStatusJSON(
 					errors.ErrorInvalidObjectID.StatusCode(),
@@ -34,24 +27,13 @@ func ObjectIDs() gin.HandlerFunc {
 						"error": errors.ErrorInvalidObjectID.Error(),
 					},
 				)
+				return
 			}
 
-			c.Set("cids", val.Hex())
-			c.Set("cid", val)
-		}
-
-		if c.Param("sid") != "" {
-			val, err := primitive.ObjectIDFromHex(c.Param("sid"))
-			if err != nil {
-				c.AbortWithStatusJSON(
-					errors.ErrorInvalidObjectID.StatusCode(),
-					gin.H{
-						"error": errors.ErrorInvalidObjectID.Error(),
-					},
-				)
+			if param == "cid" {
+				c.Set("cids", val.Hex())
 			}
-
-			c.Set("sid", val)
+			c.Set(param, val)
 		}
 
 		c.Next()
@@ -68,9 +50,16 @@ func ErrorHandler() gin.HandlerFunc {
 
 		apierr := val.(errors.APIError)
 		if apierr.GetError() != nil {
-			tyrgin.ErrorHandler(apierr.GetError(), c, apierr.StatusCode(), gin.H{
+			response := gin.H{
 				"error": apierr.Error(),
-			})
+			}
+			// Handlers can explain an error further, e.g. which files a
+			// submission is missing.
+			if details, ok := c.Get("errorDetails"); ok {
+				response["details"] = details
+			}
+
+			tyrgin.ErrorHandler(apierr.GetError(), c, apierr.StatusCode(), response)
 		}
 	}
 }
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/config"
+	"backend/jobs"
+	"backend/migrations"
+	"backend/models"
+	"backend/models/leasemodels"
+)
+
+const migrateUsage = "usage: plague_doctor migrate [up | down [steps] | status]"
+
+const (
+	// migrationsLease the lease of the instance migrating the database.
+	migrationsLease = "migrations"
+	// migrationsTTL how long an instance that died migrating holds up the
+	// others.
+	migrationsTTL = 10 * time.Minute
+)
+
+// awaitMigrationsLease waits until this instance is the one migrating, so
+// replicas starting together do not apply the same migration twice.
+func awaitMigrationsLease(lm *leasemodels.LeaseInterface) error {
+	for {
+		held, err := lm.Acquire(migrationsLease, jobs.Instance(), migrationsTTL)
+		if err != nil {
+			return err
+		}
+		if held {
+			return nil
+		}
+
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// migrateOnStartup applies pending migrations, unless MIGRATE_ON_STARTUP is
+// false, and creates missing indexes before the server starts, one instance
+// at a time.
+func migrateOnStartup() error {
+	migrator, err := migrations.New()
+	if err != nil {
+		return err
+	}
+
+	lm := models.NewMongoLeaseInterface()
+	if err = awaitMigrationsLease(lm); err != nil {
+		return err
+	}
+	defer lm.Release(migrationsLease, jobs.Instance())
+
+	if config.C.MigrateOnStartup {
+		ran, err := migrator.Up()
+		for _, migration := range ran {
+			tyrgin.NormalLog(fmt.Sprintf("Applied migration %d %s.", migration.Version, migration.Name))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	created, err := migrator.EnsureIndexes()
+	for _, index := range created {
+		tyrgin.NormalLog(fmt.Sprintf("Created index %s on %s.", index.Name, index.Collection))
+	}
+
+	return err
+}
+
+// migrate runs the migrate subcommand and returns the exit code.
+func migrate(args []string) int {
+	migrator, err := migrations.New()
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		ran, err := migrator.Up()
+		for _, migration := range ran {
+			fmt.Printf("applied %d %s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				fmt.Println(migrateUsage)
+				return 2
+			}
+		}
+
+		reverted, err := migrator.Down(steps)
+		for _, migration := range reverted {
+			fmt.Printf("reverted %d %s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%4d %-8s %s\n", status.Version, state, status.Name)
+		}
+	default:
+		fmt.Println(migrateUsage)
+		return 2
+	}
+
+	return 0
+}
@@ -0,0 +1,113 @@
+package migrations
+
+import (
+	"github.com/mongodb/mongo-go-driver/bson"
+)
+
+// Index an index the application's queries rely on.
+type Index struct {
+	Collection string
+	Name       string
+	Keys       interface{}
+	Unique     bool
+}
+
+// requiredIndexes are ensured on every startup so an index dropped by hand, or
+// missing from a fresh database, does not leave queries scanning whole
+// collections. Indexes a migration creates are left to it, so reverting the
+// migration drops them for good.
+var requiredIndexes = []Index{
+	{"users", "organizationID_1", bson.M{"organizationID": 1}, false},
+	{"courses", "organizationID_1", bson.M{"organizationID": 1}, false},
+	{"organizations", "slug_1", bson.M{"slug": 1}, true},
+	{"organizations", "auth.emailDomains_1", bson.M{"auth.emailDomains": 1}, false},
+	{"courses", "assignments_1", bson.M{"assignments": 1}, false},
+	{"courses", "pendingEnrollments.email_1", bson.M{"pendingEnrollments.email": 1}, false},
+	{
+		"submissions",
+		"assignmentID_1_userID_1_attemptNumber_1",
+		bson.D{{"assignmentID", 1}, {"userID", 1}, {"attemptNumber", 1}},
+		false,
+	},
+	{"submissions", "userID_1_submissionDate_-1", bson.D{{"userID", 1}, {"submissionDate", -1}}, false},
+	{"submissions", "shareLinks._id_1", bson.M{"shareLinks._id": 1}, false},
+	{"submissions", "submissionDate_1", bson.M{"submissionDate": 1}, false},
+	{"submissions", "queued_1_submissionDate_1", bson.D{{"queued", 1}, {"submissionDate", 1}}, false},
+	{"disputes", "courseID_1_updatedAt_-1", bson.D{{"courseID", 1}, {"updatedAt", -1}}, false},
+	{"disputes", "submissionID_1", bson.M{"submissionID": 1}, false},
+	{"attendance", "courseID_1_createdAt_1", bson.D{{"courseID", 1}, {"createdAt", 1}}, false},
+	{"webhooks", "courseID_1_events_1", bson.D{{"courseID", 1}, {"events", 1}}, false},
+	{"webhooks", "deliveries.status_1_deliveries.nextAttemptAt_1", bson.D{{"deliveries.status", 1}, {"deliveries.nextAttemptAt", 1}}, false},
+	{"graders", "name_1", bson.M{"name": 1}, true},
+	{"assignments", "repositoryLinks._id_1", bson.M{"repositoryLinks._id": 1}, false},
+	{"jobs", "status_1_runAt_1", bson.D{{"status", 1}, {"runAt", 1}}, false},
+	{"jobs", "name_1_createdAt_-1", bson.D{{"name", 1}, {"createdAt", -1}}, false},
+	{"assignments", "name_text_description_text", bson.D{{"name", "text"}, {"description", "text"}}, false},
+	{"announcements", "title_text_body_text", bson.D{{"title", "text"}, {"body", "text"}}, false},
+	{"users", "firstName_text_lastName_text_email_text", bson.D{{"firstName", "text"}, {"lastName", "text"}, {"email", "text"}}, false},
+	{"gradingtasks", "assignmentID_1_submissionID_1", bson.D{{"assignmentID", 1}, {"submissionID", 1}}, false},
+	{"impersonations", "courseID_1_at_-1", bson.D{{"courseID", 1}, {"at", -1}}, false},
+	{"submissioncode", "assignmentID_1", bson.M{"assignmentID": 1}, false},
+	{"submissioncode", "userID_1", bson.M{"userID": 1}, false},
+	{"drafts", "assignmentID_1_userID_1", bson.D{{"assignmentID", 1}, {"userID", 1}}, true},
+	{"drafts", "expiresAt_1", bson.M{"expiresAt": 1}, false},
+	{"drafts", "courseID_1", bson.M{"courseID": 1}, false},
+	{"extensionrequests", "courseID_1_createdAt_1", bson.D{{"courseID", 1}, {"createdAt", 1}}, false},
+	{"extensionrequests", "assignmentID_1_userID_1_status_1", bson.D{{"assignmentID", 1}, {"userID", 1}, {"status", 1}}, false},
+	{"extensionrequests", "userID_1", bson.M{"userID": 1}, false},
+	{"modules", "courseID_1_position_1", bson.D{{"courseID", 1}, {"position", 1}}, false},
+	{"modules", "assignments_1", bson.M{"assignments": 1}, false},
+	{"modules", "announcements_1", bson.M{"announcements": 1}, false},
+	{"regrades", "assignmentID_1_startedAt_-1", bson.D{{"assignmentID", 1}, {"startedAt", -1}}, false},
+	{"tokens", "hash_1", bson.M{"hash": 1}, true},
+	{"tokens", "userID_1_createdAt_-1", bson.D{{"userID", 1}, {"createdAt", -1}}, false},
+}
+
+func (m *Migrator) existingIndexes(collection string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	cur, err := m.db.Collection(collection).Indexes().List(m.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for cur.Next(m.ctx) {
+		var index struct {
+			Name string `bson:"name"`
+		}
+		if err = cur.Decode(&index); err != nil {
+			return nil, err
+		}
+		existing[index.Name] = true
+	}
+
+	return existing, nil
+}
+
+// EnsureIndexes creates every required index that does not exist yet and
+// returns the ones it created.
+func (m *Migrator) EnsureIndexes() ([]Index, error) {
+	created := make([]Index, 0)
+	existing := make(map[string]map[string]bool)
+
+	for _, index := range requiredIndexes {
+		if _, found := existing[index.Collection]; !found {
+			names, err := m.existingIndexes(index.Collection)
+			if err != nil {
+				return created, err
+			}
+			existing[index.Collection] = names
+		}
+
+		if existing[index.Collection][index.Name] {
+			continue
+		}
+
+		err := createIndex(index.Collection, index.Name, index.Keys, index.Unique)(m.ctx, m.db)
+		if err != nil {
+			return created, err
+		}
+		created = append(created, index)
+	}
+
+	return created, nil
+}
@@ -0,0 +1,177 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/config"
+)
+
+type (
+	// Migration a versioned change to the database schema. Up applies it and Down
+	// reverts it. Versions must be unique and are applied in ascending order.
+	Migration struct {
+		Version int
+		Name    string
+		Up      func(ctx context.Context, db *mongo.Database) error
+		Down    func(ctx context.Context, db *mongo.Database) error
+	}
+
+	// AppliedMigration is recorded in the migrations collection once a migration
+	// has been applied.
+	AppliedMigration struct {
+		Version   int                `bson:"_id" json:"version"`
+		Name      string             `bson:"name" json:"name"`
+		AppliedAt primitive.DateTime `bson:"appliedAt" json:"appliedAt"`
+	}
+
+	// MigrationStatus a migration and whether it has been applied.
+	MigrationStatus struct {
+		Version int
+		Name    string
+		Applied bool
+	}
+
+	// Migrator runs migrations against a database.
+	Migrator struct {
+		ctx        context.Context
+		db         *mongo.Database
+		col        *mongo.Collection
+		migrations []Migration
+	}
+)
+
+// New returns a Migrator for the application database with every registered
+// migration.
+func New() (*Migrator, error) {
+	db, err := tyrgin.GetMongoDB(config.C.DBName)
+	if err != nil {
+		return nil, err
+	}
+
+	ms := make([]Migration, len(registry))
+	copy(ms, registry)
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Version < ms[j].Version })
+
+	for i := 1; i < len(ms); i++ {
+		if ms[i].Version == ms[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", ms[i].Version)
+		}
+	}
+
+	return &Migrator{
+		context.Background(),
+		db,
+		tyrgin.GetMongoCollection("migrations", db),
+		ms,
+	}, nil
+}
+
+func (m *Migrator) applied() (map[int]bool, error) {
+	applied := make(map[int]bool)
+	cur, err := m.col.Find(m.ctx, bson.M{}, options.Find())
+	if err != nil {
+		return nil, err
+	}
+
+	for cur.Next(m.ctx) {
+		var migration AppliedMigration
+		if err = cur.Decode(&migration); err != nil {
+			return nil, err
+		}
+		applied[migration.Version] = true
+	}
+
+	return applied, nil
+}
+
+// Status lists every migration and whether it has been applied.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		statuses = append(statuses, MigrationStatus{migration.Version, migration.Name, applied[migration.Version]})
+	}
+
+	return statuses, nil
+}
+
+// Up applies every pending migration in order and returns the ones it applied.
+// It stops at the first failure, leaving later migrations pending.
+func (m *Migrator) Up() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	ran := make([]Migration, 0)
+	for _, migration := range m.migrations {
+		if applied[migration.Version] {
+			continue
+		}
+
+		if err = migration.Up(m.ctx, m.db); err != nil {
+			return ran, fmt.Errorf("migration %d %s failed: %v", migration.Version, migration.Name, err)
+		}
+
+		_, err = m.col.InsertOne(m.ctx, AppliedMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			AppliedAt: primitive.DateTime(time.Now().UnixNano() / 1000000),
+		})
+		if err != nil {
+			return ran, fmt.Errorf("migration %d %s could not be recorded: %v", migration.Version, migration.Name, err)
+		}
+
+		ran = append(ran, migration)
+	}
+
+	return ran, nil
+}
+
+// Down reverts the most recently applied migrations, up to steps of them, and
+// returns the ones it reverted.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	reverted := make([]Migration, 0)
+	for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration := m.migrations[i]
+		if !applied[migration.Version] {
+			continue
+		}
+
+		if migration.Down == nil {
+			return reverted, fmt.Errorf("migration %d %s cannot be reverted", migration.Version, migration.Name)
+		}
+
+		if err = migration.Down(m.ctx, m.db); err != nil {
+			return reverted, fmt.Errorf("reverting migration %d %s failed: %v", migration.Version, migration.Name, err)
+		}
+
+		_, err = m.col.DeleteOne(m.ctx, bson.M{"_id": migration.Version})
+		if err != nil {
+			return reverted, fmt.Errorf("migration %d %s could not be unrecorded: %v", migration.Version, migration.Name, err)
+		}
+
+		reverted = append(reverted, migration)
+	}
+
+	return reverted, nil
+}
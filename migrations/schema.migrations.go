@@ -0,0 +1,301 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+
+	"backend/utils"
+)
+
+// registry every schema change, new migrations are appended with the next version.
+var registry = []Migration{
+	{
+		Version: 1,
+		Name:    "backfill user fields",
+		Up: backfill("users", bson.M{
+			"timezone": "",
+			"deleted":  false,
+		}),
+		Down: unset("users", "timezone", "deleted"),
+	},
+	{
+		Version: 2,
+		Name:    "backfill course fields",
+		Up: backfill("courses", bson.M{
+			"withdrawn":             bson.A{},
+			"inviteCodes":           bson.A{},
+			"maxEnrollment":         0,
+			"waitlist":              bson.A{},
+			"archives":              bson.A{},
+			"submissionFilesPurged": false,
+			"discussionsPurged":     false,
+		}),
+		Down: unset(
+			"courses",
+			"withdrawn", "inviteCodes", "maxEnrollment", "waitlist",
+			"archives", "submissionFilesPurged", "discussionsPurged",
+		),
+	},
+	{
+		Version: 3,
+		Name:    "backfill submission fields",
+		Up: backfill("submissions", bson.M{
+			"withdrawn":  false,
+			"filePurged": false,
+		}),
+		Down: unset("submissions", "withdrawn", "filePurged"),
+	},
+	{
+		Version: 4,
+		Name:    "backfill assignment attachments",
+		Up:      backfill("assignments", bson.M{"attachments": bson.A{}}),
+		Down:    unset("assignments", "attachments"),
+	},
+	{
+		// unique once emails are normalized, by version 23, so emails that
+		// only differ in case never fail it
+		Version: 5,
+		Name:    "index users by email",
+		Up:      createIndex("users", "email_1", bson.M{"email": 1}, false),
+		Down:    dropIndex("users", "email_1"),
+	},
+	{
+		Version: 6,
+		Name:    "index notifications by user",
+		Up:      createIndex("notifications", "userID_1_createdAt_-1", bson.D{{"userID", 1}, {"createdAt", -1}}, false),
+		Down:    dropIndex("notifications", "userID_1_createdAt_-1"),
+	},
+	{
+		Version: 7,
+		Name:    "index threads by assignment",
+		Up:      createIndex("threads", "assignmentID_1", bson.M{"assignmentID": 1}, false),
+		Down:    dropIndex("threads", "assignmentID_1"),
+	},
+	{
+		Version: 8,
+		Name:    "index submissions by user and assignment",
+		Up:      createIndex("submissions", "userID_1_assignmentID_1", bson.D{{"userID", 1}, {"assignmentID", 1}}, false),
+		Down:    dropIndex("submissions", "userID_1_assignmentID_1"),
+	},
+	{
+		Version: 9,
+		Name:    "backfill timed assignment fields",
+		Up: backfill("assignments", bson.M{
+			"timeLimit":      0,
+			"starts":         bson.A{},
+			"accommodations": bson.A{},
+		}),
+		Down: unset("assignments", "timeLimit", "starts", "accommodations"),
+	},
+	{
+		Version: 10,
+		Name:    "backfill assignment attestation",
+		Up:      backfill("assignments", bson.M{"attestation": ""}),
+		Down:    unset("assignments", "attestation"),
+	},
+	{
+		Version: 11,
+		Name:    "backfill assignment repository links",
+		Up:      backfill("assignments", bson.M{"repositoryLinks": bson.A{}}),
+		Down:    unset("assignments", "repositoryLinks"),
+	},
+	{
+		Version: 12,
+		Name:    "backfill assignment fixtures",
+		Up:      backfill("assignments", bson.M{"fixtures": bson.A{}}),
+		Down:    unset("assignments", "fixtures"),
+	},
+	{
+		Version: 13,
+		Name:    "backfill assignment extra credit",
+		Up:      backfill("assignments", bson.M{"extraCredit": false}),
+		Down:    unset("assignments", "extraCredit"),
+	},
+	{
+		Version: 14,
+		Name:    "backfill course bonuses",
+		Up:      backfill("courses", bson.M{"bonuses": bson.A{}}),
+		Down:    unset("courses", "bonuses"),
+	},
+	{
+		Version: 15,
+		Name:    "backfill course office hours",
+		Up:      backfill("courses", bson.M{"officeHours": bson.A{}}),
+		Down:    unset("courses", "officeHours"),
+	},
+	{
+		Version: 16,
+		Name:    "backfill user digest",
+		Up:      backfill("users", bson.M{"digest": "weekly"}),
+		Down:    unset("users", "digest", "digestToken", "lastDigestAt"),
+	},
+	{
+		Version: 17,
+		Name:    "normalize user emails",
+		Up:      normalizeEmails,
+	},
+	{
+		Version: 18,
+		Name:    "backfill course pending enrollments",
+		Up:      backfill("courses", bson.M{"pendingEnrollments": bson.A{}}),
+		Down:    unset("courses", "pendingEnrollments"),
+	},
+	{
+		Version: 19,
+		Name:    "backfill course sections",
+		Up: backfill("courses", bson.M{
+			"sections":             bson.A{},
+			"sectionScopedGrading": false,
+		}),
+		Down: unset("courses", "sections", "sectionScopedGrading"),
+	},
+	{
+		Version: 20,
+		Name:    "backfill course archived flag",
+		Up:      backfill("courses", bson.M{"archived": false}),
+		Down:    unset("courses", "archived", "archivedAt"),
+	},
+	{
+		Version: 21,
+		Name:    "backfill course quota usage",
+		Up: backfill("courses", bson.M{
+			"usage": bson.M{"storageBytes": 0, "gradingSeconds": 0},
+		}),
+		Down: unset("courses", "usage", "quota"),
+	},
+	{
+		Version: 22,
+		Name:    "backfill assignment hidden test summaries",
+		Up:      backfill("assignments", bson.M{"showHiddenSummary": false}),
+		Down:    unset("assignments", "showHiddenSummary"),
+	},
+	{
+		Version: 23,
+		Name:    "index users by email uniquely",
+		Up:      replaceIndex("users", "email_1", bson.M{"email": 1}, true),
+		Down:    replaceIndex("users", "email_1", bson.M{"email": 1}, false),
+	},
+}
+
+// backfill sets each field to its default on documents that predate it.
+func backfill(collection string, defaults bson.M) func(context.Context, *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		col := db.Collection(collection)
+		for field, value := range defaults {
+			_, err := col.UpdateMany(
+				ctx,
+				bson.M{field: bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{field: value}},
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// normalizeEmails stores every user's email in its normalized form. Accounts
+// whose emails only differ in case, or in a +tag when those are stripped, are
+// left as they are and printed, so an admin can decide which one to keep.
+func normalizeEmails(ctx context.Context, db *mongo.Database) error {
+	col := db.Collection("users")
+	cur, err := col.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"email": 1}))
+	if err != nil {
+		return err
+	}
+
+	type account struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Email string             `bson:"email"`
+	}
+
+	accounts := make(map[string][]account)
+	for cur.Next(ctx) {
+		var user account
+		if err = cur.Decode(&user); err != nil {
+			return err
+		}
+
+		normalized := utils.NormalizeEmail(user.Email)
+		accounts[normalized] = append(accounts[normalized], user)
+	}
+
+	for normalized, users := range accounts {
+		if len(users) > 1 {
+			emails := make([]string, 0, len(users))
+			for _, user := range users {
+				emails = append(emails, user.Email)
+			}
+			tyrgin.ErrorLogger(
+				fmt.Errorf("%d accounts normalize to %s", len(users), normalized),
+				"Not normalizing "+strings.Join(emails, ", ")+", they can still log in as they are.",
+			)
+			continue
+		}
+
+		if users[0].Email == normalized {
+			continue
+		}
+
+		_, err = col.UpdateOne(ctx, bson.M{"_id": users[0].ID}, bson.M{"$set": bson.M{"email": normalized}})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unset removes fields from every document of a collection.
+func unset(collection string, fields ...string) func(context.Context, *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		unsetFields := bson.M{}
+		for _, field := range fields {
+			unsetFields[field] = ""
+		}
+
+		_, err := db.Collection(collection).UpdateMany(ctx, bson.M{}, bson.M{"$unset": unsetFields})
+		return err
+	}
+}
+
+func createIndex(collection, name string, keys interface{}, unique bool) func(context.Context, *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection(collection).Indexes().CreateOne(
+			ctx,
+			mongo.IndexModel{
+				Keys:    keys,
+				Options: options.Index().SetName(name).SetUnique(unique).SetBackground(true),
+			},
+		)
+		return err
+	}
+}
+
+func dropIndex(collection, name string) func(context.Context, *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection(collection).Indexes().DropOne(ctx, name)
+		return err
+	}
+}
+
+// replaceIndex drops an index and creates it again, as whether an index is
+// unique cannot be changed in place.
+func replaceIndex(collection, name string, keys interface{}, unique bool) func(context.Context, *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		if err := dropIndex(collection, name)(ctx, db); err != nil {
+			return err
+		}
+
+		return createIndex(collection, name, keys, unique)(ctx, db)
+	}
+}
@@ -0,0 +1,256 @@
+package announcementmodels
+
+import (
+	"context"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	"backend/config"
+	"backend/errors"
+	"backend/forms"
+	"backend/utils"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+type (
+	// MongoAnnouncement struct to store a course announcement. An announcement
+	// becomes visible to students once its publish date has passed.
+	MongoAnnouncement struct {
+		ID           primitive.ObjectID `bson:"_id" json:"id" binding:"required"`
+		CourseID     primitive.ObjectID `bson:"courseID" json:"courseID" binding:"required"`
+		AuthorID     primitive.ObjectID `bson:"authorID" json:"authorID" binding:"required"`
+		Title        string             `bson:"title" json:"title" binding:"required"`
+		Body         string             `bson:"body" json:"body" binding:"required"`
+		RenderedBody string             `bson:"-" json:"renderedBody"`
+		Pinned       bool               `bson:"pinned" json:"pinned"`
+		PublishDate  primitive.DateTime `bson:"publishDate" json:"publishDate" binding:"required"`
+		Notified     bool               `bson:"notified" json:"-"`
+		CreatedAt    primitive.DateTime `bson:"createdAt" json:"createdAt" binding:"required"`
+	}
+
+	AnnouncementInterface struct {
+		ctx context.Context
+		col *mongo.Collection
+	}
+)
+
+func New() *AnnouncementInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+	col := tyrgin.GetMongoCollection("announcements", db)
+
+	return &AnnouncementInterface{
+		context.Background(),
+		col,
+	}
+}
+
+func now() primitive.DateTime {
+	return primitive.DateTime(time.Now().UnixNano() / 1000000)
+}
+
+// Published reports whether the announcement is visible to students.
+func (m *MongoAnnouncement) Published() bool {
+	return m.PublishDate <= now()
+}
+
+func (a *AnnouncementInterface) find(filter interface{}, limit int64) ([]MongoAnnouncement, errors.APIError) {
+	opts := options.Find().SetSort(bson.D{{"pinned", -1}, {"publishDate", -1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	announcements := make([]MongoAnnouncement, 0)
+	cur, err := a.col.Find(a.ctx, filter, opts)
+	if err != nil {
+		return announcements, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(a.ctx) {
+		var announcement MongoAnnouncement
+		err = cur.Decode(&announcement)
+		if err != nil {
+			return announcements, errors.ErrorInvalidBSON
+		}
+
+		announcement.RenderedBody = utils.RenderMarkdown(announcement.Body)
+		announcements = append(announcements, announcement)
+	}
+
+	return announcements, nil
+}
+
+func (a *AnnouncementInterface) Create(cid, uid interface{}, form forms.CreateAnnouncementForm) (*MongoAnnouncement, errors.APIError) {
+	created := now()
+	publishDate := created
+	if form.PublishDate != nil {
+		publishDate = *form.PublishDate
+	}
+
+	announcement := MongoAnnouncement{
+		ID:          primitive.NewObjectID(),
+		CourseID:    cid.(primitive.ObjectID),
+		AuthorID:    uid.(primitive.ObjectID),
+		Title:       form.Title,
+		Body:        form.Body,
+		Pinned:      form.Pinned,
+		PublishDate: publishDate,
+		Notified:    false,
+		CreatedAt:   created,
+	}
+
+	_, err := a.col.InsertOne(a.ctx, &announcement, options.InsertOne())
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedCreate
+	}
+
+	announcement.RenderedBody = utils.RenderMarkdown(announcement.Body)
+	return &announcement, nil
+}
+
+func (a *AnnouncementInterface) Get(anid, cid interface{}) (*MongoAnnouncement, errors.APIError) {
+	var announcement *MongoAnnouncement
+	res := a.col.FindOne(a.ctx, bson.M{"_id": anid, "courseID": cid}, options.FindOne())
+	res.Decode(&announcement)
+
+	if announcement == nil {
+		return nil, errors.ErrorResourceNotFound
+	}
+
+	announcement.RenderedBody = utils.RenderMarkdown(announcement.Body)
+	return announcement, nil
+}
+
+func (a *AnnouncementInterface) Update(announcement MongoAnnouncement) errors.APIError {
+	_, err := a.col.UpdateOne(
+		a.ctx,
+		bson.M{"_id": announcement.ID},
+		bson.M{
+			"$set": bson.M{
+				"title":       announcement.Title,
+				"body":        announcement.Body,
+				"pinned":      announcement.Pinned,
+				"publishDate": announcement.PublishDate,
+			},
+		},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+func (a *AnnouncementInterface) Delete(anid interface{}) errors.APIError {
+	_, err := a.col.DeleteOne(a.ctx, bson.M{"_id": anid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+func (a *AnnouncementInterface) DeleteByCourseID(cid interface{}) errors.APIError {
+	_, err := a.col.DeleteMany(a.ctx, bson.M{"courseID": cid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+// GetByCourse lists a course's announcements, pinned first. Students only see
+// announcements whose publish date has passed.
+func (a *AnnouncementInterface) GetByCourse(cid interface{}, role string) ([]MongoAnnouncement, errors.APIError) {
+	filter := bson.M{"courseID": cid}
+	if role == "student" {
+		filter["publishDate"] = bson.M{"$lte": now()}
+	}
+
+	return a.find(filter, 0)
+}
+
+// GetFeed returns the newest published announcements across several courses.
+func (a *AnnouncementInterface) GetFeed(cids []primitive.ObjectID, limit int64) ([]MongoAnnouncement, errors.APIError) {
+	return a.find(
+		bson.M{
+			"courseID":    bson.M{"$in": cids},
+			"publishDate": bson.M{"$lte": now()},
+		},
+		limit,
+	)
+}
+
+// DueForNotification returns published announcements that have not been fanned out yet.
+func (a *AnnouncementInterface) DueForNotification() ([]MongoAnnouncement, errors.APIError) {
+	return a.find(
+		bson.M{
+			"notified":    false,
+			"publishDate": bson.M{"$lte": now()},
+		},
+		0,
+	)
+}
+
+func (a *AnnouncementInterface) MarkNotified(anid interface{}) errors.APIError {
+	_, err := a.col.UpdateOne(
+		a.ctx,
+		bson.M{"_id": anid},
+		bson.M{"$set": bson.M{"notified": true}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// SearchResult an announcement matching a search, Score being its relevance.
+type SearchResult struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	CourseID    primitive.ObjectID `bson:"courseID" json:"courseID"`
+	Title       string             `bson:"title" json:"title"`
+	PublishDate primitive.DateTime `bson:"publishDate" json:"publishDate"`
+	Score       float64            `bson:"score" json:"score"`
+}
+
+// Search finds announcements by their title and body, every one of staff's
+// courses and the published ones of students' courses, most relevant first.
+func (a *AnnouncementInterface) Search(q string, staff, student []primitive.ObjectID, skip, limit int64) ([]SearchResult, errors.APIError) {
+	results := make([]SearchResult, 0)
+	score := bson.M{"$meta": "textScore"}
+	cur, err := a.col.Find(
+		a.ctx,
+		bson.M{
+			"$text": bson.M{"$search": q},
+			"$or": bson.A{
+				bson.M{"courseID": bson.M{"$in": staff}},
+				bson.M{"courseID": bson.M{"$in": student}, "publishDate": bson.M{"$lte": now()}},
+			},
+		},
+		options.Find().
+			SetProjection(bson.M{"courseID": 1, "title": 1, "publishDate": 1, "score": score}).
+			SetSort(bson.M{"score": score}).
+			SetSkip(skip).
+			SetLimit(limit),
+	)
+	if err != nil {
+		return results, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(a.ctx) {
+		var result SearchResult
+		err = cur.Decode(&result)
+		if err != nil {
+			return results, errors.ErrorInvalidBSON
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
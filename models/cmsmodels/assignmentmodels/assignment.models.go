@@ -3,20 +3,73 @@ package assignmentmodels
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"encoding/json"
-	"os"
+	"hash/fnv"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/mongodb/mongo-go-driver/bson"
 	"github.com/mongodb/mongo-go-driver/bson/primitive"
 	"github.com/mongodb/mongo-go-driver/mongo"
 	"github.com/mongodb/mongo-go-driver/mongo/options"
 
+	"backend/config"
+	"backend/courtherald"
 	"backend/errors"
+	"backend/events"
 	"backend/forms"
+	"backend/models/cmsmodels/submissionmodels"
+	"backend/utils"
 
 	tyrgin "github.com/stevens-tyr/tyr-gin"
 )
 
+// How a test's output is compared to its expected output.
+const (
+	// MatchExact requires the output to be identical, the default.
+	MatchExact = "exact"
+	// MatchTrimmed ignores leading and trailing whitespace on every line.
+	MatchTrimmed = "trimmed"
+	// MatchCaseInsensitive ignores case.
+	MatchCaseInsensitive = "caseInsensitive"
+	// MatchRegex treats the expected output as a regular expression the whole
+	// output has to match.
+	MatchRegex = "regex"
+	// MatchNumeric compares the numbers in the output, allowing each to be off
+	// by the test's epsilon.
+	MatchNumeric = "numeric"
+	// MatchJSON compares the output and expected output as JSON values.
+	MatchJSON = "json"
+)
+
+// Statuses of a student's latest submission staff filter submissions by.
+const (
+	// SubmissionsGraded latest submissions that finished grading.
+	SubmissionsGraded = "graded"
+	// SubmissionsError latest submissions grading failed on.
+	SubmissionsError = "error"
+	// SubmissionsInProgress latest submissions being graded.
+	SubmissionsInProgress = "inProgress"
+	// SubmissionsUngraded latest submissions without a score yet, those
+	// being graded or that failed to.
+	SubmissionsUngraded = "ungraded"
+)
+
+// Orders staff sort students' submissions in.
+const (
+	SortByName  = "name"
+	SortByScore = "score"
+	SortByDate  = "date"
+)
+
+// repositoryDeliveries how many of a linked repository's latest webhook
+// delivery ids are kept to recognize redeliveries by.
+const repositoryDeliveries = 50
+
 type (
 	AssignmentSubmission struct {
 		UserID        primitive.ObjectID `bson:"userID" json:"userID" binding:"required"`
@@ -24,272 +77,2184 @@ type (
 		AttemptNumber int                `bson:"attemptNumber" json:"attemptNumber" binding:"required"`
 	}
 
-	Test struct {
-		Name           string `bson:"name" json:"name" binding:"required"`
-		ExpectedOutput string `bson:"expectedOutput" json:"expectedOutput" binding:"required"`
-		StudentFacing  bool   `bson:"studentFacing" json:"studentFacing" binding:"exists"`
-		TestCMD        string `bson:"testCMD" json:"testCMD" binding:"required"`
+	// Attachment a file (diagram, pdf) embedded in an assignment description.
+	Attachment struct {
+		ID          primitive.ObjectID `bson:"_id" json:"id" binding:"required"`
+		Filename    string             `bson:"filename" json:"filename" binding:"required"`
+		ContentType string             `bson:"contentType" json:"contentType" binding:"required"`
+		Size        int64              `bson:"size" json:"size" binding:"required"`
+		UploadDate  primitive.DateTime `bson:"uploadDate" json:"uploadDate" binding:"required"`
+	}
+
+	// ArtifactSlot a file students upload with every submission besides their
+	// code, like a PDF report. It is kept with the submission for staff to
+	// review and never graded.
+	ArtifactSlot struct {
+		Name     string `bson:"name" json:"name"`
+		Required bool   `bson:"required" json:"required"`
+		// Extensions the file extensions accepted, like .pdf, any when empty.
+		Extensions []string `bson:"extensions" json:"extensions"`
+	}
+
+	// RubricCriterion something staff grade the submissions of a manually
+	// graded assignment on, worth up to Points.
+	RubricCriterion struct {
+		ID          primitive.ObjectID `bson:"_id" json:"id"`
+		Name        string             `bson:"name" json:"name"`
+		Description string             `bson:"description" json:"description"`
+		Points      float64            `bson:"points" json:"points"`
+	}
+
+	// Quiz what the attempts of a quiz ask: every one of Questions, from the
+	// course's question bank, and questions drawn from each of Pools. Size
+	// the number of questions an attempt has, all students are shown.
+	Quiz struct {
+		Questions        []primitive.ObjectID `bson:"questions" json:"questions,omitempty"`
+		Pools            []QuizPool           `bson:"pools" json:"pools,omitempty"`
+		ShuffleQuestions bool                 `bson:"shuffleQuestions" json:"shuffleQuestions"`
+		ShuffleChoices   bool                 `bson:"shuffleChoices" json:"shuffleChoices"`
+		Size             int                  `bson:"size" json:"size"`
+	}
+
+	// QuizPool Count questions drawn at random from the ones tagged Tag, from
+	// the whole question bank when Tag is empty.
+	QuizPool struct {
+		Tag   string `bson:"tag" json:"tag"`
+		Count int    `bson:"count" json:"count"`
+	}
+
+	// Start when a student began a timed assignment.
+	Start struct {
+		UserID    primitive.ObjectID `bson:"userID" json:"userID" binding:"required"`
+		StartedAt primitive.DateTime `bson:"startedAt" json:"startedAt" binding:"required"`
+	}
+
+	// Accommodation extra time a student is given on a timed assignment.
+	Accommodation struct {
+		UserID       primitive.ObjectID `bson:"userID" json:"userID" binding:"required"`
+		ExtraMinutes int                `bson:"extraMinutes" json:"extraMinutes" binding:"required"`
+	}
+
+	// LatePolicy how submissions after the due date are graded: every day,
+	// or part of one, late takes PenaltyPerDay points off their score, and
+	// none are taken more than MaxDaysLate days late.
+	LatePolicy struct {
+		PenaltyPerDay float64 `bson:"penaltyPerDay" json:"penaltyPerDay"`
+		MaxDaysLate   int     `bson:"maxDaysLate" json:"maxDaysLate"`
+	}
+
+	// Extension a later due date a student is given on the assignment, with
+	// the extension request it was granted on.
+	Extension struct {
+		UserID    primitive.ObjectID  `bson:"userID" json:"userID"`
+		DueDate   primitive.DateTime  `bson:"dueDate" json:"dueDate"`
+		GrantedBy primitive.ObjectID  `bson:"grantedBy" json:"grantedBy"`
+		RequestID *primitive.ObjectID `bson:"requestID,omitempty" json:"requestID,omitempty"`
+	}
+
+	// RepositoryLink a student's repository whose pushes to a branch are
+	// submitted automatically through a webhook.
+	RepositoryLink struct {
+		ID     primitive.ObjectID `bson:"_id" json:"id"`
+		UserID primitive.ObjectID `bson:"userID" json:"userID"`
+		URL    string             `bson:"url" json:"url"`
+		Branch string             `bson:"branch" json:"branch"`
+		// Secret signs the webhook's deliveries.
+		Secret string `bson:"secret" json:"-"`
+		// StatusToken a GitHub token results are posted back to the commit with.
+		StatusToken         string             `bson:"statusToken" json:"-"`
+		AttestationAccepted bool               `bson:"attestationAccepted" json:"attestationAccepted"`
+		CreatedAt           primitive.DateTime `bson:"createdAt" json:"createdAt"`
+		// Deliveries the ids of the latest webhook deliveries, so redelivered
+		// pushes are not submitted again.
+		Deliveries []string `bson:"deliveries,omitempty" json:"-"`
+	}
+
+	// Leaderboard ranks passing submissions by a metric the grader reports.
+	Leaderboard struct {
+		Metric        string `bson:"metric" json:"metric"`
+		LowerIsBetter bool   `bson:"lowerIsBetter" json:"lowerIsBetter"`
+		// OptOuts students left off the leaderboard.
+		OptOuts []primitive.ObjectID `bson:"optOuts" json:"-"`
+	}
+
+	// Precheck quick checks a submission has to pass before it is graded,
+	// without using up an attempt.
+	Precheck struct {
+		// RequiredFiles the manifest of paths, in path.Match syntax, the archive
+		// must contain. Paths starting with ? are optional.
+		RequiredFiles []string `bson:"requiredFiles" json:"requiredFiles"`
+		// Exclusive rejects archives with files not in the manifest.
+		Exclusive bool `bson:"exclusive" json:"exclusive"`
+		// Build whether students can check their submission builds.
+		Build bool `bson:"build" json:"build"`
+	}
+
+	// Prerequisite an assignment a student must score at least MinScore, out
+	// of 100, on before they see or can submit the one requiring it.
+	Prerequisite struct {
+		AssignmentID primitive.ObjectID `bson:"assignmentID" json:"assignmentID"`
+		MinScore     float64            `bson:"minScore" json:"minScore"`
+	}
+
+	// Milestone a part of an assignment due before the assignment is, graded
+	// on a subset of its tests by the latest submission made in time.
+	Milestone struct {
+		Name    string             `bson:"name" json:"name"`
+		DueDate primitive.DateTime `bson:"dueDate" json:"dueDate"`
+		// Tests the names of the tests the milestone is graded on, kept from
+		// students as some may be hidden.
+		Tests []string `bson:"tests" json:"tests,omitempty" view:"staff"`
+		// Weight the milestone's share of the assignment's grade against the
+		// others' weights.
+		Weight float64 `bson:"weight" json:"weight"`
+	}
+
+	// MilestoneScore how a student did on a milestone, out of 100, and with
+	// which attempt, zero when they submitted none in time.
+	MilestoneScore struct {
+		Name    string  `json:"name"`
+		Score   float64 `json:"score"`
+		Attempt int     `json:"attempt"`
+	}
+
+	// ReferenceSolution a solution staff keep with the assignment, graded
+	// against the tests every night to catch tests that stopped passing.
+	ReferenceSolution struct {
+		FileID     primitive.ObjectID `bson:"fileID" json:"fileID"`
+		Filename   string             `bson:"filename" json:"filename"`
+		UploadDate primitive.DateTime `bson:"uploadDate" json:"uploadDate"`
+		// UploadedBy is who the solution is graded as, which picks the test
+		// variants it is checked against.
+		UploadedBy primitive.ObjectID `bson:"uploadedBy" json:"uploadedBy"`
+		LastCheck  *SelfCheck         `bson:"lastCheck,omitempty" json:"lastCheck,omitempty"`
+	}
+
+	// SelfCheck the outcome of grading the reference solution.
+	SelfCheck struct {
+		CheckedAt primitive.DateTime `bson:"checkedAt" json:"checkedAt"`
+		Passed    bool               `bson:"passed" json:"passed"`
+		// Failed the names of the tests that failed, or why grading failed.
+		Failed []string `bson:"failed" json:"failed"`
+		Job    string   `bson:"job" json:"job"`
+	}
+
+	// Window the period a student can submit a timed assignment in.
+	Window struct {
+		StartedAt    time.Time `json:"startedAt"`
+		EndsAt       time.Time `json:"endsAt"`
+		ExtraMinutes int       `json:"extraMinutes"`
+	}
+
+	// Fixture a file tests can use as their input, expected output or as a
+	// file in their working directory, for content too large or binary to
+	// write inline.
+	Fixture struct {
+		ID         primitive.ObjectID `bson:"_id" json:"id" binding:"required"`
+		Filename   string             `bson:"filename" json:"filename" binding:"required"`
+		Size       int64              `bson:"size" json:"size" binding:"required"`
+		UploadDate primitive.DateTime `bson:"uploadDate" json:"uploadDate" binding:"required"`
+	}
+
+	// TestFixture a fixture a test uses, as sent to the grader. The grader
+	// downloads it from job/:secret/assignment/:aid/fixture/:fid/download.
+	TestFixture struct {
+		ID       primitive.ObjectID `json:"id"`
+		Filename string             `json:"filename"`
+		// Role is input when the file is the test's stdin, expectedOutput when
+		// the output is compared to it, and file when it is only placed in
+		// the working directory.
+		Role string `json:"role"`
+	}
+
+	// TestVariant an alternative command and expected output for a test, so
+	// students can be given different inputs.
+	TestVariant struct {
+		TestCMD        string `bson:"testCMD" json:"testCMD" binding:"required"`
+		ExpectedOutput string `bson:"expectedOutput" json:"expectedOutput" binding:"required"`
+	}
+
+	Test struct {
+		Name           string        `bson:"name" json:"name" binding:"required"`
+		ExpectedOutput string        `bson:"expectedOutput" json:"expectedOutput" binding:"required"`
+		StudentFacing  bool          `bson:"studentFacing" json:"studentFacing" binding:"exists"`
+		TestCMD        string        `bson:"testCMD" json:"testCMD" binding:"required"`
+		Variants       []TestVariant `bson:"variants,omitempty" json:"variants,omitempty" view:"staff"`
+		// InputFixture, ExpectedOutputFixture and Fixtures name the fixtures
+		// the test uses.
+		InputFixture          string   `bson:"inputFixture,omitempty" json:"inputFixture,omitempty"`
+		ExpectedOutputFixture string   `bson:"expectedOutputFixture,omitempty" json:"expectedOutputFixture,omitempty"`
+		Fixtures              []string `bson:"fixtures,omitempty" json:"fixtures,omitempty"`
+		// Match how the output is compared to the expected output, exact when
+		// empty. Epsilon is the tolerance of numeric matches.
+		Match   string  `bson:"match,omitempty" json:"match,omitempty"`
+		Epsilon float64 `bson:"epsilon,omitempty" json:"epsilon,omitempty"`
+		// Variant the variant a student was given and FixtureFiles the
+		// fixtures it uses, only set on tests resolved for a student.
+		Variant      *int          `bson:"-" json:"variant,omitempty"`
+		FixtureFiles []TestFixture `bson:"-" json:"fixtureFiles,omitempty"`
+	}
+
+	// MongoAssignment struct to store information about an assignment.
+	MongoAssignment struct {
+		ID              primitive.ObjectID     `bson:"_id" form:"id" json:"id"`
+		Language        string                 `bson:"language" form:"language" binding:"required" json:"language"`
+		Version         string                 `bson:"version" form:"version" binding:"required" json:"version"`
+		Name            string                 `bson:"name" form:"name" binding:"required" json:"name"`
+		NumAttempts     int                    `bson:"numAttempts" form:"numAttempts" binding:"required" json:"numAttempts"`
+		Description     string                 `bson:"description" form:"description" binding:"required" json:"description"`
+		DueDate         primitive.DateTime     `bson:"dueDate" form:"dueDate" binding:"required" json:"dueDate"`
+		Published       bool                   `bson:"published" form:"published" binding:"required" json:"-"`
+		SupportingFiles primitive.ObjectID     `bson:"supportingFiles" form:"supportingFiles" json:"supportingFiles"`
+		TestBuildCMD    string                 `bson:"testBuildCMD" form:"testBuildCMD" json:"testBuildCMD"`
+		Tests           []Test                 `bson:"tests" form:"tests" binding:"required" json:"tests"`
+		Submissions     []AssignmentSubmission `bson:"submissions" form:"submissions" json:"submissions" view:"staff"`
+		Attachments     []Attachment           `bson:"attachments" form:"attachments" json:"attachments"`
+		// TimeLimit minutes a student has to submit once they start the
+		// assignment, zero when the assignment is not timed.
+		TimeLimit      int             `bson:"timeLimit" form:"timeLimit" json:"timeLimit"`
+		Starts         []Start         `bson:"starts" form:"starts" json:"-"`
+		Accommodations []Accommodation `bson:"accommodations" form:"accommodations" json:"-"`
+		Extensions     []Extension     `bson:"extensions" form:"extensions" json:"-"`
+		// Attestation an honor code statement students must accept to submit,
+		// empty when none is required.
+		Attestation     string           `bson:"attestation" form:"attestation" json:"attestation"`
+		RepositoryLinks []RepositoryLink `bson:"repositoryLinks" form:"repositoryLinks" json:"-"`
+		// Leaderboard set when the assignment has a leaderboard.
+		Leaderboard *Leaderboard `bson:"leaderboard,omitempty" form:"leaderboard" json:"leaderboard,omitempty"`
+		Fixtures    []Fixture    `bson:"fixtures" form:"fixtures" json:"fixtures"`
+		Precheck    *Precheck    `bson:"precheck,omitempty" form:"precheck" json:"precheck,omitempty"`
+		// ExtraCredit assignments add to the course total without counting
+		// towards what it is out of.
+		ExtraCredit bool `bson:"extraCredit" form:"extraCredit" json:"extraCredit"`
+		// ReferenceSolution is only shown to staff, by its own endpoint.
+		ReferenceSolution *ReferenceSolution `bson:"referenceSolution,omitempty" form:"referenceSolution" json:"-"`
+		// Requirements the labels a grader needs to grade the submissions,
+		// only registered graders with all of them are sent any.
+		Requirements []string `bson:"requirements,omitempty" form:"requirements" json:"requirements,omitempty"`
+		// ShowHiddenSummary tells students how many of the tests they are not
+		// shown their submissions passed, never which.
+		ShowHiddenSummary bool `bson:"showHiddenSummary" form:"showHiddenSummary" json:"showHiddenSummary"`
+		// Prerequisites keep the assignment from students until they have met
+		// every one.
+		Prerequisites []Prerequisite `bson:"prerequisites,omitempty" form:"prerequisites" json:"prerequisites"`
+		// Milestones split the assignment into parts due one after another,
+		// its grade their weighted scores, none for a single deadline.
+		Milestones []Milestone `bson:"milestones,omitempty" form:"milestones" json:"milestones,omitempty"`
+		// ArtifactSlots the files uploaded with each submission besides the
+		// code, which alone is graded.
+		ArtifactSlots []ArtifactSlot `bson:"artifactSlots,omitempty" form:"artifactSlots" json:"artifactSlots,omitempty"`
+		// Manual assignments have no tests: students upload a document that
+		// staff grade by hand on the Rubric, without court herald.
+		Manual bool              `bson:"manual" form:"manual" json:"manual"`
+		Rubric []RubricCriterion `bson:"rubric,omitempty" form:"rubric" json:"rubric,omitempty"`
+		// Quiz assignments are taken as quiz attempts, scored as they are
+		// submitted, instead of submitting code.
+		Quiz *Quiz `bson:"quiz,omitempty" form:"quiz" json:"quiz,omitempty"`
+		// CooldownMinutes students wait between submissions, none when zero.
+		CooldownMinutes int `bson:"cooldownMinutes" form:"cooldownMinutes" json:"cooldownMinutes"`
+		// LatePolicy nil takes late submissions without a penalty.
+		LatePolicy *LatePolicy `bson:"latePolicy,omitempty" form:"latePolicy" json:"latePolicy,omitempty"`
+		// Policy the course policy template the assignment was created with.
+		Policy string `bson:"policy,omitempty" form:"policy" json:"policy,omitempty"`
+	}
+
+	// AssignmentView an assignment as it is shown with its submissions, the
+	// fields students and staff both get.
+	AssignmentView struct {
+		ID                  primitive.ObjectID `bson:"_id" json:"id"`
+		Language            string             `bson:"language" json:"language"`
+		Version             string             `bson:"version" json:"version"`
+		Name                string             `bson:"name" json:"name"`
+		NumAttempts         int                `bson:"numAttempts" json:"numAttempts"`
+		Description         string             `bson:"description" json:"description"`
+		RenderedDescription string             `bson:"-" json:"renderedDescription"`
+		SupportingFiles     primitive.ObjectID `bson:"supportingFiles" json:"supportingFiles"`
+		DueDate             primitive.DateTime `bson:"dueDate" json:"dueDate"`
+		DueDateLocal        *utils.LocalTime   `bson:"-" json:"dueDateLocal,omitempty"`
+		Published           bool               `bson:"published" json:"published"`
+		TestBuildCMD        string             `bson:"testBuildCMD" json:"testBuildCMD"`
+		Tests               []Test             `bson:"tests" json:"tests"`
+		Attachments         []Attachment       `bson:"attachments" json:"attachments"`
+		TimeLimit           int                `bson:"timeLimit" json:"timeLimit"`
+		Attestation         string             `bson:"attestation" json:"attestation"`
+		Leaderboard         *Leaderboard       `bson:"leaderboard,omitempty" json:"leaderboard,omitempty"`
+		ShowHiddenSummary   bool               `bson:"showHiddenSummary" json:"showHiddenSummary"`
+		Prerequisites       []Prerequisite     `bson:"prerequisites" json:"prerequisites"`
+		Milestones          []Milestone        `bson:"milestones" json:"milestones,omitempty"`
+		ArtifactSlots       []ArtifactSlot     `bson:"artifactSlots" json:"artifactSlots,omitempty"`
+		Manual              bool               `bson:"manual" json:"manual"`
+		Rubric              []RubricCriterion  `bson:"rubric" json:"rubric,omitempty"`
+		Quiz                *Quiz              `bson:"quiz,omitempty" json:"quiz,omitempty"`
+	}
+
+	// StudentAssignmentView an assignment as a student sees it, with their
+	// own submissions and, on timed assignments they started, their window.
+	StudentAssignmentView struct {
+		AssignmentView `bson:",inline"`
+		Window         *Window                            `bson:"-" json:"window,omitempty"`
+		Submissions    []submissionmodels.MongoSubmission `bson:"submissions" json:"submissions"`
+		// MilestoneScores how they did on each milestone, when it has any.
+		MilestoneScores []MilestoneScore `bson:"-" json:"milestoneScores,omitempty"`
+	}
+
+	// StaffAssignmentView an assignment as staff see it, with every
+	// student's submissions.
+	StaffAssignmentView struct {
+		AssignmentView     `bson:",inline"`
+		StudentSubmissions []StudentSubmissions `bson:"studentSubmissions" json:"studentSubmissions"`
+		StatusCounts       []StatusCount        `bson:"statusCounts" json:"-"`
+		// Counts how many students there are of each status, and how many
+		// match the query to page through.
+		Counts SubmissionCounts `bson:"-" json:"counts"`
+	}
+
+	// StatusCount how many students' latest submission has a status.
+	StatusCount struct {
+		Status string `bson:"_id"`
+		Count  int    `bson:"count"`
+	}
+
+	// SubmissionCounts the students of an assignment by the status of their
+	// latest submission.
+	SubmissionCounts struct {
+		Students   int `json:"students"`
+		Matching   int `json:"matching"`
+		Graded     int `json:"graded"`
+		Error      int `json:"error"`
+		InProgress int `json:"inProgress"`
+		Ungraded   int `json:"ungraded"`
+	}
+
+	// StudentSubmissions a student's submissions of an assignment, oldest
+	// first and without their test outputs, with the status and score of the
+	// latest.
+	StudentSubmissions struct {
+		Student     SubmissionStudent                  `bson:"student" json:"student"`
+		Submissions []submissionmodels.MongoSubmission `bson:"submissions" json:"submissions"`
+		Status      string                             `bson:"status" json:"status"`
+		Score       float64                            `bson:"score" json:"score"`
+		SubmittedAt primitive.DateTime                 `bson:"submittedAt" json:"submittedAt"`
+	}
+
+	// StaffSubmissionsQuery which students' submissions GetFullForStaff
+	// returns and in what order.
+	StaffSubmissionsQuery struct {
+		IncludeWithdrawn bool
+		// Status keeps the students whose latest submission has it, every
+		// student when empty.
+		Status string
+		// Sort orders students by SortByName, the default, SortByScore or
+		// SortByDate of their latest submission.
+		Sort       string
+		Descending bool
+		// Skip and Limit page the students, every student when Limit is 0.
+		Skip  int64
+		Limit int64
+	}
+
+	// SubmissionStudent who made a submission.
+	SubmissionStudent struct {
+		Email     string `bson:"email" json:"email"`
+		FirstName string `bson:"firstName" json:"firstName"`
+		LastName  string `bson:"lastName" json:"lastName"`
+	}
+
+	AssignmentInterface struct {
+		ctx context.Context
+		col *mongo.Collection
+	}
+)
+
+// variantFor picks a student's variant of a test. The pick only depends on the
+// student, assignment and test so a student always gets the same variant.
+func variantFor(uid, aid primitive.ObjectID, test, variants int) int {
+	hash := fnv.New32a()
+	hash.Write(uid[:])
+	hash.Write(aid[:])
+	binary.Write(hash, binary.BigEndian, int32(test))
+
+	return int(hash.Sum32() % uint32(variants))
+}
+
+// NewPrecheck builds an assignment's precheck from a comma separated manifest
+// of required files, nil when there is nothing to check.
+func NewPrecheck(requiredFiles string, exclusive, build bool) (*Precheck, errors.APIError) {
+	files := make([]string, 0)
+	for _, file := range strings.Split(requiredFiles, ",") {
+		file = strings.TrimSpace(file)
+		if file == "" {
+			continue
+		}
+		if _, err := path.Match(strings.TrimPrefix(file, "?"), ""); err != nil {
+			return nil, errors.ErrorInvalidPrecheck
+		}
+		files = append(files, file)
+	}
+
+	if len(files) == 0 && !exclusive && !build {
+		return nil, nil
+	}
+
+	return &Precheck{files, exclusive, build}, nil
+}
+
+// artifactSlotName what an artifact slot may be named, so it can be told
+// apart in the form fields of a submission.
+var artifactSlotName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// NewArtifactSlots checks the upload slots of an assignment: each has a
+// name of lowercase letters, digits and dashes, once, and the extensions
+// are lowercased with their leading dot.
+func NewArtifactSlots(form []forms.ArtifactSlotForm) ([]ArtifactSlot, errors.APIError) {
+	slots := make([]ArtifactSlot, 0, len(form))
+	names := make(map[string]bool)
+	for _, slot := range form {
+		if !artifactSlotName.MatchString(slot.Name) || names[slot.Name] {
+			return nil, errors.ErrorInvalidArtifactSlots
+		}
+		names[slot.Name] = true
+
+		extensions := make([]string, 0, len(slot.Extensions))
+		for _, extension := range slot.Extensions {
+			extension = strings.ToLower(strings.TrimSpace(extension))
+			if extension == "" {
+				continue
+			}
+			if !strings.HasPrefix(extension, ".") {
+				extension = "." + extension
+			}
+			extensions = append(extensions, extension)
+		}
+
+		slots = append(slots, ArtifactSlot{slot.Name, slot.Required, extensions})
+	}
+
+	return slots, nil
+}
+
+// Accepts reports whether a file of the name can be uploaded to the slot.
+func (s ArtifactSlot) Accepts(filename string) bool {
+	if len(s.Extensions) == 0 {
+		return true
+	}
+
+	extension := strings.ToLower(path.Ext(filename))
+	for _, accepted := range s.Extensions {
+		if extension == accepted {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewRubric the rubric of a manually graded assignment: every criterion is
+// named and worth points. Criteria without an ID are given one.
+func NewRubric(form []forms.RubricCriterionForm) ([]RubricCriterion, errors.APIError) {
+	rubric := make([]RubricCriterion, 0, len(form))
+	for _, criterion := range form {
+		name := strings.TrimSpace(criterion.Name)
+		if name == "" || criterion.Points <= 0 {
+			return nil, errors.ErrorInvalidRubric
+		}
+
+		id := criterion.ID
+		if id.IsZero() {
+			id = primitive.NewObjectID()
+		}
+		rubric = append(rubric, RubricCriterion{id, name, criterion.Description, criterion.Points})
+	}
+
+	return rubric, nil
+}
+
+// Review grades a submission of a manually graded assignment on its rubric:
+// every criterion scored once, from zero to its points, with annotations on
+// pages of the document. Grade is the share of the rubric's points earned,
+// out of 100.
+func (m *MongoAssignment) Review(form forms.ReviewSubmissionForm, by primitive.ObjectID, at time.Time) (*submissionmodels.Review, errors.APIError) {
+	if !m.Manual || len(m.Rubric) == 0 {
+		return nil, errors.ErrorNotManuallyGraded
+	}
+
+	given := make(map[primitive.ObjectID]forms.CriterionScoreForm)
+	for _, score := range form.Scores {
+		if _, twice := given[score.CriterionID]; twice {
+			return nil, errors.ErrorInvalidReview
+		}
+		given[score.CriterionID] = forms.CriterionScoreForm(score)
+	}
+	if len(given) != len(m.Rubric) {
+		return nil, errors.ErrorInvalidReview
+	}
+
+	review := &submissionmodels.Review{
+		Scores:      make([]submissionmodels.CriterionScore, 0, len(m.Rubric)),
+		Annotations: make([]submissionmodels.Annotation, 0, len(form.Annotations)),
+		Comment:     form.Comment,
+		By:          by,
+		At:          utils.TimeToDateTime(at),
+	}
+
+	earned, total := 0.0, 0.0
+	for _, criterion := range m.Rubric {
+		score, found := given[criterion.ID]
+		if !found || score.Points < 0 || score.Points > criterion.Points {
+			return nil, errors.ErrorInvalidReview
+		}
+
+		review.Scores = append(review.Scores, submissionmodels.CriterionScore{
+			CriterionID: criterion.ID,
+			Name:        criterion.Name,
+			Points:      score.Points,
+			OutOf:       criterion.Points,
+			Comment:     score.Comment,
+		})
+		earned += score.Points
+		total += criterion.Points
+	}
+	review.Grade = 100 * earned / total
+
+	for _, annotation := range form.Annotations {
+		if annotation.Page < 1 || annotation.X < 0 || annotation.X > 1 || annotation.Y < 0 || annotation.Y > 1 {
+			return nil, errors.ErrorInvalidReview
+		}
+		review.Annotations = append(review.Annotations, submissionmodels.Annotation(annotation))
+	}
+
+	return review, nil
+}
+
+// NewRequirements an assignment's grader requirements from a comma separated
+// list of labels, nil when there are none.
+func NewRequirements(list string) []string {
+	var requirements []string
+	for _, requirement := range strings.Split(list, ",") {
+		if requirement = strings.TrimSpace(requirement); requirement != "" {
+			requirements = append(requirements, requirement)
+		}
+	}
+
+	return requirements
+}
+
+// ManifestMismatch what is wrong with a submission's files.
+type ManifestMismatch struct {
+	Missing    []string `json:"missingFiles"`
+	Unexpected []string `json:"unexpectedFiles"`
+}
+
+// CheckManifest compares the files in a submission to the assignment's
+// manifest, nil when they match.
+func (m *MongoAssignment) CheckManifest(paths []string) *ManifestMismatch {
+	if m.Precheck == nil {
+		return nil
+	}
+
+	missing, unexpected := utils.CheckManifest(paths, m.Precheck.RequiredFiles, m.Precheck.Exclusive)
+	if len(missing) == 0 && len(unexpected) == 0 {
+		return nil
+	}
+
+	return &ManifestMismatch{missing, unexpected}
+}
+
+// CheckMatch validates how a test's output is compared, including that the
+// expected output of every variant of a regex test compiles.
+func CheckMatch(test Test) errors.APIError {
+	switch test.Match {
+	case "", MatchExact, MatchTrimmed, MatchCaseInsensitive, MatchJSON:
+	case MatchNumeric:
+		if test.Epsilon < 0 {
+			return errors.ErrorInvalidTestMatch
+		}
+	case MatchRegex:
+		expected := []string{test.ExpectedOutput}
+		for _, variant := range test.Variants {
+			expected = append(expected, variant.ExpectedOutput)
+		}
+		for _, pattern := range expected {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return errors.ErrorInvalidTestMatch
+			}
+		}
+	default:
+		return errors.ErrorInvalidTestMatch
+	}
+
+	return nil
+}
+
+// What keeps an assignment from being published, or should be looked at
+// before it is.
+const (
+	ReadinessNoTests               = "noTests"
+	ReadinessNoStudentFacingTest   = "noStudentFacingTest"
+	ReadinessNoBuildCommand        = "noBuildCommand"
+	ReadinessDueDatePassed         = "dueDatePassed"
+	ReadinessNoSupportingFiles     = "noSupportingFiles"
+	ReadinessReferenceFails        = "referenceFails"
+	ReadinessReferenceNotChecked   = "referenceNotChecked"
+	ReadinessNoReferenceSolution   = "noReferenceSolution"
+	ReadinessNoDescription         = "noDescription"
+	ReadinessDueSoon               = "dueSoon"
+	ReadinessAllTestsStudentFacing = "allTestsStudentFacing"
+	ReadinessNoRubric              = "noRubric"
+	ReadinessNoQuestions           = "noQuestions"
+)
+
+// compiledLanguages the languages whose submissions need building before
+// they are tested.
+var compiledLanguages = map[string]bool{
+	"c": true, "c++": true, "cpp": true, "c#": true, "csharp": true, "go": true,
+	"haskell": true, "java": true, "kotlin": true, "rust": true, "scala": true,
+	"swift": true,
+}
+
+type (
+	// ReadinessIssue one thing wrong with an assignment about to be
+	// published.
+	ReadinessIssue struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+
+	// Readiness whether an assignment can be published. Blockers keep it
+	// from being published, warnings do not.
+	Readiness struct {
+		Ready    bool             `json:"ready"`
+		Blockers []ReadinessIssue `json:"blockers"`
+		Warnings []ReadinessIssue `json:"warnings"`
+	}
+)
+
+// Readiness checks an assignment can be published at now: it has a test
+// students see, a build command when its language is compiled, a due date
+// still to come, supporting files, and a reference solution, if it has one,
+// that passed when it was last graded. Manual assignments need a rubric,
+// and quizzes questions, in place of the tests and reference solution.
+func (m *MongoAssignment) Readiness(now time.Time) Readiness {
+	readiness := Readiness{
+		Blockers: make([]ReadinessIssue, 0),
+		Warnings: make([]ReadinessIssue, 0),
+	}
+	block := func(code, message string) {
+		readiness.Blockers = append(readiness.Blockers, ReadinessIssue{code, message})
+	}
+	warn := func(code, message string) {
+		readiness.Warnings = append(readiness.Warnings, ReadinessIssue{code, message})
+	}
+
+	switch {
+	case m.Manual:
+		if len(m.Rubric) == 0 {
+			block(ReadinessNoRubric, "The assignment is graded by hand but has no rubric.")
+		}
+	case m.Quiz != nil:
+		if m.Quiz.Size == 0 {
+			block(ReadinessNoQuestions, "The quiz has no questions.")
+		}
+	default:
+		studentFacing := 0
+		for _, test := range m.Tests {
+			if test.StudentFacing {
+				studentFacing++
+			}
+		}
+		switch {
+		case len(m.Tests) == 0:
+			block(ReadinessNoTests, "The assignment has no tests.")
+		case studentFacing == 0:
+			block(ReadinessNoStudentFacingTest, "Students are not shown any of the tests.")
+		case studentFacing == len(m.Tests):
+			warn(ReadinessAllTestsStudentFacing, "Every test is shown to students, none is hidden.")
+		}
+
+		if compiledLanguages[strings.ToLower(strings.TrimSpace(m.Language))] && strings.TrimSpace(m.TestBuildCMD) == "" {
+			block(ReadinessNoBuildCommand, "Submissions in "+m.Language+" need a build command.")
+		}
+	}
+
+	due := utils.DateTimeToTime(m.DueDate)
+	if !due.After(now) {
+		block(ReadinessDueDatePassed, "The due date has passed.")
+	} else if due.Sub(now) < 24*time.Hour {
+		warn(ReadinessDueSoon, "The assignment is due in less than a day.")
+	}
+
+	if m.SupportingFiles.IsZero() {
+		block(ReadinessNoSupportingFiles, "No supporting files were uploaded.")
+	}
+
+	if m.RunsTests() {
+		switch reference := m.ReferenceSolution; {
+		case reference == nil:
+			warn(ReadinessNoReferenceSolution, "There is no reference solution to check the tests with.")
+		case reference.LastCheck == nil:
+			warn(ReadinessReferenceNotChecked, "The reference solution has not been graded yet.")
+		case !reference.LastCheck.Passed:
+			block(ReadinessReferenceFails, "The reference solution fails: "+strings.Join(reference.LastCheck.Failed, ", ")+".")
+		}
+	}
+
+	if strings.TrimSpace(m.Description) == "" {
+		warn(ReadinessNoDescription, "The assignment has no description.")
+	}
+
+	readiness.Ready = len(readiness.Blockers) == 0
+	return readiness
+}
+
+// NewPrerequisites checks the prerequisites of assignment aid of a course
+// with the assignments of courseAssignments: each is another assignment of
+// the course, once, with a score out of 100, and none leads back to aid
+// through the prerequisites of others, which would lock it for good.
+// existing the course's assignments with prerequisites.
+func NewPrerequisites(aid primitive.ObjectID, courseAssignments []primitive.ObjectID, existing []MongoAssignment, form forms.UpdatePrerequisitesForm) ([]Prerequisite, errors.APIError) {
+	inCourse := make(map[primitive.ObjectID]bool)
+	for _, id := range courseAssignments {
+		inCourse[id] = true
+	}
+
+	requires := make(map[primitive.ObjectID][]primitive.ObjectID)
+	for _, assign := range existing {
+		for _, prerequisite := range assign.Prerequisites {
+			requires[assign.ID] = append(requires[assign.ID], prerequisite.AssignmentID)
+		}
+	}
+	requires[aid] = nil
+
+	prerequisites := make([]Prerequisite, 0, len(form.Prerequisites))
+	seen := make(map[primitive.ObjectID]bool)
+	for _, prerequisite := range form.Prerequisites {
+		id := prerequisite.AssignmentID
+		if id == aid || !inCourse[id] || seen[id] || prerequisite.MinScore < 0 || prerequisite.MinScore > 100 {
+			return nil, errors.ErrorInvalidPrerequisites
+		}
+		seen[id] = true
+
+		prerequisites = append(prerequisites, Prerequisite{id, prerequisite.MinScore})
+		requires[aid] = append(requires[aid], id)
+	}
+
+	visited := make(map[primitive.ObjectID]bool)
+	var leadsToAid func(id primitive.ObjectID) bool
+	leadsToAid = func(id primitive.ObjectID) bool {
+		if id == aid {
+			return true
+		}
+		if visited[id] {
+			return false
+		}
+		visited[id] = true
+
+		for _, next := range requires[id] {
+			if leadsToAid(next) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, prerequisite := range prerequisites {
+		if leadsToAid(prerequisite.AssignmentID) {
+			return nil, errors.ErrorInvalidPrerequisites
+		}
+	}
+
+	return prerequisites, nil
+}
+
+// UnmetPrerequisites the prerequisites a student with the best scores of
+// best, by assignment, has not met. Assignments missing from best were
+// never submitted to.
+func (m *MongoAssignment) UnmetPrerequisites(best map[primitive.ObjectID]float64) []Prerequisite {
+	unmet := make([]Prerequisite, 0)
+	for _, prerequisite := range m.Prerequisites {
+		score, submitted := best[prerequisite.AssignmentID]
+		if !submitted || score < prerequisite.MinScore {
+			unmet = append(unmet, prerequisite)
+		}
+	}
+
+	return unmet
+}
+
+// NewMilestones an assignment's milestones from the form, in the order
+// they are due, once CheckMilestones passes them.
+func (m *MongoAssignment) NewMilestones(form []forms.AssignmentMilestoneForm) ([]Milestone, errors.APIError) {
+	milestones := make([]Milestone, len(form))
+	for index, milestone := range form {
+		milestones[index] = Milestone{strings.TrimSpace(milestone.Name), milestone.DueDate, milestone.Tests, milestone.Weight}
+	}
+
+	if err := m.CheckMilestones(milestones); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(milestones, func(i, j int) bool { return milestones[i].DueDate < milestones[j].DueDate })
+	return milestones, nil
+}
+
+// CheckMilestones checks milestones fit the assignment: each is named, once,
+// due no later than the assignment, with a weight that is not negative, and
+// graded on some of its tests.
+func (m *MongoAssignment) CheckMilestones(milestones []Milestone) errors.APIError {
+	tests := make(map[string]bool)
+	for _, test := range m.Tests {
+		tests[test.Name] = true
+	}
+
+	names := make(map[string]bool)
+	for _, milestone := range milestones {
+		if milestone.Name == "" || names[milestone.Name] || milestone.Weight < 0 || len(milestone.Tests) == 0 || milestone.DueDate > m.DueDate {
+			return errors.ErrorInvalidMilestones
+		}
+		names[milestone.Name] = true
+
+		for _, test := range milestone.Tests {
+			if !tests[test] {
+				return errors.ErrorInvalidMilestones
+			}
+		}
+	}
+
+	return nil
+}
+
+// MilestoneGrade grades a student's submissions to an assignment with
+// milestones: each milestone by the latest submission made before it was
+// due, on its tests, the assignment by their scores weighted, equally when
+// every weight is zero. A grade staff overrode the latest submission with
+// stands for the whole assignment.
+func (m *MongoAssignment) MilestoneGrade(submissions []submissionmodels.MongoSubmission) (float64, []MilestoneScore) {
+	var latest *submissionmodels.MongoSubmission
+	for i := range submissions {
+		if submissions[i].Refunded {
+			continue
+		}
+		if latest == nil || submissions[i].SubmissionDate > latest.SubmissionDate {
+			latest = &submissions[i]
+		}
+	}
+
+	scores := make([]MilestoneScore, len(m.Milestones))
+	weighted, weights, sum := 0.0, 0.0, 0.0
+	for index, milestone := range m.Milestones {
+		var graded *submissionmodels.MongoSubmission
+		for i := range submissions {
+			submission := &submissions[i]
+			if submission.Refunded || submission.InProgress || submission.SubmissionDate > milestone.DueDate {
+				continue
+			}
+			if graded == nil || submission.SubmissionDate > graded.SubmissionDate {
+				graded = submission
+			}
+		}
+
+		scores[index] = MilestoneScore{Name: milestone.Name}
+		if graded != nil {
+			scores[index].Score = milestoneScore(milestone, graded.Results)
+			scores[index].Attempt = graded.AttemptNumber
+		}
+
+		weighted += milestone.Weight * scores[index].Score
+		weights += milestone.Weight
+		sum += scores[index].Score
+	}
+
+	if latest != nil && latest.GradeOverride != nil {
+		return latest.GradeOverride.Grade, scores
+	}
+	if weights > 0 {
+		return weighted / weights, scores
+	}
+	if len(scores) > 0 {
+		return sum / float64(len(scores)), scores
+	}
+
+	return 0, scores
+}
+
+// milestoneScore the share, out of 100, of a milestone's tests the results
+// passed. A test with variants passes when every variant does, and tests
+// missing from the results failed.
+func milestoneScore(milestone Milestone, results []submissionmodels.WorkerResult) float64 {
+	passed := make(map[string]bool)
+	failed := make(map[string]bool)
+	for _, result := range results {
+		if result.Passed {
+			passed[result.Name] = true
+		} else {
+			failed[result.Name] = true
+		}
+	}
+
+	count := 0
+	for _, test := range milestone.Tests {
+		if passed[test] && !failed[test] {
+			count++
+		}
+	}
+
+	return 100 * float64(count) / float64(len(milestone.Tests))
+}
+
+// Fixture finds one of the assignment's fixtures by filename.
+func (m *MongoAssignment) Fixture(filename string) *Fixture {
+	for index := range m.Fixtures {
+		if m.Fixtures[index].Filename == filename {
+			return &m.Fixtures[index]
+		}
+	}
+
+	return nil
+}
+
+// MissingFixture returns the first fixture a test names that the assignment
+// does not have, or an empty string when they all exist.
+func (m *MongoAssignment) MissingFixture(tests []Test) string {
+	for _, test := range tests {
+		names := append([]string{test.InputFixture, test.ExpectedOutputFixture}, test.Fixtures...)
+		for _, name := range names {
+			if name != "" && m.Fixture(name) == nil {
+				return name
+			}
+		}
+	}
+
+	return ""
+}
+
+// UsesFixture whether any test uses the fixture.
+func (m *MongoAssignment) UsesFixture(filename string) bool {
+	for _, test := range m.Tests {
+		if test.InputFixture == filename || test.ExpectedOutputFixture == filename {
+			return true
+		}
+		for _, name := range test.Fixtures {
+			if name == filename {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// fixtureFiles resolves the fixtures a test names for the grader.
+func (m *MongoAssignment) fixtureFiles(test Test) []TestFixture {
+	files := make([]TestFixture, 0)
+	add := func(name, role string) {
+		if fixture := m.Fixture(name); fixture != nil {
+			files = append(files, TestFixture{fixture.ID, fixture.Filename, role})
+		}
+	}
+
+	add(test.InputFixture, courtherald.FixtureInput)
+	add(test.ExpectedOutputFixture, courtherald.FixtureExpectedOutput)
+	for _, name := range test.Fixtures {
+		add(name, courtherald.FixtureFile)
+	}
+
+	return files
+}
+
+// VisibleTo only staff see tests that are not student facing.
+func (t Test) VisibleTo(view string) bool {
+	return t.StudentFacing || view == utils.ViewStaff
+}
+
+// TestsFor returns the assignment's tests as a student is graded on them,
+// with the command and expected output of their variant of each test and the
+// fixtures each test uses.
+func (m *MongoAssignment) TestsFor(uid primitive.ObjectID) []Test {
+	tests := make([]Test, len(m.Tests))
+	for index, test := range m.Tests {
+		tests[index] = test
+		tests[index].FixtureFiles = m.fixtureFiles(test)
+		if len(test.Variants) == 0 {
+			continue
+		}
+
+		variant := variantFor(uid, m.ID, index, len(test.Variants))
+		tests[index].TestCMD = test.Variants[variant].TestCMD
+		tests[index].ExpectedOutput = test.Variants[variant].ExpectedOutput
+		tests[index].Variants = nil
+		tests[index].Variant = &variant
+	}
+
+	return tests
+}
+
+// Job how court herald grades the submissions of a student.
+func (m *MongoAssignment) Job(uid primitive.ObjectID) courtherald.Job {
+	tests := m.TestsFor(uid)
+	job := courtherald.Job{
+		Tests:        make([]courtherald.Test, len(tests)),
+		TestBuildCMD: m.TestBuildCMD,
+		Language:     m.Language,
+	}
+
+	for index, test := range tests {
+		fixtures := make([]courtherald.Fixture, len(test.FixtureFiles))
+		for i, fixture := range test.FixtureFiles {
+			fixtures[i] = courtherald.Fixture{ID: fixture.ID, Filename: fixture.Filename, Role: fixture.Role}
+		}
+
+		job.Tests[index] = courtherald.Test{
+			Name:                  test.Name,
+			TestCMD:               test.TestCMD,
+			ExpectedOutput:        test.ExpectedOutput,
+			StudentFacing:         test.StudentFacing,
+			InputFixture:          test.InputFixture,
+			ExpectedOutputFixture: test.ExpectedOutputFixture,
+			Fixtures:              test.Fixtures,
+			Match:                 test.Match,
+			Epsilon:               test.Epsilon,
+			Variant:               test.Variant,
+			FixtureFiles:          fixtures,
+		}
+	}
+
+	return job
+}
+
+// RunsTests whether submissions are graded by court herald running the
+// tests, rather than by hand or as a quiz.
+func (m *MongoAssignment) RunsTests() bool {
+	return !m.Manual && m.Quiz == nil
+}
+
+// Timed whether students must start the assignment before submitting.
+func (m *MongoAssignment) Timed() bool {
+	return m.TimeLimit > 0
+}
+
+// ExtraMinutes the extra time a student has been given on the assignment.
+func (m *MongoAssignment) ExtraMinutes(uid primitive.ObjectID) int {
+	for _, accommodation := range m.Accommodations {
+		if accommodation.UserID == uid {
+			return accommodation.ExtraMinutes
+		}
+	}
+
+	return 0
+}
+
+// Window returns a student's submission window, nil if they have not started
+// the assignment.
+func (m *MongoAssignment) Window(uid primitive.ObjectID) *Window {
+	for _, start := range m.Starts {
+		if start.UserID != uid {
+			continue
+		}
+
+		extra := m.ExtraMinutes(uid)
+		startedAt := utils.DateTimeToTime(start.StartedAt)
+		return &Window{
+			StartedAt:    startedAt,
+			EndsAt:       startedAt.Add(time.Duration(m.TimeLimit+extra) * time.Minute),
+			ExtraMinutes: extra,
+		}
+	}
+
+	return nil
+}
+
+// DueDateFor the due date of a student, the one they were given by an
+// extension if any.
+func (m *MongoAssignment) DueDateFor(uid primitive.ObjectID) primitive.DateTime {
+	for _, extension := range m.Extensions {
+		if extension.UserID == uid {
+			return extension.DueDate
+		}
+	}
+
+	return m.DueDate
+}
+
+// CheckLatePolicy a late policy takes between 0 and 100 points a day, for at
+// most a year.
+func CheckLatePolicy(policy *LatePolicy) errors.APIError {
+	if policy.PenaltyPerDay < 0 || policy.PenaltyPerDay > 100 || policy.MaxDaysLate < 0 || policy.MaxDaysLate > 365 {
+		return errors.ErrorInvalidLatePolicy
+	}
+
+	return nil
+}
+
+// DaysLate how many calendar days after the student's due date a submission
+// was made, counted in the deployment's timezone, 0 when it was on time.
+func (m *MongoAssignment) DaysLate(uid primitive.ObjectID, submitted primitive.DateTime) int {
+	return utils.CalendarDaysLate(utils.DateTimeToTime(m.DueDateFor(uid)), utils.DateTimeToTime(submitted), utils.DeploymentLocation())
+}
+
+// LatePenalty the points the late policy takes off a student's submission.
+func (m *MongoAssignment) LatePenalty(uid primitive.ObjectID, submitted primitive.DateTime) float64 {
+	if m.LatePolicy == nil {
+		return 0
+	}
+
+	return float64(m.DaysLate(uid, submitted)) * m.LatePolicy.PenaltyPerDay
+}
+
+// AcceptsLate whether the late policy still takes a student's submission.
+func (m *MongoAssignment) AcceptsLate(uid primitive.ObjectID, at time.Time) bool {
+	return m.LatePolicy == nil || m.DaysLate(uid, utils.TimeToDateTime(at)) <= m.LatePolicy.MaxDaysLate
+}
+
+// Deadline when a student's work is due, the end of their window on a timed
+// assignment they started and their due date otherwise.
+func (m *MongoAssignment) Deadline(uid primitive.ObjectID) time.Time {
+	if window := m.Window(uid); window != nil {
+		return window.EndsAt
+	}
+
+	return utils.DateTimeToTime(m.DueDateFor(uid))
+}
+
+func New() *AssignmentInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+	col := tyrgin.GetMongoCollection("assignments", db)
+
+	return &AssignmentInterface{
+		context.Background(),
+		col,
+	}
+}
+
+func (a *AssignmentInterface) Create(form forms.CreateAssignmentPostForm, cid string) (*primitive.ObjectID, *primitive.ObjectID, errors.APIError) {
+	tests := make([]Test, len(form.Tests))
+	for index, test := range form.Tests {
+		variants := make([]TestVariant, len(test.Variants))
+		for v := range test.Variants {
+			variants[v] = TestVariant(test.Variants[v])
+		}
+
+		tests[index] = Test{
+			Name:                  test.Name,
+			ExpectedOutput:        test.ExpectedOutput,
+			StudentFacing:         test.StudentFacing,
+			TestCMD:               test.TestCMD,
+			Variants:              variants,
+			InputFixture:          test.InputFixture,
+			ExpectedOutputFixture: test.ExpectedOutputFixture,
+			Fixtures:              test.Fixtures,
+			Match:                 test.Match,
+			Epsilon:               test.Epsilon,
+		}
+		if err := CheckMatch(tests[index]); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	precheck, err := NewPrecheck(form.PrecheckFiles, form.PrecheckExclusive, form.PrecheckBuild)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rubric := make([]forms.RubricCriterionForm, len(form.Rubric))
+	for index, criterion := range form.Rubric {
+		rubric[index] = forms.RubricCriterionForm(criterion)
+	}
+	criteria, err := NewRubric(rubric)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	aid := primitive.NewObjectID()
+	supportingFiles := primitive.NewObjectID()
+	assign := MongoAssignment{
+		ID:              aid,
+		Language:        form.Language,
+		Version:         form.Version,
+		Name:            form.Name,
+		NumAttempts:     form.NumAttempts,
+		Description:     form.Description,
+		SupportingFiles: supportingFiles,
+		DueDate:         form.DueDate,
+		Published:       false,
+		TestBuildCMD:    form.TestBuildCMD,
+		Tests:           tests,
+		Submissions:     make([]AssignmentSubmission, 0),
+		Attachments:     make([]Attachment, 0),
+		TimeLimit:       form.TimeLimit,
+		Starts:          make([]Start, 0),
+		Accommodations:  make([]Accommodation, 0),
+		Extensions:      make([]Extension, 0),
+		Attestation:     form.Attestation,
+		RepositoryLinks: make([]RepositoryLink, 0),
+		Fixtures:        make([]Fixture, 0),
+		Precheck:        precheck,
+		ExtraCredit:     form.ExtraCredit,
+		Requirements:    NewRequirements(form.Requirements),
+
+		ShowHiddenSummary: form.ShowHiddenSummary,
+		Manual:            form.Manual,
+		Rubric:            criteria,
+		CooldownMinutes:   form.CooldownMinutes,
+		Policy:            form.Policy,
+	}
+	if form.LatePolicy != nil {
+		late := LatePolicy(*form.LatePolicy)
+		if err = CheckLatePolicy(&late); err != nil {
+			return nil, nil, err
+		}
+		assign.LatePolicy = &late
+	}
+	if form.CooldownMinutes < 0 {
+		return nil, nil, errors.ErrorInvalidCooldown
+	}
+	if form.Quiz {
+		// its questions are chosen afterwards
+		assign.Quiz = &Quiz{}
+	}
+	if form.LeaderboardMetric != "" {
+		assign.Leaderboard = &Leaderboard{
+			Metric:        form.LeaderboardMetric,
+			LowerIsBetter: form.LeaderboardLowerIsBetter,
+			OptOuts:       make([]primitive.ObjectID, 0),
+		}
+	}
+
+	_, errs := a.col.InsertOne(a.ctx, assign, options.InsertOne())
+	if errs != nil {
+		return nil, nil, errors.ErrorDatabaseFailedCreate
+	}
+
+	return &aid, &supportingFiles, nil
+}
+
+func (a *AssignmentInterface) Delete(aid interface{}) errors.APIError {
+	_, err := a.col.DeleteOne(a.ctx, bson.M{"_id": aid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+func (a *AssignmentInterface) Get(aid interface{}) (*MongoAssignment, errors.APIError) {
+	var assign *MongoAssignment
+	res := a.col.FindOne(a.ctx, bson.M{"_id": aid}, options.FindOne())
+
+	err := res.Decode(&assign)
+	if err != nil {
+		return nil, errors.ErrorInvalidBSON
+	}
+
+	return assign, nil
+}
+
+// Update saves an assignment's settings, announcing it when this publishes it.
+func (a *AssignmentInterface) Update(assign MongoAssignment) errors.APIError {
+	var before struct {
+		Published bool `bson:"published"`
+	}
+	err := a.col.FindOneAndUpdate(
+		a.ctx,
+		bson.M{
+			"_id": assign.ID,
+		},
+		bson.M{
+			"$set": bson.M{
+				"language":     assign.Language,
+				"version":      assign.Version,
+				"name":         assign.Name,
+				"description":  assign.Description,
+				"dueDate":      assign.DueDate,
+				"published":    assign.Published,
+				"testBuildCMD": assign.TestBuildCMD,
+				"tests":        assign.Tests,
+				"numAttempts":  assign.NumAttempts,
+				"timeLimit":    assign.TimeLimit,
+				"attestation":  assign.Attestation,
+				"leaderboard":  assign.Leaderboard,
+				"precheck":     assign.Precheck,
+				"extraCredit":  assign.ExtraCredit,
+				"requirements": assign.Requirements,
+
+				"showHiddenSummary": assign.ShowHiddenSummary,
+				"milestones":        assign.Milestones,
+				"artifactSlots":     assign.ArtifactSlots,
+				"rubric":            assign.Rubric,
+				"cooldownMinutes":   assign.CooldownMinutes,
+				"latePolicy":        assign.LatePolicy,
+			},
+		},
+		options.FindOneAndUpdate().SetProjection(bson.M{"published": 1}),
+	).Decode(&before)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	if assign.Published && !before.Published {
+		events.Publish(events.AssignmentPublishedEvent, events.AssignmentPublished{
+			AssignmentID: assign.ID,
+			Name:         assign.Name,
+			DueDate:      assign.DueDate,
+		})
+	}
+
+	return nil
+}
+
+// UpdateMany saves the due date, publication and limits of assignments in one
+// transaction, so either all of them change or none does, and announces those
+// it publishes. Transactions need mongo to run as a replica set.
+func (a *AssignmentInterface) UpdateMany(assigns []MongoAssignment) errors.APIError {
+	sess, err := a.col.Database().Client().StartSession()
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+	defer sess.EndSession(a.ctx)
+
+	published := make([]MongoAssignment, 0)
+	err = mongo.WithSession(a.ctx, sess, func(sc mongo.SessionContext) error {
+		if err := sess.StartTransaction(); err != nil {
+			return err
+		}
+
+		for _, assign := range assigns {
+			var before struct {
+				Published bool `bson:"published"`
+			}
+			err := a.col.FindOneAndUpdate(
+				sc,
+				bson.M{"_id": assign.ID},
+				bson.M{
+					"$set": bson.M{
+						"dueDate":     assign.DueDate,
+						"published":   assign.Published,
+						"numAttempts": assign.NumAttempts,
+						"timeLimit":   assign.TimeLimit,
+					},
+				},
+				options.FindOneAndUpdate().SetProjection(bson.M{"published": 1}),
+			).Decode(&before)
+			if err != nil {
+				sess.AbortTransaction(sc)
+				return err
+			}
+
+			if assign.Published && !before.Published {
+				published = append(published, assign)
+			}
+		}
+
+		return sess.CommitTransaction(sc)
+	})
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	for _, assign := range published {
+		events.Publish(events.AssignmentPublishedEvent, events.AssignmentPublished{
+			AssignmentID: assign.ID,
+			Name:         assign.Name,
+			DueDate:      assign.DueDate,
+		})
+	}
+
+	return nil
+}
+
+func (a *AssignmentInterface) GetAsFile(aid interface{}) (*MongoAssignment, errors.APIError) {
+	var assign *MongoAssignment
+	res := a.col.FindOne(a.ctx, bson.M{"_id": aid}, options.FindOne())
+
+	err := res.Decode(&assign)
+	if err != nil {
+		return nil, errors.ErrorInvalidBSON
+	}
+
+	return assign, nil
+}
+
+// GetFullForStudent returns a published assignment with the student's
+// submissions, nil when it is not published.
+func (a *AssignmentInterface) GetFullForStudent(aid, uid interface{}) (*StudentAssignmentView, errors.APIError) {
+	query := []interface{}{
+		bson.M{"$match": bson.M{"_id": aid}},
+		bson.M{
+			"$lookup": bson.M{
+				"from":         "submissions",
+				"localField":   "submissions.submissionID",
+				"foreignField": "_id",
+				"as":           "submissions",
+			},
+		},
+	}
+
+	project := viewProjection()
+	project["submissions"] = bson.M{
+		"$filter": bson.M{
+			"input": "$submissions",
+			"as":    "submission",
+			"cond":  bson.M{"$eq": bson.A{"$$submission.userID", uid.(primitive.ObjectID)}},
+		},
+	}
+	project["tests"] = studentFacingTests()
+	query = append(query, bson.M{"$project": project}, bson.M{
+		"$match": bson.M{
+			"$expr": bson.M{"$eq": bson.A{"$published", true}}},
+	})
+
+	var assign *StudentAssignmentView
+	if err := a.aggregateOne(query, &assign); err != nil {
+		return nil, err
+	}
+
+	if assign != nil {
+		assign.RenderedDescription = utils.RenderMarkdown(assign.Description)
+		assign.redact()
+	}
+
+	return assign, nil
+}
+
+// redact removes the hidden tests and their results from what the student is
+// sent.
+func (v *StudentAssignmentView) redact() {
+	for index := range v.Submissions {
+		v.Submissions[index].RedactFor(utils.ViewStudent, v.ShowHiddenSummary)
+	}
+	utils.Redact(v, utils.ViewStudent)
+}
+
+// GetFullForStaff returns an assignment with the submissions of the students
+// the query asks for, in its order.
+func (a *AssignmentInterface) GetFullForStaff(aid interface{}, q StaffSubmissionsQuery) (*StaffAssignmentView, errors.APIError) {
+	match := bson.M{"$expr": bson.M{"$eq": bson.A{"$$ass", "$assignmentID"}}}
+	if !q.IncludeWithdrawn {
+		match["withdrawn"] = bson.M{"$ne": true}
+	}
+
+	// every student with the status of their latest submission, without the
+	// test outputs and logs, which only the submission's own page shows
+	students := bson.A{
+		bson.M{"$match": match},
+		bson.M{"$project": bson.M{
+			"results.output": 0,
+			"results.html":   0,
+			"results.stderr": 0,
+			"buildOutput":    0,
+			"shareLinks":     0,
+		}},
+		bson.M{"$sort": bson.M{"submissionDate": 1}},
+		bson.M{"$group": bson.M{"_id": "$userID", "submissions": bson.M{"$push": "$$ROOT"}}},
+		bson.M{"$addFields": bson.M{"latest": bson.M{"$arrayElemAt": bson.A{"$submissions", -1}}}},
+		bson.M{
+			"$addFields": bson.M{
+				"status":      latestStatus(),
+				"score":       latestScore(),
+				"submittedAt": "$latest.submissionDate",
+			},
+		},
+		bson.M{"$project": bson.M{"latest": 0}},
+	}
+
+	// the page asked for, only looking up the students on it unless they are
+	// sorted by name
+	page := bson.A{}
+	switch q.Status {
+	case "":
+	case SubmissionsUngraded:
+		page = append(page, bson.M{"$match": bson.M{"status": bson.M{"$ne": SubmissionsGraded}}})
+	default:
+		page = append(page, bson.M{"$match": bson.M{"status": q.Status}})
+	}
+
+	lookupStudent := bson.M{
+		"$lookup": bson.M{
+			"from":         "users",
+			"localField":   "_id",
+			"foreignField": "_id",
+			"as":           "student",
+		},
+	}
+	byName := q.Sort == "" || q.Sort == SortByName
+	if byName {
+		page = append(page, lookupStudent, bson.M{"$addFields": bson.M{"student": bson.M{"$arrayElemAt": bson.A{"$student", 0}}}})
+	}
+	page = append(page, bson.M{"$sort": q.sort()})
+	if q.Skip > 0 {
+		page = append(page, bson.M{"$skip": q.Skip})
+	}
+	if q.Limit > 0 {
+		page = append(page, bson.M{"$limit": q.Limit})
+	}
+	if !byName {
+		page = append(page, lookupStudent, bson.M{"$addFields": bson.M{"student": bson.M{"$arrayElemAt": bson.A{"$student", 0}}}})
+	}
+	page = append(page, bson.M{
+		"$project": bson.M{
+			"_id":               0,
+			"submissions":       1,
+			"status":            1,
+			"score":             1,
+			"submittedAt":       1,
+			"student.email":     1,
+			"student.firstName": 1,
+			"student.lastName":  1,
+		},
+	})
+
+	students = append(students, bson.M{
+		"$facet": bson.M{
+			"page":   page,
+			"counts": bson.A{bson.M{"$group": bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}}},
+		},
+	})
+
+	project := viewProjection()
+	project["studentSubmissions"] = bson.M{"$arrayElemAt": bson.A{"$studentSubmissions.page", 0}}
+	project["statusCounts"] = bson.M{"$arrayElemAt": bson.A{"$studentSubmissions.counts", 0}}
+	query := []interface{}{
+		bson.M{"$match": bson.M{"_id": aid}},
+		bson.M{
+			"$lookup": bson.M{
+				"from":     "submissions",
+				"let":      bson.M{"ass": "$_id"},
+				"as":       "studentSubmissions",
+				"pipeline": students,
+			},
+		},
+		bson.M{"$project": project},
+	}
+
+	var assign *StaffAssignmentView
+	if err := a.aggregateOne(query, &assign); err != nil {
+		return nil, err
+	}
+
+	if assign != nil {
+		assign.RenderedDescription = utils.RenderMarkdown(assign.Description)
+		assign.Counts = countStatuses(assign.StatusCounts, q.Status)
+	}
+
+	return assign, nil
+}
+
+// countStatuses totals how many students' latest submission has each status,
+// and how many have the one asked for.
+func countStatuses(counts []StatusCount, status string) SubmissionCounts {
+	var totals SubmissionCounts
+	for _, count := range counts {
+		totals.Students += count.Count
+		switch count.Status {
+		case SubmissionsGraded:
+			totals.Graded += count.Count
+		case SubmissionsError:
+			totals.Error += count.Count
+		case SubmissionsInProgress:
+			totals.InProgress += count.Count
+		}
+	}
+	totals.Ungraded = totals.Error + totals.InProgress
+
+	switch status {
+	case "":
+		totals.Matching = totals.Students
+	case SubmissionsGraded:
+		totals.Matching = totals.Graded
+	case SubmissionsError:
+		totals.Matching = totals.Error
+	case SubmissionsInProgress:
+		totals.Matching = totals.InProgress
+	case SubmissionsUngraded:
+		totals.Matching = totals.Ungraded
+	}
+
+	return totals
+}
+
+// NewStaffSubmissionsQuery checks the status and sort staff asked for. Sorts
+// starting with - are descending.
+func NewStaffSubmissionsQuery(status, sort string, includeWithdrawn bool, skip, limit int64) (StaffSubmissionsQuery, errors.APIError) {
+	q := StaffSubmissionsQuery{
+		IncludeWithdrawn: includeWithdrawn,
+		Status:           status,
+		Sort:             strings.TrimPrefix(sort, "-"),
+		Descending:       strings.HasPrefix(sort, "-"),
+		Skip:             skip,
+		Limit:            limit,
+	}
+
+	switch q.Status {
+	case "", SubmissionsGraded, SubmissionsError, SubmissionsInProgress, SubmissionsUngraded:
+	default:
+		return q, errors.ErrorInvalidQuery
+	}
+
+	switch q.Sort {
+	case "", SortByName, SortByScore, SortByDate:
+	default:
+		return q, errors.ErrorInvalidQuery
+	}
+
+	return q, nil
+}
+
+// sort the $sort of the query's order. Ties are broken by the student's id
+// so pages do not overlap.
+func (q StaffSubmissionsQuery) sort() bson.D {
+	direction := 1
+	if q.Descending {
+		direction = -1
+	}
+
+	switch q.Sort {
+	case SortByScore:
+		return bson.D{{"score", direction}, {"_id", 1}}
+	case SortByDate:
+		return bson.D{{"submittedAt", direction}, {"_id", 1}}
+	}
+
+	return bson.D{{"student.lastName", direction}, {"student.firstName", direction}, {"_id", 1}}
+}
+
+// latestStatus an aggregation expression for how grading of $latest, a
+// student's latest submission, went.
+func latestStatus() bson.M {
+	return bson.M{
+		"$switch": bson.M{
+			"branches": bson.A{
+				bson.M{"case": bson.M{"$eq": bson.A{"$latest.inProgress", true}}, "then": SubmissionsInProgress},
+				bson.M{"case": bson.M{"$eq": bson.A{"$latest.errorTesting", true}}, "then": SubmissionsError},
+			},
+			"default": SubmissionsGraded,
+		},
+	}
+}
+
+// latestScore an aggregation expression for the score of $latest, as Score
+// computes it.
+func latestScore() bson.M {
+	results := bson.M{"$ifNull": bson.A{"$latest.results", bson.A{}}}
+	passed := bson.M{
+		"$size": bson.M{
+			"$filter": bson.M{
+				"input": results,
+				"as":    "result",
+				"cond":  bson.M{"$eq": bson.A{"$$result.passed", true}},
+			},
+		},
+	}
+
+	return bson.M{
+		"$cond": bson.A{
+			bson.M{"$ifNull": bson.A{"$latest.gradeOverride", false}},
+			"$latest.gradeOverride.grade",
+			bson.M{
+				"$cond": bson.A{
+					bson.M{"$eq": bson.A{bson.M{"$size": results}, 0}},
+					0,
+					bson.M{"$multiply": bson.A{100, bson.M{"$divide": bson.A{passed, bson.M{"$size": results}}}}},
+				},
+			},
+		},
+	}
+}
+
+// studentFacingTests projects the tests students are shown.
+func studentFacingTests() bson.M {
+	return bson.M{
+		"$filter": bson.M{
+			"input": bson.M{"$ifNull": bson.A{"$tests", bson.A{}}},
+			"as":    "test",
+			"cond":  bson.M{"$eq": bson.A{"$$test.studentFacing", true}},
+		},
+	}
+}
+
+// StudentProjection projects what students may see of an assignment, for
+// pipelines that decode it into a map, where the json and view tags of a
+// StudentAssignmentView do not apply: only the tests they are shown, without
+// their variants, and milestones without the tests they are graded on.
+func StudentProjection() bson.M {
+	project := viewProjection()
+	project["tests"] = bson.M{
+		"$map": bson.M{
+			"input": studentFacingTests(),
+			"as":    "test",
+			"in": bson.M{
+				"name":                  "$$test.name",
+				"expectedOutput":        "$$test.expectedOutput",
+				"studentFacing":         "$$test.studentFacing",
+				"testCMD":               "$$test.testCMD",
+				"inputFixture":          "$$test.inputFixture",
+				"expectedOutputFixture": "$$test.expectedOutputFixture",
+				"fixtures":              "$$test.fixtures",
+				"match":                 "$$test.match",
+				"epsilon":               "$$test.epsilon",
+			},
+		},
+	}
+	project["milestones"] = bson.M{
+		"$map": bson.M{
+			"input": bson.M{"$ifNull": bson.A{"$milestones", bson.A{}}},
+			"as":    "milestone",
+			"in": bson.M{
+				"name":    "$$milestone.name",
+				"dueDate": "$$milestone.dueDate",
+				"weight":  "$$milestone.weight",
+			},
+		},
+	}
+
+	return bson.M{"$project": project}
+}
+
+// StaffProjection leaves out the secrets and GitHub tokens of an
+// assignment's repository links, for pipelines that decode it into a map.
+func StaffProjection() bson.M {
+	return bson.M{"$project": bson.M{"repositoryLinks.secret": 0, "repositoryLinks.statusToken": 0}}
+}
+
+// viewProjection projects the fields of an AssignmentView.
+func viewProjection() bson.M {
+	return bson.M{
+		"_id":                       1,
+		"language":                  1,
+		"version":                   1,
+		"name":                      1,
+		"numAttempts":               1,
+		"description":               1,
+		"supportingFiles":           1,
+		"dueDate":                   1,
+		"published":                 1,
+		"testBuildCMD":              1,
+		"tests":                     1,
+		"attachments":               1,
+		"timeLimit":                 1,
+		"attestation":               1,
+		"leaderboard.metric":        1,
+		"leaderboard.lowerIsBetter": 1,
+		"showHiddenSummary":         1,
+		"prerequisites":             1,
+		"milestones":                1,
+		"artifactSlots":             1,
+		"manual":                    1,
+		"rubric":                    1,
+		"quiz.size":                 1,
+	}
+}
+
+// aggregateOne decodes the last document the query returns into out, leaving
+// it untouched when there is none.
+func (a *AssignmentInterface) aggregateOne(query []interface{}, out interface{}) errors.APIError {
+	cur, err := a.col.Aggregate(a.ctx, query, options.Aggregate())
+	if err != nil {
+		return errors.ErrorInvalidBSON
+	}
+
+	for cur.Next(a.ctx) {
+		if err = cur.Decode(out); err != nil {
+			return errors.ErrorResourceNotFound
+		}
+	}
+
+	return nil
+}
+
+func (a *AssignmentInterface) LatestUserSubmission(aid, uid interface{}) (*MongoAssignment, int, errors.APIError) {
+	assignment, err := a.Get(aid)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	attempt := 0
+	for _, assignSub := range assignment.Submissions {
+		if assignSub.UserID == uid.(primitive.ObjectID) && assignSub.AttemptNumber > attempt {
+			attempt = assignSub.AttemptNumber
+		}
+	}
+
+	return assignment, attempt, nil
+}
+
+func (a *AssignmentInterface) InsertSubmission(aid, uid, sid interface{}, attempt int) errors.APIError {
+	insert := AssignmentSubmission{
+		UserID:        uid.(primitive.ObjectID),
+		SubmissionID:  sid.(primitive.ObjectID),
+		AttemptNumber: attempt,
+	}
+
+	_, err := a.col.UpdateOne(
+		a.ctx,
+		bson.M{"_id": aid},
+		bson.M{"$push": bson.M{"submissions": &insert}},
+		options.Update(),
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+func (a *AssignmentInterface) DeleteSubmission(aid, sid interface{}) errors.APIError {
+	_, err := a.col.UpdateOne(
+		a.ctx,
+		bson.M{"_id": aid},
+		bson.M{"$pull": bson.M{"submissions": bson.M{"submissionID": sid.(primitive.ObjectID)}}},
+		options.Update(),
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// Start records when a student began a timed assignment, keeping the first
+// start if they already began it.
+func (a *AssignmentInterface) Start(aid, uid interface{}, startedAt time.Time) errors.APIError {
+	start := Start{
+		UserID:    uid.(primitive.ObjectID),
+		StartedAt: utils.TimeToDateTime(startedAt),
+	}
+
+	_, err := a.col.UpdateOne(
+		a.ctx,
+		bson.M{"_id": aid, "starts.userID": bson.M{"$ne": uid}},
+		bson.M{"$push": bson.M{"starts": &start}},
+		options.Update(),
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// SetAccommodation gives a student extra minutes on a timed assignment,
+// replacing any accommodation they already had.
+func (a *AssignmentInterface) SetAccommodation(aid, uid interface{}, extraMinutes int) errors.APIError {
+	res, err := a.col.UpdateOne(
+		a.ctx,
+		bson.M{"_id": aid, "accommodations.userID": uid},
+		bson.M{"$set": bson.M{"accommodations.$.extraMinutes": extraMinutes}},
+		options.Update(),
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	if res.MatchedCount > 0 {
+		return nil
 	}
 
-	// MongoAssignment struct to store information about an assignment.
-	MongoAssignment struct {
-		ID              primitive.ObjectID     `bson:"_id" form:"id" json:"id"`
-		Language        string                 `bson:"language" form:"language" binding:"required" json:"language"`
-		Version         string                 `bson:"version" form:"version" binding:"required" json:"version"`
-		Name            string                 `bson:"name" form:"name" binding:"required" json:"name"`
-		NumAttempts     int                    `bson:"numAttempts" form:"numAttempts" binding:"required" json:"numAttempts"`
-		Description     string                 `bson:"description" form:"description" binding:"required" json:"description"`
-		DueDate         primitive.DateTime     `bson:"dueDate" form:"dueDate" binding:"required" json:"dueDate"`
-		Published       bool                   `bson:"published" form:"published" binding:"required" json:"-"`
-		SupportingFiles primitive.ObjectID     `bson:"supportingFiles" form:"supportingFiles" json:"supportingFiles"`
-		TestBuildCMD    string                 `bson:"testBuildCMD" form:"testBuildCMD" json:"testBuildCMD"`
-		Tests           []Test                 `bson:"tests" form:"tests" binding:"required" json:"tests"`
-		Submissions     []AssignmentSubmission `bson:"submissions" form:"submissions" json:"submissions"`
+	accommodation := Accommodation{
+		UserID:       uid.(primitive.ObjectID),
+		ExtraMinutes: extraMinutes,
 	}
 
-	AssignmentInterface struct {
-		ctx context.Context
-		col *mongo.Collection
+	_, err = a.col.UpdateOne(
+		a.ctx,
+		bson.M{"_id": aid},
+		bson.M{"$push": bson.M{"accommodations": &accommodation}},
+		options.Update(),
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
 	}
-)
 
-func New() *AssignmentInterface {
-	db, _ := tyrgin.GetMongoDB(os.Getenv("DB_NAME"))
-	col := tyrgin.GetMongoCollection("assignments", db)
+	return nil
+}
 
-	return &AssignmentInterface{
-		context.Background(),
-		col,
+// SetExtension gives a student a later due date, replacing any extension
+// they already had.
+func (a *AssignmentInterface) SetExtension(aid interface{}, extension Extension) errors.APIError {
+	res, err := a.col.UpdateOne(
+		a.ctx,
+		bson.M{"_id": aid, "extensions.userID": extension.UserID},
+		bson.M{"$set": bson.M{"extensions.$": &extension}},
+		options.Update(),
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
 	}
-}
 
-func (a *AssignmentInterface) Create(form forms.CreateAssignmentPostForm, cid string) (*primitive.ObjectID, *primitive.ObjectID, errors.APIError) {
-	tests := make([]Test, len(form.Tests))
-	for index := range form.Tests {
-		tests[index] = Test(form.Tests[index])
+	if res.MatchedCount > 0 {
+		return nil
 	}
 
-	aid := primitive.NewObjectID()
-	supportingFiles := primitive.NewObjectID()
-	assign := MongoAssignment{
-		ID:              aid,
-		Language:        form.Language,
-		Version:         form.Version,
-		Name:            form.Name,
-		NumAttempts:     form.NumAttempts,
-		Description:     form.Description,
-		SupportingFiles: supportingFiles,
-		DueDate:         form.DueDate,
-		Published:       false,
-		TestBuildCMD:    form.TestBuildCMD,
-		Tests:           tests,
-		Submissions:     make([]AssignmentSubmission, 0),
+	_, err = a.col.UpdateOne(
+		a.ctx,
+		bson.M{"_id": aid},
+		bson.M{"$push": bson.M{"extensions": &extension}},
+		options.Update(),
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
 	}
 
-	_, err := a.col.InsertOne(a.ctx, assign, options.InsertOne())
+	return nil
+}
+
+// LinkRepository registers a student's repository for automatic submission,
+// replacing the one they had linked to the assignment.
+func (a *AssignmentInterface) LinkRepository(aid interface{}, link RepositoryLink) errors.APIError {
+	if err := a.UnlinkRepository(aid, link.UserID); err != nil {
+		return err
+	}
+
+	_, err := a.col.UpdateOne(
+		a.ctx,
+		bson.M{"_id": aid},
+		bson.M{"$push": bson.M{"repositoryLinks": &link}},
+		options.Update(),
+	)
 	if err != nil {
-		return nil, nil, errors.ErrorDatabaseFailedCreate
+		return errors.ErrorDatabaseFailedUpdate
 	}
 
-	return &aid, &supportingFiles, nil
+	return nil
 }
 
-func (a *AssignmentInterface) Delete(aid interface{}) errors.APIError {
-	_, err := a.col.DeleteOne(a.ctx, bson.M{"_id": aid}, options.Delete())
+// UnlinkRepository removes a student's linked repository.
+func (a *AssignmentInterface) UnlinkRepository(aid, uid interface{}) errors.APIError {
+	_, err := a.col.UpdateOne(
+		a.ctx,
+		bson.M{"_id": aid},
+		bson.M{"$pull": bson.M{"repositoryLinks": bson.M{"userID": uid}}},
+		options.Update(),
+	)
 	if err != nil {
-		return errors.ErrorDatabaseFailedDelete
+		return errors.ErrorDatabaseFailedUpdate
 	}
 
 	return nil
 }
 
-func (a *AssignmentInterface) Get(aid interface{}) (*MongoAssignment, errors.APIError) {
+// FindRepositoryLink returns a linked repository and its assignment.
+func (a *AssignmentInterface) FindRepositoryLink(lid interface{}) (*MongoAssignment, *RepositoryLink, errors.APIError) {
 	var assign *MongoAssignment
-	res := a.col.FindOne(a.ctx, bson.M{"_id": aid}, options.FindOne())
+	res := a.col.FindOne(a.ctx, bson.M{"repositoryLinks._id": lid}, options.FindOne())
 
 	err := res.Decode(&assign)
 	if err != nil {
-		return nil, errors.ErrorInvalidBSON
+		return nil, nil, errors.ErrorResourceNotFound
 	}
 
-	return assign, nil
+	for index := range assign.RepositoryLinks {
+		if assign.RepositoryLinks[index].ID == lid.(primitive.ObjectID) {
+			return assign, &assign.RepositoryLinks[index], nil
+		}
+	}
+
+	return nil, nil, errors.ErrorResourceNotFound
 }
 
-func (a *AssignmentInterface) Update(assign MongoAssignment) errors.APIError {
-	_, err := a.col.UpdateOne(
+// RecordRepositoryDelivery remembers a webhook delivery of a linked
+// repository, false when it already was, as GitHub redelivers failed
+// deliveries with the same id.
+func (a *AssignmentInterface) RecordRepositoryDelivery(lid interface{}, delivery string) (bool, errors.APIError) {
+	res, err := a.col.UpdateOne(
 		a.ctx,
+		bson.M{"repositoryLinks": bson.M{"$elemMatch": bson.M{"_id": lid, "deliveries": bson.M{"$ne": delivery}}}},
 		bson.M{
-			"_id": assign.ID,
-		},
-		bson.M{
-			"$set": bson.M{
-				"language":     assign.Language,
-				"version":      assign.Version,
-				"name":         assign.Name,
-				"description":  assign.Description,
-				"dueDate":      assign.DueDate,
-				"published":    assign.Published,
-				"testBuildCMD": assign.TestBuildCMD,
-				"tests":        assign.Tests,
-				"numAttempts":  assign.NumAttempts,
+			"$push": bson.M{
+				"repositoryLinks.$.deliveries": bson.M{"$each": bson.A{delivery}, "$slice": -repositoryDeliveries},
 			},
 		},
+		options.Update(),
+	)
+	if err != nil {
+		return false, errors.ErrorDatabaseFailedUpdate
+	}
+
+	return res.ModifiedCount == 1, nil
+}
+
+// SetLeaderboardOptOut leaves a student off, or puts them back on, the
+// assignment's leaderboard.
+func (a *AssignmentInterface) SetLeaderboardOptOut(aid, uid interface{}, optOut bool) errors.APIError {
+	update := bson.M{"$pull": bson.M{"leaderboard.optOuts": uid}}
+	if optOut {
+		update = bson.M{"$addToSet": bson.M{"leaderboard.optOuts": uid}}
+	}
+
+	res, err := a.col.UpdateOne(
+		a.ctx,
+		bson.M{"_id": aid, "leaderboard": bson.M{"$ne": nil}},
+		update,
+		options.Update(),
 	)
 	if err != nil {
 		return errors.ErrorDatabaseFailedUpdate
 	}
 
+	if res.MatchedCount == 0 {
+		return errors.ErrorNoLeaderboard
+	}
+
 	return nil
 }
 
-func (a *AssignmentInterface) GetAsFile(aid interface{}) (*MongoAssignment, errors.APIError) {
-	var assign *MongoAssignment
-	res := a.col.FindOne(a.ctx, bson.M{"_id": aid}, options.FindOne())
+// SetQuiz makes an assignment a quiz, or code again for nil.
+func (a *AssignmentInterface) SetQuiz(aid interface{}, quiz *Quiz) errors.APIError {
+	update := bson.M{"$set": bson.M{"quiz": quiz}}
+	if quiz == nil {
+		update = bson.M{"$unset": bson.M{"quiz": ""}}
+	}
 
-	err := res.Decode(&assign)
+	_, err := a.col.UpdateOne(a.ctx, bson.M{"_id": aid}, update, options.Update())
 	if err != nil {
-		return nil, errors.ErrorInvalidBSON
+		return errors.ErrorDatabaseFailedUpdate
 	}
 
-	return assign, nil
+	return nil
 }
 
-func (a *AssignmentInterface) GetFull(aid, uid interface{}, role string) (map[string]interface{}, errors.APIError) {
-	query := []interface{}{
-		bson.M{"$match": bson.M{"_id": aid}},
+// SetPrerequisites replaces an assignment's prerequisites.
+func (a *AssignmentInterface) SetPrerequisites(aid interface{}, prerequisites []Prerequisite) errors.APIError {
+	_, err := a.col.UpdateOne(
+		a.ctx,
+		bson.M{"_id": aid},
+		bson.M{"$set": bson.M{"prerequisites": prerequisites}},
+		options.Update(),
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
 	}
 
-	if role == "student" {
-		query = append(query, bson.M{
-			"$lookup": bson.M{
-				"from":         "submissions",
-				"localField":   "submissions.submissionID",
-				"foreignField": "_id",
-				"as":           "submissions",
-			},
-		})
-	} else {
-		query = append(query, bson.M{
-			"$lookup": bson.M{
-				"from": "submissions",
-				"let":  bson.M{"ass": "$_id"},
-				"as":   "studentSubmissions",
-				"pipeline": bson.A{
-					bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$$ass", "$assignmentID"}}}},
-					bson.M{"$sort": bson.M{"submissionDate": 1}},
-					bson.M{"$group": bson.M{"_id": "$userID", "submissions": bson.M{"$push": "$$ROOT"}}},
-					bson.M{
-						"$lookup": bson.M{
-							"from":         "users",
-							"localField":   "_id",
-							"foreignField": "_id",
-							"as":           "student",
-						},
-					},
-					bson.M{
-						"$project": bson.M{
-							"_id":         1,
-							"submissions": 1,
-							"student":     bson.M{"$arrayElemAt": bson.A{"$student", 0}},
-						},
-					},
-					bson.M{
-						"$project": bson.M{
-							"_id":               0,
-							"submissions":       1,
-							"student.email":     1,
-							"student.firstName": 1,
-							"student.lastName":  1,
-						},
-					},
-				},
-			},
-		})
-	}
+	return nil
+}
 
-	project := bson.M{
-		"$project": bson.M{
-			"_id":             1,
-			"language":        1,
-			"version":         1,
-			"name":            1,
-			"numAttempts":     1,
-			"description":     1,
-			"supportingFiles": 1,
-			"dueDate":         1,
-			"published":       1,
-			"testBuildCMD":    1,
-			"tests":           1,
-		},
+// WithPrerequisites the assignments of aids that have prerequisites, only
+// their ids and prerequisites.
+func (a *AssignmentInterface) WithPrerequisites(aids []primitive.ObjectID) ([]MongoAssignment, errors.APIError) {
+	assignments := make([]MongoAssignment, 0)
+	cur, err := a.col.Find(
+		a.ctx,
+		bson.M{"_id": bson.M{"$in": aids}, "prerequisites.0": bson.M{"$exists": true}},
+		options.Find().SetProjection(bson.M{"prerequisites": 1}),
+	)
+	if err != nil {
+		return assignments, errors.ErrorDatabaseFailedQuery
 	}
 
-	if role == "student" {
-		project["$project"].(primitive.M)["submissions"] = bson.M{
-			"$filter": bson.M{
-				"input": "$submissions",
-				"as":    "submission",
-				"cond":  bson.M{"$eq": bson.A{"$$submission.userID", uid.(primitive.ObjectID)}},
-			},
-		}
-		project["$project"].(primitive.M)["tests"] = bson.M{
-			"$filter": bson.M{
-				"input": "$tests",
-				"as":    "test",
-				"cond":  "$$test.studentFacing",
-			},
+	for cur.Next(a.ctx) {
+		var assign MongoAssignment
+		err = cur.Decode(&assign)
+		if err != nil {
+			return assignments, errors.ErrorInvalidBSON
 		}
-		query = append(query, project, bson.M{
-			"$match": bson.M{
-				"$expr": bson.M{"$eq": bson.A{"$published", true}}},
-		})
-	} else {
-		project["$project"].(primitive.M)["studentSubmissions"] = 1
-		query = append(query, project)
+
+		assignments = append(assignments, assign)
 	}
 
-	var assign map[string]interface{}
-	cur, err := a.col.Aggregate(a.ctx, query, options.Aggregate())
+	return assignments, nil
+}
+
+// SetReferenceSolution stores, or with nil removes, an assignment's reference
+// solution.
+func (a *AssignmentInterface) SetReferenceSolution(aid interface{}, reference *ReferenceSolution) errors.APIError {
+	update := bson.M{"$unset": bson.M{"referenceSolution": ""}}
+	if reference != nil {
+		update = bson.M{"$set": bson.M{"referenceSolution": reference}}
+	}
+
+	_, err := a.col.UpdateOne(a.ctx, bson.M{"_id": aid}, update, options.Update())
 	if err != nil {
-		return nil, errors.ErrorInvalidBSON
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// RecordSelfCheck stores the outcome of grading a reference solution, unless
+// the solution was replaced while it was being graded.
+func (a *AssignmentInterface) RecordSelfCheck(aid, fid interface{}, check SelfCheck) errors.APIError {
+	_, err := a.col.UpdateOne(
+		a.ctx,
+		bson.M{"_id": aid, "referenceSolution.fileID": fid},
+		bson.M{"$set": bson.M{"referenceSolution.lastCheck": &check}},
+		options.Update(),
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// WithReferenceSolutions returns every assignment that has a reference
+// solution.
+func (a *AssignmentInterface) WithReferenceSolutions() ([]MongoAssignment, errors.APIError) {
+	assignments := make([]MongoAssignment, 0)
+	cur, err := a.col.Find(a.ctx, bson.M{"referenceSolution": bson.M{"$exists": true}}, options.Find())
+	if err != nil {
+		return assignments, errors.ErrorDatabaseFailedQuery
 	}
 
 	for cur.Next(a.ctx) {
+		var assign MongoAssignment
 		err = cur.Decode(&assign)
 		if err != nil {
-			return nil, errors.ErrorResourceNotFound
+			return assignments, errors.ErrorInvalidBSON
 		}
+
+		assignments = append(assignments, assign)
 	}
 
-	return assign, nil
+	return assignments, nil
 }
 
-func (a *AssignmentInterface) LatestUserSubmission(aid, uid interface{}) (*MongoAssignment, int, errors.APIError) {
-	assignment, err := a.Get(aid)
+// AddFixture adds a fixture to an assignment.
+func (a *AssignmentInterface) AddFixture(aid interface{}, fixture Fixture) errors.APIError {
+	_, err := a.col.UpdateOne(
+		a.ctx,
+		bson.M{"_id": aid},
+		bson.M{"$push": bson.M{"fixtures": &fixture}},
+		options.Update(),
+	)
 	if err != nil {
-		return nil, 0, err
+		return errors.ErrorDatabaseFailedUpdate
 	}
 
-	attempt := 0
-	for _, assignSub := range assignment.Submissions {
-		if assignSub.UserID == uid.(primitive.ObjectID) && assignSub.AttemptNumber > attempt {
-			attempt = assignSub.AttemptNumber
-		}
+	return nil
+}
+
+// RemoveFixture removes a fixture from an assignment.
+func (a *AssignmentInterface) RemoveFixture(aid, fid interface{}) errors.APIError {
+	_, err := a.col.UpdateOne(
+		a.ctx,
+		bson.M{"_id": aid},
+		bson.M{"$pull": bson.M{"fixtures": bson.M{"_id": fid}}},
+		options.Update(),
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
 	}
 
-	return assignment, attempt, nil
+	return nil
 }
 
-func (a *AssignmentInterface) InsertSubmission(aid, uid, sid interface{}, attempt int) errors.APIError {
-	insert := AssignmentSubmission{
-		UserID:        uid.(primitive.ObjectID),
-		SubmissionID:  sid.(primitive.ObjectID),
-		AttemptNumber: attempt,
+func (a *AssignmentInterface) GetAttachment(aid, fid interface{}) (*MongoAssignment, *Attachment, errors.APIError) {
+	assign, err := a.Get(aid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for index := range assign.Attachments {
+		if assign.Attachments[index].ID == fid.(primitive.ObjectID) {
+			return assign, &assign.Attachments[index], nil
+		}
 	}
 
+	return assign, nil, errors.ErrorResourceNotFound
+}
+
+func (a *AssignmentInterface) AddAttachment(aid interface{}, attachment Attachment) errors.APIError {
 	_, err := a.col.UpdateOne(
 		a.ctx,
 		bson.M{"_id": aid},
-		bson.M{"$push": bson.M{"submissions": &insert}},
+		bson.M{"$push": bson.M{"attachments": &attachment}},
 		options.Update(),
 	)
 	if err != nil {
@@ -299,11 +2264,11 @@ func (a *AssignmentInterface) InsertSubmission(aid, uid, sid interface{}, attemp
 	return nil
 }
 
-func (a *AssignmentInterface) DeleteSubmission(aid, sid interface{}) errors.APIError {
+func (a *AssignmentInterface) RemoveAttachment(aid, fid interface{}) errors.APIError {
 	_, err := a.col.UpdateOne(
 		a.ctx,
 		bson.M{"_id": aid},
-		bson.M{"$pull": bson.M{"submissions": bson.M{"submissionID": sid.(primitive.ObjectID)}}},
+		bson.M{"$pull": bson.M{"attachments": bson.M{"_id": fid}}},
 		options.Update(),
 	)
 	if err != nil {
@@ -327,3 +2292,49 @@ func (a *AssignmentInterface) AsFile(aid interface{}) (*bytes.Reader, string, in
 
 	return bytes.NewReader(jsonBytes), assignment.Name, int64(len(jsonBytes)), nil
 }
+
+// SearchResult an assignment matching a search, Score being its relevance.
+type SearchResult struct {
+	ID       primitive.ObjectID `bson:"_id" json:"id"`
+	Name     string             `bson:"name" json:"name"`
+	DueDate  primitive.DateTime `bson:"dueDate" json:"dueDate"`
+	CourseID primitive.ObjectID `bson:"-" json:"courseID"`
+	Score    float64            `bson:"score" json:"score"`
+}
+
+// Search finds assignments by their name and description, every one of staff's
+// and the published ones of students', most relevant first.
+func (a *AssignmentInterface) Search(q string, staff, student []primitive.ObjectID, skip, limit int64) ([]SearchResult, errors.APIError) {
+	results := make([]SearchResult, 0)
+	score := bson.M{"$meta": "textScore"}
+	cur, err := a.col.Find(
+		a.ctx,
+		bson.M{
+			"$text": bson.M{"$search": q},
+			"$or": bson.A{
+				bson.M{"_id": bson.M{"$in": staff}},
+				bson.M{"_id": bson.M{"$in": student}, "published": true},
+			},
+		},
+		options.Find().
+			SetProjection(bson.M{"name": 1, "dueDate": 1, "score": score}).
+			SetSort(bson.M{"score": score}).
+			SetSkip(skip).
+			SetLimit(limit),
+	)
+	if err != nil {
+		return results, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(a.ctx) {
+		var result SearchResult
+		err = cur.Decode(&result)
+		if err != nil {
+			return results, errors.ErrorInvalidBSON
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
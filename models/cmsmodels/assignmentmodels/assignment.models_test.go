@@ -0,0 +1,515 @@
+package assignmentmodels
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/config"
+	"backend/errors"
+	"backend/forms"
+	"backend/forms/cmsforms"
+	"backend/models/cmsmodels/submissionmodels"
+	"backend/utils"
+)
+
+func studentView(summarize bool) *StudentAssignmentView {
+	view := &StudentAssignmentView{
+		AssignmentView: AssignmentView{
+			Tests: []Test{
+				{Name: "greets", StudentFacing: true, Variants: []TestVariant{{TestCMD: "python hello.py alice"}}},
+				{Name: "secret edge case", ExpectedOutput: "HELLO"},
+			},
+			ShowHiddenSummary: summarize,
+		},
+		Submissions: []submissionmodels.MongoSubmission{{
+			Results: []submissionmodels.WorkerResult{
+				{Name: "greets", StudentFacing: true, Passed: true, Stderr: "warning"},
+				{Name: "secret edge case", Passed: true},
+			},
+		}},
+	}
+
+	return view
+}
+
+// GetFullForStudent redacts what it decodes with redact.
+func TestStudentViewHidesHiddenTests(t *testing.T) {
+	view := studentView(false)
+	view.redact()
+
+	if len(view.Tests) != 1 || view.Tests[0].Name != "greets" {
+		t.Errorf("the student was shown tests %+v", view.Tests)
+	}
+	if view.Tests[0].Variants != nil {
+		t.Errorf("the student was shown every variant of a test")
+	}
+
+	results := view.Submissions[0].Results
+	if len(results) != 1 || results[0].Name != "greets" || results[0].Stderr != "" {
+		t.Errorf("the student was shown results %+v", results)
+	}
+	if view.Submissions[0].Hidden != nil {
+		t.Errorf("the student was told how many hidden tests passed")
+	}
+}
+
+func TestStudentViewSummarizesHiddenTests(t *testing.T) {
+	view := studentView(true)
+	view.redact()
+
+	if len(view.Tests) != 1 || len(view.Submissions[0].Results) != 1 {
+		t.Errorf("hidden tests were shown with summaries on")
+	}
+
+	hidden := view.Submissions[0].Hidden
+	if hidden == nil || *hidden != (submissionmodels.ResultCount{Passed: 1, Total: 1}) {
+		t.Errorf("the student was told %+v of the hidden tests, not 1/1 passed", hidden)
+	}
+}
+
+func TestStaffViewKeepsHiddenTests(t *testing.T) {
+	view := &StaffAssignmentView{
+		AssignmentView: studentView(false).AssignmentView,
+		StudentSubmissions: []StudentSubmissions{{
+			Submissions: studentView(false).Submissions,
+		}},
+	}
+	utils.Redact(view, utils.ViewStaff)
+
+	if len(view.Tests) != 2 || len(view.StudentSubmissions[0].Submissions[0].Results) != 2 {
+		t.Errorf("staff were not shown the hidden tests")
+	}
+}
+
+func readyAssignment(now time.Time) *MongoAssignment {
+	return &MongoAssignment{
+		Language:        "java",
+		Description:     "Say hello.",
+		DueDate:         utils.TimeToDateTime(now.Add(7 * 24 * time.Hour)),
+		SupportingFiles: primitive.NewObjectID(),
+		TestBuildCMD:    "javac Hello.java",
+		Tests: []Test{
+			{Name: "greets", StudentFacing: true},
+			{Name: "secret edge case"},
+		},
+		ReferenceSolution: &ReferenceSolution{LastCheck: &SelfCheck{Passed: true}},
+	}
+}
+
+func readinessCodes(issues []ReadinessIssue) map[string]bool {
+	codes := make(map[string]bool)
+	for _, issue := range issues {
+		codes[issue.Code] = true
+	}
+
+	return codes
+}
+
+// The course dashboard decodes assignments into maps, so nothing but what
+// StudentProjection projects reaches students.
+func TestStudentProjectionLeavesOutStaffFields(t *testing.T) {
+	project := StudentProjection()["$project"].(bson.M)
+
+	for _, field := range []string{"referenceSolution", "repositoryLinks", "starts", "accommodations", "extensions", "submissions"} {
+		if _, found := project[field]; found {
+			t.Errorf("students are sent the assignment's %s", field)
+		}
+	}
+}
+
+func TestReadinessReady(t *testing.T) {
+	now := time.Now()
+	readiness := readyAssignment(now).Readiness(now)
+
+	if !readiness.Ready || len(readiness.Blockers) != 0 || len(readiness.Warnings) != 0 {
+		t.Errorf("a ready assignment was not ready: %+v", readiness)
+	}
+}
+
+func TestReadinessBlockers(t *testing.T) {
+	now := time.Now()
+	for code, breakIt := range map[string]func(*MongoAssignment){
+		ReadinessNoTests:             func(m *MongoAssignment) { m.Tests = nil },
+		ReadinessNoStudentFacingTest: func(m *MongoAssignment) { m.Tests[0].StudentFacing = false },
+		ReadinessNoBuildCommand:      func(m *MongoAssignment) { m.TestBuildCMD = " " },
+		ReadinessDueDatePassed:       func(m *MongoAssignment) { m.DueDate = utils.TimeToDateTime(now.Add(-time.Minute)) },
+		ReadinessNoSupportingFiles:   func(m *MongoAssignment) { m.SupportingFiles = primitive.ObjectID{} },
+		ReadinessReferenceFails: func(m *MongoAssignment) {
+			m.ReferenceSolution.LastCheck = &SelfCheck{Failed: []string{"greets"}}
+		},
+	} {
+		assign := readyAssignment(now)
+		breakIt(assign)
+
+		readiness := assign.Readiness(now)
+		if readiness.Ready || !readinessCodes(readiness.Blockers)[code] {
+			t.Errorf("%s did not block publishing: %+v", code, readiness)
+		}
+	}
+}
+
+func TestReadinessWarnings(t *testing.T) {
+	now := time.Now()
+	assign := readyAssignment(now)
+	assign.Language = "python"
+	assign.TestBuildCMD = ""
+	assign.ReferenceSolution = nil
+	assign.DueDate = utils.TimeToDateTime(now.Add(time.Hour))
+
+	readiness := assign.Readiness(now)
+	codes := readinessCodes(readiness.Warnings)
+	if !readiness.Ready || !codes[ReadinessNoReferenceSolution] || !codes[ReadinessDueSoon] {
+		t.Errorf("warnings blocked publishing or were missed: %+v", readiness)
+	}
+}
+
+func TestUnmetPrerequisites(t *testing.T) {
+	lab1, lab2 := primitive.NewObjectID(), primitive.NewObjectID()
+	assign := &MongoAssignment{Prerequisites: []Prerequisite{{lab1, 70}, {lab2, 0}}}
+
+	unmet := assign.UnmetPrerequisites(map[primitive.ObjectID]float64{lab1: 69.5})
+	if len(unmet) != 2 {
+		t.Errorf("expected both prerequisites unmet, got %+v", unmet)
+	}
+
+	unmet = assign.UnmetPrerequisites(map[primitive.ObjectID]float64{lab1: 70, lab2: 0})
+	if len(unmet) != 0 {
+		t.Errorf("expected every prerequisite met, got %+v", unmet)
+	}
+}
+
+func TestNewPrerequisites(t *testing.T) {
+	lab1, lab2, lab3 := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	course := []primitive.ObjectID{lab1, lab2, lab3}
+	// lab3 already requires lab2
+	existing := []MongoAssignment{{ID: lab3, Prerequisites: []Prerequisite{{lab2, 50}}}}
+
+	requires := func(aid primitive.ObjectID, minScore float64) cmsforms.AssignmentPrerequisite {
+		return cmsforms.AssignmentPrerequisite{AssignmentID: aid, MinScore: minScore}
+	}
+	form := func(prerequisites ...cmsforms.AssignmentPrerequisite) forms.UpdatePrerequisitesForm {
+		return forms.UpdatePrerequisitesForm{Prerequisites: prerequisites}
+	}
+
+	prerequisites, err := NewPrerequisites(lab2, course, existing, form(requires(lab1, 60)))
+	if err != nil || len(prerequisites) != 1 || prerequisites[0].MinScore != 60 {
+		t.Errorf("valid prerequisites were refused: %v %+v", err, prerequisites)
+	}
+
+	for name, invalid := range map[string]forms.UpdatePrerequisitesForm{
+		"itself":       form(requires(lab2, 60)),
+		"other course": form(requires(primitive.NewObjectID(), 60)),
+		"twice":        form(requires(lab1, 60), requires(lab1, 70)),
+		"over 100":     form(requires(lab1, 101)),
+		"cycle":        form(requires(lab3, 60)),
+	} {
+		if _, err := NewPrerequisites(lab2, course, existing, invalid); err == nil {
+			t.Errorf("prerequisites on %s were accepted", name)
+		}
+	}
+}
+
+func TestNewMilestones(t *testing.T) {
+	now := time.Now()
+	assign := readyAssignment(now)
+	week := func(n int) primitive.DateTime {
+		return utils.TimeToDateTime(now.Add(time.Duration(n) * 24 * time.Hour))
+	}
+	assign.DueDate = week(2)
+
+	milestones, err := assign.NewMilestones([]forms.AssignmentMilestoneForm{
+		{Name: "part 2", DueDate: week(2), Tests: []string{"secret edge case"}, Weight: 2},
+		{Name: " part 1 ", DueDate: week(1), Tests: []string{"greets"}, Weight: 1},
+	})
+	if err != nil || len(milestones) != 2 || milestones[0].Name != "part 1" {
+		t.Errorf("valid milestones were refused or left out of order: %v %+v", err, milestones)
+	}
+
+	for name, invalid := range map[string]forms.AssignmentMilestoneForm{
+		"unknown test":     {Name: "part 1", DueDate: week(1), Tests: []string{"missing"}},
+		"no tests":         {Name: "part 1", DueDate: week(1)},
+		"after assignment": {Name: "part 1", DueDate: week(3), Tests: []string{"greets"}},
+		"negative weight":  {Name: "part 1", DueDate: week(1), Tests: []string{"greets"}, Weight: -1},
+		"unnamed":          {Name: " ", DueDate: week(1), Tests: []string{"greets"}},
+	} {
+		if _, err := assign.NewMilestones([]forms.AssignmentMilestoneForm{invalid}); err == nil {
+			t.Errorf("a milestone with %s was accepted", name)
+		}
+	}
+}
+
+func TestMilestoneGrade(t *testing.T) {
+	week := func(n int) primitive.DateTime {
+		return utils.TimeToDateTime(time.Unix(0, 0).Add(time.Duration(n) * 7 * 24 * time.Hour))
+	}
+	assign := &MongoAssignment{Milestones: []Milestone{
+		{Name: "part 1", DueDate: week(1), Tests: []string{"parse"}, Weight: 1},
+		{Name: "part 2", DueDate: week(2), Tests: []string{"parse", "eval"}, Weight: 3},
+	}}
+	result := func(name string, passed bool) submissionmodels.WorkerResult {
+		return submissionmodels.WorkerResult{Name: name, Passed: passed}
+	}
+
+	submissions := []submissionmodels.MongoSubmission{
+		{AttemptNumber: 1, SubmissionDate: week(1) - 1, Results: []submissionmodels.WorkerResult{result("parse", true), result("eval", false)}},
+		{AttemptNumber: 2, SubmissionDate: week(2), Results: []submissionmodels.WorkerResult{result("parse", true), result("eval", true)}},
+		// too late for either milestone
+		{AttemptNumber: 3, SubmissionDate: week(2) + 1},
+	}
+
+	grade, scores := assign.MilestoneGrade(submissions)
+	if scores[0].Score != 100 || scores[0].Attempt != 1 || scores[1].Score != 100 || scores[1].Attempt != 2 || grade != 100 {
+		t.Errorf("unexpected milestone grade %v: %+v", grade, scores)
+	}
+
+	grade, scores = assign.MilestoneGrade(submissions[:1])
+	if scores[1].Score != 50 || grade != (100+3*50)/4.0 {
+		t.Errorf("unexpected milestone grade %v: %+v", grade, scores)
+	}
+
+	submissions[2].GradeOverride = &submissionmodels.GradeOverride{Grade: 42}
+	if grade, _ = assign.MilestoneGrade(submissions); grade != 42 {
+		t.Errorf("the override did not stand for the assignment, got %v", grade)
+	}
+}
+
+func TestNewArtifactSlots(t *testing.T) {
+	slots, err := NewArtifactSlots([]forms.ArtifactSlotForm{
+		{Name: "report", Required: true, Extensions: []string{"PDF", " .md", ""}},
+		{Name: "notes"},
+	})
+	if err != nil || len(slots) != 2 {
+		t.Fatalf("valid slots were refused: %v %+v", err, slots)
+	}
+
+	report := slots[0]
+	if !report.Accepts("Report.Final.pdf") || !report.Accepts("README.md") || report.Accepts("report.docx") {
+		t.Errorf("report slot accepted the wrong files: %+v", report)
+	}
+	if !slots[1].Accepts("anything.bin") {
+		t.Errorf("a slot without extensions should accept any file")
+	}
+
+	for _, name := range []string{"", "Report", "submission slot", "report"} {
+		form := []forms.ArtifactSlotForm{{Name: "report"}, {Name: name}}
+		if _, err := NewArtifactSlots(form); err == nil {
+			t.Errorf("slot named %q was accepted", name)
+		}
+	}
+}
+
+func manualAssignment(now time.Time) *MongoAssignment {
+	return &MongoAssignment{
+		Description:     "Write up the lab.",
+		DueDate:         utils.TimeToDateTime(now.Add(7 * 24 * time.Hour)),
+		SupportingFiles: primitive.NewObjectID(),
+		Manual:          true,
+		Rubric: []RubricCriterion{
+			{ID: primitive.NewObjectID(), Name: "Analysis", Points: 6},
+			{ID: primitive.NewObjectID(), Name: "Writing", Points: 4},
+		},
+	}
+}
+
+func TestReadinessManual(t *testing.T) {
+	now := time.Now()
+	assign := manualAssignment(now)
+	if readiness := assign.Readiness(now); !readiness.Ready {
+		t.Fatalf("manual assignment without tests should be ready: %+v", readiness.Blockers)
+	}
+
+	assign.Rubric = nil
+	readiness := assign.Readiness(now)
+	if readiness.Ready || !readinessCodes(readiness.Blockers)[ReadinessNoRubric] {
+		t.Errorf("manual assignment without a rubric should be blocked: %+v", readiness.Blockers)
+	}
+}
+
+func TestNewRubric(t *testing.T) {
+	kept := primitive.NewObjectID()
+	rubric, err := NewRubric([]forms.RubricCriterionForm{
+		{ID: kept, Name: " Analysis ", Points: 6},
+		{Name: "Writing", Points: 4},
+	})
+	if err != nil || len(rubric) != 2 {
+		t.Fatalf("valid rubric was refused: %v %+v", err, rubric)
+	}
+	if rubric[0].ID != kept || rubric[0].Name != "Analysis" || rubric[1].ID.IsZero() {
+		t.Errorf("rubric criteria were not kept or given IDs: %+v", rubric)
+	}
+
+	for _, criterion := range []forms.RubricCriterionForm{{Name: " ", Points: 1}, {Name: "Style"}, {Name: "Style", Points: -2}} {
+		if _, err := NewRubric([]forms.RubricCriterionForm{criterion}); err == nil {
+			t.Errorf("criterion %+v was accepted", criterion)
+		}
+	}
+}
+
+func TestReview(t *testing.T) {
+	now := time.Now()
+	assign := manualAssignment(now)
+	analysis, writing := assign.Rubric[0].ID, assign.Rubric[1].ID
+
+	review, err := assign.Review(forms.ReviewSubmissionForm{
+		Scores: []cmsforms.CriterionScore{
+			{CriterionID: writing, Points: 4},
+			{CriterionID: analysis, Points: 3, Comment: "Thin on error."},
+		},
+		Annotations: []cmsforms.Annotation{{Page: 2, X: 0.5, Y: 0.25, Text: "Units?"}},
+	}, primitive.NewObjectID(), now)
+	if err != nil {
+		t.Fatalf("valid review was refused: %v", err)
+	}
+	if review.Grade != 70 || len(review.Scores) != 2 || review.Scores[0].Name != "Analysis" || review.Scores[0].OutOf != 6 {
+		t.Errorf("review graded wrong: %+v", review)
+	}
+
+	for name, form := range map[string]forms.ReviewSubmissionForm{
+		"missing criterion": {Scores: []cmsforms.CriterionScore{{CriterionID: analysis, Points: 6}}},
+		"scored twice": {Scores: []cmsforms.CriterionScore{
+			{CriterionID: analysis, Points: 6}, {CriterionID: analysis, Points: 6}, {CriterionID: writing, Points: 4},
+		}},
+		"too many points": {Scores: []cmsforms.CriterionScore{{CriterionID: analysis, Points: 7}, {CriterionID: writing, Points: 4}}},
+		"unknown criterion": {Scores: []cmsforms.CriterionScore{
+			{CriterionID: analysis, Points: 6}, {CriterionID: primitive.NewObjectID(), Points: 4},
+		}},
+		"annotation off the page": {
+			Scores:      []cmsforms.CriterionScore{{CriterionID: analysis, Points: 6}, {CriterionID: writing, Points: 4}},
+			Annotations: []cmsforms.Annotation{{Page: 1, X: 1.5, Y: 0, Text: "Here."}},
+		},
+	} {
+		if _, err := assign.Review(form, primitive.NewObjectID(), now); err == nil {
+			t.Errorf("review with %s was accepted", name)
+		}
+	}
+
+	assign.Manual = false
+	if _, err := assign.Review(forms.ReviewSubmissionForm{}, primitive.NewObjectID(), now); err == nil {
+		t.Errorf("an assignment graded by tests was reviewed")
+	}
+}
+
+func TestDeadlineExtension(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	student, other := primitive.NewObjectID(), primitive.NewObjectID()
+	extended := utils.TimeToDateTime(now.Add(72 * time.Hour))
+	assign := &MongoAssignment{
+		DueDate:    utils.TimeToDateTime(now),
+		Extensions: []Extension{{UserID: student, DueDate: extended}},
+	}
+
+	if due := assign.DueDateFor(student); due != extended {
+		t.Errorf("extended student is due %v, want %v", due, extended)
+	}
+	if !assign.Deadline(other).Equal(now) {
+		t.Errorf("student without an extension has deadline %v, want %v", assign.Deadline(other), now)
+	}
+
+	assign.TimeLimit = 30
+	assign.Starts = []Start{{UserID: student, StartedAt: utils.TimeToDateTime(now)}}
+	if !assign.Deadline(student).Equal(now.Add(30 * time.Minute)) {
+		t.Errorf("timed window should close before the extension, got %v", assign.Deadline(student))
+	}
+}
+
+func TestLatePolicy(t *testing.T) {
+	defer func(timezone string) { config.C.DefaultTimezone = timezone }(config.C.DefaultTimezone)
+	config.C.DefaultTimezone = ""
+
+	due := time.Date(2026, time.March, 2, 12, 0, 0, 0, time.UTC)
+	student := primitive.NewObjectID()
+	assign := &MongoAssignment{DueDate: utils.TimeToDateTime(due)}
+
+	late := utils.TimeToDateTime(due.Add(25 * time.Hour))
+	if penalty := assign.LatePenalty(student, late); penalty != 0 {
+		t.Errorf("assignment without a late policy took %v points", penalty)
+	}
+
+	assign.LatePolicy = &LatePolicy{PenaltyPerDay: 10, MaxDaysLate: 2}
+	if days := assign.DaysLate(student, utils.TimeToDateTime(due)); days != 0 {
+		t.Errorf("submission on the due date is %d days late", days)
+	}
+	if days := assign.DaysLate(student, utils.TimeToDateTime(due.Add(time.Minute))); days != 1 {
+		t.Errorf("submission a minute late is %d days late, not 1", days)
+	}
+	if penalty := assign.LatePenalty(student, late); penalty != 10 {
+		t.Errorf("submission the next day took %v points, want 10", penalty)
+	}
+	if !assign.AcceptsLate(student, time.Date(2026, time.March, 4, 23, 59, 0, 0, time.UTC)) || assign.AcceptsLate(student, time.Date(2026, time.March, 5, 0, 1, 0, 0, time.UTC)) {
+		t.Errorf("late submissions should be taken for 2 calendar days")
+	}
+
+	if err := CheckLatePolicy(&LatePolicy{PenaltyPerDay: 101}); err == nil {
+		t.Errorf("late policy taking over 100 points a day was accepted")
+	}
+}
+
+// Late days are calendar days of DEFAULT_TIMEZONE, the same count students are
+// shown, so the 23 and 25 hour days DST changes make are still one day each.
+func TestDaysLateAcrossDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone America/New_York is not installed: %s", err)
+	}
+
+	defer func(timezone string) { config.C.DefaultTimezone = timezone }(config.C.DefaultTimezone)
+	config.C.DefaultTimezone = "America/New_York"
+
+	student := primitive.NewObjectID()
+	for _, c := range []struct {
+		name           string
+		due, submitted time.Time
+		want           int
+	}{
+		// clocks spring forward on March 8th, 24 hours later is two days on
+		{"spring forward", time.Date(2026, time.March, 7, 23, 30, 0, 0, ny), time.Date(2026, time.March, 9, 0, 30, 0, 0, ny), 2},
+		// clocks fall back on November 1st, 25 hours later is the next day
+		{"fall back", time.Date(2026, time.October, 31, 23, 30, 0, 0, ny), time.Date(2026, time.November, 1, 23, 30, 0, 0, ny), 1},
+	} {
+		assign := &MongoAssignment{DueDate: utils.TimeToDateTime(c.due), LatePolicy: &LatePolicy{PenaltyPerDay: 10, MaxDaysLate: 1}}
+		submitted := utils.TimeToDateTime(c.submitted)
+
+		if days := assign.DaysLate(student, submitted); days != c.want {
+			t.Errorf("%s: %d days late, want %d", c.name, days, c.want)
+		}
+		if days := utils.CalendarDaysLate(c.due, c.submitted, ny); days != assign.DaysLate(student, submitted) {
+			t.Errorf("%s: students are shown %d days late but penalized for %d", c.name, days, assign.DaysLate(student, submitted))
+		}
+		if accepted := assign.AcceptsLate(student, c.submitted); accepted != (c.want <= 1) {
+			t.Errorf("%s: late policy of a day accepted the submission: %v", c.name, accepted)
+		}
+	}
+}
+
+func TestCheckMatch(t *testing.T) {
+	for name, test := range map[string]Test{
+		"no mode":            {ExpectedOutput: "hello"},
+		"exact":              {Match: MatchExact},
+		"trimmed":            {Match: MatchTrimmed},
+		"case insensitive":   {Match: MatchCaseInsensitive},
+		"json":               {Match: MatchJSON, ExpectedOutput: `{"a": 1}`},
+		"numeric":            {Match: MatchNumeric, Epsilon: 0.01},
+		"numeric, no slack":  {Match: MatchNumeric},
+		"regex":              {Match: MatchRegex, ExpectedOutput: `hello \w+`, Variants: []TestVariant{{ExpectedOutput: `[0-9]+`}}},
+		"regex, no variants": {Match: MatchRegex, ExpectedOutput: `.*`},
+	} {
+		if err := CheckMatch(test); err != nil {
+			t.Errorf("%s test was refused: %v", name, err)
+		}
+	}
+
+	for name, test := range map[string]Test{
+		"negative tolerance":   {Match: MatchNumeric, Epsilon: -0.5},
+		"broken regex":         {Match: MatchRegex, ExpectedOutput: `hello (`},
+		"broken variant regex": {Match: MatchRegex, ExpectedOutput: `ok`, Variants: []TestVariant{{ExpectedOutput: `[0-9`}}},
+		"unknown mode":         {Match: "fuzzy"},
+		"mode in wrong case":   {Match: "Numeric"},
+	} {
+		if err := CheckMatch(test); err != errors.ErrorInvalidTestMatch {
+			t.Errorf("%s test got %v, want %v", name, err, errors.ErrorInvalidTestMatch)
+		}
+	}
+}
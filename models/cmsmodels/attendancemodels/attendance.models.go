@@ -0,0 +1,226 @@
+package attendancemodels
+
+import (
+	"context"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	"backend/config"
+	"backend/errors"
+	"backend/utils"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+// codeLength the length of a session's check in code.
+const codeLength = 6
+
+type (
+	// CheckIn a student's attendance at a session. MarkedBy is set when
+	// staff marked them present instead of the student checking in.
+	CheckIn struct {
+		UserID    primitive.ObjectID  `bson:"userID" json:"userID"`
+		CheckedAt primitive.DateTime  `bson:"checkedAt" json:"checkedAt"`
+		MarkedBy  *primitive.ObjectID `bson:"markedBy,omitempty" json:"markedBy,omitempty"`
+	}
+
+	// MongoSession a class meeting students check in to with a code. With a
+	// rotation the code changes every Rotation seconds so it cannot be passed
+	// on to students who are not in the room.
+	MongoSession struct {
+		ID        primitive.ObjectID `bson:"_id" json:"id"`
+		CourseID  primitive.ObjectID `bson:"courseID" json:"courseID"`
+		Title     string             `bson:"title" json:"title"`
+		CreatedBy primitive.ObjectID `bson:"createdBy" json:"createdBy"`
+		CreatedAt primitive.DateTime `bson:"createdAt" json:"createdAt"`
+		// ClosesAt when check in ends, zero if the session stays open until
+		// staff close it.
+		ClosesAt primitive.DateTime `bson:"closesAt" json:"closesAt"`
+		Closed   bool               `bson:"closed" json:"closed"`
+		Rotation int                `bson:"rotation" json:"rotation"`
+		Secret   string             `bson:"secret" json:"-"`
+		CheckIns []CheckIn          `bson:"checkIns" json:"checkIns"`
+	}
+
+	AttendanceInterface struct {
+		ctx context.Context
+		col *mongo.Collection
+	}
+)
+
+func New() *AttendanceInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+	col := tyrgin.GetMongoCollection("attendance", db)
+
+	return &AttendanceInterface{
+		context.Background(),
+		col,
+	}
+}
+
+// window the rotation window a time falls in, always zero for sessions with
+// a fixed code.
+func (m *MongoSession) window(at time.Time) int64 {
+	if m.Rotation <= 0 {
+		return 0
+	}
+
+	return at.Unix() / int64(m.Rotation)
+}
+
+// Code the check in code at a time.
+func (m *MongoSession) Code(at time.Time) string {
+	return utils.RotatingCode(m.Secret, m.window(at), codeLength)
+}
+
+// ValidCode reports whether a code is the current one. The previous code of a
+// rotating session is still accepted, for students who typed it as it changed.
+func (m *MongoSession) ValidCode(code string, at time.Time) bool {
+	if code == m.Code(at) {
+		return true
+	}
+
+	return m.Rotation > 0 && code == m.Code(at.Add(-time.Duration(m.Rotation)*time.Second))
+}
+
+// Open reports whether students can still check in.
+func (m *MongoSession) Open(at time.Time) bool {
+	if m.Closed {
+		return false
+	}
+
+	return m.ClosesAt == 0 || at.Before(utils.DateTimeToTime(m.ClosesAt))
+}
+
+// Attended reports whether a student checked in or was marked present.
+func (m *MongoSession) Attended(uid primitive.ObjectID) bool {
+	for _, checkIn := range m.CheckIns {
+		if checkIn.UserID == uid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Create opens a session, closing after minutes unless that is zero.
+func (a *AttendanceInterface) Create(cid, uid primitive.ObjectID, title string, minutes, rotation int) (*MongoSession, errors.APIError) {
+	secret, errs := utils.RandomCode(32)
+	if errs != nil {
+		return nil, errors.ErrorDatabaseFailedCreate
+	}
+
+	created := time.Now()
+	session := MongoSession{
+		ID:        primitive.NewObjectID(),
+		CourseID:  cid,
+		Title:     title,
+		CreatedBy: uid,
+		CreatedAt: utils.TimeToDateTime(created),
+		Rotation:  rotation,
+		Secret:    secret,
+		CheckIns:  make([]CheckIn, 0),
+	}
+	if minutes > 0 {
+		session.ClosesAt = utils.TimeToDateTime(created.Add(time.Duration(minutes) * time.Minute))
+	}
+
+	_, err := a.col.InsertOne(a.ctx, &session, options.InsertOne())
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedCreate
+	}
+
+	return &session, nil
+}
+
+func (a *AttendanceInterface) Get(atid, cid interface{}) (*MongoSession, errors.APIError) {
+	var session *MongoSession
+	res := a.col.FindOne(a.ctx, bson.M{"_id": atid, "courseID": cid}, options.FindOne())
+	res.Decode(&session)
+
+	if session == nil {
+		return nil, errors.ErrorResourceNotFound
+	}
+
+	return session, nil
+}
+
+// FindByCourse lists a course's sessions, oldest first.
+func (a *AttendanceInterface) FindByCourse(cid interface{}) ([]MongoSession, errors.APIError) {
+	sessions := make([]MongoSession, 0)
+	cur, err := a.col.Find(a.ctx, bson.M{"courseID": cid}, options.Find().SetSort(bson.M{"createdAt": 1}))
+	if err != nil {
+		return sessions, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(a.ctx) {
+		var session MongoSession
+		err = cur.Decode(&session)
+		if err != nil {
+			return sessions, errors.ErrorInvalidBSON
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// AddCheckIn records a student as present, unless they already are.
+func (a *AttendanceInterface) AddCheckIn(atid interface{}, checkIn CheckIn) errors.APIError {
+	_, err := a.col.UpdateOne(
+		a.ctx,
+		bson.M{"_id": atid, "checkIns.userID": bson.M{"$ne": checkIn.UserID}},
+		bson.M{"$push": bson.M{"checkIns": &checkIn}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// RemoveCheckIn marks a student absent.
+func (a *AttendanceInterface) RemoveCheckIn(atid, uid interface{}) errors.APIError {
+	_, err := a.col.UpdateOne(
+		a.ctx,
+		bson.M{"_id": atid},
+		bson.M{"$pull": bson.M{"checkIns": bson.M{"userID": uid}}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+func (a *AttendanceInterface) Close(atid interface{}) errors.APIError {
+	_, err := a.col.UpdateOne(a.ctx, bson.M{"_id": atid}, bson.M{"$set": bson.M{"closed": true}})
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+func (a *AttendanceInterface) Delete(atid interface{}) errors.APIError {
+	_, err := a.col.DeleteOne(a.ctx, bson.M{"_id": atid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+func (a *AttendanceInterface) DeleteByCourseID(cid interface{}) errors.APIError {
+	_, err := a.col.DeleteMany(a.ctx, bson.M{"courseID": cid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
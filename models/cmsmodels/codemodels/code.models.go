@@ -0,0 +1,149 @@
+package codemodels
+
+import (
+	"context"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	"backend/config"
+	"backend/errors"
+	"backend/utils"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+type (
+	// File the text of a file of a submission.
+	File struct {
+		Path string `bson:"path" json:"path"`
+		Text string `bson:"text" json:"text"`
+	}
+
+	// MongoSubmissionCode the text files of a submission, extracted from its
+	// archive so staff can search the code of an assignment's submissions.
+	MongoSubmissionCode struct {
+		SubmissionID primitive.ObjectID `bson:"_id" json:"submissionID"`
+		AssignmentID primitive.ObjectID `bson:"assignmentID" json:"assignmentID"`
+		UserID       primitive.ObjectID `bson:"userID" json:"userID"`
+		Files        []File             `bson:"files" json:"files"`
+		IndexedAt    primitive.DateTime `bson:"indexedAt" json:"indexedAt"`
+	}
+
+	CodeInterface struct {
+		ctx context.Context
+		col *mongo.Collection
+	}
+)
+
+func New() *CodeInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+	col := tyrgin.GetMongoCollection("submissioncode", db)
+
+	return &CodeInterface{
+		context.Background(),
+		col,
+	}
+}
+
+// Index stores the text of a submission, replacing what was stored before.
+func (c *CodeInterface) Index(code MongoSubmissionCode) errors.APIError {
+	code.IndexedAt = utils.TimeToDateTime(time.Now())
+	_, err := c.col.ReplaceOne(
+		c.ctx,
+		bson.M{"_id": code.SubmissionID},
+		&code,
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// Indexed which of the submissions have their text stored.
+func (c *CodeInterface) Indexed(sids []primitive.ObjectID) (map[primitive.ObjectID]bool, errors.APIError) {
+	indexed := make(map[primitive.ObjectID]bool)
+	cur, err := c.col.Find(
+		c.ctx,
+		bson.M{"_id": bson.M{"$in": sids}},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return indexed, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(c.ctx) {
+		var code MongoSubmissionCode
+		err = cur.Decode(&code)
+		if err != nil {
+			return indexed, errors.ErrorInvalidBSON
+		}
+
+		indexed[code.SubmissionID] = true
+	}
+
+	return indexed, nil
+}
+
+// Find the text of the submissions, only those with a file matching the
+// regex pattern when one is given. The pattern is a prefilter, run by mongo.
+func (c *CodeInterface) Find(sids []primitive.ObjectID, pattern string) ([]MongoSubmissionCode, errors.APIError) {
+	filter := bson.M{"_id": bson.M{"$in": sids}}
+	if pattern != "" {
+		filter["files.text"] = bson.M{"$regex": pattern}
+	}
+
+	codes := make([]MongoSubmissionCode, 0)
+	cur, err := c.col.Find(c.ctx, filter, options.Find())
+	if err != nil {
+		return codes, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(c.ctx) {
+		var code MongoSubmissionCode
+		err = cur.Decode(&code)
+		if err != nil {
+			return codes, errors.ErrorInvalidBSON
+		}
+
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// DeleteBySubmissions removes the text of submissions, when their files are
+// purged.
+func (c *CodeInterface) DeleteBySubmissions(sids []primitive.ObjectID) errors.APIError {
+	_, err := c.col.DeleteMany(c.ctx, bson.M{"_id": bson.M{"$in": sids}}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+// DeleteByAssignmentID removes the text of an assignment's submissions.
+func (c *CodeInterface) DeleteByAssignmentID(aid interface{}) errors.APIError {
+	_, err := c.col.DeleteMany(c.ctx, bson.M{"assignmentID": aid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+// DeleteByUserID removes the text of a user's submissions.
+func (c *CodeInterface) DeleteByUserID(uid interface{}) errors.APIError {
+	_, err := c.col.DeleteMany(c.ctx, bson.M{"userID": uid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
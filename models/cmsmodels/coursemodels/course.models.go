@@ -4,20 +4,172 @@ import (
 	"bytes"
 	"context"
 	"encoding/csv"
-	"os"
+	"math"
+	"sort"
 	"strconv"
+	"time"
 
 	"github.com/mongodb/mongo-go-driver/bson"
 	"github.com/mongodb/mongo-go-driver/bson/primitive"
 	"github.com/mongodb/mongo-go-driver/mongo"
 	"github.com/mongodb/mongo-go-driver/mongo/options"
 
+	"backend/config"
 	"backend/errors"
+	"backend/events"
 	"backend/forms"
+	"backend/forms/cmsforms"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/models/cmsmodels/submissionmodels"
+	"backend/utils"
 
 	"github.com/stevens-tyr/tyr-gin"
 )
 
+// InviteCode a code users can redeem to enroll themselves in a course with a role.
+type InviteCode struct {
+	Code      string             `bson:"code" json:"code" binding:"required"`
+	Role      string             `bson:"role" json:"role" binding:"required"`
+	ExpiresAt primitive.DateTime `bson:"expiresAt" json:"expiresAt" binding:"required"`
+	Disabled  bool               `bson:"disabled" json:"disabled"`
+	CreatedAt primitive.DateTime `bson:"createdAt" json:"createdAt" binding:"required"`
+}
+
+// WaitlistEntry a student waiting for a seat in a full course.
+type WaitlistEntry struct {
+	UserID  primitive.ObjectID `bson:"userID" json:"userID" binding:"required"`
+	AddedAt primitive.DateTime `bson:"addedAt" json:"addedAt" binding:"required"`
+}
+
+// PendingEnrollment an email staff enrolled before anyone registered with it,
+// the account created with it joins the course with the role.
+type PendingEnrollment struct {
+	Email   string             `bson:"email" json:"email" binding:"required"`
+	Role    string             `bson:"role" json:"role" binding:"required"`
+	AddedBy primitive.ObjectID `bson:"addedBy" json:"addedBy" binding:"required"`
+	AddedAt primitive.DateTime `bson:"addedAt" json:"addedAt" binding:"required"`
+}
+
+// Section a group of a course's students, such as a recitation or lab, and the
+// assistants who grade them. Not to be confused with the course's own section.
+type Section struct {
+	Name       string               `bson:"name" json:"name" binding:"required"`
+	Students   []primitive.ObjectID `bson:"students" json:"students" binding:"required"`
+	Assistants []primitive.ObjectID `bson:"assistants" json:"assistants" binding:"required"`
+}
+
+// RetentionPolicy how many days after a course ends its data is kept. Zero days
+// keeps the data forever. Grades are always kept.
+type RetentionPolicy struct {
+	SubmissionFileDays int `bson:"submissionFileDays" json:"submissionFileDays"`
+	DiscussionDays     int `bson:"discussionDays" json:"discussionDays"`
+}
+
+// Quota how much storage and grading time a course may use, zero is
+// unlimited. Hard quotas refuse new submissions once used up, soft ones only
+// warn the professors.
+type Quota struct {
+	StorageMB      int  `bson:"storageMB" json:"storageMB"`
+	GradingMinutes int  `bson:"gradingMinutes" json:"gradingMinutes"`
+	Hard           bool `bson:"hard" json:"hard"`
+}
+
+// Usage what a course has used of its quota: the bytes of every submission
+// and the time court herald spent grading them.
+type Usage struct {
+	StorageBytes   int64   `bson:"storageBytes" json:"storageBytes"`
+	GradingSeconds float64 `bson:"gradingSeconds" json:"gradingSeconds"`
+	// WarnedAt when the professors were told the quota is used up, cleared
+	// when the quota changes.
+	WarnedAt *primitive.DateTime `bson:"warnedAt,omitempty" json:"warnedAt,omitempty"`
+}
+
+// CourseArchive a zip of a course's grades and metadata kept for compliance.
+type CourseArchive struct {
+	FileID    primitive.ObjectID `bson:"fileID" json:"fileID" binding:"required"`
+	Reason    string             `bson:"reason" json:"reason" binding:"required"`
+	CreatedAt primitive.DateTime `bson:"createdAt" json:"createdAt" binding:"required"`
+}
+
+// Bonus points a student was given towards their course total, in percentage
+// points.
+type Bonus struct {
+	ID        primitive.ObjectID `bson:"_id" json:"id"`
+	UserID    primitive.ObjectID `bson:"userID" json:"userID"`
+	Points    float64            `bson:"points" json:"points"`
+	Reason    string             `bson:"reason" json:"reason"`
+	GrantedBy primitive.ObjectID `bson:"grantedBy" json:"grantedBy"`
+	GrantedAt primitive.DateTime `bson:"grantedAt" json:"grantedAt"`
+}
+
+// OfficeHour a weekly office hour, Start and End are "15:04" on the wall
+// clock of Timezone. It repeats from the week of Since.
+type OfficeHour struct {
+	ID       primitive.ObjectID `bson:"_id" json:"id"`
+	Host     string             `bson:"host" json:"host"`
+	Weekday  time.Weekday       `bson:"weekday" json:"weekday"`
+	Start    string             `bson:"start" json:"start"`
+	End      string             `bson:"end" json:"end"`
+	Timezone string             `bson:"timezone" json:"timezone"`
+	Location string             `bson:"location" json:"location"`
+	Since    primitive.DateTime `bson:"since" json:"since"`
+}
+
+// Curve types.
+const (
+	// CurveShift adds the same number of points to every total.
+	CurveShift = "shift"
+	// CurvePercentile maps a student's percentile in the class to a score,
+	// interpolating between points. It never lowers a total.
+	CurvePercentile = "percentile"
+)
+
+// GradeCutoff the lowest total, after the curve, that earns a letter.
+type GradeCutoff struct {
+	Letter string  `bson:"letter" json:"letter"`
+	Min    float64 `bson:"min" json:"min"`
+}
+
+// CurvePoint the score students at a percentile of the class are curved to.
+type CurvePoint struct {
+	Percentile float64 `bson:"percentile" json:"percentile"`
+	Score      float64 `bson:"score" json:"score"`
+}
+
+// Curve a transform applied to course totals before letter grades.
+type Curve struct {
+	Type   string       `bson:"type" json:"type"`
+	Shift  float64      `bson:"shift,omitempty" json:"shift,omitempty"`
+	Points []CurvePoint `bson:"points,omitempty" json:"points,omitempty"`
+}
+
+// GradeScale how a course turns totals into letter grades.
+type GradeScale struct {
+	// Cutoffs sorted from the highest minimum to the lowest.
+	Cutoffs []GradeCutoff `bson:"cutoffs" json:"cutoffs"`
+	Curve   *Curve        `bson:"curve,omitempty" json:"curve,omitempty"`
+}
+
+// ResearchConsent a professor's agreement that the course's submissions and
+// grades, anonymized, may be part of research exports.
+type ResearchConsent struct {
+	GivenBy primitive.ObjectID `bson:"givenBy" json:"givenBy"`
+	GivenAt primitive.DateTime `bson:"givenAt" json:"givenAt"`
+}
+
+// PolicyTemplate assignment settings staff define once for a course and
+// apply to the assignments they create with it. Settings left out are not
+// part of the template.
+type PolicyTemplate struct {
+	Name              string                       `bson:"name" json:"name"`
+	NumAttempts       *int                         `bson:"numAttempts,omitempty" json:"numAttempts,omitempty"`
+	TimeLimit         *int                         `bson:"timeLimit,omitempty" json:"timeLimit,omitempty"`
+	CooldownMinutes   *int                         `bson:"cooldownMinutes,omitempty" json:"cooldownMinutes,omitempty"`
+	LatePolicy        *assignmentmodels.LatePolicy `bson:"latePolicy,omitempty" json:"latePolicy,omitempty"`
+	ShowHiddenSummary *bool                        `bson:"showHiddenSummary,omitempty" json:"showHiddenSummary,omitempty"`
+	Attestation       *string                      `bson:"attestation,omitempty" json:"attestation,omitempty"`
+}
+
 // Course struct ot store information about a course.
 type MongoCourse struct {
 	ID          primitive.ObjectID   `bson:"_id,omitempty" json:"id" binding:"required"`
@@ -29,37 +181,78 @@ type MongoCourse struct {
 	Professors  []primitive.ObjectID `bson:"professors" json:"professors" binding:"required"`
 	Assistants  []primitive.ObjectID `bson:"assistants" json:"assistants" binding:"required"`
 	Students    []primitive.ObjectID `bson:"students" json:"students" binding:"required"`
+	Withdrawn   []primitive.ObjectID `bson:"withdrawn" json:"withdrawn"`
 	Assignments []primitive.ObjectID `bson:"assignments" json:"assignments" binding:"required"`
+	InviteCodes []InviteCode         `bson:"inviteCodes" json:"-"`
+	// MaxEnrollment caps the number of students, 0 means unlimited.
+	MaxEnrollment int             `bson:"maxEnrollment" json:"maxEnrollment"`
+	Waitlist      []WaitlistEntry `bson:"waitlist" json:"-"`
+	// PendingEnrollments emails enrolled before they were registered.
+	PendingEnrollments []PendingEnrollment `bson:"pendingEnrollments" json:"-"`
+	// EndDate when the course is over, retention periods count from it.
+	EndDate               primitive.DateTime `bson:"endDate,omitempty" json:"endDate,omitempty"`
+	Retention             *RetentionPolicy   `bson:"retention,omitempty" json:"retention,omitempty"`
+	Archives              []CourseArchive    `bson:"archives" json:"-"`
+	SubmissionFilesPurged bool               `bson:"submissionFilesPurged" json:"submissionFilesPurged"`
+	DiscussionsPurged     bool               `bson:"discussionsPurged" json:"discussionsPurged"`
+	Bonuses               []Bonus            `bson:"bonuses" json:"-"`
+	GradeScale            *GradeScale        `bson:"gradeScale,omitempty" json:"gradeScale,omitempty"`
+	OfficeHours           []OfficeHour       `bson:"officeHours" json:"officeHours"`
+	Sections              []Section          `bson:"sections" json:"sections"`
+	// Quota the course's limits, the deployment's when nil, set by admins.
+	Quota *Quota `bson:"quota,omitempty" json:"-"`
+	Usage Usage  `bson:"usage" json:"-"`
+	// Archived courses are read only, ArchivedAt is when they were archived.
+	Archived   bool                `bson:"archived" json:"archived"`
+	ArchivedAt *primitive.DateTime `bson:"archivedAt,omitempty" json:"archivedAt,omitempty"`
+	// SectionScopedGrading only lets assistants with sections grade and see
+	// the students of their sections.
+	SectionScopedGrading bool `bson:"sectionScopedGrading" json:"sectionScopedGrading"`
+	// OrganizationID the organization the course belongs to, only its users
+	// can be enrolled. None for courses of a deployment without organizations.
+	OrganizationID *primitive.ObjectID `bson:"organizationID,omitempty" json:"organizationID,omitempty"`
+	// PolicyTemplates only shown to staff, by their own endpoint.
+	PolicyTemplates []PolicyTemplate `bson:"policyTemplates,omitempty" json:"-"`
+	// ResearchConsent set while the course may be part of research exports.
+	ResearchConsent *ResearchConsent `bson:"researchConsent,omitempty" json:"researchConsent,omitempty"`
 }
 
 type CourseInterface struct {
 	ctx context.Context
 	col *mongo.Collection
+	// analytics the collection as read by reports, see
+	// utils.AnalyticsCollection.
+	analytics *mongo.Collection
 }
 
 func New() *CourseInterface {
-	db, _ := tyrgin.GetMongoDB(os.Getenv("DB_NAME"))
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
 	col := tyrgin.GetMongoCollection("courses", db)
 
 	return &CourseInterface{
 		context.Background(),
 		col,
+		utils.AnalyticsCollection("courses", db),
 	}
 }
 
-func (c *CourseInterface) FindOne(department, section, semester string, number int) (*MongoCourse, errors.APIError) {
+// FindOne finds a course of an organization, nil for courses without one, by
+// its department, number, section and semester.
+func (c *CourseInterface) FindOne(oid *primitive.ObjectID, department, section, semester string, number int) (*MongoCourse, errors.APIError) {
 	var course *MongoCourse
 
-	res := c.col.FindOne(
-		c.ctx,
-		bson.M{
-			"department": department,
-			"number":     number,
-			"section":    section,
-			"semester":   semester,
-		},
-		options.FindOne(),
-	)
+	filter := bson.M{
+		"department":     department,
+		"number":         number,
+		"section":        section,
+		"semester":       semester,
+		"organizationID": bson.M{"$exists": false},
+	}
+	if oid != nil {
+		filter["organizationID"] = *oid
+	}
+
+	res := c.col.FindOne(c.ctx, filter, options.FindOne())
 	res.Decode(&course)
 
 	if course == nil {
@@ -82,6 +275,59 @@ func (c *CourseInterface) GetByID(cid interface{}) (*MongoCourse, errors.APIErro
 	return course, nil
 }
 
+// IsArchived reports whether a course is archived, reading nothing else.
+func (c *CourseInterface) IsArchived(cid interface{}) (bool, errors.APIError) {
+	var course struct {
+		Archived bool `bson:"archived"`
+	}
+
+	err := c.col.FindOne(c.ctx, bson.M{"_id": cid}, options.FindOne().SetProjection(bson.M{"archived": 1})).Decode(&course)
+	if err == mongo.ErrNoDocuments {
+		return false, errors.ErrorResourceNotFound
+	}
+	if err != nil {
+		return false, errors.ErrorDatabaseFailedQuery
+	}
+
+	return course.Archived, nil
+}
+
+// SetArchived archives a course, making it read only, or restores it.
+func (c *CourseInterface) SetArchived(cid interface{}, archived bool) errors.APIError {
+	update := bson.M{
+		"$set":   bson.M{"archived": false},
+		"$unset": bson.M{"archivedAt": ""},
+	}
+	if archived {
+		update = bson.M{"$set": bson.M{"archived": true, "archivedAt": utils.TimeToDateTime(time.Now())}}
+	}
+
+	res, err := c.col.UpdateOne(c.ctx, bson.M{"_id": cid}, update)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.ErrorResourceNotFound
+	}
+
+	return nil
+}
+
+// FindByAssignment returns the course an assignment belongs to.
+func (c *CourseInterface) FindByAssignment(aid interface{}) (*MongoCourse, errors.APIError) {
+	var course *MongoCourse
+
+	res := c.col.FindOne(c.ctx, bson.M{"assignments": aid}, options.FindOne())
+	res.Decode(&course)
+
+	if course == nil {
+		return nil, errors.ErrorResourceNotFound
+	}
+
+	return course, nil
+}
+
 func (c *CourseInterface) Delete(cid interface{}) errors.APIError {
 	_, err := c.col.DeleteOne(c.ctx, bson.M{"_id": cid}, options.Delete())
 	if err != nil {
@@ -118,10 +364,11 @@ func (c *CourseInterface) Update(course MongoCourse) errors.APIError {
 		},
 		bson.M{
 			"$set": bson.M{
-				"department": course.Department,
-				"longName":   course.LongName,
-				"section":    course.Section,
-				"semester":   course.Semester,
+				"department":    course.Department,
+				"longName":      course.LongName,
+				"section":       course.Section,
+				"semester":      course.Semester,
+				"maxEnrollment": course.MaxEnrollment,
 			},
 		},
 	)
@@ -132,12 +379,19 @@ func (c *CourseInterface) Update(course MongoCourse) errors.APIError {
 	return nil
 }
 
-func (c *CourseInterface) Get(cid, uid interface{}, role string) (map[string]interface{}, errors.APIError) {
+// Get a course with its people and assignments, for students only what they
+// may see of the published assignments not in hidden, with their own
+// submissions, and for staff without the secrets of repository links.
+func (c *CourseInterface) Get(cid, uid interface{}, role string, hidden []primitive.ObjectID) (map[string]interface{}, errors.APIError) {
+	if hidden == nil {
+		hidden = make([]primitive.ObjectID, 0)
+	}
+
 	userLookup := func(userType string) bson.M {
 		return bson.M{
 			"$lookup": bson.M{
 				"from": "users",
-				"let":  bson.M{"userType": "$" + userType},
+				"let":  bson.M{"userType": bson.M{"$ifNull": bson.A{"$" + userType, bson.A{}}}},
 				"pipeline": bson.A{
 					bson.M{"$match": bson.M{"$expr": bson.M{"$in": bson.A{"$_id", "$$userType"}}}},
 					bson.M{"$project": bson.M{"admin": 0, "enrolledCourses": 0, "password": 0}},
@@ -149,11 +403,18 @@ func (c *CourseInterface) Get(cid, uid interface{}, role string) (map[string]int
 
 	query := []interface{}{
 		bson.M{"$match": bson.M{"_id": cid}},
+		bson.M{"$project": bson.M{"archives": 0, "inviteCodes": 0, "waitlist": 0}},
 		userLookup("students"),
 		userLookup("professors"),
 		userLookup("assistants"),
 	}
 
+	if role == "student" {
+		query = append(query, bson.M{"$project": bson.M{"withdrawn": 0}})
+	} else {
+		query = append(query, userLookup("withdrawn"))
+	}
+
 	if role == "student" {
 		query = append(query, bson.M{
 			"$lookup": bson.M{
@@ -162,9 +423,14 @@ func (c *CourseInterface) Get(cid, uid interface{}, role string) (map[string]int
 				"pipeline": bson.A{
 					bson.M{
 						"$match": bson.M{
-							"$expr": bson.M{"$and": bson.A{bson.M{"$in": bson.A{"$_id", "$$ass"}}, "$published"}},
+							"$expr": bson.M{"$and": bson.A{
+								bson.M{"$in": bson.A{"$_id", "$$ass"}},
+								"$published",
+								bson.M{"$not": bson.A{bson.M{"$in": bson.A{"$_id", hidden}}}},
+							}},
 						},
 					},
+					assignmentmodels.StudentProjection(),
 					bson.M{
 						"$lookup": bson.M{
 							"from": "submissions",
@@ -186,7 +452,10 @@ func (c *CourseInterface) Get(cid, uid interface{}, role string) (map[string]int
 										"submissionDate": 1,
 										"file":           1,
 										"errorTesting":   1,
-										"results":        bson.M{"$filter": bson.M{"input": "$results", "as": "result", "cond": bson.M{"$eq": bson.A{"$$result.studentFacing", true}}}},
+										"errorReason":    1,
+										"buildOutput":    1,
+										"buildFailed":    1,
+										"results":        submissionmodels.StudentFacingResults(),
 										"attemptNumber":  1,
 										"inProgress":     1,
 									},
@@ -207,6 +476,7 @@ func (c *CourseInterface) Get(cid, uid interface{}, role string) (map[string]int
 				"let":  bson.M{"ass": "$assignments"},
 				"pipeline": bson.A{
 					bson.M{"$match": bson.M{"$expr": bson.M{"$in": bson.A{"$_id", "$$ass"}}}},
+					assignmentmodels.StaffProjection(),
 					bson.M{
 						"$lookup": bson.M{
 							"from":         "submissions",
@@ -241,8 +511,10 @@ func (c *CourseInterface) Get(cid, uid interface{}, role string) (map[string]int
 	return course, nil
 }
 
-func (c *CourseInterface) Create(uid interface{}, form forms.CreateCourseForm) (*primitive.ObjectID, errors.APIError) {
+// Create adds a course taught by uid to an organization, nil for none.
+func (c *CourseInterface) Create(uid interface{}, oid *primitive.ObjectID, form forms.CreateCourseForm) (*primitive.ObjectID, errors.APIError) {
 	course, err := c.FindOne(
+		oid,
 		form.Department,
 		form.Section,
 		form.Semester,
@@ -260,14 +532,23 @@ func (c *CourseInterface) Create(uid interface{}, form forms.CreateCourseForm) (
 	professors := []primitive.ObjectID{uidpo}
 
 	course = &MongoCourse{
-		Department:  form.Department,
-		Number:      form.Number,
-		Section:     form.Section,
-		Semester:    form.Semester,
-		Professors:  professors,
-		Assistants:  make([]primitive.ObjectID, 0),
-		Students:    make([]primitive.ObjectID, 0),
-		Assignments: make([]primitive.ObjectID, 0),
+		Department:         form.Department,
+		Number:             form.Number,
+		Section:            form.Section,
+		Semester:           form.Semester,
+		Professors:         professors,
+		Assistants:         make([]primitive.ObjectID, 0),
+		Students:           make([]primitive.ObjectID, 0),
+		Withdrawn:          make([]primitive.ObjectID, 0),
+		Assignments:        make([]primitive.ObjectID, 0),
+		InviteCodes:        make([]InviteCode, 0),
+		MaxEnrollment:      form.MaxEnrollment,
+		Waitlist:           make([]WaitlistEntry, 0),
+		PendingEnrollments: make([]PendingEnrollment, 0),
+		Bonuses:            make([]Bonus, 0),
+		OfficeHours:        make([]OfficeHour, 0),
+		Sections:           make([]Section, 0),
+		OrganizationID:     oid,
 	}
 
 	res, errs := c.col.InsertOne(c.ctx, course, options.InsertOne())
@@ -342,6 +623,10 @@ func (c *CourseInterface) AddUser(level string, uid, cid interface{}) errors.API
 		return errors.ErrorDatabaseFailedUpdate
 	}
 
+	courseID, _ := cid.(primitive.ObjectID)
+	userID, _ := uid.(primitive.ObjectID)
+	events.Publish(events.EnrollmentAddedEvent, events.EnrollmentAdded{CourseID: courseID, UserID: userID, Role: level})
+
 	return nil
 }
 
@@ -359,144 +644,1323 @@ func (c *CourseInterface) AddAssignment(aid, cid interface{}) errors.APIError {
 	return nil
 }
 
-func (c *CourseInterface) GetAssignments(cid interface{}, role string) ([]forms.AssignmentAggQuery, errors.APIError) {
-	var assignments []forms.AssignmentAggQuery
+// BonusPoints the bonus points a student was given in total.
+func (m *MongoCourse) BonusPoints(uid primitive.ObjectID) float64 {
+	points := 0.0
+	for _, bonus := range m.Bonuses {
+		if bonus.UserID == uid {
+			points += bonus.Points
+		}
+	}
 
-	query := []interface{}{
-		bson.M{"$match": bson.M{"_id": cid}},
-		bson.M{"$unwind": "$assignments"},
-		bson.M{
-			"$lookup": bson.M{
-				"as":           "assignment",
-				"from":         "assignments",
-				"localField":   "assignments",
-				"foreignField": "_id",
-			},
-		},
-		bson.M{"$project": bson.M{
-			"_id": 0,
-			"assignment": bson.M{
-				"$arrayElemAt": bson.A{"$assignment", 0},
-			},
-		},
-		},
+	return points
+}
+
+// AddBonus gives a student bonus points.
+func (c *CourseInterface) AddBonus(cid interface{}, bonus Bonus) errors.APIError {
+	_, err := c.col.UpdateOne(
+		c.ctx,
+		bson.M{"_id": cid},
+		bson.M{"$push": bson.M{"bonuses": &bonus}},
+		options.Update(),
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
 	}
 
-	cur, err := c.col.Aggregate(c.ctx, query, options.Aggregate())
+	return nil
+}
+
+// RemoveBonus takes back bonus points.
+func (c *CourseInterface) RemoveBonus(cid, bid interface{}) errors.APIError {
+	res, err := c.col.UpdateOne(
+		c.ctx,
+		bson.M{"_id": cid, "bonuses._id": bid},
+		bson.M{"$pull": bson.M{"bonuses": bson.M{"_id": bid}}},
+		options.Update(),
+	)
 	if err != nil {
-		return assignments, errors.ErrorDatabaseFailedQuery
+		return errors.ErrorDatabaseFailedUpdate
 	}
 
-	for cur.Next(c.ctx) {
-		var assignment map[string]forms.AssignmentAggQuery
-		err = cur.Decode(&assignment)
-		if err != nil {
-			return assignments, errors.ErrorInvalidBSON
-		}
-		if role != "student" || assignment["assignment"].Published {
-			assignments = append(assignments, assignment["assignment"])
-		}
+	if res.MatchedCount == 0 {
+		return errors.ErrorResourceNotFound
 	}
 
-	return assignments, nil
+	return nil
 }
 
-func (c *CourseInterface) GetGradesAsCSV(aid, cid interface{}) (*bytes.Buffer, string, int64, errors.APIError) {
-	userLookup := func(userType string) bson.M {
-		return bson.M{
-			"$lookup": bson.M{
-				"from": "users",
-				"let":  bson.M{"userType": "$" + userType},
-				"pipeline": bson.A{
-					bson.M{"$match": bson.M{"$expr": bson.M{"$in": bson.A{"$_id", "$$userType"}}}},
-					bson.M{"$project": bson.M{"admin": 0, "email": 0, "enrolledCourses": 0, "password": 0}},
-					bson.M{
-						"$lookup": bson.M{
-							"from": "submissions",
-							"let":  bson.M{"uid": "$_id"},
-							"pipeline": bson.A{
-								bson.M{
-									"$match": bson.M{
-										"$expr": bson.M{
-											"$and": bson.A{
-												bson.M{"$eq": bson.A{"$assignmentID", aid}},
-												bson.M{"$eq": bson.A{"$userID", "$$uid"}},
-											},
-										},
-									},
-								},
-								bson.M{"$sort": bson.M{"submissionDate": -1}},
-								bson.M{"$project": bson.M{"_id": 0, "assignmentID": 0, "userID": 0, "file": 0}},
-								bson.M{"$limit": 1},
-							},
-							"as": "submissions",
-						},
-					},
-				},
-				"as": userType,
-			},
-		}
+// Active reports whether the invite code can still be redeemed.
+func (i *InviteCode) Active() bool {
+	return !i.Disabled && i.ExpiresAt > primitive.DateTime(time.Now().UnixNano()/1000000)
+}
+
+// SetInviteCode replaces the course's invite code for the code's role, which
+// rotates any previously issued code for that role.
+func (c *CourseInterface) SetInviteCode(cid interface{}, invite InviteCode) errors.APIError {
+	_, err := c.col.UpdateOne(
+		c.ctx,
+		bson.M{"_id": cid},
+		bson.M{"$pull": bson.M{"inviteCodes": bson.M{"role": invite.Role}}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
 	}
 
-	query := []interface{}{
-		bson.M{"$match": bson.M{"_id": cid}},
-		userLookup("students"),
-		bson.M{
-			"$project": bson.M{
-				"_id":         0,
-				"assignments": 0,
-				"assistants":  0,
-				"department":  0,
-				"longName":    0,
-				"number":      0,
-				"professors":  0,
-				"section":     0,
-				"semester":    0,
-			},
-		},
+	_, err = c.col.UpdateOne(
+		c.ctx,
+		bson.M{"_id": cid},
+		bson.M{"$push": bson.M{"inviteCodes": &invite}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
 	}
 
-	var results forms.GradeAggQuery
-	cur, err := c.col.Aggregate(
+	return nil
+}
+
+func (c *CourseInterface) DisableInviteCode(cid interface{}, role string) errors.APIError {
+	res, err := c.col.UpdateOne(
 		c.ctx,
-		query,
-		options.Aggregate(),
+		bson.M{"_id": cid, "inviteCodes.role": role},
+		bson.M{"$set": bson.M{"inviteCodes.$.disabled": true}},
 	)
 	if err != nil {
-		return nil, "", 0, errors.ErrorInvalidBSON
+		return errors.ErrorDatabaseFailedUpdate
 	}
 
-	for cur.Next(c.ctx) {
-		err = cur.Decode(&results)
-		if err != nil {
-			return nil, "", 0, errors.ErrorResourceNotFound
-		}
+	if res.MatchedCount == 0 {
+		return errors.ErrorResourceNotFound
 	}
 
-	records := [][]string{
-		{"First Name", "Last Name", "Grade", "TestCases", "Attempt Number", "Submission Time"},
+	return nil
+}
+
+// FindByInviteCode finds the course an invite code belongs to, failing if the
+// code is unknown, disabled or expired.
+func (c *CourseInterface) FindByInviteCode(code string) (*MongoCourse, *InviteCode, errors.APIError) {
+	var course *MongoCourse
+	res := c.col.FindOne(c.ctx, bson.M{"inviteCodes.code": code}, options.FindOne())
+	res.Decode(&course)
+
+	if course == nil {
+		return nil, nil, errors.ErrorInvalidInviteCode
 	}
 
-	for _, student := range results.Students {
-		var grade, attempt string
-		if len(student.Subs) > 0 {
-			sub := student.Subs[0]
-			grade = "100"
-			attempt = strconv.Itoa(sub.Attempt)
-		} else {
-			grade = "0"
-			attempt = "0"
+	for index := range course.InviteCodes {
+		invite := &course.InviteCodes[index]
+		if invite.Code == code && invite.Active() {
+			return course, invite, nil
 		}
-		records = append(records, []string{student.First, student.Last, grade, attempt, "0"})
 	}
 
-	csvBytes := &bytes.Buffer{}
-	writer := csv.NewWriter(csvBytes)
-	err = writer.WriteAll(records)
+	return nil, nil, errors.ErrorInvalidInviteCode
+}
+
+// Full reports whether the course has reached its enrollment cap.
+func (m *MongoCourse) Full() bool {
+	return m.MaxEnrollment > 0 && len(m.Students) >= m.MaxEnrollment
+}
+
+// FreeSeats how many more students the course can enroll, -1 when it has no
+// enrollment cap.
+func (m *MongoCourse) FreeSeats() int {
+	if m.MaxEnrollment <= 0 {
+		return -1
+	}
+	if m.Full() {
+		return 0
+	}
+
+	return m.MaxEnrollment - len(m.Students)
+}
+
+// AddToWaitlist appends a user to the end of a course's waitlist unless they
+// are already on it.
+func (c *CourseInterface) AddToWaitlist(cid, uid interface{}) errors.APIError {
+	res, err := c.col.UpdateOne(
+		c.ctx,
+		bson.M{"_id": cid, "waitlist.userID": bson.M{"$ne": uid}},
+		bson.M{
+			"$push": bson.M{
+				"waitlist": WaitlistEntry{
+					UserID:  uid.(primitive.ObjectID),
+					AddedAt: primitive.DateTime(time.Now().UnixNano() / 1000000),
+				},
+			},
+		},
+	)
 	if err != nil {
-		return nil, "", 0, errors.ErrorFailedToWriteCSV
+		return errors.ErrorDatabaseFailedUpdate
 	}
-	writer.Flush()
 
-	return csvBytes, "filename", int64(csvBytes.Len()), nil
+	if res.MatchedCount == 0 {
+		return errors.ErrorUserAlreadyWaitlisted
+	}
+
+	return nil
+}
+
+func (c *CourseInterface) RemoveFromWaitlist(cid, uid interface{}) errors.APIError {
+	_, err := c.col.UpdateOne(
+		c.ctx,
+		bson.M{"_id": cid},
+		bson.M{"$pull": bson.M{"waitlist": bson.M{"userID": uid}}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// AddPendingEnrollment enrolls an email that is not registered yet, changing
+// the role when it is already pending.
+func (c *CourseInterface) AddPendingEnrollment(cid interface{}, email, role string, by primitive.ObjectID) errors.APIError {
+	email = utils.NormalizeEmail(email)
+	res, err := c.col.UpdateOne(
+		c.ctx,
+		bson.M{"_id": cid, "pendingEnrollments.email": bson.M{"$ne": email}},
+		bson.M{
+			"$push": bson.M{
+				"pendingEnrollments": PendingEnrollment{
+					Email:   email,
+					Role:    role,
+					AddedBy: by,
+					AddedAt: utils.TimeToDateTime(time.Now()),
+				},
+			},
+		},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	if res.MatchedCount != 0 {
+		return nil
+	}
+
+	_, err = c.col.UpdateOne(
+		c.ctx,
+		bson.M{"_id": cid, "pendingEnrollments.email": email},
+		bson.M{"$set": bson.M{"pendingEnrollments.$.role": role}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// RemovePendingEnrollment cancels the pending enrollment of an email.
+func (c *CourseInterface) RemovePendingEnrollment(cid interface{}, email string) errors.APIError {
+	res, err := c.col.UpdateOne(
+		c.ctx,
+		bson.M{"_id": cid, "pendingEnrollments.email": utils.NormalizeEmail(email)},
+		bson.M{"$pull": bson.M{"pendingEnrollments": bson.M{"email": utils.NormalizeEmail(email)}}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.ErrorResourceNotFound
+	}
+
+	return nil
+}
+
+// WithPendingEnrollment the courses an email was enrolled in before it was
+// registered.
+func (c *CourseInterface) WithPendingEnrollment(email string) ([]MongoCourse, errors.APIError) {
+	courses := make([]MongoCourse, 0)
+	cur, err := c.col.Find(c.ctx, bson.M{"pendingEnrollments.email": utils.NormalizeEmail(email)}, options.Find())
+	if err != nil {
+		return courses, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(c.ctx) {
+		var course MongoCourse
+		err = cur.Decode(&course)
+		if err != nil {
+			return courses, errors.ErrorInvalidBSON
+		}
+
+		courses = append(courses, course)
+	}
+
+	return courses, nil
+}
+
+// PendingEnrollment the pending enrollment of an email, if any.
+func (m *MongoCourse) PendingEnrollment(email string) (PendingEnrollment, bool) {
+	email = utils.NormalizeEmail(email)
+	for _, pending := range m.PendingEnrollments {
+		if pending.Email == email {
+			return pending, true
+		}
+	}
+
+	return PendingEnrollment{}, false
+}
+
+// Withdraw moves a student from the course's active roster to its withdrawn list.
+func (c *CourseInterface) Withdraw(cid, uid interface{}) errors.APIError {
+	res, err := c.col.UpdateOne(
+		c.ctx,
+		bson.M{"_id": cid, "students": uid},
+		bson.M{
+			"$pull":     bson.M{"students": uid},
+			"$addToSet": bson.M{"withdrawn": uid},
+		},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.ErrorResourceNotFound
+	}
+
+	return nil
+}
+
+// Reinstate moves a withdrawn student back onto the course's active roster.
+func (c *CourseInterface) Reinstate(cid, uid interface{}) errors.APIError {
+	res, err := c.col.UpdateOne(
+		c.ctx,
+		bson.M{"_id": cid, "withdrawn": uid},
+		bson.M{
+			"$pull":     bson.M{"withdrawn": uid},
+			"$addToSet": bson.M{"students": uid},
+		},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.ErrorResourceNotFound
+	}
+
+	return nil
+}
+
+func (c *CourseInterface) GetAssignments(cid interface{}, role string) ([]forms.AssignmentAggQuery, errors.APIError) {
+	var assignments []forms.AssignmentAggQuery
+
+	query := []interface{}{
+		bson.M{"$match": bson.M{"_id": cid}},
+		bson.M{"$unwind": "$assignments"},
+		bson.M{
+			"$lookup": bson.M{
+				"as":           "assignment",
+				"from":         "assignments",
+				"localField":   "assignments",
+				"foreignField": "_id",
+			},
+		},
+		bson.M{"$project": bson.M{
+			"_id": 0,
+			"assignment": bson.M{
+				"$arrayElemAt": bson.A{"$assignment", 0},
+			},
+		},
+		},
+	}
+
+	cur, err := c.col.Aggregate(c.ctx, query, options.Aggregate())
+	if err != nil {
+		return assignments, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(c.ctx) {
+		var assignment map[string]forms.AssignmentAggQuery
+		err = cur.Decode(&assignment)
+		if err != nil {
+			return assignments, errors.ErrorInvalidBSON
+		}
+		if role != "student" || assignment["assignment"].Published {
+			assignments = append(assignments, assignment["assignment"])
+		}
+	}
+
+	return assignments, nil
+}
+
+// submissionScore a submission's Score in an aggregation.
+var submissionScore = bson.M{
+	"$ifNull": bson.A{
+		"$gradeOverride.grade",
+		bson.M{"$cond": bson.A{
+			bson.M{"$gt": bson.A{bson.M{"$size": bson.M{"$ifNull": bson.A{"$results", bson.A{}}}}, 0}},
+			bson.M{"$multiply": bson.A{
+				100,
+				bson.M{"$divide": bson.A{
+					bson.M{"$size": bson.M{"$filter": bson.M{"input": "$results", "cond": "$$this.passed"}}},
+					bson.M{"$size": "$results"},
+				}},
+			}},
+			0,
+		}},
+	},
+}
+
+// GetAssignmentsWithStats lists a course's assignments in one aggregation,
+// students only the published ones with how they are doing on each, staff
+// every one with how many students have submitted.
+func (c *CourseInterface) GetAssignmentsWithStats(cid, uid interface{}, role string) ([]forms.AssignmentStatsAggQuery, errors.APIError) {
+	assignments := make([]forms.AssignmentStatsAggQuery, 0)
+
+	byAssignment := bson.M{"$eq": bson.A{"$assignmentID", "$$aid"}}
+	project := bson.M{
+		"dueDate":     1,
+		"name":        1,
+		"courseID":    1,
+		"numAttempts": 1,
+	}
+	var stats bson.M
+	if role == "student" {
+		project["status"] = bson.M{"$arrayElemAt": bson.A{"$status", 0}}
+		stats = bson.M{
+			"$lookup": bson.M{
+				"from": "submissions",
+				"let":  bson.M{"aid": "$_id"},
+				"pipeline": bson.A{
+					bson.M{"$match": bson.M{"$expr": bson.M{"$and": bson.A{byAssignment, bson.M{"$eq": bson.A{"$userID", uid}}}}}},
+					bson.M{"$match": bson.M{"refunded": bson.M{"$ne": true}}},
+					bson.M{"$group": bson.M{
+						"_id":           nil,
+						"attempts":      bson.M{"$max": "$attemptNumber"},
+						"bestScore":     bson.M{"$max": submissionScore},
+						"grading":       bson.M{"$max": "$inProgress"},
+						"lastSubmitted": bson.M{"$max": "$submissionDate"},
+					}},
+				},
+				"as": "status",
+			},
+		}
+	} else {
+		project["submittedStudents"] = bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$submitted.students", 0}}, 0}}
+		stats = bson.M{
+			"$lookup": bson.M{
+				"from": "submissions",
+				"let":  bson.M{"aid": "$_id"},
+				"pipeline": bson.A{
+					bson.M{"$match": bson.M{"$expr": byAssignment}},
+					bson.M{"$group": bson.M{"_id": "$userID"}},
+					bson.M{"$count": "students"},
+				},
+				"as": "submitted",
+			},
+		}
+	}
+
+	query := []interface{}{
+		bson.M{"$match": bson.M{"_id": cid}},
+		bson.M{"$unwind": "$assignments"},
+		bson.M{
+			"$lookup": bson.M{
+				"as":           "assignment",
+				"from":         "assignments",
+				"localField":   "assignments",
+				"foreignField": "_id",
+			},
+		},
+		bson.M{"$unwind": "$assignment"},
+		bson.M{"$replaceRoot": bson.M{"newRoot": "$assignment"}},
+	}
+	if role == "student" {
+		query = append(query, bson.M{"$match": bson.M{"published": true}})
+	}
+	query = append(query, stats, bson.M{"$project": project})
+
+	cur, err := c.col.Aggregate(c.ctx, query, options.Aggregate())
+	if err != nil {
+		return assignments, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(c.ctx) {
+		var assignment forms.AssignmentStatsAggQuery
+		err = cur.Decode(&assignment)
+		if err != nil {
+			return assignments, errors.ErrorInvalidBSON
+		}
+
+		if role == "student" {
+			if assignment.Status == nil {
+				assignment.Status = &cmsforms.AssignmentStatus{}
+			}
+			assignment.Status.Submitted = assignment.Status.Attempts > 0
+			if left := assignment.NumAttempts - assignment.Status.Attempts; left > 0 {
+				assignment.Status.AttemptsLeft = left
+			}
+		}
+
+		assignments = append(assignments, assignment)
+	}
+
+	return assignments, nil
+}
+
+// OverviewAssignment a published assignment with how far a student is on
+// it: the attempts they made and how many of them are still being graded,
+// automatically or by staff on the rubric.
+type OverviewAssignment struct {
+	CourseID   primitive.ObjectID               `bson:"courseID"`
+	Assignment assignmentmodels.MongoAssignment `bson:"assignment"`
+	Attempts   int                              `bson:"attempts"`
+	Ungraded   int                              `bson:"ungraded"`
+}
+
+// StudentOverview lists the published assignments of several courses in one
+// aggregation, each with how far the student is on it.
+func (c *CourseInterface) StudentOverview(cids []primitive.ObjectID, uid interface{}) ([]OverviewAssignment, errors.APIError) {
+	assignments := make([]OverviewAssignment, 0)
+
+	ungraded := bson.M{"$or": bson.A{
+		bson.M{"$eq": bson.A{"$inProgress", true}},
+		bson.M{"$and": bson.A{
+			bson.M{"$eq": bson.A{"$manual", true}},
+			bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$review", nil}}, nil}},
+		}},
+	}}
+	query := []interface{}{
+		bson.M{"$match": bson.M{"_id": bson.M{"$in": cids}}},
+		bson.M{"$unwind": "$assignments"},
+		bson.M{
+			"$lookup": bson.M{
+				"as":           "assignment",
+				"from":         "assignments",
+				"localField":   "assignments",
+				"foreignField": "_id",
+			},
+		},
+		bson.M{"$unwind": "$assignment"},
+		// assignments do not store their course
+		bson.M{"$addFields": bson.M{"assignment.courseID": "$_id"}},
+		bson.M{"$replaceRoot": bson.M{"newRoot": "$assignment"}},
+		bson.M{"$match": bson.M{"published": true}},
+		bson.M{"$project": bson.M{"submissions": 0}},
+		bson.M{
+			"$lookup": bson.M{
+				"from": "submissions",
+				"let":  bson.M{"aid": "$_id"},
+				"pipeline": bson.A{
+					bson.M{"$match": bson.M{"$expr": bson.M{"$and": bson.A{
+						bson.M{"$eq": bson.A{"$assignmentID", "$$aid"}},
+						bson.M{"$eq": bson.A{"$userID", uid}},
+					}}}},
+					bson.M{"$match": bson.M{"refunded": bson.M{"$ne": true}, "withdrawn": bson.M{"$ne": true}}},
+					bson.M{"$group": bson.M{
+						"_id":      nil,
+						"attempts": bson.M{"$max": "$attemptNumber"},
+						"ungraded": bson.M{"$sum": bson.M{"$cond": bson.A{ungraded, 1, 0}}},
+					}},
+				},
+				"as": "status",
+			},
+		},
+		bson.M{"$project": bson.M{
+			"_id":        0,
+			"courseID":   1,
+			"assignment": "$$ROOT",
+			"attempts":   bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$status.attempts", 0}}, 0}},
+			"ungraded":   bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$status.ungraded", 0}}, 0}},
+		}},
+	}
+
+	cur, err := c.col.Aggregate(c.ctx, query, options.Aggregate())
+	if err != nil {
+		return assignments, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(c.ctx) {
+		var assignment OverviewAssignment
+		err = cur.Decode(&assignment)
+		if err != nil {
+			return assignments, errors.ErrorInvalidBSON
+		}
+
+		assignments = append(assignments, assignment)
+	}
+
+	return assignments, nil
+}
+
+// GetGradesAsCSV builds the grade sheet of an assignment. Withdrawn students are
+// only included, with a trailing withdrawn column, when includeWithdrawn is set.
+func (c *CourseInterface) GetGradesAsCSV(aid, cid interface{}, includeWithdrawn bool) (*bytes.Buffer, string, int64, errors.APIError) {
+	userLookup := func(userType string) bson.M {
+		return bson.M{
+			"$lookup": bson.M{
+				"from": "users",
+				"let":  bson.M{"userType": bson.M{"$ifNull": bson.A{"$" + userType, bson.A{}}}},
+				"pipeline": bson.A{
+					bson.M{"$match": bson.M{"$expr": bson.M{"$in": bson.A{"$_id", "$$userType"}}}},
+					bson.M{"$project": bson.M{"admin": 0, "email": 0, "enrolledCourses": 0, "password": 0}},
+					bson.M{
+						"$lookup": bson.M{
+							"from": "submissions",
+							"let":  bson.M{"uid": "$_id"},
+							"pipeline": bson.A{
+								bson.M{
+									"$match": bson.M{
+										"$expr": bson.M{
+											"$and": bson.A{
+												bson.M{"$eq": bson.A{"$assignmentID", aid}},
+												bson.M{"$eq": bson.A{"$userID", "$$uid"}},
+											},
+										},
+									},
+								},
+								bson.M{"$sort": bson.M{"submissionDate": -1}},
+								bson.M{"$project": bson.M{"_id": 0, "assignmentID": 0, "userID": 0, "file": 0}},
+								bson.M{"$limit": 1},
+							},
+							"as": "submissions",
+						},
+					},
+				},
+				"as": userType,
+			},
+		}
+	}
+
+	query := []interface{}{
+		bson.M{"$match": bson.M{"_id": cid}},
+		userLookup("students"),
+		userLookup("withdrawn"),
+		bson.M{
+			"$project": bson.M{
+				"_id":         0,
+				"assignments": 0,
+				"assistants":  0,
+				"department":  0,
+				"longName":    0,
+				"number":      0,
+				"professors":  0,
+				"section":     0,
+				"semester":    0,
+			},
+		},
+	}
+
+	var results forms.GradeAggQuery
+	cur, err := c.analytics.Aggregate(
+		c.ctx,
+		query,
+		options.Aggregate(),
+	)
+	if err != nil {
+		return nil, "", 0, errors.ErrorInvalidBSON
+	}
+
+	for cur.Next(c.ctx) {
+		err = cur.Decode(&results)
+		if err != nil {
+			return nil, "", 0, errors.ErrorResourceNotFound
+		}
+	}
+
+	records := [][]string{
+		{"First Name", "Last Name", "Grade", "TestCases", "Attempt Number", "Submission Time"},
+	}
+
+	students := results.Students
+	if includeWithdrawn {
+		records[0] = append(records[0], "Withdrawn")
+		students = append(students, results.Withdrawn...)
+	}
+
+	for index, student := range students {
+		var grade, attempt string
+		if len(student.Subs) > 0 {
+			sub := student.Subs[0]
+			grade = "100"
+			if sub.GradeOverride != nil {
+				grade = strconv.FormatFloat(sub.GradeOverride.Grade, 'f', -1, 64)
+			}
+			attempt = strconv.Itoa(sub.Attempt)
+		} else {
+			grade = "0"
+			attempt = "0"
+		}
+		record := []string{student.First, student.Last, grade, attempt, "0"}
+		if includeWithdrawn {
+			record = append(record, strconv.FormatBool(index >= len(results.Students)))
+		}
+		records = append(records, record)
+	}
+
+	csvBytes := &bytes.Buffer{}
+	writer := csv.NewWriter(csvBytes)
+	err = writer.WriteAll(records)
+	if err != nil {
+		return nil, "", 0, errors.ErrorFailedToWriteCSV
+	}
+	writer.Flush()
+
+	return csvBytes, "filename", int64(csvBytes.Len()), nil
+}
+
+// DefaultRetentionPolicy is used by courses without their own policy. By
+// default submission files are kept for two years and discussions forever.
+func DefaultRetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{
+		SubmissionFileDays: config.C.RetentionSubmissionFileDays,
+		DiscussionDays:     config.C.RetentionDiscussionDays,
+	}
+}
+
+// RetentionPolicy returns the course's policy, or the default one if it has none.
+func (m *MongoCourse) RetentionPolicy() RetentionPolicy {
+	if m.Retention == nil {
+		return DefaultRetentionPolicy()
+	}
+
+	return *m.Retention
+}
+
+func (c *CourseInterface) SetRetention(cid interface{}, endDate primitive.DateTime, retention *RetentionPolicy) errors.APIError {
+	_, err := c.col.UpdateOne(
+		c.ctx,
+		bson.M{"_id": cid},
+		bson.M{"$set": bson.M{"endDate": endDate, "retention": retention}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// DefaultQuota is used by courses without their own quota, configured with
+// COURSE_STORAGE_QUOTA_MB, COURSE_GRADING_MINUTES_QUOTA and COURSE_QUOTA_HARD.
+func DefaultQuota() Quota {
+	return Quota{
+		StorageMB:      config.C.CourseStorageQuotaMB,
+		GradingMinutes: config.C.CourseGradingMinutesQuota,
+		Hard:           config.C.CourseQuotaHard,
+	}
+}
+
+// QuotaLimits returns the course's quota, or the default one if it has none.
+func (m *MongoCourse) QuotaLimits() Quota {
+	if m.Quota == nil {
+		return DefaultQuota()
+	}
+
+	return *m.Quota
+}
+
+// QuotaExceeded reports whether the course has used up its storage, counting
+// extraBytes about to be stored, or its grading minutes.
+func (m *MongoCourse) QuotaExceeded(extraBytes int64) bool {
+	quota := m.QuotaLimits()
+	if quota.StorageMB > 0 && m.Usage.StorageBytes+extraBytes > int64(quota.StorageMB)<<20 {
+		return true
+	}
+
+	return quota.GradingMinutes > 0 && m.Usage.GradingSeconds >= float64(quota.GradingMinutes*60)
+}
+
+// SetQuota gives a course its own quota, nil returns it to the default.
+func (c *CourseInterface) SetQuota(cid interface{}, quota *Quota) errors.APIError {
+	update := bson.M{"$unset": bson.M{"quota": "", "usage.warnedAt": ""}}
+	if quota != nil {
+		update = bson.M{"$set": bson.M{"quota": quota}, "$unset": bson.M{"usage.warnedAt": ""}}
+	}
+
+	res, err := c.col.UpdateOne(c.ctx, bson.M{"_id": cid}, update)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.ErrorResourceNotFound
+	}
+
+	return nil
+}
+
+// AddUsage counts stored bytes and grading seconds against a course's quota.
+func (c *CourseInterface) AddUsage(cid interface{}, bytes int64, seconds float64) errors.APIError {
+	_, err := c.col.UpdateOne(
+		c.ctx,
+		bson.M{"_id": cid},
+		bson.M{"$inc": bson.M{"usage.storageBytes": bytes, "usage.gradingSeconds": seconds}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// MarkQuotaWarned records the professors were told the course's quota is used
+// up, reporting false when they already were.
+func (c *CourseInterface) MarkQuotaWarned(cid interface{}) (bool, errors.APIError) {
+	res, err := c.col.UpdateOne(
+		c.ctx,
+		bson.M{"_id": cid, "usage.warnedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"usage.warnedAt": utils.TimeToDateTime(time.Now())}},
+	)
+	if err != nil {
+		return false, errors.ErrorDatabaseFailedUpdate
+	}
+
+	return res.ModifiedCount > 0, nil
+}
+
+// EndedWithDataToPurge returns the courses that have ended and still hold data
+// a retention policy may purge.
+// FindByOrganization lists an organization's courses.
+func (c *CourseInterface) FindByOrganization(oid interface{}) ([]MongoCourse, errors.APIError) {
+	courses := make([]MongoCourse, 0)
+	cur, err := c.col.Find(
+		c.ctx,
+		bson.M{"organizationID": oid},
+		options.Find().SetSort(bson.D{{"semester", -1}, {"department", 1}, {"number", 1}}),
+	)
+	if err != nil {
+		return courses, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(c.ctx) {
+		var course MongoCourse
+		err = cur.Decode(&course)
+		if err != nil {
+			return courses, errors.ErrorInvalidBSON
+		}
+
+		courses = append(courses, course)
+	}
+
+	return courses, nil
+}
+
+func (c *CourseInterface) EndedWithDataToPurge() ([]MongoCourse, errors.APIError) {
+	courses := make([]MongoCourse, 0)
+	cur, err := c.col.Find(
+		c.ctx,
+		bson.M{
+			"endDate": bson.M{"$lte": primitive.DateTime(time.Now().UnixNano() / 1000000)},
+			"$or": bson.A{
+				bson.M{"submissionFilesPurged": bson.M{"$ne": true}},
+				bson.M{"discussionsPurged": bson.M{"$ne": true}},
+			},
+		},
+		options.Find(),
+	)
+	if err != nil {
+		return courses, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(c.ctx) {
+		var course MongoCourse
+		err = cur.Decode(&course)
+		if err != nil {
+			return courses, errors.ErrorInvalidBSON
+		}
+
+		courses = append(courses, course)
+	}
+
+	return courses, nil
+}
+
+func (c *CourseInterface) AddArchive(cid interface{}, archive CourseArchive) errors.APIError {
+	_, err := c.col.UpdateOne(
+		c.ctx,
+		bson.M{"_id": cid},
+		bson.M{"$push": bson.M{"archives": archive}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// MarkPurged records which kinds of course data a retention policy has purged.
+func (c *CourseInterface) MarkPurged(cid interface{}, submissionFiles, discussions bool) errors.APIError {
+	set := bson.M{}
+	if submissionFiles {
+		set["submissionFilesPurged"] = true
+	}
+	if discussions {
+		set["discussionsPurged"] = true
+	}
+	if len(set) == 0 {
+		return nil
+	}
+
+	_, err := c.col.UpdateOne(c.ctx, bson.M{"_id": cid}, bson.M{"$set": set})
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// DefaultGradeScale is used by courses without their own scale.
+func DefaultGradeScale() GradeScale {
+	return GradeScale{
+		Cutoffs: []GradeCutoff{
+			{"A", 93}, {"A-", 90}, {"B+", 87}, {"B", 83}, {"B-", 80},
+			{"C+", 77}, {"C", 73}, {"C-", 70}, {"D", 60}, {"F", 0},
+		},
+	}
+}
+
+// Scale returns the course's scale, or the default one if it has none.
+func (m *MongoCourse) Scale() GradeScale {
+	if m.GradeScale == nil {
+		return DefaultGradeScale()
+	}
+
+	return *m.GradeScale
+}
+
+// NewGradeScale validates a grade scale form. Without cutoffs the default
+// ones are used.
+func NewGradeScale(form forms.UpdateGradeScaleForm) (*GradeScale, errors.APIError) {
+	scale := DefaultGradeScale()
+	if len(form.Cutoffs) > 0 {
+		scale.Cutoffs = make([]GradeCutoff, len(form.Cutoffs))
+		for index, cutoff := range form.Cutoffs {
+			if cutoff.Letter == "" || cutoff.Min < 0 {
+				return nil, errors.ErrorInvalidGradeScale
+			}
+			scale.Cutoffs[index] = GradeCutoff(cutoff)
+		}
+		sort.SliceStable(scale.Cutoffs, func(i, j int) bool {
+			return scale.Cutoffs[i].Min > scale.Cutoffs[j].Min
+		})
+	}
+
+	if form.Curve != nil {
+		curve := Curve{Type: form.Curve.Type}
+		switch form.Curve.Type {
+		case CurveShift:
+			curve.Shift = form.Curve.Shift
+		case CurvePercentile:
+			if len(form.Curve.Points) == 0 {
+				return nil, errors.ErrorInvalidGradeScale
+			}
+			for _, point := range form.Curve.Points {
+				if point.Percentile < 0 || point.Percentile > 100 {
+					return nil, errors.ErrorInvalidGradeScale
+				}
+				curve.Points = append(curve.Points, CurvePoint(point))
+			}
+			sort.Slice(curve.Points, func(i, j int) bool {
+				return curve.Points[i].Percentile < curve.Points[j].Percentile
+			})
+		default:
+			return nil, errors.ErrorInvalidGradeScale
+		}
+		scale.Curve = &curve
+	}
+
+	return &scale, nil
+}
+
+// percentileScore interpolates the score a percentile is curved to.
+func (c *Curve) percentileScore(percentile float64) float64 {
+	points := c.Points
+	if percentile <= points[0].Percentile {
+		return points[0].Score
+	}
+
+	for index := 1; index < len(points); index++ {
+		low, high := points[index-1], points[index]
+		if percentile <= high.Percentile {
+			if high.Percentile == low.Percentile {
+				return high.Score
+			}
+			return low.Score + (high.Score-low.Score)*(percentile-low.Percentile)/(high.Percentile-low.Percentile)
+		}
+	}
+
+	return points[len(points)-1].Score
+}
+
+// Apply curves every total of a class. Percentile curves rank each total
+// against the others.
+func (g *GradeScale) Apply(totals []float64) []float64 {
+	curved := make([]float64, len(totals))
+	copy(curved, totals)
+	if g.Curve == nil {
+		return curved
+	}
+
+	for index, total := range totals {
+		switch g.Curve.Type {
+		case CurveShift:
+			curved[index] = total + g.Curve.Shift
+		case CurvePercentile:
+			below, equal := 0, 0
+			for _, other := range totals {
+				if other < total {
+					below++
+				} else if other == total {
+					equal++
+				}
+			}
+			percentile := 100 * (float64(below) + float64(equal)/2) / float64(len(totals))
+			curved[index] = math.Max(total, g.Curve.percentileScore(percentile))
+		}
+	}
+
+	return curved
+}
+
+// Letter the letter grade a curved total earns, empty when it is below every
+// cutoff.
+func (g *GradeScale) Letter(total float64) string {
+	for _, cutoff := range g.Cutoffs {
+		if total >= cutoff.Min {
+			return cutoff.Letter
+		}
+	}
+
+	return ""
+}
+
+// SetGradeScale stores the course's grade scale.
+func (c *CourseInterface) SetGradeScale(cid interface{}, scale *GradeScale) errors.APIError {
+	_, err := c.col.UpdateOne(
+		c.ctx,
+		bson.M{"_id": cid},
+		bson.M{"$set": bson.M{"gradeScale": scale}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// NewOfficeHours validates a course's office hours.
+func NewOfficeHours(form forms.UpdateOfficeHoursForm) ([]OfficeHour, errors.APIError) {
+	since := utils.TimeToDateTime(time.Now())
+	hours := make([]OfficeHour, len(form.OfficeHours))
+	for index, hour := range form.OfficeHours {
+		start, errs := time.Parse("15:04", hour.Start)
+		if errs != nil {
+			return nil, errors.ErrorInvalidOfficeHours
+		}
+		end, errs := time.Parse("15:04", hour.End)
+		if errs != nil || !end.After(start) || hour.Weekday < 0 || hour.Weekday > 6 || !utils.ValidTimezone(hour.Timezone) {
+			return nil, errors.ErrorInvalidOfficeHours
+		}
+
+		hours[index] = OfficeHour{
+			ID:       primitive.NewObjectID(),
+			Host:     hour.Host,
+			Weekday:  time.Weekday(hour.Weekday),
+			Start:    hour.Start,
+			End:      hour.End,
+			Timezone: hour.Timezone,
+			Location: hour.Location,
+			Since:    since,
+		}
+	}
+
+	return hours, nil
+}
+
+// FirstOccurrence when the office hour is first held, the first of its
+// weekdays on or after Since.
+func (o *OfficeHour) FirstOccurrence() (time.Time, time.Time) {
+	loc := utils.LoadLocation(o.Timezone)
+	since := utils.DateTimeToTime(o.Since).In(loc)
+	day := since.AddDate(0, 0, (int(o.Weekday)-int(since.Weekday())+7)%7)
+
+	start, _ := time.Parse("15:04", o.Start)
+	end, _ := time.Parse("15:04", o.End)
+
+	return time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, loc),
+		time.Date(day.Year(), day.Month(), day.Day(), end.Hour(), end.Minute(), 0, 0, loc)
+}
+
+// SetOfficeHours replaces the course's office hours.
+func (c *CourseInterface) SetOfficeHours(cid interface{}, hours []OfficeHour) errors.APIError {
+	_, err := c.col.UpdateOne(
+		c.ctx,
+		bson.M{"_id": cid},
+		bson.M{"$set": bson.M{"officeHours": hours}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// FindByIDs the courses with the given ids.
+func (c *CourseInterface) FindByIDs(cids []primitive.ObjectID) ([]MongoCourse, errors.APIError) {
+	courses := make([]MongoCourse, 0)
+	cur, err := c.col.Find(c.ctx, bson.M{"_id": bson.M{"$in": cids}}, options.Find())
+	if err != nil {
+		return courses, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(c.ctx) {
+		var course MongoCourse
+		err = cur.Decode(&course)
+		if err != nil {
+			return courses, errors.ErrorInvalidBSON
+		}
+
+		courses = append(courses, course)
+	}
+
+	return courses, nil
+}
+
+// NewSections validates a course's sections: names are unique, students and
+// assistants belong to the course and a student is in one section at most.
+func NewSections(course *MongoCourse, form forms.UpdateSectionsForm) ([]Section, errors.APIError) {
+	names := make(map[string]bool)
+	placed := make(map[primitive.ObjectID]bool)
+	sections := make([]Section, len(form.Sections))
+	for index, section := range form.Sections {
+		if section.Name == "" || names[section.Name] {
+			return nil, errors.ErrorInvalidSections
+		}
+		names[section.Name] = true
+
+		for _, suid := range section.Students {
+			if placed[suid] || !containsID(course.Students, suid) {
+				return nil, errors.ErrorInvalidSections
+			}
+			placed[suid] = true
+		}
+
+		for _, auid := range section.Assistants {
+			if !containsID(course.Assistants, auid) {
+				return nil, errors.ErrorInvalidSections
+			}
+		}
+
+		sections[index] = Section{section.Name, section.Students, section.Assistants}
+		if sections[index].Students == nil {
+			sections[index].Students = make([]primitive.ObjectID, 0)
+		}
+		if sections[index].Assistants == nil {
+			sections[index].Assistants = make([]primitive.ObjectID, 0)
+		}
+	}
+
+	return sections, nil
+}
+
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *CourseInterface) SetSections(cid interface{}, sections []Section, scoped bool) errors.APIError {
+	_, err := c.col.UpdateOne(
+		c.ctx,
+		bson.M{"_id": cid},
+		bson.M{"$set": bson.M{"sections": sections, "sectionScopedGrading": scoped}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// NewPolicyTemplates validates a course's policy templates: names are unique
+// and their settings are ones an assignment could have.
+func NewPolicyTemplates(form forms.UpdatePolicyTemplatesForm) ([]PolicyTemplate, errors.APIError) {
+	names := make(map[string]bool)
+	templates := make([]PolicyTemplate, len(form.Policies))
+	for index, policy := range form.Policies {
+		if policy.Name == "" || names[policy.Name] {
+			return nil, errors.ErrorInvalidPolicyTemplate
+		}
+		names[policy.Name] = true
+
+		if (policy.NumAttempts != nil && *policy.NumAttempts < 1) ||
+			(policy.TimeLimit != nil && *policy.TimeLimit < 0) ||
+			(policy.CooldownMinutes != nil && *policy.CooldownMinutes < 0) {
+			return nil, errors.ErrorInvalidPolicyTemplate
+		}
+
+		templates[index] = PolicyTemplate{
+			Name:              policy.Name,
+			NumAttempts:       policy.NumAttempts,
+			TimeLimit:         policy.TimeLimit,
+			CooldownMinutes:   policy.CooldownMinutes,
+			ShowHiddenSummary: policy.ShowHiddenSummary,
+			Attestation:       policy.Attestation,
+		}
+		if policy.LatePolicy != nil {
+			late := assignmentmodels.LatePolicy(*policy.LatePolicy)
+			if err := assignmentmodels.CheckLatePolicy(&late); err != nil {
+				return nil, err
+			}
+			templates[index].LatePolicy = &late
+		}
+	}
+
+	return templates, nil
+}
+
+func (c *CourseInterface) SetPolicyTemplates(cid interface{}, templates []PolicyTemplate) errors.APIError {
+	_, err := c.col.UpdateOne(
+		c.ctx,
+		bson.M{"_id": cid},
+		bson.M{"$set": bson.M{"policyTemplates": templates}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// SetResearchConsent gives or, with nil, withdraws the course's consent to
+// research exports.
+func (c *CourseInterface) SetResearchConsent(cid interface{}, consent *ResearchConsent) errors.APIError {
+	update := bson.M{"$unset": bson.M{"researchConsent": ""}}
+	if consent != nil {
+		update = bson.M{"$set": bson.M{"researchConsent": consent}}
+	}
+
+	res, err := c.col.UpdateOne(c.ctx, bson.M{"_id": cid}, update)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.ErrorResourceNotFound
+	}
+
+	return nil
+}
+
+// WithResearchConsent the courses that consented to research exports.
+func (c *CourseInterface) WithResearchConsent() ([]MongoCourse, errors.APIError) {
+	courses := make([]MongoCourse, 0)
+	cur, err := c.col.Find(c.ctx, bson.M{"researchConsent": bson.M{"$exists": true}}, options.Find())
+	if err != nil {
+		return courses, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(c.ctx) {
+		var course MongoCourse
+		err = cur.Decode(&course)
+		if err != nil {
+			return courses, errors.ErrorInvalidBSON
+		}
+
+		courses = append(courses, course)
+	}
+
+	return courses, nil
+}
+
+// FindPolicyTemplate the policy template of the given name.
+func (m *MongoCourse) FindPolicyTemplate(name string) (PolicyTemplate, bool) {
+	for _, template := range m.PolicyTemplates {
+		if template.Name == name {
+			return template, true
+		}
+	}
+
+	return PolicyTemplate{}, false
+}
+
+// Apply sets the template's settings on a new assignment, except those the
+// form was given, by their form field, which override it.
+func (t *PolicyTemplate) Apply(form *forms.CreateAssignmentPostForm, given func(field string) bool) {
+	form.Policy = t.Name
+	if t.NumAttempts != nil && !given("numAttempts") {
+		form.NumAttempts = *t.NumAttempts
+	}
+	if t.TimeLimit != nil && !given("timeLimit") {
+		form.TimeLimit = *t.TimeLimit
+	}
+	if t.CooldownMinutes != nil && !given("cooldownMinutes") {
+		form.CooldownMinutes = *t.CooldownMinutes
+	}
+	if t.LatePolicy != nil && !given("latePolicy") {
+		late := cmsforms.LatePolicy(*t.LatePolicy)
+		form.LatePolicy = &late
+	}
+	if t.ShowHiddenSummary != nil && !given("showHiddenSummary") {
+		form.ShowHiddenSummary = *t.ShowHiddenSummary
+	}
+	if t.Attestation != nil && !given("attestation") {
+		form.Attestation = *t.Attestation
+	}
+}
+
+// FindSection the section of the given name.
+func (m *MongoCourse) FindSection(name string) (Section, bool) {
+	for _, section := range m.Sections {
+		if section.Name == name {
+			return section, true
+		}
+	}
+
+	return Section{}, false
+}
+
+// AssistantSections the sections an assistant is assigned to.
+func (m *MongoCourse) AssistantSections(auid primitive.ObjectID) []Section {
+	sections := make([]Section, 0)
+	for _, section := range m.Sections {
+		if containsID(section.Assistants, auid) {
+			sections = append(sections, section)
+		}
+	}
+
+	return sections
+}
+
+// SectionAssistants the assistants of a student's section, none when the
+// student is in no section.
+func (m *MongoCourse) SectionAssistants(suid primitive.ObjectID) []primitive.ObjectID {
+	for _, section := range m.Sections {
+		if containsID(section.Students, suid) {
+			return section.Assistants
+		}
+	}
+
+	return nil
+}
+
+// AssistantGrades reports whether an assistant may grade a student. Unless
+// grading is scoped to sections, or the assistant has no section, assistants
+// grade every student.
+func (m *MongoCourse) AssistantGrades(auid, suid primitive.ObjectID) bool {
+	if !m.SectionScopedGrading {
+		return true
+	}
+
+	sections := m.AssistantSections(auid)
+	if len(sections) == 0 {
+		return true
+	}
+
+	for _, section := range sections {
+		if containsID(section.Students, suid) {
+			return true
+		}
+	}
+
+	return false
 }
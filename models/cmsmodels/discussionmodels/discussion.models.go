@@ -0,0 +1,354 @@
+package discussionmodels
+
+import (
+	"context"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	"backend/config"
+	"backend/errors"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+type (
+	// Post a single message in a thread. The first post of a thread is the question.
+	Post struct {
+		ID         primitive.ObjectID `bson:"_id" json:"id" binding:"required"`
+		AuthorID   primitive.ObjectID `bson:"authorID" json:"authorID" binding:"required"`
+		AuthorName string             `bson:"authorName" json:"authorName" binding:"required"`
+		Body       string             `bson:"body" json:"body" binding:"required"`
+		Anonymous  bool               `bson:"anonymous" json:"anonymous"`
+		Endorsed   bool               `bson:"endorsed" json:"endorsed"`
+		CreatedAt  primitive.DateTime `bson:"createdAt" json:"createdAt" binding:"required"`
+	}
+
+	// ThreadRead keeps track of when a user last read a thread.
+	ThreadRead struct {
+		UserID primitive.ObjectID `bson:"userID" json:"userID" binding:"required"`
+		ReadAt primitive.DateTime `bson:"readAt" json:"readAt" binding:"required"`
+	}
+
+	// MongoThread struct to store a discussion thread attached to an assignment.
+	MongoThread struct {
+		ID           primitive.ObjectID `bson:"_id" json:"id" binding:"required"`
+		CourseID     primitive.ObjectID `bson:"courseID" json:"courseID" binding:"required"`
+		AssignmentID primitive.ObjectID `bson:"assignmentID" json:"assignmentID" binding:"required"`
+		Title        string             `bson:"title" json:"title" binding:"required"`
+		Posts        []Post             `bson:"posts" json:"posts" binding:"required"`
+		ReadBy       []ThreadRead       `bson:"readBy" json:"-"`
+		LastActivity primitive.DateTime `bson:"lastActivity" json:"lastActivity" binding:"required"`
+	}
+
+	// ThreadSummary is the listing view of a thread.
+	ThreadSummary struct {
+		ID           primitive.ObjectID `json:"id"`
+		Title        string             `json:"title"`
+		Question     Post               `json:"question"`
+		Replies      int                `json:"replies"`
+		Answered     bool               `json:"answered"`
+		Unread       bool               `json:"unread"`
+		LastActivity primitive.DateTime `json:"lastActivity"`
+	}
+
+	DiscussionInterface struct {
+		ctx context.Context
+		col *mongo.Collection
+	}
+)
+
+func New() *DiscussionInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+	col := tyrgin.GetMongoCollection("threads", db)
+
+	return &DiscussionInterface{
+		context.Background(),
+		col,
+	}
+}
+
+func now() primitive.DateTime {
+	return primitive.DateTime(time.Now().UnixNano() / 1000000)
+}
+
+// NewPost creates a post authored now.
+func NewPost(uid primitive.ObjectID, authorName, body string, anonymous bool) Post {
+	return Post{
+		ID:         primitive.NewObjectID(),
+		AuthorID:   uid,
+		AuthorName: authorName,
+		Body:       body,
+		Anonymous:  anonymous,
+		Endorsed:   false,
+		CreatedAt:  now(),
+	}
+}
+
+// Redact hides the author of anonymous posts from students other than the author.
+func (t *MongoThread) Redact(uid primitive.ObjectID, role string) {
+	if role != "student" {
+		return
+	}
+
+	for index := range t.Posts {
+		post := &t.Posts[index]
+		if post.Anonymous && post.AuthorID != uid {
+			post.AuthorID = primitive.NilObjectID
+			post.AuthorName = "Anonymous"
+		}
+	}
+}
+
+// Unread reports whether the thread has activity the user has not seen.
+func (t *MongoThread) Unread(uid primitive.ObjectID) bool {
+	for _, read := range t.ReadBy {
+		if read.UserID == uid {
+			return read.ReadAt < t.LastActivity
+		}
+	}
+
+	return true
+}
+
+func (t *MongoThread) Summary(uid primitive.ObjectID) ThreadSummary {
+	answered := false
+	for _, post := range t.Posts {
+		if post.Endorsed {
+			answered = true
+		}
+	}
+
+	return ThreadSummary{
+		ID:           t.ID,
+		Title:        t.Title,
+		Question:     t.Posts[0],
+		Replies:      len(t.Posts) - 1,
+		Answered:     answered,
+		Unread:       t.Unread(uid),
+		LastActivity: t.LastActivity,
+	}
+}
+
+func (d *DiscussionInterface) Create(cid, aid interface{}, title string, question Post) (*MongoThread, errors.APIError) {
+	thread := MongoThread{
+		ID:           primitive.NewObjectID(),
+		CourseID:     cid.(primitive.ObjectID),
+		AssignmentID: aid.(primitive.ObjectID),
+		Title:        title,
+		Posts:        []Post{question},
+		ReadBy:       []ThreadRead{{UserID: question.AuthorID, ReadAt: question.CreatedAt}},
+		LastActivity: question.CreatedAt,
+	}
+
+	_, err := d.col.InsertOne(d.ctx, &thread, options.InsertOne())
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedCreate
+	}
+
+	return &thread, nil
+}
+
+func (d *DiscussionInterface) Get(tid, cid interface{}) (*MongoThread, errors.APIError) {
+	var thread *MongoThread
+	res := d.col.FindOne(d.ctx, bson.M{"_id": tid, "courseID": cid}, options.FindOne())
+	res.Decode(&thread)
+
+	if thread == nil {
+		return nil, errors.ErrorResourceNotFound
+	}
+
+	return thread, nil
+}
+
+// GetByAssignment lists an assignment's threads, most recently active first.
+func (d *DiscussionInterface) GetByAssignment(aid interface{}) ([]MongoThread, errors.APIError) {
+	threads := make([]MongoThread, 0)
+	cur, err := d.col.Find(
+		d.ctx,
+		bson.M{"assignmentID": aid},
+		options.Find().SetSort(bson.M{"lastActivity": -1}),
+	)
+	if err != nil {
+		return threads, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(d.ctx) {
+		var thread MongoThread
+		err = cur.Decode(&thread)
+		if err != nil {
+			return threads, errors.ErrorInvalidBSON
+		}
+
+		threads = append(threads, thread)
+	}
+
+	return threads, nil
+}
+
+func (d *DiscussionInterface) AddPost(tid interface{}, post Post) errors.APIError {
+	_, err := d.col.UpdateOne(
+		d.ctx,
+		bson.M{"_id": tid},
+		bson.M{
+			"$push": bson.M{"posts": &post},
+			"$set":  bson.M{"lastActivity": post.CreatedAt},
+		},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// Endorse marks (or unmarks) a post as a staff endorsed answer.
+func (d *DiscussionInterface) Endorse(tid, pid interface{}, endorsed bool) errors.APIError {
+	res, err := d.col.UpdateOne(
+		d.ctx,
+		bson.M{"_id": tid, "posts._id": pid},
+		bson.M{
+			"$set": bson.M{
+				"posts.$.endorsed": endorsed,
+				"lastActivity":     now(),
+			},
+		},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.ErrorResourceNotFound
+	}
+
+	return nil
+}
+
+// MarkRead records that the user has seen the thread as of now.
+func (d *DiscussionInterface) MarkRead(tid, uid interface{}) errors.APIError {
+	_, err := d.col.UpdateOne(
+		d.ctx,
+		bson.M{"_id": tid},
+		bson.M{"$pull": bson.M{"readBy": bson.M{"userID": uid}}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	_, err = d.col.UpdateOne(
+		d.ctx,
+		bson.M{"_id": tid},
+		bson.M{"$push": bson.M{"readBy": ThreadRead{UserID: uid.(primitive.ObjectID), ReadAt: now()}}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+func (d *DiscussionInterface) DeleteByAssignmentID(aid interface{}) errors.APIError {
+	_, err := d.col.DeleteMany(d.ctx, bson.M{"assignmentID": aid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+func (d *DiscussionInterface) GetByCourse(cid interface{}) ([]MongoThread, errors.APIError) {
+	threads := make([]MongoThread, 0)
+	cur, err := d.col.Find(
+		d.ctx,
+		bson.M{"courseID": cid},
+		options.Find().SetSort(bson.M{"lastActivity": 1}).SetProjection(bson.M{"readBy": 0}),
+	)
+	if err != nil {
+		return threads, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(d.ctx) {
+		var thread MongoThread
+		err = cur.Decode(&thread)
+		if err != nil {
+			return threads, errors.ErrorInvalidBSON
+		}
+
+		threads = append(threads, thread)
+	}
+
+	return threads, nil
+}
+
+func (d *DiscussionInterface) DeleteByCourseID(cid interface{}) errors.APIError {
+	_, err := d.col.DeleteMany(d.ctx, bson.M{"courseID": cid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+// GetPostsByAuthor returns every post a user has written along with the thread
+// it belongs to. Only the user's own posts are kept on each thread.
+func (d *DiscussionInterface) GetPostsByAuthor(uid primitive.ObjectID) ([]MongoThread, errors.APIError) {
+	threads := make([]MongoThread, 0)
+	cur, err := d.col.Find(
+		d.ctx,
+		bson.M{"posts.authorID": uid},
+		options.Find().SetProjection(bson.M{"readBy": 0}),
+	)
+	if err != nil {
+		return threads, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(d.ctx) {
+		var thread MongoThread
+		err = cur.Decode(&thread)
+		if err != nil {
+			return threads, errors.ErrorInvalidBSON
+		}
+
+		posts := make([]Post, 0)
+		for _, post := range thread.Posts {
+			if post.AuthorID == uid {
+				posts = append(posts, post)
+			}
+		}
+		thread.Posts = posts
+		threads = append(threads, thread)
+	}
+
+	return threads, nil
+}
+
+// AnonymizeAuthor replaces the author name on every post a user wrote so it
+// no longer identifies them, and forgets which threads they have read.
+func (d *DiscussionInterface) AnonymizeAuthor(uid primitive.ObjectID, name string) errors.APIError {
+	_, err := d.col.UpdateMany(
+		d.ctx,
+		bson.M{"posts.authorID": uid},
+		bson.M{"$set": bson.M{"posts.$[post].authorName": name}},
+		options.Update().SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{bson.M{"post.authorID": uid}},
+		}),
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	_, err = d.col.UpdateMany(
+		d.ctx,
+		bson.M{"readBy.userID": uid},
+		bson.M{"$pull": bson.M{"readBy": bson.M{"userID": uid}}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
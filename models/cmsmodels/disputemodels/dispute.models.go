@@ -0,0 +1,267 @@
+package disputemodels
+
+import (
+	"context"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	"backend/config"
+	"backend/errors"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+// Statuses a dispute moves through.
+const (
+	StatusOpen     = "open"
+	StatusInReview = "inReview"
+	// StatusResolved closes a dispute without changing the grade.
+	StatusResolved = "resolved"
+	// StatusAdjusted closes a dispute with a grade override.
+	StatusAdjusted = "adjusted"
+)
+
+type (
+	// Message a message in a dispute, from the student or staff.
+	Message struct {
+		ID         primitive.ObjectID `bson:"_id" json:"id"`
+		AuthorID   primitive.ObjectID `bson:"authorID" json:"authorID"`
+		AuthorName string             `bson:"authorName" json:"authorName"`
+		Staff      bool               `bson:"staff" json:"staff"`
+		Body       string             `bson:"body" json:"body"`
+		CreatedAt  primitive.DateTime `bson:"createdAt" json:"createdAt"`
+	}
+
+	// StatusChange a transition of a dispute's status.
+	StatusChange struct {
+		Status    string             `bson:"status" json:"status"`
+		ChangedBy primitive.ObjectID `bson:"changedBy" json:"changedBy"`
+		ChangedAt primitive.DateTime `bson:"changedAt" json:"changedAt"`
+	}
+
+	// MongoDispute a student's appeal of the grade of one of their submissions.
+	MongoDispute struct {
+		ID           primitive.ObjectID `bson:"_id" json:"id"`
+		CourseID     primitive.ObjectID `bson:"courseID" json:"courseID"`
+		AssignmentID primitive.ObjectID `bson:"assignmentID" json:"assignmentID"`
+		SubmissionID primitive.ObjectID `bson:"submissionID" json:"submissionID"`
+		UserID       primitive.ObjectID `bson:"userID" json:"userID"`
+		Status       string             `bson:"status" json:"status"`
+		Messages     []Message          `bson:"messages" json:"messages"`
+		History      []StatusChange     `bson:"history" json:"history"`
+		// GradeOverride the grade the submission was given when the dispute
+		// was resolved as adjusted.
+		GradeOverride *float64           `bson:"gradeOverride,omitempty" json:"gradeOverride,omitempty"`
+		CreatedAt     primitive.DateTime `bson:"createdAt" json:"createdAt"`
+		UpdatedAt     primitive.DateTime `bson:"updatedAt" json:"updatedAt"`
+	}
+
+	DisputeInterface struct {
+		ctx context.Context
+		col *mongo.Collection
+	}
+)
+
+func New() *DisputeInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+	col := tyrgin.GetMongoCollection("disputes", db)
+
+	return &DisputeInterface{
+		context.Background(),
+		col,
+	}
+}
+
+func now() primitive.DateTime {
+	return primitive.DateTime(time.Now().UnixNano() / 1000000)
+}
+
+// ValidStatus reports whether a dispute can be moved to status.
+func ValidStatus(status string) bool {
+	switch status {
+	case StatusOpen, StatusInReview, StatusResolved, StatusAdjusted:
+		return true
+	}
+
+	return false
+}
+
+// Closed reports whether the dispute has been resolved.
+func (m *MongoDispute) Closed() bool {
+	return m.Status == StatusResolved || m.Status == StatusAdjusted
+}
+
+// NewMessage creates a message written now.
+func NewMessage(uid primitive.ObjectID, authorName, body string, staff bool) Message {
+	return Message{
+		ID:         primitive.NewObjectID(),
+		AuthorID:   uid,
+		AuthorName: authorName,
+		Staff:      staff,
+		Body:       body,
+		CreatedAt:  now(),
+	}
+}
+
+// Create opens a dispute on a submission.
+func (d *DisputeInterface) Create(cid, aid, sid, uid primitive.ObjectID, message Message) (*MongoDispute, errors.APIError) {
+	created := now()
+	dispute := MongoDispute{
+		ID:           primitive.NewObjectID(),
+		CourseID:     cid,
+		AssignmentID: aid,
+		SubmissionID: sid,
+		UserID:       uid,
+		Status:       StatusOpen,
+		Messages:     []Message{message},
+		History:      []StatusChange{{StatusOpen, uid, created}},
+		CreatedAt:    created,
+		UpdatedAt:    created,
+	}
+
+	_, err := d.col.InsertOne(d.ctx, &dispute, options.InsertOne())
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedCreate
+	}
+
+	return &dispute, nil
+}
+
+func (d *DisputeInterface) Get(did, cid interface{}) (*MongoDispute, errors.APIError) {
+	var dispute *MongoDispute
+	res := d.col.FindOne(d.ctx, bson.M{"_id": did, "courseID": cid}, options.FindOne())
+	res.Decode(&dispute)
+
+	if dispute == nil {
+		return nil, errors.ErrorResourceNotFound
+	}
+
+	return dispute, nil
+}
+
+// FindOpen returns the dispute on a submission that is not resolved yet.
+func (d *DisputeInterface) FindOpen(sid interface{}) (*MongoDispute, errors.APIError) {
+	var dispute *MongoDispute
+	res := d.col.FindOne(
+		d.ctx,
+		bson.M{"submissionID": sid, "status": bson.M{"$in": bson.A{StatusOpen, StatusInReview}}},
+		options.FindOne(),
+	)
+	res.Decode(&dispute)
+
+	if dispute == nil {
+		return nil, errors.ErrorResourceNotFound
+	}
+
+	return dispute, nil
+}
+
+// Find lists a course's disputes, most recently updated first, optionally
+// only those of a student or with a status.
+func (d *DisputeInterface) Find(cid interface{}, uid *primitive.ObjectID, status string) ([]MongoDispute, errors.APIError) {
+	filter := bson.M{"courseID": cid}
+	if uid != nil {
+		filter["userID"] = *uid
+	}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	disputes := make([]MongoDispute, 0)
+	cur, err := d.col.Find(d.ctx, filter, options.Find().SetSort(bson.M{"updatedAt": -1}))
+	if err != nil {
+		return disputes, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(d.ctx) {
+		var dispute MongoDispute
+		err = cur.Decode(&dispute)
+		if err != nil {
+			return disputes, errors.ErrorInvalidBSON
+		}
+
+		disputes = append(disputes, dispute)
+	}
+
+	return disputes, nil
+}
+
+// AddMessage adds a message to a dispute.
+func (d *DisputeInterface) AddMessage(did interface{}, message Message) errors.APIError {
+	_, err := d.col.UpdateOne(
+		d.ctx,
+		bson.M{"_id": did},
+		bson.M{
+			"$push": bson.M{"messages": &message},
+			"$set":  bson.M{"updatedAt": message.CreatedAt},
+		},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// SetStatus moves a dispute to a status, recording who did it and, for
+// adjusted disputes, the grade the submission was given.
+func (d *DisputeInterface) SetStatus(did interface{}, status string, uid primitive.ObjectID, gradeOverride *float64) errors.APIError {
+	change := StatusChange{status, uid, now()}
+	set := bson.M{"status": status, "updatedAt": change.ChangedAt}
+	if gradeOverride != nil {
+		set["gradeOverride"] = *gradeOverride
+	}
+
+	_, err := d.col.UpdateOne(
+		d.ctx,
+		bson.M{"_id": did},
+		bson.M{
+			"$push": bson.M{"history": &change},
+			"$set":  set,
+		},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+func (d *DisputeInterface) DeleteByAssignmentID(aid interface{}) errors.APIError {
+	_, err := d.col.DeleteMany(d.ctx, bson.M{"assignmentID": aid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+func (d *DisputeInterface) DeleteByCourseID(cid interface{}) errors.APIError {
+	_, err := d.col.DeleteMany(d.ctx, bson.M{"courseID": cid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+// AnonymizeAuthor replaces the name on every message a user wrote.
+func (d *DisputeInterface) AnonymizeAuthor(uid primitive.ObjectID, name string) errors.APIError {
+	_, err := d.col.UpdateMany(
+		d.ctx,
+		bson.M{"messages.authorID": uid},
+		bson.M{"$set": bson.M{"messages.$[message].authorName": name}},
+		options.Update().SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{bson.M{"message.authorID": uid}},
+		}),
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
@@ -0,0 +1,242 @@
+package draftmodels
+
+import (
+	"context"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	"backend/config"
+	"backend/errors"
+	"backend/forms"
+	"backend/utils"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+// Where drafts are saved from.
+const (
+	SourceEditor = "editor"
+	SourceCLI    = "cli"
+)
+
+type (
+	// File a file of a draft. Files are kept as a list since paths are not
+	// valid document keys.
+	File struct {
+		Path string `bson:"path" json:"path"`
+		Text string `bson:"text" json:"text"`
+	}
+
+	// MongoDraft the work in progress a student saved for an assignment,
+	// one per student and assignment, which does not count as an attempt.
+	// It is removed once ExpiresAt, the student's deadline, has passed.
+	MongoDraft struct {
+		ID           primitive.ObjectID `bson:"_id" json:"id"`
+		UserID       primitive.ObjectID `bson:"userID" json:"userID"`
+		AssignmentID primitive.ObjectID `bson:"assignmentID" json:"assignmentID"`
+		CourseID     primitive.ObjectID `bson:"courseID" json:"courseID"`
+		Source       string             `bson:"source" json:"source"`
+		Files        []File             `bson:"files" json:"files"`
+		SavedAt      primitive.DateTime `bson:"savedAt" json:"savedAt"`
+		ExpiresAt    primitive.DateTime `bson:"expiresAt" json:"expiresAt"`
+	}
+
+	DraftInterface struct {
+		ctx context.Context
+		col *mongo.Collection
+	}
+)
+
+func New() *DraftInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+	col := tyrgin.GetMongoCollection("drafts", db)
+
+	return &DraftInterface{
+		context.Background(),
+		col,
+	}
+}
+
+// NewDraft checks the files of a draft could be submitted, and where it is
+// saved from, the editor unless given.
+func NewDraft(form forms.SaveDraftForm) (*MongoDraft, errors.APIError) {
+	source := form.Source
+	if source == "" {
+		source = SourceEditor
+	}
+	if source != SourceEditor && source != SourceCLI {
+		return nil, errors.ErrorInvalidDraft
+	}
+
+	contents := make(map[string][]byte, len(form.Files))
+	for path, text := range form.Files {
+		contents[path] = []byte(text)
+	}
+
+	paths, err := utils.CheckFileTree(contents)
+	if err != nil {
+		return nil, errors.ErrorInvalidDraft
+	}
+
+	files := make([]File, len(paths))
+	for index, path := range paths {
+		files[index] = File{path, form.Files[path]}
+	}
+
+	return &MongoDraft{Source: source, Files: files}, nil
+}
+
+// Buffers the draft's files keyed by their path.
+func (m *MongoDraft) Buffers() map[string]string {
+	buffers := make(map[string]string, len(m.Files))
+	for _, file := range m.Files {
+		buffers[file.Path] = file.Text
+	}
+
+	return buffers
+}
+
+// Save replaces the student's draft of the assignment with draft.
+func (d *DraftInterface) Save(draft *MongoDraft, at time.Time) errors.APIError {
+	draft.SavedAt = utils.TimeToDateTime(at)
+
+	_, err := d.col.UpdateOne(
+		d.ctx,
+		bson.M{"assignmentID": draft.AssignmentID, "userID": draft.UserID},
+		bson.M{
+			"$set": bson.M{
+				"courseID":  draft.CourseID,
+				"source":    draft.Source,
+				"files":     draft.Files,
+				"savedAt":   draft.SavedAt,
+				"expiresAt": draft.ExpiresAt,
+			},
+			"$setOnInsert": bson.M{"_id": primitive.NewObjectID()},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// Get the student's draft of an assignment, nil when they have none.
+func (d *DraftInterface) Get(aid, uid interface{}) (*MongoDraft, errors.APIError) {
+	var draft *MongoDraft
+	err := d.col.FindOne(d.ctx, bson.M{"assignmentID": aid, "userID": uid}, options.FindOne()).Decode(&draft)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedQuery
+	}
+
+	return draft, nil
+}
+
+// Delete discards the student's draft of an assignment.
+func (d *DraftInterface) Delete(aid, uid interface{}) errors.APIError {
+	_, err := d.col.DeleteOne(d.ctx, bson.M{"assignmentID": aid, "userID": uid})
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+// Expired the drafts whose deadline passed before at.
+func (d *DraftInterface) Expired(at time.Time) ([]MongoDraft, errors.APIError) {
+	drafts := make([]MongoDraft, 0)
+	cur, err := d.col.Find(
+		d.ctx,
+		bson.M{"expiresAt": bson.M{"$lt": utils.TimeToDateTime(at)}},
+		options.Find().SetProjection(bson.M{"files": 0}),
+	)
+	if err != nil {
+		return drafts, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(d.ctx) {
+		var draft MongoDraft
+		if err = cur.Decode(&draft); err != nil {
+			return drafts, errors.ErrorInvalidBSON
+		}
+
+		drafts = append(drafts, draft)
+	}
+
+	return drafts, nil
+}
+
+// Extend moves a draft's expiry to the student's new deadline.
+func (d *DraftInterface) Extend(id primitive.ObjectID, expiresAt primitive.DateTime) errors.APIError {
+	_, err := d.col.UpdateOne(d.ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"expiresAt": expiresAt}})
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// DeleteByID removes a draft.
+func (d *DraftInterface) DeleteByID(id primitive.ObjectID) errors.APIError {
+	_, err := d.col.DeleteOne(d.ctx, bson.M{"_id": id})
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+// UserDrafts every draft a user saved.
+func (d *DraftInterface) UserDrafts(uid interface{}) ([]MongoDraft, errors.APIError) {
+	drafts := make([]MongoDraft, 0)
+	cur, err := d.col.Find(d.ctx, bson.M{"userID": uid}, options.Find())
+	if err != nil {
+		return drafts, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(d.ctx) {
+		var draft MongoDraft
+		if err = cur.Decode(&draft); err != nil {
+			return drafts, errors.ErrorInvalidBSON
+		}
+
+		drafts = append(drafts, draft)
+	}
+
+	return drafts, nil
+}
+
+func (d *DraftInterface) DeleteByAssignmentID(aid interface{}) errors.APIError {
+	_, err := d.col.DeleteMany(d.ctx, bson.M{"assignmentID": aid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+func (d *DraftInterface) DeleteByUserID(uid interface{}) errors.APIError {
+	_, err := d.col.DeleteMany(d.ctx, bson.M{"userID": uid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+func (d *DraftInterface) DeleteByCourseID(cid interface{}) errors.APIError {
+	_, err := d.col.DeleteMany(d.ctx, bson.M{"courseID": cid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
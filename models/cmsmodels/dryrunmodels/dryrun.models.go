@@ -0,0 +1,107 @@
+package dryrunmodels
+
+import (
+	"context"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	"backend/config"
+	"backend/errors"
+	"backend/events"
+	"backend/models/cmsmodels/submissionmodels"
+	"backend/utils"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+type (
+	// MongoDryRun a grading run of a reference solution waiting on court
+	// herald. It is stored so court herald's download and callbacks can
+	// reach any instance, not only the one waiting for the report.
+	MongoDryRun struct {
+		// ID the ID of Submission, which court herald knows the run by.
+		ID         primitive.ObjectID               `bson:"_id"`
+		Submission submissionmodels.MongoSubmission `bson:"submission"`
+		StartedAt  primitive.DateTime               `bson:"startedAt"`
+		// Report what court herald reported, nil until it has.
+		Report  *submissionmodels.GradeReport `bson:"report,omitempty"`
+		Errored bool                          `bson:"errored"`
+	}
+
+	DryRunInterface struct {
+		ctx context.Context
+		col *mongo.Collection
+	}
+)
+
+func New() *DryRunInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+	col := tyrgin.GetMongoCollection(events.DryRunsCollection, db)
+
+	return &DryRunInterface{
+		context.Background(),
+		col,
+	}
+}
+
+// Create stores a dry run of a submission, clearing out the runs started
+// before staleBefore, whose instances stopped waiting on them.
+func (d *DryRunInterface) Create(submission submissionmodels.MongoSubmission, staleBefore time.Time) (*MongoDryRun, errors.APIError) {
+	d.col.DeleteMany(d.ctx, bson.M{"startedAt": bson.M{"$lt": utils.TimeToDateTime(staleBefore)}})
+
+	run := MongoDryRun{
+		ID:         submission.ID,
+		Submission: submission,
+		StartedAt:  utils.TimeToDateTime(time.Now()),
+	}
+
+	_, err := d.col.InsertOne(d.ctx, &run, options.InsertOne())
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedCreate
+	}
+
+	return &run, nil
+}
+
+// Get the dry run of a submission id, nil when the id is not one.
+func (d *DryRunInterface) Get(sid interface{}) (*MongoDryRun, errors.APIError) {
+	var run *MongoDryRun
+	err := d.col.FindOne(d.ctx, bson.M{"_id": sid}, options.FindOne()).Decode(&run)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedQuery
+	}
+
+	return run, nil
+}
+
+// Finish records court herald's report of a dry run, the first one it sends.
+func (d *DryRunInterface) Finish(sid primitive.ObjectID, report submissionmodels.GradeReport, errored bool) errors.APIError {
+	_, err := d.col.UpdateOne(
+		d.ctx,
+		bson.M{"_id": sid, "report": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"report": report, "errored": errored}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	events.Changed(events.Change{Collection: events.DryRunsCollection, Operation: events.ChangeUpdate, ID: sid})
+	return nil
+}
+
+// Delete removes a dry run once it is over.
+func (d *DryRunInterface) Delete(sid primitive.ObjectID) errors.APIError {
+	_, err := d.col.DeleteOne(d.ctx, bson.M{"_id": sid})
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
@@ -0,0 +1,236 @@
+package extensionmodels
+
+import (
+	"context"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	"backend/config"
+	"backend/errors"
+	"backend/utils"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+// Statuses an extension request moves through.
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusDenied   = "denied"
+)
+
+type (
+	// StatusChange a step of an extension request, who took it and why.
+	StatusChange struct {
+		Status    string             `bson:"status" json:"status"`
+		ChangedBy primitive.ObjectID `bson:"changedBy" json:"changedBy"`
+		ChangedAt primitive.DateTime `bson:"changedAt" json:"changedAt"`
+		Note      string             `bson:"note,omitempty" json:"note,omitempty"`
+	}
+
+	// MongoExtensionRequest a student's request for a later due date on an
+	// assignment. DueDate is the one granted, which staff may change from
+	// the one requested.
+	MongoExtensionRequest struct {
+		ID               primitive.ObjectID  `bson:"_id" json:"id"`
+		CourseID         primitive.ObjectID  `bson:"courseID" json:"courseID"`
+		AssignmentID     primitive.ObjectID  `bson:"assignmentID" json:"assignmentID"`
+		UserID           primitive.ObjectID  `bson:"userID" json:"userID"`
+		Reason           string              `bson:"reason" json:"reason"`
+		RequestedDueDate primitive.DateTime  `bson:"requestedDueDate" json:"requestedDueDate"`
+		Status           string              `bson:"status" json:"status"`
+		DueDate          *primitive.DateTime `bson:"dueDate,omitempty" json:"dueDate,omitempty"`
+		History          []StatusChange      `bson:"history" json:"history"`
+		CreatedAt        primitive.DateTime  `bson:"createdAt" json:"createdAt"`
+		UpdatedAt        primitive.DateTime  `bson:"updatedAt" json:"updatedAt"`
+	}
+
+	ExtensionInterface struct {
+		ctx context.Context
+		col *mongo.Collection
+	}
+)
+
+func New() *ExtensionInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+	col := tyrgin.GetMongoCollection("extensionrequests", db)
+
+	return &ExtensionInterface{
+		context.Background(),
+		col,
+	}
+}
+
+// ValidStatus reports whether requests can be filtered by status.
+func ValidStatus(status string) bool {
+	switch status {
+	case StatusPending, StatusApproved, StatusDenied:
+		return true
+	}
+
+	return false
+}
+
+// Create files a pending extension request.
+func (e *ExtensionInterface) Create(cid, aid, uid primitive.ObjectID, reason string, requested primitive.DateTime, at time.Time) (*MongoExtensionRequest, errors.APIError) {
+	created := utils.TimeToDateTime(at)
+	request := MongoExtensionRequest{
+		ID:               primitive.NewObjectID(),
+		CourseID:         cid,
+		AssignmentID:     aid,
+		UserID:           uid,
+		Reason:           reason,
+		RequestedDueDate: requested,
+		Status:           StatusPending,
+		History:          []StatusChange{{StatusPending, uid, created, ""}},
+		CreatedAt:        created,
+		UpdatedAt:        created,
+	}
+
+	_, err := e.col.InsertOne(e.ctx, &request, options.InsertOne())
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedCreate
+	}
+
+	return &request, nil
+}
+
+// Get an extension request of a course.
+func (e *ExtensionInterface) Get(erid, cid interface{}) (*MongoExtensionRequest, errors.APIError) {
+	var request *MongoExtensionRequest
+	err := e.col.FindOne(e.ctx, bson.M{"_id": erid, "courseID": cid}, options.FindOne()).Decode(&request)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.ErrorResourceNotFound
+	}
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedQuery
+	}
+
+	return request, nil
+}
+
+// FindPending the student's undecided request for an assignment, nil when
+// they have none.
+func (e *ExtensionInterface) FindPending(aid, uid interface{}) (*MongoExtensionRequest, errors.APIError) {
+	var request *MongoExtensionRequest
+	err := e.col.FindOne(
+		e.ctx,
+		bson.M{"assignmentID": aid, "userID": uid, "status": StatusPending},
+		options.FindOne(),
+	).Decode(&request)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedQuery
+	}
+
+	return request, nil
+}
+
+// Find lists a course's extension requests, oldest first so the queue is
+// worked in order, optionally only those of a student or with a status.
+func (e *ExtensionInterface) Find(cid interface{}, uid *primitive.ObjectID, status string) ([]MongoExtensionRequest, errors.APIError) {
+	filter := bson.M{"courseID": cid}
+	if uid != nil {
+		filter["userID"] = *uid
+	}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	requests := make([]MongoExtensionRequest, 0)
+	cur, err := e.col.Find(e.ctx, filter, options.Find().SetSort(bson.M{"createdAt": 1}))
+	if err != nil {
+		return requests, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(e.ctx) {
+		var request MongoExtensionRequest
+		if err = cur.Decode(&request); err != nil {
+			return requests, errors.ErrorInvalidBSON
+		}
+
+		requests = append(requests, request)
+	}
+
+	return requests, nil
+}
+
+// Decide approves or denies a pending request, with the due date granted
+// when approved. Requests decided already are left as they are.
+func (e *ExtensionInterface) Decide(erid interface{}, status string, by primitive.ObjectID, dueDate *primitive.DateTime, note string, at time.Time) errors.APIError {
+	change := StatusChange{status, by, utils.TimeToDateTime(at), note}
+	set := bson.M{"status": status, "updatedAt": change.ChangedAt}
+	if dueDate != nil {
+		set["dueDate"] = *dueDate
+	}
+
+	res, err := e.col.UpdateOne(
+		e.ctx,
+		bson.M{"_id": erid, "status": StatusPending},
+		bson.M{
+			"$push": bson.M{"history": &change},
+			"$set":  set,
+		},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+	if res.MatchedCount == 0 {
+		return errors.ErrorExtensionDecided
+	}
+
+	return nil
+}
+
+// UserRequests every extension request a user made.
+func (e *ExtensionInterface) UserRequests(uid interface{}) ([]MongoExtensionRequest, errors.APIError) {
+	requests := make([]MongoExtensionRequest, 0)
+	cur, err := e.col.Find(e.ctx, bson.M{"userID": uid}, options.Find())
+	if err != nil {
+		return requests, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(e.ctx) {
+		var request MongoExtensionRequest
+		if err = cur.Decode(&request); err != nil {
+			return requests, errors.ErrorInvalidBSON
+		}
+
+		requests = append(requests, request)
+	}
+
+	return requests, nil
+}
+
+func (e *ExtensionInterface) DeleteByAssignmentID(aid interface{}) errors.APIError {
+	_, err := e.col.DeleteMany(e.ctx, bson.M{"assignmentID": aid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+func (e *ExtensionInterface) DeleteByUserID(uid interface{}) errors.APIError {
+	_, err := e.col.DeleteMany(e.ctx, bson.M{"userID": uid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+func (e *ExtensionInterface) DeleteByCourseID(cid interface{}) errors.APIError {
+	_, err := e.col.DeleteMany(e.ctx, bson.M{"courseID": cid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package gradingmodels
+
+import (
+	"context"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	"backend/config"
+	"backend/errors"
+	"backend/utils"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+type (
+	// MongoGradingTask a student's submission a staff member is responsible
+	// for grading by hand.
+	MongoGradingTask struct {
+		ID           primitive.ObjectID `bson:"_id" json:"id"`
+		CourseID     primitive.ObjectID `bson:"courseID" json:"courseID"`
+		AssignmentID primitive.ObjectID `bson:"assignmentID" json:"assignmentID"`
+		SubmissionID primitive.ObjectID `bson:"submissionID" json:"submissionID"`
+		StudentID    primitive.ObjectID `bson:"studentID" json:"studentID"`
+		GraderID     primitive.ObjectID `bson:"graderID" json:"graderID"`
+		AssignedAt   primitive.DateTime `bson:"assignedAt" json:"assignedAt"`
+		// CompletedAt and CompletedBy are set once the submission is graded.
+		CompletedAt *primitive.DateTime `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+		CompletedBy *primitive.ObjectID `bson:"completedBy,omitempty" json:"completedBy,omitempty"`
+	}
+
+	GradingInterface struct {
+		ctx context.Context
+		col *mongo.Collection
+	}
+)
+
+func New() *GradingInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+	col := tyrgin.GetMongoCollection("gradingtasks", db)
+
+	return &GradingInterface{
+		context.Background(),
+		col,
+	}
+}
+
+// NewTask a task for a grader, not stored yet.
+func NewTask(cid, aid, sid, suid, grader primitive.ObjectID) MongoGradingTask {
+	return MongoGradingTask{
+		ID:           primitive.NewObjectID(),
+		CourseID:     cid,
+		AssignmentID: aid,
+		SubmissionID: sid,
+		StudentID:    suid,
+		GraderID:     grader,
+		AssignedAt:   utils.TimeToDateTime(time.Now()),
+	}
+}
+
+// Done reports whether the task's submission was graded.
+func (m *MongoGradingTask) Done() bool {
+	return m.CompletedAt != nil
+}
+
+func (g *GradingInterface) Create(tasks []MongoGradingTask) errors.APIError {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	documents := make([]interface{}, len(tasks))
+	for index, task := range tasks {
+		documents[index] = task
+	}
+
+	_, err := g.col.InsertMany(g.ctx, documents, options.InsertMany())
+	if err != nil {
+		return errors.ErrorDatabaseFailedCreate
+	}
+
+	return nil
+}
+
+// FindByAssignment the grading tasks of an assignment, only a grader's when
+// one is given.
+func (g *GradingInterface) FindByAssignment(aid interface{}, grader *primitive.ObjectID) ([]MongoGradingTask, errors.APIError) {
+	filter := bson.M{"assignmentID": aid}
+	if grader != nil {
+		filter["graderID"] = *grader
+	}
+
+	tasks := make([]MongoGradingTask, 0)
+	cur, err := g.col.Find(g.ctx, filter, options.Find().SetSort(bson.M{"assignedAt": 1}))
+	if err != nil {
+		return tasks, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(g.ctx) {
+		var task MongoGradingTask
+		err = cur.Decode(&task)
+		if err != nil {
+			return tasks, errors.ErrorInvalidBSON
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	return tasks, nil
+}
+
+// OpenByAssignments the grading tasks of the assignments not completed yet.
+func (g *GradingInterface) OpenByAssignments(aids []primitive.ObjectID) ([]MongoGradingTask, errors.APIError) {
+	tasks := make([]MongoGradingTask, 0)
+	cur, err := g.col.Find(
+		g.ctx,
+		bson.M{"assignmentID": bson.M{"$in": aids}, "completedAt": bson.M{"$exists": false}},
+		options.Find(),
+	)
+	if err != nil {
+		return tasks, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(g.ctx) {
+		var task MongoGradingTask
+		err = cur.Decode(&task)
+		if err != nil {
+			return tasks, errors.ErrorInvalidBSON
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	return tasks, nil
+}
+
+// Get the grading task of a submission of an assignment.
+func (g *GradingInterface) Get(aid, sid interface{}) (*MongoGradingTask, errors.APIError) {
+	var task *MongoGradingTask
+	res := g.col.FindOne(g.ctx, bson.M{"assignmentID": aid, "submissionID": sid}, options.FindOne())
+	res.Decode(&task)
+
+	if task == nil {
+		return nil, errors.ErrorResourceNotFound
+	}
+
+	return task, nil
+}
+
+// Complete marks the task of a submission graded.
+func (g *GradingInterface) Complete(aid, sid interface{}, by primitive.ObjectID) errors.APIError {
+	res, err := g.col.UpdateOne(
+		g.ctx,
+		bson.M{"assignmentID": aid, "submissionID": sid},
+		bson.M{"$set": bson.M{
+			"completedAt": utils.TimeToDateTime(time.Now()),
+			"completedBy": by,
+		}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.ErrorResourceNotFound
+	}
+
+	return nil
+}
+
+func (g *GradingInterface) DeleteByAssignmentID(aid interface{}) errors.APIError {
+	_, err := g.col.DeleteMany(g.ctx, bson.M{"assignmentID": aid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package impersonationmodels
+
+import (
+	"context"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	"backend/config"
+	"backend/errors"
+	"backend/utils"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+type (
+	// MongoImpersonationEntry one request a staff member made while viewing a
+	// course as one of its students. Starting to impersonate is logged too,
+	// with the route that issued the token.
+	MongoImpersonationEntry struct {
+		ID        primitive.ObjectID `bson:"_id" json:"id"`
+		CourseID  primitive.ObjectID `bson:"courseID" json:"courseID"`
+		StaffID   primitive.ObjectID `bson:"staffID" json:"staffID"`
+		StudentID primitive.ObjectID `bson:"studentID" json:"studentID"`
+		Method    string             `bson:"method" json:"method"`
+		Path      string             `bson:"path" json:"path"`
+		At        primitive.DateTime `bson:"at" json:"at"`
+	}
+
+	ImpersonationInterface struct {
+		ctx context.Context
+		col *mongo.Collection
+	}
+)
+
+func New() *ImpersonationInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+	col := tyrgin.GetMongoCollection("impersonations", db)
+
+	return &ImpersonationInterface{
+		context.Background(),
+		col,
+	}
+}
+
+// Log records a request made while impersonating a student.
+func (i *ImpersonationInterface) Log(cid, staff, student primitive.ObjectID, method, path string) errors.APIError {
+	entry := MongoImpersonationEntry{
+		ID:        primitive.NewObjectID(),
+		CourseID:  cid,
+		StaffID:   staff,
+		StudentID: student,
+		Method:    method,
+		Path:      path,
+		At:        utils.TimeToDateTime(time.Now()),
+	}
+
+	_, err := i.col.InsertOne(i.ctx, entry, options.InsertOne())
+	if err != nil {
+		return errors.ErrorDatabaseFailedCreate
+	}
+
+	return nil
+}
+
+// FindByCourse the impersonation log of a course, newest first.
+func (i *ImpersonationInterface) FindByCourse(cid interface{}) ([]MongoImpersonationEntry, errors.APIError) {
+	entries := make([]MongoImpersonationEntry, 0)
+	cur, err := i.col.Find(i.ctx, bson.M{"courseID": cid}, options.Find().SetSort(bson.M{"at": -1}))
+	if err != nil {
+		return entries, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(i.ctx) {
+		var entry MongoImpersonationEntry
+		err = cur.Decode(&entry)
+		if err != nil {
+			return entries, errors.ErrorInvalidBSON
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// DeleteByCourseID removes the impersonation log of a course.
+func (i *ImpersonationInterface) DeleteByCourseID(cid interface{}) errors.APIError {
+	_, err := i.col.DeleteMany(i.ctx, bson.M{"courseID": cid})
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+// DeleteByUserID removes the log entries a user made or was impersonated in.
+func (i *ImpersonationInterface) DeleteByUserID(uid interface{}) errors.APIError {
+	_, err := i.col.DeleteMany(i.ctx, bson.M{"$or": bson.A{bson.M{"staffID": uid}, bson.M{"studentID": uid}}})
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
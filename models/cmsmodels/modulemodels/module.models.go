@@ -0,0 +1,260 @@
+package modulemodels
+
+import (
+	"context"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	"backend/config"
+	"backend/errors"
+	"backend/utils"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+type (
+	// File a file, like lecture slides, handed out in a module.
+	File struct {
+		ID          primitive.ObjectID `bson:"_id" json:"id"`
+		Filename    string             `bson:"filename" json:"filename"`
+		ContentType string             `bson:"contentType" json:"contentType"`
+		Size        int64              `bson:"size" json:"size"`
+		UploadDate  primitive.DateTime `bson:"uploadDate" json:"uploadDate"`
+	}
+
+	// MongoModule a week or topic of a course, grouping its assignments,
+	// announcements and files in the order they are shown. Students only see
+	// published modules.
+	MongoModule struct {
+		ID          primitive.ObjectID `bson:"_id" json:"id"`
+		CourseID    primitive.ObjectID `bson:"courseID" json:"courseID"`
+		Title       string             `bson:"title" json:"title"`
+		Description string             `bson:"description" json:"description"`
+		// Position where the module is in the course, from 0.
+		Position      int                  `bson:"position" json:"position"`
+		StartDate     *primitive.DateTime  `bson:"startDate,omitempty" json:"startDate,omitempty"`
+		Published     bool                 `bson:"published" json:"published"`
+		Assignments   []primitive.ObjectID `bson:"assignments" json:"assignments"`
+		Announcements []primitive.ObjectID `bson:"announcements" json:"announcements"`
+		Files         []File               `bson:"files" json:"files"`
+		CreatedAt     primitive.DateTime   `bson:"createdAt" json:"createdAt"`
+		UpdatedAt     primitive.DateTime   `bson:"updatedAt" json:"updatedAt"`
+	}
+
+	ModuleInterface struct {
+		ctx context.Context
+		col *mongo.Collection
+	}
+)
+
+func New() *ModuleInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+	col := tyrgin.GetMongoCollection("modules", db)
+
+	return &ModuleInterface{
+		context.Background(),
+		col,
+	}
+}
+
+// File the module's file of the given id.
+func (m *MongoModule) File(fid primitive.ObjectID) *File {
+	for index := range m.Files {
+		if m.Files[index].ID == fid {
+			return &m.Files[index]
+		}
+	}
+
+	return nil
+}
+
+// Create adds a module at the end of its course.
+func (m *ModuleInterface) Create(module *MongoModule, at time.Time) errors.APIError {
+	count, err := m.col.CountDocuments(m.ctx, bson.M{"courseID": module.CourseID}, options.Count())
+	if err != nil {
+		return errors.ErrorDatabaseFailedQuery
+	}
+
+	module.ID = primitive.NewObjectID()
+	module.Position = int(count)
+	module.CreatedAt = utils.TimeToDateTime(at)
+	module.UpdatedAt = module.CreatedAt
+	if module.Files == nil {
+		module.Files = make([]File, 0)
+	}
+
+	_, err = m.col.InsertOne(m.ctx, module, options.InsertOne())
+	if err != nil {
+		return errors.ErrorDatabaseFailedCreate
+	}
+
+	return nil
+}
+
+// Get a module of a course.
+func (m *ModuleInterface) Get(mid, cid interface{}) (*MongoModule, errors.APIError) {
+	var module *MongoModule
+	err := m.col.FindOne(m.ctx, bson.M{"_id": mid, "courseID": cid}, options.FindOne()).Decode(&module)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.ErrorResourceNotFound
+	}
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedQuery
+	}
+
+	return module, nil
+}
+
+// ByCourse a course's modules in order, only the published ones for
+// students.
+func (m *ModuleInterface) ByCourse(cid interface{}, role string) ([]MongoModule, errors.APIError) {
+	filter := bson.M{"courseID": cid}
+	if role == "student" {
+		filter["published"] = true
+	}
+
+	modules := make([]MongoModule, 0)
+	cur, err := m.col.Find(m.ctx, filter, options.Find().SetSort(bson.M{"position": 1}))
+	if err != nil {
+		return modules, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(m.ctx) {
+		var module MongoModule
+		if err = cur.Decode(&module); err != nil {
+			return modules, errors.ErrorInvalidBSON
+		}
+
+		modules = append(modules, module)
+	}
+
+	return modules, nil
+}
+
+// Update saves a module's title, description, start date, whether it is
+// published and what it contains.
+func (m *ModuleInterface) Update(module MongoModule, at time.Time) errors.APIError {
+	_, err := m.col.UpdateOne(
+		m.ctx,
+		bson.M{"_id": module.ID},
+		bson.M{
+			"$set": bson.M{
+				"title":         module.Title,
+				"description":   module.Description,
+				"startDate":     module.StartDate,
+				"published":     module.Published,
+				"assignments":   module.Assignments,
+				"announcements": module.Announcements,
+				"updatedAt":     utils.TimeToDateTime(at),
+			},
+		},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// Reorder puts a course's modules in the order given, every module of the
+// course once.
+func (m *ModuleInterface) Reorder(cid interface{}, order []primitive.ObjectID) errors.APIError {
+	for position, mid := range order {
+		_, err := m.col.UpdateOne(
+			m.ctx,
+			bson.M{"_id": mid, "courseID": cid},
+			bson.M{"$set": bson.M{"position": position}},
+		)
+		if err != nil {
+			return errors.ErrorDatabaseFailedUpdate
+		}
+	}
+
+	return nil
+}
+
+// Delete a module, closing the gap it leaves in the course's order.
+func (m *ModuleInterface) Delete(module *MongoModule) errors.APIError {
+	_, err := m.col.DeleteOne(m.ctx, bson.M{"_id": module.ID}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	_, err = m.col.UpdateMany(
+		m.ctx,
+		bson.M{"courseID": module.CourseID, "position": bson.M{"$gt": module.Position}},
+		bson.M{"$inc": bson.M{"position": -1}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+func (m *ModuleInterface) AddFile(mid interface{}, file File) errors.APIError {
+	_, err := m.col.UpdateOne(
+		m.ctx,
+		bson.M{"_id": mid},
+		bson.M{"$push": bson.M{"files": &file}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+func (m *ModuleInterface) RemoveFile(mid, fid interface{}) errors.APIError {
+	_, err := m.col.UpdateOne(
+		m.ctx,
+		bson.M{"_id": mid},
+		bson.M{"$pull": bson.M{"files": bson.M{"_id": fid}}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// RemoveAssignment takes a deleted assignment out of every module.
+func (m *ModuleInterface) RemoveAssignment(aid interface{}) errors.APIError {
+	_, err := m.col.UpdateMany(
+		m.ctx,
+		bson.M{"assignments": aid},
+		bson.M{"$pull": bson.M{"assignments": aid}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// RemoveAnnouncement takes a deleted announcement out of every module.
+func (m *ModuleInterface) RemoveAnnouncement(anid interface{}) errors.APIError {
+	_, err := m.col.UpdateMany(
+		m.ctx,
+		bson.M{"announcements": anid},
+		bson.M{"$pull": bson.M{"announcements": anid}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+func (m *ModuleInterface) DeleteByCourseID(cid interface{}) errors.APIError {
+	_, err := m.col.DeleteMany(m.ctx, bson.M{"courseID": cid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package notificationmodels
+
+import (
+	"context"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	"backend/config"
+	"backend/errors"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+type (
+	// MongoNotification a message shown to a single user in their notification feed.
+	MongoNotification struct {
+		ID        primitive.ObjectID `bson:"_id" json:"id" binding:"required"`
+		UserID    primitive.ObjectID `bson:"userID" json:"userID" binding:"required"`
+		CourseID  primitive.ObjectID `bson:"courseID" json:"courseID"`
+		Kind      string             `bson:"kind" json:"kind" binding:"required"`
+		Message   string             `bson:"message" json:"message" binding:"required"`
+		Link      string             `bson:"link" json:"link"`
+		Read      bool               `bson:"read" json:"read"`
+		CreatedAt primitive.DateTime `bson:"createdAt" json:"createdAt" binding:"required"`
+	}
+
+	NotificationInterface struct {
+		ctx context.Context
+		col *mongo.Collection
+	}
+)
+
+func New() *NotificationInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+	col := tyrgin.GetMongoCollection("notifications", db)
+
+	return &NotificationInterface{
+		context.Background(),
+		col,
+	}
+}
+
+// Notify fans a notification out to every user in uids.
+func (n *NotificationInterface) Notify(uids []primitive.ObjectID, cid interface{}, kind, message, link string) errors.APIError {
+	if len(uids) == 0 {
+		return nil
+	}
+
+	courseID, _ := cid.(primitive.ObjectID)
+	now := primitive.DateTime(time.Now().UnixNano() / 1000000)
+
+	docs := make([]interface{}, len(uids))
+	for index, uid := range uids {
+		docs[index] = MongoNotification{
+			ID:        primitive.NewObjectID(),
+			UserID:    uid,
+			CourseID:  courseID,
+			Kind:      kind,
+			Message:   message,
+			Link:      link,
+			Read:      false,
+			CreatedAt: now,
+		}
+	}
+
+	_, err := n.col.InsertMany(n.ctx, docs, options.InsertMany())
+	if err != nil {
+		return errors.ErrorDatabaseFailedCreate
+	}
+
+	return nil
+}
+
+// GetUsersNotifications returns the newest notifications of a user up until limit.
+func (n *NotificationInterface) GetUsersNotifications(uid interface{}, unreadOnly bool, limit int64) ([]MongoNotification, errors.APIError) {
+	filter := bson.M{"userID": uid}
+	if unreadOnly {
+		filter["read"] = false
+	}
+
+	notifications := make([]MongoNotification, 0)
+	cur, err := n.col.Find(
+		n.ctx,
+		filter,
+		options.Find().SetSort(bson.M{"createdAt": -1}).SetLimit(limit),
+	)
+	if err != nil {
+		return notifications, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(n.ctx) {
+		var notification MongoNotification
+		err = cur.Decode(&notification)
+		if err != nil {
+			return notifications, errors.ErrorInvalidBSON
+		}
+
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, nil
+}
+
+func (n *NotificationInterface) MarkRead(nid, uid interface{}) errors.APIError {
+	_, err := n.col.UpdateOne(
+		n.ctx,
+		bson.M{"_id": nid, "userID": uid},
+		bson.M{"$set": bson.M{"read": true}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+func (n *NotificationInterface) MarkAllRead(uid interface{}) errors.APIError {
+	_, err := n.col.UpdateMany(
+		n.ctx,
+		bson.M{"userID": uid, "read": false},
+		bson.M{"$set": bson.M{"read": true}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+func (n *NotificationInterface) DeleteByUserID(uid interface{}) errors.APIError {
+	_, err := n.col.DeleteMany(n.ctx, bson.M{"userID": uid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
@@ -0,0 +1,775 @@
+package quizmodels
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	"backend/config"
+	"backend/errors"
+	"backend/forms"
+	"backend/models/cmsmodels/assignmentmodels"
+	"backend/utils"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+// Kinds of questions.
+const (
+	MultipleChoice = "multipleChoice"
+	ShortAnswer    = "shortAnswer"
+	Numeric        = "numeric"
+)
+
+type (
+	// MongoQuestion a question of a course's question bank. Multiple choice
+	// questions are answered by choosing exactly the Correct choices, short
+	// answer ones by any of Answers, ignoring case and spacing, and numeric
+	// ones by Value, give or take Tolerance.
+	MongoQuestion struct {
+		ID        primitive.ObjectID `bson:"_id" json:"id"`
+		CourseID  primitive.ObjectID `bson:"courseID" json:"courseID"`
+		Kind      string             `bson:"kind" json:"kind"`
+		Prompt    string             `bson:"prompt" json:"prompt"`
+		Choices   []string           `bson:"choices,omitempty" json:"choices,omitempty"`
+		Correct   []int              `bson:"correct,omitempty" json:"correct,omitempty"`
+		Answers   []string           `bson:"answers,omitempty" json:"answers,omitempty"`
+		Value     float64            `bson:"value" json:"value"`
+		Tolerance float64            `bson:"tolerance" json:"tolerance"`
+		Points    float64            `bson:"points" json:"points"`
+		Tags      []string           `bson:"tags" json:"tags"`
+		CreatedBy primitive.ObjectID `bson:"createdBy" json:"createdBy"`
+		CreatedAt primitive.DateTime `bson:"createdAt" json:"createdAt"`
+	}
+
+	// AttemptQuestion a question as it was when an attempt drew it, so later
+	// edits to the bank do not change attempts under way. Order the choices
+	// in the order they are shown, by their index in the question.
+	AttemptQuestion struct {
+		Question MongoQuestion `bson:"question" json:"question"`
+		Order    []int         `bson:"order,omitempty" json:"order,omitempty"`
+	}
+
+	// Response a student's answer to a question: the indices of the choices
+	// they picked, as shown to them, or the text they wrote.
+	Response struct {
+		Choices []int  `bson:"choices,omitempty" json:"choices,omitempty"`
+		Text    string `bson:"text,omitempty" json:"text,omitempty"`
+	}
+
+	// QuestionResult the points a submitted attempt earned on a question.
+	QuestionResult struct {
+		QuestionID primitive.ObjectID `bson:"questionID" json:"questionID"`
+		Earned     float64            `bson:"earned" json:"earned"`
+		Points     float64            `bson:"points" json:"points"`
+	}
+
+	// MongoAttempt a student taking a quiz. Its responses are saved as the
+	// student answers, keyed by the hex id of the question, and scored once
+	// it is submitted, by the student or, on timed quizzes, once ClosesAt
+	// passes.
+	MongoAttempt struct {
+		ID            primitive.ObjectID  `bson:"_id" json:"id"`
+		AssignmentID  primitive.ObjectID  `bson:"assignmentID" json:"assignmentID"`
+		CourseID      primitive.ObjectID  `bson:"courseID" json:"courseID"`
+		UserID        primitive.ObjectID  `bson:"userID" json:"userID"`
+		AttemptNumber int                 `bson:"attemptNumber" json:"attemptNumber"`
+		Questions     []AttemptQuestion   `bson:"questions" json:"questions"`
+		Responses     map[string]Response `bson:"responses" json:"responses"`
+		StartedAt     primitive.DateTime  `bson:"startedAt" json:"startedAt"`
+		SavedAt       primitive.DateTime  `bson:"savedAt" json:"savedAt"`
+		// ClosesAt when the attempt is submitted on its own, zero when it
+		// stays open until the student submits it.
+		ClosesAt     primitive.DateTime  `bson:"closesAt" json:"closesAt"`
+		Submitted    bool                `bson:"submitted" json:"submitted"`
+		SubmittedAt  primitive.DateTime  `bson:"submittedAt,omitempty" json:"submittedAt,omitempty"`
+		SubmissionID *primitive.ObjectID `bson:"submissionID,omitempty" json:"submissionID,omitempty"`
+		Score        float64             `bson:"score" json:"score"`
+		Results      []QuestionResult    `bson:"results,omitempty" json:"results,omitempty"`
+	}
+
+	// QuestionView a question of an attempt as the student taking it sees
+	// it, without its answer.
+	QuestionView struct {
+		ID       primitive.ObjectID `json:"id"`
+		Kind     string             `json:"kind"`
+		Prompt   string             `json:"prompt"`
+		Choices  []string           `json:"choices,omitempty"`
+		Points   float64            `json:"points"`
+		Response *Response          `json:"response,omitempty"`
+		// Earned the points the response earned, once submitted.
+		Earned *float64 `json:"earned,omitempty"`
+	}
+
+	// AttemptView an attempt as the student taking it sees it.
+	AttemptView struct {
+		ID            primitive.ObjectID  `json:"id"`
+		AssignmentID  primitive.ObjectID  `json:"assignmentID"`
+		AttemptNumber int                 `json:"attemptNumber"`
+		Questions     []QuestionView      `json:"questions"`
+		StartedAt     primitive.DateTime  `json:"startedAt"`
+		SavedAt       primitive.DateTime  `json:"savedAt"`
+		ClosesAt      primitive.DateTime  `json:"closesAt"`
+		Submitted     bool                `json:"submitted"`
+		SubmissionID  *primitive.ObjectID `json:"submissionID,omitempty"`
+		Score         *float64            `json:"score,omitempty"`
+	}
+
+	QuizInterface struct {
+		ctx       context.Context
+		questions *mongo.Collection
+		attempts  *mongo.Collection
+	}
+)
+
+func New() *QuizInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+
+	return &QuizInterface{
+		context.Background(),
+		tyrgin.GetMongoCollection("questions", db),
+		tyrgin.GetMongoCollection("quizattempts", db),
+	}
+}
+
+// normalizeAnswer compares short answers ignoring case and spacing.
+func normalizeAnswer(answer string) string {
+	return strings.ToLower(strings.Join(strings.Fields(answer), " "))
+}
+
+// NewQuestion checks a question of the bank has a prompt, points and the
+// answer its kind needs. Points default to 1.
+func NewQuestion(form forms.QuestionForm) (MongoQuestion, errors.APIError) {
+	question := MongoQuestion{
+		Kind:      form.Kind,
+		Prompt:    strings.TrimSpace(form.Prompt),
+		Tolerance: form.Tolerance,
+		Points:    form.Points,
+		Tags:      make([]string, 0, len(form.Tags)),
+	}
+	if question.Points == 0 {
+		question.Points = 1
+	}
+	if question.Prompt == "" || question.Points < 0 {
+		return question, errors.ErrorInvalidQuestion
+	}
+
+	for _, tag := range form.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			question.Tags = append(question.Tags, tag)
+		}
+	}
+
+	switch form.Kind {
+	case MultipleChoice:
+		if len(form.Choices) < 2 || len(form.Correct) == 0 {
+			return question, errors.ErrorInvalidQuestion
+		}
+		for _, choice := range form.Choices {
+			if strings.TrimSpace(choice) == "" {
+				return question, errors.ErrorInvalidQuestion
+			}
+		}
+
+		correct := make(map[int]bool)
+		for _, index := range form.Correct {
+			if index < 0 || index >= len(form.Choices) || correct[index] {
+				return question, errors.ErrorInvalidQuestion
+			}
+			correct[index] = true
+		}
+		question.Choices, question.Correct = form.Choices, form.Correct
+	case ShortAnswer:
+		for _, answer := range form.Answers {
+			if answer = strings.TrimSpace(answer); answer != "" {
+				question.Answers = append(question.Answers, answer)
+			}
+		}
+		if len(question.Answers) == 0 {
+			return question, errors.ErrorInvalidQuestion
+		}
+	case Numeric:
+		if form.Value == nil || form.Tolerance < 0 {
+			return question, errors.ErrorInvalidQuestion
+		}
+		question.Value = *form.Value
+	default:
+		return question, errors.ErrorInvalidQuestion
+	}
+
+	return question, nil
+}
+
+// Tagged whether the question has a tag, any question for the empty tag.
+func (m *MongoQuestion) Tagged(tag string) bool {
+	if tag == "" {
+		return true
+	}
+
+	for _, own := range m.Tags {
+		if own == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Earned the points a response earns on the question.
+func (m *MongoQuestion) Earned(response Response, order []int) float64 {
+	switch m.Kind {
+	case MultipleChoice:
+		if len(response.Choices) != len(m.Correct) {
+			return 0
+		}
+
+		correct := make(map[int]bool)
+		for _, index := range m.Correct {
+			correct[index] = true
+		}
+		for _, shown := range response.Choices {
+			if shown < 0 || shown >= len(m.Choices) {
+				return 0
+			}
+			index := shown
+			if order != nil {
+				index = order[shown]
+			}
+			if !correct[index] {
+				return 0
+			}
+			// chosen twice cannot make up for a correct choice left out
+			delete(correct, index)
+		}
+	case ShortAnswer:
+		text := normalizeAnswer(response.Text)
+		for _, answer := range m.Answers {
+			if normalizeAnswer(answer) == text {
+				return m.Points
+			}
+		}
+		return 0
+	case Numeric:
+		value, err := strconv.ParseFloat(strings.TrimSpace(response.Text), 64)
+		if err != nil || math.Abs(value-m.Value) > m.Tolerance {
+			return 0
+		}
+	default:
+		return 0
+	}
+
+	return m.Points
+}
+
+// NewQuiz checks the questions of a quiz are the course's, each asked once,
+// and that its pools draw questions the bank has enough of, besides the
+// questions every attempt asks.
+func NewQuiz(form forms.UpdateQuizForm, bank []MongoQuestion) (*assignmentmodels.Quiz, errors.APIError) {
+	inBank := make(map[primitive.ObjectID]bool)
+	for _, question := range bank {
+		inBank[question.ID] = true
+	}
+
+	fixed := make(map[primitive.ObjectID]bool)
+	for _, qid := range form.Questions {
+		if !inBank[qid] || fixed[qid] {
+			return nil, errors.ErrorInvalidQuiz
+		}
+		fixed[qid] = true
+	}
+
+	quiz := &assignmentmodels.Quiz{
+		Questions:        form.Questions,
+		Pools:            make([]assignmentmodels.QuizPool, 0, len(form.Pools)),
+		ShuffleQuestions: form.ShuffleQuestions,
+		ShuffleChoices:   form.ShuffleChoices,
+		Size:             len(form.Questions),
+	}
+	if quiz.Questions == nil {
+		quiz.Questions = make([]primitive.ObjectID, 0)
+	}
+
+	for _, pool := range form.Pools {
+		tag := strings.TrimSpace(pool.Tag)
+		if pool.Count <= 0 {
+			return nil, errors.ErrorInvalidQuiz
+		}
+
+		available := 0
+		for _, question := range bank {
+			if !fixed[question.ID] && question.Tagged(tag) {
+				available++
+			}
+		}
+		if available < pool.Count {
+			return nil, errors.ErrorNotEnoughQuestions
+		}
+
+		quiz.Pools = append(quiz.Pools, assignmentmodels.QuizPool{Tag: tag, Count: pool.Count})
+		quiz.Size += pool.Count
+	}
+
+	if quiz.Size == 0 {
+		return nil, errors.ErrorInvalidQuiz
+	}
+
+	return quiz, nil
+}
+
+// Assemble draws the questions of an attempt of a quiz from the bank: its
+// fixed questions and, pool by pool, questions not drawn already, shuffled
+// as the quiz asks.
+func Assemble(quiz *assignmentmodels.Quiz, bank []MongoQuestion, rng *rand.Rand) ([]AttemptQuestion, errors.APIError) {
+	byID := make(map[primitive.ObjectID]MongoQuestion)
+	for _, question := range bank {
+		byID[question.ID] = question
+	}
+
+	drawn := make(map[primitive.ObjectID]bool)
+	questions := make([]AttemptQuestion, 0, quiz.Size)
+	for _, qid := range quiz.Questions {
+		question, found := byID[qid]
+		if !found {
+			return nil, errors.ErrorNotEnoughQuestions
+		}
+
+		drawn[qid] = true
+		questions = append(questions, AttemptQuestion{Question: question})
+	}
+
+	for _, pool := range quiz.Pools {
+		candidates := make([]MongoQuestion, 0)
+		for _, question := range bank {
+			if !drawn[question.ID] && question.Tagged(pool.Tag) {
+				candidates = append(candidates, question)
+			}
+		}
+		if len(candidates) < pool.Count {
+			return nil, errors.ErrorNotEnoughQuestions
+		}
+
+		for _, index := range rng.Perm(len(candidates))[:pool.Count] {
+			drawn[candidates[index].ID] = true
+			questions = append(questions, AttemptQuestion{Question: candidates[index]})
+		}
+	}
+
+	if quiz.ShuffleQuestions {
+		rng.Shuffle(len(questions), func(i, j int) {
+			questions[i], questions[j] = questions[j], questions[i]
+		})
+	}
+
+	if quiz.ShuffleChoices {
+		for index := range questions {
+			if questions[index].Question.Kind == MultipleChoice {
+				questions[index].Order = rng.Perm(len(questions[index].Question.Choices))
+			}
+		}
+	}
+
+	return questions, nil
+}
+
+// CheckResponses checks responses answer questions of the attempt, with
+// choices it showed, and keys them as they are saved.
+func (m *MongoAttempt) CheckResponses(form forms.SaveQuizResponsesForm) (map[string]Response, errors.APIError) {
+	questions := make(map[primitive.ObjectID]MongoQuestion)
+	for _, question := range m.Questions {
+		questions[question.Question.ID] = question.Question
+	}
+
+	responses := make(map[string]Response)
+	for _, response := range form.Responses {
+		question, found := questions[response.QuestionID]
+		if !found {
+			return nil, errors.ErrorInvalidQuizResponse
+		}
+
+		if question.Kind == MultipleChoice {
+			if response.Text != "" {
+				return nil, errors.ErrorInvalidQuizResponse
+			}
+			for _, index := range response.Choices {
+				if index < 0 || index >= len(question.Choices) {
+					return nil, errors.ErrorInvalidQuizResponse
+				}
+			}
+		} else if len(response.Choices) > 0 {
+			return nil, errors.ErrorInvalidQuizResponse
+		}
+
+		responses[response.QuestionID.Hex()] = Response{response.Choices, response.Text}
+	}
+
+	return responses, nil
+}
+
+// Grade scores the responses of the attempt out of 100, by the share of the
+// questions' points they earned.
+func (m *MongoAttempt) Grade() (float64, []QuestionResult) {
+	results := make([]QuestionResult, 0, len(m.Questions))
+	earned, total := 0.0, 0.0
+	for _, question := range m.Questions {
+		result := QuestionResult{QuestionID: question.Question.ID, Points: question.Question.Points}
+		if response, found := m.Responses[question.Question.ID.Hex()]; found {
+			result.Earned = question.Question.Earned(response, question.Order)
+		}
+
+		results = append(results, result)
+		earned += result.Earned
+		total += result.Points
+	}
+
+	if total == 0 {
+		return 0, results
+	}
+
+	return 100 * earned / total, results
+}
+
+// Open whether the attempt can still be answered at a time.
+func (m *MongoAttempt) Open(at time.Time) bool {
+	return !m.Submitted && (m.ClosesAt == 0 || at.Before(utils.DateTimeToTime(m.ClosesAt)))
+}
+
+// View the attempt as the student taking it sees it: its questions with
+// their choices in the order they were drawn, their responses and, once
+// submitted, their score.
+func (m *MongoAttempt) View() AttemptView {
+	earned := make(map[primitive.ObjectID]float64)
+	for _, result := range m.Results {
+		earned[result.QuestionID] = result.Earned
+	}
+
+	view := AttemptView{
+		ID:            m.ID,
+		AssignmentID:  m.AssignmentID,
+		AttemptNumber: m.AttemptNumber,
+		Questions:     make([]QuestionView, 0, len(m.Questions)),
+		StartedAt:     m.StartedAt,
+		SavedAt:       m.SavedAt,
+		ClosesAt:      m.ClosesAt,
+		Submitted:     m.Submitted,
+		SubmissionID:  m.SubmissionID,
+	}
+	if m.Submitted {
+		score := m.Score
+		view.Score = &score
+	}
+
+	for _, drawn := range m.Questions {
+		question := QuestionView{
+			ID:     drawn.Question.ID,
+			Kind:   drawn.Question.Kind,
+			Prompt: drawn.Question.Prompt,
+			Points: drawn.Question.Points,
+		}
+
+		question.Choices = drawn.Question.Choices
+		if drawn.Order != nil {
+			question.Choices = make([]string, len(drawn.Order))
+			for shown, index := range drawn.Order {
+				question.Choices[shown] = drawn.Question.Choices[index]
+			}
+		}
+
+		if response, found := m.Responses[drawn.Question.ID.Hex()]; found {
+			question.Response = &response
+		}
+		if points, found := earned[drawn.Question.ID]; found {
+			question.Earned = &points
+		}
+
+		view.Questions = append(view.Questions, question)
+	}
+
+	return view
+}
+
+// CreateQuestion adds a question to a course's bank.
+func (q *QuizInterface) CreateQuestion(question MongoQuestion) (*MongoQuestion, errors.APIError) {
+	question.ID = primitive.NewObjectID()
+	question.CreatedAt = utils.TimeToDateTime(time.Now())
+
+	_, err := q.questions.InsertOne(q.ctx, &question, options.InsertOne())
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedCreate
+	}
+
+	return &question, nil
+}
+
+func (q *QuizInterface) GetQuestion(qid, cid interface{}) (*MongoQuestion, errors.APIError) {
+	var question *MongoQuestion
+	res := q.questions.FindOne(q.ctx, bson.M{"_id": qid, "courseID": cid}, options.FindOne())
+	res.Decode(&question)
+
+	if question == nil {
+		return nil, errors.ErrorResourceNotFound
+	}
+
+	return question, nil
+}
+
+// CourseQuestions the questions of a course's bank, oldest first, only the
+// ones tagged tag unless it is empty.
+func (q *QuizInterface) CourseQuestions(cid interface{}, tag string) ([]MongoQuestion, errors.APIError) {
+	filter := bson.M{"courseID": cid}
+	if tag != "" {
+		filter["tags"] = tag
+	}
+
+	questions := make([]MongoQuestion, 0)
+	cur, err := q.questions.Find(q.ctx, filter, options.Find().SetSort(bson.M{"createdAt": 1}))
+	if err != nil {
+		return questions, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(q.ctx) {
+		var question MongoQuestion
+		err = cur.Decode(&question)
+		if err != nil {
+			return questions, errors.ErrorInvalidBSON
+		}
+
+		questions = append(questions, question)
+	}
+
+	return questions, nil
+}
+
+// UpdateQuestion replaces a question of the bank. Attempts that drew it
+// keep the question as it was.
+func (q *QuizInterface) UpdateQuestion(question MongoQuestion) errors.APIError {
+	_, err := q.questions.UpdateOne(
+		q.ctx,
+		bson.M{"_id": question.ID, "courseID": question.CourseID},
+		bson.M{"$set": bson.M{
+			"kind":      question.Kind,
+			"prompt":    question.Prompt,
+			"choices":   question.Choices,
+			"correct":   question.Correct,
+			"answers":   question.Answers,
+			"value":     question.Value,
+			"tolerance": question.Tolerance,
+			"points":    question.Points,
+			"tags":      question.Tags,
+		}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+func (q *QuizInterface) DeleteQuestion(qid, cid interface{}) errors.APIError {
+	_, err := q.questions.DeleteOne(q.ctx, bson.M{"_id": qid, "courseID": cid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+// StartAttempt stores a new attempt.
+func (q *QuizInterface) StartAttempt(attempt MongoAttempt) (*MongoAttempt, errors.APIError) {
+	attempt.ID = primitive.NewObjectID()
+	attempt.StartedAt = utils.TimeToDateTime(time.Now())
+	attempt.SavedAt = attempt.StartedAt
+	attempt.Responses = make(map[string]Response)
+
+	_, err := q.attempts.InsertOne(q.ctx, &attempt, options.InsertOne())
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedCreate
+	}
+
+	return &attempt, nil
+}
+
+// GetAttempt an attempt at a quiz.
+func (q *QuizInterface) GetAttempt(qaid, aid interface{}) (*MongoAttempt, errors.APIError) {
+	var attempt *MongoAttempt
+	res := q.attempts.FindOne(q.ctx, bson.M{"_id": qaid, "assignmentID": aid}, options.FindOne())
+	res.Decode(&attempt)
+
+	if attempt == nil {
+		return nil, errors.ErrorResourceNotFound
+	}
+
+	return attempt, nil
+}
+
+// OpenAttempt the attempt a student has not submitted yet at a quiz, nil
+// when there is none.
+func (q *QuizInterface) OpenAttempt(aid, uid interface{}) (*MongoAttempt, errors.APIError) {
+	var attempt *MongoAttempt
+	err := q.attempts.FindOne(
+		q.ctx,
+		bson.M{"assignmentID": aid, "userID": uid, "submitted": false},
+		options.FindOne(),
+	).Decode(&attempt)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedQuery
+	}
+
+	return attempt, nil
+}
+
+// SaveResponses saves the responses of an attempt that changed, unless it
+// was submitted meanwhile.
+func (q *QuizInterface) SaveResponses(qaid interface{}, responses map[string]Response, at time.Time) errors.APIError {
+	set := bson.M{"savedAt": utils.TimeToDateTime(at)}
+	for qid, response := range responses {
+		set["responses."+qid] = response
+	}
+
+	res, err := q.attempts.UpdateOne(q.ctx, bson.M{"_id": qaid, "submitted": false}, bson.M{"$set": set})
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+	if res.MatchedCount == 0 {
+		return errors.ErrorQuizAttemptClosed
+	}
+
+	return nil
+}
+
+// Finish submits a graded attempt, unless it was submitted already, as its
+// submission sid.
+func (q *QuizInterface) Finish(attempt MongoAttempt, sid primitive.ObjectID, at time.Time) errors.APIError {
+	res, err := q.attempts.UpdateOne(
+		q.ctx,
+		bson.M{"_id": attempt.ID, "submitted": false},
+		bson.M{"$set": bson.M{
+			"responses":    attempt.Responses,
+			"submitted":    true,
+			"submittedAt":  utils.TimeToDateTime(at),
+			"submissionID": sid,
+			"score":        attempt.Score,
+			"results":      attempt.Results,
+		}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+	if res.MatchedCount == 0 {
+		return errors.ErrorQuizAttemptClosed
+	}
+
+	return nil
+}
+
+// Reopen undoes Finish when the attempt's submission could not be stored.
+func (q *QuizInterface) Reopen(qaid interface{}) errors.APIError {
+	_, err := q.attempts.UpdateOne(
+		q.ctx,
+		bson.M{"_id": qaid},
+		bson.M{
+			"$set":   bson.M{"submitted": false},
+			"$unset": bson.M{"submittedAt": "", "submissionID": "", "results": ""},
+		},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// Closed the attempts not submitted whose time ran out before a time.
+func (q *QuizInterface) Closed(before time.Time) ([]MongoAttempt, errors.APIError) {
+	attempts := make([]MongoAttempt, 0)
+	cur, err := q.attempts.Find(
+		q.ctx,
+		bson.M{
+			"submitted": false,
+			"closesAt":  bson.M{"$ne": primitive.DateTime(0), "$lt": utils.TimeToDateTime(before)},
+		},
+		options.Find(),
+	)
+	if err != nil {
+		return attempts, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(q.ctx) {
+		var attempt MongoAttempt
+		err = cur.Decode(&attempt)
+		if err != nil {
+			return attempts, errors.ErrorInvalidBSON
+		}
+
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, nil
+}
+
+// UserAttempts every attempt of a student's, oldest first.
+func (q *QuizInterface) UserAttempts(uid interface{}) ([]MongoAttempt, errors.APIError) {
+	attempts := make([]MongoAttempt, 0)
+	cur, err := q.attempts.Find(q.ctx, bson.M{"userID": uid}, options.Find().SetSort(bson.M{"startedAt": 1}))
+	if err != nil {
+		return attempts, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(q.ctx) {
+		var attempt MongoAttempt
+		err = cur.Decode(&attempt)
+		if err != nil {
+			return attempts, errors.ErrorInvalidBSON
+		}
+
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, nil
+}
+
+func (q *QuizInterface) DeleteAttemptsByAssignmentID(aid interface{}) errors.APIError {
+	_, err := q.attempts.DeleteMany(q.ctx, bson.M{"assignmentID": aid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+// DeleteAttemptsByUserID removes a student's attempts. The grades of the
+// ones they submitted stay on their submissions.
+func (q *QuizInterface) DeleteAttemptsByUserID(uid interface{}) errors.APIError {
+	_, err := q.attempts.DeleteMany(q.ctx, bson.M{"userID": uid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+// DeleteByCourseID removes a course's question bank and every attempt at
+// its quizzes.
+func (q *QuizInterface) DeleteByCourseID(cid interface{}) errors.APIError {
+	_, err := q.questions.DeleteMany(q.ctx, bson.M{"courseID": cid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	_, err = q.attempts.DeleteMany(q.ctx, bson.M{"courseID": cid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package quizmodels
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/forms"
+	"backend/forms/cmsforms"
+	"backend/models/cmsmodels/assignmentmodels"
+)
+
+func question(kind string, tags ...string) MongoQuestion {
+	q := MongoQuestion{ID: primitive.NewObjectID(), Kind: kind, Prompt: "?", Points: 1, Tags: tags}
+	switch kind {
+	case MultipleChoice:
+		q.Choices, q.Correct = []string{"a", "b", "c", "d"}, []int{1, 3}
+	case ShortAnswer:
+		q.Answers = []string{"Big O"}
+	case Numeric:
+		q.Value, q.Tolerance = 3.14, 0.01
+	}
+
+	return q
+}
+
+func TestNewQuestion(t *testing.T) {
+	value := 2.5
+	for _, form := range []forms.QuestionForm{
+		{Kind: MultipleChoice, Prompt: "Pick", Choices: []string{"a", "b"}, Correct: []int{0}},
+		{Kind: ShortAnswer, Prompt: "Name it", Answers: []string{" stack ", ""}},
+		{Kind: Numeric, Prompt: "How much", Value: &value, Points: 3},
+	} {
+		q, err := NewQuestion(form)
+		if err != nil {
+			t.Errorf("valid %s question was refused: %v", form.Kind, err)
+		}
+		if q.Points == 0 {
+			t.Errorf("%s question should default to a point", form.Kind)
+		}
+	}
+
+	for name, form := range map[string]forms.QuestionForm{
+		"no prompt":            {Kind: ShortAnswer, Prompt: " ", Answers: []string{"x"}},
+		"unknown kind":         {Kind: "essay", Prompt: "Discuss"},
+		"one choice":           {Kind: MultipleChoice, Prompt: "Pick", Choices: []string{"a"}, Correct: []int{0}},
+		"no correct choice":    {Kind: MultipleChoice, Prompt: "Pick", Choices: []string{"a", "b"}},
+		"correct out of range": {Kind: MultipleChoice, Prompt: "Pick", Choices: []string{"a", "b"}, Correct: []int{2}},
+		"correct twice":        {Kind: MultipleChoice, Prompt: "Pick", Choices: []string{"a", "b"}, Correct: []int{1, 1}},
+		"no answers":           {Kind: ShortAnswer, Prompt: "Name it", Answers: []string{" "}},
+		"no value":             {Kind: Numeric, Prompt: "How much"},
+		"negative tolerance":   {Kind: Numeric, Prompt: "How much", Value: &value, Tolerance: -1},
+		"negative points":      {Kind: ShortAnswer, Prompt: "Name it", Answers: []string{"x"}, Points: -1},
+	} {
+		if _, err := NewQuestion(form); err == nil {
+			t.Errorf("question with %s was accepted", name)
+		}
+	}
+}
+
+func TestEarned(t *testing.T) {
+	choice, short, numeric := question(MultipleChoice), question(ShortAnswer), question(Numeric)
+	for name, c := range map[string]struct {
+		question MongoQuestion
+		response Response
+		order    []int
+		earned   float64
+	}{
+		"every correct choice":    {choice, Response{Choices: []int{3, 1}}, nil, 1},
+		"a correct choice missed": {choice, Response{Choices: []int{1}}, nil, 0},
+		"a wrong choice":          {choice, Response{Choices: []int{1, 2}}, nil, 0},
+		"a choice twice":          {choice, Response{Choices: []int{1, 1}}, nil, 0},
+		"shuffled choices":        {choice, Response{Choices: []int{0, 2}}, []int{3, 0, 1, 2}, 1},
+		"answer spaced and cased": {short, Response{Text: "  big   o "}, nil, 1},
+		"wrong answer":            {short, Response{Text: "big theta"}, nil, 0},
+		"within tolerance":        {numeric, Response{Text: "3.145"}, nil, 1},
+		"outside tolerance":       {numeric, Response{Text: "3.2"}, nil, 0},
+		"not a number":            {numeric, Response{Text: "pi"}, nil, 0},
+	} {
+		if earned := c.question.Earned(c.response, c.order); earned != c.earned {
+			t.Errorf("%s earned %v, want %v", name, earned, c.earned)
+		}
+	}
+}
+
+func TestNewQuiz(t *testing.T) {
+	fixed := question(ShortAnswer)
+	bank := []MongoQuestion{fixed, question(Numeric, "easy"), question(Numeric, "easy"), question(MultipleChoice, "hard")}
+
+	quiz, err := NewQuiz(forms.UpdateQuizForm{
+		Questions: []primitive.ObjectID{fixed.ID},
+		Pools:     []cmsforms.QuizPool{{Tag: "easy", Count: 2}, {Count: 1}},
+	}, bank)
+	if err != nil || quiz.Size != 4 {
+		t.Fatalf("valid quiz was refused: %v %+v", err, quiz)
+	}
+
+	for name, form := range map[string]forms.UpdateQuizForm{
+		"no questions":           {},
+		"question of no bank":    {Questions: []primitive.ObjectID{primitive.NewObjectID()}},
+		"question twice":         {Questions: []primitive.ObjectID{fixed.ID, fixed.ID}},
+		"empty pool":             {Pools: []cmsforms.QuizPool{{Tag: "easy"}}},
+		"pool larger than bank":  {Pools: []cmsforms.QuizPool{{Tag: "hard", Count: 2}}},
+		"pool of fixed question": {Questions: []primitive.ObjectID{fixed.ID}, Pools: []cmsforms.QuizPool{{Count: 4}}},
+	} {
+		if _, err := NewQuiz(form, bank); err == nil {
+			t.Errorf("quiz with %s was accepted", name)
+		}
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	fixed := question(MultipleChoice)
+	bank := []MongoQuestion{fixed, question(Numeric, "easy"), question(ShortAnswer, "easy"), question(Numeric, "easy")}
+	quiz := &assignmentmodels.Quiz{
+		Questions:        []primitive.ObjectID{fixed.ID},
+		Pools:            []assignmentmodels.QuizPool{{Tag: "easy", Count: 2}, {Count: 1}},
+		ShuffleQuestions: true,
+		ShuffleChoices:   true,
+		Size:             4,
+	}
+
+	questions, err := Assemble(quiz, bank, rand.New(rand.NewSource(1)))
+	if err != nil || len(questions) != 4 {
+		t.Fatalf("quiz was not assembled: %v %+v", err, questions)
+	}
+
+	drawn := make(map[primitive.ObjectID]bool)
+	for _, q := range questions {
+		if drawn[q.Question.ID] {
+			t.Errorf("question %s was drawn twice", q.Question.ID.Hex())
+		}
+		drawn[q.Question.ID] = true
+
+		if q.Question.Kind == MultipleChoice && len(q.Order) != len(q.Question.Choices) {
+			t.Errorf("choices of a multiple choice question were not shuffled: %v", q.Order)
+		}
+		if q.Question.Kind != MultipleChoice && q.Order != nil {
+			t.Errorf("a %s question has a choice order", q.Question.Kind)
+		}
+	}
+	if !drawn[fixed.ID] {
+		t.Errorf("the fixed question was not asked")
+	}
+
+	if _, err := Assemble(quiz, bank[1:], rand.New(rand.NewSource(1))); err == nil {
+		t.Errorf("quiz assembled without its fixed question")
+	}
+}
+
+func TestAttemptGrade(t *testing.T) {
+	choice, short := question(MultipleChoice), question(ShortAnswer)
+	short.Points = 3
+	attempt := MongoAttempt{
+		Questions: []AttemptQuestion{{Question: choice, Order: []int{3, 2, 1, 0}}, {Question: short}},
+	}
+
+	responses, err := attempt.CheckResponses(forms.SaveQuizResponsesForm{Responses: []cmsforms.QuizResponse{
+		{QuestionID: choice.ID, Choices: []int{0, 2}},
+		{QuestionID: short.ID, Text: "big theta"},
+	}})
+	if err != nil {
+		t.Fatalf("valid responses were refused: %v", err)
+	}
+	attempt.Responses = responses
+
+	score, results := attempt.Grade()
+	if score != 25 || len(results) != 2 || results[0].Earned != 1 || results[1].Earned != 0 {
+		t.Errorf("attempt graded %v %+v, want 25", score, results)
+	}
+
+	view := attempt.View()
+	if view.Questions[0].Choices[0] != "d" || view.Score != nil {
+		t.Errorf("attempt view shows the wrong choices or an unsubmitted score: %+v", view)
+	}
+
+	for name, response := range map[string]cmsforms.QuizResponse{
+		"question not drawn":        {QuestionID: primitive.NewObjectID(), Text: "x"},
+		"choice out of range":       {QuestionID: choice.ID, Choices: []int{4}},
+		"text to a choice question": {QuestionID: choice.ID, Text: "d"},
+		"choices to a text answer":  {QuestionID: short.ID, Choices: []int{0}},
+	} {
+		form := forms.SaveQuizResponsesForm{Responses: []cmsforms.QuizResponse{response}}
+		if _, err := attempt.CheckResponses(form); err == nil {
+			t.Errorf("response with %s was accepted", name)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package regrademodels
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	"backend/config"
+	"backend/errors"
+	"backend/models/cmsmodels/submissionmodels"
+	"backend/utils"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+type (
+	// Outcome how a submission was graded, by the names of the tests it
+	// passed and failed.
+	Outcome struct {
+		Score   float64  `bson:"score" json:"score"`
+		Errored bool     `bson:"errored" json:"errored"`
+		Passed  []string `bson:"passed" json:"passed"`
+		Failed  []string `bson:"failed" json:"failed"`
+	}
+
+	// Entry a submission regraded, with how it was graded before.
+	Entry struct {
+		SubmissionID  primitive.ObjectID `bson:"submissionID" json:"submissionID"`
+		UserID        primitive.ObjectID `bson:"userID" json:"userID"`
+		AttemptNumber int                `bson:"attemptNumber" json:"attemptNumber"`
+		Before        Outcome            `bson:"before" json:"before"`
+		// Dispatched set once the submission was sent back to court herald.
+		Dispatched bool `bson:"dispatched" json:"dispatched"`
+	}
+
+	// MongoRegrade a bulk regrade of the latest submission of every student
+	// of an assignment, keeping how each was graded before so staff can see
+	// what changed.
+	MongoRegrade struct {
+		ID           primitive.ObjectID `bson:"_id" json:"id"`
+		CourseID     primitive.ObjectID `bson:"courseID" json:"courseID"`
+		AssignmentID primitive.ObjectID `bson:"assignmentID" json:"assignmentID"`
+		StartedBy    primitive.ObjectID `bson:"startedBy" json:"startedBy"`
+		StartedAt    primitive.DateTime `bson:"startedAt" json:"startedAt"`
+		Entries      []Entry            `bson:"entries" json:"entries,omitempty"`
+	}
+
+	// Comparison how a student's submission was graded before and after a
+	// regrade. Pending while it is still being regraded.
+	Comparison struct {
+		UserID        primitive.ObjectID `json:"userID"`
+		SubmissionID  primitive.ObjectID `json:"submissionID"`
+		AttemptNumber int                `json:"attemptNumber"`
+		Before        float64            `json:"before"`
+		After         float64            `json:"after"`
+		Delta         float64            `json:"delta"`
+		Pending       bool               `json:"pending"`
+		Errored       bool               `json:"errored"`
+		NewlyPassing  []string           `json:"newlyPassing"`
+		NewlyFailing  []string           `json:"newlyFailing"`
+	}
+
+	RegradeInterface struct {
+		ctx context.Context
+		col *mongo.Collection
+	}
+)
+
+func New() *RegradeInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+	col := tyrgin.GetMongoCollection("regrades", db)
+
+	return &RegradeInterface{
+		context.Background(),
+		col,
+	}
+}
+
+// OutcomeOf how a submission is graded now.
+func OutcomeOf(submission *submissionmodels.MongoSubmission) Outcome {
+	outcome := Outcome{
+		Score:   submission.Score(),
+		Errored: submission.ErrorTesting,
+		Passed:  make([]string, 0),
+		Failed:  make([]string, 0),
+	}
+	for _, result := range submission.Results {
+		if result.Passed {
+			outcome.Passed = append(outcome.Passed, result.Name)
+		} else {
+			outcome.Failed = append(outcome.Failed, result.Name)
+		}
+	}
+
+	return outcome
+}
+
+// Compare the submissions of a regrade as they were before it to how they
+// are graded now, by student. Tests are newly passing when they pass now and
+// did not before, and newly failing when they passed before and no longer
+// do, including tests that were removed.
+func (m *MongoRegrade) Compare(current map[primitive.ObjectID]*submissionmodels.MongoSubmission) []Comparison {
+	comparisons := make([]Comparison, 0, len(m.Entries))
+	for _, entry := range m.Entries {
+		comparison := Comparison{
+			UserID:        entry.UserID,
+			SubmissionID:  entry.SubmissionID,
+			AttemptNumber: entry.AttemptNumber,
+			Before:        entry.Before.Score,
+			NewlyPassing:  make([]string, 0),
+			NewlyFailing:  make([]string, 0),
+		}
+
+		submission, found := current[entry.SubmissionID]
+		if !found || submission.InProgress {
+			comparison.Pending = true
+			comparisons = append(comparisons, comparison)
+			continue
+		}
+
+		after := OutcomeOf(submission)
+		comparison.After = after.Score
+		comparison.Delta = after.Score - entry.Before.Score
+		comparison.Errored = after.Errored
+
+		passedBefore := make(map[string]bool, len(entry.Before.Passed))
+		for _, name := range entry.Before.Passed {
+			passedBefore[name] = true
+		}
+		passedAfter := make(map[string]bool, len(after.Passed))
+		for _, name := range after.Passed {
+			passedAfter[name] = true
+			if !passedBefore[name] {
+				comparison.NewlyPassing = append(comparison.NewlyPassing, name)
+			}
+		}
+		for _, name := range entry.Before.Passed {
+			if !passedAfter[name] {
+				comparison.NewlyFailing = append(comparison.NewlyFailing, name)
+			}
+		}
+		sort.Strings(comparison.NewlyPassing)
+		sort.Strings(comparison.NewlyFailing)
+
+		comparisons = append(comparisons, comparison)
+	}
+
+	return comparisons
+}
+
+// Create stores a regrade before its submissions are sent to court herald.
+func (r *RegradeInterface) Create(regrade *MongoRegrade, at time.Time) errors.APIError {
+	regrade.ID = primitive.NewObjectID()
+	regrade.StartedAt = utils.TimeToDateTime(at)
+
+	_, err := r.col.InsertOne(r.ctx, regrade, options.InsertOne())
+	if err != nil {
+		return errors.ErrorDatabaseFailedCreate
+	}
+
+	return nil
+}
+
+// Get a regrade of an assignment.
+func (r *RegradeInterface) Get(rgid, aid interface{}) (*MongoRegrade, errors.APIError) {
+	var regrade *MongoRegrade
+	err := r.col.FindOne(r.ctx, bson.M{"_id": rgid, "assignmentID": aid}, options.FindOne()).Decode(&regrade)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.ErrorResourceNotFound
+	}
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedQuery
+	}
+
+	return regrade, nil
+}
+
+// GetByID a regrade, whichever assignment it is of.
+func (r *RegradeInterface) GetByID(rgid interface{}) (*MongoRegrade, errors.APIError) {
+	var regrade *MongoRegrade
+	err := r.col.FindOne(r.ctx, bson.M{"_id": rgid}, options.FindOne()).Decode(&regrade)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.ErrorResourceNotFound
+	}
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedQuery
+	}
+
+	return regrade, nil
+}
+
+// ForAssignment the regrades of an assignment, newest first, without their
+// entries.
+func (r *RegradeInterface) ForAssignment(aid interface{}) ([]MongoRegrade, errors.APIError) {
+	regrades := make([]MongoRegrade, 0)
+	cur, err := r.col.Find(
+		r.ctx,
+		bson.M{"assignmentID": aid},
+		options.Find().SetSort(bson.M{"startedAt": -1}).SetProjection(bson.M{"entries": 0}),
+	)
+	if err != nil {
+		return regrades, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(r.ctx) {
+		var regrade MongoRegrade
+		if err = cur.Decode(&regrade); err != nil {
+			return regrades, errors.ErrorInvalidBSON
+		}
+
+		regrades = append(regrades, regrade)
+	}
+
+	return regrades, nil
+}
+
+// MarkDispatched records that a submission of the regrade was sent back to
+// court herald, so a retried regrade does not send it twice.
+func (r *RegradeInterface) MarkDispatched(rgid, sid primitive.ObjectID) errors.APIError {
+	_, err := r.col.UpdateOne(
+		r.ctx,
+		bson.M{"_id": rgid, "entries.submissionID": sid},
+		bson.M{"$set": bson.M{"entries.$.dispatched": true}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// RemoveUser drops a user's submissions from every regrade.
+func (r *RegradeInterface) RemoveUser(uid interface{}) errors.APIError {
+	_, err := r.col.UpdateMany(
+		r.ctx,
+		bson.M{"entries.userID": uid},
+		bson.M{"$pull": bson.M{"entries": bson.M{"userID": uid}}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+func (r *RegradeInterface) DeleteByAssignmentID(aid interface{}) errors.APIError {
+	_, err := r.col.DeleteMany(r.ctx, bson.M{"assignmentID": aid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+func (r *RegradeInterface) DeleteByCourseID(cid interface{}) errors.APIError {
+	_, err := r.col.DeleteMany(r.ctx, bson.M{"courseID": cid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package regrademodels
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/models/cmsmodels/submissionmodels"
+)
+
+func graded(passed map[string]bool) *submissionmodels.MongoSubmission {
+	submission := &submissionmodels.MongoSubmission{ID: primitive.NewObjectID(), UserID: primitive.NewObjectID()}
+	for _, name := range []string{"add", "sub", "mul", "div"} {
+		if result, found := passed[name]; found {
+			submission.Results = append(submission.Results, submissionmodels.WorkerResult{Name: name, Passed: result})
+		}
+	}
+
+	return submission
+}
+
+func TestOutcomeOf(t *testing.T) {
+	outcome := OutcomeOf(graded(map[string]bool{"add": true, "sub": false, "mul": true, "div": false}))
+	if outcome.Score != 50 {
+		t.Errorf("score is %v, want 50", outcome.Score)
+	}
+	if !reflect.DeepEqual(outcome.Passed, []string{"add", "mul"}) || !reflect.DeepEqual(outcome.Failed, []string{"sub", "div"}) {
+		t.Errorf("passed %v and failed %v", outcome.Passed, outcome.Failed)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	before := graded(map[string]bool{"add": true, "sub": false, "mul": true, "div": true})
+	pending := graded(map[string]bool{"add": true})
+
+	regrade := MongoRegrade{Entries: []Entry{
+		{SubmissionID: before.ID, UserID: before.UserID, Before: OutcomeOf(before)},
+		{SubmissionID: pending.ID, UserID: pending.UserID, Before: OutcomeOf(pending)},
+	}}
+
+	after := graded(map[string]bool{"add": true, "sub": true, "mul": false})
+	after.ID, after.UserID = before.ID, before.UserID
+	inProgress := *pending
+	inProgress.InProgress = true
+
+	comparisons := regrade.Compare(map[primitive.ObjectID]*submissionmodels.MongoSubmission{
+		after.ID:      after,
+		inProgress.ID: &inProgress,
+	})
+	if len(comparisons) != 2 {
+		t.Fatalf("got %d comparisons, want 2", len(comparisons))
+	}
+
+	changed := comparisons[0]
+	if changed.Before != 75 || changed.Delta < -8.34 || changed.Delta > -8.33 || changed.Pending {
+		t.Errorf("scores went from %v by %v, pending %v", changed.Before, changed.Delta, changed.Pending)
+	}
+	if !reflect.DeepEqual(changed.NewlyPassing, []string{"sub"}) {
+		t.Errorf("newly passing %v, want [sub]", changed.NewlyPassing)
+	}
+	if !reflect.DeepEqual(changed.NewlyFailing, []string{"div", "mul"}) {
+		t.Errorf("newly failing %v, want the failed and the removed test", changed.NewlyFailing)
+	}
+
+	if !comparisons[1].Pending || comparisons[1].Before != 100 {
+		t.Errorf("submission still grading should be pending with its old score, got %+v", comparisons[1])
+	}
+}
@@ -1,13 +1,11 @@
 package submissionmodels
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"os"
+	"math"
+	"regexp"
+	"sort"
 	"time"
 
 	"github.com/mongodb/mongo-go-driver/bson"
@@ -15,7 +13,11 @@ import (
 	"github.com/mongodb/mongo-go-driver/mongo"
 	"github.com/mongodb/mongo-go-driver/mongo/options"
 
+	"backend/config"
+	"backend/courtherald"
 	"backend/errors"
+	"backend/events"
+	"backend/utils"
 
 	tyrgin "github.com/stevens-tyr/tyr-gin"
 )
@@ -31,6 +33,195 @@ type (
 		HTML          string `bson:"html" json:"html" binding:"required"`
 		TestCMD       string `bson:"testCMD" json:"testCMD" binding:"required"`
 		Name          string `bson:"name" json:"name" binding:"required"`
+		// Match how the grader compared the output, so the diff view can show
+		// what was ignored. Epsilon is the tolerance of numeric matches.
+		Match   string  `bson:"match,omitempty" json:"match,omitempty"`
+		Epsilon float64 `bson:"epsilon,omitempty" json:"epsilon,omitempty"`
+		// Stderr what the test wrote to stderr, only shown to staff.
+		Stderr string `bson:"stderr" json:"stderr,omitempty" view:"staff"`
+		// OutputFileID when the output was too large to store, Output holds its
+		// beginning and the full output is kept in gridfs.
+		OutputFileID *primitive.ObjectID `bson:"outputFileID,omitempty" json:"outputFileID,omitempty"`
+		OutputSize   int64               `bson:"outputSize,omitempty" json:"outputSize,omitempty"`
+		// DurationMS how long the test ran, in milliseconds, when the grader
+		// reports it.
+		DurationMS float64 `bson:"durationMS,omitempty" json:"durationMS,omitempty"`
+	}
+
+	// ResultCount how many of a submission's results passed.
+	ResultCount struct {
+		Passed int `json:"passed"`
+		Total  int `json:"total"`
+	}
+
+	// GradeReport what court herald reports once it is done with a submission.
+	GradeReport struct {
+		BuildOutput string         `json:"buildOutput"`
+		BuildFailed bool           `json:"buildFailed"`
+		Results     []WorkerResult `json:"results"`
+		// Metrics performance measurements of the submission, e.g. runtime,
+		// used to rank leaderboard assignments.
+		Metrics map[string]float64 `json:"metrics"`
+	}
+
+	// LeaderboardEntry a student's best submission on a leaderboard.
+	LeaderboardEntry struct {
+		UserID         primitive.ObjectID `bson:"_id" json:"-"`
+		SubmissionID   primitive.ObjectID `bson:"submissionID" json:"-"`
+		Value          float64            `bson:"value" json:"value"`
+		AttemptNumber  int                `bson:"attemptNumber" json:"attemptNumber"`
+		SubmissionDate primitive.DateTime `bson:"submissionDate" json:"submissionDate"`
+	}
+
+	// LatestSubmission a student's most recent submission of an assignment.
+	LatestSubmission struct {
+		UserID        primitive.ObjectID `bson:"_id" json:"userID"`
+		SubmissionID  primitive.ObjectID `bson:"submissionID" json:"submissionID"`
+		FileID        primitive.ObjectID `bson:"fileID" json:"-"`
+		FilePurged    bool               `bson:"filePurged" json:"-"`
+		AttemptNumber int                `bson:"attemptNumber" json:"attemptNumber"`
+	}
+
+	// UsageRow the grading a course did in a month: the submissions graded,
+	// their size and grading time, and the students who submitted.
+	UsageRow struct {
+		CourseID       primitive.ObjectID   `bson:"courseID" json:"courseID"`
+		Department     string               `bson:"department" json:"department"`
+		Number         int                  `bson:"number" json:"number"`
+		Semester       string               `bson:"semester" json:"semester"`
+		Month          string               `bson:"month" json:"month"`
+		Jobs           int                  `bson:"jobs" json:"jobs"`
+		GradingSeconds float64              `bson:"gradingSeconds" json:"gradingSeconds"`
+		StorageBytes   int64                `bson:"storageBytes" json:"storageBytes"`
+		Users          []primitive.ObjectID `bson:"users" json:"-"`
+		ActiveUsers    int                  `bson:"activeUsers" json:"activeUsers"`
+	}
+
+	// TestStability how reliably a test of an assignment ran over its graded
+	// submissions: how often it failed, how its duration varied and in how
+	// many groups of identical resubmissions it both passed and failed.
+	TestStability struct {
+		Name      string  `json:"name"`
+		Runs      int     `json:"runs"`
+		Failures  int     `json:"failures"`
+		TimedRuns int     `json:"timedRuns"`
+		MeanMS    float64 `json:"meanMS"`
+		StdDevMS  float64 `json:"stdDevMS"`
+		// Variation the standard deviation of the duration over its mean.
+		Variation       float64 `json:"variation"`
+		IdenticalGroups int     `json:"identicalGroups"`
+		Intermittent    int     `json:"intermittent"`
+		Flaky           bool    `json:"flaky"`
+		TimingSensitive bool    `json:"timingSensitive"`
+	}
+
+	// Attestation the honor code statement a student accepted when submitting.
+	Attestation struct {
+		Text       string             `bson:"text" json:"text"`
+		AcceptedAt primitive.DateTime `bson:"acceptedAt" json:"acceptedAt"`
+		IP         string             `bson:"ip" json:"ip"`
+	}
+
+	// Source where a submission was sent from.
+	Source struct {
+		IP        string `bson:"ip" json:"ip"`
+		UserAgent string `bson:"userAgent" json:"userAgent"`
+		// Fingerprint a hash identifying the device the submission came from.
+		Fingerprint string `bson:"fingerprint" json:"fingerprint"`
+	}
+
+	// SharedSource submissions for more than one account sent from the same
+	// IP or device.
+	SharedSource struct {
+		Value       string               `bson:"_id" json:"value"`
+		Users       []primitive.ObjectID `bson:"users" json:"users"`
+		Submissions []SourceSubmission   `bson:"submissions" json:"submissions"`
+	}
+
+	SourceSubmission struct {
+		ID             primitive.ObjectID `bson:"_id" json:"id"`
+		UserID         primitive.ObjectID `bson:"userID" json:"userID"`
+		SubmissionDate primitive.DateTime `bson:"submissionDate" json:"submissionDate"`
+		UserAgent      string             `bson:"userAgent" json:"userAgent"`
+	}
+
+	// Repository the git commit a submission was archived from.
+	Repository struct {
+		URL    string `bson:"url" json:"url"`
+		Commit string `bson:"commit" json:"commit"`
+		// LinkID the linked repository whose push made the submission.
+		LinkID *primitive.ObjectID `bson:"linkID,omitempty" json:"linkID,omitempty"`
+	}
+
+	// Provenance how a submission was made, and the artifacts uploaded with
+	// it.
+	Provenance struct {
+		Attestation *Attestation
+		Source      *Source
+		Repository  *Repository
+		Artifacts   []Artifact
+		// Checksum the sha256 of the submitted archive.
+		Checksum string
+	}
+
+	// Review how staff graded a submission of a manually graded assignment:
+	// the points it earned on each rubric criterion, comments on spots of
+	// the document and Grade, the points out of 100.
+	Review struct {
+		Scores      []CriterionScore   `bson:"scores" json:"scores"`
+		Annotations []Annotation       `bson:"annotations" json:"annotations"`
+		Comment     string             `bson:"comment" json:"comment"`
+		Grade       float64            `bson:"grade" json:"grade"`
+		By          primitive.ObjectID `bson:"by" json:"by"`
+		At          primitive.DateTime `bson:"at" json:"at"`
+	}
+
+	// CriterionScore the points earned on one rubric criterion.
+	CriterionScore struct {
+		CriterionID primitive.ObjectID `bson:"criterionID" json:"criterionID"`
+		Name        string             `bson:"name" json:"name"`
+		Points      float64            `bson:"points" json:"points"`
+		OutOf       float64            `bson:"outOf" json:"outOf"`
+		Comment     string             `bson:"comment" json:"comment"`
+	}
+
+	// Annotation a comment on a page of the submitted document, at X and Y
+	// as fractions of its width and height from the top left.
+	Annotation struct {
+		Page int     `bson:"page" json:"page"`
+		X    float64 `bson:"x" json:"x"`
+		Y    float64 `bson:"y" json:"y"`
+		Text string  `bson:"text" json:"text"`
+	}
+
+	// Artifact a file uploaded to one of the assignment's slots besides the
+	// code, stored with the submission and not graded.
+	Artifact struct {
+		Slot        string             `bson:"slot" json:"slot"`
+		FileID      primitive.ObjectID `bson:"fileID" json:"-"`
+		Filename    string             `bson:"filename" json:"filename"`
+		ContentType string             `bson:"contentType" json:"contentType"`
+		Size        int64              `bson:"size" json:"size"`
+	}
+
+	// GradeOverride a grade staff gave a submission in place of its graded
+	// one, e.g. after a dispute.
+	GradeOverride struct {
+		Grade     float64             `bson:"grade" json:"grade"`
+		Reason    string              `bson:"reason" json:"reason"`
+		By        primitive.ObjectID  `bson:"by" json:"by"`
+		At        primitive.DateTime  `bson:"at" json:"at"`
+		DisputeID *primitive.ObjectID `bson:"disputeID,omitempty" json:"disputeID,omitempty"`
+	}
+
+	// ShareLink a link a student made to show a submission's results to
+	// someone without an account in the course, until it expires or is
+	// revoked.
+	ShareLink struct {
+		ID        primitive.ObjectID `bson:"_id" json:"id"`
+		CreatedAt primitive.DateTime `bson:"createdAt" json:"createdAt"`
+		ExpiresAt primitive.DateTime `bson:"expiresAt" json:"expiresAt"`
+		Revoked   bool               `bson:"revoked" json:"revoked"`
 	}
 
 	// MongoSubmission struct the struct to represent a submission to an page.
@@ -45,32 +236,259 @@ type (
 		ErrorTesting   bool               `bson:"errorTesting" json:"errorTesting" binding:"exists"`
 		Results        []WorkerResult     `bson:"results" json:"results" binding:"exists"`
 		InProgress     bool               `bson:"inProgress" json:"inProgress"`
+		Withdrawn      bool               `bson:"withdrawn" json:"withdrawn"`
+		FilePurged     bool               `bson:"filePurged" json:"filePurged"`
+		// Job the court herald job grading the submission and GraderURL the
+		// grader running it, COURT_HERALD_URL when empty.
+		Job          string             `bson:"job" json:"job"`
+		GraderURL    string             `bson:"graderURL,omitempty" json:"-"`
+		DispatchedAt primitive.DateTime `bson:"dispatchedAt" json:"-"`
+		Requeues     int                `bson:"requeues" json:"-"`
+		// Queued set while the submission is held back because court herald
+		// is overloaded, it is dispatched once there is room.
+		Queued bool `bson:"queued,omitempty" json:"queued,omitempty"`
+		// Cancelled set when grading was stopped before it finished, and
+		// Refunded when the attempt was given back.
+		Cancelled   bool                `bson:"cancelled,omitempty" json:"cancelled,omitempty"`
+		CancelledAt *primitive.DateTime `bson:"cancelledAt,omitempty" json:"cancelledAt,omitempty"`
+		Refunded    bool                `bson:"refunded,omitempty" json:"refunded,omitempty"`
+		// ErrorReason explains to the student why grading failed.
+		ErrorReason string `bson:"errorReason" json:"errorReason,omitempty"`
+		// BuildOutput what the build step printed, shown to students so they
+		// can fix compile errors.
+		BuildOutput   string             `bson:"buildOutput" json:"buildOutput,omitempty"`
+		BuildFailed   bool               `bson:"buildFailed" json:"buildFailed"`
+		Attestation   *Attestation       `bson:"attestation,omitempty" json:"attestation,omitempty" view:"student,staff"`
+		Source        *Source            `bson:"source,omitempty" json:"source,omitempty" view:"student,staff"`
+		Repository    *Repository        `bson:"repository,omitempty" json:"repository,omitempty" view:"student,staff"`
+		Metrics       map[string]float64 `bson:"metrics,omitempty" json:"metrics,omitempty"`
+		GradeOverride *GradeOverride     `bson:"gradeOverride,omitempty" json:"gradeOverride,omitempty"`
+		ShareLinks    []ShareLink        `bson:"shareLinks,omitempty" json:"-"`
+		Artifacts     []Artifact         `bson:"artifacts,omitempty" json:"artifacts,omitempty" view:"student,staff"`
+		// Manual set on submissions staff grade by hand, which court herald
+		// never sees, and Review once they have.
+		Manual bool    `bson:"manual,omitempty" json:"manual,omitempty"`
+		Review *Review `bson:"review,omitempty" json:"review,omitempty" view:"student,staff"`
+		// QuizAttemptID the quiz attempt submitted, on quiz submissions.
+		QuizAttemptID *primitive.ObjectID `bson:"quizAttemptID,omitempty" json:"quizAttemptID,omitempty"`
+		// Checksum the sha256 of the submitted archive, telling identical
+		// resubmissions apart.
+		Checksum string `bson:"checksum,omitempty" json:"-"`
+		// Size the bytes of the submitted archive and GradingSeconds how long
+		// court herald took to grade it, for usage reports.
+		Size           int64   `bson:"size,omitempty" json:"-"`
+		GradingSeconds float64 `bson:"gradingSeconds,omitempty" json:"-"`
+		// Hidden how many of the tests students are not shown passed, only
+		// set for students of assignments with ShowHiddenSummary.
+		Hidden *ResultCount `bson:"-" json:"hidden,omitempty"`
+	}
+
+	// RecentSubmission a submission of the user's as the dashboard lists it,
+	// with the course and assignment it was made to.
+	RecentSubmission struct {
+		ID             primitive.ObjectID     `bson:"_id" json:"id"`
+		AssignmentID   primitive.ObjectID     `bson:"assignmentID" json:"assignmentID"`
+		SubmissionDate primitive.DateTime     `bson:"submissionDate" json:"submissionDate"`
+		File           string                 `bson:"file" json:"file"`
+		ErrorTesting   bool                   `bson:"errorTesting" json:"errorTesting"`
+		Results        []WorkerResult         `bson:"results" json:"results"`
+		AttemptNumber  int                    `bson:"attemptNumber" json:"attemptNumber"`
+		InProgress     bool                   `bson:"inProgress" json:"inProgress"`
+		ErrorReason    string                 `bson:"errorReason" json:"errorReason,omitempty"`
+		BuildOutput    string                 `bson:"buildOutput" json:"buildOutput,omitempty"`
+		BuildFailed    bool                   `bson:"buildFailed" json:"buildFailed"`
+		Course         RecentSubmissionCourse `bson:"course" json:"course"`
+		Assignment     RecentAssignment       `bson:"assignment" json:"assignment"`
+		Hidden         *ResultCount           `bson:"-" json:"hidden,omitempty"`
+	}
+
+	// RecentSubmissionCourse the course a recent submission was made in.
+	RecentSubmissionCourse struct {
+		ID         primitive.ObjectID `bson:"_id" json:"id"`
+		Department string             `bson:"department" json:"department"`
+		LongName   string             `bson:"longName" json:"longName"`
+		Number     int                `bson:"number" json:"number"`
+		Section    string             `bson:"section" json:"section"`
+		Semester   string             `bson:"semester" json:"semester"`
+	}
+
+	// RecentAssignment the assignment a recent submission was made to.
+	RecentAssignment struct {
+		ID          primitive.ObjectID `bson:"_id" json:"id"`
+		Language    string             `bson:"language" json:"language"`
+		Version     string             `bson:"version" json:"version"`
+		Name        string             `bson:"name" json:"name"`
+		NumAttempts int                `bson:"numAttempts" json:"numAttempts"`
+		DueDate     primitive.DateTime `bson:"dueDate" json:"dueDate"`
+		Published   bool               `bson:"published" json:"-"`
+		ExtraCredit bool               `bson:"extraCredit" json:"extraCredit"`
+
+		ShowHiddenSummary bool `bson:"showHiddenSummary" json:"-"`
 	}
 
 	SubmissionInterface struct {
 		ctx context.Context
 		col *mongo.Collection
+		// analytics the collection as read by reports, see
+		// utils.AnalyticsCollection.
+		analytics *mongo.Collection
 	}
 )
 
+// metricName the metric names kept from a grade report, so they are safe to
+// use as field names.
+var metricName = regexp.MustCompile("^[A-Za-z][A-Za-z0-9_]*$")
+
+// MaxOutputLength caps how much of a test's output, stderr or a build log is
+// stored on a submission. Configured in KB with MAX_RESULT_OUTPUT_KB.
+func MaxOutputLength() int {
+	return config.C.MaxResultOutputKB * 1024
+}
+
+func truncationMarker(max int) string {
+	return fmt.Sprintf("... output truncated at %d KB ...", max/1024)
+}
+
+// TruncateOutput keeps the beginning of an oversized test output.
+func TruncateOutput(output string) string {
+	max := MaxOutputLength()
+	if len(output) <= max {
+		return output
+	}
+
+	return output[:max] + "\n" + truncationMarker(max)
+}
+
+// truncateLog keeps the end of an oversized log, the part that explains a failure.
+func truncateLog(log string) string {
+	max := MaxOutputLength()
+	if len(log) <= max {
+		return log
+	}
+
+	return truncationMarker(max) + "\n" + log[len(log)-max:]
+}
+
+// OutputFileIDs returns the gridfs files holding the submission's oversized outputs.
+func (m *MongoSubmission) OutputFileIDs() []primitive.ObjectID {
+	fids := make([]primitive.ObjectID, 0)
+	for _, result := range m.Results {
+		if result.OutputFileID != nil {
+			fids = append(fids, *result.OutputFileID)
+		}
+	}
+
+	return fids
+}
+
+// ArtifactFileIDs returns the gridfs files holding the submission's artifacts.
+func (m *MongoSubmission) ArtifactFileIDs() []primitive.ObjectID {
+	fids := make([]primitive.ObjectID, len(m.Artifacts))
+	for index, artifact := range m.Artifacts {
+		fids[index] = artifact.FileID
+	}
+
+	return fids
+}
+
+// Artifact the submission's artifact of a slot, nil when it has none.
+func (m *MongoSubmission) Artifact(slot string) *Artifact {
+	for index := range m.Artifacts {
+		if m.Artifacts[index].Slot == slot {
+			return &m.Artifacts[index]
+		}
+	}
+
+	return nil
+}
+
+// StudentFacingResults is an aggregation expression for the results of a
+// submission a student may see: only student facing tests, without stderr.
+func StudentFacingResults() bson.M {
+	return bson.M{
+		"$map": bson.M{
+			"input": bson.M{
+				"$filter": bson.M{
+					"input": bson.M{"$ifNull": bson.A{"$results", bson.A{}}},
+					"as":    "result",
+					"cond":  bson.M{"$eq": bson.A{"$$result.studentFacing", true}},
+				},
+			},
+			"as": "result",
+			"in": bson.M{
+				"id":            "$$result.id",
+				"panicked":      "$$result.panicked",
+				"passed":        "$$result.passed",
+				"studentFacing": "$$result.studentFacing",
+				"output":        "$$result.output",
+				"html":          "$$result.html",
+				"testCMD":       "$$result.testCMD",
+				"name":          "$$result.name",
+				"match":         "$$result.match",
+				"epsilon":       "$$result.epsilon",
+				"outputFileID":  "$$result.outputFileID",
+				"outputSize":    "$$result.outputSize",
+			},
+		},
+	}
+}
+
 func New() *SubmissionInterface {
-	db, _ := tyrgin.GetMongoDB(os.Getenv("DB_NAME"))
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
 	col := tyrgin.GetMongoCollection("submissions", db)
 
 	return &SubmissionInterface{
 		context.Background(),
 		col,
+		utils.AnalyticsCollection("submissions", db),
 	}
 }
 
-func (s *SubmissionInterface) UpdateGrade(sid interface{}, results []WorkerResult) errors.APIError {
-	_, err := s.col.UpdateOne(
+// publishGraded announces a submission finished grading.
+func publishGraded(sid interface{}, errored bool) {
+	if id, ok := sid.(primitive.ObjectID); ok {
+		events.Publish(events.SubmissionGradedEvent, events.SubmissionGraded{SubmissionID: id, Errored: errored})
+		changedGrading(id)
+	}
+}
+
+// changedGrading tells this replica's watchers a submission left grading,
+// for when change streams are off.
+func changedGrading(sid interface{}) {
+	if id, ok := sid.(primitive.ObjectID); ok {
+		events.Changed(events.Change{Collection: events.SubmissionsCollection, Operation: events.ChangeUpdate, ID: id})
+	}
+}
+
+func (s *SubmissionInterface) UpdateGrade(sid interface{}, report GradeReport) errors.APIError {
+	results := report.Results
+	for index := range results {
+		results[index].HTML = utils.SanitizeHTML(results[index].HTML)
+		results[index].Output = TruncateOutput(results[index].Output)
+		results[index].Stderr = truncateLog(results[index].Stderr)
+		if results[index].DurationMS < 0 {
+			results[index].DurationMS = 0
+		}
+	}
+
+	metrics := make(map[string]float64)
+	for name, value := range report.Metrics {
+		if metricName.MatchString(name) {
+			metrics[name] = value
+		}
+	}
+
+	res, err := s.col.UpdateOne(
 		s.ctx,
-		bson.M{"_id": sid},
+		bson.M{"_id": sid, "cancelled": bson.M{"$ne": true}},
 		bson.M{
 			"$set": bson.M{
-				"results":    results,
-				"inProgress": false,
+				"results":     results,
+				"inProgress":  false,
+				"errorReason": "",
+				"buildOutput": truncateLog(report.BuildOutput),
+				"buildFailed": report.BuildFailed,
+				"metrics":     metrics,
 			},
 		},
 	)
@@ -78,17 +496,24 @@ func (s *SubmissionInterface) UpdateGrade(sid interface{}, results []WorkerResul
 		return errors.ErrorDatabaseFailedUpdate
 	}
 
+	if res.MatchedCount == 0 {
+		return nil
+	}
+
+	publishGraded(sid, false)
 	return nil
 }
 
-func (s *SubmissionInterface) UpdateError(sid interface{}) errors.APIError {
-	_, err := s.col.UpdateOne(
+func (s *SubmissionInterface) UpdateError(sid interface{}, report GradeReport) errors.APIError {
+	res, err := s.col.UpdateOne(
 		s.ctx,
-		bson.M{"_id": sid},
+		bson.M{"_id": sid, "cancelled": bson.M{"$ne": true}},
 		bson.M{
 			"$set": bson.M{
 				"errorTesting": true,
 				"inProgress":   false,
+				"buildOutput":  truncateLog(report.BuildOutput),
+				"buildFailed":  report.BuildFailed,
 			},
 		},
 	)
@@ -96,6 +521,11 @@ func (s *SubmissionInterface) UpdateError(sid interface{}) errors.APIError {
 		return errors.ErrorDatabaseFailedUpdate
 	}
 
+	if res.MatchedCount == 0 {
+		return nil
+	}
+
+	publishGraded(sid, true)
 	return nil
 }
 
@@ -108,18 +538,264 @@ func (s *SubmissionInterface) Get(sid interface{}, role string) (*MongoSubmissio
 		return nil, errors.ErrorInvalidBSON
 	}
 
-	if role == "student" {
-		filteredResults := make([]WorkerResult, 0)
-		for _, result := range sub.Results {
-			if result.StudentFacing {
-				filteredResults = append(filteredResults, result)
+	sub.RedactFor(utils.ViewFor(role), false)
+
+	return sub, nil
+}
+
+// Score the submission's grade out of 100: its override when staff gave it
+// one, otherwise the share of tests it passed. Overrides can exceed 100.
+func (m *MongoSubmission) Score() float64 {
+	if m.GradeOverride != nil {
+		return m.GradeOverride.Grade
+	}
+
+	if len(m.Results) == 0 {
+		return 0
+	}
+
+	passed := 0
+	for _, result := range m.Results {
+		if result.Passed {
+			passed++
+		}
+	}
+
+	return 100 * float64(passed) / float64(len(m.Results))
+}
+
+// Stability how reliably each test ran over the graded submissions. Tests
+// are flaky when identical resubmissions, by checksum, both passed and
+// failed them, and timing sensitive when at least minRuns timed runs vary
+// by more than maxVariation of their mean. Flagged tests come first.
+func Stability(submissions []MongoSubmission, minRuns int, maxVariation float64) []TestStability {
+	type outcomes struct{ runs, passed, failed int }
+
+	stats := make(map[string]*TestStability)
+	durations := make(map[string][]float64)
+	groups := make(map[string]map[string]*outcomes)
+	for _, submission := range submissions {
+		for _, result := range submission.Results {
+			stat, found := stats[result.Name]
+			if !found {
+				stat = &TestStability{Name: result.Name}
+				stats[result.Name] = stat
+				groups[result.Name] = make(map[string]*outcomes)
+			}
+
+			stat.Runs++
+			if !result.Passed {
+				stat.Failures++
+			}
+			if result.DurationMS > 0 {
+				durations[result.Name] = append(durations[result.Name], result.DurationMS)
+			}
+
+			if submission.Checksum == "" {
+				continue
+			}
+			group, found := groups[result.Name][submission.Checksum]
+			if !found {
+				group = &outcomes{}
+				groups[result.Name][submission.Checksum] = group
+			}
+			group.runs++
+			if result.Passed {
+				group.passed++
+			} else {
+				group.failed++
 			}
 		}
-		sub.Results = filteredResults
-		return sub, nil
 	}
 
-	return sub, nil
+	stability := make([]TestStability, 0, len(stats))
+	for name, stat := range stats {
+		for _, group := range groups[name] {
+			if group.runs < 2 {
+				continue
+			}
+			stat.IdenticalGroups++
+			if group.passed > 0 && group.failed > 0 {
+				stat.Intermittent++
+			}
+		}
+
+		stat.TimedRuns = len(durations[name])
+		if stat.TimedRuns > 0 {
+			var sum float64
+			for _, duration := range durations[name] {
+				sum += duration
+			}
+			stat.MeanMS = sum / float64(stat.TimedRuns)
+
+			var squares float64
+			for _, duration := range durations[name] {
+				squares += (duration - stat.MeanMS) * (duration - stat.MeanMS)
+			}
+			stat.StdDevMS = math.Sqrt(squares / float64(stat.TimedRuns))
+			stat.Variation = stat.StdDevMS / stat.MeanMS
+		}
+
+		stat.Flaky = stat.Intermittent > 0
+		stat.TimingSensitive = stat.TimedRuns >= minRuns && stat.Variation > maxVariation
+		stability = append(stability, *stat)
+	}
+
+	sort.Slice(stability, func(i, j int) bool {
+		a, b := stability[i], stability[j]
+		if flagged := a.Flaky || a.TimingSensitive; flagged != (b.Flaky || b.TimingSensitive) {
+			return flagged
+		}
+		if a.Intermittent != b.Intermittent {
+			return a.Intermittent > b.Intermittent
+		}
+		if a.Variation != b.Variation {
+			return a.Variation > b.Variation
+		}
+		return a.Name < b.Name
+	})
+
+	return stability
+}
+
+// VisibleTo only staff see the results of tests that are not student facing.
+func (r WorkerResult) VisibleTo(view string) bool {
+	return r.StudentFacing || view == utils.ViewStaff
+}
+
+// CountResults how many of the results passed, of the student facing tests
+// and of the hidden ones.
+func CountResults(results []WorkerResult) (shown, hidden ResultCount) {
+	for _, result := range results {
+		count := &shown
+		if !result.StudentFacing {
+			count = &hidden
+		}
+
+		count.Total++
+		if result.Passed {
+			count.Passed++
+		}
+	}
+
+	return shown, hidden
+}
+
+// HiddenCount what a view is told about the results of hidden tests: staff
+// see the results themselves and students nothing, unless summarize lets
+// them know how many of them passed.
+func HiddenCount(results []WorkerResult, view string, summarize bool) *ResultCount {
+	if view == utils.ViewStaff || !summarize {
+		return nil
+	}
+
+	_, hidden := CountResults(results)
+	return &hidden
+}
+
+// RedactFor removes what the view may not see from the submission. Hidden
+// tests never reach students or share links, summarize only tells them how
+// many of them passed.
+func (m *MongoSubmission) RedactFor(view string, summarize bool) {
+	m.Hidden = HiddenCount(m.Results, view, summarize)
+	utils.Redact(m, view)
+}
+
+// FilterForStudent removes what a student may not see from a submission: the
+// results of tests that are not student facing and stderr.
+func (m *MongoSubmission) FilterForStudent() {
+	m.RedactFor(utils.ViewStudent, false)
+}
+
+// FilterForShare removes from a submission what a student sees but should not
+// reach whoever a share link is given to: where and how it was submitted.
+func (m *MongoSubmission) FilterForShare() {
+	m.RedactFor(utils.ViewShare, false)
+}
+
+// RedactProgress removes from a grading progress event what the view may not
+// see. Students are not told which test is running, since it may be hidden,
+// and unless summarize, not how many tests there are either.
+func RedactProgress(event courtherald.ProgressEvent, view string, summarize bool) courtherald.ProgressEvent {
+	if view == utils.ViewStaff {
+		return event
+	}
+
+	event.Test = ""
+	event.Message = ""
+	if !summarize {
+		event.Completed = 0
+		event.Total = 0
+	}
+
+	return event
+}
+
+// Active reports whether a share link can still be viewed.
+func (l *ShareLink) Active() bool {
+	return !l.Revoked && time.Now().Before(utils.DateTimeToTime(l.ExpiresAt))
+}
+
+// ShareLink the submission's share link with the id, nil when there is none.
+func (m *MongoSubmission) ShareLink(shid primitive.ObjectID) *ShareLink {
+	for index := range m.ShareLinks {
+		if m.ShareLinks[index].ID == shid {
+			return &m.ShareLinks[index]
+		}
+	}
+
+	return nil
+}
+
+// AddShareLink gives a submission a new share link, expiring at expiresAt.
+func (s *SubmissionInterface) AddShareLink(sid interface{}, expiresAt time.Time) (*ShareLink, errors.APIError) {
+	link := ShareLink{
+		ID:        primitive.NewObjectID(),
+		CreatedAt: utils.TimeToDateTime(time.Now()),
+		ExpiresAt: utils.TimeToDateTime(expiresAt),
+	}
+
+	res, err := s.col.UpdateOne(s.ctx, bson.M{"_id": sid}, bson.M{"$push": bson.M{"shareLinks": link}})
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedUpdate
+	}
+
+	if res.MatchedCount == 0 {
+		return nil, errors.ErrorResourceNotFound
+	}
+
+	return &link, nil
+}
+
+// RevokeShareLink stops a submission's share link from being viewed.
+func (s *SubmissionInterface) RevokeShareLink(sid, shid interface{}) errors.APIError {
+	res, err := s.col.UpdateOne(
+		s.ctx,
+		bson.M{"_id": sid, "shareLinks._id": shid},
+		bson.M{"$set": bson.M{"shareLinks.$.revoked": true}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.ErrorResourceNotFound
+	}
+
+	return nil
+}
+
+// GetByShareLink the submission a share link belongs to.
+func (s *SubmissionInterface) GetByShareLink(shid interface{}) (*MongoSubmission, errors.APIError) {
+	var submission *MongoSubmission
+	res := s.col.FindOne(s.ctx, bson.M{"shareLinks._id": shid}, options.FindOne())
+	res.Decode(&submission)
+
+	if submission == nil {
+		return nil, errors.ErrorResourceNotFound
+	}
+
+	return submission, nil
 }
 
 func (s *SubmissionInterface) Delete(sid interface{}) errors.APIError {
@@ -151,7 +827,370 @@ func (s *SubmissionInterface) GetUsersSubmissions(uid interface{}) ([]MongoSubmi
 		submissions = append(submissions, submission)
 	}
 
-	return submissions, nil
+	return submissions, nil
+}
+
+// InFlight the student's submission of the assignment still being graded or
+// waiting to be, nil when there is none.
+func (s *SubmissionInterface) InFlight(aid, uid interface{}) (*MongoSubmission, errors.APIError) {
+	var submission *MongoSubmission
+	err := s.col.FindOne(
+		s.ctx,
+		bson.M{"assignmentID": aid, "userID": uid, "inProgress": true},
+		options.FindOne().SetSort(bson.M{"submissionDate": -1}),
+	).Decode(&submission)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedQuery
+	}
+
+	return submission, nil
+}
+
+// SetWithdrawn flags (or unflags) a user's submissions to the given assignments
+// as belonging to a withdrawn enrollment.
+func (s *SubmissionInterface) SetWithdrawn(aids []primitive.ObjectID, uid interface{}, withdrawn bool) errors.APIError {
+	_, err := s.col.UpdateMany(
+		s.ctx,
+		bson.M{
+			"userID":       uid,
+			"assignmentID": bson.M{"$in": aids},
+		},
+		bson.M{"$set": bson.M{"withdrawn": withdrawn}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// GetByAssignmentIDs returns every submission to the given assignments.
+// OverrideGrade gives a submission a grade in place of its graded one.
+func (s *SubmissionInterface) OverrideGrade(sid interface{}, override GradeOverride) errors.APIError {
+	_, err := s.col.UpdateOne(
+		s.ctx,
+		bson.M{"_id": sid},
+		bson.M{"$set": bson.M{"gradeOverride": &override}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// GetUsersAssignmentSubmissions returns every attempt a user made at an
+// assignment, oldest first.
+func (s *SubmissionInterface) GetUsersAssignmentSubmissions(aid, uid interface{}) ([]MongoSubmission, errors.APIError) {
+	submissions := make([]MongoSubmission, 0)
+	cur, err := s.col.Find(
+		s.ctx,
+		bson.M{"assignmentID": aid, "userID": uid},
+		options.Find().SetSort(bson.M{"attemptNumber": 1}),
+	)
+	if err != nil {
+		return submissions, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(s.ctx) {
+		var submission MongoSubmission
+		err = cur.Decode(&submission)
+		if err != nil {
+			return submissions, errors.ErrorInvalidBSON
+		}
+
+		submissions = append(submissions, submission)
+	}
+
+	return submissions, nil
+}
+
+// BestScores a user's best Score on each of the assignments they submitted
+// to, leaving out refunded attempts.
+func (s *SubmissionInterface) BestScores(uid interface{}, aids []primitive.ObjectID) (map[primitive.ObjectID]float64, errors.APIError) {
+	best := make(map[primitive.ObjectID]float64)
+	if len(aids) == 0 {
+		return best, nil
+	}
+
+	cur, err := s.col.Find(
+		s.ctx,
+		bson.M{"userID": uid, "assignmentID": bson.M{"$in": aids}, "refunded": bson.M{"$ne": true}},
+		options.Find().SetProjection(bson.M{"assignmentID": 1, "results.passed": 1, "gradeOverride": 1}),
+	)
+	if err != nil {
+		return best, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(s.ctx) {
+		var submission MongoSubmission
+		err = cur.Decode(&submission)
+		if err != nil {
+			return best, errors.ErrorInvalidBSON
+		}
+
+		score := submission.Score()
+		if current, ok := best[submission.AssignmentID]; !ok || score > current {
+			best[submission.AssignmentID] = score
+		}
+	}
+
+	return best, nil
+}
+
+func (s *SubmissionInterface) GetByAssignmentIDs(aids []primitive.ObjectID) ([]MongoSubmission, errors.APIError) {
+	return s.byAssignmentIDs(s.col, aids)
+}
+
+// ReportByAssignmentIDs GetByAssignmentIDs read for gradebooks and archives,
+// which may lag behind the latest submissions.
+func (s *SubmissionInterface) ReportByAssignmentIDs(aids []primitive.ObjectID) ([]MongoSubmission, errors.APIError) {
+	return s.byAssignmentIDs(s.analytics, aids)
+}
+
+func (s *SubmissionInterface) byAssignmentIDs(col *mongo.Collection, aids []primitive.ObjectID) ([]MongoSubmission, errors.APIError) {
+	submissions := make([]MongoSubmission, 0)
+	cur, err := col.Find(
+		s.ctx,
+		bson.M{"assignmentID": bson.M{"$in": aids}},
+		options.Find().SetSort(bson.M{"submissionDate": 1}),
+	)
+	if err != nil {
+		return submissions, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(s.ctx) {
+		var submission MongoSubmission
+		err = cur.Decode(&submission)
+		if err != nil {
+			return submissions, errors.ErrorInvalidBSON
+		}
+
+		submissions = append(submissions, submission)
+	}
+
+	return submissions, nil
+}
+
+// SharedSources finds the IPs or devices, by the source field given, that
+// submitted an assignment for at least minAccounts accounts between from and to.
+func (s *SubmissionInterface) SharedSources(aid interface{}, field string, from, to time.Time, minAccounts int) ([]SharedSource, errors.APIError) {
+	shared := make([]SharedSource, 0)
+	query := []interface{}{
+		bson.M{"$match": bson.M{
+			"assignmentID":    aid,
+			"source." + field: bson.M{"$exists": true, "$ne": ""},
+			"submissionDate": bson.M{
+				"$gte": utils.TimeToDateTime(from),
+				"$lte": utils.TimeToDateTime(to),
+			},
+		}},
+		bson.M{"$sort": bson.M{"submissionDate": 1}},
+		bson.M{"$group": bson.M{
+			"_id":   "$source." + field,
+			"users": bson.M{"$addToSet": "$userID"},
+			"submissions": bson.M{"$push": bson.M{
+				"_id":            "$_id",
+				"userID":         "$userID",
+				"submissionDate": "$submissionDate",
+				"userAgent":      "$source.userAgent",
+			}},
+		}},
+		bson.M{"$addFields": bson.M{"accounts": bson.M{"$size": "$users"}}},
+		bson.M{"$match": bson.M{"accounts": bson.M{"$gte": minAccounts}}},
+		bson.M{"$sort": bson.M{"accounts": -1}},
+	}
+
+	cur, err := s.analytics.Aggregate(s.ctx, query, options.Aggregate())
+	if err != nil {
+		return shared, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(s.ctx) {
+		var source SharedSource
+		err = cur.Decode(&source)
+		if err != nil {
+			return shared, errors.ErrorInvalidBSON
+		}
+
+		shared = append(shared, source)
+	}
+
+	return shared, nil
+}
+
+// RecordUsage adds the size or grading time of a submission to it.
+func (s *SubmissionInterface) RecordUsage(sid interface{}, size int64, seconds float64) errors.APIError {
+	_, err := s.col.UpdateOne(
+		s.ctx,
+		bson.M{"_id": sid},
+		bson.M{"$inc": bson.M{"size": size, "gradingSeconds": seconds}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// Usage totals the submissions made from one time until another per course
+// and month, oldest month first.
+func (s *SubmissionInterface) Usage(from, to time.Time) ([]UsageRow, errors.APIError) {
+	rows := make([]UsageRow, 0)
+	query := []interface{}{
+		bson.M{"$match": bson.M{"submissionDate": bson.M{
+			"$gte": utils.TimeToDateTime(from),
+			"$lt":  utils.TimeToDateTime(to),
+		}}},
+		bson.M{"$lookup": bson.M{
+			"from":         "courses",
+			"localField":   "assignmentID",
+			"foreignField": "assignments",
+			"as":           "course",
+		}},
+		bson.M{"$unwind": "$course"},
+		bson.M{"$group": bson.M{
+			"_id": bson.M{
+				"course": "$course._id",
+				"month":  bson.M{"$dateToString": bson.M{"format": "%Y-%m", "date": "$submissionDate"}},
+			},
+			"department":     bson.M{"$first": "$course.department"},
+			"number":         bson.M{"$first": "$course.number"},
+			"semester":       bson.M{"$first": "$course.semester"},
+			"jobs":           bson.M{"$sum": 1},
+			"gradingSeconds": bson.M{"$sum": "$gradingSeconds"},
+			"storageBytes":   bson.M{"$sum": "$size"},
+			"users":          bson.M{"$addToSet": "$userID"},
+		}},
+		bson.M{"$addFields": bson.M{
+			"courseID":    "$_id.course",
+			"month":       "$_id.month",
+			"activeUsers": bson.M{"$size": "$users"},
+		}},
+		bson.M{"$sort": bson.D{{"month", 1}, {"department", 1}, {"number", 1}}},
+	}
+
+	cur, err := s.analytics.Aggregate(s.ctx, query, options.Aggregate())
+	if err != nil {
+		return rows, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(s.ctx) {
+		var row UsageRow
+		err = cur.Decode(&row)
+		if err != nil {
+			return rows, errors.ErrorInvalidBSON
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// Leaderboard ranks each student's best passing submission of an assignment by
+// a metric, leaving out withdrawn students and those in exclude.
+func (s *SubmissionInterface) Leaderboard(aid interface{}, metric string, lowerIsBetter bool, exclude []primitive.ObjectID, limit int) ([]LeaderboardEntry, errors.APIError) {
+	entries := make([]LeaderboardEntry, 0)
+	order := -1
+	if lowerIsBetter {
+		order = 1
+	}
+
+	query := []interface{}{
+		bson.M{"$match": bson.M{
+			"assignmentID":      aid,
+			"userID":            bson.M{"$nin": exclude},
+			"withdrawn":         bson.M{"$ne": true},
+			"errorTesting":      false,
+			"inProgress":        false,
+			"results.0":         bson.M{"$exists": true},
+			"results.passed":    bson.M{"$ne": false},
+			"metrics." + metric: bson.M{"$exists": true},
+		}},
+		bson.M{"$sort": bson.D{{"metrics." + metric, order}, {"submissionDate", 1}}},
+		bson.M{"$group": bson.M{
+			"_id":            "$userID",
+			"submissionID":   bson.M{"$first": "$_id"},
+			"value":          bson.M{"$first": "$metrics." + metric},
+			"attemptNumber":  bson.M{"$first": "$attemptNumber"},
+			"submissionDate": bson.M{"$first": "$submissionDate"},
+		}},
+		bson.M{"$sort": bson.D{{"value", order}, {"submissionDate", 1}}},
+		bson.M{"$limit": limit},
+	}
+
+	cur, err := s.analytics.Aggregate(s.ctx, query, options.Aggregate())
+	if err != nil {
+		return entries, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(s.ctx) {
+		var entry LeaderboardEntry
+		err = cur.Decode(&entry)
+		if err != nil {
+			return entries, errors.ErrorInvalidBSON
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// LatestPerStudent finds each student's most recent submission of an
+// assignment, leaving out withdrawn students.
+func (s *SubmissionInterface) LatestPerStudent(aid interface{}) ([]LatestSubmission, errors.APIError) {
+	latest := make([]LatestSubmission, 0)
+	query := []interface{}{
+		bson.M{"$match": bson.M{
+			"assignmentID": aid,
+			"withdrawn":    bson.M{"$ne": true},
+		}},
+		bson.M{"$sort": bson.M{"submissionDate": -1}},
+		bson.M{"$group": bson.M{
+			"_id":           "$userID",
+			"submissionID":  bson.M{"$first": "$_id"},
+			"fileID":        bson.M{"$first": "$fileID"},
+			"filePurged":    bson.M{"$first": "$filePurged"},
+			"attemptNumber": bson.M{"$first": "$attemptNumber"},
+		}},
+	}
+
+	cur, err := s.col.Aggregate(s.ctx, query, options.Aggregate())
+	if err != nil {
+		return latest, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(s.ctx) {
+		var submission LatestSubmission
+		err = cur.Decode(&submission)
+		if err != nil {
+			return latest, errors.ErrorInvalidBSON
+		}
+
+		latest = append(latest, submission)
+	}
+
+	return latest, nil
+}
+
+// MarkFilesPurged records that the files of the given submissions were removed
+// while their results are kept.
+func (s *SubmissionInterface) MarkFilesPurged(sids []primitive.ObjectID) errors.APIError {
+	_, err := s.col.UpdateMany(
+		s.ctx,
+		bson.M{"_id": bson.M{"$in": sids}},
+		bson.M{"$set": bson.M{"filePurged": true}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
 }
 
 func (s *SubmissionInterface) DeleteByAssignmentID(aid interface{}) errors.APIError {
@@ -164,7 +1203,7 @@ func (s *SubmissionInterface) DeleteByAssignmentID(aid interface{}) errors.APIEr
 }
 
 // GetUsersRecentSubmissions grabs the most recent submissions up until limit
-func (s *SubmissionInterface) GetUsersRecentSubmissions(uid interface{}, limit int64) ([]map[string]interface{}, errors.APIError) {
+func (s *SubmissionInterface) GetUsersRecentSubmissions(uid interface{}, limit int64) ([]RecentSubmission, errors.APIError) {
 	query := []interface{}{
 		bson.M{"$match": bson.M{"userID": uid}},
 		bson.M{
@@ -179,6 +1218,7 @@ func (s *SubmissionInterface) GetUsersRecentSubmissions(uid interface{}, limit i
 							"assistants":  0,
 							"students":    0,
 							"assignments": 0,
+							"inviteCodes": 0,
 						},
 					},
 				},
@@ -211,9 +1251,12 @@ func (s *SubmissionInterface) GetUsersRecentSubmissions(uid interface{}, limit i
 				"submissionDate": 1,
 				"file":           1,
 				"errorTesting":   1,
-				"results":        bson.M{"$filter": bson.M{"input": "$results", "as": "result", "cond": bson.M{"$eq": bson.A{"$$result.studentFacing", true}}}},
+				"results":        1,
 				"attemptNumber":  1,
 				"inProgress":     1,
+				"errorReason":    1,
+				"buildOutput":    1,
+				"buildFailed":    1,
 				"assignment":     bson.M{"$arrayElemAt": bson.A{"$assignment", 0}},
 			},
 		},
@@ -223,7 +1266,7 @@ func (s *SubmissionInterface) GetUsersRecentSubmissions(uid interface{}, limit i
 			"$expr": bson.M{"$eq": bson.A{"$assignment.published", true}}}},
 	}
 
-	recentSubmissions := make([]map[string]interface{}, 0)
+	recentSubmissions := make([]RecentSubmission, 0)
 	cur, err := s.col.Aggregate(
 		s.ctx,
 		query,
@@ -234,11 +1277,12 @@ func (s *SubmissionInterface) GetUsersRecentSubmissions(uid interface{}, limit i
 	}
 
 	for cur.Next(s.ctx) {
-		var submission map[string]interface{}
-		err = cur.Decode(&submission)
-		if submission == nil {
+		var submission RecentSubmission
+		if err = cur.Decode(&submission); err != nil {
 			return nil, errors.ErrorResourceNotFound
 		}
+		submission.Hidden = HiddenCount(submission.Results, utils.ViewStudent, submission.Assignment.ShowHiddenSummary)
+		utils.Redact(&submission, utils.ViewStudent)
 		recentSubmissions = append(recentSubmissions, submission)
 	}
 
@@ -264,7 +1308,11 @@ func (s *SubmissionInterface) GetUsersSubmission(sid, uid interface{}) (*MongoSu
 	return submission, nil
 }
 
-func (s *SubmissionInterface) Submit(aid, fid, uid, sid interface{}, attempt int, filename string, tests interface{}, testBuildCMD string, lang string) (string, errors.APIError) {
+// Submit stores a submission and sends it to court herald. Held submissions
+// are only stored, to be dispatched later with Release, as are those court
+// herald could not be reached for. It returns the job and whether the
+// submission was held.
+func (s *SubmissionInterface) Submit(aid, fid, uid, sid interface{}, attempt int, filename string, grading courtherald.Job, graders []string, provenance Provenance, hold bool) (string, bool, errors.APIError) {
 	submission := MongoSubmission{
 		ID:             sid.(primitive.ObjectID),
 		UserID:         uid.(primitive.ObjectID),
@@ -276,47 +1324,556 @@ func (s *SubmissionInterface) Submit(aid, fid, uid, sid interface{}, attempt int
 		ErrorTesting:   false,
 		Results:        nil,
 		InProgress:     true,
+		Attestation:    provenance.Attestation,
+		Source:         provenance.Source,
+		Repository:     provenance.Repository,
+		Artifacts:      provenance.Artifacts,
+		Checksum:       provenance.Checksum,
+		Queued:         hold,
 	}
+	submission.DispatchedAt = submission.SubmissionDate
 
 	_, err := s.col.InsertOne(s.ctx, &submission, options.InsertOne())
 	if err != nil {
-		return "", errors.ErrorDatabaseFailedCreate
+		return "", false, errors.ErrorDatabaseFailedCreate
 	}
 
-	// API Call to court herald
-	url := fmt.Sprintf("%s/api/v1/grader/%s/new", os.Getenv("COURT_HERALD_URL"), sid.(primitive.ObjectID).Hex())
-	requestData := make(map[string]interface{})
-	requestData["submission"] = submission
-	requestData["tests"] = tests
-	requestData["testBuildCMD"] = testBuildCMD
-	requestData["language"] = lang
+	if hold {
+		return "", true, nil
+	}
 
-	bs, err := json.Marshal(&requestData)
-	if err != nil {
+	job, grader, errs := s.dispatch(submission, grading, graders)
+	if errs == errors.ErrorUnableToReachMicroService {
+		s.col.UpdateOne(s.ctx, bson.M{"_id": sid}, bson.M{"$set": bson.M{"queued": true}})
+		return "", true, nil
+	}
+	if errs != nil {
 		s.Delete(sid)
-		return "", errors.ErrorInvalidJSON
+		return "", false, errs
+	}
+
+	s.col.UpdateOne(s.ctx, bson.M{"_id": sid}, bson.M{"$set": bson.M{"job": job, "graderURL": grader}})
+
+	return job, false, nil
+}
+
+// SubmitManual stores a submission staff grade by hand. It is never sent to
+// court herald, so it is not in progress while it waits for them.
+func (s *SubmissionInterface) SubmitManual(aid, fid, uid, sid interface{}, attempt int, filename string, provenance Provenance) errors.APIError {
+	submission := MongoSubmission{
+		ID:             sid.(primitive.ObjectID),
+		UserID:         uid.(primitive.ObjectID),
+		FileID:         fid.(primitive.ObjectID),
+		AssignmentID:   aid.(primitive.ObjectID),
+		AttemptNumber:  attempt,
+		SubmissionDate: utils.TimeToDateTime(time.Now()),
+		File:           filename,
+		Results:        nil,
+		Attestation:    provenance.Attestation,
+		Source:         provenance.Source,
+		Artifacts:      provenance.Artifacts,
+		Checksum:       provenance.Checksum,
+		Manual:         true,
 	}
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(bs))
-	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	_, err := s.col.InsertOne(s.ctx, &submission, options.InsertOne())
 	if err != nil {
-		s.Delete(sid)
-		return "", errors.ErrorUnableToReachMicroService
+		return errors.ErrorDatabaseFailedCreate
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		s.Delete(sid)
-		return "", errors.ErrorUnableToCreateJob
+	return nil
+}
+
+// SubmitQuiz stores a submitted quiz attempt, graded already. Its score
+// stands in as a grade override, which staff can still change.
+func (s *SubmissionInterface) SubmitQuiz(aid, uid, sid, qaid primitive.ObjectID, attempt int, score float64) errors.APIError {
+	now := utils.TimeToDateTime(time.Now())
+	submission := MongoSubmission{
+		ID:             sid,
+		UserID:         uid,
+		AssignmentID:   aid,
+		AttemptNumber:  attempt,
+		SubmissionDate: now,
+		QuizAttemptID:  &qaid,
+		GradeOverride: &GradeOverride{
+			Grade:  score,
+			Reason: "Scored from the quiz.",
+			At:     now,
+		},
+	}
+
+	_, err := s.col.InsertOne(s.ctx, &submission, options.InsertOne())
+	if err != nil {
+		return errors.ErrorDatabaseFailedCreate
+	}
+
+	publishGraded(sid, false)
+	return nil
+}
+
+// SetReview grades a manually graded submission, its review's grade
+// standing in as a grade override so it counts wherever grades do.
+func (s *SubmissionInterface) SetReview(sid interface{}, review Review) errors.APIError {
+	override := GradeOverride{
+		Grade:  review.Grade,
+		Reason: "Graded on the rubric.",
+		By:     review.By,
+		At:     review.At,
+	}
+
+	_, err := s.col.UpdateOne(
+		s.ctx,
+		bson.M{"_id": sid, "manual": true},
+		bson.M{"$set": bson.M{"review": &review, "gradeOverride": &override}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	publishGraded(sid, false)
+	return nil
+}
+
+// JobSubmission the submission as court herald is sent it.
+func (s *MongoSubmission) JobSubmission() courtherald.Submission {
+	return courtherald.Submission{
+		ID:             s.ID,
+		UserID:         s.UserID,
+		FileID:         s.FileID,
+		AssignmentID:   s.AssignmentID,
+		AttemptNumber:  s.AttemptNumber,
+		SubmissionDate: s.SubmissionDate,
+		File:           s.File,
+	}
+}
+
+// dispatch asks the first of the graders that can be reached to start a
+// grading job for a submission, and returns the job's name and the grader.
+func (s *SubmissionInterface) dispatch(submission MongoSubmission, grading courtherald.Job, graders []string) (string, string, errors.APIError) {
+	req := courtherald.SubmitJobRequest{
+		Submission: submission.JobSubmission(),
+		Job:        grading,
+	}
+
+	var err errors.APIError = errors.ErrorNoGraderAvailable
+	for _, grader := range graders {
+		var job string
+		job, err = courtherald.SubmitJob(grader, req)
+		if err != errors.ErrorUnableToReachMicroService {
+			return job, grader, err
+		}
+	}
+
+	return "", "", err
+}
+
+// InProgressBefore returns the submissions that are still being graded and were
+// last sent to court herald before the given time.
+func (s *SubmissionInterface) InProgressBefore(before time.Time) ([]MongoSubmission, errors.APIError) {
+	submissions := make([]MongoSubmission, 0)
+	cur, err := s.col.Find(
+		s.ctx,
+		bson.M{
+			"inProgress": true,
+			"queued":     bson.M{"$ne": true},
+			"$or": bson.A{
+				bson.M{"dispatchedAt": bson.M{"$lt": primitive.DateTime(before.UnixNano() / 1000000)}},
+				bson.M{
+					"dispatchedAt":   bson.M{"$exists": false},
+					"submissionDate": bson.M{"$lt": primitive.DateTime(before.UnixNano() / 1000000)},
+				},
+			},
+		},
+		options.Find().SetSort(bson.M{"submissionDate": 1}),
+	)
+	if err != nil {
+		return submissions, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(s.ctx) {
+		var submission MongoSubmission
+		err = cur.Decode(&submission)
+		if err != nil {
+			return submissions, errors.ErrorInvalidBSON
+		}
+
+		submissions = append(submissions, submission)
+	}
+
+	return submissions, nil
+}
+
+// DryRun sends a submission that is not stored to court herald, to check an
+// assignment's tests against a reference solution.
+func (s *SubmissionInterface) DryRun(submission MongoSubmission, grading courtherald.Job, graders []string) (string, errors.APIError) {
+	submission.InProgress = true
+
+	job, _, err := s.dispatch(submission, grading, graders)
+	return job, err
+}
+
+// Requeue sends a submission that never finished grading back to court herald.
+func (s *SubmissionInterface) Requeue(submission MongoSubmission, grading courtherald.Job, graders []string) (string, errors.APIError) {
+	submission.Results = nil
+	submission.ErrorTesting = false
+	submission.InProgress = true
+
+	job, grader, err := s.dispatch(submission, grading, graders)
+	if err != nil {
+		return "", err
+	}
+
+	_, errs := s.col.UpdateOne(
+		s.ctx,
+		bson.M{"_id": submission.ID},
+		bson.M{
+			"$set": bson.M{
+				"job":          job,
+				"graderURL":    grader,
+				"dispatchedAt": primitive.DateTime(time.Now().UnixNano() / 1000000),
+				"results":      nil,
+				"errorTesting": false,
+				"inProgress":   true,
+			},
+			"$inc": bson.M{"requeues": 1},
+		},
+	)
+	if errs != nil {
+		return job, errors.ErrorDatabaseFailedUpdate
+	}
+
+	return job, nil
+}
+
+// Regrade sends a graded submission back to court herald to be graded
+// again, e.g. after its tests were fixed. Unlike requeues it starts over the
+// count of times grading was retried.
+func (s *SubmissionInterface) Regrade(submission MongoSubmission, grading courtherald.Job, graders []string) (string, errors.APIError) {
+	submission.Results = nil
+	submission.ErrorTesting = false
+	submission.InProgress = true
+
+	job, grader, err := s.dispatch(submission, grading, graders)
+	if err != nil {
+		return "", err
+	}
+
+	_, errs := s.col.UpdateOne(
+		s.ctx,
+		bson.M{"_id": submission.ID},
+		bson.M{"$set": bson.M{
+			"job":          job,
+			"graderURL":    grader,
+			"dispatchedAt": primitive.DateTime(time.Now().UnixNano() / 1000000),
+			"results":      nil,
+			"errorTesting": false,
+			"inProgress":   true,
+			"requeues":     0,
+		}},
+	)
+	if errs != nil {
+		return job, errors.ErrorDatabaseFailedUpdate
+	}
+
+	return job, nil
+}
+
+// GradedRuns the checksum and test outcomes of every submission of an
+// assignment court herald finished grading.
+func (s *SubmissionInterface) GradedRuns(aid interface{}) ([]MongoSubmission, errors.APIError) {
+	submissions := make([]MongoSubmission, 0)
+	cur, err := s.col.Find(
+		s.ctx,
+		bson.M{
+			"assignmentID": aid,
+			"inProgress":   false,
+			"errorTesting": false,
+			"cancelled":    bson.M{"$ne": true},
+			"manual":       bson.M{"$ne": true},
+		},
+		options.Find().SetProjection(bson.M{
+			"checksum":           1,
+			"results.name":       1,
+			"results.passed":     1,
+			"results.durationMS": 1,
+		}),
+	)
+	if err != nil {
+		return submissions, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(s.ctx) {
+		var submission MongoSubmission
+		if err = cur.Decode(&submission); err != nil {
+			return submissions, errors.ErrorInvalidBSON
+		}
+
+		submissions = append(submissions, submission)
+	}
+
+	return submissions, nil
+}
+
+// TestResults the submissions of the assignments with their test results,
+// oldest first, for exports of every test run. Withdrawn and cancelled
+// submissions are left out.
+func (s *SubmissionInterface) TestResults(aids []primitive.ObjectID) ([]MongoSubmission, errors.APIError) {
+	submissions := make([]MongoSubmission, 0)
+	cur, err := s.analytics.Find(
+		s.ctx,
+		bson.M{
+			"assignmentID": bson.M{"$in": aids},
+			"withdrawn":    bson.M{"$ne": true},
+			"cancelled":    bson.M{"$ne": true},
+		},
+		options.Find().SetSort(bson.M{"submissionDate": 1}).SetProjection(bson.M{
+			"assignmentID":       1,
+			"userID":             1,
+			"attemptNumber":      1,
+			"submissionDate":     1,
+			"results.name":       1,
+			"results.passed":     1,
+			"results.durationMS": 1,
+		}),
+	)
+	if err != nil {
+		return submissions, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(s.ctx) {
+		var submission MongoSubmission
+		if err = cur.Decode(&submission); err != nil {
+			return submissions, errors.ErrorInvalidBSON
+		}
+
+		submissions = append(submissions, submission)
+	}
+
+	return submissions, nil
+}
+
+// ResearchSubmissions the submissions of the assignments for research
+// exports, oldest first, with their results and grades but no output,
+// sources or other free text. Withdrawn submissions are left out.
+func (s *SubmissionInterface) ResearchSubmissions(aids []primitive.ObjectID) ([]MongoSubmission, errors.APIError) {
+	submissions := make([]MongoSubmission, 0)
+	cur, err := s.analytics.Find(
+		s.ctx,
+		bson.M{
+			"assignmentID": bson.M{"$in": aids},
+			"withdrawn":    bson.M{"$ne": true},
+		},
+		options.Find().SetSort(bson.M{"submissionDate": 1}).SetProjection(bson.M{
+			"assignmentID":        1,
+			"userID":              1,
+			"attemptNumber":       1,
+			"submissionDate":      1,
+			"inProgress":          1,
+			"errorTesting":        1,
+			"buildFailed":         1,
+			"manual":              1,
+			"refunded":            1,
+			"cancelled":           1,
+			"results.id":          1,
+			"results.passed":      1,
+			"results.durationMS":  1,
+			"gradeOverride.grade": 1,
+			"gradeOverride.by":    1,
+			"gradeOverride.at":    1,
+			"review.grade":        1,
+			"review.by":           1,
+			"review.at":           1,
+		}),
+	)
+	if err != nil {
+		return submissions, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(s.ctx) {
+		var submission MongoSubmission
+		if err = cur.Decode(&submission); err != nil {
+			return submissions, errors.ErrorInvalidBSON
+		}
+
+		submissions = append(submissions, submission)
+	}
+
+	return submissions, nil
+}
+
+// AwaitingReview the submissions to manually graded assignments staff have
+// yet to grade on the rubric, each student's latest of an assignment, oldest
+// first.
+func (s *SubmissionInterface) AwaitingReview(aids []primitive.ObjectID) ([]MongoSubmission, errors.APIError) {
+	submissions := make([]MongoSubmission, 0)
+	query := []interface{}{
+		bson.M{"$match": bson.M{
+			"assignmentID": bson.M{"$in": aids},
+			"manual":       true,
+			"withdrawn":    bson.M{"$ne": true},
+			"refunded":     bson.M{"$ne": true},
+		}},
+		bson.M{"$sort": bson.M{"submissionDate": -1}},
+		bson.M{"$group": bson.M{
+			"_id":        bson.M{"assignmentID": "$assignmentID", "userID": "$userID"},
+			"submission": bson.M{"$first": "$$ROOT"},
+		}},
+		bson.M{"$replaceRoot": bson.M{"newRoot": "$submission"}},
+		bson.M{"$match": bson.M{"review": bson.M{"$exists": false}}},
+		bson.M{"$sort": bson.M{"submissionDate": 1}},
+	}
+
+	cur, err := s.col.Aggregate(s.ctx, query, options.Aggregate())
+	if err != nil {
+		return submissions, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(s.ctx) {
+		var submission MongoSubmission
+		if err = cur.Decode(&submission); err != nil {
+			return submissions, errors.ErrorInvalidBSON
+		}
+
+		submissions = append(submissions, submission)
+	}
+
+	return submissions, nil
+}
+
+// Held returns the submissions held back for court herald, oldest first.
+func (s *SubmissionInterface) Held(limit int64) ([]MongoSubmission, errors.APIError) {
+	submissions := make([]MongoSubmission, 0)
+	opts := options.Find().SetSort(bson.M{"submissionDate": 1})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cur, err := s.col.Find(s.ctx, bson.M{"queued": true}, opts)
+	if err != nil {
+		return submissions, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(s.ctx) {
+		var submission MongoSubmission
+		err = cur.Decode(&submission)
+		if err != nil {
+			return submissions, errors.ErrorInvalidBSON
+		}
+
+		submissions = append(submissions, submission)
+	}
+
+	return submissions, nil
+}
+
+// HeldBefore counts the submissions held back that were submitted before the
+// given time, the ones a new submission would wait behind.
+func (s *SubmissionInterface) HeldBefore(before primitive.DateTime) (int, errors.APIError) {
+	count, err := s.col.CountDocuments(
+		s.ctx,
+		bson.M{"queued": true, "submissionDate": bson.M{"$lt": before}},
+		options.Count(),
+	)
+	if err != nil {
+		return 0, errors.ErrorDatabaseFailedQuery
+	}
+
+	return int(count), nil
+}
+
+// Release sends a held submission to court herald.
+func (s *SubmissionInterface) Release(submission MongoSubmission, grading courtherald.Job, graders []string) (string, errors.APIError) {
+	submission.Queued = false
+
+	job, grader, err := s.dispatch(submission, grading, graders)
+	if err != nil {
+		return "", err
+	}
+
+	_, errs := s.col.UpdateOne(
+		s.ctx,
+		bson.M{"_id": submission.ID},
+		bson.M{
+			"$set": bson.M{
+				"job":          job,
+				"graderURL":    grader,
+				"dispatchedAt": primitive.DateTime(time.Now().UnixNano() / 1000000),
+			},
+			"$unset": bson.M{"queued": ""},
+		},
+	)
+	if errs != nil {
+		return job, errors.ErrorDatabaseFailedUpdate
+	}
+
+	return job, nil
+}
+
+// JobDetails fetches what court herald knows about the grading job of a
+// submission: its status, queue position and the tail of the container logs.
+// A job court herald has no record of is reported with the missing status.
+func (s *SubmissionInterface) JobDetails(submission *MongoSubmission, tail int) (*courtherald.JobStatusResponse, errors.APIError) {
+	return courtherald.JobStatus(submission.GraderURL, submission.ID, tail)
+}
+
+// JobStatus asks court herald how the grading job of a submission is doing.
+func (s *SubmissionInterface) JobStatus(submission *MongoSubmission) (string, errors.APIError) {
+	details, err := s.JobDetails(submission, 0)
+	if err != nil {
+		return "", err
+	}
+
+	return details.Status, nil
+}
+
+// Cancel stops grading a submission still in grading, refunded gives the
+// attempt back. Reports court herald sends for it afterwards are ignored.
+func (s *SubmissionInterface) Cancel(sid interface{}, refunded bool) errors.APIError {
+	res, err := s.col.UpdateOne(
+		s.ctx,
+		bson.M{"_id": sid, "inProgress": true},
+		bson.M{
+			"$set": bson.M{
+				"inProgress":  false,
+				"cancelled":   true,
+				"cancelledAt": utils.TimeToDateTime(time.Now()),
+				"refunded":    refunded,
+				"errorReason": "Grading was cancelled.",
+			},
+			"$unset": bson.M{"queued": ""},
+		},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.ErrorSubmissionNotGrading
 	}
-	defer resp.Body.Close()
 
-	body, _ := ioutil.ReadAll(resp.Body)
+	changedGrading(sid)
+	return nil
+}
 
-	var data map[string]interface{}
-	json.Unmarshal(body, &data)
+// MarkGradingFailed gives up on grading a submission, leaving a reason the
+// student can see.
+func (s *SubmissionInterface) MarkGradingFailed(sid interface{}, reason string) errors.APIError {
+	_, err := s.col.UpdateOne(
+		s.ctx,
+		bson.M{"_id": sid, "inProgress": true},
+		bson.M{
+			"$set": bson.M{
+				"errorTesting": true,
+				"inProgress":   false,
+				"errorReason":  reason,
+			},
+			"$unset": bson.M{"queued": ""},
+		},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
 
-	return data["job"].(string), nil
+	publishGraded(sid, true)
+	return nil
 }
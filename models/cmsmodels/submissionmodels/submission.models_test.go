@@ -0,0 +1,165 @@
+package submissionmodels
+
+import (
+	"strings"
+	"testing"
+
+	"backend/courtherald"
+	"backend/utils"
+)
+
+func gradedSubmission() *MongoSubmission {
+	return &MongoSubmission{
+		Results: []WorkerResult{
+			{ID: 0, Name: "greets", StudentFacing: true, Passed: true, Stderr: "warning"},
+			{ID: 1, Name: "secret edge case", TestCMD: "python hello.py --edge", Passed: false},
+			{ID: 2, Name: "secret large input", Passed: true},
+		},
+		Source: &Source{IP: "10.0.0.1"},
+	}
+}
+
+// hiddenLeak names the hidden tests that show up in what the view is sent.
+func hiddenLeak(results []WorkerResult) []string {
+	leaks := make([]string, 0)
+	for _, result := range results {
+		if !result.StudentFacing || strings.HasPrefix(result.Name, "secret") {
+			leaks = append(leaks, result.Name)
+		}
+	}
+
+	return leaks
+}
+
+// the submission and diff endpoints get submissions through Get, which
+// redacts them with RedactFor.
+func TestRedactForStudent(t *testing.T) {
+	sub := gradedSubmission()
+	sub.RedactFor(utils.ViewStudent, false)
+
+	if leaks := hiddenLeak(sub.Results); len(leaks) > 0 {
+		t.Errorf("hidden tests were shown to the student: %v", leaks)
+	}
+	if len(sub.Results) != 1 || sub.Results[0].Stderr != "" {
+		t.Errorf("the student was shown %+v", sub.Results)
+	}
+	if sub.Hidden != nil {
+		t.Errorf("the student was told how many hidden tests passed: %+v", sub.Hidden)
+	}
+}
+
+func TestRedactForStudentSummarizesHidden(t *testing.T) {
+	sub := gradedSubmission()
+	sub.RedactFor(utils.ViewStudent, true)
+
+	if leaks := hiddenLeak(sub.Results); len(leaks) > 0 {
+		t.Errorf("hidden tests were shown to the student: %v", leaks)
+	}
+	if sub.Hidden == nil || *sub.Hidden != (ResultCount{Passed: 1, Total: 2}) {
+		t.Errorf("the student was told %+v of the hidden tests, not 1/2 passed", sub.Hidden)
+	}
+}
+
+func TestRedactForStaff(t *testing.T) {
+	sub := gradedSubmission()
+	sub.RedactFor(utils.ViewStaff, true)
+
+	if len(sub.Results) != 3 || sub.Results[0].Stderr == "" || sub.Hidden != nil {
+		t.Errorf("staff were not shown every result: %+v", sub)
+	}
+}
+
+func TestFilterForShare(t *testing.T) {
+	sub := gradedSubmission()
+	sub.FilterForShare()
+
+	if leaks := hiddenLeak(sub.Results); len(leaks) > 0 {
+		t.Errorf("hidden tests were shown through a share link: %v", leaks)
+	}
+	if sub.Source != nil {
+		t.Errorf("a share link was shown where the submission was sent from")
+	}
+}
+
+// GetUsersRecentSubmissions redacts the dashboard's recent submissions the
+// same way.
+func TestRecentSubmissionRedaction(t *testing.T) {
+	for _, summarize := range []bool{false, true} {
+		recent := RecentSubmission{
+			Results:    gradedSubmission().Results,
+			Assignment: RecentAssignment{ShowHiddenSummary: summarize},
+		}
+		recent.Hidden = HiddenCount(recent.Results, utils.ViewStudent, recent.Assignment.ShowHiddenSummary)
+		utils.Redact(&recent, utils.ViewStudent)
+
+		if leaks := hiddenLeak(recent.Results); len(leaks) > 0 {
+			t.Errorf("hidden tests were shown on the dashboard: %v", leaks)
+		}
+		if (recent.Hidden != nil) != summarize {
+			t.Errorf("with summaries %v the dashboard showed hidden counts %+v", summarize, recent.Hidden)
+		}
+	}
+}
+
+func TestRedactProgress(t *testing.T) {
+	event := courtherald.ProgressEvent{
+		Stage:     courtherald.StageTesting,
+		Test:      "secret edge case",
+		Completed: 1,
+		Total:     3,
+		Message:   "running secret edge case",
+	}
+
+	if got := RedactProgress(event, utils.ViewStaff, false); got != event {
+		t.Errorf("staff were shown %+v", got)
+	}
+
+	got := RedactProgress(event, utils.ViewStudent, false)
+	if got.Test != "" || got.Message != "" || got.Total != 0 || got.Completed != 0 {
+		t.Errorf("the student was shown %+v", got)
+	}
+
+	got = RedactProgress(event, utils.ViewStudent, true)
+	if got.Test != "" || got.Message != "" || got.Total != 3 {
+		t.Errorf("with summaries the student was shown %+v", got)
+	}
+}
+
+func TestCountResults(t *testing.T) {
+	shown, hidden := CountResults(gradedSubmission().Results)
+	if shown != (ResultCount{Passed: 1, Total: 1}) || hidden != (ResultCount{Passed: 1, Total: 2}) {
+		t.Errorf("counted %+v shown and %+v hidden", shown, hidden)
+	}
+}
+
+func TestStability(t *testing.T) {
+	run := func(checksum string, passed bool, durationMS float64) MongoSubmission {
+		return MongoSubmission{Checksum: checksum, Results: []WorkerResult{
+			{Name: "steady", Passed: true, DurationMS: 100},
+			{Name: "racy", Passed: passed, DurationMS: durationMS},
+		}}
+	}
+	runs := []MongoSubmission{
+		run("a", true, 10), run("a", false, 200), run("b", true, 10), run("c", false, 10), run("", true, 400),
+	}
+
+	stability := Stability(runs, 5, 0.5)
+	if len(stability) != 2 || stability[0].Name != "racy" {
+		t.Fatalf("the flagged test should come first, got %+v", stability)
+	}
+
+	racy, steady := stability[0], stability[1]
+	if !racy.Flaky || racy.Intermittent != 1 || racy.IdenticalGroups != 1 || racy.Failures != 2 {
+		t.Errorf("racy should be flaky from the identical pair a, got %+v", racy)
+	}
+	if !racy.TimingSensitive || racy.TimedRuns != 5 {
+		t.Errorf("racy durations vary widely, got %+v", racy)
+	}
+	if steady.Flaky || steady.TimingSensitive || steady.Variation != 0 || steady.MeanMS != 100 {
+		t.Errorf("steady was flagged, got %+v", steady)
+	}
+
+	if stability = Stability(runs, 6, 0.5); stability[0].TimingSensitive {
+		t.Errorf("tests with fewer timed runs than minRuns are not timing sensitive")
+	}
+}
@@ -0,0 +1,238 @@
+package webhookmodels
+
+import (
+	"context"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	"backend/config"
+	"backend/errors"
+	"backend/utils"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+// Events a webhook can subscribe to.
+const (
+	EventSubmissionGraded    = "submission.graded"
+	EventAssignmentPublished = "assignment.published"
+	EventEnrollmentAdded     = "enrollment.added"
+)
+
+// Delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// deliveryLogLength deliveries kept on a webhook, older ones are dropped.
+const deliveryLogLength = 50
+
+// Events every event a webhook can subscribe to.
+var Events = []string{EventSubmissionGraded, EventAssignmentPublished, EventEnrollmentAdded}
+
+type (
+	// Delivery an event sent, or being sent, to a webhook.
+	Delivery struct {
+		ID             primitive.ObjectID `bson:"_id" json:"id"`
+		Event          string             `bson:"event" json:"event"`
+		Payload        string             `bson:"payload" json:"payload"`
+		Status         string             `bson:"status" json:"status"`
+		Attempts       int                `bson:"attempts" json:"attempts"`
+		LastStatusCode int                `bson:"lastStatusCode,omitempty" json:"lastStatusCode,omitempty"`
+		LastError      string             `bson:"lastError,omitempty" json:"lastError,omitempty"`
+		CreatedAt      primitive.DateTime `bson:"createdAt" json:"createdAt"`
+		NextAttemptAt  primitive.DateTime `bson:"nextAttemptAt,omitempty" json:"nextAttemptAt,omitempty"`
+		DeliveredAt    primitive.DateTime `bson:"deliveredAt,omitempty" json:"deliveredAt,omitempty"`
+	}
+
+	// MongoWebhook a URL events are posted to, signed with its secret. Webhooks
+	// without a course are registered by admins and get every course's events.
+	MongoWebhook struct {
+		ID         primitive.ObjectID  `bson:"_id" json:"id"`
+		CourseID   *primitive.ObjectID `bson:"courseID,omitempty" json:"courseID,omitempty"`
+		URL        string              `bson:"url" json:"url"`
+		Events     []string            `bson:"events" json:"events"`
+		Secret     string              `bson:"secret" json:"-"`
+		CreatedBy  primitive.ObjectID  `bson:"createdBy" json:"createdBy"`
+		CreatedAt  primitive.DateTime  `bson:"createdAt" json:"createdAt"`
+		Deliveries []Delivery          `bson:"deliveries" json:"-"`
+	}
+
+	WebhookInterface struct {
+		ctx context.Context
+		col *mongo.Collection
+	}
+)
+
+func New() *WebhookInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+	col := tyrgin.GetMongoCollection("webhooks", db)
+
+	return &WebhookInterface{
+		context.Background(),
+		col,
+	}
+}
+
+// ValidEvent reports whether event is one webhooks can subscribe to.
+func ValidEvent(event string) bool {
+	for _, valid := range Events {
+		if event == valid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// courseFilter matches a course's webhooks, or the global ones when cid is nil.
+func courseFilter(cid *primitive.ObjectID) bson.M {
+	if cid == nil {
+		return bson.M{"courseID": bson.M{"$exists": false}}
+	}
+
+	return bson.M{"courseID": *cid}
+}
+
+// Create registers a webhook with a new secret.
+func (w *WebhookInterface) Create(cid *primitive.ObjectID, uid primitive.ObjectID, url string, events []string) (*MongoWebhook, errors.APIError) {
+	secret, errs := utils.RandomCode(32)
+	if errs != nil {
+		return nil, errors.ErrorDatabaseFailedCreate
+	}
+
+	hook := MongoWebhook{
+		ID:         primitive.NewObjectID(),
+		CourseID:   cid,
+		URL:        url,
+		Events:     events,
+		Secret:     secret,
+		CreatedBy:  uid,
+		CreatedAt:  utils.TimeToDateTime(time.Now()),
+		Deliveries: make([]Delivery, 0),
+	}
+
+	_, err := w.col.InsertOne(w.ctx, &hook, options.InsertOne())
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedCreate
+	}
+
+	return &hook, nil
+}
+
+func (w *WebhookInterface) Get(whid interface{}, cid *primitive.ObjectID) (*MongoWebhook, errors.APIError) {
+	filter := courseFilter(cid)
+	filter["_id"] = whid
+
+	var hook *MongoWebhook
+	res := w.col.FindOne(w.ctx, filter, options.FindOne())
+	res.Decode(&hook)
+
+	if hook == nil {
+		return nil, errors.ErrorResourceNotFound
+	}
+
+	return hook, nil
+}
+
+func (w *WebhookInterface) find(filter bson.M) ([]MongoWebhook, errors.APIError) {
+	hooks := make([]MongoWebhook, 0)
+	cur, err := w.col.Find(w.ctx, filter, options.Find().SetSort(bson.M{"createdAt": 1}))
+	if err != nil {
+		return hooks, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(w.ctx) {
+		var hook MongoWebhook
+		err = cur.Decode(&hook)
+		if err != nil {
+			return hooks, errors.ErrorInvalidBSON
+		}
+
+		hooks = append(hooks, hook)
+	}
+
+	return hooks, nil
+}
+
+// Find lists a course's webhooks, or the global ones when cid is nil.
+func (w *WebhookInterface) Find(cid *primitive.ObjectID) ([]MongoWebhook, errors.APIError) {
+	return w.find(courseFilter(cid))
+}
+
+// Subscribed lists the webhooks an event of a course is sent to, the course's
+// own and the global ones.
+func (w *WebhookInterface) Subscribed(cid primitive.ObjectID, event string) ([]MongoWebhook, errors.APIError) {
+	return w.find(bson.M{
+		"events": event,
+		"$or": bson.A{
+			bson.M{"courseID": cid},
+			bson.M{"courseID": bson.M{"$exists": false}},
+		},
+	})
+}
+
+// Pending lists the webhooks with a delivery due to be retried.
+func (w *WebhookInterface) Pending(now time.Time) ([]MongoWebhook, errors.APIError) {
+	return w.find(bson.M{
+		"deliveries": bson.M{"$elemMatch": bson.M{
+			"status":        DeliveryPending,
+			"nextAttemptAt": bson.M{"$lte": utils.TimeToDateTime(now)},
+		}},
+	})
+}
+
+// AddDelivery logs a delivery, dropping the oldest beyond the log's length.
+func (w *WebhookInterface) AddDelivery(whid interface{}, delivery Delivery) errors.APIError {
+	_, err := w.col.UpdateOne(
+		w.ctx,
+		bson.M{"_id": whid},
+		bson.M{"$push": bson.M{"deliveries": bson.M{
+			"$each":  bson.A{delivery},
+			"$slice": -deliveryLogLength,
+		}}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// UpdateDelivery records the outcome of an attempt.
+func (w *WebhookInterface) UpdateDelivery(whid interface{}, delivery Delivery) errors.APIError {
+	_, err := w.col.UpdateOne(
+		w.ctx,
+		bson.M{"_id": whid, "deliveries._id": delivery.ID},
+		bson.M{"$set": bson.M{"deliveries.$": delivery}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+func (w *WebhookInterface) Delete(whid interface{}) errors.APIError {
+	_, err := w.col.DeleteOne(w.ctx, bson.M{"_id": whid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+func (w *WebhookInterface) DeleteByCourseID(cid interface{}) errors.APIError {
+	_, err := w.col.DeleteMany(w.ctx, bson.M{"courseID": cid}, options.Delete())
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package flagmodels
+
+import (
+	"context"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	"backend/config"
+	"backend/errors"
+	"backend/utils"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+type (
+	// CourseOverride turns a flag on or off for one course.
+	CourseOverride struct {
+		CourseID primitive.ObjectID `bson:"courseID" json:"courseID"`
+		Enabled  bool               `bson:"enabled" json:"enabled"`
+	}
+
+	// MongoFlag the overrides of a feature flag set by admins. A flag without
+	// overrides follows its configured default.
+	MongoFlag struct {
+		Name string `bson:"_id" json:"name"`
+		// Enabled overrides the default for every course, nil keeps it.
+		Enabled   *bool              `bson:"enabled,omitempty" json:"enabled,omitempty"`
+		Courses   []CourseOverride   `bson:"courses" json:"courses"`
+		UpdatedBy primitive.ObjectID `bson:"updatedBy" json:"updatedBy"`
+		UpdatedAt primitive.DateTime `bson:"updatedAt" json:"updatedAt"`
+	}
+
+	FlagInterface struct {
+		ctx context.Context
+		col *mongo.Collection
+	}
+)
+
+func New() *FlagInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+	col := tyrgin.GetMongoCollection("featureflags", db)
+
+	return &FlagInterface{
+		context.Background(),
+		col,
+	}
+}
+
+// Course the override of a course, nil when it has none.
+func (f *MongoFlag) Course(cid primitive.ObjectID) *bool {
+	for _, override := range f.Courses {
+		if override.CourseID == cid {
+			return &override.Enabled
+		}
+	}
+
+	return nil
+}
+
+func (f *FlagInterface) Find() ([]MongoFlag, errors.APIError) {
+	flags := make([]MongoFlag, 0)
+	cur, err := f.col.Find(f.ctx, bson.M{}, options.Find())
+	if err != nil {
+		return flags, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(f.ctx) {
+		var flag MongoFlag
+		err = cur.Decode(&flag)
+		if err != nil {
+			return flags, errors.ErrorInvalidBSON
+		}
+
+		flags = append(flags, flag)
+	}
+
+	return flags, nil
+}
+
+func updated(uid interface{}) bson.M {
+	return bson.M{
+		"updatedBy": uid,
+		"updatedAt": utils.TimeToDateTime(time.Now()),
+	}
+}
+
+// SetGlobal overrides a flag for every course, nil goes back to the default.
+func (f *FlagInterface) SetGlobal(name string, enabled *bool, uid interface{}) errors.APIError {
+	set := updated(uid)
+	update := bson.M{
+		"$set":         set,
+		"$setOnInsert": bson.M{"courses": bson.A{}},
+	}
+	if enabled != nil {
+		set["enabled"] = *enabled
+	} else {
+		update["$unset"] = bson.M{"enabled": ""}
+	}
+
+	_, err := f.col.UpdateOne(f.ctx, bson.M{"_id": name}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// SetCourse overrides a flag for a course, nil removes the override.
+func (f *FlagInterface) SetCourse(name string, cid primitive.ObjectID, enabled *bool, uid interface{}) errors.APIError {
+	_, err := f.col.UpdateOne(
+		f.ctx,
+		bson.M{"_id": name},
+		bson.M{
+			"$set":  updated(uid),
+			"$pull": bson.M{"courses": bson.M{"courseID": cid}},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	if enabled == nil {
+		return nil
+	}
+
+	_, err = f.col.UpdateOne(
+		f.ctx,
+		bson.M{"_id": name},
+		bson.M{"$push": bson.M{"courses": CourseOverride{cid, *enabled}}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// DeleteCourseOverrides removes a course's overrides from every flag.
+func (f *FlagInterface) DeleteCourseOverrides(cid interface{}) errors.APIError {
+	_, err := f.col.UpdateMany(
+		f.ctx,
+		bson.M{"courses.courseID": cid},
+		bson.M{"$pull": bson.M{"courses": bson.M{"courseID": cid}}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package gradermodels
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	"backend/config"
+	"backend/errors"
+	"backend/utils"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+type (
+	// MongoGrader a court herald cluster submissions can be graded on, besides
+	// the one at COURT_HERALD_URL. Its rules pick the assignments it grades:
+	// those of its languages and departments, any when empty, whose
+	// requirements are among its labels, e.g. gpu or arm64.
+	MongoGrader struct {
+		ID          primitive.ObjectID `bson:"_id" json:"id"`
+		Name        string             `bson:"name" json:"name"`
+		URL         string             `bson:"url" json:"url"`
+		Labels      []string           `bson:"labels" json:"labels"`
+		Languages   []string           `bson:"languages" json:"languages"`
+		Departments []string           `bson:"departments" json:"departments"`
+		// Priority orders the graders an assignment matches, the lowest is
+		// tried first and the others on failover.
+		Priority  int                `bson:"priority" json:"priority"`
+		Enabled   bool               `bson:"enabled" json:"enabled"`
+		Healthy   bool               `bson:"healthy" json:"healthy"`
+		CheckedAt primitive.DateTime `bson:"checkedAt,omitempty" json:"checkedAt,omitempty"`
+		LastError string             `bson:"lastError,omitempty" json:"lastError,omitempty"`
+		CreatedAt primitive.DateTime `bson:"createdAt" json:"createdAt"`
+	}
+
+	GraderInterface struct {
+		ctx context.Context
+		col *mongo.Collection
+	}
+)
+
+func New() *GraderInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+	col := tyrgin.GetMongoCollection("graders", db)
+
+	return &GraderInterface{
+		context.Background(),
+		col,
+	}
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Serves whether the grader's rules match an assignment.
+func (g *MongoGrader) Serves(language, department string, requirements []string) bool {
+	if len(g.Languages) > 0 && !containsFold(g.Languages, language) {
+		return false
+	}
+
+	if len(g.Departments) > 0 && !containsFold(g.Departments, department) {
+		return false
+	}
+
+	for _, requirement := range requirements {
+		if !containsFold(g.Labels, requirement) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Route the base URLs of the enabled graders matching an assignment, in the
+// order they are tried: healthy ones first, then by priority. Unhealthy ones
+// are still tried last as their health may be out of date.
+func Route(graders []MongoGrader, language, department string, requirements []string) []string {
+	matching := make([]MongoGrader, 0)
+	for _, grader := range graders {
+		if grader.Enabled && grader.Serves(language, department, requirements) {
+			matching = append(matching, grader)
+		}
+	}
+
+	sort.SliceStable(matching, func(i, j int) bool {
+		if matching[i].Healthy != matching[j].Healthy {
+			return matching[i].Healthy
+		}
+		return matching[i].Priority < matching[j].Priority
+	})
+
+	urls := make([]string, len(matching))
+	for index, grader := range matching {
+		urls[index] = grader.URL
+	}
+
+	return urls
+}
+
+func (g *GraderInterface) Create(grader MongoGrader) (*MongoGrader, errors.APIError) {
+	grader.ID = primitive.NewObjectID()
+	grader.CreatedAt = utils.TimeToDateTime(time.Now())
+	grader.Healthy = true
+
+	_, err := g.col.InsertOne(g.ctx, &grader, options.InsertOne())
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedCreate
+	}
+
+	return &grader, nil
+}
+
+func (g *GraderInterface) Get(grid interface{}) (*MongoGrader, errors.APIError) {
+	var grader *MongoGrader
+	res := g.col.FindOne(g.ctx, bson.M{"_id": grid}, options.FindOne())
+	res.Decode(&grader)
+
+	if grader == nil {
+		return nil, errors.ErrorResourceNotFound
+	}
+
+	return grader, nil
+}
+
+// Find lists the registered graders by priority.
+func (g *GraderInterface) Find() ([]MongoGrader, errors.APIError) {
+	graders := make([]MongoGrader, 0)
+	cur, err := g.col.Find(g.ctx, bson.M{}, options.Find().SetSort(bson.M{"priority": 1}))
+	if err != nil {
+		return graders, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(g.ctx) {
+		var grader MongoGrader
+		err = cur.Decode(&grader)
+		if err != nil {
+			return graders, errors.ErrorInvalidBSON
+		}
+
+		graders = append(graders, grader)
+	}
+
+	return graders, nil
+}
+
+// Update replaces a grader's settings, keeping its health.
+func (g *GraderInterface) Update(grader MongoGrader) errors.APIError {
+	_, err := g.col.UpdateOne(
+		g.ctx,
+		bson.M{"_id": grader.ID},
+		bson.M{"$set": bson.M{
+			"name":        grader.Name,
+			"url":         grader.URL,
+			"labels":      grader.Labels,
+			"languages":   grader.Languages,
+			"departments": grader.Departments,
+			"priority":    grader.Priority,
+			"enabled":     grader.Enabled,
+		}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// SetHealth records the result of a grader's health check, an empty problem
+// when it is healthy.
+func (g *GraderInterface) SetHealth(grid interface{}, problem string) errors.APIError {
+	_, err := g.col.UpdateOne(
+		g.ctx,
+		bson.M{"_id": grid},
+		bson.M{"$set": bson.M{
+			"healthy":   problem == "",
+			"lastError": problem,
+			"checkedAt": utils.TimeToDateTime(time.Now()),
+		}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+func (g *GraderInterface) Delete(grid interface{}) errors.APIError {
+	res, err := g.col.DeleteOne(g.ctx, bson.M{"_id": grid})
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	if res.DeletedCount == 0 {
+		return errors.ErrorResourceNotFound
+	}
+
+	return nil
+}
@@ -3,12 +3,11 @@ package gridfsmodels
 import (
 	"bytes"
 	"io"
-	"os"
-	"strconv"
 
 	"github.com/mongodb/mongo-go-driver/bson/primitive"
 	"github.com/mongodb/mongo-go-driver/mongo"
 
+	"backend/config"
 	"backend/errors"
 
 	"github.com/stevens-tyr/tyr-gin"
@@ -22,9 +21,8 @@ type (
 )
 
 func New() *GridFSInterface {
-	db, _ := tyrgin.GetMongoDB(os.Getenv("GRIDFS_DB_NAME"))
-	bucketSize, _ := strconv.Atoi(os.Getenv("UPLOAD_SIZE"))
-	bucket, _ := tyrgin.GetGridFSBucket(db, "assignments", int32(bucketSize))
+	db, _ := tyrgin.GetMongoDB(config.C.GridFSDBName)
+	bucket, _ := tyrgin.GetGridFSBucket(db, "assignments", int32(config.C.UploadSize))
 
 	return &GridFSInterface{
 		bucket,
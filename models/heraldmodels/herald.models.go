@@ -0,0 +1,120 @@
+package heraldmodels
+
+import (
+	"context"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	"backend/config"
+	"backend/errors"
+	"backend/utils"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+// statusID the _id of the only status document, court herald reports for the
+// whole grading cluster.
+const statusID = "courtherald"
+
+type (
+	// MongoHeraldStatus the capacity and load court herald last reported.
+	MongoHeraldStatus struct {
+		ID string `bson:"_id" json:"-"`
+		// Running jobs grading now, Capacity how many can run at once and
+		// Queued how many are waiting in court herald for a slot.
+		Running  int `bson:"running" json:"running"`
+		Capacity int `bson:"capacity" json:"capacity"`
+		Queued   int `bson:"queued" json:"queued"`
+		// AverageJobSeconds how long a grading job recently took.
+		AverageJobSeconds float64            `bson:"averageJobSeconds" json:"averageJobSeconds"`
+		ReportedAt        primitive.DateTime `bson:"reportedAt" json:"reportedAt"`
+	}
+
+	HeraldInterface struct {
+		ctx context.Context
+		col *mongo.Collection
+	}
+)
+
+func New() *HeraldInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+	col := tyrgin.GetMongoCollection("heraldstatus", db)
+
+	return &HeraldInterface{
+		context.Background(),
+		col,
+	}
+}
+
+// Fresh whether the status was reported within maxAge. A stale status says
+// nothing about the load, court herald may be down or not reporting.
+func (h *MongoHeraldStatus) Fresh(maxAge time.Duration) bool {
+	return time.Since(utils.DateTimeToTime(h.ReportedAt)) <= maxAge
+}
+
+// Overloaded whether the jobs waiting for a slot reached the threshold, zero
+// disables the check.
+func (h *MongoHeraldStatus) Overloaded(threshold int) bool {
+	return threshold > 0 && h.Queued >= threshold
+}
+
+// Free how many more jobs court herald can be sent while keeping the jobs
+// waiting for a slot under the threshold.
+func (h *MongoHeraldStatus) Free(threshold int) int {
+	free := 0
+	if h.Running < h.Capacity {
+		free += h.Capacity - h.Running
+	}
+	if h.Queued < threshold {
+		free += threshold - h.Queued
+	}
+
+	return free
+}
+
+// EstimatedWait how long a submission is likely to wait before it starts
+// grading when ahead submissions are held back before it.
+func (h *MongoHeraldStatus) EstimatedWait(ahead int) time.Duration {
+	capacity := h.Capacity
+	if capacity <= 0 {
+		capacity = 1
+	}
+
+	rounds := float64(h.Queued+ahead)/float64(capacity) + 1
+	return time.Duration(rounds * h.AverageJobSeconds * float64(time.Second))
+}
+
+// Report replaces the status with what court herald just reported.
+func (h *HeraldInterface) Report(status MongoHeraldStatus) errors.APIError {
+	status.ID = statusID
+	status.ReportedAt = utils.TimeToDateTime(time.Now())
+
+	_, err := h.col.ReplaceOne(
+		h.ctx,
+		bson.M{"_id": statusID},
+		&status,
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// Get the status court herald last reported.
+func (h *HeraldInterface) Get() (*MongoHeraldStatus, errors.APIError) {
+	var status *MongoHeraldStatus
+	res := h.col.FindOne(h.ctx, bson.M{"_id": statusID}, options.FindOne())
+	res.Decode(&status)
+
+	if status == nil {
+		return nil, errors.ErrorResourceNotFound
+	}
+
+	return status, nil
+}
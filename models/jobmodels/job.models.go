@@ -0,0 +1,315 @@
+package jobmodels
+
+import (
+	"context"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	"backend/config"
+	"backend/errors"
+	"backend/utils"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+// Job statuses.
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Statuses every status a job can have.
+var Statuses = []string{StatusQueued, StatusRunning, StatusSucceeded, StatusFailed}
+
+type (
+	// MongoJob a unit of background work. Jobs are kept after they finish as
+	// their history. Recurring jobs enqueue their next run when they finish.
+	MongoJob struct {
+		ID          primitive.ObjectID `bson:"_id" json:"id"`
+		Name        string             `bson:"name" json:"name"`
+		Payload     string             `bson:"payload,omitempty" json:"payload,omitempty"`
+		Status      string             `bson:"status" json:"status"`
+		Attempts    int                `bson:"attempts" json:"attempts"`
+		MaxAttempts int                `bson:"maxAttempts" json:"maxAttempts"`
+		// Interval seconds between the runs of a recurring job, 0 for one off
+		// jobs.
+		Interval    int64               `bson:"interval" json:"interval"`
+		RunAt       primitive.DateTime  `bson:"runAt" json:"runAt"`
+		CreatedAt   primitive.DateTime  `bson:"createdAt" json:"createdAt"`
+		StartedAt   *primitive.DateTime `bson:"startedAt,omitempty" json:"startedAt,omitempty"`
+		FinishedAt  *primitive.DateTime `bson:"finishedAt,omitempty" json:"finishedAt,omitempty"`
+		Worker      string              `bson:"worker,omitempty" json:"worker,omitempty"`
+		LockedUntil primitive.DateTime  `bson:"lockedUntil,omitempty" json:"-"`
+		LastError   string              `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	}
+
+	JobInterface struct {
+		ctx context.Context
+		col *mongo.Collection
+	}
+)
+
+func New() *JobInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+	col := tyrgin.GetMongoCollection("jobs", db)
+
+	return &JobInterface{
+		context.Background(),
+		col,
+	}
+}
+
+// ValidStatus reports whether status is one a job can have.
+func ValidStatus(status string) bool {
+	for _, valid := range Statuses {
+		if status == valid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Recurring reports whether the job runs on a schedule.
+func (j *MongoJob) Recurring() bool {
+	return j.Interval > 0
+}
+
+// Enqueue adds a job to run once runAt has passed.
+func (j *JobInterface) Enqueue(name, payload string, runAt time.Time, maxAttempts int, interval time.Duration) (*MongoJob, errors.APIError) {
+	job := MongoJob{
+		ID:          primitive.NewObjectID(),
+		Name:        name,
+		Payload:     payload,
+		Status:      StatusQueued,
+		MaxAttempts: maxAttempts,
+		Interval:    int64(interval / time.Second),
+		RunAt:       utils.TimeToDateTime(runAt),
+		CreatedAt:   utils.TimeToDateTime(time.Now()),
+	}
+
+	_, err := j.col.InsertOne(j.ctx, &job, options.InsertOne())
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedCreate
+	}
+
+	return &job, nil
+}
+
+// EnsureScheduled enqueues the first run of a recurring job unless a run of it
+// is already queued or running.
+func (j *JobInterface) EnsureScheduled(name string, interval time.Duration, maxAttempts int) errors.APIError {
+	now := utils.TimeToDateTime(time.Now())
+	_, err := j.col.UpdateOne(
+		j.ctx,
+		bson.M{
+			"name":     name,
+			"interval": bson.M{"$gt": 0},
+			"status":   bson.M{"$in": bson.A{StatusQueued, StatusRunning}},
+		},
+		bson.M{"$setOnInsert": bson.M{
+			"_id":         primitive.NewObjectID(),
+			"status":      StatusQueued,
+			"attempts":    0,
+			"maxAttempts": maxAttempts,
+			"interval":    int64(interval / time.Second),
+			"runAt":       now,
+			"createdAt":   now,
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// Claim locks the next job that is due for a worker until lockedUntil. Running
+// jobs whose lock has expired, as their worker died, are claimed again. It
+// returns nil when no job is due.
+func (j *JobInterface) Claim(worker string, names []string, now, lockedUntil time.Time) (*MongoJob, errors.APIError) {
+	at := utils.TimeToDateTime(now)
+
+	var job *MongoJob
+	err := j.col.FindOneAndUpdate(
+		j.ctx,
+		bson.M{
+			"name": bson.M{"$in": names},
+			"$or": bson.A{
+				bson.M{"status": StatusQueued, "runAt": bson.M{"$lte": at}},
+				bson.M{"status": StatusRunning, "lockedUntil": bson.M{"$lte": at}},
+			},
+		},
+		bson.M{
+			"$set": bson.M{
+				"status":      StatusRunning,
+				"worker":      worker,
+				"startedAt":   at,
+				"lockedUntil": utils.TimeToDateTime(lockedUntil),
+			},
+			"$inc": bson.M{"attempts": 1},
+		},
+		options.FindOneAndUpdate().
+			SetSort(bson.M{"runAt": 1}).
+			SetReturnDocument(options.After),
+	).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedUpdate
+	}
+
+	return job, nil
+}
+
+// ExtendLock keeps a running job locked by its worker.
+func (j *JobInterface) ExtendLock(jid primitive.ObjectID, worker string, lockedUntil time.Time) errors.APIError {
+	_, err := j.col.UpdateOne(
+		j.ctx,
+		bson.M{"_id": jid, "worker": worker, "status": StatusRunning},
+		bson.M{"$set": bson.M{"lockedUntil": utils.TimeToDateTime(lockedUntil)}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+func (j *JobInterface) finish(jid primitive.ObjectID, set bson.M) errors.APIError {
+	_, err := j.col.UpdateOne(
+		j.ctx,
+		bson.M{"_id": jid},
+		bson.M{
+			"$set":   set,
+			"$unset": bson.M{"lockedUntil": ""},
+		},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// Succeed marks a job done.
+func (j *JobInterface) Succeed(jid primitive.ObjectID, at time.Time) errors.APIError {
+	return j.finish(jid, bson.M{
+		"status":     StatusSucceeded,
+		"finishedAt": utils.TimeToDateTime(at),
+	})
+}
+
+// Retry queues a failed attempt of a job to run again at runAt.
+func (j *JobInterface) Retry(jid primitive.ObjectID, runAt time.Time, reason string) errors.APIError {
+	return j.finish(jid, bson.M{
+		"status":    StatusQueued,
+		"runAt":     utils.TimeToDateTime(runAt),
+		"lastError": reason,
+	})
+}
+
+// Fail marks a job failed for good once it is out of attempts.
+func (j *JobInterface) Fail(jid primitive.ObjectID, at time.Time, reason string) errors.APIError {
+	return j.finish(jid, bson.M{
+		"status":     StatusFailed,
+		"finishedAt": utils.TimeToDateTime(at),
+		"lastError":  reason,
+	})
+}
+
+// Requeue runs a failed job again with a fresh set of attempts.
+func (j *JobInterface) Requeue(jid interface{}) (*MongoJob, errors.APIError) {
+	var job *MongoJob
+	err := j.col.FindOneAndUpdate(
+		j.ctx,
+		bson.M{"_id": jid, "status": StatusFailed},
+		bson.M{
+			"$set": bson.M{
+				"status":   StatusQueued,
+				"attempts": 0,
+				"runAt":    utils.TimeToDateTime(time.Now()),
+			},
+			"$unset": bson.M{"finishedAt": "", "worker": ""},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.ErrorResourceNotFound
+	}
+	if err != nil {
+		return nil, errors.ErrorDatabaseFailedUpdate
+	}
+
+	return job, nil
+}
+
+func (j *JobInterface) Get(jid interface{}) (*MongoJob, errors.APIError) {
+	var job *MongoJob
+	res := j.col.FindOne(j.ctx, bson.M{"_id": jid}, options.FindOne())
+	res.Decode(&job)
+
+	if job == nil {
+		return nil, errors.ErrorResourceNotFound
+	}
+
+	return job, nil
+}
+
+// Find lists the latest jobs, optionally only those of a name or status.
+func (j *JobInterface) Find(name, status string, limit int64) ([]MongoJob, errors.APIError) {
+	filter := bson.M{}
+	if name != "" {
+		filter["name"] = name
+	}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	jobs := make([]MongoJob, 0)
+	cur, err := j.col.Find(
+		j.ctx,
+		filter,
+		options.Find().SetSort(bson.M{"createdAt": -1}).SetLimit(limit),
+	)
+	if err != nil {
+		return jobs, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(j.ctx) {
+		var job MongoJob
+		err = cur.Decode(&job)
+		if err != nil {
+			return jobs, errors.ErrorInvalidBSON
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// DeleteFinishedBefore removes the history of jobs that finished before a time.
+func (j *JobInterface) DeleteFinishedBefore(before time.Time) errors.APIError {
+	_, err := j.col.DeleteMany(
+		j.ctx,
+		bson.M{
+			"status":     bson.M{"$in": bson.A{StatusSucceeded, StatusFailed}},
+			"finishedAt": bson.M{"$lt": utils.TimeToDateTime(before)},
+		},
+		options.Delete(),
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
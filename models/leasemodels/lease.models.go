@@ -0,0 +1,106 @@
+package leasemodels
+
+import (
+	"context"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	"backend/config"
+	"backend/errors"
+	"backend/utils"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+type (
+	// MongoLease something only one replica of the backend may do at a time,
+	// held by Holder until ExpiresAt unless renewed. A replica that dies
+	// without releasing its leases loses them once they expire.
+	MongoLease struct {
+		Name       string             `bson:"_id" json:"name"`
+		Holder     string             `bson:"holder" json:"holder"`
+		AcquiredAt primitive.DateTime `bson:"acquiredAt" json:"acquiredAt"`
+		ExpiresAt  primitive.DateTime `bson:"expiresAt" json:"expiresAt"`
+	}
+
+	LeaseInterface struct {
+		ctx context.Context
+		col *mongo.Collection
+	}
+)
+
+func New() *LeaseInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+	col := tyrgin.GetMongoCollection("leases", db)
+
+	return &LeaseInterface{
+		context.Background(),
+		col,
+	}
+}
+
+// Acquire takes a lease for holder until ttl from now, or renews it when
+// holder has it already. It reports false while another holder has it.
+func (l *LeaseInterface) Acquire(name, holder string, ttl time.Duration) (bool, errors.APIError) {
+	now := time.Now()
+
+	var lease MongoLease
+	err := l.col.FindOneAndUpdate(
+		l.ctx,
+		bson.M{
+			"_id": name,
+			"$or": bson.A{
+				bson.M{"holder": holder},
+				bson.M{"expiresAt": bson.M{"$lte": utils.TimeToDateTime(now)}},
+			},
+		},
+		bson.M{
+			"$set": bson.M{
+				"holder":    holder,
+				"expiresAt": utils.TimeToDateTime(now.Add(ttl)),
+			},
+			"$setOnInsert": bson.M{"acquiredAt": utils.TimeToDateTime(now)},
+		},
+		options.FindOneAndUpdate().
+			SetUpsert(true).
+			SetReturnDocument(options.After),
+	).Decode(&lease)
+	if err == nil {
+		return lease.Holder == holder, nil
+	}
+
+	// the upsert fails on the _id of a lease someone else holds
+	current, errs := l.Get(name)
+	if errs != nil {
+		return false, errors.ErrorDatabaseFailedUpdate
+	}
+
+	return current.Holder == holder, nil
+}
+
+// Release gives up a lease holder has, so another can take it at once.
+func (l *LeaseInterface) Release(name, holder string) errors.APIError {
+	_, err := l.col.DeleteOne(l.ctx, bson.M{"_id": name, "holder": holder})
+	if err != nil {
+		return errors.ErrorDatabaseFailedDelete
+	}
+
+	return nil
+}
+
+// Get a lease by its name.
+func (l *LeaseInterface) Get(name string) (*MongoLease, errors.APIError) {
+	var lease *MongoLease
+	res := l.col.FindOne(l.ctx, bson.M{"_id": name}, options.FindOne())
+	res.Decode(&lease)
+
+	if lease == nil {
+		return nil, errors.ErrorResourceNotFound
+	}
+
+	return lease, nil
+}
@@ -1,36 +1,169 @@
 package models
 
 import (
+	anm "backend/models/cmsmodels/announcementmodels"
 	am "backend/models/cmsmodels/assignmentmodels"
+	atm "backend/models/cmsmodels/attendancemodels"
+	cdm "backend/models/cmsmodels/codemodels"
 	cm "backend/models/cmsmodels/coursemodels"
+	dm "backend/models/cmsmodels/discussionmodels"
+	dsm "backend/models/cmsmodels/disputemodels"
+	dfm "backend/models/cmsmodels/draftmodels"
+	drm "backend/models/cmsmodels/dryrunmodels"
+	exm "backend/models/cmsmodels/extensionmodels"
+	gtm "backend/models/cmsmodels/gradingmodels"
+	imm "backend/models/cmsmodels/impersonationmodels"
+	mdm "backend/models/cmsmodels/modulemodels"
+	nm "backend/models/cmsmodels/notificationmodels"
+	qm "backend/models/cmsmodels/quizmodels"
+	regm "backend/models/cmsmodels/regrademodels"
 	sm "backend/models/cmsmodels/submissionmodels"
+	whm "backend/models/cmsmodels/webhookmodels"
+	fm "backend/models/flagmodels"
+	grm "backend/models/gradermodels"
 	gfs "backend/models/gridfsmodels"
+	hm "backend/models/heraldmodels"
+	jm "backend/models/jobmodels"
+	lsm "backend/models/leasemodels"
+	om "backend/models/orgmodels"
+	tm "backend/models/tokenmodels"
 	um "backend/models/usermodels"
 )
 
 type (
-	Assignment am.MongoAssignment
-	Course     cm.MongoCourse
-	User       um.MongoUser
-	Submission sm.MongoSubmission
+	Announcement  anm.MongoAnnouncement
+	Assignment    am.MongoAssignment
+	Attendance    atm.MongoSession
+	Course        cm.MongoCourse
+	Code          cdm.MongoSubmissionCode
+	Dispute       dsm.MongoDispute
+	Draft         dfm.MongoDraft
+	DryRun        drm.MongoDryRun
+	Extension     exm.MongoExtensionRequest
+	GradingTask   gtm.MongoGradingTask
+	Grader        grm.MongoGrader
+	HeraldStatus  hm.MongoHeraldStatus
+	Impersonation imm.MongoImpersonationEntry
+	Module        mdm.MongoModule
+	Notification  nm.MongoNotification
+	Thread        dm.MongoThread
+	User          um.MongoUser
+	Submission    sm.MongoSubmission
+	APIToken      tm.MongoAPIToken
+	FeatureFlag   fm.MongoFlag
+	Job           jm.MongoJob
+	Lease         lsm.MongoLease
+	Organization  om.MongoOrganization
+	Question      qm.MongoQuestion
+	QuizAttempt   qm.MongoAttempt
+	Regrade       regm.MongoRegrade
+	Webhook       whm.MongoWebhook
 )
 
+func NewMongoAnnouncementInterface() *anm.AnnouncementInterface {
+	return anm.New()
+}
+
 func NewMongoAssignmentInterface() *am.AssignmentInterface {
 	return am.New()
 }
 
+func NewMongoAttendanceInterface() *atm.AttendanceInterface {
+	return atm.New()
+}
+
 func NewMongoCourseInterface() *cm.CourseInterface {
 	return cm.New()
 }
 
+func NewMongoDiscussionInterface() *dm.DiscussionInterface {
+	return dm.New()
+}
+
+func NewMongoDisputeInterface() *dsm.DisputeInterface {
+	return dsm.New()
+}
+
+func NewMongoDraftInterface() *dfm.DraftInterface {
+	return dfm.New()
+}
+
+func NewMongoDryRunInterface() *drm.DryRunInterface {
+	return drm.New()
+}
+
+func NewMongoExtensionInterface() *exm.ExtensionInterface {
+	return exm.New()
+}
+
+func NewMongoFeatureFlagInterface() *fm.FlagInterface {
+	return fm.New()
+}
+
+func NewMongoGraderInterface() *grm.GraderInterface {
+	return grm.New()
+}
+
 func NewGridFSInterface() *gfs.GridFSInterface {
 	return gfs.New()
 }
 
+func NewMongoHeraldInterface() *hm.HeraldInterface {
+	return hm.New()
+}
+
+func NewMongoJobInterface() *jm.JobInterface {
+	return jm.New()
+}
+
+func NewMongoModuleInterface() *mdm.ModuleInterface {
+	return mdm.New()
+}
+
+func NewMongoLeaseInterface() *lsm.LeaseInterface {
+	return lsm.New()
+}
+
+func NewMongoNotificationInterface() *nm.NotificationInterface {
+	return nm.New()
+}
+
 func NewMongoUserInterface() *um.UserInterface {
 	return um.New()
 }
 
+func NewMongoCodeInterface() *cdm.CodeInterface {
+	return cdm.New()
+}
+
+func NewMongoGradingInterface() *gtm.GradingInterface {
+	return gtm.New()
+}
+
+func NewMongoImpersonationInterface() *imm.ImpersonationInterface {
+	return imm.New()
+}
+
+func NewMongoOrganizationInterface() *om.OrganizationInterface {
+	return om.New()
+}
+
+func NewMongoQuizInterface() *qm.QuizInterface {
+	return qm.New()
+}
+
+func NewMongoRegradeInterface() *regm.RegradeInterface {
+	return regm.New()
+}
+
 func NewMongoSubmissionInterface() *sm.SubmissionInterface {
 	return sm.New()
 }
+
+func NewMongoTokenInterface() *tm.TokenInterface {
+	return tm.New()
+}
+
+func NewMongoWebhookInterface() *whm.WebhookInterface {
+	return whm.New()
+}
@@ -0,0 +1,251 @@
+package orgmodels
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	"backend/config"
+	"backend/errors"
+	"backend/forms"
+	"backend/utils"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+var (
+	slugPattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,62}$`)
+	colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+)
+
+type (
+	// Branding how the frontend presents an organization.
+	Branding struct {
+		DisplayName  string `bson:"displayName" json:"displayName"`
+		LogoURL      string `bson:"logoURL,omitempty" json:"logoURL,omitempty"`
+		PrimaryColor string `bson:"primaryColor,omitempty" json:"primaryColor,omitempty"`
+	}
+
+	// AuthSettings who can register into an organization.
+	AuthSettings struct {
+		// EmailDomains restricts registration to emails of these domains. Users
+		// registering with one of them without naming an organization join it.
+		EmailDomains      []string `bson:"emailDomains" json:"emailDomains"`
+		AllowRegistration bool     `bson:"allowRegistration" json:"allowRegistration"`
+	}
+
+	// MongoOrganization a department or school sharing the deployment. Its
+	// users and courses are kept apart from every other organization's.
+	MongoOrganization struct {
+		ID        primitive.ObjectID `bson:"_id" json:"id"`
+		Name      string             `bson:"name" json:"name"`
+		Slug      string             `bson:"slug" json:"slug"`
+		Branding  Branding           `bson:"branding" json:"branding"`
+		Auth      AuthSettings       `bson:"auth" json:"auth"`
+		CreatedAt primitive.DateTime `bson:"createdAt" json:"createdAt"`
+	}
+
+	OrganizationInterface struct {
+		ctx context.Context
+		col *mongo.Collection
+	}
+)
+
+func New() *OrganizationInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+	col := tyrgin.GetMongoCollection("organizations", db)
+
+	return &OrganizationInterface{
+		context.Background(),
+		col,
+	}
+}
+
+// emailDomain the lowercased domain of an email.
+func emailDomain(email string) string {
+	return strings.ToLower(email[strings.LastIndex(email, "@")+1:])
+}
+
+// AllowsEmail reports whether an email may register into the organization.
+func (o *MongoOrganization) AllowsEmail(email string) bool {
+	if len(o.Auth.EmailDomains) == 0 {
+		return true
+	}
+
+	domain := emailDomain(email)
+	for _, allowed := range o.Auth.EmailDomains {
+		if domain == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newBranding checks the branding of a form, the display name defaults to the
+// organization's name.
+func newBranding(name, displayName, logoURL, primaryColor string) (Branding, errors.APIError) {
+	if primaryColor != "" && !colorPattern.MatchString(primaryColor) {
+		return Branding{}, errors.ErrorInvalidOrganization
+	}
+	if logoURL != "" && !strings.HasPrefix(logoURL, "https://") {
+		return Branding{}, errors.ErrorInvalidOrganization
+	}
+
+	if displayName == "" {
+		displayName = name
+	}
+
+	return Branding{displayName, logoURL, primaryColor}, nil
+}
+
+func newAuthSettings(emailDomains []string, allowRegistration bool) AuthSettings {
+	settings := AuthSettings{EmailDomains: make([]string, 0), AllowRegistration: allowRegistration}
+	for _, domain := range emailDomains {
+		domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+		if domain != "" {
+			settings.EmailDomains = append(settings.EmailDomains, domain)
+		}
+	}
+
+	return settings
+}
+
+func (o *OrganizationInterface) Create(form forms.CreateOrganizationForm) (*MongoOrganization, errors.APIError) {
+	if strings.TrimSpace(form.Name) == "" || !slugPattern.MatchString(form.Slug) {
+		return nil, errors.ErrorInvalidOrganization
+	}
+
+	existing, err := o.GetBySlug(form.Slug)
+	if err != nil && err != errors.ErrorResourceNotFound {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.ErrorCannotCreateDuplicateData
+	}
+
+	branding := Branding{DisplayName: form.Name}
+	if form.Branding != nil {
+		branding, err = newBranding(form.Name, form.Branding.DisplayName, form.Branding.LogoURL, form.Branding.PrimaryColor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// organizations are open to registration unless their settings say otherwise
+	settings := newAuthSettings(nil, true)
+	if form.Auth != nil {
+		settings = newAuthSettings(form.Auth.EmailDomains, form.Auth.AllowRegistration)
+	}
+
+	org := MongoOrganization{
+		ID:        primitive.NewObjectID(),
+		Name:      form.Name,
+		Slug:      form.Slug,
+		Branding:  branding,
+		Auth:      settings,
+		CreatedAt: utils.TimeToDateTime(time.Now()),
+	}
+
+	_, errs := o.col.InsertOne(o.ctx, &org, options.InsertOne())
+	if errs != nil {
+		return nil, errors.ErrorDatabaseFailedCreate
+	}
+
+	return &org, nil
+}
+
+// Update changes an organization's name, branding or auth settings, whichever
+// the form has.
+func (o *OrganizationInterface) Update(oid interface{}, form forms.UpdateOrganizationForm) (*MongoOrganization, errors.APIError) {
+	org, err := o.Get(oid)
+	if err != nil {
+		return nil, err
+	}
+
+	if form.Name != nil {
+		if strings.TrimSpace(*form.Name) == "" {
+			return nil, errors.ErrorInvalidOrganization
+		}
+		org.Name = *form.Name
+	}
+	if form.Branding != nil {
+		org.Branding, err = newBranding(org.Name, form.Branding.DisplayName, form.Branding.LogoURL, form.Branding.PrimaryColor)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if form.Auth != nil {
+		org.Auth = newAuthSettings(form.Auth.EmailDomains, form.Auth.AllowRegistration)
+	}
+
+	_, errs := o.col.UpdateOne(
+		o.ctx,
+		bson.M{"_id": org.ID},
+		bson.M{"$set": bson.M{
+			"name":     org.Name,
+			"branding": org.Branding,
+			"auth":     org.Auth,
+		}},
+	)
+	if errs != nil {
+		return nil, errors.ErrorDatabaseFailedUpdate
+	}
+
+	return org, nil
+}
+
+func (o *OrganizationInterface) findOne(filter bson.M) (*MongoOrganization, errors.APIError) {
+	var org *MongoOrganization
+	res := o.col.FindOne(o.ctx, filter, options.FindOne())
+	res.Decode(&org)
+
+	if org == nil {
+		return nil, errors.ErrorResourceNotFound
+	}
+
+	return org, nil
+}
+
+func (o *OrganizationInterface) Get(oid interface{}) (*MongoOrganization, errors.APIError) {
+	return o.findOne(bson.M{"_id": oid})
+}
+
+func (o *OrganizationInterface) GetBySlug(slug string) (*MongoOrganization, errors.APIError) {
+	return o.findOne(bson.M{"slug": slug})
+}
+
+// ForEmail the organization an email registers into when it does not name one,
+// the one open for registration that lists the email's domain.
+func (o *OrganizationInterface) ForEmail(email string) (*MongoOrganization, errors.APIError) {
+	return o.findOne(bson.M{
+		"auth.emailDomains":      emailDomain(email),
+		"auth.allowRegistration": true,
+	})
+}
+
+func (o *OrganizationInterface) Find() ([]MongoOrganization, errors.APIError) {
+	orgs := make([]MongoOrganization, 0)
+	cur, err := o.col.Find(o.ctx, bson.M{}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return orgs, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(o.ctx) {
+		var org MongoOrganization
+		err = cur.Decode(&org)
+		if err != nil {
+			return orgs, errors.ErrorInvalidBSON
+		}
+
+		orgs = append(orgs, org)
+	}
+
+	return orgs, nil
+}
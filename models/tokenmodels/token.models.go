@@ -0,0 +1,273 @@
+package tokenmodels
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+
+	"backend/config"
+	"backend/errors"
+	"backend/utils"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+const (
+	// ScopeRead allows every GET request.
+	ScopeRead = "read"
+	// ScopeSubmit allows starting and submitting assignments.
+	ScopeSubmit = "submit"
+	// ScopeWrite allows every request a logged in user can make, other than
+	// managing tokens and the account itself.
+	ScopeWrite = "write"
+	// ScopeCalendar only allows the calendar feeds. It is the one scope a
+	// token can be sent with in the url, as calendar apps cannot set headers.
+	ScopeCalendar = "calendar"
+
+	// tokenPrefix marks a string as an API token.
+	tokenPrefix = "tyr_"
+)
+
+// Scopes every scope a token can be given.
+var Scopes = []string{ScopeRead, ScopeSubmit, ScopeWrite, ScopeCalendar}
+
+type (
+	// MongoAPIToken a personal access token a user can authenticate scripts
+	// and tools with. Only a hash of the token is stored.
+	MongoAPIToken struct {
+		ID     primitive.ObjectID `bson:"_id" json:"id"`
+		UserID primitive.ObjectID `bson:"userID" json:"userID"`
+		Name   string             `bson:"name" json:"name"`
+		// Prefix the start of the token so users can tell their tokens apart.
+		Prefix     string              `bson:"prefix" json:"prefix"`
+		Hash       string              `bson:"hash" json:"-"`
+		Scopes     []string            `bson:"scopes" json:"scopes"`
+		RateLimit  int                 `bson:"rateLimit" json:"rateLimit"`
+		CreatedAt  primitive.DateTime  `bson:"createdAt" json:"createdAt"`
+		ExpiresAt  *primitive.DateTime `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+		LastUsedAt *primitive.DateTime `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+		Revoked    bool                `bson:"revoked" json:"revoked"`
+	}
+
+	TokenInterface struct {
+		ctx context.Context
+		col *mongo.Collection
+	}
+)
+
+// DefaultRateLimit requests per minute a token is allowed. Configured with
+// API_TOKEN_RATE_LIMIT.
+func DefaultRateLimit() int {
+	return config.C.APITokenRateLimit
+}
+
+// ValidScope reports whether scope is one tokens can be given.
+func ValidScope(scope string) bool {
+	for _, valid := range Scopes {
+		if scope == valid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsToken reports whether a credential looks like an API token.
+func IsToken(token string) bool {
+	return len(token) > len(tokenPrefix) && token[:len(tokenPrefix)] == tokenPrefix
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func New() *TokenInterface {
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
+	col := tyrgin.GetMongoCollection("tokens", db)
+
+	return &TokenInterface{
+		context.Background(),
+		col,
+	}
+}
+
+// Create generates a token for a user. The token itself is only returned here.
+func (t *TokenInterface) Create(uid interface{}, name string, scopes []string, rateLimit int, expiresAt *primitive.DateTime) (string, *MongoAPIToken, errors.APIError) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, errors.ErrorGenerateTokenFailure
+	}
+	token := tokenPrefix + hex.EncodeToString(secret)
+
+	apiToken := MongoAPIToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    uid.(primitive.ObjectID),
+		Name:      name,
+		Prefix:    token[:len(tokenPrefix)+8],
+		Hash:      hashToken(token),
+		Scopes:    scopes,
+		RateLimit: rateLimit,
+		CreatedAt: utils.TimeToDateTime(time.Now()),
+		ExpiresAt: expiresAt,
+		Revoked:   false,
+	}
+
+	_, err := t.col.InsertOne(t.ctx, &apiToken, options.InsertOne())
+	if err != nil {
+		return "", nil, errors.ErrorDatabaseFailedCreate
+	}
+
+	return token, &apiToken, nil
+}
+
+// GetByUser returns a user's tokens, newest first.
+func (t *TokenInterface) GetByUser(uid interface{}) ([]MongoAPIToken, errors.APIError) {
+	tokens := make([]MongoAPIToken, 0)
+	cur, err := t.col.Find(
+		t.ctx,
+		bson.M{"userID": uid},
+		options.Find().SetSort(bson.M{"createdAt": -1}),
+	)
+	if err != nil {
+		return tokens, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(t.ctx) {
+		var token MongoAPIToken
+		err = cur.Decode(&token)
+		if err != nil {
+			return tokens, errors.ErrorInvalidBSON
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+// Authenticate finds the active token matching a presented token.
+func (t *TokenInterface) Authenticate(token string) (*MongoAPIToken, errors.APIError) {
+	var apiToken MongoAPIToken
+	res := t.col.FindOne(
+		t.ctx,
+		bson.M{
+			"hash":    hashToken(token),
+			"revoked": false,
+			"$or": bson.A{
+				bson.M{"expiresAt": bson.M{"$exists": false}},
+				bson.M{"expiresAt": bson.M{"$gt": utils.TimeToDateTime(time.Now())}},
+			},
+		},
+		options.FindOne(),
+	)
+
+	err := res.Decode(&apiToken)
+	if err != nil {
+		return nil, errors.ErrorInvalidAPIToken
+	}
+
+	return &apiToken, nil
+}
+
+// Touch records that a token was used, at most once a minute.
+func (t *TokenInterface) Touch(tkid primitive.ObjectID) errors.APIError {
+	now := time.Now()
+	_, err := t.col.UpdateOne(
+		t.ctx,
+		bson.M{
+			"_id": tkid,
+			"$or": bson.A{
+				bson.M{"lastUsedAt": bson.M{"$exists": false}},
+				bson.M{"lastUsedAt": bson.M{"$lt": utils.TimeToDateTime(now.Add(-time.Minute))}},
+			},
+		},
+		bson.M{"$set": bson.M{"lastUsedAt": utils.TimeToDateTime(now)}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// CountRequest counts a request made with a token in the minute of now and
+// returns how many it has made in that minute.
+func (t *TokenInterface) CountRequest(tkid primitive.ObjectID, now time.Time) (int, errors.APIError) {
+	window := utils.TimeToDateTime(now.Truncate(time.Minute))
+
+	var counted struct {
+		Count int `bson:"rateCount"`
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		err := t.col.FindOneAndUpdate(
+			t.ctx,
+			bson.M{"_id": tkid, "rateWindow": window},
+			bson.M{"$inc": bson.M{"rateCount": 1}},
+			options.FindOneAndUpdate().
+				SetProjection(bson.M{"rateCount": 1}).
+				SetReturnDocument(options.After),
+		).Decode(&counted)
+		if err == nil {
+			return counted.Count, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return 0, errors.ErrorDatabaseFailedUpdate
+		}
+
+		// the first request of a minute starts the count over, unless
+		// another instance just did
+		res, err := t.col.UpdateOne(
+			t.ctx,
+			bson.M{"_id": tkid, "rateWindow": bson.M{"$ne": window}},
+			bson.M{"$set": bson.M{"rateWindow": window, "rateCount": 1}},
+		)
+		if err != nil {
+			return 0, errors.ErrorDatabaseFailedUpdate
+		}
+		if res.MatchedCount == 1 {
+			return 1, nil
+		}
+	}
+
+	return 0, errors.ErrorDatabaseFailedUpdate
+}
+
+// Revoke disables one of a user's tokens.
+func (t *TokenInterface) Revoke(uid, tkid interface{}) errors.APIError {
+	res, err := t.col.UpdateOne(
+		t.ctx,
+		bson.M{"_id": tkid, "userID": uid},
+		bson.M{"$set": bson.M{"revoked": true}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.ErrorResourceNotFound
+	}
+
+	return nil
+}
+
+// RevokeByUser disables every token of a user.
+func (t *TokenInterface) RevokeByUser(uid interface{}) errors.APIError {
+	_, err := t.col.UpdateMany(
+		t.ctx,
+		bson.M{"userID": uid},
+		bson.M{"$set": bson.M{"revoked": true}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
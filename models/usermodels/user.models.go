@@ -2,7 +2,8 @@ package usermodels
 
 import (
 	"context"
-	"os"
+	"fmt"
+	"time"
 
 	"github.com/mongodb/mongo-go-driver/bson"
 	"github.com/mongodb/mongo-go-driver/bson/primitive"
@@ -10,17 +11,28 @@ import (
 	"github.com/mongodb/mongo-go-driver/mongo/options"
 	bcrypt "golang.org/x/crypto/bcrypt"
 
+	"backend/config"
 	"backend/errors"
+	"backend/events"
 	"backend/forms"
+	"backend/utils"
 
 	tyrgin "github.com/stevens-tyr/tyr-gin"
 )
 
+// How often a user is emailed a digest of upcoming deadlines.
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
 type (
 	// EnrolledCourse struct keeps track of a user's course and enrollment type
 	EnrolledCourse struct {
 		CourseID       primitive.ObjectID `bson:"courseID" json:"courseID" binding:"required"`
 		EnrollmentType string             `bson:"enrollmentType" json:"enrollmentType" binding:"required"`
+		Withdrawn      bool               `bson:"withdrawn" json:"withdrawn"`
 	}
 
 	// User a default User struct to represent a User in Tyr.
@@ -32,6 +44,22 @@ type (
 		First           string             `bson:"firstName" json:"firstName" binding:"required"`
 		Last            string             `bson:"lastName" json:"lastName" binding:"required"`
 		EnrolledCourses []EnrolledCourse   `bson:"enrolledCourses" json:"enrolledCourses" binding:"required"`
+		// Timezone an IANA timezone name used to present dates to the user.
+		Timezone string `bson:"timezone" json:"timezone"`
+		// DeletionScheduledFor when set, the account is anonymized once this date passes.
+		DeletionScheduledFor primitive.DateTime `bson:"deletionScheduledFor,omitempty" json:"deletionScheduledFor,omitempty"`
+		Deleted              bool               `bson:"deleted" json:"-"`
+		// Digest how often the user is emailed upcoming deadlines.
+		Digest string `bson:"digest" json:"digest"`
+		// DigestToken unsubscribes the user from digests without logging in,
+		// set when the first digest is sent.
+		DigestToken  string             `bson:"digestToken,omitempty" json:"-"`
+		LastDigestAt primitive.DateTime `bson:"lastDigestAt,omitempty" json:"-"`
+		// OrganizationID the organization the user belongs to, none for users
+		// of a deployment without organizations.
+		OrganizationID *primitive.ObjectID `bson:"organizationID,omitempty" json:"organizationID,omitempty"`
+		// OrgAdmin whether the user administers their organization.
+		OrgAdmin bool `bson:"orgAdmin" json:"orgAdmin"`
 	}
 
 	// A struct to represent a bunch of User functions.
@@ -45,14 +73,16 @@ func (m *MongoUser) CoursesAsMap() map[string]string {
 	courses := make(map[string]string)
 
 	for _, course := range m.EnrolledCourses {
-		courses[course.CourseID.Hex()] = course.EnrollmentType
+		if !course.Withdrawn {
+			courses[course.CourseID.Hex()] = course.EnrollmentType
+		}
 	}
 
 	return courses
 }
 
 func New() *UserInterface {
-	db, _ := tyrgin.GetMongoDB(os.Getenv("DB_NAME"))
+	db, _ := tyrgin.GetMongoDB(config.C.DBName)
 	col := tyrgin.GetMongoCollection("users", db)
 
 	return &UserInterface{
@@ -61,17 +91,21 @@ func New() *UserInterface {
 	}
 }
 
+// FindOne the user with an email, compared lowercased, then in its
+// normalized form.
 func (u *UserInterface) FindOne(email string) (*MongoUser, errors.APIError) {
-	var user *MongoUser
+	for _, form := range utils.EmailForms(email) {
+		var user *MongoUser
 
-	res := u.col.FindOne(u.ctx, bson.M{"email": email}, options.FindOne())
-	res.Decode(&user)
+		res := u.col.FindOne(u.ctx, bson.M{"email": form}, options.FindOne())
+		res.Decode(&user)
 
-	if user == nil {
-		return nil, errors.ErrorResourceNotFound
+		if user != nil {
+			return user, nil
+		}
 	}
 
-	return user, nil
+	return nil, errors.ErrorResourceNotFound
 }
 
 func (u *UserInterface) FindOneById(uid interface{}) (*MongoUser, errors.APIError) {
@@ -118,7 +152,8 @@ func (u *UserInterface) Login(form forms.UserLoginForm) (interface{}, errors.API
 	return user, nil
 }
 
-func (u *UserInterface) Register(form forms.UserRegisterForm) errors.APIError {
+// Register creates a user in an organization, nil for none.
+func (u *UserInterface) Register(form forms.UserRegisterForm, oid *primitive.ObjectID) errors.APIError {
 	user, err := u.FindOne(form.Email)
 	if err != nil && err != errors.ErrorResourceNotFound {
 		return err
@@ -132,18 +167,26 @@ func (u *UserInterface) Register(form forms.UserRegisterForm) errors.APIError {
 		return errors.ErrorIncorrectCredentials
 	}
 
+	if form.Timezone != "" && !utils.ValidTimezone(form.Timezone) {
+		return errors.ErrorInvalidTimezone
+	}
+
 	hash, errs := bcrypt.GenerateFromPassword([]byte(form.Password), bcrypt.DefaultCost)
 	if errs != nil {
 		return errors.ErrorHashFailure
 	}
 
 	user = &MongoUser{
-		Email:           form.Email,
+		ID:              primitive.NewObjectID(),
+		Email:           utils.NormalizeEmail(form.Email),
 		Admin:           false,
 		Password:        hash,
 		First:           form.First,
 		Last:            form.Last,
 		EnrolledCourses: make([]EnrolledCourse, 0),
+		Timezone:        form.Timezone,
+		Digest:          DigestWeekly,
+		OrganizationID:  oid,
 	}
 
 	_, errs = u.col.InsertOne(u.ctx, user, options.InsertOne())
@@ -151,6 +194,8 @@ func (u *UserInterface) Register(form forms.UserRegisterForm) errors.APIError {
 		return errors.ErrorDatabaseFailedCreate
 	}
 
+	events.Publish(events.UserRegisteredEvent, events.UserRegistered{UserID: user.ID, Email: user.Email})
+
 	return nil
 }
 
@@ -158,6 +203,7 @@ func (u *UserInterface) GetCourses(uid interface{}, courseLevels map[string]inte
 	query := []interface{}{
 		bson.M{"$match": bson.M{"_id": uid}},
 		bson.M{"$unwind": "$enrolledCourses"},
+		bson.M{"$match": bson.M{"enrolledCourses.withdrawn": bson.M{"$ne": true}}},
 		bson.M{
 			"$lookup": bson.M{
 				"from":         "courses",
@@ -224,6 +270,113 @@ func (u *UserInterface) CourseExists(cid, uid interface{}) (bool, errors.APIErro
 	return false, nil
 }
 
+// SetCourseWithdrawn marks a user's enrollment in a course as withdrawn (or
+// reinstates it) without removing it.
+func (u *UserInterface) SetCourseWithdrawn(cid, uid interface{}, withdrawn bool) errors.APIError {
+	res, err := u.col.UpdateOne(
+		u.ctx,
+		bson.M{"_id": uid, "enrolledCourses.courseID": cid},
+		bson.M{"$set": bson.M{"enrolledCourses.$.withdrawn": withdrawn}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.ErrorResourceNotFound
+	}
+
+	return nil
+}
+
+func (u *UserInterface) SetAdmin(uid interface{}, admin bool) errors.APIError {
+	_, err := u.col.UpdateOne(
+		u.ctx,
+		bson.M{"_id": uid},
+		bson.M{"$set": bson.M{"admin": admin}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// SetOrganization moves a user into an organization, as one of its admins or
+// not.
+func (u *UserInterface) SetOrganization(uid interface{}, oid primitive.ObjectID, admin bool) errors.APIError {
+	res, err := u.col.UpdateOne(
+		u.ctx,
+		bson.M{"_id": uid},
+		bson.M{"$set": bson.M{"organizationID": oid, "orgAdmin": admin}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.ErrorResourceNotFound
+	}
+
+	return nil
+}
+
+// FindByOrganization lists an organization's users.
+func (u *UserInterface) FindByOrganization(oid interface{}) ([]MongoUser, errors.APIError) {
+	users := make([]MongoUser, 0)
+	cur, err := u.col.Find(
+		u.ctx,
+		bson.M{"organizationID": oid, "deleted": bson.M{"$ne": true}},
+		options.Find().SetSort(bson.M{"email": 1}),
+	)
+	if err != nil {
+		return users, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(u.ctx) {
+		var user MongoUser
+		err = cur.Decode(&user)
+		if err != nil {
+			return users, errors.ErrorInvalidBSON
+		}
+
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+func (u *UserInterface) SetPassword(uid interface{}, password string) errors.APIError {
+	hash, errs := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if errs != nil {
+		return errors.ErrorHashFailure
+	}
+
+	_, err := u.col.UpdateOne(
+		u.ctx,
+		bson.M{"_id": uid},
+		bson.M{"$set": bson.M{"password": hash}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+func (u *UserInterface) SetTimezone(uid interface{}, timezone string) errors.APIError {
+	_, err := u.col.UpdateOne(
+		u.ctx,
+		bson.M{"_id": uid},
+		bson.M{"$set": bson.M{"timezone": timezone}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
 func (u *UserInterface) AddCourse(level string, cid, uid interface{}) errors.APIError {
 	alreadyEnrolled, _ := u.CourseExists(cid, uid)
 	if alreadyEnrolled {
@@ -243,3 +396,244 @@ func (u *UserInterface) AddCourse(level string, cid, uid interface{}) errors.API
 
 	return nil
 }
+
+// RequestDeletion schedules a user's account to be anonymized at purgeAfter.
+func (u *UserInterface) RequestDeletion(uid interface{}, purgeAfter time.Time) errors.APIError {
+	res, err := u.col.UpdateOne(
+		u.ctx,
+		bson.M{"_id": uid, "deleted": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"deletionScheduledFor": utils.TimeToDateTime(purgeAfter)}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.ErrorResourceNotFound
+	}
+
+	return nil
+}
+
+// CancelDeletion clears a pending deletion request.
+func (u *UserInterface) CancelDeletion(uid interface{}) errors.APIError {
+	res, err := u.col.UpdateOne(
+		u.ctx,
+		bson.M{"_id": uid, "deleted": bson.M{"$ne": true}, "deletionScheduledFor": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"deletionScheduledFor": ""}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.ErrorNoPendingDeletion
+	}
+
+	return nil
+}
+
+// DueForPurge returns the users whose deletion grace period has passed.
+func (u *UserInterface) DueForPurge() ([]MongoUser, errors.APIError) {
+	users := make([]MongoUser, 0)
+	cur, err := u.col.Find(
+		u.ctx,
+		bson.M{
+			"deleted":              bson.M{"$ne": true},
+			"deletionScheduledFor": bson.M{"$lte": utils.TimeToDateTime(time.Now())},
+		},
+		options.Find(),
+	)
+	if err != nil {
+		return users, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(u.ctx) {
+		var user MongoUser
+		err = cur.Decode(&user)
+		if err != nil {
+			return users, errors.ErrorInvalidBSON
+		}
+
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+// Anonymize strips every identifying field from a user while keeping the
+// document, and with it their enrollments, so course statistics still add up.
+// The cleared password hash means the account can no longer log in.
+func (u *UserInterface) Anonymize(uid primitive.ObjectID) errors.APIError {
+	_, err := u.col.UpdateOne(
+		u.ctx,
+		bson.M{"_id": uid},
+		bson.M{
+			"$set": bson.M{
+				"email":     fmt.Sprintf("deleted-%s@invalid", uid.Hex()),
+				"firstName": "Deleted",
+				"lastName":  "User",
+				"password":  []byte{},
+				"admin":     false,
+				"timezone":  "",
+				"deleted":   true,
+				"digest":    DigestOff,
+			},
+			"$unset": bson.M{"deletionScheduledFor": "", "digestToken": ""},
+		},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// ValidDigest reports whether frequency is one digests can be sent at.
+func ValidDigest(frequency string) bool {
+	return frequency == DigestOff || frequency == DigestDaily || frequency == DigestWeekly
+}
+
+func (u *UserInterface) SetDigest(uid interface{}, frequency string) errors.APIError {
+	_, err := u.col.UpdateOne(
+		u.ctx,
+		bson.M{"_id": uid},
+		bson.M{"$set": bson.M{"digest": frequency}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// DueForDigest returns the users whose next digest is due. An hour of slack
+// keeps a digest from slipping a whole period when the job runs a little early.
+func (u *UserInterface) DueForDigest(now time.Time) ([]MongoUser, errors.APIError) {
+	due := func(frequency string, period time.Duration) bson.M {
+		return bson.M{
+			"digest": frequency,
+			"$or": bson.A{
+				bson.M{"lastDigestAt": bson.M{"$exists": false}},
+				bson.M{"lastDigestAt": bson.M{"$lte": utils.TimeToDateTime(now.Add(time.Hour - period))}},
+			},
+		}
+	}
+
+	users := make([]MongoUser, 0)
+	cur, err := u.col.Find(
+		u.ctx,
+		bson.M{
+			"deleted": bson.M{"$ne": true},
+			"$or":     bson.A{due(DigestDaily, 24*time.Hour), due(DigestWeekly, 7*24*time.Hour)},
+		},
+		options.Find(),
+	)
+	if err != nil {
+		return users, errors.ErrorDatabaseFailedQuery
+	}
+
+	for cur.Next(u.ctx) {
+		var user MongoUser
+		err = cur.Decode(&user)
+		if err != nil {
+			return users, errors.ErrorInvalidBSON
+		}
+
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+// DigestSent records when the user's digest was sent, and the token it was
+// sent with.
+func (u *UserInterface) DigestSent(uid interface{}, at time.Time, token string) errors.APIError {
+	_, err := u.col.UpdateOne(
+		u.ctx,
+		bson.M{"_id": uid},
+		bson.M{"$set": bson.M{"lastDigestAt": utils.TimeToDateTime(at), "digestToken": token}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	return nil
+}
+
+// UnsubscribeDigest turns off the digests of the user a token was sent to.
+func (u *UserInterface) UnsubscribeDigest(token string) errors.APIError {
+	res, err := u.col.UpdateOne(
+		u.ctx,
+		bson.M{"digestToken": token, "deleted": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"digest": DigestOff}},
+	)
+	if err != nil {
+		return errors.ErrorDatabaseFailedUpdate
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.ErrorResourceNotFound
+	}
+
+	return nil
+}
+
+// SearchResult a user matching a roster search, CourseIDs being the searched
+// courses they are in and Score their relevance.
+type SearchResult struct {
+	ID              primitive.ObjectID   `bson:"_id" json:"id"`
+	First           string               `bson:"firstName" json:"firstName"`
+	Last            string               `bson:"lastName" json:"lastName"`
+	Email           string               `bson:"email" json:"email"`
+	EnrolledCourses []EnrolledCourse     `bson:"enrolledCourses" json:"-"`
+	CourseIDs       []primitive.ObjectID `bson:"-" json:"courseIDs"`
+	Score           float64              `bson:"score" json:"score"`
+}
+
+// Search finds the users of courses by their name and email, most relevant
+// first.
+func (u *UserInterface) Search(q string, cids []primitive.ObjectID, skip, limit int64) ([]SearchResult, errors.APIError) {
+	results := make([]SearchResult, 0)
+	score := bson.M{"$meta": "textScore"}
+	cur, err := u.col.Find(
+		u.ctx,
+		bson.M{
+			"$text":                    bson.M{"$search": q},
+			"enrolledCourses.courseID": bson.M{"$in": cids},
+			"deleted":                  bson.M{"$ne": true},
+		},
+		options.Find().
+			SetProjection(bson.M{"firstName": 1, "lastName": 1, "email": 1, "enrolledCourses": 1, "score": score}).
+			SetSort(bson.M{"score": score}).
+			SetSkip(skip).
+			SetLimit(limit),
+	)
+	if err != nil {
+		return results, errors.ErrorDatabaseFailedQuery
+	}
+
+	searched := make(map[primitive.ObjectID]bool)
+	for _, cid := range cids {
+		searched[cid] = true
+	}
+
+	for cur.Next(u.ctx) {
+		var result SearchResult
+		err = cur.Decode(&result)
+		if err != nil {
+			return results, errors.ErrorInvalidBSON
+		}
+
+		result.CourseIDs = make([]primitive.ObjectID, 0)
+		for _, course := range result.EnrolledCourses {
+			if searched[course.CourseID] {
+				result.CourseIDs = append(result.CourseIDs, course.CourseID)
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
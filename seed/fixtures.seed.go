@@ -0,0 +1,386 @@
+package seed
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	bcrypt "golang.org/x/crypto/bcrypt"
+
+	"backend/config"
+	"backend/models"
+	anm "backend/models/cmsmodels/announcementmodels"
+	am "backend/models/cmsmodels/assignmentmodels"
+	cm "backend/models/cmsmodels/coursemodels"
+	sm "backend/models/cmsmodels/submissionmodels"
+	gfs "backend/models/gridfsmodels"
+	um "backend/models/usermodels"
+
+	tyrgin "github.com/stevens-tyr/tyr-gin"
+)
+
+// Password every seeded user can log in with.
+const Password = "password"
+
+var (
+	collections = []string{"announcements", "assignments", "courses", "notifications", "submissions", "threads", "users"}
+
+	firstNames = []string{
+		"Ada", "Alan", "Barbara", "Claude", "Dennis", "Edsger", "Frances", "Grace", "Guido", "Hedy",
+		"Ken", "Linus", "Margaret", "Niklaus", "Radia", "Shafi", "Sophie", "Tim", "Vint", "Yukihiro",
+	}
+	lastNames = []string{
+		"Allen", "Backus", "Cerf", "Dijkstra", "Hamilton", "Hopper", "Kay", "Knuth", "Lamarr", "Liskov",
+		"Lovelace", "McCarthy", "Perlman", "Ritchie", "Shannon", "Thompson", "Torvalds", "Turing", "Wirth", "Wilson",
+	}
+	departments = []string{"CS", "CPE", "SSW", "MA"}
+	topics      = []string{"Recursion", "Linked Lists", "Hash Tables", "Sorting", "Graphs", "Dynamic Programming"}
+)
+
+type (
+	// Options how much data to generate.
+	Options struct {
+		Courses          int
+		StudentsPerClass int
+		Assignments      int
+		Reset            bool
+		RandomSeed       int64
+	}
+
+	// Summary counts of what was generated.
+	Summary struct {
+		Users       int
+		Courses     int
+		Assignments int
+		Submissions int
+	}
+
+	seeder struct {
+		ctx     context.Context
+		db      *mongo.Database
+		gfs     *gfs.GridFSInterface
+		rand    *rand.Rand
+		hash    []byte
+		emails  map[string]int
+		summary Summary
+	}
+)
+
+func toDateTime(t time.Time) primitive.DateTime {
+	return primitive.DateTime(t.UnixNano() / 1000000)
+}
+
+// Run fills the application database with fake courses, users of every role,
+// assignments and graded submissions. It refuses to run when ENV is production.
+func Run(opts Options) (*Summary, error) {
+	if config.C.Env == "production" {
+		return nil, fmt.Errorf("refusing to seed a production database")
+	}
+
+	db, err := tyrgin.GetMongoDB(config.C.DBName)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &seeder{
+		ctx:    context.Background(),
+		db:     db,
+		gfs:    models.NewGridFSInterface(),
+		rand:   rand.New(rand.NewSource(opts.RandomSeed)),
+		hash:   hash,
+		emails: make(map[string]int),
+	}
+
+	if opts.Reset {
+		for _, collection := range collections {
+			if err = db.Collection(collection).Drop(s.ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if _, err = s.user("Site", "Admin", true); err != nil {
+		return nil, err
+	}
+
+	for index := 0; index < opts.Courses; index++ {
+		if err = s.course(index, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	return &s.summary, nil
+}
+
+func (s *seeder) insert(collection string, document interface{}) error {
+	_, err := s.db.Collection(collection).InsertOne(s.ctx, document)
+	return err
+}
+
+func (s *seeder) user(first, last string, admin bool) (*um.MongoUser, error) {
+	email := strings.ToLower(fmt.Sprintf("%s.%s", first, last))
+	s.emails[email]++
+	if s.emails[email] > 1 {
+		email = fmt.Sprintf("%s%d", email, s.emails[email])
+	}
+
+	user := &um.MongoUser{
+		ID:              primitive.NewObjectID(),
+		Admin:           admin,
+		Email:           email + "@example.com",
+		Password:        s.hash,
+		First:           first,
+		Last:            last,
+		EnrolledCourses: make([]um.EnrolledCourse, 0),
+		Timezone:        "America/New_York",
+	}
+
+	s.summary.Users++
+	return user, s.insert("users", user)
+}
+
+func (s *seeder) randomUser() (*um.MongoUser, error) {
+	return s.user(firstNames[s.rand.Intn(len(firstNames))], lastNames[s.rand.Intn(len(lastNames))], false)
+}
+
+// enroll adds users to a course with the given enrollment type.
+func (s *seeder) enroll(users []*um.MongoUser, cid primitive.ObjectID, level string) error {
+	for _, user := range users {
+		user.EnrolledCourses = append(user.EnrolledCourses, um.EnrolledCourse{CourseID: cid, EnrollmentType: level})
+		_, err := s.db.Collection("users").ReplaceOne(s.ctx, bson.M{"_id": user.ID}, user)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func ids(users []*um.MongoUser) []primitive.ObjectID {
+	uids := make([]primitive.ObjectID, len(users))
+	for index, user := range users {
+		uids[index] = user.ID
+	}
+
+	return uids
+}
+
+func (s *seeder) course(index int, opts Options) error {
+	teacher, err := s.randomUser()
+	if err != nil {
+		return err
+	}
+	assistant, err := s.randomUser()
+	if err != nil {
+		return err
+	}
+
+	students := make([]*um.MongoUser, opts.StudentsPerClass)
+	for i := range students {
+		if students[i], err = s.randomUser(); err != nil {
+			return err
+		}
+	}
+
+	course := cm.MongoCourse{
+		ID:                 primitive.NewObjectID(),
+		Department:         departments[index%len(departments)],
+		LongName:           fmt.Sprintf("%s %d", topics[index%len(topics)], index+1),
+		Number:             100 + 10*index + s.rand.Intn(10),
+		Section:            fmt.Sprintf("%c", 'A'+index%26),
+		Semester:           fmt.Sprintf("F%d", time.Now().Year()%100),
+		Professors:         []primitive.ObjectID{teacher.ID},
+		Assistants:         []primitive.ObjectID{assistant.ID},
+		Students:           ids(students),
+		Withdrawn:          make([]primitive.ObjectID, 0),
+		Assignments:        make([]primitive.ObjectID, 0),
+		InviteCodes:        make([]cm.InviteCode, 0),
+		Waitlist:           make([]cm.WaitlistEntry, 0),
+		PendingEnrollments: make([]cm.PendingEnrollment, 0),
+		Sections:           make([]cm.Section, 0),
+		Archives:           make([]cm.CourseArchive, 0),
+	}
+
+	for i := 0; i < opts.Assignments; i++ {
+		aid, err := s.assignment(i, opts.Assignments, students)
+		if err != nil {
+			return err
+		}
+		course.Assignments = append(course.Assignments, aid)
+	}
+
+	if err = s.insert("courses", course); err != nil {
+		return err
+	}
+	s.summary.Courses++
+
+	if err = s.enroll([]*um.MongoUser{teacher}, course.ID, "teacher"); err != nil {
+		return err
+	}
+	if err = s.enroll([]*um.MongoUser{assistant}, course.ID, "assistant"); err != nil {
+		return err
+	}
+	if err = s.enroll(students, course.ID, "student"); err != nil {
+		return err
+	}
+
+	announcement := anm.MongoAnnouncement{
+		ID:          primitive.NewObjectID(),
+		CourseID:    course.ID,
+		AuthorID:    teacher.ID,
+		Title:       "Welcome to " + course.LongName,
+		Body:        "Office hours are **Tuesdays at 3pm**. Post questions on the assignment threads.",
+		Pinned:      true,
+		PublishDate: toDateTime(time.Now().AddDate(0, 0, -30)),
+		Notified:    true,
+		CreatedAt:   toDateTime(time.Now().AddDate(0, 0, -30)),
+	}
+
+	return s.insert("announcements", announcement)
+}
+
+// tarball builds a gzipped tar holding a single file, the format submissions
+// and supporting files are stored in.
+func tarball(name, content string) (*bytes.Buffer, error) {
+	buf := new(bytes.Buffer)
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+
+	err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), ModTime: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+	if _, err = tw.Write([]byte(content)); err != nil {
+		return nil, err
+	}
+	if err = tw.Close(); err != nil {
+		return nil, err
+	}
+	if err = gw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// assignment creates an assignment whose due date is spread out around today.
+// Assignments already due are graded for most students, and the last one is
+// left unpublished.
+func (s *seeder) assignment(index, total int, students []*um.MongoUser) (primitive.ObjectID, error) {
+	topic := topics[index%len(topics)]
+	due := time.Now().AddDate(0, 0, 7*(index-total/2))
+
+	supportingFiles := primitive.NewObjectID()
+	files, err := tarball("helpers.py", "def read_input():\n    return input().split()\n")
+	if err != nil {
+		return supportingFiles, err
+	}
+	if err := s.gfs.Upload(&supportingFiles, "supporting.tar.gz", files); err != nil {
+		return supportingFiles, err
+	}
+
+	assign := am.MongoAssignment{
+		ID:              primitive.NewObjectID(),
+		Language:        "python",
+		Version:         "3.7",
+		Name:            fmt.Sprintf("Homework %d: %s", index+1, topic),
+		NumAttempts:     3,
+		Description:     fmt.Sprintf("Implement the %s exercises in `solution.py`.\n\nInclude a short write up of the running time.", strings.ToLower(topic)),
+		DueDate:         toDateTime(due),
+		Published:       index < total-1,
+		SupportingFiles: supportingFiles,
+		TestBuildCMD:    "pip install -r requirements.txt",
+		Tests: []am.Test{
+			{Name: "sample", ExpectedOutput: "ok", StudentFacing: true, TestCMD: "python3 solution.py < sample.in"},
+			{Name: "edge cases", ExpectedOutput: "ok", StudentFacing: true, TestCMD: "python3 solution.py < edge.in"},
+			{Name: "hidden", ExpectedOutput: "ok", StudentFacing: false, TestCMD: "python3 solution.py < hidden.in"},
+		},
+		Submissions: make([]am.AssignmentSubmission, 0),
+		Attachments: make([]am.Attachment, 0),
+	}
+
+	if due.Before(time.Now()) {
+		for _, student := range students {
+			if s.rand.Intn(10) == 0 {
+				continue
+			}
+
+			attempts := 1 + s.rand.Intn(assign.NumAttempts)
+			for attempt := 1; attempt <= attempts; attempt++ {
+				submission, err := s.submission(assign, student.ID, attempt, due)
+				if err != nil {
+					return assign.ID, err
+				}
+
+				assign.Submissions = append(assign.Submissions, am.AssignmentSubmission{
+					UserID:        student.ID,
+					SubmissionID:  submission.ID,
+					AttemptNumber: attempt,
+				})
+			}
+		}
+	}
+
+	s.summary.Assignments++
+	return assign.ID, s.insert("assignments", assign)
+}
+
+func (s *seeder) submission(assign am.MongoAssignment, uid primitive.ObjectID, attempt int, due time.Time) (*sm.MongoSubmission, error) {
+	fid := primitive.NewObjectID()
+	file, err := tarball("solution.py", fmt.Sprintf("# attempt %d\nprint('ok')\n", attempt))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.gfs.Upload(&fid, "submission.tar.gz", file); err != nil {
+		return nil, err
+	}
+
+	tests := assign.TestsFor(uid)
+	results := make([]sm.WorkerResult, len(tests))
+	for index, test := range tests {
+		// later attempts are more likely to pass
+		passed := s.rand.Intn(assign.NumAttempts+1) < attempt+1
+		output := "ok"
+		if !passed {
+			output = "Traceback (most recent call last):\n  AssertionError"
+		}
+
+		results[index] = sm.WorkerResult{
+			ID:            index,
+			Passed:        passed,
+			StudentFacing: test.StudentFacing,
+			Output:        output,
+			TestCMD:       test.TestCMD,
+			Name:          test.Name,
+		}
+	}
+
+	// most submissions land in the days before the due date, a few are late
+	submitted := due.Add(-time.Duration(s.rand.Intn(96)-8) * time.Hour).Add(time.Duration(attempt) * time.Minute)
+	submission := &sm.MongoSubmission{
+		ID:             primitive.NewObjectID(),
+		UserID:         uid,
+		FileID:         fid,
+		AssignmentID:   assign.ID,
+		AttemptNumber:  attempt,
+		SubmissionDate: toDateTime(submitted),
+		File:           "submission.tar.gz",
+		Results:        results,
+	}
+
+	s.summary.Submissions++
+	return submission, s.insert("submissions", submission)
+}
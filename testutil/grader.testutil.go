@@ -0,0 +1,176 @@
+// Package testutil helps tests stand in for the services the backend calls.
+package testutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"backend/courtherald"
+)
+
+// Outcomes a fake grader can be told to give the next calls.
+const (
+	// OutcomePass starts jobs whose tests all pass.
+	OutcomePass = "pass"
+	// OutcomeFail starts jobs whose tests all fail.
+	OutcomeFail = "fail"
+	// OutcomeHiddenFail starts jobs whose student facing tests pass and
+	// whose hidden tests fail.
+	OutcomeHiddenFail = "hiddenFail"
+	// OutcomeTimeout never answers, until the caller gives up.
+	OutcomeTimeout = "timeout"
+	// OutcomeError answers 500.
+	OutcomeError = "error"
+	// OutcomeUnavailable answers 503, which even new jobs are retried on.
+	OutcomeUnavailable = "unavailable"
+)
+
+type (
+	// FakeGrader a court herald that speaks the contract of the courtherald
+	// package and gives the outcomes it is told to, OutcomePass once they
+	// run out. It remembers the jobs it started and the outcome of each, so
+	// tests can report the results back like court herald would.
+	// Serve it with httptest.NewServer or http.Serve.
+	FakeGrader struct {
+		mu       sync.Mutex
+		outcomes []string
+		calls    int
+		jobs     map[string]fakeJob
+	}
+
+	fakeJob struct {
+		req     courtherald.SubmitJobRequest
+		outcome string
+	}
+
+	// result of a test, as court herald reports it.
+	result struct {
+		ID            int    `json:"id"`
+		Passed        bool   `json:"passed"`
+		StudentFacing bool   `json:"studentFacing"`
+		Output        string `json:"output"`
+		TestCMD       string `json:"testCMD"`
+		Name          string `json:"name"`
+	}
+)
+
+// NewFakeGrader a fake grader that has started no jobs.
+func NewFakeGrader() *FakeGrader {
+	return &FakeGrader{jobs: make(map[string]fakeJob)}
+}
+
+// Then queues the outcomes of the next calls, one each.
+func (f *FakeGrader) Then(outcomes ...string) *FakeGrader {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.outcomes = append(f.outcomes, outcomes...)
+	return f
+}
+
+// Calls how many calls the grader was sent.
+func (f *FakeGrader) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls
+}
+
+// Job the job started for a submission, false when none was.
+func (f *FakeGrader) Job(sid string) (courtherald.SubmitJobRequest, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	job, found := f.jobs[sid]
+	return job.req, found
+}
+
+// Report the grade report court herald would send for a submission's job,
+// nil when no job was started for it.
+func (f *FakeGrader) Report(sid string) []byte {
+	f.mu.Lock()
+	job, found := f.jobs[sid]
+	f.mu.Unlock()
+	if !found {
+		return nil
+	}
+
+	results := make([]result, len(job.req.Tests))
+	for index, test := range job.req.Tests {
+		results[index] = result{
+			ID:            index,
+			Passed:        job.outcome == OutcomePass || (job.outcome == OutcomeHiddenFail && test.StudentFacing),
+			StudentFacing: test.StudentFacing,
+			Output:        test.ExpectedOutput,
+			TestCMD:       test.TestCMD,
+			Name:          test.Name,
+		}
+	}
+
+	report, _ := json.Marshal(map[string]interface{}{"results": results})
+	return report
+}
+
+func (f *FakeGrader) next() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if len(f.outcomes) == 0 {
+		return OutcomePass
+	}
+
+	outcome := f.outcomes[0]
+	f.outcomes = f.outcomes[1:]
+	return outcome
+}
+
+func (f *FakeGrader) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	outcome := f.next()
+	switch outcome {
+	case OutcomeTimeout:
+		<-r.Context().Done()
+		return
+	case OutcomeError:
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	case OutcomeUnavailable:
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/new") && r.Method == "POST":
+		var req courtherald.SubmitJobRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Validate() != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		f.mu.Lock()
+		f.jobs[req.Submission.ID.Hex()] = fakeJob{req, outcome}
+		f.mu.Unlock()
+
+		json.NewEncoder(w).Encode(courtherald.SubmitJobResponse{Job: "fake-" + req.Submission.ID.Hex()})
+	case strings.HasSuffix(r.URL.Path, "/status"):
+		sid := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/"+courtherald.Version+"/grader/"), "/status")
+		f.mu.Lock()
+		job, found := f.jobs[sid]
+		f.mu.Unlock()
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		status := courtherald.JobSucceeded
+		if job.outcome == OutcomeFail {
+			status = courtherald.JobFailed
+		}
+		json.NewEncoder(w).Encode(courtherald.JobStatusResponse{Job: "fake-" + sid, Status: status})
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"backend/errors"
+)
+
+// walkArchive calls fn with every regular file in a zip or tar.gz archive.
+func walkArchive(content []byte, fn func(name string, size int64, open func() (io.ReadCloser, error)) error) errors.APIError {
+	if zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content))); err == nil {
+		for _, file := range zr.File {
+			if file.FileInfo().IsDir() {
+				continue
+			}
+			if err = fn(cleanArchivePath(file.Name), int64(file.UncompressedSize64), file.Open); err != nil {
+				return errors.ErrorFailedToReadFile
+			}
+		}
+
+		return nil
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return errors.ErrorFailedToReadFile
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.ErrorFailedToReadFile
+		}
+
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+		open := func() (io.ReadCloser, error) { return ioutil.NopCloser(tr), nil }
+		if err = fn(cleanArchivePath(header.Name), header.Size, open); err != nil {
+			return errors.ErrorFailedToReadFile
+		}
+	}
+
+	return nil
+}
+
+// ArchivePaths lists the files in a submitted zip or tar.gz archive.
+func ArchivePaths(content []byte) ([]string, errors.APIError) {
+	paths := make([]string, 0)
+	err := walkArchive(content, func(name string, size int64, open func() (io.ReadCloser, error)) error {
+		paths = append(paths, name)
+		return nil
+	})
+
+	return paths, err
+}
+
+// ArchiveFiles reads the files in a submitted zip or tar.gz archive. Files
+// larger than maxSize are listed with nil content.
+func ArchiveFiles(content []byte, maxSize int64) (map[string][]byte, errors.APIError) {
+	files := make(map[string][]byte)
+	err := walkArchive(content, func(name string, size int64, open func() (io.ReadCloser, error)) error {
+		if size > maxSize {
+			files[name] = nil
+			return nil
+		}
+
+		r, err := open()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		data, err := ioutil.ReadAll(io.LimitReader(r, maxSize+1))
+		if err != nil {
+			return err
+		}
+		if int64(len(data)) > maxSize {
+			data = nil
+		}
+		files[name] = data
+
+		return nil
+	})
+
+	return files, err
+}
+
+// Limits on the files of an archive built with BuildArchive.
+const (
+	maxBuiltFiles = 200
+	maxBuiltSize  = 5 << 20
+)
+
+// CheckFileTree checks files keyed by their path could be packed into an
+// archive, returning the paths sorted. Paths must be relative and stay
+// inside the archive.
+func CheckFileTree(files map[string][]byte) ([]string, errors.APIError) {
+	if len(files) == 0 || len(files) > maxBuiltFiles {
+		return nil, errors.ErrorInvalidEditorFiles
+	}
+
+	names := make([]string, 0, len(files))
+	var size int
+	for name, content := range files {
+		if path.Clean(name) != name || path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, errors.ErrorInvalidEditorFiles
+		}
+		size += len(content)
+		names = append(names, name)
+	}
+	if size > maxBuiltSize {
+		return nil, errors.ErrorInvalidEditorFiles
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// BuildArchive packs files, keyed by their path, into a tar.gz like the ones
+// students upload.
+func BuildArchive(files map[string][]byte) ([]byte, errors.APIError) {
+	names, err := CheckFileTree(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, name := range names {
+		header := &tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(files[name])),
+			ModTime:  now,
+			Typeflag: tar.TypeReg,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, errors.ErrorFailedToCreateArchive
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return nil, errors.ErrorFailedToCreateArchive
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, errors.ErrorFailedToCreateArchive
+	}
+	if err := gz.Close(); err != nil {
+		return nil, errors.ErrorFailedToCreateArchive
+	}
+
+	return buf.Bytes(), nil
+}
+
+func cleanArchivePath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// CheckManifest compares the files in an archive to a manifest of path.Match
+// patterns. Patterns starting with ? are optional, the others must match a
+// file. When exclusive, files no pattern matches are unexpected.
+func CheckManifest(paths, patterns []string, exclusive bool) ([]string, []string) {
+	missing := make([]string, 0)
+	matched := make(map[string]bool)
+	for _, pattern := range patterns {
+		optional := strings.HasPrefix(pattern, "?")
+		clean := cleanArchivePath(strings.TrimPrefix(pattern, "?"))
+
+		found := false
+		for _, name := range paths {
+			if ok, _ := path.Match(clean, name); ok {
+				found = true
+				matched[name] = true
+			}
+		}
+
+		if !found && !optional {
+			missing = append(missing, pattern)
+		}
+	}
+
+	unexpected := make([]string, 0)
+	if exclusive {
+		for _, name := range paths {
+			if !matched[name] {
+				unexpected = append(unexpected, name)
+			}
+		}
+	}
+
+	return missing, unexpected
+}
@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+
+	"backend/errors"
+)
+
+// CartridgeFileBase is how an item's html refers to its files, LMSs replace
+// it with wherever they put the files on import.
+const CartridgeFileBase = "$IMS-CC-FILEBASE$"
+
+type (
+	// CartridgeItem an assignment as it is exported to a Common Cartridge.
+	CartridgeItem struct {
+		ID    string
+		Title string
+		// HTML the assignment's description, referring to its files by
+		// CartridgeFileBase and their Path.
+		HTML  string
+		Files []CartridgeFile
+	}
+
+	// CartridgeFile a file an item's description uses, e.g. a diagram.
+	CartridgeFile struct {
+		Path    string
+		Content []byte
+	}
+
+	ccManifest struct {
+		XMLName       xml.Name       `xml:"manifest"`
+		Identifier    string         `xml:"identifier,attr"`
+		Xmlns         string         `xml:"xmlns,attr"`
+		XmlnsLOM      string         `xml:"xmlns:lomimscc,attr"`
+		Schema        string         `xml:"metadata>schema"`
+		SchemaVersion string         `xml:"metadata>schemaversion"`
+		Title         string         `xml:"metadata>lomimscc:lom>lomimscc:general>lomimscc:title>lomimscc:string"`
+		Organization  ccOrganization `xml:"organizations>organization"`
+		Resources     []ccResource   `xml:"resources>resource"`
+	}
+
+	ccOrganization struct {
+		Identifier string `xml:"identifier,attr"`
+		Structure  string `xml:"structure,attr"`
+		Root       ccItem `xml:"item"`
+	}
+
+	ccItem struct {
+		Identifier    string   `xml:"identifier,attr"`
+		IdentifierRef string   `xml:"identifierref,attr,omitempty"`
+		Title         string   `xml:"title,omitempty"`
+		Items         []ccItem `xml:"item"`
+	}
+
+	ccResource struct {
+		Identifier   string         `xml:"identifier,attr"`
+		Type         string         `xml:"type,attr"`
+		Href         string         `xml:"href,attr,omitempty"`
+		Files        []ccFile       `xml:"file"`
+		Dependencies []ccDependency `xml:"dependency"`
+	}
+
+	ccFile struct {
+		Href string `xml:"href,attr"`
+	}
+
+	ccDependency struct {
+		IdentifierRef string `xml:"identifierref,attr"`
+	}
+
+	ccAssignment struct {
+		XMLName     xml.Name       `xml:"assignment"`
+		Xmlns       string         `xml:"xmlns,attr"`
+		Identifier  string         `xml:"identifier,attr"`
+		Title       string         `xml:"title"`
+		Text        ccText         `xml:"text"`
+		Attachments *ccAttachments `xml:"attachments,omitempty"`
+		Gradable    ccGradable     `xml:"gradable"`
+		Formats     []ccFormat     `xml:"submission_formats>format"`
+	}
+
+	ccText struct {
+		Type string `xml:"texttype,attr"`
+		Body string `xml:",chardata"`
+	}
+
+	ccAttachments struct {
+		Attachments []ccAttachment `xml:"attachment"`
+	}
+
+	ccAttachment struct {
+		Href string `xml:"href,attr"`
+		Role string `xml:"role,attr"`
+	}
+
+	ccGradable struct {
+		Points float64 `xml:"points_possible,attr"`
+		Value  bool    `xml:",chardata"`
+	}
+
+	ccFormat struct {
+		Type string `xml:"type,attr"`
+	}
+)
+
+// BuildCartridge packages assignments as an IMS Common Cartridge 1.3, which
+// Canvas and Moodle import, each item as an assignment resource with its
+// files alongside.
+func BuildCartridge(identifier, title string, items []CartridgeItem) (*bytes.Buffer, errors.APIError) {
+	buf := new(bytes.Buffer)
+	archive := zip.NewWriter(buf)
+
+	manifest := ccManifest{
+		Identifier:    identifier,
+		Xmlns:         "http://www.imsglobal.org/xsd/imsccv1p3/imscp_v1p1",
+		XmlnsLOM:      "http://ltsc.ieee.org/xsd/imsccv1p3/LOM/manifest",
+		Schema:        "IMS Common Cartridge",
+		SchemaVersion: "1.3.0",
+		Title:         title,
+		Organization: ccOrganization{
+			Identifier: "organization",
+			Structure:  "rooted-hierarchy",
+			Root:       ccItem{Identifier: "root", Items: make([]ccItem, 0)},
+		},
+		Resources: make([]ccResource, 0),
+	}
+
+	for _, item := range items {
+		resource := "resource_" + item.ID
+		href := item.ID + "/assignment.xml"
+
+		assignment := ccAssignment{
+			Xmlns:      "http://www.imsglobal.org/xsd/imscc_extensions/assignment",
+			Identifier: resource,
+			Title:      item.Title,
+			Text:       ccText{Type: "text/html", Body: item.HTML},
+			Gradable:   ccGradable{Points: 100, Value: true},
+			Formats:    []ccFormat{{Type: "file"}},
+		}
+
+		files := ccResource{Identifier: resource + "_files", Type: "webcontent"}
+		if len(item.Files) > 0 {
+			assignment.Attachments = &ccAttachments{}
+		}
+		for _, file := range item.Files {
+			path := item.ID + "/" + file.Path
+			if err := writeZipFile(archive, path, file.Content); err != nil {
+				return nil, err
+			}
+
+			files.Files = append(files.Files, ccFile{path})
+			assignment.Attachments.Attachments = append(assignment.Attachments.Attachments, ccAttachment{file.Path, "Learner"})
+		}
+
+		content, err := xml.MarshalIndent(assignment, "", "  ")
+		if err != nil {
+			return nil, errors.ErrorFailedToCreateArchive
+		}
+		if err := writeZipFile(archive, href, append([]byte(xml.Header), content...)); err != nil {
+			return nil, err
+		}
+
+		res := ccResource{Identifier: resource, Type: "assignment_xmlv1p0", Href: href, Files: []ccFile{{href}}}
+		if len(files.Files) > 0 {
+			res.Dependencies = []ccDependency{{files.Identifier}}
+			manifest.Resources = append(manifest.Resources, res, files)
+		} else {
+			manifest.Resources = append(manifest.Resources, res)
+		}
+
+		manifest.Organization.Root.Items = append(manifest.Organization.Root.Items, ccItem{
+			Identifier:    "item_" + item.ID,
+			IdentifierRef: resource,
+			Title:         item.Title,
+		})
+	}
+
+	content, err := xml.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, errors.ErrorFailedToCreateArchive
+	}
+	if err := writeZipFile(archive, "imsmanifest.xml", append([]byte(xml.Header), content...)); err != nil {
+		return nil, err
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, errors.ErrorFailedToCreateArchive
+	}
+
+	return buf, nil
+}
+
+// CartridgeFilePath where an item's file is put in a cartridge, under the
+// file's id so files with the same name do not collide.
+func CartridgeFilePath(id, filename string) string {
+	return fmt.Sprintf("files/%s/%s", id, filename)
+}
+
+func writeZipFile(archive *zip.Writer, name string, content []byte) errors.APIError {
+	w, err := archive.Create(name)
+	if err != nil {
+		return errors.ErrorFailedToCreateArchive
+	}
+
+	if _, err = w.Write(content); err != nil {
+		return errors.ErrorFailedToCreateArchive
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffCells caps the lines of two files multiplied, the work a diff takes.
+const maxDiffCells = 4000000
+
+type diffOp struct {
+	kind byte
+	line string
+	// a and b are the lines of each file before this one.
+	a, b int
+}
+
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// diffOps finds the shortest edit from a to b by their longest common
+// subsequence.
+func diffOps(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, n+m)
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i], i, j})
+			i++
+			j++
+		case j == m || (i < n && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i], i, j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	return ops
+}
+
+// UnifiedDiff returns the unified diff of two versions of a file with the
+// given lines of context, empty when they are the same. It is false when the
+// files are too large to diff.
+func UnifiedDiff(fromName, toName, from, to string, context int) (string, bool) {
+	a, b := splitLines(from), splitLines(to)
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		return "", false
+	}
+
+	ops := diffOps(a, b)
+	changes := make([]int, 0)
+	for index, op := range ops {
+		if op.kind != ' ' {
+			changes = append(changes, index)
+		}
+	}
+	if len(changes) == 0 {
+		return "", true
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+
+	for c := 0; c < len(changes); {
+		last := c
+		for last+1 < len(changes) && changes[last+1]-changes[last] <= 2*context {
+			last++
+		}
+
+		start := changes[c] - context
+		if start < 0 {
+			start = 0
+		}
+		end := changes[last] + context + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		aLen, bLen := 0, 0
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				aLen++
+			}
+			if op.kind != '-' {
+				bLen++
+			}
+		}
+
+		aStart, bStart := ops[start].a, ops[start].b
+		if aLen > 0 {
+			aStart++
+		}
+		if bLen > 0 {
+			bStart++
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+
+		for _, op := range ops[start:end] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		c = last + 1
+	}
+
+	return out.String(), true
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"strings"
+
+	"backend/config"
+)
+
+// NormalizeEmail the canonical form emails are stored and looked up in, so
+// Bob@School.edu and bob@school.edu are the same account. Surrounding space is
+// trimmed and the email lowercased. With EMAIL_STRIP_PLUS_TAGS, a +tag of the
+// local part is dropped as well, bob+cs101@school.edu being bob@school.edu.
+func NormalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !config.C.StripEmailPlusTags {
+		return email
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+
+	local := email[:at]
+	if plus := strings.Index(local, "+"); plus > 0 {
+		local = local[:plus]
+	}
+
+	return local + email[at:]
+}
+
+// EmailForms the forms an account's email may be stored in, the email as
+// typed, lowercased, first, then normalized. Accounts stored before
+// EMAIL_STRIP_PLUS_TAGS was turned on keep their +tag, as do those
+// normalizing would have given the same email as another account.
+func EmailForms(email string) []string {
+	exact := strings.ToLower(strings.TrimSpace(email))
+	if normalized := NormalizeEmail(email); normalized != exact {
+		return []string{exact, normalized}
+	}
+
+	return []string{exact}
+}
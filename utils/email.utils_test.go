@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"backend/config"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	defer func(strip bool) { config.C.StripEmailPlusTags = strip }(config.C.StripEmailPlusTags)
+
+	for _, c := range []struct {
+		email, kept, stripped string
+	}{
+		{"bob@school.edu", "bob@school.edu", "bob@school.edu"},
+		{"  Bob@School.EDU \n", "bob@school.edu", "bob@school.edu"},
+		{"bob+cs101@school.edu", "bob+cs101@school.edu", "bob@school.edu"},
+		{"Bob+CS101+Extra@school.edu", "bob+cs101+extra@school.edu", "bob@school.edu"},
+		{"+tag@school.edu", "+tag@school.edu", "+tag@school.edu"},
+		{"bob+tag", "bob+tag", "bob+tag"},
+		{"bob@plus+domain.edu", "bob@plus+domain.edu", "bob@plus+domain.edu"},
+	} {
+		config.C.StripEmailPlusTags = false
+		if got := NormalizeEmail(c.email); got != c.kept {
+			t.Errorf("NormalizeEmail(%q) = %q, want %q", c.email, got, c.kept)
+		}
+
+		config.C.StripEmailPlusTags = true
+		if got := NormalizeEmail(c.email); got != c.stripped {
+			t.Errorf("NormalizeEmail(%q) stripping +tags = %q, want %q", c.email, got, c.stripped)
+		}
+	}
+}
+
+func TestEmailForms(t *testing.T) {
+	defer func(strip bool) { config.C.StripEmailPlusTags = strip }(config.C.StripEmailPlusTags)
+
+	config.C.StripEmailPlusTags = false
+	if forms := EmailForms("Bob+CS101@School.edu"); !reflect.DeepEqual(forms, []string{"bob+cs101@school.edu"}) {
+		t.Errorf("without stripping +tags the email was looked up as %v", forms)
+	}
+
+	config.C.StripEmailPlusTags = true
+	if forms := EmailForms("Bob+CS101@School.edu"); !reflect.DeepEqual(forms, []string{"bob+cs101@school.edu", "bob@school.edu"}) {
+		t.Errorf("the email as typed was not looked up before its normalized form: %v", forms)
+	}
+	if forms := EmailForms(" Bob@School.edu"); !reflect.DeepEqual(forms, []string{"bob@school.edu"}) {
+		t.Errorf("an email without a +tag was looked up as %v", forms)
+	}
+}
@@ -38,6 +38,33 @@ func CheckFileType(mf *multipart.FileHeader) ([]byte, errors.APIError) {
 	return bf, nil
 }
 
+// CheckAttachmentType reads an uploaded attachment and verifies it is an image
+// or a pdf, returning its content and mime type.
+func CheckAttachmentType(mf *multipart.FileHeader) ([]byte, string, errors.APIError) {
+	var bf []byte
+	if mf == nil {
+		return bf, "", errors.ErrorFileDNE
+	}
+
+	of, err := mf.Open()
+	if err != nil {
+		return bf, "", errors.ErrorFailedToOpenFile
+	}
+	defer of.Close()
+
+	bf, err = ioutil.ReadAll(of)
+	if err != nil {
+		return bf, "", errors.ErrorFailedToReadFile
+	}
+
+	k, u := filetype.Match(bf)
+	if u != nil || (k.MIME.Type != "image" && k.Extension != "pdf") {
+		return bf, "", errors.ErrorUnsupportedFileType
+	}
+
+	return bf, k.MIME.Value, nil
+}
+
 // ConvertZipToTarGz converts a .zip file to a tar.gz file.
 // This is for faster download and file compression reasons.
 // TODO:
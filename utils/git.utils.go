@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"backend/config"
+	"backend/errors"
+)
+
+// commitSHA a full git commit hash, short hashes cannot be fetched directly.
+var commitSHA = regexp.MustCompile("^[0-9a-f]{40}$")
+
+// maxRepositoryArchive caps the size of an archived commit, the same as an
+// uploaded submission.
+const maxRepositoryArchive = 50 << 20
+
+// CheckRepository verifies a repository is a https url on an allowed host and
+// the commit is a full sha, so the server cannot be pointed at local files or
+// internal services.
+func CheckRepository(repository, commit string) errors.APIError {
+	if !commitSHA.MatchString(commit) {
+		return errors.ErrorInvalidRepository
+	}
+
+	u, err := url.Parse(repository)
+	if err != nil || u.Scheme != "https" || u.User != nil || u.Port() != "" {
+		return errors.ErrorInvalidRepository
+	}
+
+	for _, host := range config.C.GitSubmissionHosts {
+		if strings.EqualFold(u.Hostname(), strings.TrimSpace(host)) {
+			return nil
+		}
+	}
+
+	return errors.ErrorInvalidRepository
+}
+
+// gitConfig keeps git to plain https, without following redirects elsewhere.
+var gitConfig = []string{
+	"-c", "protocol.allow=never",
+	"-c", "protocol.https.allow=always",
+	"-c", "http.followRedirects=false",
+}
+
+func git(ctx context.Context, dir string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", append(gitConfig, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	return cmd.Run()
+}
+
+// ArchiveCommit fetches a single commit of a public repository and returns it
+// as a tar.gz, the same format as an uploaded submission.
+func ArchiveCommit(repository, commit string) ([]byte, errors.APIError) {
+	if err := CheckRepository(repository, commit); err != nil {
+		return nil, err
+	}
+
+	dir, err := ioutil.TempDir("", "submission-")
+	if err != nil {
+		return nil, errors.ErrorFailedToCloneRepository
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	steps := [][]string{
+		{"init", "--quiet"},
+		{"fetch", "--quiet", "--depth", "1", repository, commit},
+	}
+	for _, step := range steps {
+		if err = git(ctx, dir, step...); err != nil {
+			return nil, errors.ErrorFailedToCloneRepository
+		}
+	}
+
+	var archive bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", append(gitConfig, "archive", "--format=tar.gz", commit)...)
+	cmd.Dir = dir
+	cmd.Stdout = &archive
+	if err = cmd.Run(); err != nil {
+		return nil, errors.ErrorFailedToCloneRepository
+	}
+
+	if archive.Len() > maxRepositoryArchive {
+		return nil, errors.ErrorRepositoryTooLarge
+	}
+
+	return archive.Bytes(), nil
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SameRepository compares repository urls, ignoring case and a .git suffix.
+func SameRepository(a, b string) bool {
+	normalize := func(repository string) string {
+		return strings.TrimSuffix(strings.TrimSuffix(strings.ToLower(repository), "/"), ".git")
+	}
+
+	return normalize(a) == normalize(b)
+}
+
+// PostCommitStatus sets the status of a commit on a GitHub repository, shown
+// next to the commit and on pull requests.
+func PostCommitStatus(repository, commit, token, state, description string) error {
+	u, err := url.Parse(repository)
+	if err != nil || !strings.EqualFold(u.Hostname(), "github.com") {
+		return fmt.Errorf("not a github repository: %s", repository)
+	}
+	path := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+
+	body, err := json.Marshal(map[string]string{
+		"state":       state,
+		"description": description,
+		"context":     "plague-doctor",
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("https://api.github.com/repos/%s/statuses/%s", path, commit), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "token "+token)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("github responded %d", resp.StatusCode)
+	}
+
+	return nil
+}
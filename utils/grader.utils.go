@@ -0,0 +1,255 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"backend/config"
+)
+
+// ErrGraderUnavailable a call to court herald refused because it kept
+// failing, without trying it.
+var ErrGraderUnavailable = errors.New("court herald circuit open")
+
+// Circuit states.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "halfOpen"
+)
+
+// GraderStats what the calls to a grader have done since the server
+// started.
+type GraderStats struct {
+	Requests  int64      `json:"requests"`
+	Failures  int64      `json:"failures"`
+	Timeouts  int64      `json:"timeouts"`
+	Retries   int64      `json:"retries"`
+	Rejected  int64      `json:"rejected"`
+	Circuit   string     `json:"circuit"`
+	OpenUntil *time.Time `json:"openUntil,omitempty"`
+}
+
+// graderBreaker counts the failures in a row of the calls to a grader. Once
+// open it refuses calls until the cooldown passes, then lets a single call
+// through to see if the grader recovered.
+type graderBreaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+	stats     GraderStats
+}
+
+var (
+	breakers   = make(map[string]*graderBreaker)
+	breakersMu sync.Mutex
+)
+
+// breakerFor the breaker of the grader at a base URL, each grader fails on
+// its own.
+func breakerFor(base string) *graderBreaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	breaker, found := breakers[base]
+	if !found {
+		breaker = &graderBreaker{}
+		breakers[base] = breaker
+	}
+
+	return breaker
+}
+
+func (b *graderBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.stats.Requests++
+	if b.openUntil.IsZero() {
+		return true
+	}
+
+	if time.Now().Before(b.openUntil) || b.probing {
+		b.stats.Rejected++
+		return false
+	}
+
+	b.probing = true
+	return true
+}
+
+func (b *graderBreaker) record(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if ok {
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return
+	}
+
+	b.failures++
+	b.stats.Failures++
+	if b.failures >= config.C.Grader.BreakerFailures {
+		b.openUntil = time.Now().Add(config.C.Grader.BreakerCooldown)
+	}
+}
+
+func (b *graderBreaker) count(counter *int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	*counter++
+}
+
+// CourtHeraldStats the counters of the calls to each grader called since the
+// server started, by base URL, and the state of their circuits.
+func CourtHeraldStats() map[string]GraderStats {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	all := make(map[string]GraderStats)
+	for base, breaker := range breakers {
+		all[base] = breaker.snapshot()
+	}
+
+	return all
+}
+
+func (b *graderBreaker) snapshot() GraderStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := b.stats
+	switch {
+	case b.openUntil.IsZero():
+		stats.Circuit = CircuitClosed
+	case time.Now().Before(b.openUntil):
+		stats.Circuit = CircuitOpen
+		openUntil := b.openUntil
+		stats.OpenUntil = &openUntil
+	default:
+		stats.Circuit = CircuitHalfOpen
+	}
+
+	return stats
+}
+
+// retryable whether a failed call can be made again. Calls that change
+// something are only retried when court herald cannot have acted on them:
+// the connection was refused or it answered it is unavailable.
+func retryable(method string, resp *http.Response, err error) bool {
+	if err != nil {
+		if method == "GET" {
+			return true
+		}
+
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		opErr, ok := err.(*net.OpError)
+		return ok && opErr.Op == "dial"
+	}
+
+	if method == "GET" {
+		return resp.StatusCode >= 500
+	}
+
+	return resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable
+}
+
+// backoff how long to wait before a retry, doubling each attempt with full
+// jitter so instances do not retry in step.
+func backoff(attempt int) time.Duration {
+	max := 200 * time.Millisecond << uint(attempt)
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// CourtHerald calls the court herald at the base URL at the path with a JSON
+// body, nil for none. Each attempt has GRADER_TIMEOUT, failures are retried
+// with backoff and while it keeps failing calls fail with
+// ErrGraderUnavailable without being made. Answers of 5xx count as failures
+// and are returned.
+func CourtHerald(base, method, path string, body []byte) (*http.Response, error) {
+	breaker := breakerFor(base)
+	if !breaker.allow() {
+		return nil, ErrGraderUnavailable
+	}
+
+	target := base + path
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, target, bytes.NewReader(body))
+		if err != nil {
+			breaker.record(true)
+			return nil, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		client := &http.Client{Timeout: config.C.Grader.Timeout}
+		resp, err := client.Do(req)
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			breaker.count(&breaker.stats.Timeouts)
+		}
+		if err == nil && resp.StatusCode < 500 {
+			breaker.record(true)
+			return resp, nil
+		}
+
+		if attempt >= config.C.Grader.Retries || !retryable(method, resp, err) {
+			breaker.record(false)
+			if err != nil {
+				return nil, err
+			}
+			return resp, nil
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+		breaker.count(&breaker.stats.Retries)
+		time.Sleep(backoff(attempt))
+	}
+}
+
+// CourtHeraldStream opens a long lived stream from the court herald at the
+// base URL at the path. It is not retried or timed out, the context ends it,
+// but failing to answer counts against the circuit like any call.
+func CourtHeraldStream(ctx context.Context, base, path string) (*http.Response, error) {
+	breaker := breakerFor(base)
+	if !breaker.allow() {
+		return nil, ErrGraderUnavailable
+	}
+
+	req, err := http.NewRequest("GET", base+path, nil)
+	if err != nil {
+		breaker.record(true)
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil || resp.StatusCode >= 500 {
+		breaker.record(false)
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+
+	breaker.record(true)
+	return resp, nil
+}
+
+func init() {
+	rand.Seed(time.Now().UnixNano())
+}
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"backend/config"
+)
+
+func TestGraderBreaker(t *testing.T) {
+	defer func(grader config.Grader) { config.C.Grader = grader }(config.C.Grader)
+	config.C.Grader.BreakerFailures = 3
+	config.C.Grader.BreakerCooldown = time.Hour
+
+	breaker := &graderBreaker{}
+	for call := 1; call <= 3; call++ {
+		if !breaker.allow() {
+			t.Fatalf("call %d was refused before the circuit opened", call)
+		}
+		if circuit := breaker.snapshot().Circuit; circuit != CircuitClosed {
+			t.Errorf("circuit is %s after %d failures, not closed", circuit, call-1)
+		}
+		breaker.record(false)
+	}
+
+	stats := breaker.snapshot()
+	if stats.Circuit != CircuitOpen || stats.OpenUntil == nil {
+		t.Fatalf("circuit is %s after 3 failures in a row, not open", stats.Circuit)
+	}
+	if breaker.allow() {
+		t.Errorf("a call was let through the open circuit")
+	}
+
+	breaker.openUntil = time.Now().Add(-time.Second)
+	if circuit := breaker.snapshot().Circuit; circuit != CircuitHalfOpen {
+		t.Errorf("circuit is %s once the cooldown passed, not half open", circuit)
+	}
+	if !breaker.allow() {
+		t.Fatalf("no call was let through to probe the grader")
+	}
+	if breaker.allow() {
+		t.Errorf("a second call was let through while probing")
+	}
+
+	breaker.record(false)
+	if circuit := breaker.snapshot().Circuit; circuit != CircuitOpen {
+		t.Errorf("circuit is %s after a failed probe, not open", circuit)
+	}
+
+	breaker.openUntil = time.Now().Add(-time.Second)
+	breaker.allow()
+	breaker.record(true)
+	if circuit := breaker.snapshot().Circuit; circuit != CircuitClosed || breaker.failures != 0 {
+		t.Errorf("circuit is %s with %d failures after a probe succeeded, not closed", circuit, breaker.failures)
+	}
+
+	stats = breaker.snapshot()
+	if stats.Requests != 7 || stats.Rejected != 2 || stats.Failures != 4 {
+		t.Errorf("breaker counted %+v", stats)
+	}
+}
+
+func TestGraderBreakerSuccessResets(t *testing.T) {
+	defer func(grader config.Grader) { config.C.Grader = grader }(config.C.Grader)
+	config.C.Grader.BreakerFailures = 2
+	config.C.Grader.BreakerCooldown = time.Hour
+
+	breaker := &graderBreaker{}
+	for _, ok := range []bool{false, true, false, true, false} {
+		breaker.allow()
+		breaker.record(ok)
+	}
+	if circuit := breaker.snapshot().Circuit; circuit != CircuitClosed {
+		t.Errorf("circuit is %s though failures were never in a row, not closed", circuit)
+	}
+}
+
+func TestBreakerFor(t *testing.T) {
+	one, two := breakerFor("http://one.test"), breakerFor("http://two.test")
+	if one == two {
+		t.Errorf("graders at different URLs share a breaker")
+	}
+	if breakerFor("http://one.test") != one {
+		t.Errorf("a grader was given a new breaker")
+	}
+	if _, found := CourtHeraldStats()["http://one.test"]; !found {
+		t.Errorf("a grader's stats were not reported")
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	refused := &url.Error{Op: "Post", URL: "http://grader", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}
+	timeout := &url.Error{Op: "Post", URL: "http://grader", Err: &net.OpError{Op: "read", Err: errors.New("i/o timeout")}}
+	answered := func(status int) *http.Response { return &http.Response{StatusCode: status} }
+
+	for _, c := range []struct {
+		name   string
+		method string
+		resp   *http.Response
+		err    error
+		want   bool
+	}{
+		{"GET refused", "GET", nil, refused, true},
+		{"GET timed out", "GET", nil, timeout, true},
+		{"GET 500", "GET", answered(500), nil, true},
+		{"GET 404", "GET", answered(404), nil, false},
+		{"GET 200", "GET", answered(200), nil, false},
+		{"POST refused", "POST", nil, refused, true},
+		{"POST timed out", "POST", nil, timeout, false},
+		{"POST 502", "POST", answered(http.StatusBadGateway), nil, true},
+		{"POST 503", "POST", answered(http.StatusServiceUnavailable), nil, true},
+		{"POST 500", "POST", answered(500), nil, false},
+		{"POST 504", "POST", answered(http.StatusGatewayTimeout), nil, false},
+	} {
+		if got := retryable(c.method, c.resp, c.err); got != c.want {
+			t.Errorf("%s: retryable = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt, max := range []time.Duration{200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, 1600 * time.Millisecond} {
+		for i := 0; i < 100; i++ {
+			if wait := backoff(attempt); wait < 0 || wait >= max {
+				t.Fatalf("backoff(%d) = %v, want under %v", attempt, wait, max)
+			}
+		}
+	}
+}
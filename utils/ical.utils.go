@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	icalUTC   = "20060102T150405Z"
+	icalLocal = "20060102T150405"
+	// icalLineLength octets a content line may have before it is folded.
+	icalLineLength = 75
+)
+
+// CalendarEvent an event of an iCalendar feed. Weekly events repeat from Start
+// until Until, on the wall clock of Location so they follow daylight saving.
+type CalendarEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time
+	Weekly      bool
+	Until       time.Time
+	TimeZone    *time.Location
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// writeICalLine writes a content line, folding it at 75 octets without
+// splitting a character.
+func writeICalLine(buf *bytes.Buffer, line string) {
+	limit := icalLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		buf.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// the leading space of a continuation counts towards its length
+		limit = icalLineLength - 1
+	}
+	buf.WriteString(line + "\r\n")
+}
+
+// ICalendar renders events as an iCalendar (RFC 5545) feed.
+func ICalendar(name string, events []CalendarEvent) []byte {
+	buf := new(bytes.Buffer)
+	stamp := time.Now().UTC().Format(icalUTC)
+
+	writeICalLine(buf, "BEGIN:VCALENDAR")
+	writeICalLine(buf, "VERSION:2.0")
+	writeICalLine(buf, "PRODID:-//Tyr//Plague Doctor//EN")
+	writeICalLine(buf, "CALSCALE:GREGORIAN")
+	writeICalLine(buf, "METHOD:PUBLISH")
+	writeICalLine(buf, "X-WR-CALNAME:"+icalEscaper.Replace(name))
+
+	for _, event := range events {
+		writeICalLine(buf, "BEGIN:VEVENT")
+		writeICalLine(buf, "UID:"+event.UID)
+		writeICalLine(buf, "DTSTAMP:"+stamp)
+		if event.Weekly && event.TimeZone != nil {
+			tz := event.TimeZone.String()
+			writeICalLine(buf, "DTSTART;TZID="+tz+":"+event.Start.In(event.TimeZone).Format(icalLocal))
+			writeICalLine(buf, "DTEND;TZID="+tz+":"+event.End.In(event.TimeZone).Format(icalLocal))
+			rule := "RRULE:FREQ=WEEKLY"
+			if !event.Until.IsZero() {
+				rule += ";UNTIL=" + event.Until.UTC().Format(icalUTC)
+			}
+			writeICalLine(buf, rule)
+		} else {
+			writeICalLine(buf, "DTSTART:"+event.Start.UTC().Format(icalUTC))
+			writeICalLine(buf, "DTEND:"+event.End.UTC().Format(icalUTC))
+		}
+		writeICalLine(buf, "SUMMARY:"+icalEscaper.Replace(event.Summary))
+		if event.Description != "" {
+			writeICalLine(buf, "DESCRIPTION:"+icalEscaper.Replace(event.Description))
+		}
+		if event.Location != "" {
+			writeICalLine(buf, "LOCATION:"+icalEscaper.Replace(event.Location))
+		}
+		writeICalLine(buf, "END:VEVENT")
+	}
+
+	writeICalLine(buf, "END:VCALENDAR")
+
+	return buf.Bytes()
+}
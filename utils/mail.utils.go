@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+
+	"backend/config"
+	"backend/errors"
+)
+
+// MailConfigured reports whether SMTP_HOST is set, without it no email is
+// sent.
+func MailConfigured() bool {
+	return config.C.SMTP.Host != ""
+}
+
+// SendMail sends a plain text email through SMTP_HOST:SMTP_PORT from
+// MAIL_FROM, authenticating when SMTP_USERNAME is set.
+func SendMail(to, subject, body string, headers map[string]string) errors.APIError {
+	settings := config.C.SMTP
+	host := settings.Host
+
+	var auth smtp.Auth
+	if settings.Username != "" {
+		auth = smtp.PlainAuth("", settings.Username, settings.Password, host)
+	}
+
+	from := settings.From
+	message := new(strings.Builder)
+	fmt.Fprintf(message, "From: %s\r\nTo: %s\r\nSubject: %s\r\n", from, to, subject)
+	for name, value := range headers {
+		fmt.Fprintf(message, "%s: %s\r\n", name, value)
+	}
+	fmt.Fprintf(message, "MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s", strings.Replace(body, "\n", "\r\n", -1))
+
+	err := smtp.SendMail(host+":"+settings.Port, auth, from, []string{to}, []byte(message.String()))
+	if err != nil {
+		return errors.ErrorSendingMail
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	blackfriday "github.com/russross/blackfriday/v2"
+)
+
+var (
+	fencePattern = regexp.MustCompile("^\\s*(```|~~~)")
+	mathPattern  = regexp.MustCompile(`(?s)\$\$.+?\$\$|\$[^$\n]+?\$`)
+	classPattern = regexp.MustCompile(`^[a-zA-Z0-9 _-]+$`)
+
+	htmlPolicy = newHTMLPolicy()
+)
+
+// newHTMLPolicy builds the sanitization policy used for both rendered markdown
+// and grader supplied html. Classes are kept so code highlighting and diff
+// styling still work on the frontend.
+func newHTMLPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(classPattern).OnElements("code", "div", "pre", "span")
+
+	return p
+}
+
+func mathPlaceholder(index int) string {
+	return fmt.Sprintf("MATHPLACEHOLDER%dEND", index)
+}
+
+// protectMath swaps every $inline$ and $$display$$ math span outside of code
+// fences for a placeholder so the markdown renderer does not mangle it.
+func protectMath(md string) (string, []string) {
+	var (
+		out     []string
+		outside []string
+		spans   []string
+		inFence bool
+	)
+
+	flush := func() {
+		if len(outside) == 0 {
+			return
+		}
+		text := mathPattern.ReplaceAllStringFunc(strings.Join(outside, "\n"), func(m string) string {
+			spans = append(spans, m)
+			return mathPlaceholder(len(spans) - 1)
+		})
+		out = append(out, text)
+		outside = nil
+	}
+
+	for _, line := range strings.Split(md, "\n") {
+		if fencePattern.MatchString(line) {
+			if !inFence {
+				flush()
+			}
+			inFence = !inFence
+			out = append(out, line)
+			continue
+		}
+
+		if inFence {
+			out = append(out, line)
+		} else {
+			outside = append(outside, line)
+		}
+	}
+	flush()
+
+	return strings.Join(out, "\n"), spans
+}
+
+// RenderMarkdown converts markdown into sanitized html. Fenced code blocks keep
+// their language class and math spans are emitted as escaped
+// <span class="math inline|display"> elements for client side typesetting.
+func RenderMarkdown(md string) string {
+	protected, spans := protectMath(md)
+
+	rendered := blackfriday.Run(
+		[]byte(protected),
+		blackfriday.WithExtensions(blackfriday.CommonExtensions),
+	)
+	out := SanitizeHTML(string(rendered))
+
+	for index, span := range spans {
+		class := "math inline"
+		if strings.HasPrefix(span, "$$") {
+			class = "math display"
+		}
+		out = strings.Replace(
+			out,
+			mathPlaceholder(index),
+			fmt.Sprintf(`<span class="%s">%s</span>`, class, html.EscapeString(span)),
+			1,
+		)
+	}
+
+	return out
+}
+
+// SanitizeHTML strips scripts, event handlers and other unsafe markup from html.
+func SanitizeHTML(content string) string {
+	return htmlPolicy.Sanitize(content)
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/options"
+	"github.com/mongodb/mongo-go-driver/mongo/readconcern"
+	"github.com/mongodb/mongo-go-driver/mongo/readpref"
+
+	"backend/config"
+)
+
+// AnalyticsCollection the collection of db named name as the heavy read only
+// aggregations read it, with the read preference and concern of
+// config.C.AnalyticsReads. Nothing is written through it.
+func AnalyticsCollection(name string, db *mongo.Database) *mongo.Collection {
+	reads := config.C.AnalyticsReads
+
+	opts := make([]readpref.Option, 0)
+	if reads.MaxStaleness != 0 {
+		opts = append(opts, readpref.WithMaxStaleness(reads.MaxStaleness))
+	}
+
+	var pref *readpref.ReadPref
+	switch reads.ReadPreference {
+	case "primaryPreferred":
+		pref = readpref.PrimaryPreferred(opts...)
+	case "secondary":
+		pref = readpref.Secondary(opts...)
+	case "secondaryPreferred":
+		pref = readpref.SecondaryPreferred(opts...)
+	case "nearest":
+		pref = readpref.Nearest(opts...)
+	default:
+		pref = readpref.Primary()
+	}
+
+	concern := readconcern.Local()
+	if reads.ReadConcern == "majority" {
+		concern = readconcern.Majority()
+	}
+
+	return db.Collection(name, options.Collection().SetReadPreference(pref).SetReadConcern(concern))
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Views a value can be redacted for. A field tagged view:"student,staff" is
+// only kept in those views, fields without a view tag are kept in all of them.
+const (
+	// ViewStaff what professors and assistants see.
+	ViewStaff = "staff"
+	// ViewStudent what a student sees of their own work.
+	ViewStudent = "student"
+	// ViewShare what whoever a student shares a submission with sees.
+	ViewShare = "share"
+)
+
+// Redactable is implemented by values that are left out of a view entirely,
+// like the results of tests students are not shown.
+type Redactable interface {
+	VisibleTo(view string) bool
+}
+
+// ViewFor the view a course role sees.
+func ViewFor(role string) string {
+	if role == "student" {
+		return ViewStudent
+	}
+
+	return ViewStaff
+}
+
+// Redact removes from v, a pointer, what the view may not see, all the way
+// down: fields tagged with other views are zeroed and slice elements that are
+// not VisibleTo the view are dropped.
+func Redact(v interface{}, view string) {
+	redact(reflect.ValueOf(v), view)
+}
+
+func redact(v reflect.Value, view string) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			redact(v.Elem(), view)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Field(i)
+			if !field.CanSet() {
+				continue
+			}
+
+			if views, tagged := t.Field(i).Tag.Lookup("view"); tagged && !inView(views, view) {
+				field.Set(reflect.Zero(field.Type()))
+				continue
+			}
+			redact(field, view)
+		}
+	case reflect.Slice:
+		if v.IsNil() {
+			return
+		}
+
+		kept := reflect.MakeSlice(v.Type(), 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			elem := v.Index(i)
+			if !visible(elem, view) {
+				continue
+			}
+			redact(elem, view)
+			kept = reflect.Append(kept, elem)
+		}
+
+		if v.CanSet() {
+			v.Set(kept)
+		}
+	}
+}
+
+func visible(v reflect.Value, view string) bool {
+	if r, ok := v.Interface().(Redactable); ok {
+		return r.VisibleTo(view)
+	}
+	if v.CanAddr() {
+		if r, ok := v.Addr().Interface().(Redactable); ok {
+			return r.VisibleTo(view)
+		}
+	}
+
+	return true
+}
+
+func inView(views, view string) bool {
+	for _, v := range strings.Split(views, ",") {
+		if strings.TrimSpace(v) == view {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,84 @@
+package utils
+
+import "testing"
+
+type redactResult struct {
+	Name   string
+	Hidden bool
+	Stderr string `view:"staff"`
+}
+
+func (r redactResult) VisibleTo(view string) bool {
+	return !r.Hidden || view == ViewStaff
+}
+
+type redactSubmission struct {
+	Results []redactResult
+	Source  *string `view:"student,staff"`
+	Nested  *redactSubmission
+	private []redactResult
+}
+
+func newRedactSubmission() *redactSubmission {
+	source := "10.0.0.1"
+	results := []redactResult{{Name: "shown", Stderr: "trace"}, {Name: "hidden", Hidden: true}}
+	return &redactSubmission{
+		Results: results,
+		Source:  &source,
+		Nested:  &redactSubmission{Results: append([]redactResult(nil), results...)},
+		private: results,
+	}
+}
+
+func TestRedactStaffSeesEverything(t *testing.T) {
+	sub := newRedactSubmission()
+	Redact(sub, ViewStaff)
+
+	if len(sub.Results) != 2 || sub.Results[0].Stderr != "trace" || sub.Source == nil {
+		t.Errorf("staff were not shown everything: %+v", sub)
+	}
+}
+
+func TestRedactStudent(t *testing.T) {
+	sub := newRedactSubmission()
+	Redact(sub, ViewStudent)
+
+	for _, results := range [][]redactResult{sub.Results, sub.Nested.Results} {
+		if len(results) != 1 || results[0].Name != "shown" {
+			t.Fatalf("students were shown results %+v", results)
+		}
+		if results[0].Stderr != "" {
+			t.Errorf("students were shown stderr")
+		}
+	}
+	if sub.Source == nil {
+		t.Errorf("students were not shown where they submitted from")
+	}
+	if len(sub.private) != 2 {
+		t.Errorf("unexported fields were redacted")
+	}
+}
+
+func TestRedactShare(t *testing.T) {
+	sub := newRedactSubmission()
+	Redact(sub, ViewShare)
+
+	if sub.Source != nil {
+		t.Errorf("a share link was shown where the submission was sent from")
+	}
+	if len(sub.Results) != 1 {
+		t.Errorf("a share link was shown %d results", len(sub.Results))
+	}
+}
+
+func TestViewFor(t *testing.T) {
+	for role, view := range map[string]string{
+		"student":   ViewStudent,
+		"assistant": ViewStaff,
+		"teacher":   ViewStaff,
+	} {
+		if got := ViewFor(role); got != view {
+			t.Errorf("%s sees the %s view, not %s", role, got, view)
+		}
+	}
+}
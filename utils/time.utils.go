@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/bson/primitive"
+
+	"backend/config"
+)
+
+// LocalTime an instant stored in UTC together with the hints a client needs to
+// present it in a user's timezone.
+type LocalTime struct {
+	UTC              string `json:"utc"`
+	Local            string `json:"local"`
+	Timezone         string `json:"timezone"`
+	Abbreviation     string `json:"abbreviation"`
+	UTCOffsetMinutes int    `json:"utcOffsetMinutes"`
+}
+
+// ValidTimezone reports whether name is a known IANA timezone.
+func ValidTimezone(name string) bool {
+	_, err := time.LoadLocation(name)
+	return name != "" && err == nil
+}
+
+// LoadLocation returns the named timezone, falling back to DEFAULT_TIMEZONE and
+// then UTC when the name is empty or unknown.
+func LoadLocation(name string) *time.Location {
+	for _, candidate := range []string{name, config.C.DefaultTimezone} {
+		if candidate == "" {
+			continue
+		}
+
+		if loc, err := time.LoadLocation(candidate); err == nil {
+			return loc
+		}
+	}
+
+	return time.UTC
+}
+
+// DeploymentLocation the timezone of DEFAULT_TIMEZONE, UTC when unset. Late
+// days are counted in it, so students and staff see the same count wherever
+// they are.
+func DeploymentLocation() *time.Location {
+	return LoadLocation("")
+}
+
+// DateTimeToTime converts a stored DateTime (ms since the epoch) into a UTC time.
+func DateTimeToTime(dt primitive.DateTime) time.Time {
+	ms := int64(dt)
+	return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond)).UTC()
+}
+
+// TimeToDateTime converts a time into a DateTime to store.
+func TimeToDateTime(t time.Time) primitive.DateTime {
+	return primitive.DateTime(t.UnixNano() / int64(time.Millisecond))
+}
+
+// Localize describes a stored instant in the given timezone. The offset is the
+// one in effect at that instant, so dates on either side of a DST change are
+// presented correctly.
+func Localize(dt primitive.DateTime, loc *time.Location) LocalTime {
+	utc := DateTimeToTime(dt)
+	local := utc.In(loc)
+	abbreviation, offset := local.Zone()
+
+	return LocalTime{
+		UTC:              utc.Format(time.RFC3339),
+		Local:            local.Format(time.RFC3339),
+		Timezone:         loc.String(),
+		Abbreviation:     abbreviation,
+		UTCOffsetMinutes: offset / 60,
+	}
+}
+
+// CalendarDaysLate counts how many local calendar days after due the submitted
+// instant falls, 0 if it is on time. Days are counted on the wall clock of loc
+// rather than in 24 hour blocks so a DST change never adds or drops a day.
+func CalendarDaysLate(due, submitted time.Time, loc *time.Location) int {
+	if !submitted.After(due) {
+		return 0
+	}
+
+	d := due.In(loc)
+	s := submitted.In(loc)
+	dueDay := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)
+	subDay := time.Date(s.Year(), s.Month(), s.Day(), 0, 0, 0, 0, time.UTC)
+
+	days := int(subDay.Sub(dueDay).Hours() / 24)
+	if days == 0 {
+		days = 1
+	}
+
+	return days
+}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"backend/config"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone %s is not installed: %s", name, err)
+	}
+
+	return loc
+}
+
+func TestCalendarDaysLate(t *testing.T) {
+	ny := mustLocation(t, "America/New_York")
+	at := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, ny)
+	}
+
+	tests := []struct {
+		name      string
+		due       time.Time
+		submitted time.Time
+		days      int
+	}{
+		{"on time", at(2019, 3, 1, 23, 59), at(2019, 3, 1, 23, 58), 0},
+		{"at the deadline", at(2019, 3, 1, 23, 59), at(2019, 3, 1, 23, 59), 0},
+		{"a minute late, same day", at(2019, 3, 1, 22, 0), at(2019, 3, 1, 22, 1), 1},
+		{"past midnight", at(2019, 3, 1, 23, 59), at(2019, 3, 2, 0, 1), 1},
+		{"just before the second midnight", at(2019, 3, 1, 23, 59), at(2019, 3, 2, 23, 59), 1},
+		{"past the second midnight", at(2019, 3, 1, 23, 59), at(2019, 3, 3, 0, 1), 2},
+		// clocks spring forward on March 10, the day is 23 hours long
+		{"over spring forward", at(2019, 3, 9, 23, 30), at(2019, 3, 11, 0, 30), 2},
+		// clocks fall back on November 3, the day is 25 hours long
+		{"over fall back", at(2019, 11, 2, 23, 30), at(2019, 11, 3, 23, 30), 1},
+		{"past fall back", at(2019, 11, 2, 23, 30), at(2019, 11, 4, 0, 10), 2},
+	}
+
+	for _, test := range tests {
+		if days := CalendarDaysLate(test.due, test.submitted, ny); days != test.days {
+			t.Errorf("%s: %d days late, not %d", test.name, days, test.days)
+		}
+	}
+}
+
+// The same instants can be a different number of days apart on another
+// wall clock, which is why late days are counted in one timezone for all.
+func TestCalendarDaysLateDependsOnTimezone(t *testing.T) {
+	ny := mustLocation(t, "America/New_York")
+	due := time.Date(2019, 3, 1, 22, 0, 0, 0, ny)
+	submitted := time.Date(2019, 3, 3, 0, 30, 0, 0, ny)
+
+	if days := CalendarDaysLate(due, submitted, ny); days != 2 {
+		t.Errorf("%d days late in New York, not 2", days)
+	}
+	if days := CalendarDaysLate(due, submitted, time.UTC); days != 1 {
+		t.Errorf("%d days late in UTC, not 1", days)
+	}
+}
+
+func TestDeploymentLocation(t *testing.T) {
+	defer func(timezone string) { config.C.DefaultTimezone = timezone }(config.C.DefaultTimezone)
+
+	config.C.DefaultTimezone = ""
+	if loc := DeploymentLocation(); loc != time.UTC {
+		t.Errorf("without DEFAULT_TIMEZONE late days are counted in %s, not UTC", loc)
+	}
+
+	config.C.DefaultTimezone = "America/New_York"
+	if loc := DeploymentLocation(); loc.String() != "America/New_York" {
+		t.Errorf("late days are counted in %s, not DEFAULT_TIMEZONE", loc)
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	ny := mustLocation(t, "America/New_York")
+
+	tests := []struct {
+		name         string
+		at           time.Time
+		local        string
+		abbreviation string
+		offset       int
+	}{
+		{"midnight", time.Date(2019, 3, 2, 5, 0, 0, 0, time.UTC), "2019-03-02T00:00:00-05:00", "EST", -300},
+		{"a minute before midnight", time.Date(2019, 3, 2, 4, 59, 0, 0, time.UTC), "2019-03-01T23:59:00-05:00", "EST", -300},
+		{"before spring forward", time.Date(2019, 3, 10, 6, 59, 0, 0, time.UTC), "2019-03-10T01:59:00-05:00", "EST", -300},
+		{"after spring forward", time.Date(2019, 3, 10, 7, 0, 0, 0, time.UTC), "2019-03-10T03:00:00-04:00", "EDT", -240},
+		{"first 1:30 of fall back", time.Date(2019, 11, 3, 5, 30, 0, 0, time.UTC), "2019-11-03T01:30:00-04:00", "EDT", -240},
+		{"second 1:30 of fall back", time.Date(2019, 11, 3, 6, 30, 0, 0, time.UTC), "2019-11-03T01:30:00-05:00", "EST", -300},
+	}
+
+	for _, test := range tests {
+		local := Localize(TimeToDateTime(test.at), ny)
+		if local.Local != test.local || local.Abbreviation != test.abbreviation || local.UTCOffsetMinutes != test.offset {
+			t.Errorf("%s: localized to %+v", test.name, local)
+		}
+		if local.UTC != test.at.Format(time.RFC3339) || local.Timezone != "America/New_York" {
+			t.Errorf("%s: localized to %+v", test.name, local)
+		}
+	}
+}
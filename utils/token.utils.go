@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math/big"
+)
+
+// codeAlphabet leaves out characters that are easy to confuse when read aloud
+// or copied by hand (0/O, 1/I/L).
+const codeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// RandomCode returns a cryptographically random, human friendly code.
+func RandomCode(length int) (string, error) {
+	code := make([]byte, length)
+	max := big.NewInt(int64(len(codeAlphabet)))
+	for index := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[index] = codeAlphabet[n.Int64()]
+	}
+
+	return string(code), nil
+}
+
+// Sign an HMAC of a message, so a value handed out can be checked to be one the
+// server made.
+func Sign(secret, message string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// RotatingCode derives a human friendly code from a secret and a counter, so
+// the code changes as the counter does without storing every code.
+func RotatingCode(secret string, counter int64, length int) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+
+	code := make([]byte, length)
+	for index := range code {
+		code[index] = codeAlphabet[int(sum[index%len(sum)])%len(codeAlphabet)]
+	}
+
+	return string(code)
+}
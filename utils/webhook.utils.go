@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// privateNetworks the addresses webhooks may not be delivered to: loopback,
+// private, link-local, including cloud metadata services, shared and
+// unspecified addresses, where the cluster's own services live.
+var privateNetworks = func() []*net.IPNet {
+	cidrs := []string{
+		"0.0.0.0/8",
+		"10.0.0.0/8",
+		"100.64.0.0/10",
+		"127.0.0.0/8",
+		"169.254.0.0/16",
+		"172.16.0.0/12",
+		"192.168.0.0/16",
+		"::/128",
+		"::1/128",
+		"fc00::/7",
+		"fe80::/10",
+	}
+
+	networks := make([]*net.IPNet, len(cidrs))
+	for index, cidr := range cidrs {
+		_, networks[index], _ = net.ParseCIDR(cidr)
+	}
+
+	return networks
+}()
+
+// errPrivateAddress refuses to reach a webhook on a private address.
+var errPrivateAddress = errors.New("webhook address is not public")
+
+// PublicAddress whether webhooks may be delivered to an address.
+func PublicAddress(ip net.IP) bool {
+	if ip.IsMulticast() {
+		return false
+	}
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CheckWebhookHost refuses hosts that resolve to any address webhooks may
+// not be delivered to. Deliveries check the address they dial again, as
+// the host may resolve differently by then.
+func CheckWebhookHost(host string) error {
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return err
+	}
+
+	for _, ip := range ips {
+		if !PublicAddress(ip) {
+			return errPrivateAddress
+		}
+	}
+
+	return nil
+}
+
+// webhookClient delivers webhooks only to public addresses, checked once the
+// host is resolved, and does not follow redirects, which could lead anywhere.
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+
+				if ip := net.ParseIP(host); ip == nil || !PublicAddress(ip) {
+					return errPrivateAddress
+				}
+
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// SignPayload the signature of a webhook payload, "sha256=" and the hex HMAC
+// of the body keyed with the webhook's secret, as GitHub signs its own.
+func SignPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// PostWebhook posts a signed event to a webhook's URL. It returns the status
+// code the receiver answered with, an error unless that was a 2xx.
+func PostWebhook(url, secret, event, delivery string, payload []byte) (int, error) {
+	req, err := http.NewRequest("POST", url, strings.NewReader(string(payload)))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Plague-Doctor-Webhook")
+	req.Header.Set("X-Tyr-Event", event)
+	req.Header.Set("X-Tyr-Delivery", delivery)
+	req.Header.Set("X-Tyr-Signature", SignPayload(secret, payload))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
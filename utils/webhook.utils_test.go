@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSignPayload(t *testing.T) {
+	for _, c := range []struct {
+		secret, payload, want string
+	}{
+		{"key", "The quick brown fox jumps over the lazy dog", "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"},
+		{"", "", "sha256=b613679a0814d9ec772f95d778c35fc5ff1697c493715653c6c712144292c5ad"},
+	} {
+		if got := SignPayload(c.secret, []byte(c.payload)); got != c.want {
+			t.Errorf("SignPayload(%q, %q) = %s, want %s", c.secret, c.payload, got, c.want)
+		}
+	}
+
+	if SignPayload("one", []byte("{}")) == SignPayload("two", []byte("{}")) {
+		t.Errorf("payloads signed with different secrets have the same signature")
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	for address, want := range map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.20.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"224.0.0.1":       false,
+		"::1":             false,
+		"::":              false,
+		"fd00::1":         false,
+		"fe80::1":         false,
+		"::ffff:10.0.0.1": false,
+	} {
+		if got := PublicAddress(net.ParseIP(address)); got != want {
+			t.Errorf("PublicAddress(%s) = %v, want %v", address, got, want)
+		}
+	}
+}
+
+func TestCheckWebhookHost(t *testing.T) {
+	if err := CheckWebhookHost("127.0.0.1"); err != errPrivateAddress {
+		t.Errorf("loopback host got %v, want %v", err, errPrivateAddress)
+	}
+	if err := CheckWebhookHost("93.184.216.34"); err != nil {
+		t.Errorf("public host was refused: %v", err)
+	}
+}
+
+// Deliveries are refused once the host resolves to a private address, even
+// when it was public as the webhook was created.
+func TestPostWebhookRefusesPrivateAddresses(t *testing.T) {
+	received := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = true
+	}))
+	defer server.Close()
+
+	if _, err := PostWebhook(server.URL, "secret", "ping", "1", []byte("{}")); err == nil || received {
+		t.Errorf("a webhook was delivered to %s", server.URL)
+	}
+}